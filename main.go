@@ -1,25 +1,307 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/mcp"
+	"github.com/evilvic/ollama-tui/pkg/session"
+	"github.com/evilvic/ollama-tui/pkg/tunnel"
 	"github.com/evilvic/ollama-tui/pkg/ui"
+	"github.com/evilvic/ollama-tui/pkg/utils"
 )
 
 func main() {
-	// Use the full terminal screen and enable mouse support
-	p := tea.NewProgram(
-		ui.NewModel(),
+	os.Exit(run())
+}
+
+// run starts the application and returns its exit code. What it opens is
+// closed by deferred calls, which would be skipped by an os.Exit in it.
+func run() int {
+	record := flag.String("record", "", "record raw provider streams (API keys redacted) to a fixture `file`")
+	replay := flag.String("replay", "", "replay provider streams from a fixture `file` instead of the network")
+	last := flag.Bool("last", false, "skip provider and model selection and chat with the last used model")
+	provider := flag.String("provider", "", "skip provider selection and use `name` (ollama, openai, lmstudio, mistral, deepseek, together, fireworks, perplexity, huggingface or a plugin)")
+	modelName := flag.String("model", "", "skip model selection and chat with `name`")
+	accessible := flag.Bool("accessible", false, "print the conversation as plain text for screen readers")
+	proxy := flag.String("proxy", "", "send provider requests through the proxy at `url`")
+	caCert := flag.String("ca-cert", "", "trust the certificate authorities in the PEM `file`")
+	insecure := flag.Bool("insecure", false, "accept any server certificate, for self-signed local servers")
+	connectTimeout := flag.String("connect-timeout", "", "limit connecting to a provider to `duration` (default 10s)")
+	readTimeout := flag.String("read-timeout", "", "fail responses that send nothing for `duration` (default 5m)")
+	showVersion := flag.Bool("version", false, "print the version and build details and exit")
+	profile := flag.String("profile", "", "use the settings of the profile `name` in config.json")
+	mock := flag.Bool("mock", false, "offer the mock provider, which streams canned responses without a server")
+	mockSpeed := flag.Int("mock-speed", api.DefaultMockSpeed, "stream mock responses at `n` tokens per second, 0 for all at once")
+	mockScript := flag.String("mock-script", "", "answer with the mock provider from the scripted responses in the JSON lines `file`")
+	serveAddr := flag.String("serve", "", "answer OpenAI-style chat completions requests on `addr` with the selected model; :8080 listens on 127.0.0.1, other hosts need $OLLAMA_TUI_SERVE_TOKEN")
+	dotEnv := flag.Bool("dotenv", false, "also read OLLAMA_TUI_* variables from .env in the current directory")
+	host := flag.String("host", "", "connect to the Ollama server at `url`, or through ssh://user@host (default $OLLAMA_HOST or http://localhost:11434)")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(utils.GetBuildInfo())
+		return 0
+	}
+
+	// "ollama-tui doctor" checks the setup instead of starting the interface
+	doctor := flag.Arg(0) == "doctor"
+	if doctor {
+		_ = flag.CommandLine.Parse(flag.Args()[1:])
+	}
+
+	// Files kept in ~/.config/ollama-tui by older versions move to the
+	// platform's directories
+	if err := utils.MigrateLegacyDirs(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	// .env files fill in the environment before anything reads it. A file
+	// the app can't fully read is not a reason not to start.
+	for _, file := range utils.DotEnvFiles(*dotEnv) {
+		if err := utils.LoadDotEnv(file); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	if *record != "" && *replay != "" {
+		fmt.Println("Error: --record and --replay cannot be used together")
+		return 1
+	}
+
+	// The profile, which every other setting depends on, comes from the
+	// flag, the environment or the config file, or else is picked from a list
+	base, _ := utils.LoadEffectiveConfig()
+	profileName := *profile
+	if profileName == "" {
+		profileName = base.Profile
+	}
+	if profileName == "" && len(base.Profiles) > 0 && *replay == "" && !doctor {
+		picked, err := ui.PickProfile(base)
+		if err != nil {
+			fmt.Printf("Error picking a profile: %v\n", err)
+			return 1
+		}
+		if picked == "" {
+			return 0
+		}
+		profileName = picked
+	}
+	if profileName != "" {
+		if err := utils.ApplyProfile(&base, profileName); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		utils.ActiveProfile = profileName
+	}
+
+	// Connection settings from the config file, overridden by the
+	// environment and then by flags
+	config, _ := utils.LoadEffectiveConfig()
+	httpConfig := config.HTTP
+	if *proxy != "" {
+		httpConfig.Proxy = *proxy
+	}
+	if *caCert != "" {
+		httpConfig.CACert = *caCert
+	}
+	if *insecure {
+		httpConfig.InsecureSkipVerify = true
+	}
+	if *connectTimeout != "" {
+		httpConfig.ConnectTimeout = *connectTimeout
+	}
+	if *readTimeout != "" {
+		httpConfig.ReadTimeout = *readTimeout
+	}
+	transport, err := api.NewTransport(api.TransportOptions{
+		ConnectTimeout:      httpConfig.ConnectTimeoutDuration(),
+		ReadTimeout:         httpConfig.ReadTimeoutDuration(),
+		IdleConnTimeout:     httpConfig.IdleConnTimeoutDuration(),
+		MaxIdleConnsPerHost: httpConfig.MaxIdleConnsPerHost,
+		DisableKeepAlives:   httpConfig.DisableKeepAlives,
+		Proxy:               httpConfig.Proxy,
+		CACert:              httpConfig.CACert,
+		InsecureSkipVerify:  httpConfig.InsecureSkipVerify,
+	})
+	if err != nil {
+		fmt.Printf("Error configuring connections: %v\n", err)
+		return 1
+	}
+	hosts := make(map[string]api.HostAuth, len(config.Hosts))
+	for name, h := range config.Hosts {
+		hosts[name] = api.HostAuth{BearerToken: h.BearerToken, Username: h.Username, Password: h.Password, Headers: h.Headers}
+		// Headers sent to a host are credentials, kept out of recordings
+		for header := range h.Headers {
+			api.AddSecretHeaders(header)
+		}
+	}
+	api.DefaultTransport = api.WithHostAuth(transport, hosts)
+	mcp.HTTPTransport = api.DefaultTransport
+
+	// The Ollama server, from the flag, the environment or the config file.
+	// OLLAMA_TUI_OLLAMA_HOST wins over Ollama's own variable.
+	ollamaHost := *host
+	if ollamaHost == "" {
+		ollamaHost = os.Getenv(utils.EnvPrefix + "OLLAMA_HOST")
+	}
+	if ollamaHost == "" {
+		ollamaHost = os.Getenv("OLLAMA_HOST")
+	}
+	if ollamaHost == "" {
+		ollamaHost = config.OllamaHost
+	}
+	var sshTunnel *tunnel.Tunnel
+	switch {
+	case tunnel.IsSSH(ollamaHost) && *replay == "":
+		// Forward a local port to the remote machine before the interface
+		// takes over the terminal, so ssh can ask for a password
+		fmt.Printf("Connecting to %s…\n", ollamaHost)
+		sshTunnel, err = tunnel.Open(ollamaHost)
+		if err != nil {
+			fmt.Printf("Error opening ssh tunnel: %v\n", err)
+			return 1
+		}
+		defer sshTunnel.Close()
+		api.OllamaURL = "http://" + sshTunnel.LocalAddr
+	case ollamaHost != "" && !tunnel.IsSSH(ollamaHost):
+		api.OllamaURL = api.NormalizeOllamaURL(ollamaHost)
+	}
+	ui.OllamaHostFromConfig = *host == "" && ollamaHost == config.OllamaHost && !tunnel.IsSSH(ollamaHost)
+	if logDir, err := utils.GetLogDir(); err == nil {
+		api.LogDir = logDir
+	}
+	ui.ApplyProviderConfig(config)
+	api.DefaultSystemPrompt = config.SystemPrompt
+
+	// Capture or play back provider traffic for reproducible bug reports
+	if *record != "" {
+		recorder, err := api.NewRecordingTransport(*record)
+		if err != nil {
+			fmt.Printf("Error initializing recorder: %v\n", err)
+			return 1
+		}
+		defer recorder.Close()
+		api.DefaultTransport = recorder
+	}
+	if *replay != "" {
+		replayer, err := api.NewReplayTransport(*replay)
+		if err != nil {
+			fmt.Printf("Error loading fixture: %v\n", err)
+			return 1
+		}
+		api.DefaultTransport = replayer
+	}
+
+	// The mock provider stands in for a model when working on the interface
+	if *mockScript != "" {
+		*mock = true
+		script, err := api.LoadMockScript(*mockScript)
+		if err != nil {
+			fmt.Printf("Error loading mock script: %v\n", err)
+			return 1
+		}
+		api.MockScript = script
+	}
+	api.MockEnabled = *mock
+	api.MockSpeed = max(*mockSpeed, 0)
+
+	if doctor {
+		results := ui.RunDiagnostics(context.Background())
+		ui.WriteDiagnostics(os.Stdout, results)
+		if ui.DiagnosticsFailed(results) {
+			return 1
+		}
+		return 0
+	}
+
+	model := ui.NewModel()
+	if *accessible && !model.Accessible {
+		model.EnableAccessibleMode()
+	}
+	if *modelName != "" && *provider == "" {
+		*provider = "ollama"
+	}
+	switch {
+	case *provider != "":
+		if !model.StartWithModel(*provider, *modelName) {
+			fmt.Printf("Cannot start with provider %q, starting with provider selection\n", *provider)
+		}
+	case *last:
+		if !model.ResumeLastModel() {
+			fmt.Println("No previously used model found, starting with provider selection")
+		}
+	}
+
+	// Use the full terminal screen and enable mouse support, or print the
+	// conversation into the terminal's own scrollback in accessible mode
+	options := []tea.ProgramOption{
 		tea.WithAltScreen(),       // Use the alternate screen buffer
 		tea.WithMouseCellMotion(), // Enable mouse support
-	)
+	}
+	if model.Accessible {
+		options = []tea.ProgramOption{tea.WithFilter(ui.AccessibleFilter)}
+	}
+	// Know when the window is in the background to announce responses
+	options = append(options, tea.WithReportFocus())
+	// A panic quits through the guard, which restores the terminal and keeps
+	// the stack trace for a crash report
+	options = append(options, tea.WithoutCatchPanics())
+	guard := ui.NewPanicGuard(model)
+	p := tea.NewProgram(guard, options...)
+	// Responses stream their tokens to the program as they arrive
+	ui.Program = p
+
+	// Other tools can share the selected model and the session history
+	// through the local API server
+	if *serveAddr != "" {
+		server, err := ui.StartServer(*serveAddr, os.Getenv(utils.EnvPrefix+"SERVE_TOKEN"))
+		if err != nil {
+			fmt.Printf("Error starting the API server: %v\n", err)
+			return 1
+		}
+		defer server.Close()
+	}
 
 	// Run the program
-	if _, err := p.Run(); err != nil {
+	final, err := p.Run()
+
+	// Stop MCP servers started by the application and any voice recording
+	if g, ok := final.(ui.PanicGuard); ok {
+		final = g.Model
+	}
+	if m, ok := final.(ui.Model); ok {
+		if m.MCP != nil {
+			m.MCP.Close()
+		}
+		if m.Recorder != nil {
+			m.Recorder.Cancel()
+		}
+	}
+
+	// The recovery file is kept after a crash, for /recover on the next start
+	if crash := guard.Crash(); crash != nil {
+		fmt.Printf("ollama-tui crashed: %v\n", crash.Value)
+		if path, err := utils.WriteCrashReport(crash.Value, crash.Stack); err != nil {
+			fmt.Printf("Failed to write the crash report: %v\n\n%s", err, crash.Stack)
+		} else {
+			fmt.Printf("A crash report with the stack trace was written to %s\n", path)
+		}
+		fmt.Println("Your conversation and draft can be restored with /recover on the next start.")
+		return 2
+	}
+	if err != nil {
 		fmt.Printf("Error initializing application: %v\n", err)
-		os.Exit(1)
+		return 1
+	}
+	// Nothing is left to recover after a clean exit
+	if err := session.ClearRecovery(); err != nil {
+		fmt.Printf("Failed to remove the recovery file: %v\n", err)
 	}
+	return 0
 }
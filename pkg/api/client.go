@@ -5,11 +5,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -19,302 +23,513 @@ import (
 const (
 	DefaultOllamaURL = "http://localhost:11434"
 	DefaultOpenAIURL = "https://api.openai.com/v1"
+	// DefaultLMStudioURL is where LM Studio serves its OpenAI-compatible API
+	DefaultLMStudioURL = "http://localhost:1234/v1"
 )
 
+var (
+	// OllamaURL is the Ollama server that new clients connect to
+	OllamaURL = DefaultOllamaURL
+	// OpenAIURL is the OpenAI API, or a server compatible with it, that new
+	// clients of the openai provider connect to
+	OpenAIURL = DefaultOpenAIURL
+	// MistralURL is Mistral's platform, which clients of the mistral
+	// provider connect to
+	MistralURL = "https://api.mistral.ai/v1"
+	// DeepSeekURL is DeepSeek's API, which clients of the deepseek provider
+	// connect to
+	DeepSeekURL = "https://api.deepseek.com"
+	// DefaultSystemPrompt is the system prompt new clients start with
+	DefaultSystemPrompt string
+	// LogDir is where the debug logs of requests are written; none are
+	// written when it is empty
+	LogDir string
+)
+
+// NormalizeOllamaURL turns a host given like OLLAMA_HOST, such as
+// "example.com", "10.0.0.2:11434" or "https://ollama.example.com/", into a
+// base URL. Hosts without a scheme use Ollama's default port.
+func NormalizeOllamaURL(host string) string {
+	host = strings.TrimRight(strings.TrimSpace(host), "/")
+	if host == "" {
+		return DefaultOllamaURL
+	}
+	if !strings.Contains(host, "://") {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host += ":11434"
+		}
+		host = "http://" + host
+	}
+	return host
+}
+
 type Client struct {
 	BaseURL string
 	APIKey  string
 	client  *http.Client
+	memory  *memory
+	// openAI is set for the OpenAI API and servers compatible with it
+	openAI bool
+	// provider is the provider the client was created for. Mistral and
+	// DeepSeek speak the OpenAI API with a few extensions.
+	provider string
+	// plugin is the executable of a provider plugin
+	plugin *Plugin
+
+	// SystemPrompt is sent with every request when set
+	SystemPrompt string
+	// Memories are facts about the user added to the system prompt
+	Memories []string
+	// Temperature overrides the provider's default sampling temperature when set
+	Temperature *float64
+	// Stop ends responses where one of these sequences appears, and Seed
+	// makes sampling repeatable
+	Stop []string
+	Seed *int
+	// Options are passed through as is: into Ollama's options object (such
+	// as num_ctx or mirostat), or into the body of OpenAI-compatible requests
+	Options map[string]interface{}
+	// KeepAlive controls how long Ollama keeps the model loaded after a
+	// request (e.g. "5m", "0" to unload immediately, "-1" to keep it loaded)
+	KeepAlive string
+	// Tools are offered to models that support function calling, and run
+	// by ToolHandler when the model calls them
+	Tools       []models.Tool
+	ToolHandler ToolHandler
+	// Format constrains responses to JSON: the string "json" for any valid
+	// JSON, or a JSON Schema object the response must follow
+	Format json.RawMessage
+	// Images are base64-encoded images sent with the prompt to vision models
+	Images []string
+	// SafePrompt asks Mistral to prepend its safety system prompt
+	SafePrompt bool
+	// RecordRequests sends a RequestEvent with each request as it is sent,
+	// so it can be replayed
+	RecordRequests bool
+}
+
+// memory is the conversation a client remembers. Responses update it as
+// they finish in the background, so it is shared by the copies a client
+// streams with and guarded by mu.
+type memory struct {
+	mu      sync.Mutex
 	context []int
 
-	// OpenAI conversation history
-	openAIMessages []models.ChatMessage
+	// Message-based conversation history, used by OpenAI and by Ollama once
+	// the conversation has been handed over from another model
+	messages []models.ChatMessage
 }
 
 func NewClient(provider string, apiKey string) *Client {
-	var baseURL string
+	baseURL, openAI := OllamaURL, true
 	switch provider {
 	case "openai":
-		baseURL = DefaultOpenAIURL
-	case "ollama":
-		baseURL = DefaultOllamaURL
+		baseURL = OpenAIURL
+	case "mistral":
+		baseURL = MistralURL
+	case "deepseek":
+		baseURL = DeepSeekURL
+	case "huggingface":
+		baseURL = HuggingFaceURL
 	default:
-		baseURL = DefaultOllamaURL
+		if preset, ok := Presets[provider]; ok {
+			baseURL = preset.URL
+		} else {
+			openAI = false
+		}
 	}
 
-	return &Client{
-		BaseURL:        baseURL,
-		APIKey:         apiKey,
-		client:         &http.Client{},
-		openAIMessages: []models.ChatMessage{},
+	client := &Client{
+		BaseURL:      baseURL,
+		APIKey:       apiKey,
+		SystemPrompt: DefaultSystemPrompt,
+		client:       &http.Client{Transport: DefaultTransport},
+		memory:       &memory{},
+		openAI:       openAI,
+		provider:     provider,
+	}
+	if plugin, ok := Plugins[provider]; ok && !openAI {
+		client.plugin = &plugin
 	}
+	return client
 }
 
 func (c *Client) FetchModels() ([]models.Model, error) {
-	// Create a log file
-	logFile, err := os.OpenFile("openai_api.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if c.provider == "huggingface" {
+		return fetchHuggingFaceModels(), nil
+	}
+	if c.provider == MockProvider {
+		return fetchMockModels(), nil
+	}
+	if c.plugin != nil {
+		return c.fetchPluginModels(*c.plugin)
+	}
+	if c.openAI {
+		return c.fetchOpenAIModels()
+	}
+
+	// For Ollama, use the existing implementation
+	resp, err := c.client.Get(c.BaseURL + "/api/tags")
 	if err != nil {
-		return getHardcodedOpenAIModels(), nil
+		return nil, fmt.Errorf("failed to fetch models: %w", err)
 	}
-	defer logFile.Close()
+	defer resp.Body.Close()
 
-	logger := log.New(logFile, "", log.LstdFlags)
+	// A reverse proxy rejecting the credentials answers with an HTML page
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch models from %s: %s", c.BaseURL, resp.Status)
+	}
 
-	if c.BaseURL == DefaultOpenAIURL {
-		logger.Println("Fetching OpenAI models from API...")
+	var modelList models.ModelListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&modelList); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
 
-		// Create a request to the OpenAI API
-		req, err := http.NewRequest("GET", c.BaseURL+"/models", nil)
-		if err != nil {
-			logger.Printf("Error creating request: %v\n", err)
-			return getHardcodedOpenAIModels(), nil
+	return modelList.Models, nil
+}
+
+// fetchOpenAIModels lists the chat models of OpenAI or a compatible server
+// that match the allowlist, with the context windows of known models
+func (c *Client) fetchOpenAIModels() ([]models.Model, error) {
+	// LM Studio describes its models in more detail in its own API, which
+	// older versions lack
+	if c.provider == "lmstudio" {
+		if list, err := c.fetchLMStudioModels(); err == nil {
+			return list, nil
 		}
+	}
+	if preset, ok := Presets[c.provider]; ok && len(preset.Models) > 0 {
+		result := make([]models.Model, 0, len(preset.Models))
+		for _, id := range preset.Models {
+			model := openAIModel(id)
+			model.Details.Family = preset.Name
+			result = append(result, model)
+		}
+		return result, nil
+	}
 
-		// Add the API key to the request header
+	req, err := http.NewRequest("GET", c.BaseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.APIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+c.APIKey)
-		req.Header.Set("Content-Type", "application/json")
+	}
 
-		// Log a masked version of the API key for debugging
-		maskedKey := "****"
-		if len(c.APIKey) > 4 {
-			maskedKey = c.APIKey[:4] + "..." + c.APIKey[len(c.APIKey)-4:]
-		}
-		logger.Printf("Sending request to %s with API key: %s (length: %d)\n",
-			c.BaseURL+"/models", maskedKey, len(c.APIKey))
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch models: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, fmt.Errorf("%s rejected the API key: %s", c.BaseURL, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to fetch models from %s: %s", c.BaseURL, resp.Status)
+	}
 
-		// Send the request
-		resp, err := c.client.Do(req)
-		if err != nil {
-			logger.Printf("Error sending request: %v\n", err)
-			return getHardcodedOpenAIModels(), nil
+	list, err := decodeModelList(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+	result := make([]models.Model, 0, len(list))
+	for _, m := range list {
+		// The allowlist is OpenAI's
+		if !chatCapable(m) || (c.provider == "openai" && !allowedModel(m.ID)) {
+			continue
+		}
+		model := openAIModel(m.ID)
+		switch {
+		case m.MaxContextLength > 0:
+			model.Details.Context = m.MaxContextLength
+		case m.ContextLength > 0:
+			model.Details.Context = m.ContextLength
+		}
+		if c.provider == "mistral" {
+			model.Details.Family = "Mistral"
+		}
+		if preset, ok := Presets[c.provider]; ok {
+			model.Details.Family = preset.Name
 		}
-		defer resp.Body.Close()
+		result = append(result, model)
+	}
+	if len(result) == 0 && len(list) > 0 {
+		return nil, fmt.Errorf("none of the %d models of %s are chat models matching openai_models", len(list), c.BaseURL)
+	}
+	return result, nil
+}
 
-		logger.Printf("Response status code: %d\n", resp.StatusCode)
+// keepAliveValue converts a keep_alive setting to the value Ollama expects:
+// plain numbers are sent as seconds, anything else as a duration string
+func keepAliveValue(keepAlive string) interface{} {
+	if keepAlive == "" {
+		return nil
+	}
+	if seconds, err := strconv.Atoi(keepAlive); err == nil {
+		return seconds
+	}
+	return keepAlive
+}
 
-		// Check for error status codes
-		if resp.StatusCode != http.StatusOK {
-			// Read the response body to get error details
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				logger.Printf("Error reading error response body: %v\n", err)
-				return getHardcodedOpenAIModels(), nil
-			}
+// RunningModels returns the models Ollama currently has loaded in memory
+func (c *Client) RunningModels() ([]models.RunningModel, error) {
+	if c.provider == "lmstudio" {
+		return c.lmStudioRunningModels()
+	}
+	if c.openAI || c.plugin != nil || c.provider == MockProvider {
+		return nil, nil
+	}
 
-			logger.Printf("Error response body: %s\n", string(bodyBytes))
+	resp, err := c.client.Get(c.BaseURL + "/api/ps")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch running models: %w", err)
+	}
+	defer resp.Body.Close()
 
-			if resp.StatusCode == 401 {
-				logger.Println("Authentication error: The API key is invalid or missing.")
-				logger.Printf("API Key format check: starts with 'sk-'? %v, length > 20? %v\n",
-					strings.HasPrefix(c.APIKey, "sk-"), len(c.APIKey) > 20)
-			}
+	var running models.RunningModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&running); err != nil {
+		return nil, fmt.Errorf("failed to decode running models: %w", err)
+	}
 
-			return getHardcodedOpenAIModels(), nil
-		}
+	return running.Models, nil
+}
 
-		// Read the response body for debugging
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			logger.Printf("Error reading response body: %v\n", err)
-			return getHardcodedOpenAIModels(), nil
-		}
-
-		// Log the response body
-		logger.Printf("Response body: %s\n", string(bodyBytes))
-
-		// Create a new reader from the bytes for JSON decoding
-		respBodyReader := bytes.NewReader(bodyBytes)
-
-		// Decode the response
-		var openAIResp models.OpenAIModelResponse
-		if err := json.NewDecoder(respBodyReader).Decode(&openAIResp); err != nil {
-			logger.Printf("Error decoding response: %v\n", err)
-			return getHardcodedOpenAIModels(), nil
-		}
-
-		logger.Printf("Decoded %d models from API\n", len(openAIResp.Data))
-
-		// Define the allowed models
-		allowedModels := map[string]bool{
-			"gpt-4o-mini":     true,
-			"o3-mini":         true,
-			"gpt-4o":          true,
-			"o1":              true,
-			"gpt-4.5-preview": true,
-		}
-
-		// Convert OpenAI models to our internal model format, filtering for allowed models
-		result := make([]models.Model, 0)
-		for _, m := range openAIResp.Data {
-			logger.Printf("Processing model: %s\n", m.ID)
-
-			// Check if this model is in our allowed list
-			if allowedModels[m.ID] {
-				model := models.Model{
-					Name: m.ID,
-					Details: struct {
-						Family  string `json:"family"`
-						Format  string `json:"format"`
-						Context int    `json:"context"`
-					}{
-						Family:  "OpenAI",
-						Format:  "Chat",
-						Context: 4096, // Default context size
-					},
-				}
-				result = append(result, model)
-				logger.Printf("Added allowed model: %s\n", m.ID)
-			}
-		}
+// UnloadModel asks Ollama to release a model from memory right away
+func (c *Client) UnloadModel(model string) error {
+	reqBody, err := json.Marshal(models.GenerateRequest{Model: model, KeepAlive: 0})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
 
-		// Ensure we have at least some models
-		if len(result) == 0 {
-			logger.Println("No allowed models found in API response, using hardcoded filtered models")
-			return getFilteredHardcodedOpenAIModels(), nil
-		}
+	resp, err := c.client.Post(c.BaseURL+"/api/generate", "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to unload model: %w", err)
+	}
+	defer resp.Body.Close()
 
-		logger.Printf("Returning %d filtered models from API\n", len(result))
-		return result, nil
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Ollama API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
 	}
+	return nil
+}
 
-	// For Ollama, use the existing implementation
-	resp, err := c.client.Get(c.BaseURL + "/api/tags")
+// CopyModel duplicates a model under a new name
+func (c *Client) CopyModel(source, destination string) error {
+	reqBody, err := json.Marshal(models.CopyRequest{Source: source, Destination: destination})
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch models: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.client.Post(c.BaseURL+"/api/copy", "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to copy model: %w", err)
 	}
 	defer resp.Body.Close()
 
-	var modelList models.ModelListResponse
-	if err := json.NewDecoder(resp.Body).Decode(&modelList); err != nil {
-		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Ollama API returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
 	}
+	return nil
+}
 
-	return modelList.Models, nil
+// DeleteModel removes a model from the Ollama server
+func (c *Client) DeleteModel(model string) error {
+	reqBody, err := json.Marshal(models.DeleteRequest{Model: model, Name: model})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("DELETE", c.BaseURL+"/api/delete", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete model: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Ollama API returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+	}
+	return nil
+}
+
+// RenameModel renames a model by copying it and deleting the original, since
+// Ollama has no rename endpoint
+func (c *Client) RenameModel(source, destination string) error {
+	if err := c.CopyModel(source, destination); err != nil {
+		return err
+	}
+	return c.DeleteModel(source)
 }
 
-// getFilteredHardcodedOpenAIModels returns a filtered list of hardcoded OpenAI models
-func getFilteredHardcodedOpenAIModels() []models.Model {
-	return []models.Model{
-		{
-			Name: "gpt-4o-mini",
-			Details: struct {
-				Family  string `json:"family"`
-				Format  string `json:"format"`
-				Context int    `json:"context"`
-			}{
-				Family:  "GPT-4",
-				Format:  "Chat",
-				Context: 8192,
-			},
-		},
-		{
-			Name: "gpt-4o",
-			Details: struct {
-				Family  string `json:"family"`
-				Format  string `json:"format"`
-				Context int    `json:"context"`
-			}{
-				Family:  "GPT-4",
-				Format:  "Chat",
-				Context: 128000,
-			},
-		},
-		{
-			Name: "o1",
-			Details: struct {
-				Family  string `json:"family"`
-				Format  string `json:"format"`
-				Context int    `json:"context"`
-			}{
-				Family:  "OpenAI",
-				Format:  "Chat",
-				Context: 128000,
-			},
-		},
-		{
-			Name: "o3-mini",
-			Details: struct {
-				Family  string `json:"family"`
-				Format  string `json:"format"`
-				Context int    `json:"context"`
-			}{
-				Family:  "OpenAI",
-				Format:  "Chat",
-				Context: 128000,
-			},
-		},
-		{
-			Name: "gpt-4.5-preview",
-			Details: struct {
-				Family  string `json:"family"`
-				Format  string `json:"format"`
-				Context int    `json:"context"`
-			}{
-				Family:  "GPT-4.5",
-				Format:  "Chat",
-				Context: 128000,
-			},
-		},
-	}
-}
-
-// getHardcodedOpenAIModels returns a list of hardcoded OpenAI models
-func getHardcodedOpenAIModels() []models.Model {
-	return []models.Model{
-		{
-			Name: "gpt-3.5-turbo",
-			Details: struct {
-				Family  string `json:"family"`
-				Format  string `json:"format"`
-				Context int    `json:"context"`
-			}{
-				Family:  "GPT-3.5",
-				Format:  "Chat",
-				Context: 4096,
-			},
-		},
-		{
-			Name: "gpt-4",
-			Details: struct {
-				Family  string `json:"family"`
-				Format  string `json:"format"`
-				Context int    `json:"context"`
-			}{
-				Family:  "GPT-4",
-				Format:  "Chat",
-				Context: 8192,
-			},
-		},
-		{
-			Name: "gpt-4-turbo",
-			Details: struct {
-				Family  string `json:"family"`
-				Format  string `json:"format"`
-				Context int    `json:"context"`
-			}{
-				Family:  "GPT-4",
-				Format:  "Chat",
-				Context: 128000,
-			},
-		},
+// Detached returns a client for the same provider without any conversation
+// memory, for one-off requests that must not affect the chat
+func (c *Client) Detached() *Client {
+	return &Client{
+		BaseURL:    c.BaseURL,
+		APIKey:     c.APIKey,
+		client:     c.client,
+		memory:     &memory{},
+		openAI:     c.openAI,
+		provider:   c.provider,
+		plugin:     c.plugin,
+		KeepAlive:  c.KeepAlive,
+		SafePrompt: c.SafePrompt,
 	}
 }
 
+// ollamaOptions returns the options object of Ollama requests: the options
+// passed through with the temperature, stop sequences and seed set on top
+func (c *Client) ollamaOptions() map[string]interface{} {
+	options := make(map[string]interface{}, len(c.Options)+3)
+	for key, value := range c.Options {
+		options[key] = value
+	}
+	if c.Temperature != nil {
+		options["temperature"] = *c.Temperature
+	}
+	if len(c.Stop) > 0 {
+		options["stop"] = c.Stop
+	}
+	if c.Seed != nil {
+		options["seed"] = *c.Seed
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	return options
+}
+
+// NewConversation returns a copy of the client with the same settings,
+// such as the system prompt and tools, and no conversation memory yet
+func (c *Client) NewConversation() *Client {
+	client := *c
+	client.memory = &memory{}
+	client.Format = nil
+	client.Images = nil
+	return &client
+}
+
+// Complete sends a single prompt and returns the full response
+func (c *Client) Complete(ctx context.Context, model, prompt string) (string, error) {
+	var sb strings.Builder
+	var err error
+	for event := range c.Stream(ctx, model, prompt) {
+		switch event := event.(type) {
+		case TokenEvent:
+			sb.WriteString(event.Text)
+		case ErrorEvent:
+			err = event.Err
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return models.StripReasoning(sb.String()), err
+}
+
 // ClearContext clears the conversation context
 func (c *Client) ClearContext() {
-	c.context = nil
-	c.openAIMessages = nil
+	c.memory.mu.Lock()
+	defer c.memory.mu.Unlock()
+	c.memory.context = nil
+	c.memory.messages = nil
+}
+
+// ConversationState holds the provider-side conversation memory so it can be
+// saved and restored
+type ConversationState struct {
+	Context  []int
+	Messages []models.ChatMessage
+}
+
+// Conversation returns a copy of the current conversation memory
+func (c *Client) Conversation() ConversationState {
+	c.memory.mu.Lock()
+	defer c.memory.mu.Unlock()
+	return ConversationState{
+		Context:  append([]int(nil), c.memory.context...),
+		Messages: append([]models.ChatMessage(nil), c.memory.messages...),
+	}
+}
+
+// RestoreConversation replaces the conversation memory with a saved state
+func (c *Client) RestoreConversation(state ConversationState) {
+	c.memory.mu.Lock()
+	defer c.memory.mu.Unlock()
+	c.memory.context = append([]int(nil), state.Context...)
+	c.memory.messages = append([]models.ChatMessage(nil), state.Messages...)
+}
+
+// SwitchModel prepares the client to continue the conversation with a
+// different model. OpenAI and plugins keep their message history; Ollama's
+// model-specific token context is replaced by the transcript so the new model
+// receives it.
+func (c *Client) SwitchModel(history []models.ChatMessage) {
+	if c.openAI || c.plugin != nil {
+		return
+	}
+	c.SetHistory(history)
+}
+
+// SetHistory replaces the conversation memory with a message history
+func (c *Client) SetHistory(history []models.ChatMessage) {
+	c.memory.mu.Lock()
+	defer c.memory.mu.Unlock()
+	c.memory.context = nil
+	c.memory.messages = append([]models.ChatMessage(nil), history...)
+}
+
+// abortOnCancel closes body once ctx is cancelled, so that a read blocked on
+// a server that stopped sending returns at once instead of when the next line
+// arrives. Calling the returned function stops watching ctx.
+func abortOnCancel(ctx context.Context, body io.Closer) func() bool {
+	return context.AfterFunc(ctx, func() { body.Close() })
 }
 
 // HasContext returns true if the client has a conversation context
 func (c *Client) HasContext() bool {
-	return (c.context != nil && len(c.context) > 0) || (c.openAIMessages != nil && len(c.openAIMessages) > 0)
+	c.memory.mu.Lock()
+	defer c.memory.mu.Unlock()
+	return len(c.memory.context) > 0 || len(c.memory.messages) > 0
 }
 
-// GenerateResponse generates a response from a model
-func (c *Client) GenerateResponse(ctx context.Context, model, prompt string, callback func(string, bool)) error {
+// history returns a copy of the message history
+func (c *Client) history() []models.ChatMessage {
+	c.memory.mu.Lock()
+	defer c.memory.mu.Unlock()
+	return append([]models.ChatMessage(nil), c.memory.messages...)
+}
+
+// remember adds the messages of a finished turn to the history and returns
+// its new length
+func (c *Client) remember(turn []models.ChatMessage) int {
+	c.memory.mu.Lock()
+	defer c.memory.mu.Unlock()
+	c.memory.messages = append(c.memory.messages, turn...)
+	return len(c.memory.messages)
+}
+
+// openLog opens the debug log name in LogDir for appending, never in the
+// current directory
+func openLog(name string) (*os.File, error) {
+	if LogDir == "" {
+		return nil, errors.New("no log directory")
+	}
+	return os.OpenFile(filepath.Join(LogDir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+}
+
+// generate generates a response from a model, streaming it to out
+func (c *Client) generate(ctx context.Context, model, prompt string, out *sink) error {
 	// Create a log file for debugging
-	logFile, err := os.OpenFile("api_response.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	logFile, err := openLog("api_response.log")
 	if err == nil {
 		defer logFile.Close()
 		logger := log.New(logFile, "", log.LstdFlags)
@@ -322,25 +537,62 @@ func (c *Client) GenerateResponse(ctx context.Context, model, prompt string, cal
 		logger.Printf("Using provider: %s\n", c.BaseURL)
 	}
 
+	// Endpoints without the Messages API stream text-generation-inference's
+	// own format
+	if endpoint, ok := huggingFaceEndpoint(model); ok && c.provider == "huggingface" && endpoint.API == "generate" {
+		return c.generateTGIResponse(ctx, endpoint, prompt, out)
+	}
+
+	if c.plugin != nil {
+		return c.generatePluginResponse(ctx, *c.plugin, model, prompt, out)
+	}
+	if c.provider == MockProvider {
+		return c.generateMockResponse(ctx, model, prompt, out)
+	}
+
 	// Handle OpenAI API
-	if c.BaseURL == DefaultOpenAIURL {
-		return c.generateOpenAIResponse(ctx, model, prompt, callback)
+	if c.openAI {
+		return c.generateOpenAIResponse(ctx, model, prompt, out)
+	}
+
+	// Ollama's token context only works with the model that produced it, so
+	// conversations handed over from another model continue via the chat API,
+	// which is also the only one that supports tools
+	c.memory.mu.Lock()
+	chat := len(c.memory.messages) > 0
+	tokenContext := c.memory.context
+	c.memory.mu.Unlock()
+	if chat || len(c.activeTools()) > 0 {
+		return c.generateOllamaChatResponse(ctx, model, prompt, out)
 	}
 
 	// Handle Ollama API (existing implementation)
 	// Create the request with context if available
-	reqBody, err := json.Marshal(models.GenerateRequest{
+	genReq := models.GenerateRequest{
 		Model:   model,
 		Prompt:  prompt,
+		Images:  c.Images,
+		System:  c.systemPrompt(),
+		Format:  c.Format,
 		Stream:  true,
-		Context: c.context,
-	})
+		Context: tokenContext,
+	}
+	genReq.Options = c.ollamaOptions()
+	genReq.KeepAlive = keepAliveValue(c.KeepAlive)
+
+	reqBody, err := json.Marshal(genReq)
 
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
+	return c.postGenerate(ctx, c.BaseURL+"/api/generate", reqBody, out)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/generate", bytes.NewBuffer(reqBody))
+// postGenerate sends a request to Ollama's generate API and streams the
+// reply to out, keeping the token context it returns
+func (c *Client) postGenerate(ctx context.Context, url string, reqBody []byte, out *sink) error {
+	out.request(url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -351,65 +603,63 @@ func (c *Client) GenerateResponse(ctx context.Context, model, prompt string, cal
 		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
+	defer abortOnCancel(ctx, resp.Body)()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Ollama API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
 
-	var mu sync.Mutex
 	scanner := bufio.NewScanner(resp.Body)
 	const maxCapacity = 1024 * 1024
 	buf := make([]byte, maxCapacity)
 	scanner.Buffer(buf, maxCapacity)
 
 	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			callback("", true)
+		if ctx.Err() != nil {
 			return nil
-		default:
-			line := scanner.Text()
-			if line == "" {
-				continue
-			}
+		}
 
-			var genResp models.GenerateResponse
-			if err := json.Unmarshal([]byte(line), &genResp); err != nil {
-				continue
-			}
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
 
-			mu.Lock()
-			if genResp.Response != "" {
-				callback(genResp.Response, genResp.Done)
-			}
+		var genResp models.GenerateResponse
+		if err := json.Unmarshal([]byte(line), &genResp); err != nil {
+			continue
+		}
 
-			// Save the context for future requests
-			if genResp.Context != nil && len(genResp.Context) > 0 {
-				c.context = genResp.Context
-			}
+		out.token(genResp.Response)
 
-			if genResp.Done {
-				callback("", true)
-				mu.Unlock()
-				return nil
-			}
-			mu.Unlock()
+		// Save the context for future requests
+		if len(genResp.Context) > 0 {
+			c.memory.mu.Lock()
+			c.memory.context = genResp.Context
+			c.memory.mu.Unlock()
+		}
+
+		if genResp.Done {
+			out.finish(genResp.DoneReason)
+			out.usage(genResp.Model, genResp.CreatedAt, genResp.ResponseMetrics)
+			return nil
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
 		return fmt.Errorf("scanner error: %w", err)
 	}
-
-	callback("", true)
 	return nil
 }
 
 // generateOpenAIResponse generates a response using the OpenAI API
-func (c *Client) generateOpenAIResponse(ctx context.Context, model, prompt string, callback func(string, bool)) error {
+func (c *Client) generateOpenAIResponse(ctx context.Context, model, prompt string, out *sink) error {
 	// Create a log file for debugging
-	logFile, err := os.OpenFile("openai_chat.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	logFile, err := openLog("openai_chat.log")
 	if err == nil {
 		defer logFile.Close()
 		logger := log.New(logFile, "", log.LstdFlags)
 		logger.Printf("Generating OpenAI response for model: %s, prompt: %s\n", model, prompt)
-		logger.Printf("Conversation history: %d messages\n", len(c.openAIMessages))
 	}
 
 	// Create a logger function for convenience
@@ -420,61 +670,134 @@ func (c *Client) generateOpenAIResponse(ctx context.Context, model, prompt strin
 		}
 	}
 
-	// Create messages array
+	// Create messages array, starting with the system prompt if one is set
 	var messages []models.ChatMessage
+	if system := c.systemPrompt(); system != "" {
+		messages = append(messages, models.ChatMessage{
+			Role:    "system",
+			Content: system,
+		})
+	}
 
 	// If we have conversation history, use it
-	if c.openAIMessages != nil && len(c.openAIMessages) > 0 {
-		messages = append(messages, c.openAIMessages...)
-	}
+	history := c.history()
+	logMessage("Conversation history: %d messages", len(history))
+	messages = append(messages, history...)
 
 	// Add the new user message
 	userMessage := models.ChatMessage{
 		Role:    "user",
 		Content: prompt,
+		Images:  c.Images,
 	}
 	messages = append(messages, userMessage)
 
 	// Create the request
+	temperature := 0.7
+	if c.Temperature != nil {
+		temperature = *c.Temperature
+	}
 	chatReq := models.OpenAIChatRequest{
-		Model:       model,
-		Messages:    messages,
-		Stream:      true,
-		Temperature: 0.7,
+		Model:          model,
+		Stream:         true,
+		Temperature:    &temperature,
+		Tools:          c.activeTools(),
+		ResponseFormat: openAIResponseFormat(c.Format),
+		SafePrompt:     c.provider == "mistral" && c.SafePrompt,
+		Stop:           c.Stop,
+		Seed:           c.Seed,
+		Extra:          c.Options,
 	}
 
+	// Messages added during this turn: the prompt, any tool calls and their
+	// results, and the final answer
+	turn := []models.ChatMessage{userMessage}
+	for round := 0; ; round++ {
+		chatReq.Messages = append(append([]models.ChatMessage(nil), messages...), turn[1:]...)
+
+		content, calls, err := c.streamOpenAIChat(ctx, chatReq, out, logMessage)
+		if ctx.Err() != nil {
+			logMessage("Context cancelled")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Reasoning is not sent back to the model with the history
+		content = models.StripReasoning(content)
+		if len(calls) == 0 || round == MaxToolRounds {
+			if content != "" {
+				turn = append(turn, models.ChatMessage{Role: "assistant", Content: content})
+			}
+			break
+		}
+		turn = append(turn, models.ChatMessage{Role: "assistant", Content: content, ToolCalls: calls})
+		turn = append(turn, c.runTools(ctx, calls, out)...)
+	}
+
+	// Add the exchange to the conversation history
+	if len(turn) > 1 {
+		logMessage("Added conversation history. Total messages: %d", c.remember(turn))
+	} else {
+		logMessage("No assistant response received")
+	}
+	return nil
+}
+
+// streamOpenAIChat sends one chat completions request and streams the reply
+// to out, returning its content and any tool calls it requested
+func (c *Client) streamOpenAIChat(ctx context.Context, chatReq models.OpenAIChatRequest, out *sink, logMessage func(string, ...interface{})) (string, []models.ToolCall, error) {
+	chatCompletionsURL, model, apiKey := c.chatTarget(chatReq.Model)
+	chatReq.Model = model
+
 	// Marshal the request to JSON
 	reqBody, err := json.Marshal(chatReq)
 	if err != nil {
 		logMessage("Error marshaling request: %v", err)
-		return fmt.Errorf("failed to marshal OpenAI request: %w", err)
+		return "", nil, fmt.Errorf("failed to marshal OpenAI request: %w", err)
 	}
 
+	return c.postOpenAIChat(ctx, chatCompletionsURL, apiKey, reqBody, out, logMessage)
+}
+
+// postOpenAIChat sends a chat completions request and streams the reply to
+// out, returning its content and any tool calls it requested
+func (c *Client) postOpenAIChat(ctx context.Context, chatCompletionsURL, apiKey string, reqBody []byte, out *sink, logMessage func(string, ...interface{})) (string, []models.ToolCall, error) {
+	out.request(chatCompletionsURL, reqBody)
 	logMessage("Request body: %s", string(reqBody))
 
-	// Create the HTTP request - Fix the URL by using the correct path
-	chatCompletionsURL := c.BaseURL + "/chat/completions"
 	logMessage("Using URL: %s", chatCompletionsURL)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", chatCompletionsURL, bytes.NewBuffer(reqBody))
-	if err != nil {
-		logMessage("Error creating request: %v", err)
-		return fmt.Errorf("failed to create OpenAI request: %w", err)
-	}
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", chatCompletionsURL, bytes.NewBuffer(reqBody))
+		if err != nil {
+			logMessage("Error creating request: %v", err)
+			return nil, fmt.Errorf("failed to create OpenAI request: %w", err)
+		}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+		// Set headers
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
 
-	logMessage("Sending request to %s with API key length: %d", chatCompletionsURL, len(c.APIKey))
+		logMessage("Sending request to %s with API key length: %d", chatCompletionsURL, len(apiKey))
+		return req, nil
+	}
 
-	// Send the request
-	resp, err := c.client.Do(req)
+	// Send the request, waiting out rate limits
+	resp, err := c.doRateLimited(ctx, out, newRequest)
 	if err != nil {
 		logMessage("Error sending request: %v", err)
-		return fmt.Errorf("failed to send OpenAI request: %w", err)
+		var rateLimited *RateLimitError
+		if errors.As(err, &rateLimited) || ctx.Err() != nil {
+			return "", nil, err
+		}
+		return "", nil, fmt.Errorf("failed to send OpenAI request: %w", err)
 	}
 	defer resp.Body.Close()
+	defer abortOnCancel(ctx, resp.Body)()
 
 	logMessage("Response status code: %d", resp.StatusCode)
 
@@ -482,127 +805,265 @@ func (c *Client) generateOpenAIResponse(ctx context.Context, model, prompt strin
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		logMessage("Error response body: %s", string(bodyBytes))
-		return fmt.Errorf("OpenAI API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
+		return "", nil, fmt.Errorf("OpenAI API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	// Process the streaming response
 	reader := bufio.NewReader(resp.Body)
 
-	// Store the assistant's response
+	// Store the assistant's response and any tool calls, which arrive in
+	// fragments keyed by their index
 	var assistantResponse strings.Builder
+	var calls []models.ToolCall
+	var arguments []string
+	// Services that search the web, like Perplexity, list the pages the
+	// answer cites with every chunk
+	var citations []string
+	// Reasoning streamed separately, as DeepSeek's reasoning_content, is
+	// wrapped in think tags like the reasoning of models that write it
+	// into their answer
+	reasoning := false
+	emit := func(text string) {
+		assistantResponse.WriteString(text)
+		out.token(text)
+	}
+	result := func() (string, []models.ToolCall, error) {
+		if reasoning {
+			emit(models.ReasoningEnd)
+		}
+		if len(citations) > 0 {
+			out.send(CitationsEvent{URLs: citations})
+		}
+		for i := range calls {
+			calls[i].Function.Arguments, _ = json.Marshal(arguments[i])
+		}
+		return assistantResponse.String(), calls, nil
+	}
 
 	logMessage("Starting to read response stream")
 
 	for {
-		select {
-		case <-ctx.Done():
-			logMessage("Context cancelled")
-			callback("", true)
-			return nil
-		default:
-			// Read a line from the response
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err == io.EOF {
-					logMessage("End of response stream (EOF)")
-					// Add the assistant's message to the conversation history
-					if assistantResponse.Len() > 0 {
-						c.openAIMessages = append(c.openAIMessages, userMessage)
-						c.openAIMessages = append(c.openAIMessages, models.ChatMessage{
-							Role:    "assistant",
-							Content: assistantResponse.String(),
-						})
-						logMessage("Added conversation history. Total messages: %d", len(c.openAIMessages))
-					} else {
-						logMessage("No assistant response received")
-					}
-					callback("", true)
-					return nil
-				}
-				logMessage("Error reading response: %v", err)
-				return fmt.Errorf("error reading OpenAI response: %w", err)
+		if ctx.Err() != nil {
+			return "", nil, ctx.Err()
+		}
+
+		// Read a line from the response
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				logMessage("End of response stream (EOF)")
+				return result()
+			}
+			if ctx.Err() != nil {
+				return "", nil, ctx.Err()
 			}
+			logMessage("Error reading response: %v", err)
+			return "", nil, fmt.Errorf("error reading OpenAI response: %w", err)
+		}
 
-			logMessage("Received line: %s", line)
+		logMessage("Received line: %s", line)
 
-			// Skip empty lines and "data: [DONE]"
-			line = strings.TrimSpace(line)
-			if line == "" {
-				logMessage("Empty line, skipping")
-				continue
-			}
+		// Skip empty lines and "data: [DONE]"
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if line == "data: [DONE]" {
+			logMessage("Received DONE signal")
+			return result()
+		}
 
-			if line == "data: [DONE]" {
-				logMessage("Received DONE signal")
-				// If we're done, add the messages to the conversation history
-				if assistantResponse.Len() > 0 {
-					c.openAIMessages = append(c.openAIMessages, userMessage)
-					c.openAIMessages = append(c.openAIMessages, models.ChatMessage{
-						Role:    "assistant",
-						Content: assistantResponse.String(),
-					})
-					logMessage("Added conversation history. Total messages: %d", len(c.openAIMessages))
-				} else {
-					logMessage("No assistant response received at DONE signal")
-				}
-				callback("", true)
-				return nil
+		// Remove "data: " prefix
+		if !strings.HasPrefix(line, "data: ") {
+			logMessage("Line doesn't have data prefix, skipping: %s", line)
+			continue
+		}
+		line = strings.TrimPrefix(line, "data: ")
+
+		// Parse the JSON
+		var streamResp models.OpenAIChatStreamResponse
+		if err := json.Unmarshal([]byte(line), &streamResp); err != nil {
+			logMessage("Error parsing JSON: %v, line: %s", err, line)
+			continue
+		}
+
+		if len(streamResp.Citations) > 0 {
+			citations = streamResp.Citations
+		}
+
+		if len(streamResp.Choices) == 0 {
+			logMessage("No choices in response")
+			continue
+		}
+		choice := streamResp.Choices[0]
+
+		if choice.Delta.ReasoningContent != "" {
+			if !reasoning {
+				reasoning = true
+				emit(models.ReasoningStart)
 			}
+			emit(choice.Delta.ReasoningContent)
+		}
 
-			// Remove "data: " prefix
-			if strings.HasPrefix(line, "data: ") {
-				line = strings.TrimPrefix(line, "data: ")
-				logMessage("Trimmed data prefix: %s", line)
-			} else {
-				logMessage("Line doesn't have data prefix, skipping: %s", line)
-				continue
+		// Send the content
+		if choice.Delta.Content != "" {
+			if reasoning {
+				reasoning = false
+				emit(models.ReasoningEnd + "\n\n")
 			}
+			emit(choice.Delta.Content)
+		}
 
-			// Parse the JSON
-			var streamResp models.OpenAIChatStreamResponse
-			if err := json.Unmarshal([]byte(line), &streamResp); err != nil {
-				logMessage("Error parsing JSON: %v, line: %s", err, line)
-				continue
+		for _, delta := range choice.Delta.ToolCalls {
+			for len(calls) <= delta.Index {
+				calls = append(calls, models.ToolCall{Type: "function"})
+				arguments = append(arguments, "")
 			}
+			if delta.ID != "" {
+				calls[delta.Index].ID = delta.ID
+			}
+			if delta.Function.Name != "" {
+				calls[delta.Index].Function.Name = delta.Function.Name
+			}
+			arguments[delta.Index] += delta.Function.Arguments
+		}
+
+		// Check if this is the end of the response
+		if choice.FinishReason != nil {
+			logMessage("Finish reason: %v", *choice.FinishReason)
+			out.finish(*choice.FinishReason)
+			return result()
+		}
+	}
+}
+
+// generateOllamaChatResponse generates a response using Ollama's chat API,
+// sending the message history instead of a token context
+func (c *Client) generateOllamaChatResponse(ctx context.Context, model, prompt string, out *sink) error {
+	var messages []models.ChatMessage
+	if system := c.systemPrompt(); system != "" {
+		messages = append(messages, models.ChatMessage{Role: "system", Content: system})
+	}
+	messages = append(messages, c.history()...)
+
+	chatReq := models.ChatRequest{
+		Model:  model,
+		Stream: true,
+		Tools:  c.activeTools(),
+		Format: c.Format,
+	}
+	chatReq.Options = c.ollamaOptions()
+	chatReq.KeepAlive = keepAliveValue(c.KeepAlive)
 
-			logMessage("Parsed stream response: %+v", streamResp)
-
-			// Process the choices
-			if len(streamResp.Choices) > 0 {
-				choice := streamResp.Choices[0]
-				logMessage("Processing choice: %+v", choice)
-
-				// Check if this is the end of the response
-				if choice.FinishReason != nil {
-					logMessage("Finish reason: %v", *choice.FinishReason)
-					// Add the assistant's message to the conversation history
-					if assistantResponse.Len() > 0 {
-						c.openAIMessages = append(c.openAIMessages, userMessage)
-						c.openAIMessages = append(c.openAIMessages, models.ChatMessage{
-							Role:    "assistant",
-							Content: assistantResponse.String(),
-						})
-						logMessage("Added conversation history. Total messages: %d", len(c.openAIMessages))
-					} else {
-						logMessage("No assistant response received at finish")
-					}
-					callback("", true)
-					return nil
-				}
-
-				// Send the content
-				if choice.Delta.Content != "" {
-					logMessage("Delta content: %s", choice.Delta.Content)
-					assistantResponse.WriteString(choice.Delta.Content)
-					callback(choice.Delta.Content, false)
-				} else if choice.Delta.Role != "" {
-					logMessage("Delta role: %s", choice.Delta.Role)
-				} else {
-					logMessage("Empty delta")
-				}
-			} else {
-				logMessage("No choices in response")
+	// Messages added during this turn: the prompt, any tool calls and their
+	// results, and the final answer
+	turn := []models.ChatMessage{{Role: "user", Content: prompt, Images: c.Images}}
+	for round := 0; ; round++ {
+		chatReq.Messages = append(append([]models.ChatMessage(nil), messages...), turn...)
+
+		content, calls, err := c.streamOllamaChat(ctx, chatReq, out)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && len(chatReq.Tools) > 0 && strings.Contains(err.Error(), "does not support tools") {
+			// Models without function calling still answer, just without tools
+			chatReq.Tools = nil
+			content, calls, err = c.streamOllamaChat(ctx, chatReq, out)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Reasoning is not sent back to the model with the history
+		content = models.StripReasoning(content)
+		if len(calls) == 0 || round == MaxToolRounds {
+			if content != "" {
+				turn = append(turn, models.ChatMessage{Role: "assistant", Content: content})
 			}
+			break
 		}
+		turn = append(turn, models.ChatMessage{Role: "assistant", Content: content, ToolCalls: calls})
+		turn = append(turn, c.runTools(ctx, calls, out)...)
+	}
+
+	// Add the exchange to the conversation history
+	if len(turn) > 1 {
+		c.remember(turn)
+	}
+	return nil
+}
+
+// streamOllamaChat sends one chat request and streams the reply to out,
+// returning its content and any tool calls it requested
+func (c *Client) streamOllamaChat(ctx context.Context, chatReq models.ChatRequest, out *sink) (string, []models.ToolCall, error) {
+	reqBody, err := json.Marshal(chatReq)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal chat request: %w", err)
+	}
+	return c.postOllamaChat(ctx, c.BaseURL+"/api/chat", reqBody, out)
+}
+
+// postOllamaChat sends a request to Ollama's chat API and streams the reply
+// to out, returning its content and any tool calls it requested
+func (c *Client) postOllamaChat(ctx context.Context, url string, reqBody []byte, out *sink) (string, []models.ToolCall, error) {
+	out.request(url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	defer abortOnCancel(ctx, resp.Body)()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", nil, fmt.Errorf("Ollama API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var assistantResponse strings.Builder
+	var calls []models.ToolCall
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return "", nil, ctx.Err()
+		}
+
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		var chatResp models.ChatResponse
+		if err := json.Unmarshal([]byte(line), &chatResp); err != nil {
+			continue
+		}
+
+		if chatResp.Message.Content != "" {
+			assistantResponse.WriteString(chatResp.Message.Content)
+			out.token(chatResp.Message.Content)
+		}
+		calls = append(calls, chatResp.Message.ToolCalls...)
+
+		if chatResp.Done {
+			out.finish(chatResp.DoneReason)
+			out.usage(chatResp.Model, chatResp.CreatedAt, chatResp.ResponseMetrics)
+			break
+		}
+	}
+
+	if ctx.Err() != nil {
+		return "", nil, ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, fmt.Errorf("scanner error: %w", err)
 	}
+	return assistantResponse.String(), calls, nil
 }
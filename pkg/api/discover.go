@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// DefaultDiscoveryPorts are where servers listen by default: Ollama (11434),
+// LM Studio (1234), llama.cpp (8080) and vLLM (8000)
+var DefaultDiscoveryPorts = []int{11434, 1234, 8080, 8000}
+
+const (
+	// probeTimeout limits each probe, so unreachable addresses don't hold
+	// up discovery
+	probeTimeout = 1500 * time.Millisecond
+	// probeDialTimeout limits connecting to an address, most of which have
+	// nothing listening
+	probeDialTimeout = 500 * time.Millisecond
+	// maxProbes is how many addresses are probed at once
+	maxProbes = 64
+)
+
+// probeTransport sends the probes straight to the addresses probed rather
+// than through DefaultTransport: a configured proxy would be probed instead
+// of the local network, and be told its addresses
+var probeTransport http.RoundTripper = &http.Transport{
+	Proxy:                 nil,
+	DialContext:           (&net.Dialer{Timeout: probeDialTimeout}).DialContext,
+	ResponseHeaderTimeout: probeTimeout,
+	DisableKeepAlives:     true,
+}
+
+// Endpoint is a server found by Discover
+type Endpoint struct {
+	// Provider is "ollama", or "openai" for OpenAI-compatible servers
+	Provider string
+	// URL is the base URL clients of the provider use
+	URL string
+	// Version is the Ollama version, empty for other servers
+	Version string
+	// Models is how many models the server offers
+	Models int
+}
+
+// DiscoveryTargets lists the host:port addresses to probe: each host on
+// every port unless it names its own, and with scanLAN every address of the
+// machine's local IPv4 networks, up to a /24 each
+func DiscoveryTargets(hosts []string, ports []int, scanLAN bool) []string {
+	if len(ports) == 0 {
+		ports = DefaultDiscoveryPorts
+	}
+	if len(hosts) == 0 {
+		hosts = []string{"localhost"}
+	}
+	if scanLAN {
+		hosts = append(hosts, lanHosts()...)
+	}
+
+	seen := make(map[string]bool)
+	var targets []string
+	add := func(target string) {
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	for _, host := range hosts {
+		if _, _, err := net.SplitHostPort(host); err == nil {
+			add(host)
+			continue
+		}
+		for _, port := range ports {
+			add(net.JoinHostPort(host, strconv.Itoa(port)))
+		}
+	}
+	return targets
+}
+
+// lanHosts returns the other addresses of the /24 around each of the
+// machine's private IPv4 addresses
+func lanHosts() []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var hosts []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP.To4()
+		if ip == nil || !ip.IsPrivate() {
+			continue
+		}
+		for i := 1; i < 255; i++ {
+			if byte(i) != ip[3] {
+				hosts = append(hosts, net.IPv4(ip[0], ip[1], ip[2], byte(i)).String())
+			}
+		}
+	}
+	return hosts
+}
+
+// Discover probes each host:port target for an Ollama or OpenAI-compatible
+// server and returns those found, in the order of the targets
+func Discover(ctx context.Context, targets []string) []Endpoint {
+	client := &http.Client{Transport: probeTransport, Timeout: probeTimeout}
+	found := make([]*Endpoint, len(targets))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxProbes)
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+			found[i] = probe(ctx, client, target)
+		}()
+	}
+	wg.Wait()
+
+	var endpoints []Endpoint
+	for _, endpoint := range found {
+		if endpoint != nil {
+			endpoints = append(endpoints, *endpoint)
+		}
+	}
+	return endpoints
+}
+
+// probe asks the server at target for Ollama's version, then for an OpenAI
+// model list, and returns what it found or nil
+func probe(ctx context.Context, client *http.Client, target string) *Endpoint {
+	base := "http://" + target
+
+	var version struct {
+		Version string `json:"version"`
+	}
+	if getJSON(ctx, client, base+"/api/version", &version) == nil && version.Version != "" {
+		var tags models.ModelListResponse
+		getJSON(ctx, client, base+"/api/tags", &tags)
+		return &Endpoint{Provider: "ollama", URL: base, Version: version.Version, Models: len(tags.Models)}
+	}
+
+	var list models.OpenAIModelResponse
+	if getJSON(ctx, client, base+"/v1/models", &list) == nil && list.Data != nil {
+		return &Endpoint{Provider: "openai", URL: base + "/v1", Models: len(list.Data)}
+	}
+	return nil
+}
+
+// OllamaVersion asks the Ollama server new clients connect to for its
+// version
+func OllamaVersion(ctx context.Context) (string, error) {
+	var version struct {
+		Version string `json:"version"`
+	}
+	client := &http.Client{Transport: DefaultTransport}
+	if err := getJSON(ctx, client, OllamaURL+"/api/version", &version); err != nil {
+		return "", err
+	}
+	return version.Version, nil
+}
+
+// getJSON decodes the JSON response to a GET request into v
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
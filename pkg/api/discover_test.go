@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDiscoverBypassesProxy(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			w.Write([]byte(`{"version":"0.5.1"}`))
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"llama3"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ollama.Close()
+
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		http.Error(w, "proxy", http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+
+	transport, err := NewTransport(TransportOptions{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultTransport := DefaultTransport
+	DefaultTransport = transport
+	defer func() { DefaultTransport = defaultTransport }()
+
+	target := strings.TrimPrefix(ollama.URL, "http://")
+	endpoints := Discover(context.Background(), []string{target})
+	if len(endpoints) != 1 || endpoints[0].Provider != "ollama" || endpoints[0].Models != 1 {
+		t.Errorf("Discover(%s) = %+v, want the Ollama server with one model", target, endpoints)
+	}
+	if n := proxied.Load(); n > 0 {
+		t.Errorf("%d probes went through the configured proxy", n)
+	}
+}
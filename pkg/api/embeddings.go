@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+const (
+	// DefaultOllamaEmbeddingModel is used for embeddings when none is configured
+	DefaultOllamaEmbeddingModel = "nomic-embed-text"
+	// DefaultOpenAIEmbeddingModel is used for embeddings when none is configured
+	DefaultOpenAIEmbeddingModel = "text-embedding-3-small"
+	// DefaultMistralEmbeddingModel is used for embeddings on Mistral
+	DefaultMistralEmbeddingModel = "mistral-embed"
+)
+
+// DefaultEmbeddingModel returns the embedding model used for the client's provider
+func (c *Client) DefaultEmbeddingModel() string {
+	if c.provider == "mistral" {
+		return DefaultMistralEmbeddingModel
+	}
+	if c.openAI {
+		return DefaultOpenAIEmbeddingModel
+	}
+	return DefaultOllamaEmbeddingModel
+}
+
+// Embeddings returns one embedding vector per input text, in order
+func (c *Client) Embeddings(ctx context.Context, model string, input []string) ([][]float64, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+
+	var body interface{} = models.EmbedRequest{Model: model, Input: input, KeepAlive: keepAliveValue(c.KeepAlive)}
+	url := c.BaseURL + "/api/embed"
+	if c.openAI {
+		body = models.OpenAIEmbeddingRequest{Model: model, Input: input}
+		url = c.BaseURL + "/embeddings"
+	}
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.openAI {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("embeddings API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var embeddings [][]float64
+	if c.openAI {
+		var result models.OpenAIEmbeddingResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode embeddings: %w", err)
+		}
+		embeddings = make([][]float64, len(input))
+		for _, d := range result.Data {
+			if d.Index >= 0 && d.Index < len(embeddings) {
+				embeddings[d.Index] = d.Embedding
+			}
+		}
+	} else {
+		var result models.EmbedResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode embeddings: %w", err)
+		}
+		embeddings = result.Embeddings
+	}
+
+	if len(embeddings) != len(input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(input), len(embeddings))
+	}
+	return embeddings, nil
+}
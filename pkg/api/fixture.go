@@ -0,0 +1,359 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTransport is used by every client created with NewClient. It can be
+// replaced to record or replay provider traffic.
+var DefaultTransport http.RoundTripper = http.DefaultTransport
+
+// FixtureChunk is a piece of a response body and the delay before it arrived
+type FixtureChunk struct {
+	DelayMs int64  `json:"delay_ms"`
+	Data    string `json:"data"`
+}
+
+// FixtureExchange is a recorded request and its streamed response
+type FixtureExchange struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Headers are the request's headers, with credentials redacted
+	Headers     map[string]string `json:"headers,omitempty"`
+	RequestBody string            `json:"request_body,omitempty"`
+	Status      int               `json:"status"`
+	ContentType string            `json:"content_type,omitempty"`
+	Chunks      []FixtureChunk    `json:"chunks"`
+}
+
+// secretPattern matches API keys and credentials that must not end up in
+// fixture files or the inspector: the key formats of the providers, and
+// bearer and basic credentials however they are written
+var secretPattern = regexp.MustCompile(`((?:sk-|hf_|pplx-|fw_|gsk_|xai-)|(?i:bearer|basic)\s+)[A-Za-z0-9_\-.+/=]{8,}`)
+
+// secretHeaders are the request headers whose values are always redacted,
+// keyed by their canonical name
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+	"Api-Key":             true,
+}
+
+// secretHeaderWords mark a header as carrying a credential when its name
+// contains one of them
+var secretHeaderWords = []string{"key", "token", "secret", "auth", "password", "cookie"}
+
+// secretQueryParams are the URL query parameters whose values are redacted
+var secretQueryParams = []string{"key", "api_key", "apikey", "access_token", "token", "secret"}
+
+// redacted replaces a secret that is dropped entirely
+const redacted = "REDACTED"
+
+var (
+	secretsMu sync.RWMutex
+	// configuredSecretHeaders are header names set up in the config, such as
+	// the headers sent to a host, whose values are redacted whatever they are
+	configuredSecretHeaders = map[string]bool{}
+)
+
+// AddSecretHeaders marks the headers named as carrying credentials, so that
+// their values are redacted from fixtures and the inspector
+func AddSecretHeaders(names ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, name := range names {
+		configuredSecretHeaders[http.CanonicalHeaderKey(name)] = true
+	}
+}
+
+// isSecretHeader reports whether the value of the header name is a credential
+func isSecretHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	if secretHeaders[name] {
+		return true
+	}
+	secretsMu.RLock()
+	configured := configuredSecretHeaders[name]
+	secretsMu.RUnlock()
+	if configured {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, word := range secretHeaderWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizeFixtureData redacts API keys from recorded data
+func SanitizeFixtureData(data string) string {
+	return secretPattern.ReplaceAllString(data, "${1}"+redacted)
+}
+
+// SanitizeHeaders returns the headers with one value per name, redacting
+// those that carry credentials. The scheme of an Authorization header is
+// kept, since it helps when debugging.
+func SanitizeHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	sanitized := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if isSecretHeader(name) {
+			scheme, _, found := strings.Cut(value, " ")
+			if found && (strings.EqualFold(scheme, "Bearer") || strings.EqualFold(scheme, "Basic")) {
+				value = scheme + " " + redacted
+			} else {
+				value = redacted
+			}
+		} else {
+			value = SanitizeFixtureData(value)
+		}
+		sanitized[http.CanonicalHeaderKey(name)] = value
+	}
+	return sanitized
+}
+
+// SanitizeURL returns u as a string with the credentials in its user info
+// and in its known query parameters redacted
+func SanitizeURL(u *url.URL) string {
+	clean := *u
+	if clean.User != nil {
+		clean.User = url.User(redacted)
+	}
+	if clean.RawQuery != "" {
+		query := clean.Query()
+		for name := range query {
+			for _, secret := range secretQueryParams {
+				if strings.EqualFold(name, secret) {
+					query.Set(name, redacted)
+				}
+			}
+		}
+		clean.RawQuery = query.Encode()
+	}
+	return SanitizeFixtureData(clean.String())
+}
+
+// SortedHeaderNames returns the names of headers in order, to show them
+// the same way every time
+func SortedHeaderNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RecordingTransport captures every exchange into a JSONL fixture file
+type RecordingTransport struct {
+	Base http.RoundTripper
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecordingTransport creates a transport that appends exchanges to path,
+// sending requests through DefaultTransport
+func NewRecordingTransport(path string) (*RecordingTransport, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fixture file: %w", err)
+	}
+	return &RecordingTransport{Base: DefaultTransport, file: file}, nil
+}
+
+// RoundTrip sends the request and wraps the response body so that every
+// chunk read from it is recorded with its timing
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	exchange := FixtureExchange{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Headers: SanitizeHeaders(req.Header),
+	}
+
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		exchange.RequestBody = SanitizeFixtureData(string(body))
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	exchange.Status = resp.StatusCode
+	exchange.ContentType = resp.Header.Get("Content-Type")
+	resp.Body = &recordingBody{
+		body:      resp.Body,
+		transport: t,
+		exchange:  exchange,
+		last:      time.Now(),
+	}
+	return resp, nil
+}
+
+// Close flushes and closes the fixture file
+func (t *RecordingTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.file.Close()
+}
+
+func (t *RecordingTransport) write(exchange FixtureExchange) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := json.Marshal(exchange)
+	if err != nil {
+		return
+	}
+	t.file.Write(append(data, '\n'))
+}
+
+// recordingBody may be closed while a read is in progress, when a request is
+// cancelled, so mu guards the recorded exchange
+type recordingBody struct {
+	body      io.ReadCloser
+	transport *RecordingTransport
+
+	mu       sync.Mutex
+	exchange FixtureExchange
+	last     time.Time
+	closed   bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		now := time.Now()
+		b.exchange.Chunks = append(b.exchange.Chunks, FixtureChunk{
+			DelayMs: now.Sub(b.last).Milliseconds(),
+			Data:    SanitizeFixtureData(string(p[:n])),
+		})
+		b.last = now
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		b.transport.write(b.exchange)
+	}
+	return b.body.Close()
+}
+
+// ReplayTransport serves responses from a fixture file instead of the network
+type ReplayTransport struct {
+	mu        sync.Mutex
+	exchanges []FixtureExchange
+	used      []bool
+}
+
+// NewReplayTransport loads the exchanges recorded in path
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixture file: %w", err)
+	}
+	defer file.Close()
+
+	var exchanges []FixtureExchange
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var exchange FixtureExchange
+		if err := json.Unmarshal(scanner.Bytes(), &exchange); err != nil {
+			return nil, fmt.Errorf("invalid fixture entry: %w", err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read fixture file: %w", err)
+	}
+
+	return &ReplayTransport{exchanges: exchanges, used: make([]bool, len(exchanges))}, nil
+}
+
+// RoundTrip returns the next unused recorded exchange for the request's
+// method and path, streaming its chunks with the recorded delays
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	t.mu.Lock()
+	var exchange *FixtureExchange
+	for i := range t.exchanges {
+		if !t.used[i] && t.exchanges[i].Method == req.Method && t.exchanges[i].Path == req.URL.Path {
+			t.used[i] = true
+			exchange = &t.exchanges[i]
+			break
+		}
+	}
+	t.mu.Unlock()
+
+	if exchange == nil {
+		return nil, fmt.Errorf("no recorded response left for %s %s", req.Method, req.URL.Path)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		for _, chunk := range exchange.Chunks {
+			select {
+			case <-req.Context().Done():
+				pw.CloseWithError(req.Context().Err())
+				return
+			case <-time.After(time.Duration(chunk.DelayMs) * time.Millisecond):
+			}
+			if _, err := pw.Write([]byte(chunk.Data)); err != nil {
+				return
+			}
+		}
+		pw.Close()
+	}()
+
+	header := http.Header{}
+	if exchange.ContentType != "" {
+		header.Set("Content-Type", exchange.ContentType)
+	}
+
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", exchange.Status, http.StatusText(exchange.Status)),
+		StatusCode: exchange.Status,
+		Header:     header,
+		Body:       pr,
+		Request:    req,
+	}, nil
+}
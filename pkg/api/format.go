@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// jsonInstruction is added to the system prompt in JSON mode. Models follow
+// the format more reliably when asked, and OpenAI requires JSON to be
+// mentioned in the messages.
+const jsonInstruction = "Respond only with valid JSON, without any text before or after it."
+
+// memoriesIntro introduces the user's memories in the system prompt
+const memoriesIntro = "The user asked you to remember these facts about them:"
+
+// IsJSONFormat reports whether format asks for plain JSON rather than a schema
+func IsJSONFormat(format json.RawMessage) bool {
+	return strings.TrimSpace(string(format)) == `"json"`
+}
+
+// systemPrompt returns the system prompt, with the user's memories and the
+// JSON instruction when a response format is set
+func (c *Client) systemPrompt() string {
+	var parts []string
+	if c.SystemPrompt != "" {
+		parts = append(parts, c.SystemPrompt)
+	}
+	if len(c.Memories) > 0 {
+		parts = append(parts, memoriesIntro+"\n- "+strings.Join(c.Memories, "\n- "))
+	}
+	if len(c.Format) > 0 {
+		instruction := jsonInstruction
+		if !IsJSONFormat(c.Format) {
+			instruction += " Follow this JSON Schema: " + string(c.Format)
+		}
+		parts = append(parts, instruction)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// openAIResponseFormat converts a response format to OpenAI's response_format
+func openAIResponseFormat(format json.RawMessage) *models.OpenAIResponseFormat {
+	if len(format) == 0 {
+		return nil
+	}
+	if IsJSONFormat(format) {
+		return &models.OpenAIResponseFormat{Type: "json_object"}
+	}
+	return &models.OpenAIResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &models.OpenAIJSONSchema{
+			Name:   "response",
+			Schema: format,
+		},
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// HuggingFaceURL is the Inference API router, which serves the models of
+// many providers through the OpenAI API
+const HuggingFaceURL = "https://router.huggingface.co/v1"
+
+// HuggingFaceEndpoint is a model served by Hugging Face: a dedicated
+// Inference Endpoint, a text-generation-inference server or a model of the
+// Inference API
+type HuggingFaceEndpoint struct {
+	// Name is how the model is listed
+	Name string
+	// URL is the endpoint, or HuggingFaceURL for the Inference API
+	URL string
+	// Model is the model id sent to the Inference API; dedicated endpoints
+	// serve a single model and ignore it
+	Model string
+	// API is "chat" for the OpenAI-style Messages API, or "generate" for
+	// text-generation-inference's own /generate_stream
+	API string
+	// Token replaces the client's token for this endpoint
+	Token string
+}
+
+// HuggingFaceEndpoints are the models the huggingface provider lists
+var HuggingFaceEndpoints []HuggingFaceEndpoint
+
+// huggingFaceEndpoint returns the configured endpoint listed as name
+func huggingFaceEndpoint(name string) (HuggingFaceEndpoint, bool) {
+	for _, endpoint := range HuggingFaceEndpoints {
+		if endpoint.Name == name {
+			return endpoint, true
+		}
+	}
+	return HuggingFaceEndpoint{}, false
+}
+
+// fetchHuggingFaceModels lists the configured endpoints
+func fetchHuggingFaceModels() []models.Model {
+	result := make([]models.Model, 0, len(HuggingFaceEndpoints))
+	for _, endpoint := range HuggingFaceEndpoints {
+		format := "Chat"
+		if endpoint.API == "generate" {
+			format = "Text"
+		}
+		result = append(result, models.Model{
+			Name:    endpoint.Name,
+			Details: models.ModelDetails{Family: "Hugging Face", Format: format},
+		})
+	}
+	return result
+}
+
+// chatTarget returns the URL, model id and token of a chat completions
+// request for model
+func (c *Client) chatTarget(model string) (url, id, token string) {
+	endpoint, ok := huggingFaceEndpoint(model)
+	if c.provider != "huggingface" || !ok {
+		return c.BaseURL + "/chat/completions", model, c.APIKey
+	}
+
+	url = strings.TrimRight(endpoint.URL, "/")
+	if !strings.HasSuffix(url, "/v1") {
+		url += "/v1"
+	}
+	id = endpoint.Model
+	if id == "" {
+		// text-generation-inference ignores the model of its Messages API
+		id = "tgi"
+	}
+	token = c.APIKey
+	if endpoint.Token != "" {
+		token = endpoint.Token
+	}
+	return url + "/chat/completions", id, token
+}
+
+// tgiRequest is a request to text-generation-inference's /generate_stream
+type tgiRequest struct {
+	Inputs     string        `json:"inputs"`
+	Parameters tgiParameters `json:"parameters"`
+}
+
+// tgiParameters are the generation parameters of a tgiRequest
+type tgiParameters struct {
+	MaxNewTokens   int      `json:"max_new_tokens"`
+	Temperature    *float64 `json:"temperature,omitempty"`
+	Stop           []string `json:"stop,omitempty"`
+	ReturnFullText bool     `json:"return_full_text"`
+}
+
+// tgiStreamResponse is an event of text-generation-inference's stream
+type tgiStreamResponse struct {
+	Token struct {
+		Text    string `json:"text"`
+		Special bool   `json:"special"`
+	} `json:"token"`
+	Details *struct {
+		FinishReason    string `json:"finish_reason"`
+		GeneratedTokens int    `json:"generated_tokens"`
+	} `json:"details"`
+	Error string `json:"error"`
+}
+
+// tgiMaxNewTokens limits the length of answers from /generate_stream,
+// which otherwise stops after a few tokens
+const tgiMaxNewTokens = 2048
+
+// tgiPrompt writes the conversation as a plain transcript for servers
+// without a chat template
+func tgiPrompt(system string, history []models.ChatMessage, prompt string) string {
+	var sb strings.Builder
+	if system != "" {
+		sb.WriteString(system + "\n\n")
+	}
+	for _, msg := range history {
+		switch msg.Role {
+		case "user":
+			sb.WriteString("User: " + msg.Content + "\n")
+		case "assistant":
+			sb.WriteString("Assistant: " + msg.Content + "\n")
+		}
+	}
+	sb.WriteString("User: " + prompt + "\nAssistant:")
+	return sb.String()
+}
+
+// generateTGIResponse streams an answer from text-generation-inference's
+// /generate_stream, for endpoints that don't offer the Messages API
+func (c *Client) generateTGIResponse(ctx context.Context, endpoint HuggingFaceEndpoint, prompt string, out *sink) error {
+	genReq := tgiRequest{
+		Inputs: tgiPrompt(c.systemPrompt(), c.history(), prompt),
+		Parameters: tgiParameters{
+			MaxNewTokens: tgiMaxNewTokens,
+			Stop:         append([]string{"\nUser:"}, c.Stop...),
+		},
+	}
+	// text-generation-inference rejects a temperature of 0
+	if c.Temperature != nil && *c.Temperature > 0 {
+		genReq.Parameters.Temperature = c.Temperature
+	}
+	reqBody, err := json.Marshal(genReq)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := strings.TrimRight(endpoint.URL, "/") + "/generate_stream"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	token := c.APIKey
+	if endpoint.Token != "" {
+		token = endpoint.Token
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	defer abortOnCancel(ctx, resp.Body)()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s returned status code %d: %s", url, resp.StatusCode, string(bodyBytes))
+	}
+
+	var answer strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+
+		var event tgiStreamResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		if event.Error != "" {
+			return fmt.Errorf("%s: %s", endpoint.Name, event.Error)
+		}
+		if !event.Token.Special {
+			answer.WriteString(event.Token.Text)
+			out.token(event.Token.Text)
+		}
+		if event.Details != nil {
+			out.finish(event.Details.FinishReason)
+			out.usage(endpoint.Name, time.Now().Format(time.RFC3339Nano), models.ResponseMetrics{EvalCount: event.Details.GeneratedTokens})
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+
+	c.remember([]models.ChatMessage{
+		{Role: "user", Content: prompt},
+		{Role: "assistant", Content: strings.TrimSpace(models.StripReasoning(answer.String()))},
+	})
+	return nil
+}
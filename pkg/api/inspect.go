@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxInspectedBytes limits how much of a response body is kept for the
+// inspector, so long answers don't hold on to a lot of memory
+const maxInspectedBytes = 256 * 1024
+
+// Exchange is a request made for a response, as it was sent, and the raw
+// body the provider streamed back, with API keys redacted
+type Exchange struct {
+	Method string
+	URL    string
+	// Headers are the request's headers, with credentials redacted
+	Headers     map[string]string
+	RequestBody string
+	SentAt      time.Time
+	// Status is the response status, such as "200 OK", and Err why no
+	// response came
+	Status string
+	Err    string
+	Chunks []InspectedChunk
+	// Truncated is set when the body went on past maxInspectedBytes
+	Truncated bool
+}
+
+// InspectedChunk is a piece of a response body as it was read, with when it
+// arrived after the request was sent
+type InspectedChunk struct {
+	After time.Duration
+	Data  string
+}
+
+// InspectEvent carries the HTTP exchanges of a response, sent before it
+// ends, for providers reached over HTTP
+type InspectEvent struct {
+	Exchanges []Exchange
+}
+
+func (InspectEvent) event() {}
+
+// inspectTransport keeps the exchanges of one response as they happen
+type inspectTransport struct {
+	base http.RoundTripper
+
+	mu        sync.Mutex
+	exchanges []*Exchange
+	size      int
+}
+
+// newInspectTransport wraps base, or DefaultTransport when it is nil
+func newInspectTransport(base http.RoundTripper) *inspectTransport {
+	if base == nil {
+		base = DefaultTransport
+	}
+	return &inspectTransport{base: base}
+}
+
+// RoundTrip sends the request, keeping its body and wrapping the response
+// body so every chunk read from it is kept
+func (t *inspectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	exchange := &Exchange{
+		Method:  req.Method,
+		URL:     SanitizeURL(req.URL),
+		Headers: SanitizeHeaders(req.Header),
+		SentAt:  time.Now(),
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		exchange.RequestBody = SanitizeFixtureData(string(body))
+	}
+	t.mu.Lock()
+	t.exchanges = append(t.exchanges, exchange)
+	t.mu.Unlock()
+
+	resp, err := t.base.RoundTrip(req)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		exchange.Err = err.Error()
+		return nil, err
+	}
+	exchange.Status = resp.Status
+	resp.Body = &inspectBody{ReadCloser: resp.Body, transport: t, exchange: exchange}
+	return resp, nil
+}
+
+// Exchanges returns a copy of the exchanges so far
+func (t *inspectTransport) Exchanges() []Exchange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	exchanges := make([]Exchange, len(t.exchanges))
+	for i, exchange := range t.exchanges {
+		exchanges[i] = *exchange
+		exchanges[i].Chunks = append([]InspectedChunk(nil), exchange.Chunks...)
+	}
+	return exchanges
+}
+
+// inspectBody keeps what is read from a response body
+type inspectBody struct {
+	io.ReadCloser
+	transport *inspectTransport
+	exchange  *Exchange
+}
+
+func (b *inspectBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		t := b.transport
+		t.mu.Lock()
+		if t.size+n > maxInspectedBytes {
+			b.exchange.Truncated = true
+		} else {
+			t.size += n
+			b.exchange.Chunks = append(b.exchange.Chunks, InspectedChunk{
+				After: time.Since(b.exchange.SentAt),
+				Data:  SanitizeFixtureData(string(p[:n])),
+			})
+		}
+		t.mu.Unlock()
+	}
+	return n, err
+}
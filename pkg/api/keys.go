@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// huggingFaceWhoAmIURL answers with the account of a Hugging Face token
+const huggingFaceWhoAmIURL = "https://huggingface.co/api/whoami-v2"
+
+// CheckKey checks the client's API key with a request that costs nothing:
+// listing the models, or asking Hugging Face whose token it is. Perplexity
+// has no such endpoint, so it is sent an empty chat request, which it only
+// refuses for a bad request once the key was accepted.
+func (c *Client) CheckKey(ctx context.Context) error {
+	method, url, body := "GET", c.BaseURL+"/models", ""
+	switch c.provider {
+	case "huggingface":
+		url = huggingFaceWhoAmIURL
+	case "perplexity":
+		method, url, body = "POST", c.BaseURL+"/chat/completions", "{}"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		if msg := errorMessage(respBody); msg != "" {
+			return fmt.Errorf("the API key was rejected: %s", msg)
+		}
+		return fmt.Errorf("the API key was rejected: %s", resp.Status)
+	case resp.StatusCode < 300:
+		return nil
+	case body != "" && resp.StatusCode < 500:
+		return nil
+	}
+	if msg := errorMessage(respBody); msg != "" {
+		return fmt.Errorf("%s: %s: %s", url, resp.Status, msg)
+	}
+	return fmt.Errorf("%s: %s", url, resp.Status)
+}
+
+// errorMessage returns the message of a provider's JSON error response,
+// which is {"error": {"message": …}}, {"error": "…"}, {"message": …} or
+// {"detail": …} depending on the provider
+func errorMessage(body []byte) string {
+	var resp struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+		Detail  string          `json:"detail"`
+	}
+	if json.Unmarshal(body, &resp) != nil {
+		return ""
+	}
+	var nested struct {
+		Message string `json:"message"`
+	}
+	var plain string
+	switch {
+	case json.Unmarshal(resp.Error, &nested) == nil && nested.Message != "":
+		return nested.Message
+	case json.Unmarshal(resp.Error, &plain) == nil && plain != "":
+		return plain
+	case resp.Message != "":
+		return resp.Message
+	}
+	return resp.Detail
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// lmStudioModel is a model as LM Studio's own REST API describes it
+type lmStudioModel struct {
+	ID               string `json:"id"`
+	Type             string `json:"type"`
+	Arch             string `json:"arch"`
+	Quantization     string `json:"quantization"`
+	State            string `json:"state"`
+	MaxContextLength int    `json:"max_context_length"`
+}
+
+// LMStudioStatus is what DetectLMStudio found out about the LM Studio
+// server
+type LMStudioStatus struct {
+	// Running is set when the server answered
+	Running bool
+	// Models is how many chat models it offers
+	Models int
+	// Loaded are the models it has in memory
+	Loaded []string
+}
+
+// lmStudioRoot returns the server of an LM Studio base URL, whose REST API
+// lives next to the OpenAI-compatible one
+func lmStudioRoot(baseURL string) string {
+	return strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
+}
+
+// lmStudioModels lists the models LM Studio offers with their
+// architecture, quantization and whether they are loaded
+func lmStudioModels(ctx context.Context, client *http.Client, baseURL string) ([]lmStudioModel, error) {
+	var list struct {
+		Data []lmStudioModel `json:"data"`
+	}
+	if err := getJSON(ctx, client, lmStudioRoot(baseURL)+"/api/v0/models", &list); err != nil {
+		return nil, err
+	}
+	chat := list.Data[:0]
+	for _, m := range list.Data {
+		if m.Type != "embeddings" {
+			chat = append(chat, m)
+		}
+	}
+	return chat, nil
+}
+
+// fetchLMStudioModels lists LM Studio's chat models with their details
+func (c *Client) fetchLMStudioModels() ([]models.Model, error) {
+	list, err := lmStudioModels(context.Background(), c.client, c.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.Model, 0, len(list))
+	for _, m := range list {
+		result = append(result, models.Model{
+			Name: m.ID,
+			Details: models.ModelDetails{
+				Family:            m.Arch,
+				Format:            "Chat",
+				Context:           m.MaxContextLength,
+				QuantizationLevel: m.Quantization,
+			},
+		})
+	}
+	return result, nil
+}
+
+// lmStudioRunningModels returns the models LM Studio has loaded
+func (c *Client) lmStudioRunningModels() ([]models.RunningModel, error) {
+	list, err := lmStudioModels(context.Background(), c.client, c.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	var running []models.RunningModel
+	for _, m := range list {
+		if m.State == "loaded" {
+			running = append(running, models.RunningModel{Name: m.ID, Model: m.ID})
+		}
+	}
+	return running, nil
+}
+
+// DetectLMStudio checks whether LM Studio's server is running and which of
+// its models are loaded
+func DetectLMStudio(ctx context.Context) LMStudioStatus {
+	client := &http.Client{Transport: DefaultTransport, Timeout: 2 * time.Second}
+	list, err := lmStudioModels(ctx, client, Presets["lmstudio"].URL)
+	if err != nil {
+		return LMStudioStatus{}
+	}
+	status := LMStudioStatus{Running: true, Models: len(list)}
+	for _, m := range list {
+		if m.State == "loaded" {
+			status.Loaded = append(status.Loaded, m.ID)
+		}
+	}
+	return status
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// MockProvider streams canned responses without any server, for working on
+// the interface and for end-to-end tests. It is offered only when
+// MockEnabled is set, by the --mock flag.
+const MockProvider = "mock"
+
+// DefaultMockSpeed is how many tokens per second mock responses stream at
+// unless MockSpeed says otherwise
+const DefaultMockSpeed = 30
+
+var (
+	// MockEnabled offers the mock provider
+	MockEnabled bool
+	// MockSpeed is how many tokens per second mock responses stream at, or
+	// 0 to send them all at once
+	MockSpeed = DefaultMockSpeed
+	// MockScript are the responses the mock provider gives instead of its
+	// canned ones, loaded with LoadMockScript
+	MockScript []MockResponse
+)
+
+// MockResponse is a scripted response of the mock provider
+type MockResponse struct {
+	// Prompt makes this the response to every prompt containing it. Responses
+	// without one are given in turn to the prompts no other one matches.
+	Prompt   string `json:"prompt,omitempty"`
+	Response string `json:"response"`
+	// DoneReason is why the model says it stopped, such as "length"
+	DoneReason string `json:"done_reason,omitempty"`
+	// Error fails the response once its text has streamed
+	Error string `json:"error,omitempty"`
+	// DelayMs waits before the first token, like a model loading
+	DelayMs int `json:"delay_ms,omitempty"`
+}
+
+// mockModels are the models the mock provider lists. mock-echo repeats the
+// prompt, mock-markdown answers with a list, a table and a code block,
+// mock-reasoning thinks in <think> tags first and mock-long fills the screen.
+var mockModels = []string{"mock-echo", "mock-markdown", "mock-reasoning", "mock-long"}
+
+// mockMarkdown is the canned response of mock-markdown
+const mockMarkdown = "## Mock response\n\nThis answer comes from the **mock** provider; no model was run.\n\n" +
+	"- Headings, lists and *emphasis*\n- `inline code`\n- A table and a code block\n\n" +
+	"| Column | Value |\n|--------|-------|\n| speed  | %d tokens/s |\n| prompt | %d characters |\n\n" +
+	"```go\npackage main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello from the mock provider\")\n}\n```\n"
+
+// mockScript hands out the scripted responses without a prompt in turn
+var mockScript struct {
+	mu   sync.Mutex
+	next int
+}
+
+// LoadMockScript reads scripted responses from a file with one JSON object
+// per line. Blank lines and lines starting with # are skipped.
+func LoadMockScript(path string) ([]MockResponse, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var script []MockResponse
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var response MockResponse
+		if err := json.Unmarshal([]byte(text), &response); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		script = append(script, response)
+	}
+	return script, scanner.Err()
+}
+
+// fetchMockModels lists the models of the mock provider
+func fetchMockModels() []models.Model {
+	result := make([]models.Model, len(mockModels))
+	for i, name := range mockModels {
+		result[i] = models.Model{
+			Name:    name,
+			Details: models.ModelDetails{Family: "Mock", Format: "Chat", Context: 8192},
+		}
+	}
+	return result
+}
+
+// mockResponse picks the response to prompt: the scripted one matching it,
+// the next scripted one, or the canned response of model
+func mockResponse(model, prompt string) MockResponse {
+	mockScript.mu.Lock()
+	defer mockScript.mu.Unlock()
+
+	var unmatched []MockResponse
+	for _, response := range MockScript {
+		if response.Prompt == "" {
+			unmatched = append(unmatched, response)
+		} else if strings.Contains(strings.ToLower(prompt), strings.ToLower(response.Prompt)) {
+			return response
+		}
+	}
+	if len(unmatched) > 0 {
+		response := unmatched[mockScript.next%len(unmatched)]
+		mockScript.next++
+		return response
+	}
+
+	switch model {
+	case "mock-markdown":
+		return MockResponse{Response: fmt.Sprintf(mockMarkdown, MockSpeed, len(prompt))}
+	case "mock-reasoning":
+		return MockResponse{Response: "<think>\nThe user asked: " + prompt + "\nA mock has nothing to think about, so this is brief.\n</think>\n\nThe mock provider considered your prompt and answers: " + prompt}
+	case "mock-long":
+		var sb strings.Builder
+		for i := 1; i <= 40; i++ {
+			fmt.Fprintf(&sb, "%d. This is line %d of a long mock response, there to fill the screen so that scrolling can be tried.\n", i, i)
+		}
+		return MockResponse{Response: sb.String()}
+	}
+	return MockResponse{Response: prompt}
+}
+
+// mockTokenPattern splits a response into tokens: words with the spaces
+// after them
+var mockTokenPattern = regexp.MustCompile(`\S+\s*|\s+`)
+
+// generateMockResponse streams the mock response to prompt at MockSpeed,
+// reporting usage like a provider would
+func (c *Client) generateMockResponse(ctx context.Context, model, prompt string, out *sink) error {
+	response := mockResponse(model, prompt)
+	start := time.Now()
+
+	wait := func(d time.Duration) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(d):
+			return true
+		}
+	}
+	if response.DelayMs > 0 && !wait(time.Duration(response.DelayMs)*time.Millisecond) {
+		return nil
+	}
+	first := time.Now()
+
+	var interval time.Duration
+	if MockSpeed > 0 {
+		interval = time.Second / time.Duration(MockSpeed)
+	}
+	tokens := mockTokenPattern.FindAllString(response.Response, -1)
+	for i, token := range tokens {
+		if i > 0 && interval > 0 && !wait(interval) {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		out.token(token)
+	}
+	if response.Error != "" {
+		return errors.New(response.Error)
+	}
+
+	promptTokens := len(mockTokenPattern.FindAllString(c.systemPrompt(), -1)) + len(mockTokenPattern.FindAllString(prompt, -1))
+	for _, msg := range c.history() {
+		promptTokens += len(mockTokenPattern.FindAllString(msg.Content, -1))
+	}
+	out.usage(model, time.Now().Format(time.RFC3339Nano), models.ResponseMetrics{
+		TotalDuration:      time.Since(start),
+		PromptEvalCount:    promptTokens,
+		PromptEvalDuration: first.Sub(start),
+		EvalCount:          len(tokens),
+		EvalDuration:       time.Since(first),
+	})
+	out.finish(response.DoneReason)
+
+	c.remember([]models.ChatMessage{
+		{Role: "user", Content: prompt},
+		{Role: "assistant", Content: strings.TrimSpace(models.StripReasoning(response.Response))},
+	})
+	return nil
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// ParseModelfile converts a Modelfile into a create request for the named
+// model. FROM, SYSTEM, TEMPLATE and PARAMETER instructions are supported;
+// values may span several lines when wrapped in triple quotes.
+func ParseModelfile(name, modelfile string) (models.CreateRequest, error) {
+	req := models.CreateRequest{Model: name, Stream: true}
+
+	lines := strings.Split(modelfile, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		instruction, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+
+		// Collect triple-quoted values spanning several lines
+		if strings.HasPrefix(value, `"""`) {
+			value = strings.TrimPrefix(value, `"""`)
+			for !strings.HasSuffix(value, `"""`) {
+				i++
+				if i >= len(lines) {
+					return req, fmt.Errorf("unterminated \"\"\" in %s", instruction)
+				}
+				value += "\n" + lines[i]
+			}
+			value = strings.TrimSuffix(value, `"""`)
+		}
+
+		switch strings.ToUpper(instruction) {
+		case "FROM":
+			req.From = value
+		case "SYSTEM":
+			req.System = value
+		case "TEMPLATE":
+			req.Template = value
+		case "PARAMETER":
+			key, raw, ok := strings.Cut(value, " ")
+			if !ok {
+				return req, fmt.Errorf("PARAMETER %q has no value", value)
+			}
+			if req.Parameters == nil {
+				req.Parameters = map[string]interface{}{}
+			}
+			addParameter(req.Parameters, key, strings.Trim(strings.TrimSpace(raw), `"`))
+		default:
+			return req, fmt.Errorf("unsupported instruction %s", instruction)
+		}
+	}
+
+	if req.From == "" {
+		return req, fmt.Errorf("the Modelfile needs a FROM instruction")
+	}
+	return req, nil
+}
+
+// addParameter stores a parameter with a numeric value when possible. Stop
+// sequences may be repeated and are collected into a list.
+func addParameter(params map[string]interface{}, key, raw string) {
+	if key == "stop" {
+		stops, _ := params[key].([]string)
+		params[key] = append(stops, raw)
+		return
+	}
+	if n, err := strconv.Atoi(raw); err == nil {
+		params[key] = n
+		return
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		params[key] = f
+		return
+	}
+	params[key] = raw
+}
+
+// CreateModel creates a model on the Ollama server, reporting each progress
+// status as it streams in
+func (c *Client) CreateModel(ctx context.Context, req models.CreateRequest, progress func(status string)) error {
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal create request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/create", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Ollama API returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var status models.ProgressResponse
+		if err := json.Unmarshal(scanner.Bytes(), &status); err != nil {
+			continue
+		}
+		if status.Error != "" {
+			return fmt.Errorf("failed to create model: %s", status.Error)
+		}
+		progress(status.Status)
+	}
+	return scanner.Err()
+}
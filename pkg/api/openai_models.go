@@ -0,0 +1,84 @@
+package api
+
+import (
+	"path"
+	"strings"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// OpenAIModelAllowlist limits the OpenAI models listed to these ids. Entries
+// may use shell patterns like "gpt-4o*". Every chat model is listed when it
+// is empty.
+var OpenAIModelAllowlist []string
+
+// nonChatModels are parts of the ids of models that can't chat: embeddings,
+// speech, images, OCR and moderation
+var nonChatModels = []string{
+	"embed", "ocr", "whisper", "tts", "dall-e", "gpt-image", "moderation",
+	"transcribe", "realtime", "audio", "davinci", "babbage", "search",
+}
+
+// openAIModelFamilies are the context windows of OpenAI model families in
+// tokens, keyed by id prefix. The longest matching prefix wins.
+var openAIModelFamilies = map[string]struct {
+	family  string
+	context int
+}{
+	"gpt-3.5-turbo": {"GPT-3.5", 16385},
+	"gpt-4":         {"GPT-4", 8192},
+	"gpt-4-32k":     {"GPT-4", 32768},
+	"gpt-4-turbo":   {"GPT-4", 128000},
+	"gpt-4o":        {"GPT-4o", 128000},
+	"gpt-4.1":       {"GPT-4.1", 1047576},
+	"gpt-4.5":       {"GPT-4.5", 128000},
+	"gpt-5":         {"GPT-5", 400000},
+	"chatgpt-4o":    {"GPT-4o", 128000},
+	"o1":            {"o1", 200000},
+	"o1-mini":       {"o1", 128000},
+	"o1-preview":    {"o1", 128000},
+	"o3":            {"o3", 200000},
+	"o4-mini":       {"o4", 200000},
+	"deepseek":      {"DeepSeek", 128000},
+	"sonar":         {"Sonar", 128000},
+	"sonar-pro":     {"Sonar", 200000},
+}
+
+// IsChatModel reports whether an OpenAI model id names a model that can chat
+func IsChatModel(id string) bool {
+	id = strings.ToLower(id)
+	for _, part := range nonChatModels {
+		if strings.Contains(id, part) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowedModel reports whether id matches OpenAIModelAllowlist
+func allowedModel(id string) bool {
+	if len(OpenAIModelAllowlist) == 0 {
+		return true
+	}
+	for _, pattern := range OpenAIModelAllowlist {
+		if ok, _ := path.Match(pattern, id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// openAIModel describes an OpenAI model id with its family and context
+// window, left empty for models the table doesn't know
+func openAIModel(id string) models.Model {
+	model := models.Model{Name: id, Details: models.ModelDetails{Format: "Chat"}}
+	longest := 0
+	for prefix, meta := range openAIModelFamilies {
+		if strings.HasPrefix(id, prefix) && len(prefix) > longest {
+			longest = len(prefix)
+			model.Details.Family = meta.family
+			model.Details.Context = meta.context
+		}
+	}
+	return model
+}
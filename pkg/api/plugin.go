@@ -0,0 +1,257 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// PluginPrefix starts the file names of provider plugins: the executable
+// ollama-tui-provider-foo provides the provider foo
+const PluginPrefix = "ollama-tui-provider-"
+
+// PluginProtocol is the version of the plugin protocol sent with every
+// request
+const PluginProtocol = 1
+
+// builtinProviders are the provider names plugins can't take over
+var builtinProviders = []string{"ollama", "openai", "mistral", "deepseek", "huggingface", MockProvider, "all"}
+
+// Plugin is a provider implemented by an external executable. Each request
+// runs it once with the request as a JSON line on stdin; it answers with
+// JSON lines on stdout.
+type Plugin struct {
+	// Name is the provider it adds
+	Name string
+	// Path is the executable
+	Path string
+}
+
+// Plugins are the provider plugins found by LoadPlugins, by provider name
+var Plugins = map[string]Plugin{}
+
+// LoadPlugins finds the provider plugins in dir. Plugins named like a
+// built-in provider are skipped.
+func LoadPlugins(dir string) ([]Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var plugins []Plugin
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if runtime.GOOS == "windows" {
+			name, ok = strings.CutSuffix(name, ".exe")
+		} else if info, err := entry.Info(); err == nil {
+			ok = info.Mode()&0111 != 0
+		}
+		if !ok || name == "" || isBuiltinProvider(name) {
+			continue
+		}
+		plugins = append(plugins, Plugin{Name: name, Path: filepath.Join(dir, entry.Name())})
+	}
+	return plugins, nil
+}
+
+// isBuiltinProvider reports whether name is a provider of the client itself
+func isBuiltinProvider(name string) bool {
+	if _, ok := Presets[name]; ok {
+		return true
+	}
+	for _, builtin := range builtinProviders {
+		if name == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginRequest is the line a plugin reads from stdin
+type pluginRequest struct {
+	Protocol int `json:"protocol"`
+	// Method is "models" to list the models or "generate" to stream an
+	// answer to the last message
+	Method      string               `json:"method"`
+	Model       string               `json:"model,omitempty"`
+	Messages    []models.ChatMessage `json:"messages,omitempty"`
+	Temperature *float64             `json:"temperature,omitempty"`
+	Format      json.RawMessage      `json:"format,omitempty"`
+}
+
+// pluginResponse is a line a plugin writes to stdout
+type pluginResponse struct {
+	// Models answers a "models" request
+	Models []pluginModel `json:"models,omitempty"`
+	// Token is the next piece of a "generate" answer
+	Token string `json:"token,omitempty"`
+	// Usage ends a "generate" answer with its token counts
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// pluginModel is a model as a plugin lists it
+type pluginModel struct {
+	Name    string `json:"name"`
+	Family  string `json:"family,omitempty"`
+	Context int    `json:"context,omitempty"`
+}
+
+// pluginWaitDelay is how long a stopped plugin's output may stay open, held
+// by processes it started, before it is closed
+const pluginWaitDelay = 500 * time.Millisecond
+
+// runPlugin sends req to the plugin and calls handle with each line it
+// answers until handle returns false or the plugin exits. A plugin still
+// running when handle returns false, or after it reports an error, is killed.
+func runPlugin(ctx context.Context, plugin Plugin, req pluginRequest, handle func(pluginResponse) bool) error {
+	req.Protocol = PluginProtocol
+	line, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// The plugin is stopped once its answer is complete, so that one that
+	// keeps running or writing doesn't hold up the response
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	cmd := exec.CommandContext(runCtx, plugin.Path)
+	// Processes the plugin started may keep its output open after it exits
+	cmd.WaitDelay = pluginWaitDelay
+	cmd.Stdin = bytes.NewReader(append(line, '\n'))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin %s: %w", plugin.Name, err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	var failure error
+	stopped := false
+	for scanner.Scan() {
+		var resp pluginResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			continue
+		}
+		if resp.Error != "" {
+			failure = fmt.Errorf("%s: %s", plugin.Name, resp.Error)
+			break
+		}
+		if !handle(resp) {
+			stopped = true
+			break
+		}
+	}
+	// Whatever the plugin still writes is not read, so it is stopped rather
+	// than left blocked on its output
+	if failure != nil || stopped || scanner.Err() != nil {
+		stop()
+	}
+
+	err = cmd.Wait()
+	switch {
+	case failure != nil:
+		return failure
+	case ctx.Err() != nil:
+		return nil
+	case stopped:
+		return nil
+	case scanner.Err() != nil:
+		return fmt.Errorf("plugin %s: %w", plugin.Name, scanner.Err())
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("plugin %s failed: %s", plugin.Name, msg)
+		}
+		return fmt.Errorf("plugin %s failed: %w", plugin.Name, err)
+	}
+	return nil
+}
+
+// fetchPluginModels asks a plugin for its models
+func (c *Client) fetchPluginModels(plugin Plugin) ([]models.Model, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var result []models.Model
+	answered := false
+	err := runPlugin(ctx, plugin, pluginRequest{Method: "models"}, func(resp pluginResponse) bool {
+		for _, m := range resp.Models {
+			family := m.Family
+			if family == "" {
+				family = plugin.Name
+			}
+			result = append(result, models.Model{
+				Name:    m.Name,
+				Details: models.ModelDetails{Family: family, Format: "Chat", Context: m.Context},
+			})
+		}
+		answered = true
+		return false
+	})
+	if err == nil && !answered {
+		err = fmt.Errorf("plugin %s listed no models", plugin.Name)
+	}
+	return result, err
+}
+
+// generatePluginResponse streams an answer from a plugin, which receives
+// the whole conversation with every request
+func (c *Client) generatePluginResponse(ctx context.Context, plugin Plugin, model, prompt string, out *sink) error {
+	var messages []models.ChatMessage
+	if system := c.systemPrompt(); system != "" {
+		messages = append(messages, models.ChatMessage{Role: "system", Content: system})
+	}
+	messages = append(messages, c.history()...)
+	messages = append(messages, models.ChatMessage{Role: "user", Content: prompt, Images: c.Images})
+
+	req := pluginRequest{
+		Method:      "generate",
+		Model:       model,
+		Messages:    messages,
+		Temperature: c.Temperature,
+		Format:      c.Format,
+	}
+	var answer strings.Builder
+	err := runPlugin(ctx, plugin, req, func(resp pluginResponse) bool {
+		answer.WriteString(resp.Token)
+		out.token(resp.Token)
+		if resp.Usage != nil {
+			out.usage(model, time.Now().Format(time.RFC3339Nano), models.ResponseMetrics{
+				PromptEvalCount: resp.Usage.PromptTokens,
+				EvalCount:       resp.Usage.CompletionTokens,
+			})
+		}
+		return !resp.Done
+	})
+	if err != nil || ctx.Err() != nil {
+		return err
+	}
+
+	c.remember([]models.ChatMessage{
+		{Role: "user", Content: prompt},
+		{Role: "assistant", Content: strings.TrimSpace(models.StripReasoning(answer.String()))},
+	})
+	return nil
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// writePlugin writes a plugin script that answers every request with script
+func writePlugin(t *testing.T, script string) Plugin {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "slow-plugin")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nread request\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return Plugin{Name: "slow-plugin", Path: path}
+}
+
+func TestRunPluginStopsAfterDone(t *testing.T) {
+	tests := map[string]string{
+		"sleeps":       `echo '{"token":"hi"}'; echo '{"done":true}'; sleep 30`,
+		"keeps going":  `echo '{"token":"hi"}'; echo '{"done":true}'; while true; do echo '{"token":"more"}'; done`,
+		"fails":        `echo '{"error":"out of credits"}'; sleep 30`,
+		"exec'd sleep": `echo '{"done":true}'; exec sleep 30`,
+	}
+	for name, script := range tests {
+		t.Run(name, func(t *testing.T) {
+			plugin := writePlugin(t, script)
+			start := time.Now()
+			var tokens []string
+			err := runPlugin(context.Background(), plugin, pluginRequest{Method: "generate"}, func(resp pluginResponse) bool {
+				tokens = append(tokens, resp.Token)
+				return !resp.Done
+			})
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("runPlugin returned after %v, waiting for the plugin to exit", elapsed)
+			}
+			if name == "fails" {
+				if err == nil {
+					t.Error("the plugin's error was not returned")
+				}
+			} else if err != nil {
+				t.Errorf("runPlugin = %v after a complete answer", err)
+			}
+			if len(tokens) > 2 {
+				t.Errorf("handle was called %d times, after the answer was done", len(tokens))
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// Preset is a hosted service that speaks the OpenAI API, usable as a
+// provider with nothing but an API key
+type Preset struct {
+	// Name is how the service is called, also used as the family of its
+	// models
+	Name string
+	// URL is the base URL of its OpenAI-compatible API
+	URL string
+	// Models are listed instead of asking the service, for services that
+	// can't list their models
+	Models []string
+}
+
+// Presets are the OpenAI-compatible services built in as providers
+var Presets = map[string]Preset{
+	"lmstudio":  {Name: "LM Studio", URL: DefaultLMStudioURL},
+	"together":  {Name: "Together AI", URL: "https://api.together.xyz/v1"},
+	"fireworks": {Name: "Fireworks", URL: "https://api.fireworks.ai/inference/v1"},
+	"perplexity": {
+		Name:   "Perplexity",
+		URL:    "https://api.perplexity.ai",
+		Models: []string{"sonar", "sonar-pro", "sonar-reasoning", "sonar-reasoning-pro", "sonar-deep-research"},
+	},
+}
+
+// decodeModelList decodes a model list, which Together sends as a bare
+// array rather than in a "data" field
+func decodeModelList(body io.Reader) ([]models.OpenAIModel, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var list []models.OpenAIModel
+		err := json.Unmarshal(trimmed, &list)
+		return list, err
+	}
+	var list models.OpenAIModelResponse
+	err = json.Unmarshal(data, &list)
+	return list.Data, err
+}
+
+// chatCapable reports whether a listed model can chat, from what the server
+// says about it and from its id
+func chatCapable(m models.OpenAIModel) bool {
+	switch {
+	case m.Capabilities != nil: // Mistral
+		if !m.Capabilities.CompletionChat {
+			return false
+		}
+	case m.SupportsChat != nil: // Fireworks
+		if !*m.SupportsChat {
+			return false
+		}
+	case m.Type != "": // Together
+		if m.Type != "chat" {
+			return false
+		}
+	}
+	return IsChatModel(m.ID)
+}
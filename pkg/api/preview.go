@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// RequestPreview is what the client would send with a prompt, worked out
+// without calling the provider
+type RequestPreview struct {
+	// Endpoint is the API the request would go to, such as /api/chat
+	Endpoint string
+	// System is the system prompt with the memories and the JSON
+	// instruction added
+	System string
+	// History is the conversation sent as messages before the prompt
+	History []models.ChatMessage
+	// ContextTokens is the length of Ollama's token context sent in place
+	// of the history, when the conversation continues with it
+	ContextTokens int
+	Prompt        string
+	Images        int
+	Format        json.RawMessage
+	// Options are the sampling options and those passed through
+	Options map[string]interface{}
+	Tools   []string
+}
+
+// Preview works out the request the client would send with prompt to model,
+// taking the same route as a response would
+func (c *Client) Preview(model, prompt string) RequestPreview {
+	preview := RequestPreview{
+		System:  c.systemPrompt(),
+		Prompt:  prompt,
+		Images:  len(c.Images),
+		Format:  c.Format,
+		Options: c.ollamaOptions(),
+	}
+	for _, tool := range c.activeTools() {
+		preview.Tools = append(preview.Tools, tool.Function.Name)
+	}
+
+	c.memory.mu.Lock()
+	chat := len(c.memory.messages) > 0
+	preview.ContextTokens = len(c.memory.context)
+	c.memory.mu.Unlock()
+
+	switch endpoint, ok := huggingFaceEndpoint(model); {
+	case ok && c.provider == "huggingface" && endpoint.API == "generate":
+		preview.Endpoint = endpoint.URL
+	case c.plugin != nil:
+		preview.Endpoint = "plugin " + c.plugin.Path
+	case c.provider == MockProvider:
+		preview.Endpoint = "mock provider"
+	case c.openAI:
+		preview.Endpoint, _, _ = c.chatTarget(model)
+	case chat || len(c.activeTools()) > 0:
+		preview.Endpoint = c.BaseURL + "/api/chat"
+	default:
+		preview.Endpoint = c.BaseURL + "/api/generate"
+		return preview
+	}
+	preview.ContextTokens = 0
+	preview.History = c.history()
+	return preview
+}
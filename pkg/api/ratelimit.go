@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxRateLimitRetries is how many times a request turned down by a rate
+	// limit is sent again before it fails
+	MaxRateLimitRetries = 3
+	// MaxRateLimitWait is the longest a request waits for a limit to reset;
+	// longer waits fail right away
+	MaxRateLimitWait = 2 * time.Minute
+)
+
+// RateLimit is what a provider said about its rate limits in the headers of
+// its last response. Counts it didn't send are -1.
+type RateLimit struct {
+	LimitRequests     int
+	RemainingRequests int
+	LimitTokens       int
+	RemainingTokens   int
+	// ResetRequests and ResetTokens are when the remaining counts are full
+	// again
+	ResetRequests time.Time
+	ResetTokens   time.Time
+	// RetryAfter is when a turned down request may be sent again
+	RetryAfter time.Time
+	UpdatedAt  time.Time
+}
+
+// RateLimitEvent carries the rate limits of the provider after each request,
+// and the wait before a request is sent again when a limit was hit
+type RateLimitEvent struct {
+	Provider string
+	Limit    RateLimit
+	// Wait is set while the request waits for the limit to reset, before
+	// its Attempt-th retry
+	Wait    time.Duration
+	Attempt int
+}
+
+func (RateLimitEvent) event() {}
+
+// RateLimitError is a request the provider kept turning down for its rate
+// limit, or whose limit resets too late to wait for
+type RateLimitError struct {
+	Provider string
+	// RetryIn is how long until the limit resets, when the provider said
+	RetryIn time.Duration
+	Message string
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("%s rate limit reached", e.Provider)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RetryIn > 0 {
+		msg += fmt.Sprintf(" (try again in %s)", e.RetryIn.Round(time.Second))
+	}
+	return msg
+}
+
+// rateLimits are the last rate limits of each provider, shared by all its
+// clients so that a request waits for a limit another one hit
+var rateLimits = struct {
+	mu         sync.Mutex
+	byProvider map[string]RateLimit
+}{byProvider: map[string]RateLimit{}}
+
+// ParseRateLimit reads the x-ratelimit-* and retry-after headers of a
+// response, reporting false when it has none
+func ParseRateLimit(header http.Header) (RateLimit, bool) {
+	now := time.Now()
+	limit := RateLimit{
+		LimitRequests:     headerCount(header, "X-Ratelimit-Limit-Requests"),
+		RemainingRequests: headerCount(header, "X-Ratelimit-Remaining-Requests"),
+		LimitTokens:       headerCount(header, "X-Ratelimit-Limit-Tokens"),
+		RemainingTokens:   headerCount(header, "X-Ratelimit-Remaining-Tokens"),
+		ResetRequests:     headerTime(header, "X-Ratelimit-Reset-Requests", now),
+		ResetTokens:       headerTime(header, "X-Ratelimit-Reset-Tokens", now),
+		RetryAfter:        headerTime(header, "Retry-After", now),
+		UpdatedAt:         now,
+	}
+	ok := limit.LimitRequests >= 0 || limit.RemainingRequests >= 0 || limit.LimitTokens >= 0 ||
+		limit.RemainingTokens >= 0 || !limit.RetryAfter.IsZero()
+	return limit, ok
+}
+
+// headerCount reads a count header, or returns -1
+func headerCount(header http.Header, name string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(header.Get(name)))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// headerTime reads when a reset header says a limit resets: after a
+// duration such as "6m0s" or "20ms", after a number of seconds, or at an
+// HTTP date
+func headerTime(header http.Header, name string, now time.Time) time.Time {
+	value := strings.TrimSpace(header.Get(name))
+	if value == "" {
+		return time.Time{}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d)
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return now.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// Exhausted returns how long until a limit with nothing remaining resets
+func (l RateLimit) Exhausted(now time.Time) time.Duration {
+	var wait time.Duration
+	if l.RemainingRequests == 0 && l.ResetRequests.After(now) {
+		wait = l.ResetRequests.Sub(now)
+	}
+	if l.RemainingTokens == 0 && l.ResetTokens.After(now) {
+		wait = max(wait, l.ResetTokens.Sub(now))
+	}
+	if l.RetryAfter.After(now) {
+		wait = max(wait, l.RetryAfter.Sub(now))
+	}
+	return wait
+}
+
+// LastRateLimit returns the rate limits a provider sent last
+func LastRateLimit(provider string) (RateLimit, bool) {
+	rateLimits.mu.Lock()
+	defer rateLimits.mu.Unlock()
+	limit, ok := rateLimits.byProvider[provider]
+	return limit, ok
+}
+
+// recordRateLimit keeps the rate limits of a response and sends them out
+func (c *Client) recordRateLimit(header http.Header, out *sink) {
+	limit, ok := ParseRateLimit(header)
+	if !ok {
+		return
+	}
+	rateLimits.mu.Lock()
+	rateLimits.byProvider[c.provider] = limit
+	rateLimits.mu.Unlock()
+	out.send(RateLimitEvent{Provider: c.provider, Limit: limit})
+}
+
+// retryWait returns how long to wait before sending a request turned down
+// by a rate limit again: until the limit resets, or longer after each
+// attempt when the provider doesn't say
+func retryWait(header http.Header, attempt int) time.Duration {
+	limit, _ := ParseRateLimit(header)
+	if wait := limit.Exhausted(time.Now()); wait > 0 {
+		return wait
+	}
+	return time.Duration(2<<attempt) * time.Second
+}
+
+// sleep waits for d unless ctx is done first, reporting whether it waited
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// doRateLimited sends the request newRequest builds, first waiting for a
+// limit of the provider that ran out, and sends it again when it is turned
+// down by a rate limit. The waits are sent to out.
+func (c *Client) doRateLimited(ctx context.Context, out *sink, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	if limit, ok := LastRateLimit(c.provider); ok {
+		if wait := limit.Exhausted(time.Now()); wait > 0 && wait <= MaxRateLimitWait {
+			out.send(RateLimitEvent{Provider: c.provider, Limit: limit, Wait: wait})
+			if !sleep(ctx, wait) {
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		c.recordRateLimit(resp.Header, out)
+		if resp.StatusCode != http.StatusTooManyRequests {
+			return resp, nil
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		// OpenAI answers 429 when the account is out of credit too, which
+		// waiting doesn't fix
+		if strings.Contains(string(body), "insufficient_quota") {
+			return nil, &RateLimitError{Provider: c.provider, Message: errorMessage(body)}
+		}
+		wait := retryWait(resp.Header, attempt)
+		if attempt >= MaxRateLimitRetries || wait > MaxRateLimitWait {
+			return nil, &RateLimitError{Provider: c.provider, RetryIn: wait, Message: errorMessage(body)}
+		}
+		limit, _ := ParseRateLimit(resp.Header)
+		out.send(RateLimitEvent{Provider: c.provider, Limit: limit, Wait: wait, Attempt: attempt + 1})
+		if !sleep(ctx, wait) {
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// RegistryCatalogURL serves the latest catalog of the model registry
+// browser, the registry.json next to this file
+const RegistryCatalogURL = "https://raw.githubusercontent.com/evilvic/ollama-tui/main/pkg/api/registry.json"
+
+// bundledCatalog is the catalog shipped with this build
+//
+//go:embed registry.json
+var bundledCatalog []byte
+
+// RegistryTag is a variant of a library model, such as its 7b or q8_0 build
+type RegistryTag struct {
+	Name string `json:"name"`
+	// Size is the download size in bytes
+	Size int64 `json:"size"`
+}
+
+// RegistryModel is a model of the Ollama library
+type RegistryModel struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Tags        []RegistryTag `json:"tags"`
+}
+
+// RegistryCatalog lists popular models of the Ollama library
+type RegistryCatalog struct {
+	Models []RegistryModel `json:"models"`
+}
+
+// ParseRegistryCatalog decodes a catalog, which must list at least one model
+func ParseRegistryCatalog(data []byte) (RegistryCatalog, error) {
+	var catalog RegistryCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return catalog, fmt.Errorf("invalid registry catalog: %w", err)
+	}
+	if len(catalog.Models) == 0 {
+		return catalog, fmt.Errorf("the registry catalog lists no models")
+	}
+	return catalog, nil
+}
+
+// BundledRegistryCatalog returns the catalog shipped with this build
+func BundledRegistryCatalog() RegistryCatalog {
+	catalog, _ := ParseRegistryCatalog(bundledCatalog)
+	return catalog
+}
+
+// FetchRegistryCatalog downloads the catalog at url. Like LatestRelease it
+// doesn't go through DefaultTransport.
+func FetchRegistryCatalog(ctx context.Context, url string) (RegistryCatalog, error) {
+	var catalog RegistryCatalog
+	if err := getJSON(ctx, http.DefaultClient, url, &catalog); err != nil {
+		return catalog, err
+	}
+	if len(catalog.Models) == 0 {
+		return catalog, fmt.Errorf("the registry catalog lists no models")
+	}
+	return catalog, nil
+}
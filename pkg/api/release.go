@@ -0,0 +1,25 @@
+package api
+
+import (
+	"context"
+	"net/http"
+)
+
+// LatestReleaseURL answers with the latest release of ollama-tui
+const LatestReleaseURL = "https://api.github.com/repos/evilvic/ollama-tui/releases/latest"
+
+// Release is a published release of ollama-tui
+type Release struct {
+	// Version is the release's tag, such as v1.2.0
+	Version string `json:"tag_name"`
+	// URL is the release's page
+	URL string `json:"html_url"`
+}
+
+// LatestRelease asks GitHub for the latest release. It doesn't go through
+// DefaultTransport, so recorded fixtures only hold provider traffic.
+func LatestRelease(ctx context.Context) (Release, error) {
+	var release Release
+	err := getJSON(ctx, http.DefaultClient, LatestReleaseURL, &release)
+	return release, err
+}
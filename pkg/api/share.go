@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GistsURL creates GitHub Gists
+const GistsURL = "https://api.github.com/gists"
+
+// CreateGist uploads content as a gist with a single file and returns its
+// page. Like LatestRelease, it doesn't go through DefaultTransport.
+func CreateGist(ctx context.Context, token, filename, description, content string, public bool) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"description": description,
+		"public":      public,
+		"files":       map[string]map[string]string{filename: {"content": content}},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", GistsURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated {
+		return "", uploadError("GitHub", resp.Status, data)
+	}
+	var gist struct {
+		URL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(data, &gist); err != nil || gist.URL == "" {
+		return "", errors.New("GitHub didn't answer with the gist's URL")
+	}
+	return gist.URL, nil
+}
+
+// UploadPaste posts content to a paste service and returns the URL it
+// answers with: the whole body, or the url or link field of a JSON body
+func UploadPaste(ctx context.Context, url, content string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/markdown; charset=utf-8")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", uploadError(url, resp.Status, data)
+	}
+
+	var answer struct {
+		URL  string `json:"url"`
+		Link string `json:"link"`
+	}
+	if json.Unmarshal(data, &answer) == nil {
+		if answer.URL != "" {
+			return answer.URL, nil
+		}
+		if answer.Link != "" {
+			return answer.Link, nil
+		}
+	}
+	link := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+		if location := resp.Header.Get("Location"); location != "" {
+			return location, nil
+		}
+		return "", fmt.Errorf("%s didn't answer with a URL", url)
+	}
+	return link, nil
+}
+
+// uploadError describes an upload that was turned down, with the message of
+// the answer when it has one
+func uploadError(service, status string, body []byte) error {
+	message := errorMessage(body)
+	if message == "" && !bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		return fmt.Errorf("%s answered %s", service, status)
+	}
+	if runes := []rune(message); len(runes) > 200 {
+		message = string(runes[:200]) + "…"
+	}
+	return fmt.Errorf("%s answered %s: %s", service, status, message)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// Event is something that happened while streaming a response: a TokenEvent,
+// CitationsEvent, UsageEvent, RequestEvent, RateLimitEvent, InspectEvent,
+// DoneEvent or ErrorEvent
+type Event interface {
+	event()
+}
+
+// TokenEvent carries the next piece of the response text
+type TokenEvent struct {
+	Text string
+}
+
+// CitationsEvent carries the web pages a response cites as [1], [2] and so
+// on, from providers that search the web. It comes after the last token.
+type CitationsEvent struct {
+	URLs []string
+}
+
+// UsageEvent carries what the provider reported about the finished response.
+// It comes just before the DoneEvent, and only from providers that report it.
+type UsageEvent struct {
+	Metadata models.ResponseMetadata
+}
+
+// RequestEvent carries a request as it was sent, from clients recording
+// requests. Responses that call tools send one for every round.
+type RequestEvent struct {
+	Request models.RecordedRequest
+}
+
+// DoneEvent ends a response that finished or was cancelled, with why the
+// model stopped when the provider said
+type DoneEvent struct {
+	Reason string
+}
+
+// DoneReasonLength is the reason of a response cut off by the token limit
+const DoneReasonLength = "length"
+
+// ErrorEvent ends a response that failed
+type ErrorEvent struct {
+	Err error
+}
+
+func (TokenEvent) event()     {}
+func (CitationsEvent) event() {}
+func (UsageEvent) event()     {}
+func (RequestEvent) event()   {}
+func (DoneEvent) event()      {}
+func (ErrorEvent) event()     {}
+
+// Stream generates a response to prompt in the background. The returned
+// channel delivers its events in order, ends with a DoneEvent or ErrorEvent
+// and is then closed. Cancelling ctx aborts the request at once and closes
+// the channel, possibly without a final event, so the stream never outlives
+// it even when nobody drains the channel.
+//
+// The response uses the client's settings as they are when Stream is called,
+// so they can be changed while it streams.
+func (c *Client) Stream(ctx context.Context, model, prompt string) <-chan Event {
+	settings := *c
+	return settings.stream(ctx, func(out *sink) error {
+		return settings.generate(ctx, model, prompt, out)
+	})
+}
+
+// Replay sends a recorded request again exactly as it was, streaming the
+// response like Stream without adding it to the conversation. Requests to
+// Ollama's generate and chat APIs and to chat completions can be replayed.
+func (c *Client) Replay(ctx context.Context, req models.RecordedRequest) <-chan Event {
+	settings := *c
+	settings.memory = &memory{}
+	settings.RecordRequests = false
+	return settings.stream(ctx, func(out *sink) error {
+		switch {
+		case strings.HasSuffix(req.URL, "/api/generate"):
+			return settings.postGenerate(ctx, req.URL, req.Body, out)
+		case strings.HasSuffix(req.URL, "/api/chat"):
+			_, _, err := settings.postOllamaChat(ctx, req.URL, req.Body, out)
+			return err
+		case strings.HasSuffix(req.URL, "/chat/completions"):
+			_, _, err := settings.postOpenAIChat(ctx, req.URL, settings.APIKey, req.Body, out, func(string, ...interface{}) {})
+			return err
+		}
+		return fmt.Errorf("requests to %s can't be replayed", req.URL)
+	})
+}
+
+// stream runs generate in the background, delivering what it sends to out
+// as events followed by its HTTP exchanges, its usage and how it ended
+func (c *Client) stream(ctx context.Context, generate func(out *sink) error) <-chan Event {
+	events := make(chan Event, 64)
+	// The requests of this response alone go through the inspector
+	var inspector *inspectTransport
+	if c.client != nil {
+		client := *c.client
+		inspector = newInspectTransport(client.Transport)
+		client.Transport = inspector
+		c.client = &client
+	}
+	go func() {
+		defer close(events)
+		out := &sink{ctx: ctx, events: events, record: c.RecordRequests, provider: c.provider}
+		err := generate(out)
+		if inspector != nil {
+			if exchanges := inspector.Exchanges(); len(exchanges) > 0 {
+				// A stopped response keeps them too when there is room
+				select {
+				case events <- InspectEvent{Exchanges: exchanges}:
+				default:
+					out.send(InspectEvent{Exchanges: exchanges})
+				}
+			}
+		}
+		if out.metadata != nil {
+			out.send(UsageEvent{Metadata: *out.metadata})
+		}
+		if err != nil && ctx.Err() == nil {
+			out.send(ErrorEvent{Err: err})
+			return
+		}
+		out.send(DoneEvent{Reason: out.reason})
+	}()
+	return events
+}
+
+// sink collects what a response produces while it streams: text goes out as
+// events right away, metadata is sent once the response is done
+type sink struct {
+	ctx      context.Context
+	events   chan<- Event
+	metadata *models.ResponseMetadata
+	// reason is why the model stopped, as the provider put it
+	reason string
+	// record sends each request made, naming the provider it went to
+	record   bool
+	provider string
+}
+
+// send delivers an event unless the response was cancelled
+func (s *sink) send(event Event) {
+	select {
+	case s.events <- event:
+	case <-s.ctx.Done():
+	}
+}
+
+// token sends the next piece of the response
+func (s *sink) token(text string) {
+	if text != "" {
+		s.send(TokenEvent{Text: text})
+	}
+}
+
+// request sends the body of a request about to be sent, when recording
+func (s *sink) request(url string, body []byte) {
+	if s.record {
+		s.send(RequestEvent{Request: models.RecordedRequest{Provider: s.provider, URL: url, Body: json.RawMessage(body)}})
+	}
+}
+
+// finish records why the model stopped
+func (s *sink) finish(reason string) {
+	s.reason = reason
+}
+
+// usage records the metadata of a finished request, adding to that of the
+// earlier requests of a response that called tools
+func (s *sink) usage(model, createdAt string, metrics models.ResponseMetrics) {
+	if s.metadata == nil {
+		s.metadata = models.NewResponseMetadata(model, createdAt, metrics)
+		return
+	}
+	s.metadata.Model = model
+	s.metadata.Add(metrics)
+}
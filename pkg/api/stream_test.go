@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// slowServer sends one piece of a response and then holds the body open
+// until the client goes away or the test ends
+func slowServer(t *testing.T, chunk string) *httptest.Server {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, chunk)
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	t.Cleanup(func() {
+		close(done)
+		server.Close()
+	})
+	return server
+}
+
+func TestCancelAbortsStream(t *testing.T) {
+	tests := map[string]string{
+		"ollama": `{"model":"m","response":"Hello","done":false}` + "\n",
+		"openai": `data: {"choices":[{"delta":{"content":"Hello"}}]}` + "\n\n",
+	}
+	for provider, chunk := range tests {
+		t.Run(provider, func(t *testing.T) {
+			server := slowServer(t, chunk)
+			client := NewClient(provider, "sk-test")
+			client.BaseURL = server.URL
+			client.client = &http.Client{Transport: &http.Transport{}}
+			client.SystemPrompt = ""
+
+			baseline := runtime.NumGoroutine()
+			ctx, cancel := context.WithCancel(context.Background())
+			events := client.Stream(ctx, "m", "hi")
+
+			select {
+			case event := <-events:
+				if token, ok := event.(TokenEvent); !ok || token.Text != "Hello" {
+					t.Fatalf("first event = %#v, want the token Hello", event)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no token arrived")
+			}
+
+			cancel()
+			closed := time.After(time.Second)
+		drain:
+			for {
+				select {
+				case _, ok := <-events:
+					if !ok {
+						break drain
+					}
+				case <-closed:
+					t.Fatal("the event channel stayed open after cancelling")
+				}
+			}
+
+			client.client.Transport.(*http.Transport).CloseIdleConnections()
+			deadline := time.Now().Add(2 * time.Second)
+			for runtime.NumGoroutine() > baseline {
+				if time.Now().After(deadline) {
+					buf := make([]byte, 1<<16)
+					t.Fatalf("%d goroutines left after cancelling, %d before streaming:\n%s",
+						runtime.NumGoroutine(), baseline, buf[:runtime.Stack(buf, true)])
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// MaxToolRounds limits how many times a model may call tools before it has
+// to answer
+const MaxToolRounds = 8
+
+// ToolHandler runs a tool call requested by a model and returns its result
+type ToolHandler func(ctx context.Context, call models.ToolCall) (string, error)
+
+// activeTools returns the tools offered to the model, if a handler is set to
+// run them
+func (c *Client) activeTools() []models.Tool {
+	if c.ToolHandler == nil {
+		return nil
+	}
+	return c.Tools
+}
+
+// runTools executes the tool calls requested by the model, noting each one
+// in the streamed response, and returns the results as tool messages
+func (c *Client) runTools(ctx context.Context, calls []models.ToolCall, out *sink) []models.ChatMessage {
+	results := make([]models.ChatMessage, 0, len(calls))
+	for _, call := range calls {
+		out.token(fmt.Sprintf("\n⚙ %s %s\n", call.Function.Name, call.Function.ArgumentsJSON()))
+
+		result, err := c.ToolHandler(ctx, call)
+		if err != nil {
+			result = "Error: " + err.Error()
+			out.token(fmt.Sprintf("  ✗ %v\n", err))
+		}
+		results = append(results, models.ChatMessage{Role: "tool", Content: result, ToolCallID: call.ID})
+	}
+	out.token("\n")
+	return results
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportOptions tunes the connections to the providers. Zero values keep
+// Go's defaults, except that no timeout is applied.
+type TransportOptions struct {
+	// ConnectTimeout limits establishing a connection, including the TLS
+	// handshake
+	ConnectTimeout time.Duration
+	// ReadTimeout limits the wait for a response to start and for each
+	// further piece of a streamed response
+	ReadTimeout time.Duration
+	// IdleConnTimeout closes reusable connections left unused this long
+	IdleConnTimeout time.Duration
+	// MaxIdleConnsPerHost is how many unused connections are kept per host
+	MaxIdleConnsPerHost int
+	// DisableKeepAlives opens a new connection for every request
+	DisableKeepAlives bool
+	// Proxy is the URL of a proxy for every request. HTTP_PROXY, HTTPS_PROXY
+	// and NO_PROXY are used when it is empty.
+	Proxy string
+	// CACert is a PEM file of certificate authorities to trust besides the
+	// system's
+	CACert string
+	// InsecureSkipVerify accepts any server certificate, for local servers
+	// with self-signed ones
+	InsecureSkipVerify bool
+}
+
+// NewTransport creates a transport for provider requests with opts
+func NewTransport(opts TransportOptions) (http.RoundTripper, error) {
+	proxy := http.ProxyFromEnvironment
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", opts.Proxy)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	dialer := &net.Dialer{Timeout: opts.ConnectTimeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   opts.ConnectTimeout,
+		ResponseHeaderTimeout: opts.ReadTimeout,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		DisableKeepAlives:     opts.DisableKeepAlives,
+		ForceAttemptHTTP2:     true,
+	}
+	if opts.ReadTimeout <= 0 {
+		return transport, nil
+	}
+	return &readTimeoutTransport{base: transport, timeout: opts.ReadTimeout}, nil
+}
+
+// readTimeoutTransport fails responses whose body stops arriving for longer
+// than timeout. A total request timeout would cut off long streamed answers.
+type readTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+// noReadTimeoutKey marks the contexts of requests WithoutReadTimeout
+type noReadTimeoutKey struct{}
+
+// WithoutReadTimeout returns a context whose requests may wait on their
+// response body for as long as they like, for event streams that can stay
+// quiet. The wait for the response to start is still limited.
+func WithoutReadTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noReadTimeoutKey{}, true)
+}
+
+// RoundTrip sends the request and watches the response body for stalls
+func (t *readTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || req.Context().Value(noReadTimeoutKey{}) != nil {
+		return resp, err
+	}
+	body := &readTimeoutBody{body: resp.Body, timeout: t.timeout}
+	body.timer = time.AfterFunc(t.timeout, body.expire)
+	resp.Body = body
+	return resp, nil
+}
+
+// readTimeoutBody closes the body when no data arrived within timeout
+type readTimeoutBody struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+
+	mu      sync.Mutex
+	expired bool
+}
+
+func (b *readTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.expired {
+		return n, fmt.Errorf("no data received from the server for %s", b.timeout)
+	}
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+func (b *readTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.body.Close()
+}
+
+// expire aborts a read blocked on a server that stopped sending
+func (b *readTimeoutBody) expire() {
+	b.mu.Lock()
+	b.expired = true
+	b.mu.Unlock()
+	b.body.Close()
+}
+
+// HostAuth is what requests to one server send to get through the reverse
+// proxy in front of it
+type HostAuth struct {
+	// BearerToken is sent as "Authorization: Bearer <token>"
+	BearerToken string
+	// Username and Password are sent with basic authentication
+	Username string
+	Password string
+	// Headers are sent as they are, e.g. Cloudflare Access service tokens
+	Headers map[string]string
+}
+
+// WithHostAuth wraps base so that requests carry the credentials of their
+// server. hosts is keyed by host name, host:port or URL.
+func WithHostAuth(base http.RoundTripper, hosts map[string]HostAuth) http.RoundTripper {
+	if len(hosts) == 0 {
+		return base
+	}
+	byHost := make(map[string]HostAuth, len(hosts))
+	for key, auth := range hosts {
+		if u, err := url.Parse(key); err == nil && u.Host != "" {
+			key = u.Host
+		}
+		byHost[strings.ToLower(key)] = auth
+	}
+	return &hostAuthTransport{base: base, hosts: byHost}
+}
+
+// hostAuthTransport adds the credentials configured for a request's host
+type hostAuthTransport struct {
+	base  http.RoundTripper
+	hosts map[string]HostAuth
+}
+
+// RoundTrip sends the request with the credentials of its host, matching
+// host:port before the bare host name
+func (t *hostAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	auth, ok := t.hosts[strings.ToLower(req.URL.Host)]
+	if !ok {
+		auth, ok = t.hosts[strings.ToLower(req.URL.Hostname())]
+	}
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	for name, value := range auth.Headers {
+		req.Header.Set(name, value)
+	}
+	switch {
+	case auth.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+auth.BearerToken)
+	case auth.Username != "":
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,235 @@
+// Package batch reads files of prompts to run one after another and writes
+// each prompt with its response to an output directory
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// Prompt is a prompt of a batch file
+type Prompt struct {
+	// ID names the prompt in the results (default its position, 001 on)
+	ID     string `yaml:"id"`
+	Prompt string `yaml:"prompt"`
+	// System and Model replace those of the file for this prompt
+	System string `yaml:"system"`
+	Model  string `yaml:"model"`
+	// Metadata is copied to the results as is, such as the expected answer
+	Metadata map[string]interface{} `yaml:"metadata"`
+}
+
+// UnmarshalYAML reads a prompt given as a plain string or as a mapping
+func (p *Prompt) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		p.Prompt = node.Value
+		return nil
+	}
+	type plain Prompt
+	return node.Decode((*plain)(p))
+}
+
+// File is a batch of prompts with the model and system prompt they run with
+// unless a prompt says otherwise
+type File struct {
+	Model   string   `yaml:"model"`
+	System  string   `yaml:"system"`
+	Prompts []Prompt `yaml:"prompts"`
+}
+
+// Load reads a batch file: YAML when its extension is .yaml or .yml, with
+// the prompts either listed at the top or under "prompts" next to a model
+// and system prompt; otherwise one prompt per line, skipping blank lines
+// and lines starting with #
+func Load(path string) (File, error) {
+	data, err := os.ReadFile(utils.ExpandHome(path))
+	if err != nil {
+		return File{}, err
+	}
+
+	var file File
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		file, err = parseYAML(data)
+		if err != nil {
+			return File{}, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	default:
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			file.Prompts = append(file.Prompts, Prompt{Prompt: line})
+		}
+	}
+
+	if len(file.Prompts) == 0 {
+		return File{}, fmt.Errorf("%s has no prompts", path)
+	}
+	for i := range file.Prompts {
+		p := &file.Prompts[i]
+		if strings.TrimSpace(p.Prompt) == "" {
+			return File{}, fmt.Errorf("prompt %d of %s is empty", i+1, path)
+		}
+		if p.ID == "" {
+			p.ID = fmt.Sprintf("%03d", i+1)
+		}
+	}
+	return file, nil
+}
+
+// parseYAML reads a YAML batch file, a list of prompts or a mapping with them
+func parseYAML(data []byte) (File, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return File{}, err
+	}
+	var file File
+	if len(node.Content) == 0 {
+		return file, nil
+	}
+	if node.Content[0].Kind == yaml.SequenceNode {
+		err := node.Content[0].Decode(&file.Prompts)
+		return file, err
+	}
+	err := node.Content[0].Decode(&file)
+	return file, err
+}
+
+// Run returns the model and system prompt p runs with
+func (f File) Run(p Prompt) (model, system string) {
+	model, system = f.Model, f.System
+	if p.Model != "" {
+		model = p.Model
+	}
+	if p.System != "" {
+		system = p.System
+	}
+	return model, system
+}
+
+// Result is a prompt of a batch run with its response
+type Result struct {
+	ID       string                 `json:"id"`
+	Model    string                 `json:"model"`
+	System   string                 `json:"system,omitempty"`
+	Prompt   string                 `json:"prompt"`
+	Response string                 `json:"response"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// Time is when the response finished and DurationMS how long it took
+	Time       time.Time `json:"time"`
+	DurationMS int64     `json:"duration_ms"`
+	// PromptTokens and CompletionTokens are the counts the provider
+	// reported, if it did
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// OutputDir returns where the results of the batch file are written by
+// default: a directory next to it named after it and the time of the run
+func OutputDir(path string, now time.Time) string {
+	path = utils.ExpandHome(path)
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return filepath.Join(filepath.Dir(path), name+"-results-"+now.Format("20060102-150405"))
+}
+
+// Writer writes the results of a batch run to a directory: a Markdown file
+// for each prompt pairing it with its response, and every result as a line
+// of results.jsonl
+type Writer struct {
+	Dir string
+}
+
+// NewWriter creates the output directory
+func NewWriter(dir string) (*Writer, error) {
+	dir = utils.ExpandHome(dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Writer{Dir: dir}, nil
+}
+
+// Write saves the result of the prompt at index
+func (w *Writer) Write(index int, r Result) error {
+	name := fmt.Sprintf("%03d", index+1)
+	if slug := fileSlug(r.ID); slug != "" && slug != name {
+		name += "-" + slug
+	}
+	if err := os.WriteFile(filepath.Join(w.Dir, name+".md"), []byte(Markdown(r)), 0644); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(w.Dir, "results.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Markdown renders a result as a document with the prompt above the
+// response and the model, timing and metadata at the top
+func Markdown(r Result) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s\n\n", r.ID))
+	sb.WriteString(fmt.Sprintf("- Model: %s\n", r.Model))
+	sb.WriteString(fmt.Sprintf("- Duration: %s\n", (time.Duration(r.DurationMS) * time.Millisecond).String()))
+	if r.CompletionTokens > 0 {
+		sb.WriteString(fmt.Sprintf("- Tokens: %d prompt, %d response\n", r.PromptTokens, r.CompletionTokens))
+	}
+	keys := make([]string, 0, len(r.Metadata))
+	for key := range r.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, ok := r.Metadata[key].(string)
+		if !ok {
+			data, _ := json.Marshal(r.Metadata[key])
+			value = string(data)
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", key, value))
+	}
+
+	if r.System != "" {
+		sb.WriteString("\n## System\n\n" + r.System + "\n")
+	}
+	sb.WriteString("\n## Prompt\n\n" + r.Prompt + "\n")
+	if r.Error != "" {
+		sb.WriteString("\n## Error\n\n" + r.Error + "\n")
+	}
+	if r.Response != "" || r.Error == "" {
+		sb.WriteString("\n## Response\n\n" + r.Response + "\n")
+	}
+	return sb.String()
+}
+
+// fileSlug reduces an ID to characters safe in file names
+func fileSlug(id string) string {
+	var sb strings.Builder
+	for _, r := range strings.TrimSpace(id) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('-')
+		}
+	}
+	return strings.Trim(sb.String(), "-.")
+}
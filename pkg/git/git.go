@@ -0,0 +1,143 @@
+// Package git runs the few git commands the chat needs, in the current
+// working directory.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// run executes git with args, feeding it stdin, and returns its output
+func run(ctx context.Context, stdin string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			return "", errors.New("git is not installed")
+		}
+		// The last line of git's error output says what went wrong
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), errors.New(msg[strings.LastIndex(msg, "\n")+1:])
+		}
+		return stdout.String(), fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// StagedDiff returns the staged changes and a summary of the files they
+// touch. It fails outside a repository or when nothing is staged.
+func StagedDiff(ctx context.Context) (diff, stat string, err error) {
+	if _, err := run(ctx, "", "rev-parse", "--is-inside-work-tree"); err != nil {
+		return "", "", err
+	}
+	stat, err = run(ctx, "", "diff", "--staged", "--stat")
+	if err != nil {
+		return "", "", err
+	}
+	if strings.TrimSpace(stat) == "" {
+		return "", "", errors.New("no staged changes; stage them with git add first")
+	}
+	diff, err = run(ctx, "", "diff", "--staged", "--no-color", "--no-ext-diff")
+	if err != nil {
+		return "", "", err
+	}
+	return diff, strings.TrimRight(stat, "\n"), nil
+}
+
+// Commit commits the staged changes with message and returns git's summary
+func Commit(ctx context.Context, message string) (string, error) {
+	out, err := run(ctx, message, "commit", "--file", "-")
+	return strings.TrimSpace(out), err
+}
+
+// TruncateDiff shortens a diff to about maxBytes. Every file keeps its
+// header; the space is shared fairly so one large file cannot crowd out the
+// rest, and cut files note how many lines were dropped.
+func TruncateDiff(diff string, maxBytes int) (string, bool) {
+	if len(diff) <= maxBytes {
+		return diff, false
+	}
+
+	files := splitFiles(diff)
+
+	// Hand out the budget smallest file first, so files under their share
+	// leave the remainder to the larger ones
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return len(files[order[a]]) < len(files[order[b]]) })
+
+	budgets := make([]int, len(files))
+	remaining := maxBytes
+	for n, i := range order {
+		share := remaining / (len(files) - n)
+		budgets[i] = min(len(files[i]), share)
+		remaining -= budgets[i]
+	}
+
+	var sb strings.Builder
+	for i, file := range files {
+		sb.WriteString(truncateFile(file, budgets[i]))
+	}
+	return sb.String(), true
+}
+
+// splitFiles splits a diff at each "diff --git" header
+func splitFiles(diff string) []string {
+	var files []string
+	start := 0
+	for {
+		next := strings.Index(diff[start+1:], "\ndiff --git ")
+		if next < 0 {
+			return append(files, diff[start:])
+		}
+		end := start + 1 + next + 1
+		files = append(files, diff[start:end])
+		start = end
+	}
+}
+
+// truncateFile cuts a file's diff to whole lines within budget, always
+// keeping its header up to the first hunk
+func truncateFile(file string, budget int) string {
+	if len(file) <= budget {
+		return file
+	}
+
+	lines := strings.SplitAfter(file, "\n")
+	var sb strings.Builder
+	kept := 0
+	inHunks := false
+	for _, line := range lines {
+		inHunks = inHunks || strings.HasPrefix(line, "@@")
+		if inHunks && sb.Len()+len(line) > budget {
+			break
+		}
+		sb.WriteString(line)
+		kept++
+	}
+
+	dropped := 0
+	for _, line := range lines[kept:] {
+		if line != "" {
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		fmt.Fprintf(&sb, "[… %d more lines of this file omitted]\n", dropped)
+	}
+	return sb.String()
+}
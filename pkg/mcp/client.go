@@ -0,0 +1,327 @@
+// Package mcp connects to Model Context Protocol servers and exposes their
+// tools and resources as functions models can call.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// ProtocolVersion is the MCP revision requested when connecting
+const ProtocolVersion = "2024-11-05"
+
+// Tool is a tool offered by an MCP server
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// Resource is a piece of context an MCP server can provide by URI
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// rpcMessage is any JSON-RPC message: a request, notification or response
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// Server is a connection to one MCP server
+type Server struct {
+	Name      string
+	Transport string
+	Tools     []Tool
+	Resources []Resource
+	// Err is set when the server could not be reached or has disconnected
+	Err error
+
+	transport transport
+	mu        sync.Mutex
+	nextID    int
+	pending   map[string]chan rpcMessage
+}
+
+// Connect starts or opens the configured server and performs the MCP
+// handshake, then lists its tools and resources
+func Connect(ctx context.Context, name string, cfg utils.MCPServerConfig) (*Server, error) {
+	s := &Server{Name: name, pending: map[string]chan rpcMessage{}}
+
+	var err error
+	switch {
+	case cfg.Command != "":
+		s.Transport = "stdio"
+		s.transport, err = startStdio(cfg, s.dispatch, s.disconnected)
+	case cfg.URL != "":
+		s.Transport = "sse"
+		s.transport, err = startSSE(ctx, cfg.URL, s.dispatch, s.disconnected)
+	default:
+		err = errors.New("either command or url must be set")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": "ollama-tui", "version": utils.Version()},
+	}
+	if err := s.call(ctx, "initialize", params, nil); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("initialize failed: %w", err)
+	}
+	if err := s.notify("notifications/initialized"); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Refresh reloads the server's tools and resources. Servers that do not
+// offer resources simply have none.
+func (s *Server) Refresh(ctx context.Context) error {
+	var tools struct {
+		Tools []Tool `json:"tools"`
+	}
+	if err := s.call(ctx, "tools/list", map[string]interface{}{}, &tools); err != nil {
+		return fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	var resources struct {
+		Resources []Resource `json:"resources"`
+	}
+	if err := s.call(ctx, "resources/list", map[string]interface{}{}, &resources); err != nil {
+		resources.Resources = nil
+	}
+
+	s.mu.Lock()
+	s.Tools = tools.Tools
+	s.Resources = resources.Resources
+	s.mu.Unlock()
+	return nil
+}
+
+// CallTool runs a tool and returns its text output
+func (s *Server) CallTool(ctx context.Context, name string, arguments json.RawMessage) (string, error) {
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	params := map[string]interface{}{"name": name, "arguments": arguments}
+	if err := s.call(ctx, "tools/call", params, &result); err != nil {
+		return "", err
+	}
+
+	parts := make([]string, 0, len(result.Content))
+	for _, c := range result.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+		} else {
+			parts = append(parts, fmt.Sprintf("[%s content]", c.Type))
+		}
+	}
+	text := strings.Join(parts, "\n")
+	if result.IsError {
+		return "", errors.New(text)
+	}
+	return text, nil
+}
+
+// ReadResource returns the text contents of a resource
+func (s *Server) ReadResource(ctx context.Context, uri string) (string, error) {
+	var result struct {
+		Contents []struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+			Blob string `json:"blob"`
+		} `json:"contents"`
+	}
+	if err := s.call(ctx, "resources/read", map[string]string{"uri": uri}, &result); err != nil {
+		return "", err
+	}
+
+	parts := make([]string, 0, len(result.Contents))
+	for _, c := range result.Contents {
+		if c.Blob != "" && c.Text == "" {
+			parts = append(parts, fmt.Sprintf("[binary content of %s]", c.URI))
+			continue
+		}
+		parts = append(parts, c.Text)
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// Close disconnects from the server, stopping it if it was started locally
+func (s *Server) Close() error {
+	if s.transport == nil {
+		return nil
+	}
+	return s.transport.close()
+}
+
+// call sends a request and waits for its response
+func (s *Server) call(ctx context.Context, method string, params, result interface{}) error {
+	s.mu.Lock()
+	if s.Err != nil {
+		s.mu.Unlock()
+		return s.Err
+	}
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	replies := make(chan rpcMessage, 1)
+	s.pending[id] = replies
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(rpcMessage{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if err := s.transport.send(data); err != nil {
+		return err
+	}
+
+	select {
+	case reply, ok := <-replies:
+		if !ok {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.Err
+		}
+		if reply.Error != nil {
+			return reply.Error
+		}
+		if result != nil && len(reply.Result) > 0 {
+			return json.Unmarshal(reply.Result, result)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification, which has no response
+func (s *Server) notify(method string) error {
+	data, err := json.Marshal(rpcMessage{JSONRPC: "2.0", Method: method})
+	if err != nil {
+		return err
+	}
+	return s.transport.send(data)
+}
+
+// dispatch routes a message from the server to the request waiting for it
+// and answers pings from the server
+func (s *Server) dispatch(data []byte) {
+	var msg rpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil || len(msg.ID) == 0 || string(msg.ID) == "null" {
+		return
+	}
+
+	if msg.Method != "" {
+		reply := rpcMessage{JSONRPC: "2.0", ID: msg.ID, Result: json.RawMessage("{}")}
+		if msg.Method != "ping" {
+			reply = rpcMessage{JSONRPC: "2.0", ID: msg.ID, Error: &rpcError{Code: -32601, Message: "method not found"}}
+		}
+		if data, err := json.Marshal(reply); err == nil {
+			go s.transport.send(data)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if replies, ok := s.pending[string(msg.ID)]; ok {
+		select {
+		case replies <- msg:
+		default:
+		}
+	}
+}
+
+// disconnected fails every pending request once the connection is lost
+func (s *Server) disconnected(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err == nil {
+		s.Err = err
+	}
+	for id, replies := range s.pending {
+		close(replies)
+		delete(s.pending, id)
+	}
+}
+
+// Manager holds the connections to every configured server
+type Manager struct {
+	Servers []*Server
+	// Failed records servers that could not be connected, by name
+	Failed map[string]error
+}
+
+// ConnectAll connects to every configured server, in name order. Servers
+// that fail are recorded rather than stopping the others.
+func ConnectAll(ctx context.Context, configs map[string]utils.MCPServerConfig) *Manager {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m := &Manager{Failed: map[string]error{}}
+	for _, name := range names {
+		server, err := Connect(ctx, name, configs[name])
+		if err != nil {
+			m.Failed[name] = err
+			continue
+		}
+		m.Servers = append(m.Servers, server)
+	}
+	return m
+}
+
+// Close disconnects from every server
+func (m *Manager) Close() {
+	for _, s := range m.Servers {
+		s.Close()
+	}
+}
@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// echoTransport answers every request of the client with its ID, and keeps
+// what the client sends otherwise
+type echoTransport struct {
+	server *Server
+	sent   chan rpcMessage
+}
+
+func (t *echoTransport) send(data []byte) error {
+	var msg rpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	if msg.Method != "" {
+		go t.server.dispatch([]byte(`{"jsonrpc":"2.0","id":` + string(msg.ID) + `,"result":{"ok":true}}`))
+		return nil
+	}
+	t.sent <- msg
+	return nil
+}
+
+func (t *echoTransport) close() error { return nil }
+
+func TestRPCIDs(t *testing.T) {
+	s := &Server{Name: "test", pending: map[string]chan rpcMessage{}}
+	transport := &echoTransport{server: s, sent: make(chan rpcMessage, 1)}
+	s.transport = transport
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var result struct{ OK bool }
+	if err := s.call(ctx, "tools/list", nil, &result); err != nil || !result.OK {
+		t.Fatalf("call = %v with %+v, want the echoed result", err, result)
+	}
+
+	// JSON-RPC lets servers pick string IDs, which the reply must carry back
+	s.dispatch([]byte(`{"jsonrpc":"2.0","id":"ping-7","method":"ping"}`))
+	select {
+	case reply := <-transport.sent:
+		if string(reply.ID) != `"ping-7"` || reply.Error != nil {
+			t.Errorf("the ping was answered with ID %s and error %v", reply.ID, reply.Error)
+		}
+	case <-ctx.Done():
+		t.Fatal("a ping with a string ID went unanswered")
+	}
+}
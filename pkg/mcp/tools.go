@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// toolSeparator joins a server name and tool name into the function name
+// shown to the model, keeping tools from different servers apart
+const toolSeparator = "__"
+
+// readResourceTool is the function offered for servers that have resources
+const readResourceTool = "read_resource"
+
+// invalidNameChars are characters providers do not accept in function names
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// FunctionName returns the name a server's tool is offered to models under
+func FunctionName(server, tool string) string {
+	return invalidNameChars.ReplaceAllString(server+toolSeparator+tool, "_")
+}
+
+// Tools returns every connected server's tools as functions for the model.
+// Servers with resources also get a function to read them by URI.
+func (m *Manager) Tools() []models.Tool {
+	var tools []models.Tool
+	for _, s := range m.Servers {
+		s.mu.Lock()
+		for _, t := range s.Tools {
+			schema := t.InputSchema
+			if len(schema) == 0 {
+				schema = json.RawMessage(`{"type":"object","properties":{}}`)
+			}
+			tools = append(tools, models.Tool{
+				Type: "function",
+				Function: models.ToolFunction{
+					Name:        FunctionName(s.Name, t.Name),
+					Description: t.Description,
+					Parameters:  schema,
+				},
+			})
+		}
+		if len(s.Resources) > 0 {
+			tools = append(tools, resourceTool(s))
+		}
+		s.mu.Unlock()
+	}
+	return tools
+}
+
+// resourceTool describes the function that reads one of a server's
+// resources, listing the available URIs in its description
+func resourceTool(s *Server) models.Tool {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Read a resource from the %s server. Available resources:", s.Name))
+	for _, r := range s.Resources {
+		sb.WriteString("\n- " + r.URI)
+		if r.Name != "" {
+			sb.WriteString(" (" + r.Name + ")")
+		}
+		if r.Description != "" {
+			sb.WriteString(": " + r.Description)
+		}
+	}
+	return models.Tool{
+		Type: "function",
+		Function: models.ToolFunction{
+			Name:        FunctionName(s.Name, readResourceTool),
+			Description: sb.String(),
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"uri":{"type":"string","description":"URI of the resource to read"}},"required":["uri"]}`),
+		},
+	}
+}
+
+// Call runs a tool call from the model on the server that offers it
+func (m *Manager) Call(ctx context.Context, call models.ToolCall) (string, error) {
+	for _, s := range m.Servers {
+		s.mu.Lock()
+		tools, hasResources := s.Tools, len(s.Resources) > 0
+		s.mu.Unlock()
+
+		for _, t := range tools {
+			if FunctionName(s.Name, t.Name) == call.Function.Name {
+				return s.CallTool(ctx, t.Name, call.Function.ArgumentsJSON())
+			}
+		}
+		if hasResources && FunctionName(s.Name, readResourceTool) == call.Function.Name {
+			var args struct {
+				URI string `json:"uri"`
+			}
+			if err := json.Unmarshal(call.Function.ArgumentsJSON(), &args); err != nil || args.URI == "" {
+				return "", fmt.Errorf("missing resource uri")
+			}
+			return s.ReadResource(ctx, args.URI)
+		}
+	}
+	return "", fmt.Errorf("unknown tool %s", call.Function.Name)
+}
@@ -0,0 +1,199 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// transport carries JSON-RPC messages to a server. Incoming messages are
+// passed to the dispatch function given when the transport was started.
+type transport interface {
+	send(msg []byte) error
+	close() error
+}
+
+// stdioTransport talks to a server process over its stdin and stdout, one
+// JSON message per line
+type stdioTransport struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	mu    sync.Mutex
+}
+
+// startStdio launches the server command
+func startStdio(cfg utils.MCPServerConfig, dispatch func([]byte), closed func(error)) (*stdioTransport, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", cfg.Command, err)
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+		for scanner.Scan() {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				dispatch(append([]byte(nil), line...))
+			}
+		}
+		err := scanner.Err()
+		if err == nil {
+			err = fmt.Errorf("server %s exited", cfg.Command)
+		}
+		closed(err)
+	}()
+
+	return &stdioTransport{cmd: cmd, stdin: stdin}, nil
+}
+
+func (t *stdioTransport) send(msg []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.stdin.Write(append(msg, '\n'))
+	return err
+}
+
+// close shuts stdin so the server can exit on its own, killing it if it
+// has not done so shortly after
+func (t *stdioTransport) close() error {
+	t.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- t.cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.cmd.Process.Kill()
+		<-done
+	}
+	return nil
+}
+
+// HTTPTransport carries the requests to SSE servers. It is set to the
+// transport built from the proxy, CA certificate and timeout settings,
+// without the recording of --record, which holds only provider traffic.
+var HTTPTransport http.RoundTripper = http.DefaultTransport
+
+// sseTransport receives messages from a server-sent events stream and sends
+// requests by POSTing them to the endpoint the server announces
+type sseTransport struct {
+	endpoint string
+	client   *http.Client
+	cancel   context.CancelFunc
+}
+
+// startSSE opens the event stream and waits for the server's endpoint event
+func startSSE(ctx context.Context, rawURL string, dispatch func([]byte), closed func(error)) (*sseTransport, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL: %w", err)
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	// Servers may send nothing for long stretches between requests
+	req, err := http.NewRequestWithContext(api.WithoutReadTimeout(streamCtx), "GET", rawURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	client := &http.Client{Transport: HTTPTransport}
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to connect to %s: %w", rawURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("%s returned status code %d", rawURL, resp.StatusCode)
+	}
+
+	endpoints := make(chan string, 1)
+	go func() {
+		defer resp.Body.Close()
+		reader := bufio.NewReader(resp.Body)
+		event, data := "message", ""
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				closed(fmt.Errorf("event stream closed: %w", err))
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case line == "":
+				// A blank line ends the event
+				if event == "endpoint" {
+					if ref, err := base.Parse(strings.TrimSpace(data)); err == nil {
+						select {
+						case endpoints <- ref.String():
+						default:
+						}
+					}
+				} else if data != "" {
+					dispatch([]byte(data))
+				}
+				event, data = "message", ""
+			case strings.HasPrefix(line, "event:"):
+				event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				if data != "" {
+					data += "\n"
+				}
+				data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+			}
+		}
+	}()
+
+	select {
+	case endpoint := <-endpoints:
+		return &sseTransport{endpoint: endpoint, client: client, cancel: cancel}, nil
+	case <-ctx.Done():
+		cancel()
+		return nil, fmt.Errorf("no endpoint event from %s: %w", rawURL, ctx.Err())
+	}
+}
+
+func (t *sseTransport) send(msg []byte) error {
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status code %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func (t *sseTransport) close() error {
+	t.cancel()
+	return nil
+}
@@ -1,15 +1,103 @@
 package models
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
 // Model represents an Ollama model
 type Model struct {
-	Name    string `json:"name"`
-	Size    int64  `json:"size"`
-	Digest  string `json:"digest"`
-	Details struct {
-		Family  string `json:"family"`
-		Format  string `json:"format"`
-		Context int    `json:"context"`
-	} `json:"details"`
+	Name    string       `json:"name"`
+	Size    int64        `json:"size"`
+	Digest  string       `json:"digest"`
+	Details ModelDetails `json:"details"`
+	// Provider tags the model in a list merged from several providers
+	Provider string `json:"-"`
+}
+
+// ModelDetails describes a model's architecture and quantization
+type ModelDetails struct {
+	Family            string `json:"family"`
+	Format            string `json:"format"`
+	Context           int    `json:"context"`
+	ParameterSize     string `json:"parameter_size,omitempty"`
+	QuantizationLevel string `json:"quantization_level,omitempty"`
+}
+
+// RunningModel represents a model currently loaded in memory by Ollama
+type RunningModel struct {
+	Name      string    `json:"name"`
+	Model     string    `json:"model"`
+	Size      int64     `json:"size"`
+	SizeVRAM  int64     `json:"size_vram"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RunningModelsResponse represents the response from the Ollama API for listing loaded models
+type RunningModelsResponse struct {
+	Models []RunningModel `json:"models"`
+}
+
+// CreateRequest represents a request to create a model from a base model
+type CreateRequest struct {
+	Model      string                 `json:"model"`
+	From       string                 `json:"from"`
+	System     string                 `json:"system,omitempty"`
+	Template   string                 `json:"template,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Stream     bool                   `json:"stream"`
+}
+
+// CopyRequest represents a request to copy a model under a new name
+type CopyRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// DeleteRequest represents a request to delete a model. Older Ollama versions
+// read the name field instead of model.
+type DeleteRequest struct {
+	Model string `json:"model"`
+	Name  string `json:"name,omitempty"`
+}
+
+// ProgressResponse represents a streamed status update from the Ollama API
+type ProgressResponse struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// EmbedRequest represents a request to the Ollama embeddings API
+type EmbedRequest struct {
+	Model     string      `json:"model"`
+	Input     []string    `json:"input"`
+	KeepAlive interface{} `json:"keep_alive,omitempty"`
+}
+
+// EmbedResponse represents a response from the Ollama embeddings API
+type EmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+// OpenAIEmbeddingRequest represents a request to the OpenAI embeddings API
+type OpenAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// OpenAIEmbeddingResponse represents a response from the OpenAI embeddings API
+type OpenAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
 }
 
 // ModelListResponse represents the response from the Ollama API for listing models
@@ -29,6 +117,21 @@ type OpenAIModel struct {
 	Object  string `json:"object"`
 	Created int    `json:"created"`
 	OwnedBy string `json:"owned_by"`
+	// MaxContextLength and Capabilities are only listed by Mistral
+	MaxContextLength int                      `json:"max_context_length,omitempty"`
+	Capabilities     *OpenAIModelCapabilities `json:"capabilities,omitempty"`
+	// SupportsChat is only listed by Fireworks
+	SupportsChat *bool `json:"supports_chat,omitempty"`
+	// Type, such as "chat", "language" or "embedding", is only listed by
+	// Together
+	Type string `json:"type,omitempty"`
+	// ContextLength is listed by Together and Fireworks
+	ContextLength int `json:"context_length,omitempty"`
+}
+
+// OpenAIModelCapabilities lists what a Mistral model can do
+type OpenAIModelCapabilities struct {
+	CompletionChat bool `json:"completion_chat"`
 }
 
 // OpenAIChatRequest represents a request to the OpenAI chat completions API
@@ -36,8 +139,97 @@ type OpenAIChatRequest struct {
 	Model       string        `json:"model"`
 	Messages    []ChatMessage `json:"messages"`
 	Stream      bool          `json:"stream"`
-	Temperature float64       `json:"temperature,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Tools       []Tool        `json:"tools,omitempty"`
+
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"`
+	// SafePrompt is Mistral's option to prepend its safety system prompt
+	SafePrompt bool     `json:"safe_prompt,omitempty"`
+	Stop       []string `json:"stop,omitempty"`
+	Seed       *int     `json:"seed,omitempty"`
+	// Extra are added to the body as is, replacing the fields above of the
+	// same name, for parameters of a particular provider
+	Extra map[string]interface{} `json:"-"`
+}
+
+// OpenAIContentPart is a piece of an OpenAI message that mixes text and images
+type OpenAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *OpenAIImageURL `json:"image_url,omitempty"`
+}
+
+// OpenAIImageURL points an image content part at its data
+type OpenAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON sends messages with images the way OpenAI expects them: as a
+// list of content parts with the images inlined as data URLs, adding the
+// extra fields to the body
+func (r OpenAIChatRequest) MarshalJSON() ([]byte, error) {
+	type openAIMessage struct {
+		Role       string      `json:"role"`
+		Content    interface{} `json:"content"`
+		ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+		ToolCallID string      `json:"tool_call_id,omitempty"`
+	}
+	type request OpenAIChatRequest
+	out := struct {
+		request
+		Messages []openAIMessage `json:"messages"`
+	}{request: request(r)}
+
+	for _, m := range r.Messages {
+		msg := openAIMessage{Role: m.Role, Content: m.Content, ToolCalls: m.ToolCalls, ToolCallID: m.ToolCallID}
+		if len(m.Images) > 0 {
+			parts := []OpenAIContentPart{{Type: "text", Text: m.Content}}
+			for _, image := range m.Images {
+				parts = append(parts, OpenAIContentPart{
+					Type:     "image_url",
+					ImageURL: &OpenAIImageURL{URL: "data:" + imageMediaType(image) + ";base64," + image},
+				})
+			}
+			msg.Content = parts
+		}
+		out.Messages = append(out.Messages, msg)
+	}
+	data, err := json.Marshal(out)
+	if err != nil || len(r.Extra) == 0 {
+		return data, err
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	for key, value := range r.Extra {
+		body[key] = value
+	}
+	return json.Marshal(body)
+}
+
+// imageMediaType sniffs the type of a base64-encoded image
+func imageMediaType(image string) string {
+	head, _ := base64.StdEncoding.DecodeString(image[:min(len(image), 64)])
+	if mediaType := http.DetectContentType(head); strings.HasPrefix(mediaType, "image/") {
+		return mediaType
+	}
+	return "image/png"
+}
+
+// OpenAIResponseFormat constrains an OpenAI response to JSON, optionally
+// following a schema
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// OpenAIJSONSchema names the schema an OpenAI response must follow
+type OpenAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
 }
 
 // OpenAIChatResponse represents a response from the OpenAI chat completions API
@@ -56,6 +248,8 @@ type OpenAIChatStreamResponse struct {
 	Created int64          `json:"created"`
 	Model   string         `json:"model"`
 	Choices []StreamChoice `json:"choices"`
+	// Citations are the pages Perplexity's answer cites
+	Citations []string `json:"citations,omitempty"`
 }
 
 // Choice represents a choice in an OpenAI chat completion response
@@ -74,45 +268,294 @@ type StreamChoice struct {
 
 // Delta represents the delta in a streaming response
 type Delta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role      string          `json:"role,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"`
+	// ReasoningContent is the reasoning of models like deepseek-reasoner,
+	// streamed before the answer
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// ToolCallDelta is a fragment of a tool call streamed by the OpenAI API.
+// Fragments with the same index belong to the same call.
+type ToolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
 }
 
 // GenerateRequest represents a request to generate text from a model
 type GenerateRequest struct {
-	Model    string        `json:"model"`
-	Prompt   string        `json:"prompt"`
-	Stream   bool          `json:"stream"`
-	Context  []int         `json:"context,omitempty"`
-	Messages []ChatMessage `json:"messages,omitempty"`
+	Model     string                 `json:"model"`
+	Prompt    string                 `json:"prompt"`
+	Images    []string               `json:"images,omitempty"`
+	System    string                 `json:"system,omitempty"`
+	Stream    bool                   `json:"stream"`
+	Context   []int                  `json:"context,omitempty"`
+	Messages  []ChatMessage          `json:"messages,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	KeepAlive interface{}            `json:"keep_alive,omitempty"`
+	Format    json.RawMessage        `json:"format,omitempty"`
 }
 
 // ChatMessage represents a message in a chat conversation
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// Images are base64-encoded images sent with the message to vision models
+	Images []string `json:"images,omitempty"`
+}
+
+// Tool describes a function a model may call
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is the name, description and JSON Schema parameters of a tool
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// ToolCall is a model's request to call a tool
+type ToolCall struct {
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the called tool and its arguments. Ollama sends the
+// arguments as a JSON object, OpenAI as a string containing JSON; the raw
+// form is kept so the call can be sent back unchanged.
+type ToolCallFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ArgumentsJSON returns the call's arguments as a JSON object
+func (f ToolCallFunction) ArgumentsJSON() json.RawMessage {
+	if len(f.Arguments) > 0 && f.Arguments[0] == '"' {
+		var s string
+		if err := json.Unmarshal(f.Arguments, &s); err == nil {
+			return json.RawMessage(s)
+		}
+	}
+	if len(f.Arguments) == 0 {
+		return json.RawMessage("{}")
+	}
+	return f.Arguments
+}
+
+// ChatRequest represents a request to the Ollama chat API
+type ChatRequest struct {
+	Model     string                 `json:"model"`
+	Messages  []ChatMessage          `json:"messages"`
+	Stream    bool                   `json:"stream"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	KeepAlive interface{}            `json:"keep_alive,omitempty"`
+	Tools     []Tool                 `json:"tools,omitempty"`
+	Format    json.RawMessage        `json:"format,omitempty"`
+}
+
+// ChatResponse represents a streamed response from the Ollama chat API
+type ChatResponse struct {
+	Model     string      `json:"model"`
+	CreatedAt string      `json:"created_at"`
+	Message   ChatMessage `json:"message"`
+	Done      bool        `json:"done"`
+	// DoneReason is why the model stopped, "length" at the token limit
+	DoneReason string `json:"done_reason,omitempty"`
+	ResponseMetrics
 }
 
 // GenerateResponse represents a response from the Ollama API for text generation
 type GenerateResponse struct {
-	Model     string `json:"model"`
-	Response  string `json:"response"`
-	Done      bool   `json:"done"`
-	CreatedAt string `json:"created_at"`
-	Context   []int  `json:"context,omitempty"`
+	Model    string `json:"model"`
+	Response string `json:"response"`
+	Done     bool   `json:"done"`
+	// DoneReason is why the model stopped, "length" at the token limit
+	DoneReason string `json:"done_reason,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	Context    []int  `json:"context,omitempty"`
+	ResponseMetrics
+}
+
+// ResponseMetrics are the timings and token counts Ollama reports with the
+// last chunk of a response. Durations are sent in nanoseconds.
+type ResponseMetrics struct {
+	TotalDuration      time.Duration `json:"total_duration,omitempty"`
+	LoadDuration       time.Duration `json:"load_duration,omitempty"`
+	PromptEvalCount    int           `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration time.Duration `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int           `json:"eval_count,omitempty"`
+	EvalDuration       time.Duration `json:"eval_duration,omitempty"`
+}
+
+// Add sums the metrics of a request with those of an earlier one, as for the
+// several requests of a response that called tools
+func (r *ResponseMetrics) Add(other ResponseMetrics) {
+	r.TotalDuration += other.TotalDuration
+	r.LoadDuration += other.LoadDuration
+	r.PromptEvalCount += other.PromptEvalCount
+	r.PromptEvalDuration += other.PromptEvalDuration
+	r.EvalCount += other.EvalCount
+	r.EvalDuration += other.EvalDuration
+}
+
+// ResponseMetadata is what Ollama reports about a finished response
+type ResponseMetadata struct {
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+	ResponseMetrics
+}
+
+// NewResponseMetadata returns the metadata of the last chunk of a response;
+// createdAt is the RFC 3339 time Ollama sends
+func NewResponseMetadata(model, createdAt string, metrics ResponseMetrics) *ResponseMetadata {
+	created, _ := time.Parse(time.RFC3339Nano, createdAt)
+	return &ResponseMetadata{Model: model, CreatedAt: created, ResponseMetrics: metrics}
+}
+
+const (
+	// RoleUser marks messages written by the user
+	RoleUser = "user"
+	// RoleAssistant marks messages generated by a model
+	RoleAssistant = "assistant"
+	// RoleInfo marks local notices that are never sent to a model
+	RoleInfo = "info"
+)
+
+// Message is a single entry in the conversation transcript. The UI renders
+// it, sessions save it as is, and the history sent to providers is built
+// from it.
+type Message struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Model     string    `json:"model,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Tokens    int       `json:"tokens,omitempty"`
+	// Format is the JSON format the response was requested in, if any
+	Format json.RawMessage `json:"format,omitempty"`
+	// Attachments are the sources attached to a prompt
+	Attachments []string `json:"attachments,omitempty"`
+	// Metadata is what Ollama reported about the response
+	Metadata *ResponseMetadata `json:"metadata,omitempty"`
+	// Duration is how long the response took, from sending the prompt to
+	// its last token
+	Duration time.Duration `json:"duration,omitempty"`
+	// Request is the request the response was generated from, recorded in
+	// deterministic mode so it can be replayed
+	Request *RecordedRequest `json:"request,omitempty"`
+	// CutOff is why the response ended before the model finished it, one
+	// of the CutOff reasons
+	CutOff string `json:"cut_off,omitempty"`
+}
+
+// Reasons a response was cut off before the model finished it
+const (
+	// CutOffStopped marks a response the user stopped
+	CutOffStopped = "stopped"
+	// CutOffLength marks a response that reached the token limit
+	CutOffLength = "length"
+	// CutOffTimeout marks a response that reached the time limit
+	CutOffTimeout = "timeout"
+)
+
+// Usage is the tokens of an exchange as the provider reported them
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Usage returns the tokens the provider reported for a response, if it did
+func (m Message) Usage() (Usage, bool) {
+	if m.Metadata == nil || m.Metadata.EvalCount == 0 {
+		return Usage{}, false
+	}
+	return Usage{PromptTokens: m.Metadata.PromptEvalCount, CompletionTokens: m.Metadata.EvalCount}, true
+}
+
+// RecordedRequest is a request as it was sent to a provider
+type RecordedRequest struct {
+	Provider string          `json:"provider,omitempty"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// Reasoning models such as deepseek-r1 think aloud between these tags before
+// answering
+const (
+	ReasoningStart = "<think>"
+	ReasoningEnd   = "</think>"
+)
+
+// SplitReasoning separates the reasoning a model wrote before its answer.
+// closed is false while the reasoning is still being streamed; content
+// without reasoning is returned as the answer.
+func SplitReasoning(content string) (reasoning, answer string, closed bool) {
+	trimmed := strings.TrimLeft(content, " \t\r\n")
+	if !strings.HasPrefix(trimmed, ReasoningStart) {
+		return "", content, true
+	}
+	rest := trimmed[len(ReasoningStart):]
+	end := strings.Index(rest, ReasoningEnd)
+	if end < 0 {
+		return strings.TrimSpace(rest), "", false
+	}
+	return strings.TrimSpace(rest[:end]), strings.TrimLeft(rest[end+len(ReasoningEnd):], " \t\r\n"), true
+}
+
+// StripReasoning returns content without the reasoning before the answer
+func StripReasoning(content string) string {
+	_, answer, _ := SplitReasoning(content)
+	return answer
+}
+
+// Answer returns the message content without a model's reasoning
+func (m Message) Answer() string {
+	if m.Role != RoleAssistant {
+		return m.Content
+	}
+	return StripReasoning(m.Content)
+}
+
+// Attachment represents a file or URL whose contents are included with a prompt
+type Attachment struct {
+	Name    string
+	Source  string
+	Content string
+	// Image holds the data of an image attachment, which is sent to vision
+	// models instead of being included in the prompt text
+	Image []byte
 }
 
 // ListItem represents an item in the model selection list
 type ListItem struct {
 	Name    string
 	Details string
+	// Provider tags models in a list merged from several providers
+	Provider string
 }
 
 // Title returns the name of the model for the list item
 func (i ListItem) Title() string { return i.Name }
 
-// Description returns the details of the model for the list item
-func (i ListItem) Description() string { return i.Details }
+// Description returns the details of the model for the list item, led by
+// its provider in a merged list
+func (i ListItem) Description() string {
+	if i.Provider != "" {
+		return i.Provider + " · " + i.Details
+	}
+	return i.Details
+}
 
 // FilterValue returns the value to use for filtering the list
 func (i ListItem) FilterValue() string { return i.Name }
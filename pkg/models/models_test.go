@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMessageLegacyDecoding(t *testing.T) {
+	// Sessions saved before messages carried metadata hold only the text
+	legacy := `{"role":"assistant","content":"<think>plan</think>Hello","model":"llama3","created_at":"2025-03-01T10:00:00Z","tokens":12}`
+	var msg Message
+	if err := json.Unmarshal([]byte(legacy), &msg); err != nil {
+		t.Fatal(err)
+	}
+	want := Message{
+		Role:      RoleAssistant,
+		Content:   "<think>plan</think>Hello",
+		Model:     "llama3",
+		CreatedAt: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+		Tokens:    12,
+	}
+	if !reflect.DeepEqual(msg, want) {
+		t.Errorf("decoded %+v, want %+v", msg, want)
+	}
+	if _, ok := msg.Usage(); ok {
+		t.Error("a legacy message reports usage")
+	}
+	if msg.Answer() != "Hello" {
+		t.Errorf("Answer() = %q, want Hello", msg.Answer())
+	}
+
+	// The oldest prompts have no model or time
+	var prompt Message
+	if err := json.Unmarshal([]byte(`{"role":"user","content":"Hi"}`), &prompt); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(prompt, Message{Role: RoleUser, Content: "Hi"}) {
+		t.Errorf("decoded %+v", prompt)
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	created := time.Date(2025, 6, 2, 8, 30, 0, 0, time.UTC)
+	msg := Message{
+		Role:        RoleAssistant,
+		Content:     "Hello",
+		Model:       "gpt-4o",
+		CreatedAt:   created,
+		Tokens:      34,
+		Format:      json.RawMessage(`{"type":"object"}`),
+		Attachments: []string{"main.go", "https://example.com"},
+		Metadata: &ResponseMetadata{
+			Model:     "gpt-4o",
+			CreatedAt: created,
+			ResponseMetrics: ResponseMetrics{
+				TotalDuration:   2 * time.Second,
+				PromptEvalCount: 12,
+				EvalCount:       34,
+			},
+		},
+		Duration: 2500 * time.Millisecond,
+		Request:  &RecordedRequest{Provider: "openai", URL: "https://api.openai.com/v1/chat/completions", Body: json.RawMessage(`{"seed":1}`)},
+		CutOff:   CutOffLength,
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, msg) {
+		t.Errorf("round trip of\n%s\ngave %+v, want %+v", data, decoded, msg)
+	}
+
+	usage, ok := decoded.Usage()
+	if !ok || usage != (Usage{PromptTokens: 12, CompletionTokens: 34}) {
+		t.Errorf("Usage() = %+v, %v, want 12 prompt and 34 completion tokens", usage, ok)
+	}
+}
+
+func TestMessageOmitsEmptyFields(t *testing.T) {
+	data, err := json.Marshal(Message{Role: RoleUser, Content: "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"model", "tokens", "format", "attachments", "metadata", "duration", "request", "cut_off"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("an unset %s is written: %s", name, data)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// usage is the tokens of a response in the shape OpenAI reports them
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// completion is a whole chat completions response, with its usage when the
+// provider reported it
+type completion struct {
+	models.OpenAIChatResponse
+	Usage *usage `json:"usage,omitempty"`
+}
+
+// responder writes a response as it streams in: as server-sent chunks when
+// the request asked for a stream, else all at once when it is over
+type responder struct {
+	w       http.ResponseWriter
+	stream  bool
+	id      string
+	model   string
+	created int64
+	started bool
+}
+
+func newResponder(w http.ResponseWriter, stream bool, id, model string, created time.Time) *responder {
+	return &responder{w: w, stream: stream, id: id, model: model, created: created.Unix()}
+}
+
+// chunk sends a streamed chunk, starting the stream with the first one
+func (r *responder) chunk(delta models.Delta, reason *string) {
+	if !r.started {
+		r.started = true
+		r.w.Header().Set("Content-Type", "text/event-stream")
+		r.w.Header().Set("Cache-Control", "no-cache")
+		delta.Role = models.RoleAssistant
+	}
+	data, _ := json.Marshal(models.OpenAIChatStreamResponse{
+		ID:      r.id,
+		Object:  "chat.completion.chunk",
+		Created: r.created,
+		Model:   r.model,
+		Choices: []models.StreamChoice{{Delta: delta, FinishReason: reason}},
+	})
+	fmt.Fprintf(r.w, "data: %s\n\n", data)
+	if f, ok := r.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// token passes on a token of the response, when streaming
+func (r *responder) token(text string) {
+	if r.stream {
+		r.chunk(models.Delta{Content: text}, nil)
+	}
+}
+
+// finish ends a response that finished, with why the model stopped
+func (r *responder) finish(text, reason string, metadata *models.ResponseMetadata) {
+	if r.stream {
+		r.chunk(models.Delta{}, &reason)
+		fmt.Fprint(r.w, "data: [DONE]\n\n")
+		return
+	}
+
+	resp := completion{OpenAIChatResponse: models.OpenAIChatResponse{
+		ID:      r.id,
+		Object:  "chat.completion",
+		Created: r.created,
+		Model:   r.model,
+		Choices: []models.Choice{{
+			Message:      models.ChatMessage{Role: models.RoleAssistant, Content: text},
+			FinishReason: reason,
+		}},
+	}}
+	if metadata != nil && metadata.EvalCount > 0 {
+		resp.Usage = &usage{
+			PromptTokens:     metadata.PromptEvalCount,
+			CompletionTokens: metadata.EvalCount,
+			TotalTokens:      metadata.PromptEvalCount + metadata.EvalCount,
+		}
+	}
+	r.w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(r.w).Encode(resp)
+}
+
+// fail ends a response that failed: with an error status when nothing was
+// sent yet, else with an error event in the stream
+func (r *responder) fail(err error) {
+	if !r.started {
+		writeError(r.w, http.StatusBadGateway, err.Error())
+		return
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]string{"message": err.Error(), "type": "provider_error"},
+	})
+	fmt.Fprintf(r.w, "data: %s\n\n", data)
+}
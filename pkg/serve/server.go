@@ -0,0 +1,332 @@
+// Package serve answers OpenAI-style chat completions requests from other
+// tools through the provider and model selected in the interface.
+package serve
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// maxRequestBytes limits the body of a request
+const maxRequestBytes = 32 * 1024 * 1024
+
+// Route is where a request goes: a client of the selected provider, with no
+// conversation of its own, and the selected model
+type Route struct {
+	Client   *api.Client
+	Provider string
+	Model    string
+}
+
+// Exchange is a request answered through the server
+type Exchange struct {
+	Provider string
+	Model    string
+	// Messages are those of the request, the last one the prompt answered
+	Messages []models.ChatMessage
+	Response string
+	Metadata *models.ResponseMetadata
+	Started  time.Time
+	Duration time.Duration
+	// Err is why the response failed, and Stopped is set when the client
+	// went away before it finished
+	Err     error
+	Stopped bool
+}
+
+// Server answers chat completions requests on an address until it is closed
+type Server struct {
+	// Route returns where to send a request, or why it can't be answered
+	Route func(ctx context.Context) (Route, error)
+	// Record is called with every exchange once it is over
+	Record func(Exchange)
+	// Token, when set, must be sent by requests as a bearer token
+	Token string
+
+	listener net.Listener
+	http     *http.Server
+}
+
+// Start listens on addr and serves requests in the background. A bare port
+// such as ":8080" listens on 127.0.0.1 only; any address other than a
+// loopback one needs a token, since whoever can reach the server spends the
+// provider's keys.
+func Start(addr, token string, route func(ctx context.Context) (Route, error), record func(Exchange)) (*Server, error) {
+	addr, err := ListenAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	if token == "" && !isLoopback(host) {
+		return nil, fmt.Errorf("listening on %s, which other machines can reach, needs a token (set %sSERVE_TOKEN)", addr, utils.EnvPrefix)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{Route: route, Record: record, Token: token, listener: listener}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", s.handleChatCompletions)
+	mux.HandleFunc("/v1/models", s.handleModels)
+	s.http = &http.Server{Handler: s.authorize(mux), ReadHeaderTimeout: 10 * time.Second}
+	go s.http.Serve(listener)
+	return s, nil
+}
+
+// ListenAddr returns the address to listen on for addr, with the host of a
+// bare port set to 127.0.0.1
+func ListenAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q (use :8080 or host:8080)", addr)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// isLoopback reports whether host only accepts connections from this machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requestHost returns the host name of a request's Host header, without
+// the port
+func requestHost(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.Host); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(r.Host, "["), "]")
+}
+
+// authorize refuses requests without the server's token, when it has one.
+// Requests from web pages, which send an Origin header, are refused too, and
+// without a token so are requests for a host other than this machine: a page
+// whose name was rebound to 127.0.0.1 would otherwise reach the server.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, "requests from web pages are not accepted")
+			return
+		}
+		if s.Token == "" && !isLoopback(requestHost(r)) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("host %q is not this machine; set a token to serve other hosts", r.Host))
+			return
+		}
+		if s.Token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "invalid or missing bearer token")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server, ending the responses in progress
+func (s *Server) Close() error {
+	return s.http.Close()
+}
+
+// chatRequest is the part of a chat completions request the server uses
+type chatRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	Stream      bool            `json:"stream"`
+	Temperature *float64        `json:"temperature"`
+	Stop        json.RawMessage `json:"stop"`
+	Seed        *int            `json:"seed"`
+}
+
+// messages converts the messages of the request, with the text of content
+// parts joined and images taken from data URLs
+func (r chatRequest) messages() ([]models.ChatMessage, error) {
+	messages := make([]models.ChatMessage, 0, len(r.Messages))
+	for _, msg := range r.Messages {
+		message := models.ChatMessage{Role: msg.Role}
+		var text string
+		var parts []models.OpenAIContentPart
+		switch {
+		case len(msg.Content) == 0 || string(msg.Content) == "null":
+		case json.Unmarshal(msg.Content, &text) == nil:
+			message.Content = text
+		case json.Unmarshal(msg.Content, &parts) == nil:
+			var texts []string
+			for _, part := range parts {
+				switch {
+				case part.Type == "text":
+					texts = append(texts, part.Text)
+				case part.Type == "image_url" && part.ImageURL != nil:
+					_, data, ok := strings.Cut(part.ImageURL.URL, ";base64,")
+					if !ok {
+						return nil, errors.New("only images inlined as base64 data URLs are supported")
+					}
+					message.Images = append(message.Images, data)
+				}
+			}
+			message.Content = strings.Join(texts, "\n")
+		default:
+			return nil, fmt.Errorf("invalid content of a %s message", msg.Role)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// stop returns the stop sequences, given as a string or a list
+func (r chatRequest) stop() []string {
+	var one string
+	if json.Unmarshal(r.Stop, &one) == nil && one != "" {
+		return []string{one}
+	}
+	var list []string
+	_ = json.Unmarshal(r.Stop, &list)
+	return list
+}
+
+// writeError answers with an error in the shape OpenAI clients expect
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"message": message, "type": "invalid_request_error"},
+	})
+}
+
+// handleModels lists the selected model, the only one requests go to
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	route, err := s.Route(r.Context())
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.OpenAIModelResponse{
+		Object: "list",
+		Data:   []models.OpenAIModel{{ID: route.Model, Object: "model", OwnedBy: route.Provider}},
+	})
+}
+
+// handleChatCompletions answers a chat completions request with the
+// selected model, streamed as server-sent events when asked to
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "the request body must be application/json")
+		return
+	}
+	var req chatRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	messages, err := req.messages()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(messages) == 0 || messages[len(messages)-1].Role != "user" {
+		writeError(w, http.StatusBadRequest, "the last message must be from the user")
+		return
+	}
+	route, err := s.Route(r.Context())
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	// The system messages become the system prompt, the others before the
+	// prompt the conversation so far
+	client := route.Client
+	var system []string
+	var history []models.ChatMessage
+	for _, msg := range messages[:len(messages)-1] {
+		if msg.Role == "system" || msg.Role == "developer" {
+			system = append(system, msg.Content)
+		} else {
+			history = append(history, msg)
+		}
+	}
+	prompt := messages[len(messages)-1]
+	client.SystemPrompt = strings.Join(system, "\n\n")
+	client.SetHistory(history)
+	client.Images = prompt.Images
+	if req.Temperature != nil {
+		client.Temperature = req.Temperature
+	}
+	if stop := req.stop(); len(stop) > 0 {
+		client.Stop = stop
+	}
+	if req.Seed != nil {
+		client.Seed = req.Seed
+	}
+
+	exchange := Exchange{Provider: route.Provider, Model: route.Model, Messages: messages, Started: time.Now()}
+	defer func() {
+		exchange.Duration = time.Since(exchange.Started)
+		if s.Record != nil {
+			s.Record(exchange)
+		}
+	}()
+
+	id := fmt.Sprintf("chatcmpl-%d", exchange.Started.UnixNano())
+	respond := newResponder(w, req.Stream, id, route.Model, exchange.Started)
+	var sb strings.Builder
+	reason := "stop"
+	for event := range client.Stream(r.Context(), route.Model, prompt.Content) {
+		switch event := event.(type) {
+		case api.TokenEvent:
+			sb.WriteString(event.Text)
+			respond.token(event.Text)
+		case api.UsageEvent:
+			metadata := event.Metadata
+			exchange.Metadata = &metadata
+		case api.DoneEvent:
+			if event.Reason == api.DoneReasonLength {
+				reason = "length"
+			}
+		case api.ErrorEvent:
+			exchange.Err = event.Err
+		}
+	}
+	exchange.Response = sb.String()
+	exchange.Stopped = r.Context().Err() != nil
+
+	switch {
+	case exchange.Stopped:
+	case exchange.Err != nil:
+		respond.fail(exchange.Err)
+	default:
+		respond.finish(sb.String(), reason, exchange.Metadata)
+	}
+}
@@ -0,0 +1,139 @@
+package serve
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestListenAddr(t *testing.T) {
+	tests := map[string]string{
+		":8080":          "127.0.0.1:8080",
+		"0.0.0.0:8080":   "0.0.0.0:8080",
+		"localhost:9000": "localhost:9000",
+		"[::1]:8080":     "[::1]:8080",
+	}
+	for addr, want := range tests {
+		if got, err := ListenAddr(addr); err != nil || got != want {
+			t.Errorf("ListenAddr(%q) = %q, %v, want %q", addr, got, err, want)
+		}
+	}
+	if _, err := ListenAddr("8080"); err == nil {
+		t.Error("ListenAddr accepted an address without a colon")
+	}
+}
+
+func noRoute(context.Context) (Route, error) { return Route{}, nil }
+
+func TestStartNeedsTokenBeyondLoopback(t *testing.T) {
+	if s, err := Start("0.0.0.0:0", "", noRoute, nil); err == nil {
+		s.Close()
+		t.Fatal("Start listened on every interface without a token")
+	}
+	s, err := Start(":0", "", noRoute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if host := s.Addr(); !strings.HasPrefix(host, "127.0.0.1:") {
+		t.Errorf("a bare port listens on %s, want 127.0.0.1", host)
+	}
+}
+
+func TestTokenRequired(t *testing.T) {
+	s, err := Start("127.0.0.1:0", "secret", noRoute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "secret": http.StatusOK} {
+		req, _ := http.NewRequest(http.MethodGet, "http://"+s.Addr()+"/v1/models", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("token %q: status %d, want %d", token, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestRefusesBrowserRequests(t *testing.T) {
+	s, err := Start("127.0.0.1:0", "", noRoute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	_, port, _ := net.SplitHostPort(s.Addr())
+
+	tests := []struct {
+		name        string
+		host        string
+		origin      string
+		contentType string
+		want        int
+	}{
+		{name: "json", contentType: "application/json", want: http.StatusBadRequest},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", want: http.StatusBadRequest},
+		{name: "form", contentType: "application/x-www-form-urlencoded", want: http.StatusUnsupportedMediaType},
+		{name: "plain text", contentType: "text/plain", want: http.StatusUnsupportedMediaType},
+		{name: "no content type", want: http.StatusUnsupportedMediaType},
+		{name: "origin", origin: "https://example.com", contentType: "application/json", want: http.StatusForbidden},
+		{name: "localhost", host: "localhost:" + port, contentType: "application/json", want: http.StatusBadRequest},
+		{name: "ipv6 loopback", host: "[::1]:" + port, contentType: "application/json", want: http.StatusBadRequest},
+		{name: "rebound name", host: "attacker.example:" + port, contentType: "application/json", want: http.StatusForbidden},
+		{name: "rebound name without port", host: "attacker.example", contentType: "application/json", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+s.Addr()+"/v1/chat/completions", strings.NewReader(`{"messages":[]}`))
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestTokenServesOtherHosts(t *testing.T) {
+	s, err := Start("127.0.0.1:0", "secret", noRoute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for origin, want := range map[string]int{"": http.StatusOK, "https://example.com": http.StatusForbidden} {
+		req, _ := http.NewRequest(http.MethodGet, "http://"+s.Addr()+"/v1/models", nil)
+		req.Host = "gpu-box.lan:8080"
+		req.Header.Set("Authorization", "Bearer secret")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("origin %q: status %d, want %d", origin, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package session
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// EmbedFunc returns one embedding vector per input text
+type EmbedFunc func(input []string) ([][]float64, error)
+
+// embedBatchSize limits how many messages are embedded per request
+const embedBatchSize = 32
+
+// EmbeddingIndex caches message embeddings by content so each message is
+// only embedded once per embedding model
+type EmbeddingIndex struct {
+	Path    string               `json:"-"`
+	Model   string               `json:"model"`
+	Vectors map[string][]float64 `json:"vectors"`
+
+	// db is the database of an index kept by a SQLite store, and stored the
+	// keys of the vectors already written to it
+	db     *sql.DB
+	stored map[string]bool
+}
+
+// Snippet is a stored message that matched a recall query
+type Snippet struct {
+	SessionID string
+	Title     string
+	Message   models.Message
+	Score     float64
+}
+
+// LoadEmbeddingIndex reads the index for model from dir. A missing index, or
+// one built with a different model, starts empty.
+func LoadEmbeddingIndex(dir, model string) (*EmbeddingIndex, error) {
+	index := &EmbeddingIndex{
+		// Not a .json file, so the store does not list it as a session
+		Path:    filepath.Join(dir, "embeddings.index"),
+		Model:   model,
+		Vectors: map[string][]float64{},
+	}
+
+	data, err := os.ReadFile(index.Path)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding index: %w", err)
+	}
+
+	var stored EmbeddingIndex
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Model != model {
+		return index, nil
+	}
+	if stored.Vectors != nil {
+		index.Vectors = stored.Vectors
+	}
+	return index, nil
+}
+
+// LoadEmbeddingIndex reads the store's index for model, from its database or
+// from the file in its directory
+func (s *Store) LoadEmbeddingIndex(model string) (*EmbeddingIndex, error) {
+	if s.db != nil {
+		return s.loadEmbeddingRows(model)
+	}
+	return LoadEmbeddingIndex(s.Dir, model)
+}
+
+// Save writes the index to disk
+func (i *EmbeddingIndex) Save() error {
+	if i.db != nil {
+		return i.saveEmbeddingRows()
+	}
+	data, err := json.Marshal(i)
+	if err != nil {
+		return fmt.Errorf("failed to encode embedding index: %w", err)
+	}
+	if err := os.WriteFile(i.Path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save embedding index: %w", err)
+	}
+	return nil
+}
+
+// ContentKey identifies a message's text in the embedding index
+func ContentKey(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:12])
+}
+
+// Recall embeds any stored messages missing from the index and returns the
+// limit messages most similar to query. Messages from the session with the
+// exclude ID are skipped.
+func (s *Store) Recall(query string, index *EmbeddingIndex, embed EmbedFunc, exclude string, limit int) ([]Snippet, error) {
+	sessions, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []Snippet
+	var missing []string
+	queued := map[string]bool{}
+	for _, session := range sessions {
+		if session.ID == exclude {
+			continue
+		}
+		for _, msg := range session.Messages {
+			text := strings.TrimSpace(msg.Content)
+			if msg.Role == models.RoleInfo || text == "" {
+				continue
+			}
+			candidates = append(candidates, Snippet{SessionID: session.ID, Title: session.Title, Message: msg})
+			key := ContentKey(text)
+			if _, ok := index.Vectors[key]; !ok && !queued[key] {
+				queued[key] = true
+				missing = append(missing, text)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	for start := 0; start < len(missing); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		vectors, err := embed(missing[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for j, vector := range vectors {
+			index.Vectors[ContentKey(missing[start+j])] = vector
+		}
+	}
+	if len(missing) > 0 {
+		if err := index.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	vectors, err := embed([]string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range candidates {
+		key := ContentKey(strings.TrimSpace(candidates[i].Message.Content))
+		candidates[i].Score = CosineSimilarity(vectors[0], index.Vectors[key])
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	// The same text can appear in several branches; keep its best match only
+	results := make([]Snippet, 0, limit)
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if len(results) == limit {
+			break
+		}
+		key := ContentKey(strings.TrimSpace(c.Message.Content))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		results = append(results, c)
+	}
+	return results, nil
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, or 0
+// if their lengths differ or either is zero
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
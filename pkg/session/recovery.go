@@ -0,0 +1,97 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// Recovery is what the app was in the middle of, saved every few seconds so
+// it can be restored after a crash
+type Recovery struct {
+	SavedAt time.Time `json:"saved_at"`
+	// Session is the conversation on screen, with the response being
+	// generated as far as it streamed
+	Session Session `json:"session"`
+	// Draft is the text left in the input box
+	Draft string `json:"draft,omitempty"`
+	// Streaming is set when a response was being generated
+	Streaming bool `json:"streaming,omitempty"`
+}
+
+// RecoveryPath returns the path of the recovery file, next to the sessions
+// of the active profile
+func RecoveryPath() (string, error) {
+	dataDir, err := utils.GetDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := dataDir
+	if utils.ActiveProfile != "" {
+		dir = filepath.Join(dataDir, "profiles", utils.ActiveProfile)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "recovery.json"), nil
+}
+
+// SaveRecovery writes the recovery file, replacing it at once so a crash
+// while writing leaves the previous one
+func SaveRecovery(recovery Recovery) error {
+	path, err := RecoveryPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(recovery)
+	if err != nil {
+		return fmt.Errorf("failed to encode recovery: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadRecovery reads the recovery file left by a run that didn't exit
+// cleanly, or returns nil when there is none
+func LoadRecovery() (*Recovery, error) {
+	path, err := RecoveryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var recovery Recovery
+	if err := json.Unmarshal(data, &recovery); err != nil {
+		return nil, fmt.Errorf("failed to read recovery: %w", err)
+	}
+	return &recovery, nil
+}
+
+// ClearRecovery removes the recovery file, once there is nothing left to
+// recover or the app exits cleanly
+func ClearRecovery() error {
+	path, err := RecoveryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
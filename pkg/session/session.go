@@ -0,0 +1,235 @@
+package session
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// Session is a saved conversation. Branches link back to the session they
+// were forked from.
+type Session struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// CustomTitle is set when the user named the session, so generated
+	// titles do not replace it
+	CustomTitle bool             `json:"custom_title,omitempty"`
+	ParentID    string           `json:"parent_id,omitempty"`
+	ForkIndex   int              `json:"fork_index,omitempty"`
+	Provider    string           `json:"provider"`
+	Model       string           `json:"model"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Messages    []models.Message `json:"messages"`
+}
+
+// Entry is a session positioned in the branch tree
+type Entry struct {
+	Session Session
+	Depth   int
+}
+
+// Store persists sessions as JSON files in a directory, or in a SQLite
+// database kept in it when the storage setting asks for one
+type Store struct {
+	Dir string
+	// db is the database of a store opened with OpenDatabaseStore
+	db *sql.DB
+}
+
+// NewID returns a new unique session identifier
+func NewID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// DefaultStore returns the store in the data directory, or the active
+// profile's own store in profiles/<name> of it, kept the way the storage
+// setting says
+func DefaultStore() (*Store, error) {
+	dataDir, err := utils.GetDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(dataDir, "sessions")
+	if utils.ActiveProfile != "" {
+		dir = filepath.Join(dataDir, "profiles", utils.ActiveProfile, "sessions")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	config, _ := utils.LoadEffectiveConfig()
+	switch config.Storage {
+	case "", StorageJSON:
+		return &Store{Dir: dir}, nil
+	case StorageSQLite:
+		return OpenDatabaseStore(dir)
+	}
+	return nil, fmt.Errorf("unknown storage %q, use %q or %q", config.Storage, StorageJSON, StorageSQLite)
+}
+
+// Save writes the session, assigning an ID if it does not have one yet
+func (s *Store) Save(session *Session) error {
+	now := time.Now()
+	if session.ID == "" {
+		session.ID = NewID()
+	}
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = now
+	}
+	session.UpdatedAt = now
+	if s.db != nil {
+		return s.saveRow(session)
+	}
+
+	return writeJSON(s.path(session.ID), *session)
+}
+
+// writeJSON writes a session to a file of its own
+func writeJSON(path string, session Session) error {
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// ExportJSON writes every session to dir as a JSON file of its own, the way
+// JSON stores keep them, and returns how many there were. Copied into the
+// sessions directory, they are used again with the storage set to json.
+func (s *Store) ExportJSON(dir string) (int, error) {
+	sessions, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	for _, session := range sessions {
+		if err := writeJSON(filepath.Join(dir, session.ID+".json"), session); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+// Load reads the session with the given ID
+func (s *Store) Load(id string) (Session, error) {
+	if s.db != nil {
+		return s.loadRow(id)
+	}
+	var session Session
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		return session, fmt.Errorf("failed to read session: %w", err)
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return session, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return session, nil
+}
+
+// List returns all saved sessions, most recently updated first
+func (s *Store) List() ([]Session, error) {
+	if s.db != nil {
+		return s.listRows()
+	}
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(files))
+	for _, file := range files {
+		session, err := s.Load(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+// Tree orders sessions so that every branch follows the session it was
+// forked from, with its depth in the tree. Sessions whose parent no longer
+// exists are shown as roots.
+func Tree(sessions []Session) []Entry {
+	known := map[string]bool{}
+	for _, s := range sessions {
+		known[s.ID] = true
+	}
+
+	children := map[string][]Session{}
+	var roots []Session
+	for _, s := range sessions {
+		if s.ParentID != "" && known[s.ParentID] && s.ParentID != s.ID {
+			children[s.ParentID] = append(children[s.ParentID], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+
+	entries := make([]Entry, 0, len(sessions))
+	visited := map[string]bool{}
+	var walk func(s Session, depth int)
+	walk = func(s Session, depth int) {
+		if visited[s.ID] {
+			return
+		}
+		visited[s.ID] = true
+		entries = append(entries, Entry{Session: s, Depth: depth})
+		for _, child := range children[s.ID] {
+			walk(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	return entries
+}
+
+// Fork creates a branch of the session containing its first n messages
+func (s Session) Fork(n int) Session {
+	if n > len(s.Messages) {
+		n = len(s.Messages)
+	}
+	title := s.Title
+	if title == "" {
+		title = "Untitled"
+	}
+	if !strings.HasSuffix(title, " (branch)") {
+		title += " (branch)"
+	}
+	return Session{
+		Title:     title,
+		ParentID:  s.ID,
+		ForkIndex: n,
+		Provider:  s.Provider,
+		Model:     s.Model,
+		Messages:  append([]models.Message(nil), s.Messages[:n]...),
+	}
+}
@@ -0,0 +1,54 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+func TestLoadLegacySession(t *testing.T) {
+	dir := t.TempDir()
+	// A session saved before messages carried metadata, requests or cut-offs
+	legacy := `{
+  "id": "20250301-100000-abcd1234",
+  "title": "Greetings",
+  "provider": "ollama",
+  "model": "llama3",
+  "created_at": "2025-03-01T10:00:00Z",
+  "updated_at": "2025-03-01T10:01:00Z",
+  "messages": [
+    {"role": "user", "content": "Hi", "created_at": "2025-03-01T10:00:00Z"},
+    {"role": "assistant", "content": "Hello!", "model": "llama3", "created_at": "2025-03-01T10:00:05Z", "tokens": 3}
+  ]
+}`
+	if err := os.WriteFile(filepath.Join(dir, "20250301-100000-abcd1234.json"), []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &Store{Dir: dir}
+	s, err := store.Load("20250301-100000-abcd1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Title != "Greetings" || len(s.Messages) != 2 {
+		t.Fatalf("loaded %+v", s)
+	}
+	reply := s.Messages[1]
+	if reply.Role != models.RoleAssistant || reply.Content != "Hello!" || reply.Tokens != 3 || reply.Metadata != nil {
+		t.Errorf("loaded reply %+v", reply)
+	}
+
+	// Saving it again keeps it readable and adds nothing it didn't have
+	if err := store.Save(&s); err != nil {
+		t.Fatal(err)
+	}
+	again, err := store.Load(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Messages) != 2 || again.Messages[1].Content != "Hello!" || again.Messages[1].CutOff != "" {
+		t.Errorf("reloaded %+v", again)
+	}
+}
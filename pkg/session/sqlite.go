@@ -0,0 +1,384 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+
+	// Pure Go, so builds need no C compiler
+	_ "modernc.org/sqlite"
+)
+
+// Storage settings for where sessions are kept
+const (
+	// StorageJSON keeps every session in a JSON file of its own
+	StorageJSON = "json"
+	// StorageSQLite keeps sessions, their messages and embeddings in one
+	// SQLite database
+	StorageSQLite = "sqlite"
+)
+
+// DatabaseFile is the name of the SQLite database in the sessions directory
+const DatabaseFile = "sessions.db"
+
+// migrations bring the database schema up to date, one version each. They
+// are applied in order and never changed once released; a new version adds
+// a new entry.
+var migrations = []string{
+	// 1: sessions with their full JSON, messages broken out for searching and
+	// aggregating usage, and embeddings keyed by model and content
+	`CREATE TABLE sessions (
+		id TEXT PRIMARY KEY,
+		title TEXT NOT NULL,
+		parent_id TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		updated_at TEXT NOT NULL,
+		data TEXT NOT NULL
+	);
+	CREATE INDEX sessions_updated_at ON sessions (updated_at);
+	CREATE TABLE messages (
+		session_id TEXT NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		role TEXT NOT NULL,
+		model TEXT NOT NULL,
+		content TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		prompt_tokens INTEGER NOT NULL,
+		completion_tokens INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		PRIMARY KEY (session_id, position)
+	);
+	CREATE INDEX messages_model ON messages (model, created_at);
+	CREATE TABLE embeddings (
+		model TEXT NOT NULL,
+		key TEXT NOT NULL,
+		vector BLOB NOT NULL,
+		PRIMARY KEY (model, key)
+	);`,
+}
+
+var (
+	databasesMu sync.Mutex
+	// databases are kept open for the life of the app, by path, since every
+	// feature that needs sessions opens the default store
+	databases = map[string]*sql.DB{}
+)
+
+// OpenDatabaseStore returns a store keeping its sessions in the SQLite
+// database in dir. A new database is migrated to the latest schema and
+// filled with the JSON sessions and embeddings already in dir, which are
+// left in place.
+func OpenDatabaseStore(dir string) (*Store, error) {
+	path := filepath.Join(dir, DatabaseFile)
+
+	databasesMu.Lock()
+	defer databasesMu.Unlock()
+	if db, ok := databases[path]; ok {
+		return &Store{Dir: dir, db: db}, nil
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %w", err)
+	}
+	from, err := migrate(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate session database: %w", err)
+	}
+	store := &Store{Dir: dir, db: db}
+	if from == 0 {
+		if err := store.importJSON(); err != nil {
+			db.Close()
+			os.Remove(path)
+			return nil, fmt.Errorf("failed to import JSON sessions: %w", err)
+		}
+	}
+	databases[path] = db
+	return store, nil
+}
+
+// migrate applies the migrations the database is missing and returns the
+// schema version it had before
+func migrate(db *sql.DB) (int, error) {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return 0, err
+	}
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version); err != nil {
+		return 0, err
+	}
+	if version > len(migrations) {
+		return version, fmt.Errorf("the database is at schema version %d, newer than this version of ollama-tui knows (%d)", version, len(migrations))
+	}
+
+	for v := version; v < len(migrations); v++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return version, err
+		}
+		if _, err := tx.Exec(migrations[v]); err != nil {
+			tx.Rollback()
+			return version, fmt.Errorf("version %d: %w", v+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, v+1); err != nil {
+			tx.Rollback()
+			return version, err
+		}
+		if err := tx.Commit(); err != nil {
+			return version, err
+		}
+	}
+	return version, nil
+}
+
+// importJSON copies the sessions and the embedding index kept as files in
+// the store's directory into its database
+func (s *Store) importJSON() error {
+	sessions, err := (&Store{Dir: s.Dir}).List()
+	if err != nil {
+		return err
+	}
+	for i := range sessions {
+		if err := s.saveRow(&sessions[i]); err != nil {
+			return err
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.Dir, "embeddings.index"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var index EmbeddingIndex
+	if json.Unmarshal(data, &index) != nil || index.Model == "" {
+		// A broken cache is rebuilt by the next /recall
+		return nil
+	}
+	index.db = s.db
+	return index.Save()
+}
+
+// saveRow writes the session and its messages to the database as they are
+func (s *Store) saveRow(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO sessions (id, title, parent_id, provider, model, created_at, updated_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET title = excluded.title, parent_id = excluded.parent_id,
+			provider = excluded.provider, model = excluded.model, updated_at = excluded.updated_at, data = excluded.data`,
+		session.ID, session.Title, session.ParentID, session.Provider, session.Model,
+		formatTime(session.CreatedAt), formatTime(session.UpdatedAt), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM messages WHERE session_id = ?`, session.ID); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	for i, msg := range session.Messages {
+		usage, _ := msg.Usage()
+		model := msg.Model
+		if model == "" && msg.Role == models.RoleAssistant {
+			model = session.Model
+		}
+		_, err := tx.Exec(`INSERT INTO messages (session_id, position, role, model, content, created_at, prompt_tokens, completion_tokens, duration_ms)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			session.ID, i, msg.Role, model, msg.Content, formatTime(msg.CreatedAt),
+			usage.PromptTokens, usage.CompletionTokens, msg.Duration.Milliseconds())
+		if err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// loadRow reads the session with the given ID from the database
+func (s *Store) loadRow(id string) (Session, error) {
+	var session Session
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM sessions WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, fmt.Errorf("failed to read session: no session %s", id)
+	}
+	if err != nil {
+		return session, fmt.Errorf("failed to read session: %w", err)
+	}
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return session, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return session, nil
+}
+
+// listRows returns every session in the database, most recently updated
+// first. Sessions that can't be decoded are skipped, like unreadable files.
+func (s *Store) listRows() ([]Session, error) {
+	rows, err := s.db.Query(`SELECT data FROM sessions ORDER BY updated_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		var session Session
+		if json.Unmarshal([]byte(data), &session) != nil {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Search returns the sessions with a message containing text, ignoring
+// case, most recently updated first. JSON stores read every session for it.
+func (s *Store) Search(text string) ([]Session, error) {
+	if s.db == nil {
+		sessions, err := s.List()
+		if err != nil {
+			return nil, err
+		}
+		needle := strings.ToLower(text)
+		var matches []Session
+		for _, session := range sessions {
+			for _, msg := range session.Messages {
+				if strings.Contains(strings.ToLower(msg.Content), needle) {
+					matches = append(matches, session)
+					break
+				}
+			}
+		}
+		return matches, nil
+	}
+
+	rows, err := s.db.Query(`SELECT DISTINCT session_id, updated_at FROM messages JOIN sessions ON sessions.id = session_id
+		WHERE instr(lower(content), lower(?)) > 0 ORDER BY updated_at DESC`, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id, updated string
+		if err := rows.Scan(&id, &updated); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to search sessions: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+
+	matches := make([]Session, 0, len(ids))
+	for _, id := range ids {
+		session, err := s.loadRow(id)
+		if err != nil {
+			continue
+		}
+		matches = append(matches, session)
+	}
+	return matches, nil
+}
+
+// loadEmbeddingRows reads the vectors stored for model
+func (s *Store) loadEmbeddingRows(model string) (*EmbeddingIndex, error) {
+	index := &EmbeddingIndex{Model: model, Vectors: map[string][]float64{}, db: s.db, stored: map[string]bool{}}
+	rows, err := s.db.Query(`SELECT key, vector FROM embeddings WHERE model = ?`, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding index: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var blob []byte
+		if err := rows.Scan(&key, &blob); err != nil {
+			return nil, fmt.Errorf("failed to read embedding index: %w", err)
+		}
+		index.Vectors[key] = decodeVector(blob)
+		index.stored[key] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read embedding index: %w", err)
+	}
+	return index, nil
+}
+
+// saveEmbeddingRows writes the vectors of the index not stored yet
+func (i *EmbeddingIndex) saveEmbeddingRows() error {
+	tx, err := i.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to save embedding index: %w", err)
+	}
+	defer tx.Rollback()
+	for key, vector := range i.Vectors {
+		if i.stored[key] {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO embeddings (model, key, vector) VALUES (?, ?, ?)`, i.Model, key, encodeVector(vector)); err != nil {
+			return fmt.Errorf("failed to save embedding index: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save embedding index: %w", err)
+	}
+	if i.stored == nil {
+		i.stored = map[string]bool{}
+	}
+	for key := range i.Vectors {
+		i.stored[key] = true
+	}
+	return nil
+}
+
+// formatTime stores times so that they sort as text
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+// encodeVector packs a vector as little-endian float64s
+func encodeVector(vector []float64) []byte {
+	blob := make([]byte, 8*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint64(blob[8*i:], math.Float64bits(v))
+	}
+	return blob
+}
+
+// decodeVector unpacks a vector written by encodeVector
+func decodeVector(blob []byte) []float64 {
+	vector := make([]float64, len(blob)/8)
+	for i := range vector {
+		vector[i] = math.Float64frombits(binary.LittleEndian.Uint64(blob[8*i:]))
+	}
+	return vector
+}
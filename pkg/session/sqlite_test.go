@@ -0,0 +1,132 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+func TestDatabaseStoreImportsJSON(t *testing.T) {
+	dir := t.TempDir()
+	files := &Store{Dir: dir}
+	old := Session{
+		Title:    "Old",
+		Provider: "ollama",
+		Model:    "llama3",
+		Messages: []models.Message{
+			{Role: models.RoleUser, Content: "How do goroutines work?"},
+			{Role: models.RoleAssistant, Content: "They are cheap threads.", Metadata: &models.ResponseMetadata{
+				Model:           "llama3",
+				ResponseMetrics: models.ResponseMetrics{PromptEvalCount: 12, EvalCount: 34},
+			}},
+		},
+	}
+	if err := files.Save(&old); err != nil {
+		t.Fatal(err)
+	}
+	index := &EmbeddingIndex{Path: dir + "/embeddings.index", Model: "nomic-embed-text", Vectors: map[string][]float64{"k": {0.5, -1}}}
+	if err := index.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := OpenDatabaseStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := store.Load(old.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Title != "Old" || len(imported.Messages) != 2 || !imported.UpdatedAt.Equal(old.UpdatedAt) {
+		t.Errorf("imported session = %+v, want %+v", imported, old)
+	}
+	if usage, ok := imported.Messages[1].Usage(); !ok || usage.CompletionTokens != 34 {
+		t.Errorf("imported usage = %+v, %v", usage, ok)
+	}
+
+	vectors, err := store.LoadEmbeddingIndex("nomic-embed-text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vectors.Vectors, index.Vectors) {
+		t.Errorf("imported vectors = %v, want %v", vectors.Vectors, index.Vectors)
+	}
+}
+
+func TestDatabaseStoreSaveListSearch(t *testing.T) {
+	store, err := OpenDatabaseStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := Session{Title: "First", Messages: []models.Message{{Role: models.RoleUser, Content: "Tell me about SQLite"}}}
+	second := Session{Title: "Second", Messages: []models.Message{{Role: models.RoleUser, Content: "Write a haiku"}}}
+	if err := store.Save(&first); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := store.Save(&second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving again updates the session instead of adding another
+	second.Messages = append(second.Messages, models.Message{Role: models.RoleAssistant, Content: "Autumn moonlight"})
+	if err := store.Save(&second); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != second.ID || len(sessions[0].Messages) != 2 {
+		t.Fatalf("List() = %+v, want Second with 2 messages, then First", sessions)
+	}
+
+	matches, err := store.Search("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].ID != first.ID {
+		t.Errorf("Search(sqlite) = %+v, want First", matches)
+	}
+	if _, err := store.Load("missing"); err == nil {
+		t.Error("Load of a missing session succeeded")
+	}
+}
+
+func TestDatabaseStoreMigratesOnce(t *testing.T) {
+	store, err := OpenDatabaseStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := migrate(store.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != len(migrations) {
+		t.Errorf("a migrated database is at version %d, want %d", from, len(migrations))
+	}
+
+	index, err := store.LoadEmbeddingIndex("m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Vectors["a"] = []float64{1, 2, 3}
+	if err := index.Save(); err != nil {
+		t.Fatal(err)
+	}
+	index.Vectors["b"] = []float64{4}
+	if err := index.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := store.LoadEmbeddingIndex("m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded.Vectors, index.Vectors) {
+		t.Errorf("reloaded vectors = %v, want %v", reloaded.Vectors, index.Vectors)
+	}
+}
@@ -0,0 +1,205 @@
+// Package speech records prompts from the microphone and transcribes them
+// with a Whisper backend.
+package speech
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// Recorder captures audio from the microphone
+type Recorder interface {
+	// Name identifies the recording program in status messages
+	Name() string
+	// Start begins recording in the background
+	Start() error
+	// Stop ends the recording and returns it as 16 kHz mono WAV data
+	Stop() ([]byte, error)
+	// Cancel ends the recording and discards it
+	Cancel()
+}
+
+// stopTimeout is how long a recorder may take to finish its file once asked
+// to stop
+const stopTimeout = 3 * time.Second
+
+// NewRecorder returns the recorder selected in the config, or the first of
+// sox, arecord and ffmpeg that is installed
+func NewRecorder(cfg utils.SpeechConfig) (Recorder, error) {
+	programs := []string{"sox", "arecord", "ffmpeg"}
+	if runtime.GOOS != "linux" {
+		programs = []string{"sox", "ffmpeg"}
+	}
+	if cfg.Recorder != "" {
+		programs = []string{cfg.Recorder}
+	}
+
+	for _, program := range programs {
+		if _, err := exec.LookPath(program); err != nil {
+			if cfg.Recorder != "" {
+				return nil, fmt.Errorf("%s is not installed", program)
+			}
+			continue
+		}
+		switch program {
+		case "sox":
+			return &commandRecorder{program: program, args: soxArgs}, nil
+		case "arecord":
+			return &commandRecorder{program: program, args: arecordArgs(cfg.Device)}, nil
+		case "ffmpeg":
+			if runtime.GOOS == "windows" && cfg.Device == "" {
+				return nil, errors.New("recording with ffmpeg on Windows needs a \"device\", such as \"audio=Microphone\"")
+			}
+			return &commandRecorder{program: program, args: ffmpegArgs(cfg.Device), quitInput: "q"}, nil
+		default:
+			return nil, fmt.Errorf("unknown recorder %q (use sox, arecord or ffmpeg)", program)
+		}
+	}
+	return nil, errors.New("recording needs sox, arecord or ffmpeg to be installed")
+}
+
+// soxArgs records the default input device with sox
+func soxArgs(path string) []string {
+	return []string{"-q", "-d", "-r", "16000", "-c", "1", "-b", "16", path}
+}
+
+// arecordArgs records an ALSA device with arecord
+func arecordArgs(device string) func(string) []string {
+	return func(path string) []string {
+		args := []string{"-q", "-f", "S16_LE", "-r", "16000", "-c", "1", "-t", "wav"}
+		if device != "" {
+			args = append(args, "-D", device)
+		}
+		return append(args, path)
+	}
+}
+
+// ffmpegArgs records a device with the platform's capture API
+func ffmpegArgs(device string) func(string) []string {
+	return func(path string) []string {
+		var input []string
+		switch runtime.GOOS {
+		case "darwin":
+			if device == "" {
+				device = ":0"
+			}
+			input = []string{"-f", "avfoundation", "-i", device}
+		case "windows":
+			input = []string{"-f", "dshow", "-i", device}
+		default:
+			if device == "" {
+				device = "default"
+			}
+			input = []string{"-f", "pulse", "-i", device}
+		}
+		args := append([]string{"-hide_banner", "-loglevel", "error", "-y"}, input...)
+		return append(args, "-ac", "1", "-ar", "16000", "-c:a", "pcm_s16le", path)
+	}
+}
+
+// commandRecorder records by running a program that writes a WAV file until
+// it is interrupted, or until quitInput is written to its stdin
+type commandRecorder struct {
+	program   string
+	args      func(path string) []string
+	quitInput string
+
+	path   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+	done   chan error
+}
+
+// Name identifies the recording program
+func (r *commandRecorder) Name() string { return r.program }
+
+// Start runs the recording program on a temporary file
+func (r *commandRecorder) Start() error {
+	dir, err := os.MkdirTemp("", "ollama-tui-voice-*")
+	if err != nil {
+		return err
+	}
+	r.path = filepath.Join(dir, "prompt.wav")
+
+	r.cmd = exec.Command(r.program, r.args(r.path)...)
+	r.cmd.Stderr = &r.stderr
+	if r.quitInput != "" {
+		if r.stdin, err = r.cmd.StdinPipe(); err != nil {
+			return err
+		}
+	}
+	if err := r.cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("cannot start %s: %w", r.program, err)
+	}
+
+	r.done = make(chan error, 1)
+	go func() { r.done <- r.cmd.Wait() }()
+	return nil
+}
+
+// Stop asks the program to finish the file and returns its contents
+func (r *commandRecorder) Stop() ([]byte, error) {
+	defer os.RemoveAll(filepath.Dir(r.path))
+
+	exited := false
+	select {
+	case <-r.done:
+		// The program stopped by itself, usually because it could not open
+		// the device
+		exited = true
+	default:
+		r.interrupt()
+		select {
+		case <-r.done:
+		case <-time.After(stopTimeout):
+			r.cmd.Process.Kill()
+			<-r.done
+		}
+	}
+
+	data, err := os.ReadFile(r.path)
+	// A WAV header alone is 44 bytes
+	if err != nil || len(data) <= 44 {
+		if msg := strings.TrimSpace(r.stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", r.program, msg[strings.LastIndex(msg, "\n")+1:])
+		}
+		if exited {
+			return nil, fmt.Errorf("%s stopped before recording anything", r.program)
+		}
+		return nil, errors.New("no audio was recorded")
+	}
+	return data, nil
+}
+
+// Cancel stops the program and deletes the recording
+func (r *commandRecorder) Cancel() {
+	r.cmd.Process.Kill()
+	<-r.done
+	os.RemoveAll(filepath.Dir(r.path))
+}
+
+// interrupt asks the program to stop, which makes it finish the WAV header
+func (r *commandRecorder) interrupt() {
+	if r.stdin != nil {
+		io.WriteString(r.stdin, r.quitInput)
+		r.stdin.Close()
+		return
+	}
+	// Windows cannot deliver interrupts, so the program is killed and its
+	// file used as far as it was written
+	if err := r.cmd.Process.Signal(os.Interrupt); err != nil {
+		r.cmd.Process.Kill()
+	}
+}
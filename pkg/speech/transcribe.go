@@ -0,0 +1,160 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+const (
+	// DefaultWhisperCppURL is the inference endpoint of a local whisper.cpp server
+	DefaultWhisperCppURL = "http://127.0.0.1:8080/inference"
+	// DefaultOpenAIURL is the base URL of the OpenAI audio API
+	DefaultOpenAIURL = "https://api.openai.com/v1"
+	// DefaultOpenAIModel is the OpenAI transcription model
+	DefaultOpenAIModel = "whisper-1"
+)
+
+// Transcriber turns recorded speech into text
+type Transcriber interface {
+	// Name identifies the backend in status messages
+	Name() string
+	Transcribe(ctx context.Context, wav []byte) (string, error)
+}
+
+// NewTranscriber returns the backend selected in the config: a local
+// whisper.cpp server by default, or the OpenAI audio API (or a server
+// compatible with it). openAIKey is used when the config has no api_key.
+func NewTranscriber(cfg utils.SpeechConfig, openAIKey string) (Transcriber, error) {
+	switch cfg.Backend {
+	case "", "whisper.cpp", "whispercpp":
+		url := cfg.URL
+		if url == "" {
+			url = DefaultWhisperCppURL
+		}
+		return WhisperCpp{URL: url, Language: cfg.Language}, nil
+	case "openai":
+		url := strings.TrimRight(cfg.URL, "/")
+		if url == "" {
+			url = DefaultOpenAIURL
+		}
+		key := cfg.APIKey
+		if key == "" {
+			key = openAIKey
+		}
+		if key == "" && url == DefaultOpenAIURL {
+			return nil, fmt.Errorf("the openai speech backend needs an api_key or OPENAI_API_KEY")
+		}
+		model := cfg.Model
+		if model == "" {
+			model = DefaultOpenAIModel
+		}
+		return OpenAI{URL: url, APIKey: key, Model: model, Language: cfg.Language}, nil
+	}
+	return nil, fmt.Errorf("unknown speech backend %q (use whisper.cpp or openai)", cfg.Backend)
+}
+
+// WhisperCpp transcribes with the HTTP server that ships with whisper.cpp
+type WhisperCpp struct {
+	URL      string
+	Language string
+}
+
+// Name identifies the backend
+func (w WhisperCpp) Name() string { return "whisper.cpp" }
+
+// Transcribe posts the recording to the server's inference endpoint
+func (w WhisperCpp) Transcribe(ctx context.Context, wav []byte) (string, error) {
+	fields := map[string]string{"response_format": "json", "temperature": "0"}
+	if w.Language != "" {
+		fields["language"] = w.Language
+	}
+	text, err := postAudio(ctx, w.URL, nil, fields, wav)
+	// Silence comes back as a marker rather than as no text
+	return strings.TrimSpace(strings.ReplaceAll(text, "[BLANK_AUDIO]", "")), err
+}
+
+// OpenAI transcribes with the OpenAI audio API
+type OpenAI struct {
+	URL      string
+	APIKey   string
+	Model    string
+	Language string
+}
+
+// Name identifies the backend
+func (o OpenAI) Name() string { return "OpenAI" }
+
+// Transcribe posts the recording to the transcriptions endpoint
+func (o OpenAI) Transcribe(ctx context.Context, wav []byte) (string, error) {
+	fields := map[string]string{"model": o.Model, "response_format": "json"}
+	if o.Language != "" {
+		fields["language"] = o.Language
+	}
+	headers := map[string]string{}
+	if o.APIKey != "" {
+		headers["Authorization"] = "Bearer " + o.APIKey
+	}
+	return postAudio(ctx, o.URL+"/audio/transcriptions", headers, fields, wav)
+}
+
+// postAudio uploads a WAV file with form fields and returns the "text" of
+// the JSON response
+func postAudio(ctx context.Context, endpoint string, headers, fields map[string]string, wav []byte) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	file, err := form.CreateFormFile("file", "prompt.wav")
+	if err != nil {
+		return "", err
+	}
+	file.Write(wav)
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("transcription request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Text  string          `json:"text"`
+		Error json.RawMessage `json:"error"`
+	}
+	json.Unmarshal(data, &result)
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(result.Error, &apiErr) == nil && apiErr.Message != "" {
+			return "", fmt.Errorf("transcription failed: %s", apiErr.Message)
+		}
+		return "", fmt.Errorf("transcription returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data[:min(len(data), 200)])))
+	}
+	return strings.TrimSpace(result.Text), nil
+}
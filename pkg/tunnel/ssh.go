@@ -0,0 +1,163 @@
+// Package tunnel reaches a remote Ollama server through an ssh port forward.
+package tunnel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRemotePort is the port Ollama listens on at the remote end
+const DefaultRemotePort = 11434
+
+// readyTimeout is how long ssh may take to connect, including the time
+// spent typing a password or key passphrase
+const readyTimeout = 2 * time.Minute
+
+// IsSSH reports whether host names an ssh target like ssh://user@gpu-box
+func IsSSH(host string) bool {
+	return strings.HasPrefix(host, "ssh://")
+}
+
+// Tunnel is a running ssh process forwarding a local port to Ollama on a
+// remote machine
+type Tunnel struct {
+	// LocalAddr is the forwarded address on this machine, e.g. 127.0.0.1:53412
+	LocalAddr string
+
+	cmd    *exec.Cmd
+	exited chan struct{}
+	stderr bytes.Buffer
+}
+
+// Open forwards a free local port to Ollama on the machine named by target,
+// an ssh://[user@]host[:ssh-port] URL. A "port" query parameter selects
+// the remote Ollama port. ssh asks for passwords on the terminal, so Open
+// must run before the interface takes it over.
+func Open(target string) (*Tunnel, error) {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return nil, errors.New("ssh tunnels need the ssh program to be installed")
+	}
+	localPort, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	args, err := sshArgs(target, localPort)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tunnel{
+		LocalAddr: fmt.Sprintf("127.0.0.1:%d", localPort),
+		cmd:       exec.Command("ssh", args...),
+		exited:    make(chan struct{}),
+	}
+	t.cmd.Stderr = &t.stderr
+	if err := t.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %w", err)
+	}
+	go func() {
+		t.cmd.Wait()
+		close(t.exited)
+	}()
+
+	if err := t.waitReady(); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// sshArgs returns the arguments of the ssh command forwarding localPort to
+// Ollama on the machine named by target. A user or host starting with "-"
+// is refused, and "--" ends the options, so that the URL can't pass ssh
+// options such as ProxyCommand.
+func sshArgs(target string, localPort int) ([]string, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "ssh" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid ssh host %q (use ssh://user@host)", target)
+	}
+	remotePort := DefaultRemotePort
+	if port := u.Query().Get("port"); port != "" {
+		if remotePort, err = strconv.Atoi(port); err != nil || remotePort <= 0 || remotePort > 65535 {
+			return nil, fmt.Errorf("invalid remote port %q", port)
+		}
+	}
+
+	destination := u.Hostname()
+	if strings.HasPrefix(destination, "-") {
+		return nil, fmt.Errorf("invalid ssh host %q", destination)
+	}
+	if u.User != nil {
+		user := u.User.Username()
+		if user == "" || strings.HasPrefix(user, "-") || strings.ContainsAny(user, "@ \t\r\n") {
+			return nil, fmt.Errorf("invalid ssh user %q", user)
+		}
+		destination = user + "@" + destination
+	}
+	args := []string{
+		"-N",
+		"-L", fmt.Sprintf("127.0.0.1:%d:localhost:%d", localPort, remotePort),
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "ServerAliveInterval=30",
+	}
+	if u.Port() != "" {
+		args = append(args, "-p", u.Port())
+	}
+	return append(args, "--", destination), nil
+}
+
+// waitReady waits until the forwarded port accepts connections
+func (t *Tunnel) waitReady() error {
+	deadline := time.Now().Add(readyTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-t.exited:
+			return fmt.Errorf("ssh exited: %s", t.failure())
+		case <-time.After(200 * time.Millisecond):
+		}
+		if conn, err := net.DialTimeout("tcp", t.LocalAddr, time.Second); err == nil {
+			conn.Close()
+			return nil
+		}
+	}
+	return fmt.Errorf("ssh did not connect within %s", readyTimeout)
+}
+
+// failure describes why ssh exited from its error output
+func (t *Tunnel) failure() string {
+	if message := strings.TrimSpace(t.stderr.String()); message != "" {
+		return message
+	}
+	return t.cmd.ProcessState.String()
+}
+
+// Close stops the port forward
+func (t *Tunnel) Close() error {
+	select {
+	case <-t.exited:
+		return nil
+	default:
+	}
+	if err := t.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	<-t.exited
+	return nil
+}
+
+// freePort returns a local TCP port nothing is listening on
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
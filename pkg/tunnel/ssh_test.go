@@ -0,0 +1,40 @@
+package tunnel
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSSHArgs(t *testing.T) {
+	args, err := sshArgs("ssh://me@gpu-box:2222?port=11500", 40000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"-N",
+		"-L", "127.0.0.1:40000:localhost:11500",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "ServerAliveInterval=30",
+		"-p", "2222",
+		"--", "me@gpu-box",
+	}
+	if !slices.Equal(args, want) {
+		t.Errorf("sshArgs = %q, want %q", args, want)
+	}
+}
+
+func TestSSHArgsRejectsOptions(t *testing.T) {
+	for _, target := range []string{
+		"ssh://-oProxyCommand=touch%20pwned@gpu-box",
+		"ssh://-oProxyCommand=sh@gpu-box",
+		"ssh://-v",
+		"ssh://me@-oProxyCommand=sh",
+		"ssh://me%40other@gpu-box",
+		"ssh://gpu-box?port=-1",
+	} {
+		args, err := sshArgs(target, 40000)
+		if err == nil {
+			t.Errorf("sshArgs(%q) = %q, want an error", target, args)
+		}
+	}
+}
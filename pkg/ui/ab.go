@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// ABVariant is one side of an A/B comparison: the settings prompts are sent
+// with instead of the chat's
+type ABVariant struct {
+	Model       string
+	Temperature *float64
+	System      *string
+}
+
+// ParseABVariant reads a side of /ab such as "0.2", "temp=1.0",
+// "model=llama3 temp=0.7" or "system=Answer in one sentence". A system
+// prompt takes the rest of the side.
+func ParseABVariant(spec string) (ABVariant, error) {
+	var v ABVariant
+	rest := strings.TrimSpace(spec)
+	if rest == "" {
+		return v, fmt.Errorf("empty side")
+	}
+	for rest != "" {
+		if value, ok := strings.CutPrefix(rest, "system="); ok {
+			system := strings.Trim(strings.TrimSpace(value), `"'`)
+			v.System = &system
+			break
+		}
+		field, remaining, _ := strings.Cut(rest, " ")
+		rest = strings.TrimSpace(remaining)
+
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			key, value = "temp", field
+		}
+		switch key {
+		case "temp", "temperature":
+			t, err := strconv.ParseFloat(value, 64)
+			if err != nil || t < 0 || t > 2 {
+				return v, fmt.Errorf("invalid temperature %q: expected a number between 0 and 2", value)
+			}
+			v.Temperature = &t
+		case "model":
+			v.Model = value
+		default:
+			return v, fmt.Errorf("unknown setting %q: use temp=, model= or system=", key)
+		}
+	}
+	return v, nil
+}
+
+// Label describes what the side changes
+func (v ABVariant) Label() string {
+	var parts []string
+	if v.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temp %g", *v.Temperature))
+	}
+	if v.System != nil {
+		parts = append(parts, fmt.Sprintf("system %q", truncate(*v.System, 24)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// splitABSides splits the arguments of /ab into its two sides: "A | B", or
+// two bare temperatures
+func splitABSides(args string) ([]string, bool) {
+	if a, b, ok := strings.Cut(args, "|"); ok {
+		return []string{a, b}, !strings.Contains(b, "|")
+	}
+	fields := strings.Fields(args)
+	return fields, len(fields) == 2
+}
+
+// StartAB shows the answers of the chat model with two sets of settings side
+// by side, sending the latest prompt again right away and every prompt
+// after it to both sides
+func (m *Model) StartAB(args string) tea.Cmd {
+	if m.IsGenerating {
+		m.StatusMessage = "Wait for the response to finish before starting an A/B comparison"
+		return nil
+	}
+	sides, ok := splitABSides(args)
+	if !ok {
+		m.StatusMessage = "Usage: /ab <A> | <B> (e.g. /ab 0.2 1.0 or /ab system=Be terse | system=Be thorough)"
+		return nil
+	}
+
+	panes := make([]ComparePane, 0, len(sides))
+	for i, side := range sides {
+		variant, err := ParseABVariant(side)
+		if err != nil {
+			m.StatusMessage = fmt.Sprintf("Side %c: %v", 'A'+i, err)
+			return nil
+		}
+		model := m.SelectedModel
+		if variant.Model != "" {
+			model = variant.Model
+		}
+		pane, err := m.NewComparePane(model)
+		if err != nil {
+			m.StatusMessage = err.Error()
+			return nil
+		}
+		if variant.Temperature != nil {
+			pane.Client.Temperature = variant.Temperature
+		}
+		if variant.System != nil {
+			pane.Client.SystemPrompt = *variant.System
+		}
+		pane.Variant = fmt.Sprintf("%c", 'A'+i)
+		if label := variant.Label(); label != "" {
+			pane.Variant += ": " + label
+		}
+		panes = append(panes, pane)
+	}
+
+	m.StopComparison()
+	m.Comparison = panes
+	m.StatusMessage = fmt.Sprintf("A/B: %s vs %s, /ab to stop", panes[0].Variant, panes[1].Variant)
+
+	// The latest prompt is asked again so the two answers can be compared
+	// at once
+	prompt := ""
+	for i := len(m.Messages) - 1; i >= 0; i-- {
+		if m.Messages[i].Role == models.RoleUser {
+			prompt = m.Messages[i].Content
+			break
+		}
+	}
+	if prompt == "" {
+		return RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight)
+	}
+	m.CurrentPrompt = prompt
+	m.State = StateLoading
+	m.IsGenerating = true
+	m.Messages = append(m.Messages, models.Message{Role: models.RoleUser, Content: prompt, CreatedAt: time.Now()})
+	m.SelectedMessage = -1
+	return tea.Batch(RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight), m.StartComparison(prompt))
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "ab",
+		Usage:       "<A> | <B>",
+		Description: "Ask the latest prompt again with two settings side by side, e.g. temp=0.2 | temp=1.0 (empty to stop)",
+		Run: func(m *Model, args string) tea.Cmd {
+			if strings.TrimSpace(args) == "" {
+				if len(m.Comparison) == 0 {
+					m.StatusMessage = "Usage: /ab <A> | <B> (e.g. /ab 0.2 1.0 or /ab system=Be terse | system=Be thorough)"
+					return nil
+				}
+				m.StopComparison()
+				m.StatusMessage = "A/B comparison off"
+				return RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight)
+			}
+			return m.StartAB(args)
+		},
+	})
+}
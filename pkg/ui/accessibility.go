@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// plainText is set in accessible mode, where lists avoid box-drawing
+// characters
+var plainText bool
+
+// selectedMarker marks the selected list item in accessible mode, in place
+// of the default delegate's bar
+var selectedMarker = lipgloss.Border{Left: ">"}
+
+// EnableAccessibleMode switches to output a screen reader can follow: no
+// alternate screen, animations or box drawing, and the conversation printed
+// line by line above the input as plain text
+func (m *Model) EnableAccessibleMode() {
+	m.Accessible = true
+	m.ImageProtocol = utils.ImageNone
+	UseAccessibleStyles()
+
+	delegate := AccessibleDelegate()
+	m.ProviderList.SetDelegate(delegate)
+	m.List.SetDelegate(delegate)
+	m.SessionList.SetDelegate(delegate)
+
+	m.Input.Prompt = "> "
+	m.Input.SetWidth(m.Input.Width())
+	m.Input.Cursor.SetMode(cursor.CursorStatic)
+	m.APIKeyInput.Cursor.SetMode(cursor.CursorStatic)
+}
+
+// UseAccessibleStyles replaces the borders of boxes and popups with blank
+// space, keeping their layout
+func UseAccessibleStyles() {
+	plainText = true
+	InputBoxStyle = InputBoxStyle.BorderStyle(lipgloss.HiddenBorder())
+	SlashPopupStyle = SlashPopupStyle.BorderStyle(lipgloss.HiddenBorder())
+	ComparePaneStyle = ComparePaneStyle.BorderStyle(lipgloss.HiddenBorder())
+}
+
+// AccessibleDelegate returns the default list delegate with the selected
+// item marked by ">" instead of a bar
+func AccessibleDelegate() list.DefaultDelegate {
+	d := list.NewDefaultDelegate()
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Border(selectedMarker, false, false, false, true)
+	d.Styles.SelectedDesc = d.Styles.SelectedDesc.Border(selectedMarker, false, false, false, true)
+	return d
+}
+
+// AccessibleFilter drops the messages that would clear the printed
+// conversation or switch to the alternate screen
+func AccessibleFilter(_ tea.Model, msg tea.Msg) tea.Msg {
+	switch msg {
+	case tea.ClearScreen(), tea.EnterAltScreen():
+		return nil
+	}
+	return msg
+}
+
+// PrintNewMessages prints the messages added since the last call, each
+// prefixed with its role. A response still being generated is printed as its
+// lines complete.
+func (m *Model) PrintNewMessages() tea.Cmd {
+	// The conversation was cleared or replaced
+	if m.PrintedMessages > len(m.Messages) {
+		m.PrintedMessages = len(m.Messages)
+		m.PrintedLines = 0
+	}
+
+	var out []string
+	for m.PrintedMessages < len(m.Messages) {
+		msg := m.Messages[m.PrintedMessages]
+		content := m.printedContent(msg)
+		lines := strings.Split(content, "\n")
+		if len(msg.Attachments) > 0 {
+			lines = append(lines, "Attached: "+strings.Join(msg.Attachments, ", "))
+		}
+
+		generating := m.IsGenerating && m.PrintedMessages == len(m.Messages)-1
+		complete := len(lines)
+		if generating {
+			// The last line may still grow
+			complete = strings.Count(content, "\n")
+		}
+		for i := m.PrintedLines; i < complete; i++ {
+			line := lines[i]
+			if i == 0 {
+				line = rolePrefix(msg) + line
+			}
+			out = append(out, line)
+		}
+		m.PrintedLines = max(m.PrintedLines, complete)
+		if generating {
+			break
+		}
+		m.PrintedMessages++
+		m.PrintedLines = 0
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	return tea.Println(strings.Join(out, "\n"))
+}
+
+// rolePrefix names who a printed message is from
+func rolePrefix(msg models.Message) string {
+	switch msg.Role {
+	case models.RoleUser:
+		return "You: "
+	case models.RoleAssistant:
+		if msg.Model != "" {
+			return msg.Model + ": "
+		}
+		return "Assistant: "
+	default:
+		return "Note: "
+	}
+}
+
+// AccessibleChatView renders the chat screen as plain lines: the status, the
+// command suggestions and the input. The conversation itself was printed
+// above it.
+func (m Model) AccessibleChatView() string {
+	var lines []string
+	if m.State == StateLoading && m.IsGenerating {
+		lines = append(lines, fmt.Sprintf("Generating… %s: cancel", m.KeyMap.Help(ActionQuit)))
+	}
+	if popup := m.SlashPopupView(m.ScreenWidth - 8); m.HistorySearching {
+		lines = append(lines, m.HistorySearchView(m.ScreenWidth-8))
+	} else if popup != "" {
+		lines = append(lines, popup)
+	}
+	lines = append(lines, m.Input.View(), strings.TrimSpace(m.ChatStatusText()))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// citationInstruction asks the model to cite numbered sources when more than
+// one attachment is sent with a prompt
+const citationInstruction = "When you use information from the sources above, cite them inline " +
+	"with their bracketed number, e.g. [1] or [2]. Cite every source you rely on."
+
+// AttachmentRefs returns the @path and @url references found in a prompt.
+// Local paths are only included if the file exists.
+func AttachmentRefs(prompt string) []string {
+	var refs []string
+	for _, word := range strings.Fields(prompt) {
+		if !strings.HasPrefix(word, "@") || len(word) < 2 {
+			continue
+		}
+		ref := strings.TrimRight(word[1:], ",.;:!?)")
+		if utils.IsURL(ref) {
+			refs = append(refs, ref)
+			continue
+		}
+		if info, err := os.Stat(ref); err == nil && !info.IsDir() {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// CollectAttachments loads the pending attachments plus any referenced
+// inline in the prompt
+func (m Model) CollectAttachments(prompt string) ([]models.Attachment, error) {
+	attachments := append([]models.Attachment(nil), m.PendingAttachments...)
+	for _, ref := range AttachmentRefs(prompt) {
+		if hasAttachment(attachments, ref) {
+			continue
+		}
+		attachment, err := utils.LoadAttachment(ref)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// BuildPrompt assembles the text sent to the model from the user's prompt
+// and its attachments. Multiple attachments are numbered so the model can
+// cite them. Images are sent separately and left out of the text.
+func BuildPrompt(prompt string, attachments []models.Attachment) string {
+	attachments = TextAttachments(attachments)
+	if len(attachments) == 0 {
+		return prompt
+	}
+
+	var sb strings.Builder
+	if len(attachments) == 1 {
+		a := attachments[0]
+		sb.WriteString(fmt.Sprintf("Attached source: %s\n```\n%s\n```\n\n", a.Source, a.Content))
+		sb.WriteString(prompt)
+		return sb.String()
+	}
+
+	sb.WriteString("Sources:\n\n")
+	for i, a := range attachments {
+		sb.WriteString(fmt.Sprintf("[%d] %s\n```\n%s\n```\n\n", i+1, a.Source, a.Content))
+	}
+	sb.WriteString(citationInstruction)
+	sb.WriteString("\n\n")
+	sb.WriteString(prompt)
+	return sb.String()
+}
+
+// AttachmentSummary returns a short line naming the attached sources
+func AttachmentSummary(attachments []models.Attachment) string {
+	if len(attachments) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(attachments))
+	for _, a := range attachments {
+		names = append(names, a.Name)
+	}
+	return fmt.Sprintf("\n[attached: %s]", strings.Join(names, ", "))
+}
+
+// SourcesLegend renders the mapping from citation numbers to sources shown
+// under a response
+func SourcesLegend(attachments []models.Attachment) string {
+	var sb strings.Builder
+	sb.WriteString("Sources:")
+	for i, a := range attachments {
+		sb.WriteString(fmt.Sprintf("\n  [%d] %s", i+1, a.Name))
+		if a.Source != a.Name {
+			sb.WriteString(fmt.Sprintf(" (%s)", a.Source))
+		}
+	}
+	return sb.String()
+}
+
+func hasAttachment(attachments []models.Attachment, source string) bool {
+	for _, a := range attachments {
+		if a.Source == source {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "attach",
+		Usage:       "<path|url>",
+		Description: "Attach a file or URL to the next prompt (or use @path inline)",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				m.StatusMessage = "Usage: /attach <path|url>"
+				return nil
+			}
+			if hasAttachment(m.PendingAttachments, args) {
+				m.StatusMessage = fmt.Sprintf("%s is already attached", args)
+				return nil
+			}
+			attachment, err := utils.LoadAttachment(args)
+			if err != nil {
+				m.StatusMessage = err.Error()
+				return nil
+			}
+			m.PendingAttachments = append(m.PendingAttachments, attachment)
+			m.StatusMessage = fmt.Sprintf("Attached %s (%d pending)", attachment.Name, len(m.PendingAttachments))
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "detach",
+		Description: "Remove all pending attachments",
+		Run: func(m *Model, args string) tea.Cmd {
+			m.PendingAttachments = nil
+			m.PendingSchema = nil
+			m.StatusMessage = "Attachments cleared"
+			return nil
+		},
+	})
+}
@@ -0,0 +1,219 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/batch"
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// BatchChan is a channel for progress updates while a batch of prompts runs
+var BatchChan = make(chan BatchProgressMsg, 100)
+
+// BatchProgressMsg reports a prompt of the batch run with the given ID
+// starting or finishing, or the run ending
+type BatchProgressMsg struct {
+	ID    int
+	Index int
+	// Result is set once the prompt at Index finished
+	Result *batch.Result
+	Done   bool
+	Err    error
+}
+
+// BatchRun is a batch of prompts running in the background
+type BatchRun struct {
+	ID      int
+	Path    string
+	Dir     string
+	Total   int
+	Current int
+	// Completed and Failed count the prompts answered and those that failed
+	Completed int
+	Failed    int
+	cancel    context.CancelFunc
+}
+
+// StartBatch runs the prompts of the file one after another, each in a
+// conversation of its own, writing the results to dir (default a directory
+// next to the file)
+func (m *Model) StartBatch(path, dir string) tea.Cmd {
+	if m.Batch != nil {
+		m.StatusMessage = fmt.Sprintf("A batch is already running (%d/%d); /batch stop cancels it", m.Batch.Current+1, m.Batch.Total)
+		return nil
+	}
+	file, err := batch.Load(path)
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Failed to read the batch: %v", err)
+		return nil
+	}
+	if file.Model == "" {
+		file.Model = m.SelectedModel
+	}
+	if file.Model == "" {
+		m.StatusMessage = "Choose a model, or name one in the batch file, before running a batch"
+		return nil
+	}
+	if dir == "" {
+		dir = batch.OutputDir(path, time.Now())
+	}
+	writer, err := batch.NewWriter(dir)
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Failed to create %s: %v", dir, err)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.BatchID++
+	m.Batch = &BatchRun{ID: m.BatchID, Path: path, Dir: writer.Dir, Total: len(file.Prompts), cancel: cancel}
+	m.StatusMessage = fmt.Sprintf("Running %d prompts from %s", len(file.Prompts), path)
+	return RunBatchCmd(ctx, m.BatchID, APIClient, file, writer)
+}
+
+// RunBatchCmd runs the batch in the background, streaming its progress into
+// BatchChan
+func RunBatchCmd(ctx context.Context, id int, client *api.Client, file batch.File, writer *batch.Writer) tea.Cmd {
+	go func() {
+		for i, p := range file.Prompts {
+			if ctx.Err() != nil {
+				break
+			}
+			BatchChan <- BatchProgressMsg{ID: id, Index: i}
+			model, system := file.Run(p)
+			result := runBatchPrompt(ctx, client, model, system, p)
+			if ctx.Err() != nil {
+				break
+			}
+			if err := writer.Write(i, result); err != nil {
+				BatchChan <- BatchProgressMsg{ID: id, Done: true, Err: err}
+				return
+			}
+			BatchChan <- BatchProgressMsg{ID: id, Index: i, Result: &result}
+		}
+		BatchChan <- BatchProgressMsg{ID: id, Done: true, Err: ctx.Err()}
+	}()
+	return ListenForBatchProgressCmd()
+}
+
+// ListenForBatchProgressCmd waits for the next batch update
+func ListenForBatchProgressCmd() tea.Cmd {
+	return func() tea.Msg {
+		return <-BatchChan
+	}
+}
+
+// runBatchPrompt sends a prompt of a batch in a new conversation with the
+// chat's settings and the given system prompt
+func runBatchPrompt(ctx context.Context, client *api.Client, model, system string, p batch.Prompt) batch.Result {
+	client = client.NewConversation()
+	if system != "" {
+		client.SystemPrompt = system
+	}
+	result := batch.Result{
+		ID:       p.ID,
+		Model:    model,
+		System:   client.SystemPrompt,
+		Prompt:   p.Prompt,
+		Metadata: p.Metadata,
+	}
+
+	start := time.Now()
+	var sb strings.Builder
+	for event := range client.Stream(ctx, model, p.Prompt) {
+		switch event := event.(type) {
+		case api.TokenEvent:
+			sb.WriteString(event.Text)
+		case api.UsageEvent:
+			result.PromptTokens = event.Metadata.PromptEvalCount
+			result.CompletionTokens = event.Metadata.EvalCount
+		case api.ErrorEvent:
+			result.Error = event.Err.Error()
+		}
+	}
+	result.Time = time.Now()
+	result.DurationMS = result.Time.Sub(start).Milliseconds()
+	result.Response = models.StripReasoning(sb.String())
+	return result
+}
+
+// HandleBatchProgress follows the batch run and reports where its results
+// are once it ends
+func (m *Model) HandleBatchProgress(msg BatchProgressMsg) tea.Cmd {
+	run := m.Batch
+	if run == nil || msg.ID != run.ID {
+		// Updates of a cancelled run are drained until it stops
+		if msg.Done {
+			return nil
+		}
+		return ListenForBatchProgressCmd()
+	}
+
+	if !msg.Done {
+		run.Current = msg.Index
+		if msg.Result != nil {
+			run.Completed++
+			if msg.Result.Error != "" {
+				run.Failed++
+			}
+		}
+		return ListenForBatchProgressCmd()
+	}
+
+	m.Batch = nil
+	switch {
+	case msg.Err != nil:
+		m.AddNotice(fmt.Sprintf("Batch %s stopped after %d of %d prompts: %v\nResults so far are in %s", run.Path, run.Completed, run.Total, msg.Err, run.Dir))
+	case run.Failed > 0:
+		m.AddNotice(fmt.Sprintf("Batch %s finished: %d prompts, %d failed\nResults are in %s", run.Path, run.Total, run.Failed, run.Dir))
+	default:
+		m.AddNotice(fmt.Sprintf("Batch %s finished: %d prompts\nResults are in %s", run.Path, run.Total, run.Dir))
+	}
+	m.StatusMessage = "Batch finished"
+	return nil
+}
+
+// CancelBatch stops the batch run, dropping the prompt in progress
+func (m *Model) CancelBatch() {
+	if m.Batch == nil {
+		m.StatusMessage = "No batch is running"
+		return
+	}
+	m.Batch.cancel()
+	m.StatusMessage = "Stopping the batch…"
+}
+
+// BatchIndicator returns the status bar indicator of a running batch
+func (m Model) BatchIndicator() string {
+	if m.Batch == nil {
+		return ""
+	}
+	return fmt.Sprintf("▶ Batch %d/%d | ", m.Batch.Current+1, m.Batch.Total)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "batch",
+		Usage:       "<file> [output dir] | stop",
+		Description: "Run the prompts of a file one after another and save each response next to its prompt",
+		Run: func(m *Model, args string) tea.Cmd {
+			fields := strings.Fields(args)
+			switch {
+			case len(fields) == 0:
+				m.StatusMessage = "Usage: /batch <file> [output dir]"
+				return nil
+			case len(fields) == 1 && fields[0] == "stop":
+				m.CancelBatch()
+				return nil
+			case len(fields) == 1:
+				return m.StartBatch(fields[0], "")
+			}
+			return m.StartBatch(fields[0], fields[1])
+		},
+	})
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// sourceLine matches an entry of a sources legend, "  [1] https://…" or
+// "  [1] Name (https://…)"
+var sourceLine = regexp.MustCompile(`^\s+\[(\d+)\] (?:.*\()?(https?://[^\s)]+)\)?$`)
+
+// CitationsLegend lists the pages a response cites as numbered footnotes
+func CitationsLegend(urls []string) string {
+	var sb strings.Builder
+	sb.WriteString("Sources:")
+	for i, url := range urls {
+		sb.WriteString(fmt.Sprintf("\n  [%d] %s", i+1, url))
+	}
+	return sb.String()
+}
+
+// SourceURLs returns the URLs of the sources legend at the end of a
+// response, keyed by their number
+func SourceURLs(content string) map[int]string {
+	index := strings.LastIndex(content, "\nSources:")
+	if index < 0 {
+		return nil
+	}
+	urls := make(map[int]string)
+	for _, line := range strings.Split(content[index:], "\n") {
+		if match := sourceLine.FindStringSubmatch(line); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			urls[n] = match[2]
+		}
+	}
+	return urls
+}
+
+// OpenCitation opens the source numbered by the last digit of key, of the
+// selected response or the latest one with sources, in the browser
+func (m *Model) OpenCitation(key string) {
+	n, err := strconv.Atoi(key[len(key)-1:])
+	if err != nil {
+		return
+	}
+
+	index := m.SelectedMessage
+	if index < 0 {
+		for i := len(m.Messages) - 1; i >= 0; i-- {
+			if m.Messages[i].Role == models.RoleAssistant && len(SourceURLs(m.Messages[i].Content)) > 0 {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 || index >= len(m.Messages) {
+		m.StatusMessage = "No cited sources in the conversation"
+		return
+	}
+	url, ok := SourceURLs(m.Messages[index].Content)[n]
+	if !ok {
+		m.StatusMessage = fmt.Sprintf("The response has no source [%d]", n)
+		return
+	}
+	if err := utils.OpenURL(url); err != nil {
+		m.StatusMessage = fmt.Sprintf("Cannot open %s: %v", url, err)
+		return
+	}
+	m.StatusMessage = fmt.Sprintf("Opened source [%d]: %s", n, url)
+}
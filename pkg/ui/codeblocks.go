@@ -0,0 +1,480 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// Steps of saving a code block
+const (
+	// CodeBlockPick lists the blocks to choose from
+	CodeBlockPick = iota
+	// CodeBlockPath asks where to save the chosen block
+	CodeBlockPath
+	// CodeBlockOverwrite shows the changes to an existing file before
+	// replacing it
+	CodeBlockOverwrite
+)
+
+// codeBlockListHeight is how many blocks are listed at once
+const codeBlockListHeight = 6
+
+// diffContextLines is how many unchanged lines surround each change
+const diffContextLines = 3
+
+// responseIndex returns the selected response, or the latest one, or -1
+func (m Model) responseIndex() int {
+	if m.SelectedMessage >= 0 && m.SelectedMessage < len(m.Messages) && m.Messages[m.SelectedMessage].Role == models.RoleAssistant {
+		return m.SelectedMessage
+	}
+	for i := len(m.Messages) - 1; i >= 0; i-- {
+		if m.Messages[i].Role == models.RoleAssistant {
+			return i
+		}
+	}
+	return -1
+}
+
+// attachedFiles returns the files attached to the prompt that the response
+// at index answers
+func (m Model) attachedFiles(index int) []string {
+	for i := index - 1; i >= 0; i-- {
+		if m.Messages[i].Role != models.RoleUser {
+			continue
+		}
+		var files []string
+		for _, source := range m.Messages[i].Attachments {
+			if utils.IsURL(source) || utils.IsImagePath(source) {
+				continue
+			}
+			if info, err := os.Stat(utils.ExpandHome(source)); err == nil && !info.IsDir() {
+				files = append(files, source)
+			}
+		}
+		return files
+	}
+	return nil
+}
+
+// EditTarget returns the attached file a code block rewrites: the one it is
+// named after, or the only attached file when an unnamed block is in the
+// same language
+func EditTarget(block utils.CodeBlock, files []string) string {
+	if block.Filename != "" {
+		for _, file := range files {
+			if file == block.Filename || strings.HasSuffix(file, "/"+block.Filename) ||
+				strings.HasSuffix(block.Filename, "/"+filepath.Base(file)) || filepath.Base(file) == block.Filename {
+				return file
+			}
+		}
+		return ""
+	}
+	if len(files) == 1 && block.Lang != "" {
+		if filepath.Ext(files[0]) == filepath.Ext(block.SuggestedFilename()) {
+			return files[0]
+		}
+	}
+	return ""
+}
+
+// ResponseEdits returns the attached files the response at index rewrites
+func (m Model) ResponseEdits(index int) []string {
+	files := m.attachedFiles(index)
+	if len(files) == 0 {
+		return nil
+	}
+	var edits []string
+	for _, block := range utils.ExtractCodeBlocks(m.Messages[index].Answer()) {
+		if target := EditTarget(block, files); target != "" && !containsString(edits, target) {
+			edits = append(edits, target)
+		}
+	}
+	return edits
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenCodeBlocks lists the code blocks of the selected response, or of the
+// latest one. With review set it goes straight to the changes the response
+// makes to an attached file.
+func (m *Model) OpenCodeBlocks(review bool) tea.Cmd {
+	index := m.responseIndex()
+	if index < 0 {
+		m.StatusMessage = "No response to save code from"
+		return nil
+	}
+
+	blocks := utils.ExtractCodeBlocks(m.Messages[index].Answer())
+	if len(blocks) == 0 {
+		m.StatusMessage = "The response has no code blocks"
+		return nil
+	}
+
+	files := m.attachedFiles(index)
+	targets := make([]string, len(blocks))
+	first := -1
+	for i, block := range blocks {
+		targets[i] = EditTarget(block, files)
+		if targets[i] != "" && first < 0 {
+			first = i
+		}
+	}
+	if review && first < 0 {
+		m.StatusMessage = "The response does not change any attached file"
+		return nil
+	}
+
+	m.CodeBlocks = blocks
+	m.CodeBlockTargets = targets
+	m.CodeBlockIndex = 0
+	m.CodeBlockStep = CodeBlockPick
+	m.CodeBlockStatus = ""
+	m.CodeBlockReturnState = m.State
+	m.State = StateCodeBlocks
+	m.ShowCodeBlock()
+
+	cmd := tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	if review {
+		m.CodeBlockIndex = first
+		m.CodeBlockPathInput.SetValue(targets[first])
+		m.SaveCodeBlock(false)
+	}
+	return cmd
+}
+
+// UpdateCodeBlocks handles keys on the code blocks screen
+func (m Model) UpdateCodeBlocks(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, m.RequestQuit()
+	}
+
+	switch m.CodeBlockStep {
+	case CodeBlockPick:
+		switch msg.String() {
+		case "esc", "q":
+			m.State = m.CodeBlockReturnState
+			return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+		case "up", "k":
+			if m.CodeBlockIndex > 0 {
+				m.CodeBlockIndex--
+				m.ShowCodeBlock()
+			}
+			return m, nil
+		case "down", "j":
+			if m.CodeBlockIndex < len(m.CodeBlocks)-1 {
+				m.CodeBlockIndex++
+				m.ShowCodeBlock()
+			}
+			return m, nil
+		case "enter":
+			m.CodeBlockStatus = ""
+			// Changes to an attached file go straight to the diff
+			if target := m.CodeBlockTargets[m.CodeBlockIndex]; target != "" {
+				m.CodeBlockPathInput.SetValue(target)
+				return m, m.SaveCodeBlock(false)
+			}
+			m.CodeBlockStep = CodeBlockPath
+			m.CodeBlockPathInput.SetValue(m.CodeBlocks[m.CodeBlockIndex].SuggestedFilename())
+			m.CodeBlockPathInput.CursorEnd()
+			return m, m.CodeBlockPathInput.Focus()
+		}
+
+	case CodeBlockPath:
+		switch msg.String() {
+		case "esc":
+			m.CodeBlockStep = CodeBlockPick
+			m.CodeBlockPathInput.Blur()
+			m.CodeBlockStatus = ""
+			return m, nil
+		case "enter":
+			return m, m.SaveCodeBlock(false)
+		}
+		var cmd tea.Cmd
+		m.CodeBlockPathInput, cmd = m.CodeBlockPathInput.Update(msg)
+		return m, cmd
+
+	case CodeBlockOverwrite:
+		switch msg.String() {
+		case "y", "a":
+			return m, m.SaveCodeBlock(true)
+		case "d":
+			m.CodeBlockShowDiff = !m.CodeBlockShowDiff
+			m.ShowCodeBlock()
+			return m, nil
+		case "v":
+			m.CodeBlockSideBySide = !m.CodeBlockSideBySide
+			m.CodeBlockShowDiff = true
+			m.ShowCodeBlock()
+			return m, nil
+		case "esc", "n":
+			m.CodeBlockStatus = ""
+			if m.CodeBlockTargets[m.CodeBlockIndex] != "" {
+				m.CodeBlockStep = CodeBlockPick
+				m.ShowCodeBlock()
+				return m, nil
+			}
+			m.CodeBlockStep = CodeBlockPath
+			m.ShowCodeBlock()
+			return m, m.CodeBlockPathInput.Focus()
+		}
+	}
+
+	var cmd tea.Cmd
+	m.CodeViewport, cmd = m.CodeViewport.Update(msg)
+	return m, cmd
+}
+
+// SaveCodeBlock writes the chosen block to the entered path. An existing
+// file with different content is only replaced when overwrite is set;
+// otherwise the changes are shown for confirmation.
+func (m *Model) SaveCodeBlock(overwrite bool) tea.Cmd {
+	name := strings.TrimSpace(m.CodeBlockPathInput.Value())
+	if name == "" {
+		m.CodeBlockStatus = "Enter a path"
+		return nil
+	}
+	path := utils.ExpandHome(name)
+	code := m.CodeBlocks[m.CodeBlockIndex].Code + "\n"
+	editing := name == m.CodeBlockTargets[m.CodeBlockIndex]
+
+	existing, err := os.ReadFile(path)
+	switch {
+	case err == nil && string(existing) == code:
+		m.CodeBlockStatus = fmt.Sprintf("%s already has this content", name)
+		return nil
+	case err == nil && !overwrite:
+		m.CodeBlockStep = CodeBlockOverwrite
+		m.CodeBlockShowDiff = true
+		m.CodeBlockExisting = string(existing)
+		m.CodeBlockPathInput.Blur()
+		if editing {
+			m.CodeBlockStatus = fmt.Sprintf("Apply these changes to %s?", name)
+		} else {
+			m.CodeBlockStatus = fmt.Sprintf("%s exists. Overwrite it?", name)
+		}
+		m.ShowCodeBlock()
+		return nil
+	case err != nil && !os.IsNotExist(err):
+		m.CodeBlockStatus = fmt.Sprintf("Cannot read %s: %v", name, err)
+		return nil
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			m.CodeBlockStatus = fmt.Sprintf("Cannot create %s: %v", dir, err)
+			return nil
+		}
+	}
+	// Existing files keep their permissions
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		m.CodeBlockStatus = fmt.Sprintf("Cannot write %s: %v", name, err)
+		return nil
+	}
+
+	m.CodeBlockPathInput.Blur()
+	m.State = m.CodeBlockReturnState
+	if editing {
+		m.StatusMessage = fmt.Sprintf("Applied the changes to %s", name)
+	} else {
+		m.StatusMessage = fmt.Sprintf("Saved %s", name)
+	}
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// ShowCodeBlock fills the preview with the chosen block, or with its diff
+// against the file it would replace
+func (m *Model) ShowCodeBlock() {
+	if len(m.CodeBlocks) == 0 {
+		return
+	}
+	code := m.CodeBlocks[m.CodeBlockIndex].Code
+
+	if m.CodeBlockStep != CodeBlockOverwrite || !m.CodeBlockShowDiff {
+		m.CodeViewport.SetContent(code)
+		m.CodeViewport.GotoTop()
+		return
+	}
+
+	lines, ok := utils.LineDiff(m.CodeBlockExisting, code)
+	if !ok {
+		m.CodeViewport.SetContent(CollapsedStyle.Render("The files are too large to compare."))
+		return
+	}
+	hunks := utils.DiffHunks(lines, diffContextLines)
+	if m.CodeBlockSideBySide {
+		m.CodeViewport.SetContent(RenderSideBySide(hunks, m.CodeViewport.Width))
+	} else {
+		m.CodeViewport.SetContent(RenderUnifiedDiff(hunks))
+	}
+	m.CodeViewport.GotoTop()
+}
+
+// RenderUnifiedDiff renders diff lines with -/+ markers
+func RenderUnifiedDiff(lines []utils.DiffLine) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		switch l.Kind {
+		case '+':
+			sb.WriteString(DiffAddedStyle.Render("+" + l.Text))
+		case '-':
+			sb.WriteString(DiffRemovedStyle.Render("-" + l.Text))
+		case '@':
+			sb.WriteString(CollapsedStyle.Render(l.Text))
+		default:
+			sb.WriteString(" " + l.Text)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// RenderSideBySide renders diff lines in two columns, the old file on the
+// left and the new one on the right. Removed and added lines next to each
+// other are paired up as changed lines.
+func RenderSideBySide(lines []utils.DiffLine, width int) string {
+	column := max((width-3)/2, 10)
+	cell := func(text string, style lipgloss.Style) string {
+		runes := []rune(strings.ReplaceAll(text, "\t", "    "))
+		if len(runes) > column {
+			runes = append(runes[:column-1], '…')
+		}
+		return style.Render(fmt.Sprintf("%-*s", column, string(runes)))
+	}
+	plain := lipgloss.NewStyle()
+	separator := CollapsedStyle.Render(" │ ")
+
+	var rows []string
+	for i := 0; i < len(lines); {
+		switch lines[i].Kind {
+		case '@':
+			rows = append(rows, CollapsedStyle.Render(lines[i].Text))
+			i++
+		case ' ':
+			rows = append(rows, cell(lines[i].Text, plain)+separator+cell(lines[i].Text, plain))
+			i++
+		default:
+			// Collect a run of removals and additions and pair them up
+			var removed, added []string
+			for ; i < len(lines) && lines[i].Kind == '-'; i++ {
+				removed = append(removed, lines[i].Text)
+			}
+			for ; i < len(lines) && lines[i].Kind == '+'; i++ {
+				added = append(added, lines[i].Text)
+			}
+			for j := 0; j < max(len(removed), len(added)); j++ {
+				left, right := cell("", plain), cell("", plain)
+				if j < len(removed) {
+					left = cell(removed[j], DiffRemovedStyle)
+				}
+				if j < len(added) {
+					right = cell(added[j], DiffAddedStyle)
+				}
+				rows = append(rows, left+separator+right)
+			}
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// ResizeCodeBlocks fits the preview below the list of blocks
+func (m *Model) ResizeCodeBlocks(width, height int) {
+	listHeight := min(len(m.CodeBlocks), codeBlockListHeight)
+	m.CodeViewport.Width = width - 4
+	m.CodeViewport.Height = max(height-listHeight-6, 3)
+	m.CodeBlockPathInput.Width = width - 16
+	m.ShowCodeBlock()
+}
+
+// CodeBlocksView renders the code blocks screen
+func (m Model) CodeBlocksView() string {
+	// Scroll the list so the chosen block stays visible
+	start := 0
+	if m.CodeBlockIndex >= codeBlockListHeight {
+		start = m.CodeBlockIndex - codeBlockListHeight + 1
+	}
+	var list []string
+	for i := start; i < len(m.CodeBlocks) && i < start+codeBlockListHeight; i++ {
+		block := m.CodeBlocks[i]
+		label := fmt.Sprintf("%d. %s", i+1, block.SuggestedFilename())
+		if target := m.CodeBlockTargets[i]; target != "" {
+			label = fmt.Sprintf("%d. ✎ %s", i+1, target)
+		}
+		if block.Lang != "" {
+			label += " · " + block.Lang
+		}
+		label += fmt.Sprintf(" · %d lines", strings.Count(block.Code, "\n")+1)
+		if i == m.CodeBlockIndex {
+			list = append(list, SlashSelectedStyle.Render("▶ "+label))
+		} else {
+			list = append(list, "  "+label)
+		}
+	}
+
+	help := "↑/↓: choose | Enter: save | Esc: back"
+	footer := ""
+	switch m.CodeBlockStep {
+	case CodeBlockPath:
+		help = "Enter: save | Esc: back"
+		footer = "Save to: " + m.CodeBlockPathInput.View()
+	case CodeBlockOverwrite:
+		layout := "side-by-side"
+		if m.CodeBlockSideBySide {
+			layout = "unified"
+		}
+		help = fmt.Sprintf("y: apply | d: diff/full block | v: %s | Esc: back", layout)
+		footer = m.CodeBlockStatus
+	}
+	if m.CodeBlockStatus != "" && m.CodeBlockStep != CodeBlockOverwrite {
+		help = m.CodeBlockStatus
+	}
+
+	preview := lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderTop(true).
+		BorderBottom(true).
+		BorderForeground(CurrentTheme.Subtle).
+		Render(m.CodeViewport.View())
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		TitleStyle.Render(fmt.Sprintf("Code blocks (%d)", len(m.CodeBlocks))),
+		lipgloss.NewStyle().Padding(0, 2).Render(strings.Join(list, "\n")),
+		lipgloss.NewStyle().Padding(0, 2).Render(preview),
+		lipgloss.NewStyle().Padding(0, 2).Render(footer),
+		HelpStyle.Render(help),
+	)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "code",
+		Description: "Save a code block from the latest response to a file",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenCodeBlocks(false)
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "diff",
+		Description: "Review and apply the changes the latest response makes to attached files",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenCodeBlocks(true)
+		},
+	})
+}
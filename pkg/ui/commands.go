@@ -2,32 +2,54 @@ package ui
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
 )
 
 var (
-	// TokenChan is a channel for token messages
-	TokenChan chan TokenMsg
 	// APIClient is the API client
 	APIClient *api.Client
+	// Program is the running program, which streamed tokens are sent to
+	Program *tea.Program
 )
 
-// Initialize the token channel
 func init() {
-	TokenChan = make(chan TokenMsg, 100)
 	APIClient = api.NewClient("", "")
 }
 
+// OpenAIAPIKey returns the OpenAI API key from the environment or the config file
+func OpenAIAPIKey() string {
+	if apiKey := utils.GetEnv("OPENAI_API_KEY", ""); apiKey != "" {
+		return apiKey
+	}
+	if config, err := utils.LoadConfig(); err == nil {
+		return config.OpenAIAPIKey
+	}
+	return ""
+}
+
+// OpenAIKeyRequired reports whether the openai provider talks to OpenAI
+// itself, which needs an API key, rather than a compatible server
+func OpenAIKeyRequired() bool {
+	return api.OpenAIURL == api.DefaultOpenAIURL
+}
+
 // FetchModelsCmd fetches the list of available models for the specified provider
 func FetchModelsCmd(provider string, apiKey string) tea.Cmd {
+	// Create a new API client for the selected provider
+	APIClient = api.NewClient(provider, apiKey)
+	client := APIClient
 	return func() tea.Msg {
-		// Create a new API client for the selected provider
-		APIClient = api.NewClient(provider, apiKey)
-
-		models, err := APIClient.FetchModels()
+		models, err := client.FetchModels()
 		if err != nil {
 			return ErrorMsg{Err: err}
 		}
@@ -35,37 +57,201 @@ func FetchModelsCmd(provider string, apiKey string) tea.Cmd {
 	}
 }
 
-// ListenForTokensCmd listens for token messages
-func ListenForTokensCmd() tea.Cmd {
+// RefreshLayoutCmd sends a window size message so the layout is recomputed
+// for the current state
+func RefreshLayoutCmd(width, height int) tea.Cmd {
 	return func() tea.Msg {
-		return <-TokenChan
+		return tea.WindowSizeMsg{Width: width, Height: height}
 	}
 }
 
-// StartGenerateResponseCmd starts generating a response
-func StartGenerateResponseCmd(model, prompt string) tea.Cmd {
-	return func() tea.Msg {
-		ctx, cancel := context.WithCancel(context.Background())
+// OpenEditorCmd suspends the TUI and opens $VISUAL or $EDITOR on a temporary
+// file containing content. The edited text is returned in an EditorFinishedMsg.
+func OpenEditorCmd(content string) tea.Cmd {
+	return editFileCmd(content, "ollama-tui-prompt-*.md", func(content string, err error) tea.Msg {
+		return EditorFinishedMsg{Content: content, Err: err}
+	})
+}
+
+// editFileCmd opens the user's editor on a temporary file named after
+// pattern and reports the edited content through done
+func editFileCmd(content, pattern string, done func(string, error) tea.Msg) tea.Cmd {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return func() tea.Msg { return done("", err) }
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return func() tea.Msg { return done("", err) }
+	}
+	file.Close()
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Allow editors configured with arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], file.Name())...)
+
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		defer os.Remove(file.Name())
+		if err != nil {
+			return done("", err)
+		}
+		data, err := os.ReadFile(file.Name())
+		if err != nil {
+			return done("", err)
+		}
+		return done(strings.TrimRight(string(data), "\n"), nil)
+	})
+}
 
-		cmds := []tea.Cmd{
-			func() tea.Msg {
-				return SetCancelFuncMsg{Cancel: cancel}
-			},
+// StartGenerateResponseCmd starts generating a response and returns the
+// command that streams it. A positive timeout cancels the generation once the
+// wall-clock limit is reached.
+func (m *Model) StartGenerateResponseCmd(model, prompt string, timeout time.Duration) tea.Cmd {
+	ctx, cancel := generationContext(timeout)
+	m.GenerationID++
+	m.CancelGenerate = cancel
+	m.resetProgress()
+
+	tab, id := m.TabID, m.GenerationID
+	events := APIClient.Stream(ctx, model, prompt)
+	var coldStart tea.Cmd
+	if m.SelectedProvider == "ollama" {
+		coldStart = ColdStartCmd(tab, id, model)
+	}
+	return tea.Batch(coldStart, func() tea.Msg {
+		end := forwardTokens(events, func(token string) tea.Msg {
+			return TokenMsg{Tab: tab, ID: id, Token: token}
+		})
+		stopped := ctx.Err() == context.Canceled
+		cancel()
+		return TokenMsg{
+			Tab:       tab,
+			ID:        id,
+			Done:      true,
+			TimedOut:  ctx.Err() == context.DeadlineExceeded,
+			Stopped:   stopped,
+			Err:       end.Err,
+			Metadata:  end.Metadata,
+			Citations: end.Citations,
+			Request:   end.Request,
+			Reason:    end.Reason,
+			Exchanges: end.Exchanges,
 		}
+	})
+}
 
-		go generateResponseAsync(ctx, model, prompt, func(token string, done bool) {
-			TokenChan <- TokenMsg{Token: token, Done: done}
+// CompareResponseCmd streams the response of one comparison pane, tagged
+// with its round and pane index
+func CompareResponseCmd(ctx context.Context, round, pane int, client *api.Client, model, prompt string) tea.Cmd {
+	events := client.Stream(ctx, model, prompt)
+	return func() tea.Msg {
+		end := forwardTokens(events, func(token string) tea.Msg {
+			return CompareTokenMsg{Round: round, Pane: pane, Token: token}
 		})
+		return CompareTokenMsg{
+			Round:    round,
+			Pane:     pane,
+			Done:     true,
+			TimedOut: ctx.Err() == context.DeadlineExceeded,
+			Err:      end.Err,
+		}
+	}
+}
 
-		cmds = append(cmds, ListenForTokensCmd())
-		return tea.Batch(cmds...)()
+// generationContext returns the context a generation runs in, cancelled
+// after timeout when it is positive
+func generationContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
 	}
+	return context.WithCancel(context.Background())
 }
 
-// generateResponseAsync generates a response asynchronously
-func generateResponseAsync(ctx context.Context, model, prompt string, callback func(string, bool)) {
-	err := APIClient.GenerateResponse(ctx, model, prompt, callback)
-	if err != nil {
-		callback("", true)
+// streamEnd is how a streamed response ended
+type streamEnd struct {
+	Metadata  *models.ResponseMetadata
+	Citations []string
+	// Request is the first request of the response, when recorded
+	Request *models.RecordedRequest
+	// Reason is why the model stopped, such as "length" at the token limit
+	Reason string
+	// Exchanges are the HTTP requests of the response and their raw replies
+	Exchanges []api.Exchange
+	Err       error
+}
+
+// forwardTokens sends each token of a streamed response to the program as
+// the message built by token, and returns how the response ended once the
+// stream closes
+func forwardTokens(events <-chan api.Event, token func(string) tea.Msg) streamEnd {
+	var end streamEnd
+	for event := range events {
+		switch event := event.(type) {
+		case api.TokenEvent:
+			if Program != nil {
+				Program.Send(token(event.Text))
+			}
+		case api.RateLimitEvent:
+			if Program != nil {
+				Program.Send(RateLimitMsg{Provider: event.Provider, Limit: event.Limit, Wait: event.Wait, Attempt: event.Attempt})
+			}
+		case api.CitationsEvent:
+			end.Citations = event.URLs
+		case api.UsageEvent:
+			end.Metadata = &event.Metadata
+		case api.RequestEvent:
+			if end.Request == nil {
+				end.Request = &event.Request
+			}
+		case api.InspectEvent:
+			end.Exchanges = event.Exchanges
+		case api.DoneEvent:
+			end.Reason = event.Reason
+		case api.ErrorEvent:
+			end.Err = event.Err
+		}
+	}
+	return end
+}
+
+// titlePrompt asks the model for a short session title
+const titlePrompt = "Write a short title of at most six words for a conversation that starts with " +
+	"the exchange below. Reply with the title only, without quotes or punctuation at the end.\n\n" +
+	"User: %s\n\nAssistant: %s"
+
+// GenerateTitleCmd asks the model for a session title of a tab in the
+// background, without touching the conversation context
+func GenerateTitleCmd(tab int, model, prompt, response string) tea.Cmd {
+	client := APIClient.Detached()
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		title, err := client.Complete(ctx, model, fmt.Sprintf(titlePrompt, truncate(prompt, 1000), truncate(response, 1000)))
+		if err != nil {
+			return SessionTitleMsg{Tab: tab, Err: err}
+		}
+		return SessionTitleMsg{Tab: tab, Title: CleanTitle(title)}
+	}
+}
+
+// CleanTitle keeps the first line of a generated title without quotes or
+// trailing punctuation
+func CleanTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if line, _, ok := strings.Cut(title, "\n"); ok {
+		title = line
 	}
+	title = strings.TrimPrefix(title, "Title:")
+	title = strings.Trim(title, " \"'`*#.")
+	return truncate(title, 60)
 }
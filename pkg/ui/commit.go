@@ -0,0 +1,228 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/git"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// MaxCommitDiffBytes is how much of the staged diff is sent to the model
+const MaxCommitDiffBytes = 16000
+
+// commitTimeout limits writing a commit message and running git commit
+const commitTimeout = 2 * time.Minute
+
+// commitPrompt asks for a commit message in the usual git style
+const commitPrompt = `Write a git commit message for the staged changes below.
+
+Rules:
+- Start with a subject line in the imperative mood ("Add", "Fix", "Remove"), at most 72 characters, without a trailing period.
+- If the change is not obvious from the subject, add a blank line and a short body wrapped at 72 characters explaining what changed and why.
+- Describe the change itself, not the files; do not list every file.
+- Reply with only the commit message, without quotes, Markdown or commentary.
+%s
+Files changed:
+%s
+
+Diff:
+%s`
+
+// CommitProposal is a generated commit message waiting for confirmation
+type CommitProposal struct {
+	Message string
+	Stat    string
+	// Truncated is set when only part of the diff was sent to the model
+	Truncated bool
+	// Hint is the extra guidance given to /commit, kept for regenerating
+	Hint string
+}
+
+// GenerateCommitMessageCmd reads the staged diff and asks the model for a
+// commit message describing it
+func GenerateCommitMessageCmd(model, hint string) tea.Cmd {
+	client := APIClient.Detached()
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+		defer cancel()
+
+		diff, stat, err := git.StagedDiff(ctx)
+		if err != nil {
+			return CommitMessageMsg{Hint: hint, Err: err}
+		}
+		diff, truncated := git.TruncateDiff(diff, MaxCommitDiffBytes)
+
+		guidance := ""
+		if hint != "" {
+			guidance = fmt.Sprintf("- Take this into account: %s\n", hint)
+		}
+		response, err := client.Complete(ctx, model, fmt.Sprintf(commitPrompt, guidance, stat, diff))
+		if err != nil {
+			return CommitMessageMsg{Hint: hint, Err: err}
+		}
+		return CommitMessageMsg{
+			Message:   CleanCommitMessage(response),
+			Stat:      stat,
+			Truncated: truncated,
+			Hint:      hint,
+		}
+	}
+}
+
+// CleanCommitMessage strips the fences and quotes models put around a
+// commit message and trims trailing spaces from its lines
+func CleanCommitMessage(response string) string {
+	message := strings.TrimSpace(response)
+	if strings.HasPrefix(message, "```") {
+		message = message[strings.Index(message, "\n")+1:]
+		if end := strings.LastIndex(message, "```"); end >= 0 {
+			message = message[:end]
+		}
+	}
+	message = strings.Trim(strings.TrimSpace(message), "\"`")
+
+	lines := strings.Split(message, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// HandleCommitMessage shows a generated commit message for confirmation
+func (m *Model) HandleCommitMessage(msg CommitMessageMsg) {
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("Cannot write a commit message: %v", msg.Err)
+		return
+	}
+	if msg.Message == "" {
+		m.StatusMessage = "The model did not return a commit message"
+		return
+	}
+	m.CommitProposal = &CommitProposal{
+		Message:   msg.Message,
+		Stat:      msg.Stat,
+		Truncated: msg.Truncated,
+		Hint:      msg.Hint,
+	}
+	m.StatusMessage = ""
+}
+
+// CommitCmd commits the staged changes with message
+func CommitCmd(message string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+		defer cancel()
+		output, err := git.Commit(ctx, message)
+		return CommitDoneMsg{Output: output, Err: err}
+	}
+}
+
+// HandleCommitDone reports the result of git commit in the transcript
+func (m *Model) HandleCommitDone(msg CommitDoneMsg) {
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("git commit failed: %v", msg.Err)
+		return
+	}
+	m.AddNotice("git commit\n" + msg.Output)
+	m.StatusMessage = "Committed"
+}
+
+// UpdateCommitConfirm handles keys while a commit message waits for approval
+func (m Model) UpdateCommitConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	proposal := m.CommitProposal
+	switch msg.String() {
+	case "y", "enter":
+		m.CommitProposal = nil
+		m.StatusMessage = "Committing…"
+		return m, CommitCmd(proposal.Message)
+	case "e":
+		m.CommitProposal = nil
+		return m, editFileCmd(proposal.Message, "COMMIT_EDITMSG-*", func(content string, err error) tea.Msg {
+			return CommitMessageEditedMsg{Proposal: *proposal, Content: content, Err: err}
+		})
+	case "r":
+		m.CommitProposal = nil
+		m.StatusMessage = "Writing another commit message…"
+		return m, GenerateCommitMessageCmd(m.SelectedModel, proposal.Hint)
+	}
+
+	m.CommitProposal = nil
+	m.StatusMessage = "Commit cancelled"
+	return m, nil
+}
+
+// HandleCommitMessageEdited shows the edited message for confirmation again
+func (m *Model) HandleCommitMessageEdited(msg CommitMessageEditedMsg) tea.Cmd {
+	refresh := tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("Editor failed: %v", msg.Err)
+		return refresh
+	}
+	message := CleanCommitMessage(msg.Content)
+	if message == "" {
+		m.StatusMessage = "Commit cancelled: empty message"
+		return refresh
+	}
+	proposal := msg.Proposal
+	proposal.Message = message
+	m.CommitProposal = &proposal
+	return refresh
+}
+
+// CommitConfirmView renders the dialog asking to commit with a message
+func (m Model) CommitConfirmView() string {
+	proposal := m.CommitProposal
+	width := m.ScreenWidth - 12
+	if width > 100 {
+		width = 100
+	}
+
+	lines := []string{TitleStyle.Render("Commit with this message?"), ""}
+	message := strings.SplitN(proposal.Message, "\n", 2)
+	lines = append(lines, AssistantHeaderStyle.Render(utils.WrapText(message[0], width)))
+	if len(message) > 1 {
+		lines = append(lines, utils.WrapText(message[1], width))
+	}
+	// Long file lists keep their first entries and the summary line
+	stat := strings.Split(proposal.Stat, "\n")
+	if len(stat) > 12 {
+		stat = append(append(stat[:10:10], "  …"), stat[len(stat)-1])
+	}
+	lines = append(lines, "", CollapsedStyle.Render(strings.Join(stat, "\n")))
+	if proposal.Truncated {
+		lines = append(lines, CollapsedStyle.Render("The diff was too long and was shortened for the model."))
+	}
+	lines = append(lines,
+		"",
+		"y / enter   git commit",
+		"e           edit the message",
+		"r           write another one",
+		"any key     cancel",
+	)
+
+	return lipgloss.Place(
+		m.ScreenWidth,
+		m.ScreenHeight,
+		lipgloss.Center,
+		lipgloss.Center,
+		InputBoxStyle.Copy().Padding(1, 2).Render(lipgloss.JoinVertical(lipgloss.Left, lines...)),
+	)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "commit",
+		Usage:       "[hint]",
+		Description: "Write a commit message for the staged changes and commit after confirming",
+		Run: func(m *Model, args string) tea.Cmd {
+			m.StatusMessage = "Writing a commit message…"
+			return GenerateCommitMessageCmd(m.SelectedModel, args)
+		},
+	})
+}
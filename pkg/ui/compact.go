@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// CompactScreenWidth and CompactScreenHeight are the sizes below which the
+	// chat screen drops its title, borders and spacing. The full layout takes
+	// 11 lines around a transcript of 5.
+	CompactScreenWidth  = 40
+	CompactScreenHeight = 16
+	// MinScreenWidth and MinScreenHeight are the smallest screen the app is
+	// drawn on; below it a notice asks for a bigger terminal
+	MinScreenWidth  = 20
+	MinScreenHeight = 5
+)
+
+// compact reports whether the screen is too small for the full chat layout
+func (m Model) compact() bool {
+	if m.ScreenWidth <= 0 || m.ScreenHeight <= 0 {
+		return false
+	}
+	return m.ScreenWidth < CompactScreenWidth || m.ScreenHeight < CompactScreenHeight
+}
+
+// tooSmall reports whether the screen is smaller than anything is drawn on
+func (m Model) tooSmall() bool {
+	if m.Accessible || m.ScreenWidth <= 0 || m.ScreenHeight <= 0 {
+		return false
+	}
+	return m.ScreenWidth < MinScreenWidth || m.ScreenHeight < MinScreenHeight
+}
+
+// TooSmallView asks for a bigger terminal, in as much of it as there is
+func (m Model) TooSmallView() string {
+	lines := []string{
+		"Terminal too small",
+		fmt.Sprintf("%dx%d, needs %dx%d", m.ScreenWidth, m.ScreenHeight, MinScreenWidth, MinScreenHeight),
+	}
+	for i, line := range lines {
+		lines[i] = truncate(line, m.ScreenWidth)
+	}
+	if len(lines) > m.ScreenHeight {
+		lines = lines[:m.ScreenHeight]
+	}
+	return TitleStyle.Copy().UnsetMargins().Render(strings.Join(lines, "\n"))
+}
+
+// compactStatus shortens the status bar to what fits on a small screen: a
+// marker while a response streams in, the new lines below and the status
+// message or the model
+func (m Model) compactStatus(width int) string {
+	var parts []string
+	if m.State == StateLoading && m.IsGenerating {
+		parts = append(parts, "●")
+	}
+	if m.NewLines > 0 {
+		parts = append(parts, fmt.Sprintf("↓%d", m.NewLines))
+	}
+	text := m.StatusMessage
+	if text == "" {
+		text = m.SelectedModel
+	}
+	parts = append(parts, text)
+	return truncate(strings.Join(parts, " "), width)
+}
+
+// layoutCompact lays the chat screen out for small terminals: the
+// transcript without borders or margins, a one-line input box without a
+// border and a shortened status line
+func (m Model) layoutCompact(width, height int) chatLayout {
+	input := m.Input.View()
+	status := StatusBarStyle.Copy().Width(width).Render(m.compactStatus(width))
+	return chatLayout{
+		compact:        true,
+		width:          width,
+		height:         height,
+		viewportWidth:  width,
+		input:          input,
+		status:         status,
+		viewportHeight: max(height-lipgloss.Height(input)-lipgloss.Height(status), 1),
+		viewportStyle:  lipgloss.NewStyle(),
+	}
+}
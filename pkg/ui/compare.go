@@ -0,0 +1,349 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// MaxComparePanes is the largest number of models that can be compared at once
+const MaxComparePanes = 4
+
+// ComparePane holds one model's side of a comparison and its generation stats
+type ComparePane struct {
+	Provider string
+	Model    string
+	// Variant names the settings of an A/B comparison side
+	Variant    string
+	Client     *api.Client
+	Response   string
+	Start      time.Time
+	FirstToken time.Duration
+	Duration   time.Duration
+	Done       bool
+	TimedOut   bool
+	Err        error
+	Cancel     context.CancelFunc
+}
+
+// Label returns the pane title, including the provider when it differs from
+// the one selected for the chat and the settings of an A/B side
+func (p ComparePane) Label(defaultProvider string) string {
+	label := p.Model
+	if p.Provider != defaultProvider {
+		label = p.Provider + ":" + p.Model
+	}
+	if p.Variant != "" {
+		label += " · " + p.Variant
+	}
+	return label
+}
+
+// Stats summarizes the pane's generation speed
+func (p ComparePane) Stats() string {
+	if p.Start.IsZero() {
+		return "waiting for a prompt"
+	}
+
+	elapsed := p.Duration
+	if !p.Done {
+		elapsed = time.Since(p.Start)
+	}
+	tokens := utils.EstimateTokens(p.Response)
+
+	parts := []string{fmt.Sprintf("~%d tokens", tokens), elapsed.Round(100 * time.Millisecond).String()}
+	if seconds := elapsed.Seconds(); seconds > 0 && tokens > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f tok/s", float64(tokens)/seconds))
+	}
+	if p.FirstToken > 0 {
+		parts = append(parts, fmt.Sprintf("first token %s", p.FirstToken.Round(10*time.Millisecond)))
+	}
+	switch {
+	case p.Err != nil:
+		parts = append(parts, "error")
+	case p.TimedOut:
+		parts = append(parts, "timed out")
+	case !p.Done:
+		parts = append(parts, "generating")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// NewComparePane creates a pane for a model spec like "llama3",
+// "openai:gpt-4o" or "mistral:mistral-small-latest". Models without a provider use the chat's provider.
+func (m Model) NewComparePane(spec string) (ComparePane, error) {
+	provider, model := m.SelectedProvider, spec
+	if p, name, ok := strings.Cut(spec, ":"); ok && IsProvider(p) {
+		provider, model = p, name
+	}
+	if model == "" {
+		return ComparePane{}, fmt.Errorf("missing model name in %q", spec)
+	}
+
+	apiKey := ProviderAPIKey(provider)
+	if KeyRequired(provider) && apiKey == "" {
+		return ComparePane{}, fmt.Errorf("no %s API key available for %s", KeyedProviders[provider].Name, model)
+	}
+
+	client := api.NewClient(provider, apiKey)
+	client.SystemPrompt = APIClient.SystemPrompt
+	client.Temperature = APIClient.Temperature
+	client.SafePrompt = APIClient.SafePrompt
+	m.ApplyRequestOptions(client, provider)
+
+	return ComparePane{Provider: provider, Model: model, Client: client}, nil
+}
+
+// StartComparison sends prompt to every pane concurrently
+func (m *Model) StartComparison(prompt string) tea.Cmd {
+	m.CompareRound++
+	m.CompareOffset = 0
+
+	cancels := make([]context.CancelFunc, 0, len(m.Comparison))
+	cmds := make([]tea.Cmd, 0, len(m.Comparison))
+	for i := range m.Comparison {
+		pane := &m.Comparison[i]
+		if pane.Cancel != nil {
+			pane.Cancel()
+		}
+
+		ctx, cancel := generationContext(m.GenerationTimeout)
+
+		pane.Response = ""
+		pane.Start = time.Now()
+		pane.FirstToken = 0
+		pane.Duration = 0
+		pane.Done = false
+		pane.TimedOut = false
+		pane.Err = nil
+		pane.Cancel = cancel
+		cancels = append(cancels, cancel)
+
+		cmds = append(cmds, CompareResponseCmd(ctx, m.CompareRound, i, pane.Client, pane.Model, prompt))
+	}
+
+	m.CancelGenerate = func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// HandleCompareToken applies a streamed token to its pane and records the
+// responses in the transcript once every pane has finished
+func (m *Model) HandleCompareToken(msg CompareTokenMsg) tea.Cmd {
+	if msg.Round != m.CompareRound || msg.Pane >= len(m.Comparison) {
+		// Stale token from a cancelled round
+		return nil
+	}
+
+	pane := &m.Comparison[msg.Pane]
+	if pane.Done {
+		return nil
+	}
+	if msg.Token != "" && pane.FirstToken == 0 {
+		pane.FirstToken = time.Since(pane.Start)
+	}
+	pane.Response += msg.Token
+
+	if msg.Done {
+		pane.Done = true
+		pane.Duration = time.Since(pane.Start)
+		pane.TimedOut = msg.TimedOut
+		pane.Err = msg.Err
+		pane.Cancel = nil
+		if msg.Err != nil {
+			pane.Response += fmt.Sprintf("\n\n[Error: %v]", msg.Err)
+		} else if msg.TimedOut {
+			pane.Response += fmt.Sprintf("\n\n[Generation stopped: time limit of %s reached]", m.GenerationTimeout)
+		}
+	}
+
+	for _, p := range m.Comparison {
+		if !p.Done {
+			return nil
+		}
+	}
+
+	// Every model has finished: keep the results in the transcript so they
+	// can be exported or revisited after leaving comparison mode
+	for _, p := range m.Comparison {
+		m.Messages = append(m.Messages, models.Message{
+			Role:      models.RoleAssistant,
+			Content:   p.Response,
+			Model:     p.Label(m.SelectedProvider),
+			CreatedAt: p.Start,
+			Tokens:    utils.EstimateTokens(p.Response),
+			Duration:  p.Duration,
+		})
+	}
+	m.IsGenerating = false
+	m.State = StatePrompting
+	m.CancelGenerate = nil
+	m.RefreshTranscript()
+	m.AutosaveSession()
+
+	var elapsed time.Duration
+	for _, p := range m.Comparison {
+		elapsed = max(elapsed, p.Duration)
+	}
+	if !m.ShouldNotify(elapsed) {
+		return nil
+	}
+	return m.NotifyCmd(fmt.Sprintf("Comparison finished in %s", elapsed.Round(time.Second)),
+		fmt.Sprintf("%d models answered", len(m.Comparison)))
+}
+
+// StopComparison cancels any running comparison and returns to the single chat view
+func (m *Model) StopComparison() {
+	for _, pane := range m.Comparison {
+		if pane.Cancel != nil {
+			pane.Cancel()
+		}
+	}
+	m.Comparison = nil
+	m.CompareRound++
+	m.CompareOffset = 0
+	if m.IsGenerating {
+		m.IsGenerating = false
+		m.State = StatePrompting
+		m.CancelGenerate = nil
+	}
+	m.UpdateViewportContent()
+}
+
+// ScrollComparison scrolls every pane by delta lines; positive values move
+// towards older output
+func (m *Model) ScrollComparison(delta int) {
+	maxOffset := 0
+	if len(m.Comparison) > 0 {
+		paneWidth := (m.ScreenWidth-4)/len(m.Comparison) - 2
+		for _, pane := range m.Comparison {
+			if lines := len(pane.Lines(paneWidth)); lines > maxOffset {
+				maxOffset = lines
+			}
+		}
+	}
+
+	m.CompareOffset += delta
+	if m.CompareOffset > maxOffset {
+		m.CompareOffset = maxOffset
+	}
+	if m.CompareOffset < 0 {
+		m.CompareOffset = 0
+	}
+}
+
+// Lines returns the pane's response wrapped to width
+func (p ComparePane) Lines(width int) []string {
+	content := p.Response
+	if content == "" && !p.Start.IsZero() {
+		content = "…"
+	}
+	if width > 4 {
+		content = utils.WrapText(content, width-2)
+	}
+	return strings.Split(content, "\n")
+}
+
+// ComparisonView renders the panes side by side within width and height
+func (m Model) ComparisonView(width, height int) string {
+	n := len(m.Comparison)
+	paneWidth := width/n - 2
+	bodyHeight := height - 4
+	if bodyHeight < 1 {
+		bodyHeight = 1
+	}
+
+	panes := make([]string, 0, n)
+	for _, pane := range m.Comparison {
+		// Show the tail of the response, scrolled back by the shared offset
+		lines := pane.Lines(paneWidth)
+		end := len(lines) - m.CompareOffset
+		if end < bodyHeight {
+			end = bodyHeight
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+		start := end - bodyHeight
+		if start < 0 {
+			start = 0
+		}
+
+		body := lipgloss.NewStyle().Width(paneWidth - 2).Height(bodyHeight).
+			Render(strings.Join(lines[start:end], "\n"))
+
+		panes = append(panes, ComparePaneStyle.Copy().Width(paneWidth).Render(lipgloss.JoinVertical(
+			lipgloss.Left,
+			AssistantHeaderStyle.Render(truncate(pane.Label(m.SelectedProvider), paneWidth-2)),
+			body,
+			CollapsedStyle.Render(truncate(pane.Stats(), paneWidth-2)),
+		)))
+	}
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, panes...)
+}
+
+// truncate shortens s to at most width runes
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width <= 1 || len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "compare",
+		Usage:       "[model ...]",
+		Description: "Stream prompts to several models side by side (empty to stop)",
+		Run: func(m *Model, args string) tea.Cmd {
+			specs := strings.Fields(args)
+			if len(specs) == 0 {
+				if len(m.Comparison) == 0 {
+					m.StatusMessage = "Usage: /compare <model> [model ...] (e.g. /compare llama3 openai:gpt-4o)"
+					return nil
+				}
+				m.StopComparison()
+				m.StatusMessage = "Comparison mode off"
+				return RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight)
+			}
+
+			// The current model is always the first pane
+			if specs[0] != m.SelectedModel {
+				specs = append([]string{m.SelectedModel}, specs...)
+			}
+			if len(specs) > MaxComparePanes {
+				m.StatusMessage = fmt.Sprintf("Can compare at most %d models at once", MaxComparePanes)
+				return nil
+			}
+
+			panes := make([]ComparePane, 0, len(specs))
+			for _, spec := range specs {
+				pane, err := m.NewComparePane(spec)
+				if err != nil {
+					m.StatusMessage = err.Error()
+					return nil
+				}
+				panes = append(panes, pane)
+			}
+
+			m.StopComparison()
+			m.Comparison = panes
+			m.StatusMessage = fmt.Sprintf("Comparing %d models, /compare to stop", len(panes))
+			return RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight)
+		},
+	})
+}
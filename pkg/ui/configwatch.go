@@ -0,0 +1,244 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/tunnel"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// ConfigWatchInterval is how often config.json is checked for changes when
+// its directory can't be watched, and while a change waits for a response
+// to finish
+const ConfigWatchInterval = 2 * time.Second
+
+// configSettleDelay lets an editor finish writing config.json, which many do
+// in several steps, before it is read
+const configSettleDelay = 100 * time.Millisecond
+
+// OllamaHostFromConfig is set when the Ollama server comes from config.json
+// rather than --host or OLLAMA_HOST, so that editing "ollama_host" moves the
+// client to the new server
+var OllamaHostFromConfig bool
+
+// ConfigWatchMsg reports whether config.json was written since the last
+// check, with its new content
+type ConfigWatchMsg struct {
+	ModTime time.Time
+	Changed bool
+	Config  utils.Config
+	Err     error
+}
+
+// configModTime returns when config.json was last written, or the zero
+// time when it doesn't exist
+func configModTime() time.Time {
+	path, err := utils.GetConfigPath()
+	if err != nil {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// configWatcher watches the directory of config.json, since editors often
+// replace the file rather than write to it
+var configWatcher struct {
+	mu      sync.Mutex
+	dir     string
+	watcher *fsnotify.Watcher
+}
+
+// watchConfigDir returns a watcher of config.json's directory, or nil when
+// the directory can't be watched and the file is polled instead
+func watchConfigDir() *fsnotify.Watcher {
+	path, err := utils.GetConfigPath()
+	if err != nil {
+		return nil
+	}
+	dir := filepath.Dir(path)
+
+	configWatcher.mu.Lock()
+	defer configWatcher.mu.Unlock()
+	if configWatcher.watcher != nil && configWatcher.dir == dir {
+		return configWatcher.watcher
+	}
+	if configWatcher.watcher != nil {
+		configWatcher.watcher.Close()
+		configWatcher.watcher = nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil
+	}
+	configWatcher.dir, configWatcher.watcher = dir, watcher
+	return watcher
+}
+
+// WatchConfigCmd waits for config.json to be written, loading it when it
+// was written after since. Without fsnotify it polls every
+// ConfigWatchInterval instead.
+func WatchConfigCmd(since time.Time) tea.Cmd {
+	watcher := watchConfigDir()
+	if watcher == nil {
+		return PollConfigCmd(since)
+	}
+	path, _ := utils.GetConfigPath()
+	return func() tea.Msg {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return PollConfigCmd(since)()
+				}
+				if filepath.Clean(event.Name) != filepath.Clean(path) {
+					continue
+				}
+				time.Sleep(configSettleDelay)
+				return checkConfig(since)
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return PollConfigCmd(since)()
+				}
+				// Events may have been lost, so the file is checked anyway
+				return checkConfig(since)
+			}
+		}
+	}
+}
+
+// PollConfigCmd checks config.json for changes after ConfigWatchInterval
+func PollConfigCmd(since time.Time) tea.Cmd {
+	return tea.Tick(ConfigWatchInterval, func(time.Time) tea.Msg {
+		return checkConfig(since)
+	})
+}
+
+// checkConfig loads config.json when it was written after since
+func checkConfig(since time.Time) ConfigWatchMsg {
+	modTime := configModTime()
+	if modTime.Equal(since) {
+		return ConfigWatchMsg{ModTime: modTime}
+	}
+	config, err := utils.LoadConfig()
+	if err == nil && utils.ActiveProfile != "" {
+		err = utils.ApplyProfile(&config, utils.ActiveProfile)
+	}
+	if err == nil {
+		// Variables set for the session keep winning over the file; their
+		// errors were reported at startup
+		_ = utils.ApplyEnvOverrides(&config)
+	}
+	return ConfigWatchMsg{ModTime: modTime, Changed: true, Config: config, Err: err}
+}
+
+// HandleConfigWatch applies a change to config.json and keeps watching.
+// Changes made during a response wait for it to finish.
+func (m *Model) HandleConfigWatch(msg ConfigWatchMsg) tea.Cmd {
+	if !msg.Changed {
+		return WatchConfigCmd(m.ConfigModTime)
+	}
+	if m.IsGenerating {
+		// No event may follow, so the file is checked again until the
+		// response has finished
+		return PollConfigCmd(m.ConfigModTime)
+	}
+	m.ConfigModTime = msg.ModTime
+	notice := m.ReloadConfig(msg.Config, msg.Err)
+	if notice == "" {
+		return WatchConfigCmd(m.ConfigModTime)
+	}
+	m.StatusMessage = notice
+	return tea.Batch(
+		WatchConfigCmd(m.ConfigModTime),
+		m.List.NewStatusMessage(notice),
+		m.ProviderList.NewStatusMessage(notice),
+	)
+}
+
+// ReloadConfig applies the theme, keybindings and provider settings of a
+// new config.json and returns a notice of what changed. Writes of the
+// application itself, such as recent models, change none of them and go
+// unnoticed. An invalid file leaves every setting as it was.
+func (m *Model) ReloadConfig(config utils.Config, err error) string {
+	if err != nil {
+		return fmt.Sprintf("config.json is invalid, keeping the current settings: %v", err)
+	}
+	previous := m.LoadedConfig
+	m.LoadedConfig = config
+
+	var applied []string
+	var notice string
+	if config.Theme != previous.Theme {
+		if err := ApplyTheme(config.Theme); err != nil {
+			notice = "; kept the current theme: " + err.Error()
+		} else {
+			m.RefreshTheme()
+			applied = append(applied, "theme")
+		}
+	}
+	if !reflect.DeepEqual(config.Keybindings, previous.Keybindings) {
+		keyMap, conflicts := DefaultKeyMap().WithOverrides(config.Keybindings)
+		conflicts = append(conflicts, keyMap.DetectConflicts()...)
+		if len(conflicts) > 0 {
+			c := conflicts[0]
+			notice += fmt.Sprintf("; kept the current keybindings: %s for %s: %s", FormatKey(c.Key), c.Action, c.Reason)
+		} else {
+			m.SetKeyMap(keyMap)
+			applied = append(applied, "keybindings")
+		}
+	}
+
+	if providerSettingsChanged(previous, config) {
+		ApplyProviderConfig(config)
+		if OllamaHostFromConfig && !tunnel.IsSSH(config.OllamaHost) {
+			api.OllamaURL = api.DefaultOllamaURL
+			if config.OllamaHost != "" {
+				api.OllamaURL = api.NormalizeOllamaURL(config.OllamaHost)
+			}
+		}
+		// Clients keep the server they were created for
+		if m.SelectedProvider != "" {
+			m.UseProvider(m.SelectedProvider)
+		}
+		m.RefreshProviderItems()
+		applied = append(applied, "providers")
+	}
+
+	if len(applied) == 0 && notice == "" {
+		return ""
+	}
+	if len(applied) == 0 {
+		return "Reloaded config.json" + notice
+	}
+	return "Reloaded config.json: " + strings.Join(applied, ", ") + notice
+}
+
+// providerSettingsChanged reports whether the settings ApplyProviderConfig
+// and the Ollama server use differ between two configs
+func providerSettingsChanged(a, b utils.Config) bool {
+	return a.OllamaHost != b.OllamaHost ||
+		a.OpenAIBaseURL != b.OpenAIBaseURL ||
+		a.LMStudioURL != b.LMStudioURL ||
+		a.PluginsDir != b.PluginsDir ||
+		!reflect.DeepEqual(a.OpenAIModels, b.OpenAIModels) ||
+		!reflect.DeepEqual(a.Providers, b.Providers) ||
+		!reflect.DeepEqual(a.HuggingFace, b.HuggingFace)
+}
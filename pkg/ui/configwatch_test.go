@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+func TestWatchConfigCmdSeesReplacedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	t.Setenv(utils.ConfigPathEnv, path)
+	if watchConfigDir() == nil {
+		t.Skip("fsnotify can't watch the directory here")
+	}
+
+	cmd := WatchConfigCmd(time.Time{})
+	done := make(chan ConfigWatchMsg, 1)
+	go func() { done <- cmd().(ConfigWatchMsg) }()
+
+	// Editors write a new file and rename it over the old one
+	tmp := filepath.Join(dir, "config.json.tmp")
+	if err := os.WriteFile(tmp, []byte(`{"theme": "light"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-done:
+		if !msg.Changed || msg.Err != nil || msg.Config.Theme != "light" {
+			t.Errorf("WatchConfigCmd = %+v, want the light theme loaded", msg)
+		}
+	case <-time.After(ConfigWatchInterval / 2):
+		t.Fatal("the replaced config.json went unnoticed")
+	}
+}
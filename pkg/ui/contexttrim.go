@@ -0,0 +1,335 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// Context strategies decide what is left out of the conversation sent to
+// the model when it nears the end of the context window
+const (
+	// ContextStrategyOff sends the whole conversation
+	ContextStrategyOff = "off"
+	// ContextStrategyDropOldest leaves out just enough of the oldest
+	// messages for the prompt to fit
+	ContextStrategyDropOldest = "drop-oldest"
+	// ContextStrategySlidingWindow keeps the most recent exchanges that
+	// fill part of the window, next to the system prompt
+	ContextStrategySlidingWindow = "sliding-window"
+	// ContextStrategySummarize keeps the first and the most recent
+	// exchanges and has the model summarize the ones in between
+	ContextStrategySummarize = "summarize"
+)
+
+// ContextStrategies lists the strategies /trim accepts
+var ContextStrategies = []string{ContextStrategyOff, ContextStrategyDropOldest, ContextStrategySlidingWindow, ContextStrategySummarize}
+
+const (
+	// ContextTrimThreshold is the share of the context window the
+	// conversation and the prompt may fill before the conversation is trimmed
+	ContextTrimThreshold = 0.9
+	// ContextTrimTarget is the share the sliding window and summarization
+	// trim down to, so the next prompts fit without trimming again
+	ContextTrimTarget = 0.6
+	// contextSummaryTokens is the room kept for the summary of the middle
+	contextSummaryTokens = 500
+	// contextSummaryPrefix introduces the summary in the history
+	contextSummaryPrefix = "Summary of the earlier conversation:\n"
+)
+
+// contextSummaryPrompt asks the model to summarize the middle of a
+// conversation
+const contextSummaryPrompt = "Summarize the part of a conversation below in a few short paragraphs. " +
+	"Keep the names, facts, decisions and open questions the rest of the conversation may refer to. " +
+	"Reply with the summary only.\n\n%s"
+
+// ContextTrim records the messages of the transcript that are no longer sent
+// to the model
+type ContextTrim struct {
+	// From and To delimit the messages left out. Those before From stay
+	// pinned: the first exchange once the middle was summarized.
+	From int
+	To   int
+	// Summary stands in for the messages left out when they were summarized
+	Summary string
+}
+
+// Dropped returns how many messages are left out
+func (t ContextTrim) Dropped() int {
+	return t.To - t.From
+}
+
+// ContextSummaryMsg carries the summary of the messages to leave out before
+// sending the prompt of the response with the given ID
+type ContextSummaryMsg struct {
+	Tab    int
+	ID     int
+	Trim   ContextTrim
+	Prompt string
+	Err    error
+}
+
+// contextStrategy returns the strategy in use, sliding-window by default
+func (m Model) contextStrategy() string {
+	if m.ContextStrategy == "" {
+		return ContextStrategySlidingWindow
+	}
+	return m.ContextStrategy
+}
+
+// messageTokens returns the tokens of a message, as reported or estimated
+func messageTokens(msg models.Message) int {
+	if msg.Role == models.RoleInfo {
+		return 0
+	}
+	if msg.Tokens > 0 {
+		return msg.Tokens
+	}
+	return utils.EstimateTokens(msg.Content)
+}
+
+// sentHistory returns the history sent with the first n messages: those
+// not left out, with the summary of the ones that were
+func (m Model) sentHistory(n int) []models.ChatMessage {
+	return m.historyWith(m.Trim, n)
+}
+
+// historyWith returns the history of the first n messages trimmed by trim
+func (m Model) historyWith(trim ContextTrim, n int) []models.ChatMessage {
+	from, to := min(trim.From, n), min(trim.To, n)
+	history := chatHistory(m.Messages[:from])
+	if trim.Summary != "" {
+		history = append(history, models.ChatMessage{Role: "system", Content: contextSummaryPrefix + trim.Summary})
+	}
+	return append(history, chatHistory(m.Messages[to:n])...)
+}
+
+// tokensWith estimates the tokens sent with prompt after the first n
+// messages trimmed by trim, counting summaryTokens for a summary to come
+func (m Model) tokensWith(trim ContextTrim, n int, prompt string, summaryTokens int) int {
+	from, to := min(trim.From, n), min(trim.To, n)
+	total := utils.EstimateTokens(APIClient.SystemPrompt) + utils.EstimateTokens(prompt) + summaryTokens
+	if trim.Summary != "" {
+		total += utils.EstimateTokens(trim.Summary)
+	}
+	for _, msg := range m.Messages[:from] {
+		total += messageTokens(msg)
+	}
+	for _, msg := range m.Messages[to:n] {
+		total += messageTokens(msg)
+	}
+	return total
+}
+
+// nextExchange returns the index of the first prompt after i, or n
+func (m Model) nextExchange(i, n int) int {
+	for i++; i < n; i++ {
+		if m.Messages[i].Role == models.RoleUser {
+			return i
+		}
+	}
+	return n
+}
+
+// FitContext starts generating the response to prompt, first leaving out
+// older messages with the chosen strategy when the conversation and the
+// prompt near the end of the context window
+func (m *Model) FitContext(prompt string) tea.Cmd {
+	// The prompt and the response being generated are the last messages
+	n := len(m.Messages) - 2
+	trim, strategy, ok := m.plannedTrim(n, prompt)
+	if !ok {
+		return m.StartGenerateResponseCmd(m.SelectedModel, prompt, m.GenerationTimeout)
+	}
+	if strategy == ContextStrategySummarize {
+		return m.SummarizeContextCmd(trim, prompt)
+	}
+	m.applyTrim(trim, n, m.contextWindow())
+	return m.StartGenerateResponseCmd(m.SelectedModel, prompt, m.GenerationTimeout)
+}
+
+// plannedTrim chooses what to leave out of the first n messages for prompt
+// to fit, and with which strategy, reporting false when it fits as is. The
+// messages are still to be summarized when the strategy is summarize.
+func (m Model) plannedTrim(n int, prompt string) (ContextTrim, string, bool) {
+	limit := m.contextWindow()
+	strategy := m.contextStrategy()
+	if strategy == ContextStrategyOff || limit <= 0 || n <= 0 ||
+		m.tokensWith(m.Trim, n, prompt, 0) <= int(float64(limit)*ContextTrimThreshold) {
+		return m.Trim, strategy, false
+	}
+
+	if strategy == ContextStrategySummarize {
+		if trim, ok := m.summaryTrim(n, prompt, limit); ok {
+			return trim, strategy, true
+		}
+		strategy = ContextStrategySlidingWindow
+	}
+	return m.dropTrim(strategy, n, prompt, limit), strategy, true
+}
+
+// dropTrim leaves out the oldest messages after the pinned ones: one at a
+// time until the prompt fits for drop-oldest, whole exchanges down to the
+// target for the sliding window
+func (m Model) dropTrim(strategy string, n int, prompt string, limit int) ContextTrim {
+	trim := m.Trim
+	trim.To = max(min(trim.To, n), trim.From)
+	budget := int(float64(limit) * ContextTrimTarget)
+	if strategy == ContextStrategyDropOldest {
+		budget = int(float64(limit) * ContextTrimThreshold)
+	}
+	for trim.To < n && m.tokensWith(trim, n, prompt, 0) > budget {
+		if strategy == ContextStrategyDropOldest {
+			trim.To++
+		} else {
+			trim.To = m.nextExchange(trim.To, n)
+		}
+	}
+	return trim
+}
+
+// summaryTrim chooses the exchanges to summarize: those after the first
+// exchange that must go for the rest to fit in the target with a summary
+func (m Model) summaryTrim(n int, prompt string, limit int) (ContextTrim, bool) {
+	trim := m.Trim
+	if trim.Dropped() <= 0 {
+		// Keep a memory written by hand
+		trim.From = m.nextExchange(0, n)
+		trim.To = trim.From
+	}
+	previous := trim.To
+	budget := int(float64(limit) * ContextTrimTarget)
+	// Small windows keep less room for the summary
+	room := min(contextSummaryTokens, limit/8)
+	for trim.To < n && m.tokensWith(trim, n, prompt, room) > budget {
+		trim.To = m.nextExchange(trim.To, n)
+	}
+	return trim, trim.To > previous
+}
+
+// applyTrim leaves the messages out of the conversation the model sees and
+// warns about it in the status bar
+func (m *Model) applyTrim(trim ContextTrim, n, limit int) {
+	dropped := 0
+	for _, msg := range m.Messages[max(min(m.Trim.To, trim.To), trim.From):trim.To] {
+		dropped += messageTokens(msg)
+	}
+	m.Trim = trim
+	APIClient.SetHistory(m.sentHistory(n))
+
+	what := fmt.Sprintf("left out the %d oldest messages", trim.Dropped())
+	if trim.Summary != "" {
+		what = fmt.Sprintf("summarized messages %d-%d", trim.From+1, trim.To)
+	}
+	m.StatusMessage = fmt.Sprintf("⚠ Near the %s-token context of %s: %s (~%s tokens)", formatCount(limit), m.SelectedModel, what, formatCount(dropped))
+}
+
+// SummarizeContextCmd has the model summarize the messages trim leaves out
+// together with the earlier summary, before the prompt is sent
+func (m *Model) SummarizeContextCmd(trim ContextTrim, prompt string) tea.Cmd {
+	text := m.conversationText(m.Trim.Summary, max(m.Trim.To, trim.From), trim.To)
+	ctx, cancel := generationContext(m.GenerationTimeout)
+	m.GenerationID++
+	m.CancelGenerate = cancel
+	m.StatusMessage = fmt.Sprintf("Summarizing messages %d-%d to fit the context…", trim.From+1, trim.To)
+
+	tab, id, model := m.TabID, m.GenerationID, m.SelectedModel
+	client := APIClient.Detached()
+	return func() tea.Msg {
+		defer cancel()
+		summary, err := client.Complete(ctx, model, fmt.Sprintf(contextSummaryPrompt, text))
+		trim.Summary = strings.TrimSpace(summary)
+		if err == nil && trim.Summary == "" {
+			err = errors.New("the summary is empty")
+		}
+		return ContextSummaryMsg{Tab: tab, ID: id, Trim: trim, Prompt: prompt, Err: err}
+	}
+}
+
+// HandleContextSummary leaves the summarized messages out and sends the
+// prompt, dropping the oldest exchanges instead when summarizing failed
+func (m *Model) HandleContextSummary(msg ContextSummaryMsg) tea.Cmd {
+	if msg.ID != m.GenerationID || !m.IsGenerating {
+		return nil
+	}
+	if errors.Is(msg.Err, context.Canceled) {
+		// Stopped like a response without tokens
+		return func() tea.Msg { return TokenMsg{Tab: msg.Tab, ID: msg.ID, Done: true} }
+	}
+
+	n := len(m.Messages) - 2
+	limit := m.contextWindow()
+	if msg.Err != nil {
+		m.applyTrim(m.dropTrim(ContextStrategySlidingWindow, n, msg.Prompt, limit), n, limit)
+		m.StatusMessage = fmt.Sprintf("Couldn't summarize (%v); %s", msg.Err, m.StatusMessage)
+	} else {
+		m.applyTrim(msg.Trim, n, limit)
+	}
+	m.GenerationStart = time.Now()
+	return m.StartGenerateResponseCmd(m.SelectedModel, msg.Prompt, m.GenerationTimeout)
+}
+
+// TrimIndicator returns the status bar indicator of messages left out of
+// the conversation
+func (m Model) TrimIndicator() string {
+	if m.Trim.Dropped() <= 0 {
+		return ""
+	}
+	if m.Trim.Summary != "" {
+		return fmt.Sprintf("⚠ %d summarized | ", m.Trim.Dropped())
+	}
+	return fmt.Sprintf("⚠ %d trimmed | ", m.Trim.Dropped())
+}
+
+// SetContextStrategy chooses the context strategy and remembers it
+func (m *Model) SetContextStrategy(strategy string) error {
+	valid := false
+	for _, s := range ContextStrategies {
+		valid = valid || s == strategy
+	}
+	if !valid {
+		return fmt.Errorf("unknown strategy %q: use %s", strategy, strings.Join(ContextStrategies, ", "))
+	}
+	m.ContextStrategy = strategy
+	return utils.UpdateConfig(func(c *utils.Config) { c.ContextStrategy = strategy })
+}
+
+// contextNumCtx returns the num_ctx option passed to Ollama, if any
+func contextNumCtx(client *api.Client) int {
+	switch value := client.Options["num_ctx"].(type) {
+	case float64:
+		return int(value)
+	case int:
+		return value
+	}
+	return 0
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "trim",
+		Usage:       "[off|drop-oldest|sliding-window|summarize]",
+		Description: "Choose how the conversation is trimmed when it nears the context limit",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				m.StatusMessage = fmt.Sprintf("Context strategy: %s (%s)", m.contextStrategy(), strings.Join(ContextStrategies, ", "))
+				return nil
+			}
+			if err := m.SetContextStrategy(args); err != nil {
+				m.StatusMessage = err.Error()
+				return nil
+			}
+			m.StatusMessage = fmt.Sprintf("Context strategy: %s", args)
+			return nil
+		},
+	})
+}
@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// continuePrompt asks the model to resume the response it was cut off in
+const continuePrompt = "Your previous answer was cut off. Continue it exactly where it stopped, " +
+	"without repeating anything and without an introduction."
+
+// Labels added after the text of a response that failed or was cut off by
+// the time limit
+const (
+	errorLabel     = "\n\n[Error: "
+	timeLimitLabel = "\n\n[Generation stopped: time limit of"
+)
+
+// legendEntry matches a line of a sources or citations legend
+var legendEntry = regexp.MustCompile(`^  \[\d+\] .+$`)
+
+// splitResponse separates the text a model generated from what was added
+// to it once it streamed: the error and time limit labels, dropped, and the
+// legends of sources and citations, returned in the order they came
+func splitResponse(content string) (text, legends string) {
+	for {
+		switch {
+		case strings.HasSuffix(content, "]") && strings.LastIndex(content, errorLabel) >= 0:
+			content = content[:strings.LastIndex(content, errorLabel)]
+		case strings.HasSuffix(content, " reached]") && strings.LastIndex(content, timeLimitLabel) >= 0:
+			content = content[:strings.LastIndex(content, timeLimitLabel)]
+		case isLegend(content):
+			i := strings.LastIndex(content, "\n\nSources:")
+			legends = content[i:] + legends
+			content = content[:i]
+		default:
+			return content, legends
+		}
+	}
+}
+
+// isLegend reports whether content ends with a legend of sources or
+// citations, which SourcesLegend and CitationsLegend write
+func isLegend(content string) bool {
+	i := strings.LastIndex(content, "\n\nSources:")
+	if i < 0 {
+		return false
+	}
+	lines := strings.Split(content[i+len("\n\nSources:"):], "\n")
+	if len(lines) < 2 || lines[0] != "" {
+		return false
+	}
+	for _, line := range lines[1:] {
+		if !legendEntry.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// cutOffReason returns why a finished response was cut off, if it was
+func cutOffReason(msg TokenMsg) string {
+	switch {
+	case msg.TimedOut:
+		return models.CutOffTimeout
+	case msg.Stopped:
+		return models.CutOffStopped
+	case msg.Reason == api.DoneReasonLength:
+		return models.CutOffLength
+	}
+	return ""
+}
+
+// cutOffLabel describes in a message header why the response was cut off
+func cutOffLabel(reason string) string {
+	switch reason {
+	case models.CutOffTimeout:
+		return "timed out"
+	case models.CutOffStopped:
+		return "stopped"
+	case models.CutOffLength:
+		return "hit the token limit"
+	}
+	return ""
+}
+
+// SetResponseCutOff records why the latest response was cut off, adding to
+// the metrics of the part it continues
+func (m *Model) SetResponseCutOff(msg TokenMsg) {
+	if len(m.Messages) == 0 {
+		return
+	}
+	last := &m.Messages[len(m.Messages)-1]
+	if last.Role != models.RoleAssistant {
+		return
+	}
+	last.CutOff = cutOffReason(msg)
+	if m.Continued != nil {
+		if previous := m.Continued.Metadata; previous != nil && last.Metadata != nil {
+			merged := *last.Metadata
+			merged.Add(previous.ResponseMetrics)
+			last.Metadata = &merged
+			last.Tokens = merged.EvalCount
+		}
+		m.Continued = nil
+		// The conversation holds the whole response from now on, rather
+		// than the request to continue it
+		APIClient.SetHistory(m.ChatHistory())
+		if last.CutOff == "" {
+			m.StatusMessage = "Response continued"
+		}
+	}
+	if last.CutOff == models.CutOffLength && m.StatusMessage == "" {
+		m.StatusMessage = "The response hit the token limit: /continue to resume it"
+	}
+}
+
+// StartContinue asks the model to resume the latest response where it
+// stopped, streaming the rest into the same message
+func (m *Model) StartContinue() tea.Cmd {
+	if m.IsGenerating {
+		m.StatusMessage = "Wait for the response to finish, or stop it, before continuing it"
+		return nil
+	}
+	if len(m.Comparison) > 0 {
+		m.StatusMessage = "Responses can't be continued in comparison mode"
+		return nil
+	}
+	if len(m.Messages) == 0 || m.Messages[len(m.Messages)-1].Role != models.RoleAssistant {
+		m.StatusMessage = "No response to continue: /continue resumes the latest response"
+		return nil
+	}
+
+	last := &m.Messages[len(m.Messages)-1]
+	previous := *last
+	// The labels and legends added after the text are not the model's; the
+	// legends go back on once the response is done
+	last.Content, _ = splitResponse(last.Content)
+	last.CutOff = ""
+	// The model sees the response as far as it got
+	APIClient.SetHistory(m.ChatHistory())
+
+	m.Continued = &previous
+	m.CurrentAttachments = nil
+	m.InProgressResponse = last.Content
+	m.State = StateLoading
+	m.IsGenerating = true
+	m.SelectedMessage = -1
+	// The duration of the response covers both parts
+	m.GenerationStart = time.Now().Add(-previous.Duration)
+	m.StatusMessage = fmt.Sprintf("Continuing the response with %s…", m.SelectedModel)
+	return m.StartGenerateResponseCmd(m.SelectedModel, continuePrompt, m.GenerationTimeout)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "continue",
+		Description: "Resume the latest response where it was stopped or cut off",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.StartContinue()
+		},
+	})
+}
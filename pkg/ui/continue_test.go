@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+func TestStartContinueStripsAppendedText(t *testing.T) {
+	legends := "\n\n" + SourcesLegend([]models.Attachment{{Name: "a.go", Source: "a.go"}, {Name: "b.go", Source: "b.go"}}) +
+		"\n\n" + CitationsLegend([]string{"https://example.com"})
+	m := newTestModel(t, 80, 24)
+	m.Messages = []models.Message{
+		{Role: models.RoleUser, Content: "Explain"},
+		{Role: models.RoleAssistant, Content: "The first half" + errorLabel + "connection reset]" + legends, CutOff: models.CutOffStopped},
+	}
+
+	if cmd := m.StartContinue(); cmd == nil {
+		t.Fatalf("StartContinue did not start: %s", m.StatusMessage)
+	}
+	history := m.ChatHistory()
+	if got := history[len(history)-1].Content; got != "The first half" {
+		t.Errorf("the model is sent %q as its response so far, want only its text", got)
+	}
+
+	updated, _ := m.Update(TokenMsg{Tab: m.TabID, ID: m.GenerationID, Token: " and the rest.", Done: true})
+	m = updated.(Model)
+	want := "The first half and the rest." + legends
+	if got := m.Messages[len(m.Messages)-1].Content; got != want {
+		t.Errorf("continued response is %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// ConversationLogEntry describes the exchange that just finished for the
+// conversation log
+func (m Model) ConversationLogEntry(timedOut bool, err error) utils.ConversationLogEntry {
+	entry := utils.ConversationLogEntry{
+		Time:       time.Now(),
+		StartedAt:  m.GenerationStart,
+		DurationMS: m.LastExchange.Duration.Milliseconds(),
+		SessionID:  m.Session.ID,
+		Profile:    utils.ActiveProfile,
+		Provider:   m.LastExchange.Provider,
+		Model:      m.LastExchange.Model,
+		Prompt:     m.CurrentPrompt,
+		Response:   models.StripReasoning(m.CurrentResponse),
+		TimedOut:   timedOut,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	var usage models.Usage
+	reported := false
+	if n := len(m.Messages); n > 0 && m.Messages[n-1].Role == models.RoleAssistant {
+		usage, reported = m.Messages[n-1].Usage()
+	}
+	if reported {
+		entry.PromptTokens = usage.PromptTokens
+		entry.CompletionTokens = usage.CompletionTokens
+	} else {
+		entry.PromptTokens = utils.EstimateTokens(entry.Prompt)
+		entry.CompletionTokens = utils.EstimateTokens(entry.Response)
+		entry.TokensEstimated = true
+	}
+	return entry
+}
+
+// LogConversation appends the exchange that just finished to the
+// conversation log when it is turned on. A failed write is reported but
+// doesn't turn the log off.
+func (m *Model) LogConversation(timedOut bool, err error) {
+	config := m.LoadedConfig.ConversationLog
+	if !config.Enabled {
+		return
+	}
+	if err := utils.AppendConversationLog(config, m.ConversationLogEntry(timedOut, err)); err != nil && m.StatusMessage == "" {
+		m.StatusMessage = fmt.Sprintf("Failed to write the conversation log: %v", err)
+	}
+}
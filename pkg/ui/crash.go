@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"reflect"
+	"runtime/debug"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Crash is a panic caught while the program ran, with where it happened
+type Crash struct {
+	Value any
+	Stack []byte
+}
+
+// crashState keeps the first panic caught, from the event loop or from a
+// command's goroutine
+type crashState struct {
+	mu    sync.Mutex
+	crash *Crash
+}
+
+// PanicGuard wraps the model so that a panic in Init, Update, View or a
+// command quits the program, restoring the terminal, instead of leaving it
+// in the alternate screen with the cursor hidden. The program must be
+// started with tea.WithoutCatchPanics for the guard to see the panic first.
+type PanicGuard struct {
+	Model tea.Model
+	state *crashState
+}
+
+// NewPanicGuard wraps model in a PanicGuard
+func NewPanicGuard(model tea.Model) PanicGuard {
+	return PanicGuard{Model: model, state: &crashState{}}
+}
+
+// Crash returns the panic that stopped the program, or nil
+func (g PanicGuard) Crash() *Crash {
+	g.state.mu.Lock()
+	defer g.state.mu.Unlock()
+	return g.state.crash
+}
+
+// catch records the panic being recovered, keeping the first one
+func (g PanicGuard) catch(value any) {
+	g.state.mu.Lock()
+	defer g.state.mu.Unlock()
+	if g.state.crash == nil {
+		g.state.crash = &Crash{Value: value, Stack: debug.Stack()}
+	}
+}
+
+// cmdType is the type of the elements of tea.BatchMsg and of the unexported
+// message tea.Sequence returns
+var cmdType = reflect.TypeOf((*tea.Cmd)(nil)).Elem()
+
+// guard recovers a panic in cmd and the commands it batches or sequences,
+// quitting the program instead
+func (g PanicGuard) guard(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() (msg tea.Msg) {
+		defer func() {
+			if r := recover(); r != nil {
+				g.catch(r)
+				msg = tea.Quit()
+			}
+		}()
+		return g.guardCmds(cmd())
+	}
+}
+
+// guardCmds guards the commands of a message that carries them. Bubble Tea
+// runs tea.BatchMsg and the message of tea.Sequence, whose type isn't
+// exported, as slices of commands, so any such slice is guarded, keeping
+// its type.
+func (g PanicGuard) guardCmds(msg tea.Msg) tea.Msg {
+	v := reflect.ValueOf(msg)
+	if !v.IsValid() || v.Kind() != reflect.Slice || v.Type().Elem() != cmdType {
+		return msg
+	}
+	guarded := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	for i := 0; i < v.Len(); i++ {
+		cmd, _ := v.Index(i).Interface().(tea.Cmd)
+		guarded.Index(i).Set(reflect.ValueOf(g.guard(cmd)))
+	}
+	return guarded.Interface()
+}
+
+// Init starts the wrapped model
+func (g PanicGuard) Init() (cmd tea.Cmd) {
+	defer func() {
+		if r := recover(); r != nil {
+			g.catch(r)
+			cmd = tea.Quit
+		}
+	}()
+	return g.guard(g.Model.Init())
+}
+
+// Update updates the wrapped model, quitting once it panicked
+func (g PanicGuard) Update(msg tea.Msg) (next tea.Model, cmd tea.Cmd) {
+	if g.Crash() != nil {
+		return g, tea.Quit
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			g.catch(r)
+			next, cmd = g, tea.Quit
+		}
+	}()
+	model, cmd := g.Model.Update(msg)
+	g.Model = model
+	return g, g.guard(cmd)
+}
+
+// View renders the wrapped model, or nothing once it panicked
+func (g PanicGuard) View() (view string) {
+	if g.Crash() != nil {
+		return ""
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			g.catch(r)
+			view = ""
+			// View runs in the event loop, which must be free to take the
+			// message
+			if Program != nil {
+				go Program.Quit()
+			}
+		}
+	}()
+	return g.Model.View()
+}
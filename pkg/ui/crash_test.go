@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// panicModel returns cmd from Init and ignores every message, so only the
+// guard quits the program
+type panicModel struct {
+	cmd tea.Cmd
+}
+
+func (p panicModel) Init() tea.Cmd                       { return p.cmd }
+func (p panicModel) Update(tea.Msg) (tea.Model, tea.Cmd) { return p, nil }
+func (p panicModel) View() string                        { return "" }
+
+type okMsg struct{}
+
+func TestPanicGuardCatchesNestedCommands(t *testing.T) {
+	boom := func() tea.Msg { panic("boom") }
+	ok := func() tea.Msg { return okMsg{} }
+	tests := map[string]tea.Cmd{
+		"command":           boom,
+		"batch":             tea.Batch(ok, boom),
+		"sequence":          tea.Sequence(ok, boom),
+		"batch in sequence": tea.Sequence(ok, tea.Batch(ok, boom)),
+		"sequence in batch": tea.Batch(ok, tea.Sequence(ok, boom)),
+	}
+	for name, cmd := range tests {
+		t.Run(name, func(t *testing.T) {
+			guard := NewPanicGuard(panicModel{cmd: cmd})
+			p := tea.NewProgram(guard, tea.WithInput(nil), tea.WithOutput(io.Discard),
+				tea.WithoutRenderer(), tea.WithoutSignalHandler(), tea.WithoutCatchPanics())
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := p.Run()
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-time.After(5 * time.Second):
+				p.Kill()
+				t.Fatal("the program did not quit")
+			}
+
+			crash := guard.Crash()
+			if crash == nil || crash.Value != "boom" || len(crash.Stack) == 0 {
+				t.Errorf("Crash() = %+v, want the panic with its stack", crash)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// keyCheckTimeout limits checking an API key with its provider
+const keyCheckTimeout = 15 * time.Second
+
+// credentialProviders are the rows of the API keys screen, in order
+var credentialProviders = []string{"openai", "mistral", "deepseek", "together", "fireworks", "perplexity", "huggingface"}
+
+// KeyCheckedMsg carries the outcome of checking a provider's API key
+type KeyCheckedMsg struct {
+	Provider string
+	APIKey   string
+	Check    utils.KeyCheck
+}
+
+// KeyCheckCmd checks an API key with its provider in the background
+func KeyCheckCmd(provider, apiKey string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), keyCheckTimeout)
+		defer cancel()
+		check := utils.KeyCheck{At: time.Now()}
+		if err := api.NewClient(provider, apiKey).CheckKey(ctx); err != nil {
+			check.Error = err.Error()
+		}
+		return KeyCheckedMsg{Provider: provider, APIKey: apiKey, Check: check}
+	}
+}
+
+// NewCredentialsTable creates the table for the API keys screen
+func NewCredentialsTable() table.Model {
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Provider", Width: 14},
+			{Title: "Key", Width: 16},
+			{Title: "From", Width: 20},
+			{Title: "Last checked", Width: 40},
+		}),
+		table.WithFocused(true),
+	)
+
+	t.SetStyles(TableStyles())
+
+	return t
+}
+
+// NewAPIKeyInput creates the masked input API keys are typed or pasted into
+func NewAPIKeyInput() textinput.Model {
+	input := textinput.New()
+	input.EchoMode = textinput.EchoPassword
+	input.EchoCharacter = '•'
+	return input
+}
+
+// maskKey hides an API key but its last 4 characters, enough to tell keys
+// apart
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("•", len(key))
+	}
+	return "••••" + key[len(key)-4:]
+}
+
+// storedAPIKey returns a provider's key and where it comes from: its
+// environment variable or the config file
+func storedAPIKey(provider string, config utils.Config) (key, source string) {
+	env := KeyedProviders[provider].Env
+	saved := *configAPIKey(&config, provider)
+	switch key = utils.GetEnv(env, ""); {
+	case key != "" && key != saved:
+		return key, "$" + env
+	case saved != "":
+		return saved, "config.json"
+	}
+	return "", ""
+}
+
+// describeKeyCheck says when a key was last checked and how that went
+func describeKeyCheck(check utils.KeyCheck, ok bool) string {
+	if !ok {
+		return "never"
+	}
+	when := check.At.Local().Format("2006-01-02 15:04")
+	if check.Error != "" {
+		return when + " ✗ " + check.Error
+	}
+	return when + " ✓ accepted"
+}
+
+// OpenCredentials shows the API keys screen
+func (m *Model) OpenCredentials() tea.Cmd {
+	if m.State != StateCredentials {
+		m.CredentialsReturnState = m.State
+	}
+	m.State = StateCredentials
+	m.CredentialsStatus = ""
+	m.UpdateCredentialsTable()
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// UpdateCredentialsTable lists every keyed provider with its masked key
+func (m *Model) UpdateCredentialsTable() {
+	config, _ := utils.LoadConfig()
+	rows := make([]table.Row, 0, len(credentialProviders))
+	for _, provider := range credentialProviders {
+		key, source := storedAPIKey(provider, config)
+		masked := maskKey(key)
+		switch {
+		case key != "":
+		case provider == "openai" && !OpenAIKeyRequired():
+			masked = "not needed"
+		case !KeyRequired(provider):
+			masked = "optional"
+		default:
+			masked = "not set"
+		}
+		check, checked := config.KeyChecks[provider]
+		rows = append(rows, table.Row{KeyedProviders[provider].Name, masked, source, describeKeyCheck(check, checked)})
+	}
+	m.CredentialsTable.SetRows(rows)
+}
+
+// selectedCredential returns the provider of the highlighted row
+func (m Model) selectedCredential() string {
+	return credentialProviders[m.CredentialsTable.Cursor()]
+}
+
+// SetProviderAPIKey saves or, when apiKey is empty, deletes a provider's
+// key, and hands it to the chat if it uses that provider
+func (m *Model) SetProviderAPIKey(provider, apiKey string) error {
+	err := SaveProviderAPIKey(provider, apiKey)
+	if m.SelectedProvider == provider {
+		APIClient.APIKey = apiKey
+	}
+	// A check of the previous key says nothing about the new one
+	checkErr := utils.UpdateConfig(func(c *utils.Config) { delete(c.KeyChecks, provider) })
+	if err == nil {
+		err = checkErr
+	}
+	return err
+}
+
+// UpdateCredentials handles keys on the API keys screen
+func (m Model) UpdateCredentials(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.CredentialsEditing != "" {
+		return m.updateCredentialInput(msg)
+	}
+
+	provider := m.selectedCredential()
+	name := KeyedProviders[provider].Name
+	switch msg.String() {
+	case "esc", "q":
+		m.State = m.CredentialsReturnState
+		return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	case "ctrl+c":
+		return m, m.RequestQuit()
+	case "enter", "a":
+		m.CredentialsEditing = provider
+		m.CredentialInput.Reset()
+		m.CredentialInput.Placeholder = fmt.Sprintf("Enter your %s API key...", name)
+		m.CredentialInput.Focus()
+		return m, textinput.Blink
+	case "d", "delete":
+		config, _ := utils.LoadConfig()
+		key, source := storedAPIKey(provider, config)
+		if key == "" {
+			m.CredentialsStatus = "No " + name + " key to delete"
+			return m, nil
+		}
+		if err := m.SetProviderAPIKey(provider, ""); err != nil {
+			m.CredentialsStatus = fmt.Sprintf("Failed to delete the %s key: %v", name, err)
+			return m, nil
+		}
+		m.CredentialsStatus = "Deleted the " + name + " key"
+		if strings.HasPrefix(source, "$") {
+			m.CredentialsStatus += fmt.Sprintf(" for this session; unset %s in your shell to delete it for good", source)
+		}
+		m.UpdateCredentialsTable()
+		return m, nil
+	case "t":
+		config, _ := utils.LoadConfig()
+		key, _ := storedAPIKey(provider, config)
+		if key == "" && KeyRequired(provider) {
+			m.CredentialsStatus = "No " + name + " key to check"
+			return m, nil
+		}
+		m.CredentialsStatus = "Checking the " + name + " key…"
+		return m, KeyCheckCmd(provider, key)
+	}
+
+	var cmd tea.Cmd
+	m.CredentialsTable, cmd = m.CredentialsTable.Update(msg)
+	return m, cmd
+}
+
+// updateCredentialInput handles keys while a key is typed in
+func (m Model) updateCredentialInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	provider := m.CredentialsEditing
+	switch msg.String() {
+	case "esc":
+		m.CredentialsEditing = ""
+		m.CredentialInput.Blur()
+		return m, nil
+	case "ctrl+c":
+		return m, m.RequestQuit()
+	case "enter":
+		apiKey := strings.TrimSpace(m.CredentialInput.Value())
+		if apiKey == "" {
+			return m, nil
+		}
+		m.CredentialsEditing = ""
+		m.CredentialInput.Blur()
+		if err := m.SetProviderAPIKey(provider, apiKey); err != nil {
+			m.CredentialsStatus = fmt.Sprintf("Failed to save the %s key: %v", KeyedProviders[provider].Name, err)
+			return m, nil
+		}
+		m.UpdateCredentialsTable()
+		m.CredentialsStatus = "Saved the " + KeyedProviders[provider].Name + " key, checking it…"
+		return m, KeyCheckCmd(provider, apiKey)
+	}
+
+	var cmd tea.Cmd
+	m.CredentialInput, cmd = m.CredentialInput.Update(msg)
+	return m, cmd
+}
+
+// HandleKeyChecked records the outcome of a key check for the next start
+// and shows it
+func (m *Model) HandleKeyChecked(msg KeyCheckedMsg) {
+	err := utils.UpdateConfig(func(c *utils.Config) {
+		if c.KeyChecks == nil {
+			c.KeyChecks = map[string]utils.KeyCheck{}
+		}
+		c.KeyChecks[msg.Provider] = msg.Check
+	})
+	name := KeyedProviders[msg.Provider].Name
+	switch {
+	case err != nil:
+		m.CredentialsStatus = fmt.Sprintf("Failed to save the check of the %s key: %v", name, err)
+	case msg.Check.Error != "":
+		m.CredentialsStatus = fmt.Sprintf("The %s key doesn't work: %s", name, msg.Check.Error)
+	default:
+		m.CredentialsStatus = "The " + name + " key works"
+	}
+	m.UpdateCredentialsTable()
+}
+
+// HandleAPIKeyChecked moves on to the model list once the provider accepted
+// the key entered on the API key screen, saving it for the next sessions.
+// A rejected key stays on the screen with the provider's error.
+func (m *Model) HandleAPIKeyChecked(msg KeyCheckedMsg) tea.Cmd {
+	m.APIKeyChecking = ""
+	if msg.Check.Error != "" {
+		m.APIKeyError = msg.Check.Error
+		return nil
+	}
+
+	// Keep the API key for this session and save it for future ones; the
+	// session can go on when saving fails
+	if err := m.SetProviderAPIKey(msg.Provider, msg.APIKey); err != nil {
+		m.Err = err
+	}
+	m.HandleKeyChecked(msg)
+	m.State = StateModelSelect
+	return tea.Batch(
+		tea.ClearScreen,
+		RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight),
+		FetchModelsCmd(msg.Provider, msg.APIKey),
+	)
+}
+
+// CredentialsView renders the API keys screen
+func (m Model) CredentialsView() string {
+	help := "Enter/a: add or replace | d: delete | t: test | Esc: back"
+	views := []string{
+		TitleStyle.Render("API keys"),
+		ResponseStyle.Render(m.CredentialsTable.View()),
+	}
+	if m.CredentialsEditing != "" {
+		provider := KeyedProviders[m.CredentialsEditing]
+		views = append(views,
+			lipgloss.NewStyle().Padding(0, 2).Render(fmt.Sprintf("New %s key, from %s · Enter: save | Esc: cancel", provider.Name, provider.KeysURL)),
+			InputBoxStyle.Copy().Width(m.ScreenWidth-4).Render(m.CredentialInput.View()),
+		)
+		help = ""
+	}
+	if m.CredentialsStatus != "" {
+		views = append(views, lipgloss.NewStyle().Padding(0, 2).Render(m.CredentialsStatus))
+	}
+	if help != "" {
+		views = append(views, HelpStyle.Render(help))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, views...)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "keys",
+		Description: "Add, replace, delete and test the API keys of the providers",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenCredentials()
+		},
+	})
+}
@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// RunningModelsRefreshInterval is how often the loaded models screen polls Ollama
+const RunningModelsRefreshInterval = 2 * time.Second
+
+// RunningModelsTickMsg triggers a refresh of the loaded models screen. Ticks
+// from a previous visit to the screen are ignored by their ID.
+type RunningModelsTickMsg struct {
+	ID int
+}
+
+// RunningModelsTickCmd schedules the next refresh of the loaded models screen
+func RunningModelsTickCmd(id int) tea.Cmd {
+	return tea.Tick(RunningModelsRefreshInterval, func(time.Time) tea.Msg {
+		return RunningModelsTickMsg{ID: id}
+	})
+}
+
+// NewRunningModelsTable creates the table for the loaded models screen
+func NewRunningModelsTable() table.Model {
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Model", Width: 30},
+			{Title: "Size", Width: 10},
+			{Title: "VRAM", Width: 10},
+			{Title: "Processor", Width: 16},
+			{Title: "Unloads", Width: 16},
+		}),
+		table.WithFocused(true),
+	)
+
+	t.SetStyles(TableStyles())
+
+	return t
+}
+
+// RunningModelRow formats a loaded model as a table row
+func RunningModelRow(r models.RunningModel, now time.Time) table.Row {
+	return table.Row{
+		r.Name,
+		utils.FormatBytes(r.Size),
+		utils.FormatBytes(r.SizeVRAM),
+		processorSplit(r),
+		expiresIn(r.ExpiresAt, now),
+	}
+}
+
+// processorSplit describes how a model is split between CPU and GPU memory,
+// matching the PROCESSOR column of `ollama ps`
+func processorSplit(r models.RunningModel) string {
+	switch {
+	case r.Size <= 0 || r.SizeVRAM <= 0:
+		return "100% CPU"
+	case r.SizeVRAM >= r.Size:
+		return "100% GPU"
+	}
+	gpu := r.SizeVRAM * 100 / r.Size
+	return fmt.Sprintf("%d%%/%d%% CPU/GPU", 100-gpu, gpu)
+}
+
+// expiresIn describes when Ollama will unload a model
+func expiresIn(expires, now time.Time) string {
+	if expires.IsZero() {
+		return "-"
+	}
+	d := expires.Sub(now)
+	switch {
+	case d > 24*time.Hour*365:
+		return "never"
+	case d <= 0:
+		return "now"
+	}
+	return "in " + d.Round(time.Second).String()
+}
+
+// OpenRunningModels shows the loaded models screen and starts polling
+func (m *Model) OpenRunningModels() tea.Cmd {
+	if m.SelectedProvider != "ollama" {
+		m.StatusMessage = "Loaded models are only available for Ollama"
+		return nil
+	}
+	m.RunningModelsReturnState = m.State
+	m.RunningModelsTick++
+	m.State = StateRunningModels
+	m.UpdateRunningModelsTable()
+	return tea.Batch(
+		tea.ClearScreen,
+		RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight),
+		FetchRunningModelsCmd(),
+		RunningModelsTickCmd(m.RunningModelsTick),
+	)
+}
+
+// UpdateRunningModelsTable refreshes the table rows from the latest /api/ps data
+func (m *Model) UpdateRunningModelsTable() {
+	now := time.Now()
+	rows := make([]table.Row, 0, len(m.RunningModels))
+	for _, r := range m.RunningModels {
+		rows = append(rows, RunningModelRow(r, now))
+	}
+	m.RunningModelsTable.SetRows(rows)
+}
+
+// UpdateRunningModels handles keys on the loaded models screen
+func (m Model) UpdateRunningModels(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		m.State = m.RunningModelsReturnState
+		m.RunningModelsTick++
+		return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	case "ctrl+c":
+		return m, m.RequestQuit()
+	case "r":
+		return m, FetchRunningModelsCmd()
+	case "u":
+		if row := m.RunningModelsTable.SelectedRow(); row != nil {
+			m.RunningModelsStatus = fmt.Sprintf("Unloading %s…", row[0])
+			return m, UnloadModelCmd(row[0])
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.RunningModelsTable, cmd = m.RunningModelsTable.Update(msg)
+	return m, cmd
+}
+
+// RunningModelsView renders the loaded models screen
+func (m Model) RunningModelsView() string {
+	titleView := TitleStyle.Render("Loaded models")
+
+	body := m.RunningModelsTable.View()
+	if len(m.RunningModels) == 0 {
+		body = "No models are loaded right now."
+	}
+
+	var total, vram int64
+	for _, r := range m.RunningModels {
+		total += r.Size
+		vram += r.SizeVRAM
+	}
+	summary := fmt.Sprintf("%d loaded · %s total · %s in VRAM · refreshed every %s",
+		len(m.RunningModels), utils.FormatBytes(total), utils.FormatBytes(vram), RunningModelsRefreshInterval)
+	if m.RunningModelsStatus != "" {
+		summary += " · " + m.RunningModelsStatus
+	}
+
+	help := "u: unload | r: refresh | Esc: back"
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		titleView,
+		ResponseStyle.Render(body),
+		lipgloss.NewStyle().Padding(0, 2).Render(summary),
+		HelpStyle.Render(help),
+	)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "ps",
+		Description: "Show the models Ollama has loaded and their memory use",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenRunningModels()
+		},
+	})
+}
@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// DeterministicSeed is the seed deterministic mode pins when /seed set none
+const DeterministicSeed = 42
+
+// ReplayMsg carries the response of a replayed request
+type ReplayMsg struct {
+	Tab int
+	// Index is the response whose request was replayed
+	Index    int
+	Response string
+	Duration time.Duration
+	Err      error
+}
+
+// ToggleDeterministic turns deterministic mode on or off and remembers the
+// choice. While it is on, requests are sent with temperature 0 and a fixed
+// seed, and each response keeps the request it came from for /replay.
+func (m *Model) ToggleDeterministic() {
+	m.Deterministic = !m.Deterministic
+	if m.Deterministic {
+		m.TemperatureBeforeDeterministic = APIClient.Temperature
+	} else {
+		APIClient.Temperature = m.TemperatureBeforeDeterministic
+		m.TemperatureBeforeDeterministic = nil
+	}
+	m.ApplyRequestOptions(APIClient, m.SelectedProvider)
+
+	deterministic := m.Deterministic
+	if err := utils.UpdateConfig(func(c *utils.Config) { c.Deterministic = deterministic }); err != nil {
+		m.Err = err
+	}
+	if deterministic {
+		m.StatusMessage = fmt.Sprintf("Deterministic mode on: temperature 0, seed %d, requests recorded for /replay", *APIClient.Seed)
+	} else {
+		m.StatusMessage = "Deterministic mode off"
+	}
+}
+
+// SetResponseRequest records the request the latest response was generated
+// from, keeping the first of a response that called tools
+func (m *Model) SetResponseRequest(request *models.RecordedRequest) {
+	if len(m.Messages) == 0 {
+		return
+	}
+	last := &m.Messages[len(m.Messages)-1]
+	if last.Role == models.RoleAssistant && last.Request == nil {
+		last.Request = request
+	}
+}
+
+// replayIndex returns the response to replay: the argument (1-based), the
+// selected message, or the latest response with a recorded request
+func (m Model) replayIndex(args string) (int, error) {
+	if args != "" || m.SelectedMessage >= 0 {
+		return m.forkIndex(args)
+	}
+	for i := len(m.Messages) - 1; i >= 0; i-- {
+		if m.Messages[i].Request != nil {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no response has a recorded request: turn on /deterministic and send a prompt")
+}
+
+// StartReplay sends the recorded request of a response again as is
+func (m *Model) StartReplay(args string) tea.Cmd {
+	index, err := m.replayIndex(args)
+	if err != nil {
+		m.StatusMessage = err.Error()
+		return nil
+	}
+	msg := m.Messages[index]
+	if msg.Role != models.RoleAssistant || msg.Request == nil {
+		m.StatusMessage = fmt.Sprintf("Message %d has no recorded request: only responses sent in /deterministic mode can be replayed", index+1)
+		return nil
+	}
+
+	client := APIClient
+	if provider := msg.Request.Provider; provider != "" && provider != m.SelectedProvider {
+		client = api.NewClient(provider, ProviderAPIKey(provider))
+	}
+	m.StatusMessage = fmt.Sprintf("Replaying response %d…", index+1)
+	return ReplayCmd(m.TabID, index, client, *msg.Request, m.GenerationTimeout)
+}
+
+// ReplayCmd sends a recorded request again in the background and returns
+// the whole response
+func ReplayCmd(tab, index int, client *api.Client, request models.RecordedRequest, timeout time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := generationContext(timeout)
+		defer cancel()
+
+		start := time.Now()
+		var sb strings.Builder
+		var err error
+		for event := range client.Replay(ctx, request) {
+			switch event := event.(type) {
+			case api.TokenEvent:
+				sb.WriteString(event.Text)
+			case api.ErrorEvent:
+				err = event.Err
+			}
+		}
+		if err == nil && ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("time limit of %s reached", timeout)
+		}
+		return ReplayMsg{Tab: tab, Index: index, Response: sb.String(), Duration: time.Since(start), Err: err}
+	}
+}
+
+// HandleReplay reports whether the replayed response matches the original,
+// showing it when it doesn't
+func (m *Model) HandleReplay(msg ReplayMsg) {
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("Replay of response %d failed: %v", msg.Index+1, msg.Err)
+		return
+	}
+	if msg.Index >= len(m.Messages) {
+		return
+	}
+
+	// Legends of sources and citations and the labels of errors and the
+	// time limit were added to the original after it streamed
+	original, _ := splitResponse(m.Messages[msg.Index].Content)
+	replayed := strings.TrimSpace(msg.Response)
+	if strings.TrimSpace(original) == replayed {
+		m.StatusMessage = fmt.Sprintf("Replay of response %d matches it exactly (%s)", msg.Index+1, msg.Duration.Round(100*time.Millisecond))
+		return
+	}
+	m.AddNotice(fmt.Sprintf("Replay of response %d differs from it (%s):\n\n%s", msg.Index+1, msg.Duration.Round(100*time.Millisecond), replayed))
+	m.FollowTranscript()
+	m.StatusMessage = fmt.Sprintf("Replay of response %d differs from it", msg.Index+1)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "deterministic",
+		Description: "Toggle temperature 0 with a fixed seed, recording each request for /replay",
+		Run: func(m *Model, args string) tea.Cmd {
+			m.ToggleDeterministic()
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "replay",
+		Usage:       "[n]",
+		Description: "Send the exact request of a response again and compare the answers",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.StartReplay(args)
+		},
+	})
+}
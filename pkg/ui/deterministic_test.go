@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+func TestHandleReplay(t *testing.T) {
+	answer := "First paragraph.\n\nSecond paragraph."
+	legends := "\n\n" + SourcesLegend([]models.Attachment{{Name: "a.go", Source: "a.go"}, {Name: "b.go", Source: "b.go"}}) +
+		"\n\n" + CitationsLegend([]string{"https://example.com"})
+	tests := []struct {
+		name     string
+		original string
+		replayed string
+		matches  bool
+	}{
+		{name: "same", original: answer, replayed: answer, matches: true},
+		{name: "same with legends", original: answer + legends, replayed: answer, matches: true},
+		{name: "same before the time limit", original: answer + timeLimitLabel + " 1m0s reached]", replayed: answer, matches: true},
+		{name: "same before an error", original: answer + errorLabel + "connection reset]", replayed: answer, matches: true},
+		{name: "truncated", original: answer, replayed: "First paragraph.", matches: false},
+		{name: "truncated with legends", original: answer + legends, replayed: "First paragraph.", matches: false},
+		{name: "diverging", original: answer, replayed: "First paragraph.\n\nAnother one.", matches: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, 80, 24)
+			m.Messages = []models.Message{
+				{Role: models.RoleUser, Content: "Explain"},
+				{Role: models.RoleAssistant, Content: tt.original},
+			}
+			m.HandleReplay(ReplayMsg{Index: 1, Response: tt.replayed})
+			if matched := strings.Contains(m.StatusMessage, "matches"); matched != tt.matches {
+				t.Errorf("status %q, want a match: %v", m.StatusMessage, tt.matches)
+			}
+		})
+	}
+}
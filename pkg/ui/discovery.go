@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// discoveryTimeout limits a whole discovery run
+const discoveryTimeout = 30 * time.Second
+
+// EndpointItem is a discovered server in the provider list
+type EndpointItem struct {
+	api.Endpoint
+}
+
+// Title returns the server's URL
+func (i EndpointItem) Title() string { return i.URL }
+
+// Description names the kind of server and how many models it offers
+func (i EndpointItem) Description() string {
+	kind := "OpenAI-compatible server"
+	if i.Provider == "ollama" {
+		kind = "Ollama " + i.Version
+	}
+	return fmt.Sprintf("%s · %d models", kind, i.Models)
+}
+
+// FilterValue returns the value to use for filtering the list
+func (i EndpointItem) FilterValue() string { return i.URL }
+
+// ProviderItems returns the built-in providers and plugins, described with
+// the server each one connects to and, once detected, whether LM Studio is
+// running. Providers left out of EnabledProviders are skipped.
+func ProviderItems(lmStudio *api.LMStudioStatus) []list.Item {
+	ollama := "Local LLM server"
+	if api.OllamaURL != api.DefaultOllamaURL {
+		ollama = "Ollama at " + api.OllamaURL
+	}
+	openAI := "OpenAI API"
+	if !OpenAIKeyRequired() {
+		openAI = "OpenAI-compatible server at " + api.OpenAIURL
+	}
+	huggingFace := fmt.Sprintf("Hugging Face · %d endpoints", len(api.HuggingFaceEndpoints))
+	if len(api.HuggingFaceEndpoints) == 0 {
+		huggingFace = `Hugging Face endpoints, listed under "huggingface" in config.json`
+	}
+	items := []list.Item{
+		models.ListItem{Name: "ollama", Details: ollama},
+		models.ListItem{Name: "openai", Details: openAI},
+		models.ListItem{Name: "lmstudio", Details: lmStudioDetails(lmStudio)},
+		models.ListItem{Name: "mistral", Details: "Mistral AI platform"},
+		models.ListItem{Name: "deepseek", Details: "DeepSeek API, with deepseek-reasoner's thinking"},
+		models.ListItem{Name: "together", Details: "Together AI, open models hosted at " + api.Presets["together"].URL},
+		models.ListItem{Name: "fireworks", Details: "Fireworks, open models hosted at " + api.Presets["fireworks"].URL},
+		models.ListItem{Name: "perplexity", Details: "Perplexity, answers from web search with cited sources"},
+		models.ListItem{Name: "huggingface", Details: huggingFace},
+	}
+	for _, name := range pluginNames() {
+		items = append(items, models.ListItem{Name: name, Details: "Plugin · " + api.Plugins[name].Path})
+	}
+	if api.MockEnabled {
+		items = append(items, models.ListItem{Name: api.MockProvider, Details: fmt.Sprintf("Canned responses at %d tokens/s, no server needed", api.MockSpeed)})
+	}
+	items = append(items, models.ListItem{Name: AllProviders, Details: "Models of every configured provider in one list"})
+
+	enabled := items[:0]
+	for _, item := range items {
+		if providerEnabled(item.(models.ListItem).Name) {
+			enabled = append(enabled, item)
+		}
+	}
+	return enabled
+}
+
+// RefreshProviderItems lists the built-in providers followed by the
+// discovered servers
+func (m *Model) RefreshProviderItems() {
+	items := ProviderItems(m.LMStudio)
+	for _, endpoint := range m.Endpoints {
+		items = append(items, EndpointItem{endpoint})
+	}
+	m.ProviderList.SetItems(items)
+}
+
+// DiscoverCmd probes the hosts and ports in the config for servers
+func DiscoverCmd() tea.Cmd {
+	config, _ := utils.LoadConfig()
+	targets := api.DiscoveryTargets(config.Discovery.Hosts, config.Discovery.Ports, config.Discovery.ScanLAN)
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+		defer cancel()
+		return DiscoveryMsg{Endpoints: api.Discover(ctx, targets), Probed: len(targets)}
+	}
+}
+
+// StartDiscovery starts looking for servers on the network
+func (m *Model) StartDiscovery() tea.Cmd {
+	if m.Discovering {
+		return nil
+	}
+	m.Discovering = true
+	m.ProviderList.Title = "Available providers · searching the network…"
+	return DiscoverCmd()
+}
+
+// HandleDiscovery lists the servers a discovery run found
+func (m *Model) HandleDiscovery(msg DiscoveryMsg) {
+	m.Discovering = false
+	m.Endpoints = msg.Endpoints
+	m.RefreshProviderItems()
+	switch len(msg.Endpoints) {
+	case 0:
+		m.ProviderList.Title = fmt.Sprintf("Available providers · no servers found at %d addresses", msg.Probed)
+	case 1:
+		m.ProviderList.Title = "Available providers · found 1 server"
+	default:
+		m.ProviderList.Title = fmt.Sprintf("Available providers · found %d servers", len(msg.Endpoints))
+	}
+}
+
+// SelectEndpoint connects the provider of a discovered server to it,
+// remembers the choice for the next start and opens its model list
+func (m *Model) SelectEndpoint(endpoint api.Endpoint) tea.Cmd {
+	if endpoint.Provider == "openai" {
+		api.OpenAIURL = endpoint.URL
+	} else {
+		api.OllamaURL = endpoint.URL
+	}
+	err := utils.UpdateConfig(func(c *utils.Config) {
+		if endpoint.Provider == "openai" {
+			c.OpenAIBaseURL = endpoint.URL
+		} else {
+			c.OllamaHost = endpoint.URL
+		}
+	})
+	if err != nil {
+		m.Err = err
+	}
+	m.RefreshProviderItems()
+
+	// OpenAI keys are not sent to other servers; credentials for those go
+	// in "hosts"
+	m.SelectedProvider = endpoint.Provider
+	m.AllProviders = false
+	m.State = StateModelSelect
+	return tea.Batch(
+		tea.ClearScreen,
+		RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight),
+		FetchModelsCmd(endpoint.Provider, ""),
+	)
+}
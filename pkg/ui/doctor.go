@@ -0,0 +1,463 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// doctorTimeout limits all the checks of the doctor together
+const doctorTimeout = 20 * time.Second
+
+// minOllamaVersion is the oldest Ollama with everything the chat uses:
+// structured outputs need 0.5
+const minOllamaVersion = "0.5.0"
+
+// DiagnosticStatus is how a check of the doctor went
+type DiagnosticStatus int
+
+const (
+	// DiagnosticOK is a check that passed
+	DiagnosticOK DiagnosticStatus = iota
+	// DiagnosticInfo is a fact worth knowing, such as an unused provider
+	DiagnosticInfo
+	// DiagnosticWarn is a problem that leaves the app usable
+	DiagnosticWarn
+	// DiagnosticFail is a problem that keeps part of the app from working
+	DiagnosticFail
+)
+
+// Diagnostic is the outcome of one check of the doctor
+type Diagnostic struct {
+	// Section groups checks: Ollama, Providers, Config or Terminal
+	Section string
+	Name    string
+	Status  DiagnosticStatus
+	Detail  string
+	// Fix says how to solve a problem
+	Fix string
+}
+
+// DiagnosticsMsg carries the outcome of the doctor's checks
+type DiagnosticsMsg struct {
+	Results []Diagnostic
+}
+
+// RunDiagnostics checks the Ollama server, the providers and their keys,
+// the config file and the terminal. Checks that reach a server run at once.
+func RunDiagnostics(ctx context.Context) []Diagnostic {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	var results []Diagnostic
+	results = append(results, diagnoseOllama(ctx)...)
+	results = append(results, diagnoseProviders(ctx)...)
+	results = append(results, diagnoseConfig()...)
+	return append(results, diagnoseTerminal()...)
+}
+
+// DiagnosticsCmd runs the doctor's checks in the background
+func DiagnosticsCmd() tea.Cmd {
+	return func() tea.Msg {
+		return DiagnosticsMsg{Results: RunDiagnostics(context.Background())}
+	}
+}
+
+// diagnoseOllama checks that the Ollama server answers and has models
+func diagnoseOllama(ctx context.Context) []Diagnostic {
+	server := Diagnostic{Section: "Ollama", Name: "Server"}
+	version, err := api.OllamaVersion(ctx)
+	if err != nil {
+		server.Status = DiagnosticFail
+		server.Detail = fmt.Sprintf("%s doesn't answer: %v", api.OllamaURL, err)
+		server.Fix = "Start Ollama with `ollama serve`, or point --host, OLLAMA_HOST or \"ollama_host\" in config.json at your server"
+		return []Diagnostic{server}
+	}
+	server.Detail = fmt.Sprintf("Ollama %s at %s", version, api.OllamaURL)
+	if versionBefore(version, minOllamaVersion) {
+		server.Status = DiagnosticWarn
+		server.Fix = fmt.Sprintf("Update Ollama to %s or later for structured outputs (/json with a schema)", minOllamaVersion)
+	}
+
+	available := Diagnostic{Section: "Ollama", Name: "Models"}
+	list, err := api.NewClient("ollama", "").FetchModels()
+	switch {
+	case err != nil:
+		available.Status = DiagnosticFail
+		available.Detail = err.Error()
+	case len(list) == 0:
+		available.Status = DiagnosticWarn
+		available.Detail = "No models installed"
+		available.Fix = "Download one with `ollama pull llama3.2`"
+	default:
+		available.Detail = fmt.Sprintf("%d models installed", len(list))
+	}
+	return []Diagnostic{server, available}
+}
+
+// versionBefore reports whether version v is older than min, comparing
+// their dotted numbers. Versions that don't parse, like development
+// builds, are taken to be recent.
+func versionBefore(v, min string) bool {
+	a := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	b := strings.Split(min, ".")
+	for i := range b {
+		if i >= len(a) {
+			return true
+		}
+		x, err := strconv.Atoi(strings.SplitN(a[i], "-", 2)[0])
+		if err != nil {
+			return false
+		}
+		y, _ := strconv.Atoi(b[i])
+		if x != y {
+			return x < y
+		}
+	}
+	return false
+}
+
+// diagnoseProviders checks the key of every keyed provider that has one
+// and lists LM Studio, Hugging Face endpoints and plugins
+func diagnoseProviders(ctx context.Context) []Diagnostic {
+	config, _ := utils.LoadEffectiveConfig()
+	results := make([]Diagnostic, len(credentialProviders))
+	var wg sync.WaitGroup
+	for i, provider := range credentialProviders {
+		keyed := KeyedProviders[provider]
+		result := Diagnostic{Section: "Providers", Name: keyed.Name}
+		key, source := storedAPIKey(provider, config)
+		switch {
+		case !providerEnabled(provider):
+			result.Status = DiagnosticInfo
+			result.Detail = "Left out of \"providers\""
+		case provider == "huggingface" && len(api.HuggingFaceEndpoints) == 0:
+			result.Status = DiagnosticInfo
+			result.Detail = "No endpoints under \"huggingface\" in config.json"
+		case key == "" && KeyRequired(provider):
+			result.Status = DiagnosticInfo
+			result.Detail = "Not configured"
+		default:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = diagnoseKey(ctx, provider, key, source)
+			}()
+			continue
+		}
+		results[i] = result
+	}
+
+	lmStudio := Diagnostic{Section: "Providers", Name: "LM Studio", Status: DiagnosticInfo}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		status := api.DetectLMStudio(ctx)
+		lmStudio.Detail = "Not running at " + api.Presets["lmstudio"].URL
+		if status.Running {
+			lmStudio.Status = DiagnosticOK
+			lmStudio.Detail = "Running at " + api.Presets["lmstudio"].URL
+		}
+	}()
+	wg.Wait()
+
+	results = append(results, lmStudio)
+	for _, name := range pluginNames() {
+		results = append(results, Diagnostic{Section: "Providers", Name: name, Detail: "Plugin at " + api.Plugins[name].Path})
+	}
+	return results
+}
+
+// diagnoseKey checks a provider's key with the provider
+func diagnoseKey(ctx context.Context, provider, key, source string) Diagnostic {
+	keyed := KeyedProviders[provider]
+	result := Diagnostic{Section: "Providers", Name: keyed.Name}
+	described := "No key"
+	if key != "" {
+		described = fmt.Sprintf("Key %s from %s", maskKey(key), source)
+	}
+	if provider == "openai" && !OpenAIKeyRequired() {
+		described = "Compatible server at " + api.OpenAIURL
+	}
+
+	if err := api.NewClient(provider, key).CheckKey(ctx); err != nil {
+		result.Status = DiagnosticFail
+		result.Detail = fmt.Sprintf("%s: %v", described, err)
+		result.Fix = fmt.Sprintf("Replace the key with /keys, or K on the provider list; keys are at %s", keyed.KeysURL)
+		if provider == "openai" && !OpenAIKeyRequired() {
+			result.Fix = "Check that the server at \"openai_base_url\" in config.json is running"
+		}
+		return result
+	}
+	result.Detail = described + ", accepted"
+	return result
+}
+
+// diagnoseConfig checks that config.json is valid and private, and that
+// its keybindings and environment overrides apply
+func diagnoseConfig() []Diagnostic {
+	path, err := utils.GetConfigPath()
+	if err != nil {
+		return []Diagnostic{{Section: "Config", Name: "File", Status: DiagnosticFail, Detail: err.Error()}}
+	}
+	file := Diagnostic{Section: "Config", Name: "File", Detail: path}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		file.Status = DiagnosticInfo
+		file.Detail = path + " doesn't exist yet; the defaults are used"
+		return []Diagnostic{file}
+	}
+	if err != nil {
+		file.Status = DiagnosticFail
+		file.Detail = err.Error()
+		return []Diagnostic{file}
+	}
+
+	config, err := utils.LoadConfig()
+	if err != nil {
+		file.Status = DiagnosticFail
+		file.Detail = fmt.Sprintf("%s is invalid: %v", path, err)
+		file.Fix = "Fix the JSON syntax; the defaults are used until then"
+		return []Diagnostic{file}
+	}
+	results := []Diagnostic{file}
+
+	permissions := Diagnostic{Section: "Config", Name: "Permissions", Detail: info.Mode().Perm().String()}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		permissions.Status = DiagnosticWarn
+		permissions.Detail += ", readable by other users, and it can hold API keys"
+		permissions.Fix = "chmod 600 " + path
+	}
+	results = append(results, permissions)
+
+	if len(config.Keybindings) > 0 {
+		bindings := Diagnostic{Section: "Config", Name: "Keybindings", Detail: fmt.Sprintf("%d custom bindings", len(config.Keybindings))}
+		custom, conflicts := DefaultKeyMap().WithOverrides(config.Keybindings)
+		conflicts = append(conflicts, custom.DetectConflicts()...)
+		if len(conflicts) > 0 {
+			c := conflicts[0]
+			bindings.Status = DiagnosticWarn
+			bindings.Detail = fmt.Sprintf("%s for %s: %s", FormatKey(c.Key), c.Action, c.Reason)
+			if len(conflicts) > 1 {
+				bindings.Detail += fmt.Sprintf(", and %d more conflicts", len(conflicts)-1)
+			}
+			bindings.Fix = "Change \"keybindings\" in config.json, or review the conflicts at startup"
+		}
+		results = append(results, bindings)
+	}
+
+	if err := utils.ApplyEnvOverrides(&config); err != nil {
+		results = append(results, Diagnostic{
+			Section: "Config", Name: "Environment", Status: DiagnosticWarn,
+			Detail: err.Error(), Fix: "Fix or unset the " + utils.EnvPrefix + "* variables named",
+		})
+	}
+	if utils.ActiveProfile != "" {
+		results = append(results, Diagnostic{Section: "Config", Name: "Profile", Status: DiagnosticInfo, Detail: utils.ActiveProfile})
+	}
+	return results
+}
+
+// diagnoseTerminal checks what the terminal can draw
+func diagnoseTerminal() []Diagnostic {
+	termName := os.Getenv("TERM")
+	colorTerm := os.Getenv("COLORTERM")
+	tmux := os.Getenv("TMUX") != ""
+
+	screen := Diagnostic{Section: "Terminal", Name: "Screen", Detail: "TERM=" + termName}
+	switch {
+	case !term.IsTerminal(int(os.Stdout.Fd())):
+		screen.Status = DiagnosticWarn
+		screen.Detail = "Output is not a terminal"
+		screen.Fix = "Run ollama-tui in an interactive terminal, or use --accessible for plain text"
+	case runtime.GOOS != "windows" && (termName == "" || termName == "dumb"):
+		screen.Status = DiagnosticFail
+		screen.Detail = fmt.Sprintf("TERM=%q can't switch to the alternate screen", termName)
+		screen.Fix = "Set TERM=xterm-256color, or use --accessible for plain text"
+	default:
+		if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			screen.Detail += fmt.Sprintf(", %dx%d", width, height)
+			if width < 80 || height < 24 {
+				screen.Status = DiagnosticWarn
+				screen.Fix = "Enlarge the window to at least 80x24 so the chat and lists fit"
+			}
+		}
+	}
+
+	colors := Diagnostic{Section: "Terminal", Name: "Colors"}
+	switch {
+	case colorTerm == "truecolor" || colorTerm == "24bit":
+		colors.Detail = "Truecolor"
+	case strings.Contains(termName, "256color"):
+		colors.Status = DiagnosticWarn
+		colors.Detail = "256 colors; colors are approximated"
+		colors.Fix = "Set COLORTERM=truecolor if your terminal supports 24-bit color"
+	default:
+		colors.Status = DiagnosticWarn
+		colors.Detail = "Basic colors only"
+		colors.Fix = "Use a terminal with 256 colors or truecolor, or set TERM=xterm-256color"
+	}
+	if tmux && colors.Status != DiagnosticOK {
+		colors.Fix = "Add `set -as terminal-features ',*:RGB'` to ~/.tmux.conf and set COLORTERM=truecolor"
+	}
+
+	images := Diagnostic{Section: "Terminal", Name: "Images", Status: DiagnosticInfo}
+	switch protocol := utils.DetectImageProtocol(); {
+	case protocol != utils.ImageNone:
+		images.Status = DiagnosticOK
+		images.Detail = fmt.Sprintf("Drawn with the %s protocol", protocol)
+	case tmux:
+		images.Detail = "Not drawn inside tmux"
+	default:
+		images.Detail = "The terminal has no known image protocol, so images aren't drawn"
+	}
+	return []Diagnostic{screen, colors, images}
+}
+
+// diagnosticSymbol marks a check by its status
+func diagnosticSymbol(status DiagnosticStatus) string {
+	switch status {
+	case DiagnosticInfo:
+		return "·"
+	case DiagnosticWarn:
+		return "!"
+	case DiagnosticFail:
+		return "✗"
+	}
+	return "✓"
+}
+
+// WriteDiagnostics prints the checks as a report, grouped by section, with
+// the fixes of the problems
+func WriteDiagnostics(w io.Writer, results []Diagnostic) {
+	section := ""
+	for _, r := range results {
+		if r.Section != section {
+			if section != "" {
+				fmt.Fprintln(w)
+			}
+			section = r.Section
+			fmt.Fprintln(w, section)
+		}
+		fmt.Fprintf(w, "  %s %s: %s\n", diagnosticSymbol(r.Status), r.Name, r.Detail)
+		if r.Fix != "" {
+			fmt.Fprintf(w, "    → %s\n", r.Fix)
+		}
+	}
+}
+
+// DiagnosticsFailed reports whether any check failed
+func DiagnosticsFailed(results []Diagnostic) bool {
+	for _, r := range results {
+		if r.Status == DiagnosticFail {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenDiagnostics shows the diagnostics screen and runs the checks
+func (m *Model) OpenDiagnostics() tea.Cmd {
+	if m.State != StateDiagnostics {
+		m.DoctorReturnState = m.State
+	}
+	m.State = StateDiagnostics
+	m.DoctorRunning = true
+	m.UpdateDiagnosticsView()
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight), DiagnosticsCmd())
+}
+
+// HandleDiagnostics shows the outcome of the checks
+func (m *Model) HandleDiagnostics(msg DiagnosticsMsg) {
+	m.DoctorRunning = false
+	m.Diagnostics = msg.Results
+	m.UpdateDiagnosticsView()
+}
+
+// UpdateDiagnostics handles keys on the diagnostics screen
+func (m Model) UpdateDiagnostics(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		m.State = m.DoctorReturnState
+		return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	case "ctrl+c":
+		return m, m.RequestQuit()
+	case "r":
+		if m.DoctorRunning {
+			return m, nil
+		}
+		m.DoctorRunning = true
+		m.UpdateDiagnosticsView()
+		return m, DiagnosticsCmd()
+	}
+
+	var cmd tea.Cmd
+	m.DoctorViewport, cmd = m.DoctorViewport.Update(msg)
+	return m, cmd
+}
+
+// UpdateDiagnosticsView renders the checks into the diagnostics viewport
+func (m *Model) UpdateDiagnosticsView() {
+	if m.DoctorRunning {
+		m.DoctorViewport.SetContent("Running checks…")
+		return
+	}
+
+	var sb strings.Builder
+	section := ""
+	for _, r := range m.Diagnostics {
+		if r.Section != section {
+			if section != "" {
+				sb.WriteString("\n")
+			}
+			section = r.Section
+			sb.WriteString(SelectedHeaderStyle.Render(section) + "\n")
+		}
+		symbol := diagnosticSymbol(r.Status)
+		switch r.Status {
+		case DiagnosticWarn:
+			symbol = lipgloss.NewStyle().Foreground(CurrentTheme.Warning).Render(symbol)
+		case DiagnosticFail:
+			symbol = ErrorTextStyle.Render(symbol)
+		}
+		sb.WriteString(fmt.Sprintf("  %s %s: %s\n", symbol, r.Name, r.Detail))
+		if r.Fix != "" {
+			sb.WriteString(CollapsedStyle.Render("    → "+r.Fix) + "\n")
+		}
+	}
+	m.DoctorViewport.SetContent(lipgloss.NewStyle().Width(m.DoctorViewport.Width).Render(strings.TrimRight(sb.String(), "\n")))
+}
+
+// DiagnosticsView renders the diagnostics screen
+func (m Model) DiagnosticsView() string {
+	help := "r: run again | ↑/↓: scroll | Esc: back"
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		TitleStyle.Render("Diagnostics"),
+		lipgloss.NewStyle().Padding(0, 2).Render(m.DoctorViewport.View()),
+		HelpStyle.Render(help),
+	)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "doctor",
+		Description: "Check the Ollama server, provider keys, config file and terminal",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenDiagnostics()
+		},
+	})
+}
@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// ExportMarkdown writes the current transcript to path as a Markdown document
+func (m Model) ExportMarkdown(path string) error {
+	if err := os.WriteFile(path, []byte(m.MarkdownTranscript()), 0644); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// MarkdownTranscript renders the current transcript as a Markdown document
+func (m Model) MarkdownTranscript() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Chat with %s\n\n", m.SelectedModel))
+	sb.WriteString(fmt.Sprintf("_Exported %s_\n\n", time.Now().Format(time.RFC1123)))
+
+	for _, msg := range m.Messages {
+		switch msg.Role {
+		case models.RoleUser:
+			sb.WriteString(fmt.Sprintf("## You%s\n\n", exportedTiming(msg)))
+		case models.RoleAssistant:
+			sb.WriteString(fmt.Sprintf("## %s%s\n\n", msg.Model, exportedTiming(msg)))
+		default:
+			continue
+		}
+		sb.WriteString(m.exportedContent(msg))
+		sb.WriteString("\n\n---\n\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// IssueURL is where feedback reports are filed
+const IssueURL = "https://github.com/evilvic/ollama-tui/issues/new"
+
+// ExchangeStats holds anonymized metadata about a prompt and its response
+type ExchangeStats struct {
+	Provider       string
+	Model          string
+	PromptChars    int
+	ResponseChars  int
+	Attachments    int
+	Duration       time.Duration
+	TimedOut       bool
+	ContextEnabled bool
+}
+
+// FeedbackReport renders a bug report with the description, the last
+// exchange's metadata (without any prompt or response text), terminal
+// information and the application version
+func (m Model) FeedbackReport(description string) string {
+	if description == "" {
+		description = "_No description provided_"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Description\n\n")
+	sb.WriteString(description)
+	sb.WriteString("\n\n## Environment\n\n")
+	sb.WriteString(fmt.Sprintf("- Version: %s\n", utils.Version()))
+	sb.WriteString(fmt.Sprintf("- OS/Arch: %s/%s\n", runtime.GOOS, runtime.GOARCH))
+	sb.WriteString(fmt.Sprintf("- Go: %s\n", runtime.Version()))
+	sb.WriteString(fmt.Sprintf("- TERM: %s\n", os.Getenv("TERM")))
+	sb.WriteString(fmt.Sprintf("- COLORTERM: %s\n", os.Getenv("COLORTERM")))
+	sb.WriteString(fmt.Sprintf("- TERM_PROGRAM: %s\n", os.Getenv("TERM_PROGRAM")))
+	sb.WriteString(fmt.Sprintf("- Inside tmux: %t\n", os.Getenv("TMUX") != ""))
+	sb.WriteString(fmt.Sprintf("- Terminal size: %dx%d\n", m.ScreenWidth, m.ScreenHeight))
+
+	sb.WriteString("\n## Last request\n\n")
+	if s := m.LastExchange; s.Model != "" {
+		sb.WriteString(fmt.Sprintf("- Provider: %s\n", s.Provider))
+		sb.WriteString(fmt.Sprintf("- Model: %s\n", s.Model))
+		sb.WriteString(fmt.Sprintf("- Prompt length: %d chars\n", s.PromptChars))
+		sb.WriteString(fmt.Sprintf("- Response length: %d chars\n", s.ResponseChars))
+		sb.WriteString(fmt.Sprintf("- Attachments: %d\n", s.Attachments))
+		sb.WriteString(fmt.Sprintf("- Duration: %s\n", s.Duration.Round(time.Millisecond)))
+		sb.WriteString(fmt.Sprintf("- Timed out: %t\n", s.TimedOut))
+		sb.WriteString(fmt.Sprintf("- Conversation context: %t\n", s.ContextEnabled))
+	} else {
+		sb.WriteString("_No request sent yet_\n")
+	}
+	if m.Err != nil {
+		sb.WriteString(fmt.Sprintf("\n## Last error\n\n```\n%s\n```\n", m.Err))
+	}
+
+	return sb.String()
+}
+
+// SaveFeedback writes the report to the data directory and returns its path
+func SaveFeedback(report string) (string, error) {
+	dataDir, err := utils.GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dataDir, fmt.Sprintf("feedback-%s.md", time.Now().Format("20060102-150405")))
+	if err := os.WriteFile(path, []byte(report), 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// FeedbackIssueURL returns a GitHub new-issue URL prefilled with the report
+func FeedbackIssueURL(description, report string) string {
+	title := "Feedback: " + description
+	if description == "" {
+		title = "Feedback from ollama-tui"
+	}
+	if len(title) > 80 {
+		title = title[:77] + "..."
+	}
+	return IssueURL + "?" + url.Values{"title": {title}, "body": {report}}.Encode()
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "feedback",
+		Usage:       "[description]",
+		Description: "Save a bug report and open a prefilled GitHub issue",
+		Run: func(m *Model, args string) tea.Cmd {
+			report := m.FeedbackReport(args)
+			path, err := SaveFeedback(report)
+			if err != nil {
+				m.StatusMessage = fmt.Sprintf("Could not save feedback: %v", err)
+				return nil
+			}
+			if err := utils.OpenURL(FeedbackIssueURL(args, report)); err != nil {
+				m.StatusMessage = fmt.Sprintf("Feedback saved to %s (could not open browser: %v)", path, err)
+				return nil
+			}
+			m.StatusMessage = fmt.Sprintf("Feedback saved to %s and opened in the browser", path)
+			return nil
+		},
+	})
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/muesli/termenv"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+)
+
+// The golden tests drive the whole interface through the mock provider and
+// compare what it shows at the end with testdata/<test name>.golden. Run
+// them with -update to rewrite the golden files after changing the views.
+
+const (
+	goldenWidth  = 80
+	goldenHeight = 24
+	goldenWait   = 5 * time.Second
+)
+
+// startGolden runs the interface from the provider list with the mock
+// provider streaming its responses at once. Only ollama, openai and the mock
+// are offered, so that the list does not depend on the machine, and colours,
+// timestamps and response footers are left out of the views.
+func startGolden(t *testing.T, script ...api.MockResponse) *teatest.TestModel {
+	t.Helper()
+	isolateTest(t)
+	t.Setenv("OPENAI_API_KEY", "")
+
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.Ascii)
+	enabled, speed, mockScript, program := EnabledProviders, api.MockSpeed, api.MockScript, Program
+	t.Cleanup(func() {
+		lipgloss.SetColorProfile(profile)
+		api.MockEnabled = false
+		EnabledProviders, api.MockSpeed, api.MockScript, Program = enabled, speed, mockScript, program
+	})
+	api.MockEnabled, api.MockSpeed, api.MockScript = true, 0, script
+	EnabledProviders = []string{"ollama", "openai", api.MockProvider}
+
+	m := NewModel()
+	m.ShowTimestamps, m.ShowMetadata = false, false
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(goldenWidth, goldenHeight))
+	Program = tm.GetProgram()
+	waitForText(t, tm, "Available providers")
+	return tm
+}
+
+// waitForText waits until the interface has drawn text
+func waitForText(t *testing.T, tm *teatest.TestModel, text string) {
+	t.Helper()
+	teatest.WaitFor(t, tm.Output(), func(out []byte) bool {
+		return bytes.Contains(out, []byte(text))
+	}, teatest.WithDuration(goldenWait))
+}
+
+// pressKeys sends keys of the given types one after another
+func pressKeys(tm *teatest.TestModel, keys ...tea.KeyType) {
+	for _, key := range keys {
+		tm.Send(tea.KeyMsg{Type: key})
+	}
+}
+
+// chatWithMockEcho picks the mock provider and its echo model and sends
+// prompt
+func chatWithMockEcho(t *testing.T, tm *teatest.TestModel, prompt string) {
+	t.Helper()
+	pressKeys(tm, tea.KeyDown, tea.KeyDown, tea.KeyEnter)
+	waitForText(t, tm, "mock-markdown")
+	pressKeys(tm, tea.KeyEnter)
+	waitForText(t, tm, "Write your prompt here")
+	tm.Type(prompt)
+	pressKeys(tm, tea.KeyEnter)
+}
+
+// requireGoldenView quits the interface and compares its last view with the
+// test's golden file
+func requireGoldenView(t *testing.T, tm *teatest.TestModel) {
+	t.Helper()
+	if err := tm.Quit(); err != nil {
+		t.Fatal(err)
+	}
+	final := tm.FinalModel(t, teatest.WithFinalTimeout(goldenWait))
+	teatest.RequireEqualOutput(t, []byte(final.View()))
+}
+
+func TestGoldenProviderSelect(t *testing.T) {
+	tm := startGolden(t)
+	requireGoldenView(t, tm)
+}
+
+func TestGoldenAPIKeyInput(t *testing.T) {
+	tm := startGolden(t)
+	pressKeys(tm, tea.KeyDown, tea.KeyEnter)
+	waitForText(t, tm, "API Key Required")
+	requireGoldenView(t, tm)
+}
+
+func TestGoldenChat(t *testing.T) {
+	tm := startGolden(t)
+	chatWithMockEcho(t, tm, "hello from the golden test")
+	// mock-echo names the session with the prompt asking for a title
+	waitForText(t, tm, "Write a short title")
+	requireGoldenView(t, tm)
+}
+
+func TestGoldenError(t *testing.T) {
+	tm := startGolden(t, api.MockResponse{Prompt: "fail", Response: "Half an answer", Error: "the mock failed on purpose"})
+	chatWithMockEcho(t, tm, "please fail")
+	waitForText(t, tm, "the mock failed on purpose")
+	requireGoldenView(t, tm)
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// maxHistoryMatches is the number of fuzzy search results shown at once
+const maxHistoryMatches = 8
+
+// AddToHistory records a submitted prompt and resets history navigation
+func (m *Model) AddToHistory(prompt string) {
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return
+	}
+
+	if n := len(m.PromptHistory); n == 0 || m.PromptHistory[n-1] != prompt {
+		m.PromptHistory = append(m.PromptHistory, prompt)
+		if m.PersistHistory {
+			if err := utils.SaveHistory(m.PromptHistory); err != nil {
+				m.Err = err
+			}
+		}
+	}
+	m.HistoryIndex = len(m.PromptHistory)
+}
+
+// RecallHistory moves through the prompt history by delta (-1 for older,
+// +1 for newer) and loads the entry into the input. It reports whether the
+// key press was consumed.
+func (m *Model) RecallHistory(delta int) bool {
+	browsing := m.HistoryIndex < len(m.PromptHistory) && m.Input.Value() == m.PromptHistory[m.HistoryIndex]
+	if !browsing {
+		if m.Input.Value() != "" || delta > 0 {
+			return false
+		}
+		m.HistoryIndex = len(m.PromptHistory)
+	}
+
+	index := m.HistoryIndex + delta
+	if index < 0 || len(m.PromptHistory) == 0 {
+		return true
+	}
+
+	if index >= len(m.PromptHistory) {
+		m.HistoryIndex = len(m.PromptHistory)
+		m.Input.Reset()
+		m.ResizeInput()
+		return true
+	}
+
+	m.HistoryIndex = index
+	m.Input.SetValue(m.PromptHistory[index])
+	m.Input.CursorEnd()
+	m.ResizeInput()
+	return true
+}
+
+// HistoryMatches returns the history entries fuzzily matching the current
+// search query, most recent first
+func (m Model) HistoryMatches() []string {
+	recent := make([]string, len(m.PromptHistory))
+	for i, prompt := range m.PromptHistory {
+		recent[len(m.PromptHistory)-1-i] = prompt
+	}
+
+	if m.HistoryQuery == "" {
+		if len(recent) > maxHistoryMatches {
+			recent = recent[:maxHistoryMatches]
+		}
+		return recent
+	}
+
+	var results []string
+	for _, match := range fuzzy.Find(m.HistoryQuery, recent) {
+		results = append(results, match.Str)
+		if len(results) == maxHistoryMatches {
+			break
+		}
+	}
+	return results
+}
+
+// UpdateHistorySearch handles key presses while the Ctrl+R search is open
+func (m Model) UpdateHistorySearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	matches := m.HistoryMatches()
+
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.HistorySearching = false
+	case tea.KeyEnter, tea.KeyTab:
+		if len(matches) > 0 {
+			m.Input.SetValue(matches[m.HistorySearchIndex%len(matches)])
+			m.Input.CursorEnd()
+			m.ResizeInput()
+		}
+		m.HistorySearching = false
+	case tea.KeyUp, tea.KeyCtrlR:
+		if len(matches) > 0 {
+			m.HistorySearchIndex = (m.HistorySearchIndex + 1) % len(matches)
+		}
+	case tea.KeyDown:
+		if len(matches) > 0 {
+			m.HistorySearchIndex = (m.HistorySearchIndex + len(matches) - 1) % len(matches)
+		}
+	case tea.KeyBackspace:
+		if m.HistoryQuery != "" {
+			runes := []rune(m.HistoryQuery)
+			m.HistoryQuery = string(runes[:len(runes)-1])
+			m.HistorySearchIndex = 0
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.HistoryQuery += string(msg.Runes)
+		m.HistorySearchIndex = 0
+	}
+
+	return m, nil
+}
+
+// HistorySearchView renders the Ctrl+R history search popup
+func (m Model) HistorySearchView(width int) string {
+	matches := m.HistoryMatches()
+	lines := []string{fmt.Sprintf("history search: %s_", m.HistoryQuery)}
+
+	if len(matches) == 0 {
+		lines = append(lines, "  no matches")
+	}
+	for i, prompt := range matches {
+		line := strings.ReplaceAll(prompt, "\n", " ")
+		if width > 10 && len(line) > width-6 {
+			line = line[:width-9] + "..."
+		}
+		if i == m.HistorySearchIndex%len(matches) {
+			line = SlashSelectedStyle.Render("> " + line)
+		} else {
+			line = "  " + line
+		}
+		lines = append(lines, line)
+	}
+
+	return SlashPopupStyle.Copy().Width(width).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
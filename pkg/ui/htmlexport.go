@@ -0,0 +1,274 @@
+package ui
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/session"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// htmlSidebarLength is how much of a message its sidebar entry shows
+const htmlSidebarLength = 60
+
+// htmlTheme styles exported pages in the colors of the TUI, dark or light
+// as the browser prefers
+const htmlTheme = `
+:root { --bg: #1c1c1c; --fg: #d0d0d0; --muted: #767676; --border: #444444; --prompt: #262626;
+  --user: #5FAFFF; --assistant: #FF5F87; --mark: #FFD75F; --str: #87D787; --num: #D787FF; }
+@media (prefers-color-scheme: light) {
+  :root { --bg: #ffffff; --fg: #1c1c1c; --muted: #6c6c6c; --border: #d0d0d0; --prompt: #EEEEEE;
+    --user: #0069c2; --assistant: #d7005f; --mark: #ffd75f; --str: #2e7d32; --num: #8e24aa; }
+}
+* { box-sizing: border-box; }
+body { margin: 0; background: var(--bg); color: var(--fg); font: 15px/1.55 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; }
+a { color: var(--user); }
+nav { position: fixed; top: 0; bottom: 0; left: 0; width: 280px; overflow-y: auto; padding: 16px; border-right: 1px solid var(--border); font-size: 13px; }
+nav h1 { color: var(--assistant); font-size: 16px; margin: 0 0 12px; }
+nav h2 { font-size: 14px; margin: 16px 0 4px; }
+nav h2 a { color: var(--fg); text-decoration: none; }
+nav ol { list-style: none; margin: 0; padding: 0; }
+nav li a { display: block; padding: 2px 6px; border-radius: 4px; color: var(--muted); text-decoration: none; white-space: nowrap; overflow: hidden; text-overflow: ellipsis; }
+nav li a:hover { background: var(--prompt); color: var(--fg); }
+nav .user { color: var(--user); font-weight: bold; }
+nav .assistant { color: var(--assistant); font-weight: bold; }
+main { margin-left: 280px; padding: 24px 40px; max-width: 1000px; }
+section + section { margin-top: 48px; border-top: 1px solid var(--border); }
+section > header h1 { color: var(--assistant); font-size: 22px; margin: 24px 0 4px; }
+.meta, .metadata, footer { color: var(--muted); font-size: 13px; }
+article { margin: 20px 0; scroll-margin-top: 16px; }
+article:target > .header { background: var(--mark); color: #000000; }
+.header { font-weight: bold; padding: 0 4px; }
+.header .time { font-weight: normal; color: var(--muted); margin-left: 8px; }
+.header .anchor { visibility: hidden; margin-left: 6px; text-decoration: none; }
+article:hover .anchor { visibility: visible; }
+.user > .header { color: var(--user); }
+.assistant > .header { color: var(--assistant); }
+.user > .content { background: var(--prompt); padding: 4px 12px; border-radius: 4px; }
+.assistant > .content { padding-left: 16px; }
+.cut-off { color: var(--mark); font-weight: normal; margin-left: 8px; }
+.attachments { color: var(--muted); font-size: 13px; padding-left: 4px; }
+details { color: var(--muted); font-style: italic; margin: 8px 0 8px 16px; }
+pre { background: var(--prompt); padding: 10px 12px; border-radius: 4px; overflow-x: auto; }
+code { font: 13px/1.45 "SF Mono", Menlo, Consolas, monospace; }
+:not(pre) > code { background: var(--prompt); padding: 1px 4px; border-radius: 3px; }
+blockquote { margin: 8px 0; padding-left: 12px; border-left: 3px solid var(--border); color: var(--muted); }
+table { border-collapse: collapse; }
+th, td { border: 1px solid var(--border); padding: 4px 10px; }
+.kw { color: var(--user); font-weight: bold; }
+.str { color: var(--str); }
+.num { color: var(--num); }
+.com { color: var(--muted); font-style: italic; }
+footer { margin-top: 48px; }
+@media (max-width: 800px) { nav { display: none; } main { margin-left: 0; padding: 16px; } }
+@media print { nav { display: none; } main { margin-left: 0; } }
+`
+
+// ExportHTML writes the current conversation to path as a standalone HTML
+// page
+func (m Model) ExportHTML(path string) error {
+	page := m.HTMLTranscript(fmt.Sprintf("Chat with %s", m.SelectedModel), []session.Session{m.exportedSession()})
+	if err := os.WriteFile(path, []byte(page), 0644); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// ExportArchive writes every saved session to path as one HTML page, most
+// recent first, and returns how many there were
+func (m Model) ExportArchive(path string) (int, error) {
+	store, err := session.DefaultStore()
+	if err != nil {
+		return 0, err
+	}
+	sessions, err := store.List()
+	if err != nil {
+		return 0, err
+	}
+	if len(sessions) == 0 {
+		return 0, fmt.Errorf("no saved sessions")
+	}
+	page := m.HTMLTranscript(fmt.Sprintf("ollama-tui sessions (%d)", len(sessions)), sessions)
+	if err := os.WriteFile(path, []byte(page), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return len(sessions), nil
+}
+
+// exportedSession returns the conversation on screen as a session, named
+// after its model when it has no title yet
+func (m Model) exportedSession() session.Session {
+	s := m.Session
+	s.Messages = m.Messages
+	if s.Title == "" {
+		s.Title = "Chat with " + m.SelectedModel
+	}
+	if s.Provider == "" {
+		s.Provider, s.Model = m.SelectedProvider, m.SelectedModel
+	}
+	return s
+}
+
+// HTMLTranscript renders sessions as a standalone HTML page, with a sidebar
+// of their messages linking to each one
+func (m Model) HTMLTranscript(title string, sessions []session.Session) string {
+	var nav, main strings.Builder
+	for i, s := range sessions {
+		id := fmt.Sprintf("s%d", i+1)
+		fmt.Fprintf(&nav, "<h2><a href=\"#%s\">%s</a></h2>\n<ol>\n", id, html.EscapeString(s.Title))
+		fmt.Fprintf(&main, "<section id=\"%s\">\n<header><h1>%s</h1><p class=\"meta\">%s</p></header>\n", id, html.EscapeString(s.Title), html.EscapeString(sessionSummary(s)))
+		for j, msg := range s.Messages {
+			if msg.Role != models.RoleUser && msg.Role != models.RoleAssistant {
+				continue
+			}
+			anchor := fmt.Sprintf("%s-m%d", id, j+1)
+			who := "You"
+			if msg.Role == models.RoleAssistant {
+				who = msg.Model
+				if who == "" {
+					who = s.Model
+				}
+			}
+			fmt.Fprintf(&nav, "<li><a href=\"#%s\"><span class=\"%s\">%s</span> %s</a></li>\n",
+				anchor, msg.Role, html.EscapeString(who), html.EscapeString(truncate(strings.Join(strings.Fields(msg.Answer()), " "), htmlSidebarLength)))
+			main.WriteString(m.htmlMessage(msg, anchor, who))
+		}
+		nav.WriteString("</ol>\n")
+		main.WriteString("</section>\n")
+	}
+
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="generator" content="ollama-tui %s">
+<title>%s</title>
+<style>%s</style>
+</head>
+<body>
+<nav>
+<h1>%s</h1>
+%s</nav>
+<main>
+%s<footer>Exported from ollama-tui on %s</footer>
+</main>
+</body>
+</html>
+`, html.EscapeString(utils.GetBuildInfo().Version), html.EscapeString(title), htmlTheme, html.EscapeString(title),
+		nav.String(), main.String(), time.Now().Format("2006-01-02 15:04"))
+}
+
+// sessionSummary describes a session under its title: provider, model,
+// dates and how many messages
+func sessionSummary(s session.Session) string {
+	var parts []string
+	if s.Provider != "" {
+		parts = append(parts, s.Provider)
+	}
+	if s.Model != "" {
+		parts = append(parts, s.Model)
+	}
+	if !s.CreatedAt.IsZero() {
+		parts = append(parts, "started "+s.CreatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	if !s.UpdatedAt.IsZero() {
+		parts = append(parts, "updated "+s.UpdatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	count := 0
+	for _, msg := range s.Messages {
+		if msg.Role == models.RoleUser || msg.Role == models.RoleAssistant {
+			count++
+		}
+	}
+	parts = append(parts, fmt.Sprintf("%d messages", count))
+	return strings.Join(parts, " · ")
+}
+
+// htmlMessage renders a message with its header, attachments, reasoning
+// and metadata
+func (m Model) htmlMessage(msg models.Message, anchor, who string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<article class=\"%s\" id=\"%s\">\n<div class=\"header\">%s", msg.Role, anchor, html.EscapeString(who))
+	if timing := strings.TrimPrefix(exportedTiming(msg), " · "); timing != "" {
+		fmt.Fprintf(&sb, "<span class=\"time\">%s</span>", html.EscapeString(timing))
+	}
+	if label := cutOffLabel(msg.CutOff); label != "" {
+		fmt.Fprintf(&sb, "<span class=\"cut-off\">%s</span>", label)
+	}
+	fmt.Fprintf(&sb, "<a class=\"anchor\" href=\"#%s\">#</a></div>\n", anchor)
+	if len(msg.Attachments) > 0 {
+		fmt.Fprintf(&sb, "<div class=\"attachments\">Attached: %s</div>\n", html.EscapeString(strings.Join(msg.Attachments, ", ")))
+	}
+
+	if msg.Role == models.RoleUser {
+		fmt.Fprintf(&sb, "<div class=\"content\">%s</div>\n", utils.MarkdownHTML(msg.Content))
+	} else {
+		reasoning, answer, _ := models.SplitReasoning(msg.Content)
+		if reasoning != "" && !m.HideReasoning {
+			fmt.Fprintf(&sb, "<details><summary>Reasoning</summary>\n%s</details>\n", utils.MarkdownHTML(reasoning))
+		}
+		fmt.Fprintf(&sb, "<div class=\"content\">%s</div>\n", utils.MarkdownHTML(answer))
+		if msg.Metadata != nil {
+			fmt.Fprintf(&sb, "<div class=\"metadata\">%s</div>\n", html.EscapeString(FormatMetadata(*msg.Metadata)))
+		}
+	}
+	sb.WriteString("</article>\n")
+	return sb.String()
+}
+
+// isHTMLPath reports whether an export path asks for an HTML page
+func isHTMLPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".html" || ext == ".htm"
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "archive",
+		Usage:       "[path|json [dir]]",
+		Description: "Export every saved session to one HTML page with a sidebar of their messages, or as JSON files",
+		Run: func(m *Model, args string) tea.Cmd {
+			if format, dir, _ := strings.Cut(args, " "); format == "json" {
+				return m.archiveJSON(strings.TrimSpace(dir))
+			}
+			path := args
+			if path == "" {
+				path = fmt.Sprintf("ollama-tui-sessions-%s.html", time.Now().Format("20060102-150405"))
+			}
+			n, err := m.ExportArchive(path)
+			if err != nil {
+				m.StatusMessage = fmt.Sprintf("Archive failed: %v", err)
+				return nil
+			}
+			m.StatusMessage = fmt.Sprintf("Exported %d sessions to %s", n, path)
+			return nil
+		},
+	})
+}
+
+// archiveJSON writes every saved session to dir as JSON files
+func (m *Model) archiveJSON(dir string) tea.Cmd {
+	if dir == "" {
+		dir = fmt.Sprintf("ollama-tui-sessions-%s", time.Now().Format("20060102-150405"))
+	}
+	store, err := session.DefaultStore()
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Archive failed: %v", err)
+		return nil
+	}
+	n, err := store.ExportJSON(dir)
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Archive failed: %v", err)
+		return nil
+	}
+	m.StatusMessage = fmt.Sprintf("Exported %d sessions as JSON to %s", n, dir)
+	return nil
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// TextAttachments returns the attachments whose contents go into the prompt
+// text, leaving out images
+func TextAttachments(attachments []models.Attachment) []models.Attachment {
+	var text []models.Attachment
+	for _, a := range attachments {
+		if a.Image == nil {
+			text = append(text, a)
+		}
+	}
+	return text
+}
+
+// AttachmentImages returns the attached images base64-encoded, as the APIs
+// expect them
+func AttachmentImages(attachments []models.Attachment) []string {
+	var images []string
+	for _, a := range attachments {
+		if a.Image != nil {
+			images = append(images, base64.StdEncoding.EncodeToString(a.Image))
+		}
+	}
+	return images
+}
+
+// ReadClipboardImageCmd reads the image on the system clipboard
+func ReadClipboardImageCmd() tea.Cmd {
+	return func() tea.Msg {
+		data, err := utils.ReadClipboardImage()
+		return ClipboardImageMsg{Data: data, Err: err}
+	}
+}
+
+// HandleClipboardImage saves the image read from the clipboard and attaches
+// it to the next prompt
+func (m *Model) HandleClipboardImage(msg ClipboardImageMsg) {
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("Cannot paste an image: %v", msg.Err)
+		return
+	}
+	path, err := utils.SaveClipboardImage(msg.Data)
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Cannot save the pasted image: %v", err)
+		return
+	}
+
+	name := filepath.Base(path)
+	m.PendingAttachments = append(m.PendingAttachments, models.Attachment{Name: name, Source: path, Image: msg.Data})
+	m.StatusMessage = fmt.Sprintf("Attached %s (%d KB, %d pending)", name, len(msg.Data)/1024, len(m.PendingAttachments))
+}
+
+// AttachPastedImage attaches the image file named by pasted text, which is
+// what terminals paste when a file is dropped onto them. It reports whether
+// the text was an image path.
+func (m *Model) AttachPastedImage(text string) bool {
+	path := utils.PastedPath(text)
+	if path == "" || !utils.IsImagePath(path) {
+		return false
+	}
+	if info, err := os.Stat(utils.ExpandHome(path)); err != nil || info.IsDir() {
+		return false
+	}
+
+	if hasAttachment(m.PendingAttachments, path) {
+		m.StatusMessage = fmt.Sprintf("%s is already attached", path)
+		return true
+	}
+	attachment, err := utils.LoadAttachment(path)
+	if err != nil {
+		m.StatusMessage = err.Error()
+		return true
+	}
+	m.PendingAttachments = append(m.PendingAttachments, attachment)
+	m.StatusMessage = fmt.Sprintf("Attached %s (%d pending)", attachment.Name, len(m.PendingAttachments))
+	return true
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "paste-image",
+		Description: "Attach the image on the clipboard to the next prompt for vision models",
+		Run: func(m *Model, args string) tea.Cmd {
+			m.StatusMessage = "Reading the clipboard…"
+			return ReadClipboardImageCmd()
+		},
+	})
+}
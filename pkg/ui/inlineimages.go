@@ -0,0 +1,256 @@
+package ui
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+const (
+	// MaxImageRows is the tallest an image is drawn in the transcript
+	MaxImageRows = 14
+	// maxImageCols is the widest an image is drawn in the transcript
+	maxImageCols = 60
+)
+
+// markdownImagePattern matches ![alt](path "title") in a response
+var markdownImagePattern = regexp.MustCompile(`!\[([^\]]*)\]\(<?([^)\s>]+)>?(?:\s+"[^"]*")?\)`)
+
+// ImageRef is an image shown under a message: an attached image, or one a
+// response links to with Markdown image syntax
+type ImageRef struct {
+	Source string
+	Alt    string
+}
+
+// ImagePlacement is an image drawn in the transcript, at a line offset of
+// the rendered transcript
+type ImagePlacement struct {
+	Path string
+	Line int
+	Cols int
+	Rows int
+}
+
+// imageInfo is what is known about an image file for drawing it
+type imageInfo struct {
+	width, height int
+	format        string
+	err           error
+}
+
+// inlineImages caches image sizes and the escape sequences that draw them,
+// since the transcript is re-rendered for every streamed token
+var inlineImages = struct {
+	sync.Mutex
+	info      map[string]imageInfo
+	sequences map[string]string
+}{info: map[string]imageInfo{}, sequences: map[string]string{}}
+
+// MessageImages returns the images shown under a message
+func MessageImages(msg models.Message) []ImageRef {
+	var refs []ImageRef
+	switch msg.Role {
+	case models.RoleUser:
+		for _, source := range msg.Attachments {
+			if utils.IsImagePath(source) {
+				refs = append(refs, ImageRef{Source: source, Alt: filepath.Base(source)})
+			}
+		}
+	case models.RoleAssistant:
+		for _, m := range markdownImagePattern.FindAllStringSubmatch(msg.Answer(), -1) {
+			source := m[2]
+			if strings.HasPrefix(source, "file://") {
+				source = utils.PastedPath(source)
+			}
+			alt := m[1]
+			if alt == "" {
+				alt = filepath.Base(source)
+			}
+			refs = append(refs, ImageRef{Source: source, Alt: alt})
+		}
+	}
+	return refs
+}
+
+// lookupImage returns the size of a local image file, cached until the
+// file changes. Formats that cannot be decoded, such as WebP, have no size.
+func lookupImage(path string) (imageInfo, string) {
+	stat, err := os.Stat(utils.ExpandHome(path))
+	if err != nil {
+		return imageInfo{err: err}, ""
+	}
+	key := fmt.Sprintf("%s@%d", path, stat.ModTime().UnixNano())
+
+	inlineImages.Lock()
+	defer inlineImages.Unlock()
+	info, ok := inlineImages.info[key]
+	if !ok {
+		config, format, err := utils.ImageFileSize(path)
+		if err != nil {
+			format = strings.TrimPrefix(filepath.Ext(path), ".")
+		}
+		info = imageInfo{width: config.Width, height: config.Height, format: format}
+		inlineImages.info[key] = info
+	}
+	return info, key
+}
+
+// renderImages renders the images of a message: a box the image is drawn
+// over when the terminal supports it, or a one-line placeholder. It returns
+// the placements with line offsets relative to the rendered text.
+func (m Model) renderImages(msg models.Message, width int) (string, []ImagePlacement) {
+	refs := MessageImages(msg)
+	if len(refs) == 0 {
+		return "", nil
+	}
+
+	openHint := fmt.Sprintf("%s to open", m.KeyMap.Help(ActionOpenImage))
+	var lines []string
+	var placements []ImagePlacement
+	for _, ref := range refs {
+		if utils.IsURL(ref.Source) {
+			lines = append(lines, CollapsedStyle.Render(fmt.Sprintf("[image: %s · %s · %s]", ref.Alt, ref.Source, openHint)))
+			continue
+		}
+		info, _ := lookupImage(ref.Source)
+		if info.err != nil {
+			lines = append(lines, CollapsedStyle.Render(fmt.Sprintf("[image: %s · not found]", ref.Alt)))
+			continue
+		}
+
+		size := strings.ToUpper(info.format)
+		if info.width > 0 {
+			size = fmt.Sprintf("%dx%d %s", info.width, info.height, size)
+		}
+		label := fmt.Sprintf("[image: %s · %s · %s]", ref.Alt, size, openHint)
+		cols, rows := utils.ImageCells(info.width, info.height, min(maxImageCols, width), MaxImageRows)
+		if m.ImageProtocol == utils.ImageNone || cols == 0 {
+			lines = append(lines, CollapsedStyle.Render(label))
+			continue
+		}
+
+		// The label stays visible while the image is scrolled partly out of
+		// view and cannot be drawn
+		placements = append(placements, ImagePlacement{Path: ref.Source, Line: len(lines), Cols: cols, Rows: rows})
+		lines = append(lines, CollapsedStyle.Render(truncate(label, max(cols, 20))))
+		for i := 1; i < rows; i++ {
+			lines = append(lines, "")
+		}
+	}
+	return strings.Join(lines, "\n"), placements
+}
+
+// ImageOverlay returns the escape sequences that draw the transcript's
+// images which are entirely in view. top and left are the screen position of
+// the viewport's first line. The cursor is restored after each image, so the
+// sequences can follow the rest of the view.
+func (m Model) ImageOverlay(top, left int) string {
+	if m.ImageProtocol == utils.ImageNone {
+		return ""
+	}
+
+	var sb strings.Builder
+	// Kitty keeps images above the text until they are deleted
+	if m.ImageProtocol == utils.ImageKitty {
+		sb.WriteString(utils.KittyDeleteImages)
+	}
+	if len(m.Comparison) > 0 {
+		return sb.String()
+	}
+
+	for _, p := range m.ImagePlacements {
+		if p.Line < m.Viewport.YOffset || p.Line+p.Rows > m.Viewport.YOffset+m.Viewport.Height {
+			continue
+		}
+		sequence := m.imageSequence(p)
+		if sequence == "" {
+			continue
+		}
+		sb.WriteString("\x1b7")
+		fmt.Fprintf(&sb, "\x1b[%d;%dH", top+p.Line-m.Viewport.YOffset+1, left+1)
+		sb.WriteString(sequence)
+		sb.WriteString("\x1b8")
+	}
+	return sb.String()
+}
+
+// frameOffset returns how far a style's margin, border and padding move its
+// content down and to the right
+func frameOffset(style lipgloss.Style) (top, left int) {
+	top = style.GetMarginTop() + style.GetBorderTopSize() + style.GetPaddingTop()
+	left = style.GetMarginLeft() + style.GetBorderLeftSize() + style.GetPaddingLeft()
+	return top, left
+}
+
+// imageSequence returns the cached escape sequence drawing an image
+func (m Model) imageSequence(p ImagePlacement) string {
+	_, key := lookupImage(p.Path)
+	if key == "" {
+		return ""
+	}
+	key = fmt.Sprintf("%s:%s:%dx%d", key, m.ImageProtocol, p.Cols, p.Rows)
+
+	inlineImages.Lock()
+	sequence, ok := inlineImages.sequences[key]
+	inlineImages.Unlock()
+	if ok {
+		return sequence
+	}
+
+	if img, err := utils.DecodeImageFile(p.Path); err == nil {
+		id := fnv.New32a()
+		id.Write([]byte(p.Path))
+		sequence = utils.ImageSequence(m.ImageProtocol, img, p.Cols, p.Rows, id.Sum32()|1)
+	}
+	inlineImages.Lock()
+	inlineImages.sequences[key] = sequence
+	inlineImages.Unlock()
+	return sequence
+}
+
+// OpenImages opens the images of the selected message, or of the latest
+// message with images, in the system's image viewer
+func (m *Model) OpenImages() {
+	index := m.SelectedMessage
+	if index < 0 {
+		for i := len(m.Messages) - 1; i >= 0; i-- {
+			if len(MessageImages(m.Messages[i])) > 0 {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 || index >= len(m.Messages) {
+		m.StatusMessage = "No images in the conversation"
+		return
+	}
+	refs := MessageImages(m.Messages[index])
+	if len(refs) == 0 {
+		m.StatusMessage = "The selected message has no images"
+		return
+	}
+
+	for _, ref := range refs {
+		source := ref.Source
+		if !utils.IsURL(source) {
+			if abs, err := filepath.Abs(utils.ExpandHome(source)); err == nil {
+				source = abs
+			}
+		}
+		if err := utils.OpenURL(source); err != nil {
+			m.StatusMessage = fmt.Sprintf("Cannot open %s: %v", ref.Alt, err)
+			return
+		}
+	}
+	m.StatusMessage = fmt.Sprintf("Opened %d image(s)", len(refs))
+}
@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// OpenInspector shows the requests of the latest response and what the
+// provider streamed back
+func (m *Model) OpenInspector() tea.Cmd {
+	if m.State != StateInspector {
+		m.InspectorReturnState = m.State
+	}
+	m.State = StateInspector
+	m.UpdateInspectorView()
+	m.InspectorViewport.GotoTop()
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// UpdateInspector handles keys on the inspector
+func (m Model) UpdateInspector(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// The key that opened the inspector closes it too
+	if action, ok := m.KeyMap.Lookup(m.InspectorReturnState, msg.String()); ok && action == ActionInspector {
+		m.State = m.InspectorReturnState
+		return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	}
+	switch msg.String() {
+	case "esc", "q":
+		m.State = m.InspectorReturnState
+		return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	case "ctrl+c":
+		return m, m.RequestQuit()
+	case "p":
+		m.InspectorRaw = !m.InspectorRaw
+		m.UpdateInspectorView()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.InspectorViewport, cmd = m.InspectorViewport.Update(msg)
+	return m, cmd
+}
+
+// UpdateInspectorView renders the exchanges of the latest response into the
+// inspector viewport
+func (m *Model) UpdateInspectorView() {
+	width := m.InspectorViewport.Width
+	if len(m.Exchanges) == 0 {
+		m.InspectorViewport.SetContent(lipgloss.NewStyle().Width(width).Render(
+			"No request yet. The requests of the latest response and the raw chunks streamed back show up here. " +
+				"The mock provider and plugins don't go over HTTP, so they have nothing to show."))
+		return
+	}
+
+	var sb strings.Builder
+	for i, exchange := range m.Exchanges {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		status := exchange.Status
+		if exchange.Err != "" {
+			status = "failed: " + exchange.Err
+		}
+		header := fmt.Sprintf("%s %s · %s · sent %s", exchange.Method, exchange.URL, status, exchange.SentAt.Local().Format("15:04:05"))
+		if len(m.Exchanges) > 1 {
+			header = fmt.Sprintf("Request %d of %d · %s", i+1, len(m.Exchanges), header)
+		}
+		sb.WriteString(SelectedHeaderStyle.Render(header) + "\n\n")
+
+		if len(exchange.Headers) > 0 {
+			sb.WriteString(CollapsedStyle.Render("Request headers") + "\n")
+			for _, name := range api.SortedHeaderNames(exchange.Headers) {
+				sb.WriteString(name + ": " + exchange.Headers[name] + "\n")
+			}
+			sb.WriteString("\n")
+		}
+
+		sb.WriteString(CollapsedStyle.Render(fmt.Sprintf("Request body (%s)", utils.FormatBytes(int64(len(exchange.RequestBody))))) + "\n")
+		sb.WriteString(m.inspectedBody(exchange.RequestBody) + "\n\n")
+
+		sb.WriteString(CollapsedStyle.Render(inspectedResponseSummary(exchange)) + "\n")
+		for _, chunk := range exchange.Chunks {
+			offset := fmt.Sprintf("+%6.2fs ", chunk.After.Seconds())
+			lines := strings.Split(strings.TrimRight(chunk.Data, "\n"), "\n")
+			for j, line := range lines {
+				if j > 0 {
+					offset = strings.Repeat(" ", len(offset))
+				}
+				sb.WriteString(CollapsedStyle.Render(offset) + line + "\n")
+			}
+		}
+		if exchange.Truncated {
+			sb.WriteString(CollapsedStyle.Render("… the rest of the body was left out") + "\n")
+		}
+	}
+	m.InspectorViewport.SetContent(lipgloss.NewStyle().Width(width).Render(strings.TrimRight(sb.String(), "\n")))
+}
+
+// inspectedBody returns a request body indented when it is JSON, unless the
+// inspector shows it raw
+func (m Model) inspectedBody(body string) string {
+	if body == "" {
+		return "(empty)"
+	}
+	var indented bytes.Buffer
+	if m.InspectorRaw || json.Indent(&indented, []byte(body), "", "  ") != nil {
+		return body
+	}
+	return indented.String()
+}
+
+// inspectedResponseSummary describes what came back for an exchange: how
+// many chunks and bytes, and when they started and stopped arriving
+func inspectedResponseSummary(exchange api.Exchange) string {
+	if len(exchange.Chunks) == 0 {
+		return "Response: nothing received"
+	}
+	size := 0
+	for _, chunk := range exchange.Chunks {
+		size += len(chunk.Data)
+	}
+	first := exchange.Chunks[0].After
+	last := exchange.Chunks[len(exchange.Chunks)-1].After
+	chunks := fmt.Sprintf("%d chunks", len(exchange.Chunks))
+	if len(exchange.Chunks) == 1 {
+		chunks = "1 chunk"
+	}
+	return fmt.Sprintf("Response: %s, %s, first after %s, last after %s",
+		chunks, utils.FormatBytes(int64(size)), first.Round(time.Millisecond), last.Round(time.Millisecond))
+}
+
+// InspectorView renders the inspector
+func (m Model) InspectorView() string {
+	title := "Inspector · latest response"
+	if m.IsGenerating {
+		title += " (before the one streaming)"
+	}
+	format := "raw"
+	if m.InspectorRaw {
+		format = "indented"
+	}
+	help := fmt.Sprintf("p: %s request body | ↑/↓: scroll | Esc: back", format)
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		TitleStyle.Render(title),
+		lipgloss.NewStyle().Padding(0, 2).Render(m.InspectorViewport.View()),
+		HelpStyle.Render(help),
+	)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "inspect",
+		Description: "Show the exact requests of the latest response and the raw chunks streamed back",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenInspector()
+		},
+	})
+}
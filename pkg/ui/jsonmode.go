@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// ParseJSONFormat turns /json arguments into a response format: "on" for
+// any JSON, or a JSON Schema given inline or as a file path
+func ParseJSONFormat(args string) (json.RawMessage, error) {
+	if args == "on" || args == "json" {
+		return json.RawMessage(`"json"`), nil
+	}
+
+	schema := args
+	if !strings.HasPrefix(schema, "{") {
+		attachment, err := utils.LoadAttachment(args)
+		if err != nil {
+			return nil, err
+		}
+		schema = attachment.Content
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(schema)); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(compact.Bytes()), nil
+}
+
+// RenderJSONResponse renders a response requested in JSON mode, wrapped to
+// width. Valid JSON is pretty-printed; once the response is complete, values
+// that break the schema are highlighted and a note says whether it is valid.
+func RenderJSONResponse(content string, format json.RawMessage, complete bool, width int) string {
+	// Some models still wrap their answer in a Markdown code fence
+	text := strings.TrimSpace(content)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(strings.TrimPrefix(text, "```json"), "```")
+		text = strings.TrimSpace(strings.TrimSuffix(text, "```"))
+	}
+
+	lines, err := utils.IndentJSON([]byte(text))
+	if err != nil {
+		rendered := utils.WrapText(content, width)
+		if complete {
+			rendered += "\n" + JSONViolationStyle.Render(fmt.Sprintf("⚠ invalid JSON: %v", err))
+		}
+		return rendered
+	}
+
+	var violations []utils.SchemaViolation
+	if complete && !api.IsJSONFormat(format) {
+		var value interface{}
+		json.Unmarshal([]byte(text), &value)
+		violations, _ = utils.ValidateJSON(value, format)
+	}
+	byPath := map[string][]string{}
+	for _, v := range violations {
+		byPath[v.Path] = append(byPath[v.Path], v.Message)
+	}
+
+	var out []string
+	for _, line := range lines {
+		wrapped := utils.WrapText(line.Text, width)
+		messages, violated := byPath[line.Path]
+		if !violated {
+			out = append(out, wrapped)
+			continue
+		}
+		// Mark only the line where the value starts, not its closing bracket
+		delete(byPath, line.Path)
+		for _, l := range strings.Split(wrapped, "\n") {
+			out = append(out, JSONViolationStyle.Render(l))
+		}
+		indent := line.Text[:len(line.Text)-len(strings.TrimLeft(line.Text, " "))]
+		for _, msg := range messages {
+			out = append(out, JSONViolationStyle.Render(indent+"  ⚠ "+msg))
+		}
+	}
+
+	if !complete {
+		return strings.Join(out, "\n")
+	}
+	status := "✓ valid JSON"
+	switch {
+	case len(violations) == 1:
+		status = "⚠ 1 schema violation"
+	case len(violations) > 1:
+		status = fmt.Sprintf("⚠ %d schema violations", len(violations))
+	case !api.IsJSONFormat(format):
+		status = "✓ valid JSON, matches the schema"
+	}
+	return strings.Join(out, "\n") + "\n" + CollapsedStyle.Render(status)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "json",
+		Usage:       "[on|off|schema]",
+		Description: "Toggle JSON mode, or require responses to follow a JSON Schema (inline or a file)",
+		Run: func(m *Model, args string) tea.Cmd {
+			switch {
+			case args == "off" || (args == "" && m.JSONFormat != nil):
+				m.JSONFormat = nil
+				m.StatusMessage = "JSON mode off"
+				return nil
+			case args == "":
+				args = "on"
+			}
+
+			format, err := ParseJSONFormat(args)
+			if err != nil {
+				m.StatusMessage = err.Error()
+				return nil
+			}
+			m.JSONFormat = format
+			if api.IsJSONFormat(format) {
+				m.StatusMessage = "JSON mode on: responses must be valid JSON"
+			} else {
+				m.StatusMessage = "JSON mode on: responses must follow the schema"
+			}
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "schema",
+		Usage:       "<file>|off",
+		Description: "Constrain the next response to a JSON Schema file and check it against the schema",
+		Run: func(m *Model, args string) tea.Cmd {
+			switch args {
+			case "":
+				m.StatusMessage = "Usage: /schema <file>|off"
+				return nil
+			case "off":
+				m.PendingSchema = nil
+				m.StatusMessage = "Schema removed"
+				return nil
+			}
+
+			schema, err := ParseJSONFormat(args)
+			if err != nil {
+				m.StatusMessage = err.Error()
+				return nil
+			}
+			if api.IsJSONFormat(schema) {
+				m.StatusMessage = "Use /json to turn on JSON mode without a schema"
+				return nil
+			}
+			m.PendingSchema = schema
+			m.StatusMessage = fmt.Sprintf("Schema %s will apply to the next prompt", args)
+			return nil
+		},
+	})
+}
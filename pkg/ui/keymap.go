@@ -0,0 +1,361 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Action identifies something the user can trigger from the keyboard
+type Action string
+
+const (
+	// ActionQuit cancels generation, steps back or exits the application
+	ActionQuit Action = "quit"
+	// ActionToggleFocus switches focus between the chat history and the input box
+	ActionToggleFocus Action = "toggle_focus"
+	// ActionNewChat clears the conversation context
+	ActionNewChat Action = "new_chat"
+	// ActionSubmit selects a list item or sends a prompt
+	ActionSubmit Action = "submit"
+	// ActionHistorySearch opens fuzzy search over previously submitted prompts
+	ActionHistorySearch Action = "history_search"
+	// ActionExternalEditor opens the input contents in $EDITOR
+	ActionExternalEditor Action = "external_editor"
+	// ActionSend sends the prompt in multi-line mode, or inserts a newline otherwise
+	ActionSend Action = "send"
+	// ActionPrevMessage jumps to the previous message in the focused chat history
+	ActionPrevMessage Action = "prev_message"
+	// ActionNextMessage jumps to the next message in the focused chat history
+	ActionNextMessage Action = "next_message"
+	// ActionToggleCollapse collapses or expands the selected message
+	ActionToggleCollapse Action = "toggle_collapse"
+	// ActionUnloadModel unloads the highlighted model from memory in the model list
+	ActionUnloadModel Action = "unload_model"
+	// ActionCreateModel derives a new model from the highlighted one in the model list
+	ActionCreateModel Action = "create_model"
+	// ActionCopyModel duplicates the highlighted model under a new name
+	ActionCopyModel Action = "copy_model"
+	// ActionRenameModel renames the highlighted model
+	ActionRenameModel Action = "rename_model"
+	// ActionSortModels cycles the model list order (name, size, family, recent)
+	ActionSortModels Action = "sort_models"
+	// ActionGroupModels toggles family headers in the model list
+	ActionGroupModels Action = "group_models"
+	// ActionBrowseRegistry opens the Ollama library from the model list
+	ActionBrowseRegistry Action = "browse_registry"
+	// ActionFavoriteModel pins or unpins the highlighted model as a favorite
+	ActionFavoriteModel Action = "favorite_model"
+	// ActionSelectModel returns from the chat to the model list
+	ActionSelectModel Action = "select_model"
+	// ActionFork branches the conversation at the selected message
+	ActionFork Action = "fork"
+	// ActionReplay sends the recorded request of the selected or latest
+	// response again
+	ActionReplay Action = "replay"
+	// ActionSaveCode saves a code block from the selected or latest response to a file
+	ActionSaveCode Action = "save_code"
+	// ActionReviewEdits shows the changes a response makes to attached files
+	ActionReviewEdits Action = "review_edits"
+	// ActionOpenImage opens the images of the selected or latest message in the system viewer
+	ActionOpenImage Action = "open_image"
+	// ActionToggleMetadata shows or hides the metadata footer under responses
+	ActionToggleMetadata Action = "toggle_metadata"
+	// ActionToggleReasoning expands or collapses the reasoning of the selected or latest response
+	ActionToggleReasoning Action = "toggle_reasoning"
+	// ActionVoiceInput starts or stops recording a voice prompt
+	ActionVoiceInput Action = "voice_input"
+	// ActionOpenCitation opens source [n] of the selected or latest response
+	// in the browser, n being the digit pressed
+	ActionOpenCitation Action = "open_citation"
+	// ActionDiscover looks for Ollama and OpenAI-compatible servers on the network
+	ActionDiscover Action = "discover"
+	// ActionManageKeys opens the API keys screen
+	ActionManageKeys Action = "manage_keys"
+	// ActionCancelQueued removes the most recently queued prompt
+	ActionCancelQueued Action = "cancel_queued"
+	// ActionNewTab opens a conversation in a new tab
+	ActionNewTab Action = "new_tab"
+	// ActionNextTab switches to the next tab
+	ActionNextTab Action = "next_tab"
+	// ActionPrevTab switches to the previous tab
+	ActionPrevTab Action = "prev_tab"
+	// ActionCloseTab closes the current tab
+	ActionCloseTab Action = "close_tab"
+	// ActionToggleSidePane shows or hides the info pane beside the transcript
+	ActionToggleSidePane Action = "toggle_side_pane"
+	// ActionSelectionMode releases the mouse so the terminal can select text
+	ActionSelectionMode Action = "selection_mode"
+	// ActionInspector shows the raw requests and replies of the latest response
+	ActionInspector Action = "inspector"
+)
+
+// Binding maps an action to its keys and the states where it is active
+type Binding struct {
+	Action Action
+	Keys   []string
+	States []int
+}
+
+// KeyMap holds the active key bindings
+type KeyMap struct {
+	Bindings []Binding
+}
+
+// KeyConflict describes a key binding that clashes with another binding or
+// with a key the terminal is likely to intercept
+type KeyConflict struct {
+	Action     Action
+	Key        string
+	Reason     string
+	Suggestion string
+}
+
+// allStates lists every state a global binding should be active in
+var allStates = []int{StateProviderSelect, StateAPIKeyInput, StateModelSelect, StatePrompting, StateLoading, StateSessionBrowser}
+
+// reservedKeys are keys commonly swallowed by terminals, shells or multiplexers
+var reservedKeys = map[string]string{
+	"ctrl+a":  "GNU screen prefix",
+	"ctrl+b":  "tmux prefix",
+	"ctrl+s":  "XOFF flow control freezes output",
+	"ctrl+q":  "XON flow control",
+	"ctrl+z":  "suspends the process",
+	"ctrl+\\": "sends SIGQUIT",
+	"ctrl+h":  "sent as Backspace by many terminals",
+	"ctrl+i":  "indistinguishable from Tab",
+	"ctrl+m":  "indistinguishable from Enter",
+	"ctrl+[":  "indistinguishable from Esc",
+}
+
+// suggestionKeys are candidate keys offered as alternatives for conflicting bindings
+var suggestionKeys = []string{
+	"ctrl+o", "ctrl+g", "ctrl+t", "ctrl+y", "ctrl+x", "ctrl+l",
+	"alt+n", "alt+o", "alt+t", "f2", "f3", "f4", "f5",
+}
+
+// DefaultKeyMap returns the built-in key bindings
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Bindings: []Binding{
+			{Action: ActionQuit, Keys: []string{"ctrl+c", "esc"}, States: allStates},
+			{Action: ActionToggleFocus, Keys: []string{"tab"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionNewChat, Keys: []string{"ctrl+n"}, States: []int{StatePrompting}},
+			{Action: ActionSubmit, Keys: []string{"enter"}, States: allStates},
+			{Action: ActionHistorySearch, Keys: []string{"ctrl+r"}, States: []int{StatePrompting}},
+			{Action: ActionExternalEditor, Keys: []string{"ctrl+e"}, States: []int{StatePrompting}},
+			{Action: ActionSend, Keys: []string{"alt+enter"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionPrevMessage, Keys: []string{"["}, States: []int{StatePrompting}},
+			{Action: ActionNextMessage, Keys: []string{"]"}, States: []int{StatePrompting}},
+			{Action: ActionToggleCollapse, Keys: []string{"c"}, States: []int{StatePrompting}},
+			{Action: ActionToggleReasoning, Keys: []string{"t"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionToggleMetadata, Keys: []string{"i"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionFork, Keys: []string{"f"}, States: []int{StatePrompting}},
+			{Action: ActionReplay, Keys: []string{"r"}, States: []int{StatePrompting}},
+			{Action: ActionSaveCode, Keys: []string{"w"}, States: []int{StatePrompting}},
+			{Action: ActionReviewEdits, Keys: []string{"d"}, States: []int{StatePrompting}},
+			{Action: ActionOpenImage, Keys: []string{"o"}, States: []int{StatePrompting}},
+			{Action: ActionOpenCitation, Keys: []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}, States: []int{StatePrompting}},
+			{Action: ActionVoiceInput, Keys: []string{"ctrl+t"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionSelectModel, Keys: []string{"ctrl+o"}, States: []int{StatePrompting}},
+			{Action: ActionCancelQueued, Keys: []string{"ctrl+x"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionNewTab, Keys: []string{"alt+t"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionNextTab, Keys: []string{"alt+n", "ctrl+pgdown"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionPrevTab, Keys: []string{"alt+p", "ctrl+pgup"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionCloseTab, Keys: []string{"alt+w"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionToggleSidePane, Keys: []string{"f2"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionSelectionMode, Keys: []string{"alt+s"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionInspector, Keys: []string{"ctrl+d"}, States: []int{StatePrompting, StateLoading}},
+			{Action: ActionUnloadModel, Keys: []string{"u"}, States: []int{StateModelSelect}},
+			{Action: ActionCreateModel, Keys: []string{"m"}, States: []int{StateModelSelect}},
+			{Action: ActionCopyModel, Keys: []string{"c"}, States: []int{StateModelSelect}},
+			{Action: ActionRenameModel, Keys: []string{"r"}, States: []int{StateModelSelect}},
+			{Action: ActionSortModels, Keys: []string{"s"}, States: []int{StateModelSelect}},
+			{Action: ActionGroupModels, Keys: []string{"g"}, States: []int{StateModelSelect}},
+			{Action: ActionFavoriteModel, Keys: []string{"f"}, States: []int{StateModelSelect}},
+			{Action: ActionBrowseRegistry, Keys: []string{"b"}, States: []int{StateModelSelect}},
+			{Action: ActionDiscover, Keys: []string{"d"}, States: []int{StateProviderSelect}},
+			{Action: ActionManageKeys, Keys: []string{"K"}, States: []int{StateProviderSelect}},
+		},
+	}
+}
+
+// WithOverrides returns a copy of the key map with the custom bindings applied.
+// Unknown action names are returned as conflicts so they can be reported.
+func (k KeyMap) WithOverrides(custom map[string][]string) (KeyMap, []KeyConflict) {
+	var unknown []KeyConflict
+
+	result := KeyMap{Bindings: make([]Binding, len(k.Bindings))}
+	copy(result.Bindings, k.Bindings)
+
+	for name, keys := range custom {
+		found := false
+		for i := range result.Bindings {
+			if string(result.Bindings[i].Action) == name {
+				result.Bindings[i].Keys = normalizeKeys(keys)
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, KeyConflict{
+				Action: Action(name),
+				Key:    strings.Join(keys, ","),
+				Reason: "unknown action",
+			})
+		}
+	}
+
+	return result, unknown
+}
+
+// Lookup returns the action bound to key in the given state
+func (k KeyMap) Lookup(state int, key string) (Action, bool) {
+	for _, b := range k.Bindings {
+		if !containsState(b.States, state) {
+			continue
+		}
+		for _, bk := range b.Keys {
+			if bk == key {
+				return b.Action, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Help returns a human-readable label for the first key bound to an action
+func (k KeyMap) Help(action Action) string {
+	for _, b := range k.Bindings {
+		if b.Action == action && len(b.Keys) > 0 {
+			return FormatKey(b.Keys[0])
+		}
+	}
+	return "unbound"
+}
+
+// keys returns the keys bound to an action
+func (k KeyMap) keys(action Action) []string {
+	for _, b := range k.Bindings {
+		if b.Action == action {
+			return b.Keys
+		}
+	}
+	return nil
+}
+
+// Rebind replaces oldKey with newKey for the given action
+func (k *KeyMap) Rebind(action Action, oldKey, newKey string) {
+	for i := range k.Bindings {
+		if k.Bindings[i].Action != action {
+			continue
+		}
+		for j, key := range k.Bindings[i].Keys {
+			if key == oldKey {
+				k.Bindings[i].Keys[j] = newKey
+			}
+		}
+	}
+}
+
+// DetectConflicts finds keys bound to more than one action in overlapping
+// states and keys likely to be intercepted before reaching the application
+func (k KeyMap) DetectConflicts() []KeyConflict {
+	var conflicts []KeyConflict
+	used := map[string]bool{}
+	for _, b := range k.Bindings {
+		for _, key := range b.Keys {
+			used[key] = true
+		}
+	}
+
+	suggest := func() string {
+		for _, candidate := range suggestionKeys {
+			if !used[candidate] {
+				used[candidate] = true
+				return candidate
+			}
+		}
+		return ""
+	}
+
+	for i, b := range k.Bindings {
+		for _, key := range b.Keys {
+			if reason, ok := reservedKeys[key]; ok {
+				conflicts = append(conflicts, KeyConflict{
+					Action:     b.Action,
+					Key:        key,
+					Reason:     reason,
+					Suggestion: suggest(),
+				})
+				continue
+			}
+
+			for _, other := range k.Bindings[:i] {
+				if !statesOverlap(b.States, other.States) || !containsKey(other.Keys, key) {
+					continue
+				}
+				conflicts = append(conflicts, KeyConflict{
+					Action:     b.Action,
+					Key:        key,
+					Reason:     fmt.Sprintf("also bound to %s", other.Action),
+					Suggestion: suggest(),
+				})
+				break
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// FormatKey turns a key name like "ctrl+n" into "Ctrl+N"
+func FormatKey(key string) string {
+	parts := strings.Split(key, "+")
+	for i, p := range parts {
+		switch {
+		case len(p) == 1:
+			parts[i] = strings.ToUpper(p)
+		case p != "":
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "+")
+}
+
+func normalizeKeys(keys []string) []string {
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key != "" {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+func containsState(states []int, state int) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func statesOverlap(a, b []int) bool {
+	for _, s := range a {
+		if containsState(b, s) {
+			return true
+		}
+	}
+	return false
+}
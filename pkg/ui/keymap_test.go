@@ -0,0 +1,21 @@
+package ui
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadmeListsActions(t *testing.T) {
+	readme, err := os.ReadFile("../../README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, section, _ := strings.Cut(string(readme), "## Custom Keybindings")
+	section, _, _ = strings.Cut(section, "\n## ")
+	for _, binding := range DefaultKeyMap().Bindings {
+		if !strings.Contains(section, "| `"+string(binding.Action)+"` |") {
+			t.Errorf("the Custom Keybindings section of the README doesn't list %s", binding.Action)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+)
+
+// DetectLMStudioCmd checks in the background whether LM Studio's server is
+// running
+func DetectLMStudioCmd() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		return LMStudioStatusMsg{Status: api.DetectLMStudio(ctx)}
+	}
+}
+
+// lmStudioDetails describes LM Studio in the provider list with what its
+// detection found, or just its URL while it hasn't finished
+func lmStudioDetails(status *api.LMStudioStatus) string {
+	details := "LM Studio at " + api.Presets["lmstudio"].URL
+	switch {
+	case status == nil:
+		return details
+	case !status.Running:
+		return details + " · server not running (start it from the Developer tab or with `lms server start`)"
+	case len(status.Loaded) == 0:
+		return fmt.Sprintf("%s · running, %d models, none loaded", details, status.Models)
+	}
+	return fmt.Sprintf("%s · running, %d models, loaded: %s", details, status.Models, strings.Join(status.Loaded, ", "))
+}
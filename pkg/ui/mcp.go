@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/mcp"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// mcpConnectTimeout bounds connecting to all configured MCP servers
+const mcpConnectTimeout = 30 * time.Second
+
+// ConnectMCPCmd connects to the configured MCP servers in the background
+func ConnectMCPCmd(servers map[string]utils.MCPServerConfig) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), mcpConnectTimeout)
+		defer cancel()
+		return MCPConnectedMsg{Manager: mcp.ConnectAll(ctx, servers)}
+	}
+}
+
+// HandleMCPConnected replaces the MCP connections and offers their tools
+// to the model
+func (m *Model) HandleMCPConnected(msg MCPConnectedMsg) {
+	if m.MCP != nil {
+		m.MCP.Close()
+	}
+	m.MCP = msg.Manager
+	m.MCPConnecting = false
+	m.ApplyTools()
+
+	m.StatusMessage = fmt.Sprintf("MCP: %d servers connected, %d tools", len(m.MCP.Servers), len(m.MCP.Tools()))
+	if len(m.MCP.Failed) > 0 {
+		m.StatusMessage += fmt.Sprintf(", %d failed (/mcp for details)", len(m.MCP.Failed))
+	}
+	m.UpdateMCPView()
+}
+
+// ApplyTools offers the connected MCP servers' tools to the current client.
+// Ollama only supports tools on its chat API, so an ongoing conversation
+// is handed over as message history.
+func (m *Model) ApplyTools() {
+	if m.MCP == nil {
+		return
+	}
+	APIClient.Tools = m.MCP.Tools()
+	APIClient.ToolHandler = m.MCP.Call
+	if len(APIClient.Tools) > 0 && len(m.Messages) > 0 {
+		APIClient.SwitchModel(m.ChatHistory())
+	}
+}
+
+// OpenMCPStatus shows the MCP servers screen
+func (m *Model) OpenMCPStatus() tea.Cmd {
+	if m.State != StateMCP {
+		m.MCPReturnState = m.State
+	}
+	m.State = StateMCP
+	m.UpdateMCPView()
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// UpdateMCPStatus handles keys on the MCP servers screen
+func (m Model) UpdateMCPStatus(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		m.State = m.MCPReturnState
+		return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	case "ctrl+c":
+		return m, m.RequestQuit()
+	case "r":
+		// Pick up servers added or changed in the config file
+		if config, err := utils.LoadConfig(); err == nil {
+			m.MCPServers = config.MCPServers
+		}
+		if len(m.MCPServers) > 0 && !m.MCPConnecting {
+			m.MCPConnecting = true
+			m.UpdateMCPView()
+			return m, ConnectMCPCmd(m.MCPServers)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.MCPViewport, cmd = m.MCPViewport.Update(msg)
+	return m, cmd
+}
+
+// UpdateMCPView renders the server and tool list into the MCP viewport
+func (m *Model) UpdateMCPView() {
+	var sb strings.Builder
+	switch {
+	case len(m.MCPServers) == 0:
+		sb.WriteString("No MCP servers configured. Add them under \"mcp_servers\" in config.json.")
+	case m.MCPConnecting:
+		sb.WriteString("Connecting…")
+	case m.MCP == nil:
+		sb.WriteString("Not connected.")
+	}
+
+	if m.MCP != nil && !m.MCPConnecting {
+		for _, s := range m.MCP.Servers {
+			status := "● connected"
+			if s.Err != nil {
+				status = "✗ " + s.Err.Error()
+			}
+			sb.WriteString(SelectedHeaderStyle.Render(s.Name))
+			sb.WriteString(fmt.Sprintf(" %s · %s · %d tools · %d resources\n", s.Transport, status, len(s.Tools), len(s.Resources)))
+			for _, t := range s.Tools {
+				sb.WriteString(fmt.Sprintf("  %s", mcp.FunctionName(s.Name, t.Name)))
+				if t.Description != "" {
+					sb.WriteString(" — " + strings.Join(strings.Fields(t.Description), " "))
+				}
+				sb.WriteString("\n")
+			}
+			for _, r := range s.Resources {
+				sb.WriteString(CollapsedStyle.Render("  resource " + r.URI))
+				sb.WriteString("\n")
+			}
+			sb.WriteString("\n")
+		}
+
+		failed := make([]string, 0, len(m.MCP.Failed))
+		for name := range m.MCP.Failed {
+			failed = append(failed, name)
+		}
+		sort.Strings(failed)
+		for _, name := range failed {
+			sb.WriteString(SelectedHeaderStyle.Render(name))
+			sb.WriteString(fmt.Sprintf(" ✗ %v\n\n", m.MCP.Failed[name]))
+		}
+	}
+
+	m.MCPViewport.SetContent(lipgloss.NewStyle().Width(m.MCPViewport.Width).Render(strings.TrimRight(sb.String(), "\n")))
+}
+
+// MCPStatusView renders the MCP servers screen
+func (m Model) MCPStatusView() string {
+	help := "r: reconnect | ↑/↓: scroll | Esc: back"
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		TitleStyle.Render("MCP servers"),
+		lipgloss.NewStyle().Padding(0, 2).Render(m.MCPViewport.View()),
+		HelpStyle.Render(help),
+	)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "mcp",
+		Description: "Show connected MCP servers and the tools they offer",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenMCPStatus()
+		},
+	})
+}
@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// NewMemoriesTable creates the table for the memories screen
+func NewMemoriesTable() table.Model {
+	t := table.New(
+		table.WithColumns(memoriesColumns(80)),
+		table.WithFocused(true),
+	)
+
+	t.SetStyles(TableStyles())
+
+	return t
+}
+
+// memoriesColumns sizes the columns of the memories screen to its width,
+// giving the facts what the number and date leave
+func memoriesColumns(width int) []table.Column {
+	// Each column is padded by a space on either side
+	return []table.Column{
+		{Title: "#", Width: 3},
+		{Title: "Remembered", Width: max(width-3-16-6, 10)},
+		{Title: "Added", Width: 16},
+	}
+}
+
+// memoryTexts returns the facts added to the system prompt, none when they
+// are turned off
+func (m Model) memoryTexts() []string {
+	if m.NoMemories || len(m.Memories) == 0 {
+		return nil
+	}
+	texts := make([]string, len(m.Memories))
+	for i, memory := range m.Memories {
+		texts[i] = memory.Text
+	}
+	return texts
+}
+
+// Remember saves a fact for the system prompt of every chat
+func (m *Model) Remember(text string) error {
+	memories := append(append([]utils.Memory(nil), m.Memories...), utils.Memory{Text: text, CreatedAt: time.Now()})
+	if err := utils.SaveMemories(memories); err != nil {
+		return err
+	}
+	m.Memories = memories
+	APIClient.Memories = m.memoryTexts()
+	return nil
+}
+
+// Forget deletes the fact at index
+func (m *Model) Forget(index int) error {
+	memories := append(append([]utils.Memory(nil), m.Memories[:index]...), m.Memories[index+1:]...)
+	if err := utils.SaveMemories(memories); err != nil {
+		return err
+	}
+	m.Memories = memories
+	APIClient.Memories = m.memoryTexts()
+	return nil
+}
+
+// OpenMemories shows the memories screen
+func (m *Model) OpenMemories() tea.Cmd {
+	if m.State != StateMemories {
+		m.MemoriesReturnState = m.State
+	}
+	m.State = StateMemories
+	m.MemoriesStatus = ""
+	m.UpdateMemoriesTable()
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// UpdateMemoriesTable lists the remembered facts, oldest first
+func (m *Model) UpdateMemoriesTable() {
+	rows := make([]table.Row, 0, len(m.Memories))
+	for i, memory := range m.Memories {
+		rows = append(rows, table.Row{fmt.Sprint(i + 1), memory.Text, memory.CreatedAt.Local().Format("2006-01-02 15:04")})
+	}
+	m.MemoriesTable.SetRows(rows)
+}
+
+// ResizeMemories fits the memories screen to the window
+func (m *Model) ResizeMemories(width, height int) {
+	m.MemoriesTable.SetColumns(memoriesColumns(width - 4))
+	m.MemoriesTable.SetWidth(width - 4)
+	m.MemoriesTable.SetHeight(max(height-4, 3))
+}
+
+// UpdateMemories handles keys on the memories screen
+func (m Model) UpdateMemories(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		m.State = m.MemoriesReturnState
+		return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	case "ctrl+c":
+		return m, m.RequestQuit()
+	case "d", "delete":
+		if len(m.Memories) == 0 {
+			m.MemoriesStatus = "No memory to delete"
+			return m, nil
+		}
+		index := m.MemoriesTable.Cursor()
+		text := m.Memories[index].Text
+		if err := m.Forget(index); err != nil {
+			m.MemoriesStatus = fmt.Sprintf("Failed to delete the memory: %v", err)
+			return m, nil
+		}
+		m.UpdateMemoriesTable()
+		if index >= len(m.Memories) && index > 0 {
+			m.MemoriesTable.SetCursor(index - 1)
+		}
+		m.MemoriesStatus = fmt.Sprintf("Forgot %q", truncate(text, 40))
+		return m, nil
+	case "p":
+		m.NoMemories = !m.NoMemories
+		off := m.NoMemories
+		if err := utils.UpdateConfig(func(c *utils.Config) { c.NoMemories = off }); err != nil {
+			m.Err = err
+		}
+		APIClient.Memories = m.memoryTexts()
+		if off {
+			m.MemoriesStatus = "Memories paused: they are kept but no longer sent"
+		} else {
+			m.MemoriesStatus = "Memories are sent with every chat again"
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.MemoriesTable, cmd = m.MemoriesTable.Update(msg)
+	return m, cmd
+}
+
+// MemoriesView renders the memories screen
+func (m Model) MemoriesView() string {
+	title := fmt.Sprintf("Memories (%d)", len(m.Memories))
+	if m.NoMemories {
+		title += " · paused"
+	}
+	views := []string{TitleStyle.Render(title)}
+	if len(m.Memories) == 0 {
+		views = append(views, lipgloss.NewStyle().Padding(0, 2).Render("Nothing remembered yet. /remember <fact> adds a fact sent with every chat."))
+	} else {
+		views = append(views, ResponseStyle.Render(m.MemoriesTable.View()))
+	}
+	if m.MemoriesStatus != "" {
+		views = append(views, lipgloss.NewStyle().Padding(0, 2).Render(m.MemoriesStatus))
+	}
+	help := "d: delete | p: pause or resume | Esc: back"
+	views = append(views, HelpStyle.Render(help))
+	return lipgloss.JoinVertical(lipgloss.Left, views...)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "remember",
+		Usage:       "<fact>",
+		Description: "Remember a fact about you in the system prompt of every chat",
+		Run: func(m *Model, args string) tea.Cmd {
+			fact := strings.TrimSpace(args)
+			if fact == "" {
+				m.StatusMessage = "Usage: /remember <fact>, e.g. /remember I write Go and prefer short answers"
+				return nil
+			}
+			if err := m.Remember(fact); err != nil {
+				m.StatusMessage = fmt.Sprintf("Failed to remember: %v", err)
+				return nil
+			}
+			m.StatusMessage = fmt.Sprintf("Remembered (%d memories, /memories to manage them)", len(m.Memories))
+			if m.NoMemories {
+				m.StatusMessage += "; memories are paused"
+			}
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "memories",
+		Description: "View and delete the facts remembered with /remember",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenMemories()
+		},
+	})
+}
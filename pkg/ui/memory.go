@@ -0,0 +1,265 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+const (
+	// memoryKeepExchanges is how many of the latest exchanges are always
+	// sent as they are, outside the memory
+	memoryKeepExchanges = 3
+	// memoryEvery is how many older exchanges pile up before they are
+	// folded into the memory
+	memoryEvery = 3
+)
+
+// memoryPrompt asks the model to fold turns of a conversation into its memory
+const memoryPrompt = "You keep the memory of a long conversation: a compact block of notes that replaces its older turns. " +
+	"Rewrite the memory below to also cover the new turns, keeping the names, facts, preferences, decisions and open questions " +
+	"the rest of the conversation may refer to, as short bullet points. Reply with the updated memory only.\n\n%s"
+
+// MemoryMsg carries the memory of the conversation summarized in the
+// background: Trim with its Summary, to apply if the conversation was still
+// trimmed as in Base
+type MemoryMsg struct {
+	Tab  int
+	ID   int
+	Base ContextTrim
+	Trim ContextTrim
+	Err  error
+}
+
+// MemoryEditedMsg is sent when the editor opened with /memory edit exits
+type MemoryEditedMsg struct {
+	Tab     int
+	Content string
+	Err     error
+}
+
+// memoryRange returns the messages to fold into the memory: those after the
+// ones already left out and before the latest exchanges, with how many
+// exchanges they hold
+func (m Model) memoryRange() (from, to, exchanges int) {
+	from, to = m.Trim.To, len(m.Messages)
+	for kept := 0; to > from && kept < memoryKeepExchanges; {
+		to--
+		if m.Messages[to].Role == models.RoleUser {
+			kept++
+		}
+	}
+	for _, msg := range m.Messages[from:to] {
+		if msg.Role == models.RoleUser {
+			exchanges++
+		}
+	}
+	return from, to, exchanges
+}
+
+// UpdateMemoryCmd folds the older exchanges into the memory in the
+// background once enough of them piled up, or right away when now is set
+func (m *Model) UpdateMemoryCmd(now bool) tea.Cmd {
+	if m.Memorizing || m.IsGenerating {
+		return nil
+	}
+	from, to, exchanges := m.memoryRange()
+	if exchanges == 0 || (!now && exchanges < memoryEvery) {
+		return nil
+	}
+
+	base := m.Trim
+	trim := m.Trim
+	trim.To = to
+	text := m.conversationText(m.Trim.Summary, from, to)
+	m.MemoryID++
+	m.Memorizing = true
+
+	tab, id, model := m.TabID, m.MemoryID, m.SelectedModel
+	client := APIClient.Detached()
+	timeout := m.GenerationTimeout
+	return func() tea.Msg {
+		ctx, cancel := generationContext(timeout)
+		defer cancel()
+		summary, err := client.Complete(ctx, model, fmt.Sprintf(memoryPrompt, text))
+		trim.Summary = strings.TrimSpace(summary)
+		if err == nil && trim.Summary == "" {
+			err = errors.New("the memory is empty")
+		}
+		return MemoryMsg{Tab: tab, ID: id, Base: base, Trim: trim, Err: err}
+	}
+}
+
+// conversationText writes the messages from index from to index to as a
+// transcript for the model to summarize, after the earlier summary
+func (m Model) conversationText(summary string, from, to int) string {
+	var sb strings.Builder
+	if summary != "" {
+		sb.WriteString("Earlier summary: " + summary + "\n\n")
+	}
+	for _, msg := range chatHistory(m.Messages[from:to]) {
+		role := "User"
+		if msg.Role == models.RoleAssistant {
+			role = "Assistant"
+		}
+		sb.WriteString(role + ": " + msg.Content + "\n\n")
+	}
+	return sb.String()
+}
+
+// HandleMemory keeps the memory summarized in the background, holding it
+// until the response being generated is done
+func (m *Model) HandleMemory(msg MemoryMsg) {
+	if msg.ID != m.MemoryID {
+		return
+	}
+	m.Memorizing = false
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("Couldn't update the memory: %v", msg.Err)
+		return
+	}
+	// The conversation was trimmed or its memory edited meanwhile
+	if m.Trim != msg.Base || msg.Trim.To > len(m.Messages) {
+		return
+	}
+	if m.IsGenerating {
+		m.PendingMemory = &msg.Trim
+		return
+	}
+	m.applyMemory(msg.Trim)
+}
+
+// applyMemory sends the memory in place of the messages it covers from now on
+func (m *Model) applyMemory(trim ContextTrim) {
+	freed := 0
+	for _, msg := range m.Messages[m.Trim.To:trim.To] {
+		freed += messageTokens(msg)
+	}
+	freed -= utils.EstimateTokens(trim.Summary) - utils.EstimateTokens(m.Trim.Summary)
+	m.Trim = trim
+	APIClient.SetHistory(m.ChatHistory())
+	m.StatusMessage = fmt.Sprintf("Memory updated: messages %d-%d folded in (~%s tokens freed)", trim.From+1, trim.To, formatCount(max(freed, 0)))
+}
+
+// FinishMemory applies the memory that arrived during the response that just
+// finished, or folds the older exchanges into it when auto memory is on
+func (m *Model) FinishMemory() tea.Cmd {
+	if m.PendingMemory != nil {
+		trim := *m.PendingMemory
+		m.PendingMemory = nil
+		m.applyMemory(trim)
+		return nil
+	}
+	if !m.AutoMemory {
+		return nil
+	}
+	return m.UpdateMemoryCmd(false)
+}
+
+// ResetTrim sends the whole conversation again, forgetting its memory and
+// any memory still being summarized
+func (m *Model) ResetTrim() {
+	m.Trim = ContextTrim{}
+	m.MemoryID++
+	m.Memorizing = false
+	m.PendingMemory = nil
+}
+
+// HandleMemoryEdited replaces the memory with the edited text
+func (m *Model) HandleMemoryEdited(msg MemoryEditedMsg) {
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("Editor failed: %v", msg.Err)
+		return
+	}
+	if m.IsGenerating {
+		m.StatusMessage = "Wait for the response to finish before changing the memory"
+		return
+	}
+	// A memory summarized meanwhile would overwrite the edit
+	m.MemoryID++
+	m.Memorizing = false
+	m.PendingMemory = nil
+	m.Trim.Summary = strings.TrimSpace(msg.Content)
+	APIClient.SetHistory(m.ChatHistory())
+	if m.Trim.Summary == "" {
+		m.StatusMessage = "Memory emptied"
+		return
+	}
+	m.StatusMessage = "Memory saved"
+}
+
+// SetAutoMemory turns auto memory on or off and remembers the choice
+func (m *Model) SetAutoMemory(on bool) error {
+	m.AutoMemory = on
+	return utils.UpdateConfig(func(c *utils.Config) { c.AutoMemory = on })
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "memory",
+		Usage:       "[on|off|now|edit|clear]",
+		Description: "Fold older turns into a memory kept in context (now to update it, edit to change it, clear to send everything again)",
+		Run: func(m *Model, args string) tea.Cmd {
+			switch args {
+			case "":
+				state := "off"
+				if m.AutoMemory {
+					state = "on"
+				}
+				if m.Trim.Summary == "" {
+					m.StatusMessage = fmt.Sprintf("Auto memory %s, no memory yet (/memory now to create it)", state)
+				} else {
+					m.StatusMessage = fmt.Sprintf("Auto memory %s, ~%s tokens covering %d messages (F2 to see it, /memory edit to change it)",
+						state, formatCount(utils.EstimateTokens(m.Trim.Summary)), m.Trim.Dropped())
+				}
+				return nil
+			case "on", "off":
+				if err := m.SetAutoMemory(args == "on"); err != nil {
+					m.Err = err
+				}
+				m.StatusMessage = "Auto memory " + args
+				if args == "on" {
+					return m.UpdateMemoryCmd(false)
+				}
+				return nil
+			case "now":
+				if m.IsGenerating || m.Memorizing {
+					m.StatusMessage = "Wait for the response to finish before updating the memory"
+					return nil
+				}
+				cmd := m.UpdateMemoryCmd(true)
+				if cmd == nil {
+					m.StatusMessage = fmt.Sprintf("Nothing to fold into the memory: the latest %d exchanges are always sent as they are", memoryKeepExchanges)
+					return nil
+				}
+				m.StatusMessage = "Updating the memory…"
+				return cmd
+			case "edit":
+				if m.IsGenerating {
+					m.StatusMessage = "Wait for the response to finish before changing the memory"
+					return nil
+				}
+				tab := m.TabID
+				return editFileCmd(m.Trim.Summary, "ollama-tui-memory-*.md", func(content string, err error) tea.Msg {
+					return MemoryEditedMsg{Tab: tab, Content: content, Err: err}
+				})
+			case "clear":
+				if m.IsGenerating {
+					m.StatusMessage = "Wait for the response to finish before clearing the memory"
+					return nil
+				}
+				m.ResetTrim()
+				APIClient.SetHistory(m.ChatHistory())
+				m.StatusMessage = "Memory cleared: the whole conversation is sent again"
+				return nil
+			}
+			m.StatusMessage = "Usage: /memory [on|off|now|edit|clear]"
+			return nil
+		},
+	})
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// SetResponseMetadata attaches what Ollama reported to the latest response
+func (m *Model) SetResponseMetadata(meta *models.ResponseMetadata) {
+	if len(m.Messages) == 0 {
+		return
+	}
+	last := &m.Messages[len(m.Messages)-1]
+	if last.Role != models.RoleAssistant {
+		return
+	}
+	last.Metadata = meta
+	// Ollama's count replaces the estimate
+	if meta.EvalCount > 0 {
+		last.Tokens = meta.EvalCount
+	}
+}
+
+// ToggleMetadata shows or hides the metadata footers and remembers the
+// choice
+func (m *Model) ToggleMetadata() {
+	m.ShowMetadata = !m.ShowMetadata
+	hide := !m.ShowMetadata
+	if err := utils.UpdateConfig(func(c *utils.Config) { c.HideResponseMetadata = hide }); err != nil {
+		m.Err = err
+	}
+	if m.ShowMetadata {
+		m.StatusMessage = "Response metadata shown"
+	} else {
+		m.StatusMessage = "Response metadata hidden"
+	}
+	m.RefreshTranscript()
+}
+
+// metadataFooter renders the footer under a response, or nothing when the
+// provider reported no metadata or footers are hidden
+func (m Model) metadataFooter(msg models.Message) string {
+	if !m.ShowMetadata || msg.Metadata == nil {
+		return ""
+	}
+	return MetadataStyle.Render(FormatMetadata(*msg.Metadata))
+}
+
+// FormatMetadata describes a response's metadata on one line
+func FormatMetadata(meta models.ResponseMetadata) string {
+	var parts []string
+	if meta.Model != "" {
+		parts = append(parts, meta.Model)
+	}
+	if !meta.CreatedAt.IsZero() {
+		parts = append(parts, meta.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if meta.TotalDuration > 0 {
+		parts = append(parts, "total "+formatDuration(meta.TotalDuration))
+	}
+	if meta.LoadDuration >= time.Millisecond {
+		parts = append(parts, "load "+formatDuration(meta.LoadDuration))
+	}
+	if meta.PromptEvalCount > 0 {
+		prompt := fmt.Sprintf("prompt %d tokens", meta.PromptEvalCount)
+		if meta.PromptEvalDuration > 0 {
+			prompt += " in " + formatDuration(meta.PromptEvalDuration)
+		}
+		parts = append(parts, prompt)
+	}
+	if meta.EvalCount > 0 {
+		response := fmt.Sprintf("%d tokens", meta.EvalCount)
+		if meta.EvalDuration > 0 {
+			response += fmt.Sprintf(" at %.1f tok/s", float64(meta.EvalCount)/meta.EvalDuration.Seconds())
+		}
+		parts = append(parts, response)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// formatDuration shortens a duration to milliseconds below a second and to
+// hundredths of a second above
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
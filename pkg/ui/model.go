@@ -1,19 +1,28 @@
 package ui
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"golang.org/x/term"
 
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/mcp"
 	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/session"
+	"github.com/evilvic/ollama-tui/pkg/speech"
 	"github.com/evilvic/ollama-tui/pkg/utils"
 )
 
@@ -28,41 +37,442 @@ const (
 	StatePrompting
 	// StateLoading is the state for loading a response
 	StateLoading
+	// StateKeyConflicts is the state for reviewing custom keybinding conflicts
+	StateKeyConflicts
+	// StateSessionBrowser is the state for browsing saved sessions
+	StateSessionBrowser
+	// StateRunningModels is the state for the loaded models screen
+	StateRunningModels
+	// StateMCP is the state for the MCP servers screen
+	StateMCP
+	// StateCodeBlocks is the state for saving code blocks to files
+	StateCodeBlocks
+	// StateCredentials is the state for the API keys screen
+	StateCredentials
+	// StateDiagnostics is the state for the diagnostics screen
+	StateDiagnostics
+	// StateUsage is the state for the usage screen
+	StateUsage
+	// StatePull is the state for the model pull screen
+	StatePull
+	// StateRegistry is the state for browsing the Ollama library
+	StateRegistry
+	// StateMemories is the state for the remembered facts screen
+	StateMemories
+	// StateInspector is the state for the request inspector
+	StateInspector
+	// StatePreview is the state for the preview of a prompt's request
+	StatePreview
+)
+
+const (
+	// MaxRecentModels is the number of recently used models remembered
+	MaxRecentModels = 10
+	// RecentSectionSize is the number of recent models shown above the model list
+	RecentSectionSize = 3
+)
+
+const (
+	// MinInputLines is the smallest height of the prompt input box
+	MinInputLines = 3
+	// DefaultMaxInputLines is the height the input box grows to by default
+	DefaultMaxInputLines = 10
 )
 
 // Model represents the UI model
 type Model struct {
+	// Tab is the conversation on screen; the others wait in Tabs
+	Tab
+	Tabs      []Tab
+	ActiveTab int
+	NextTabID int
+
 	State              int
 	ProviderList       list.Model
 	List               list.Model
 	Models             []models.Model
-	SelectedProvider   string
-	SelectedModel      string
+	AllProviders       bool
 	Input              textarea.Model
-	APIKeyInput        textarea.Model
+	APIKeyInput        textinput.Model
 	Viewport           viewport.Model
 	Spinner            spinner.Model
-	Responses          []string
-	CurrentPrompt      string
-	CurrentResponse    string
+	HideReasoning      bool
+	ShowMetadata       bool
+	ShowTimestamps     bool
+	ShowSidePane       bool
+	SelectionMode      bool
+	MessageOffsets     []int
 	Err                error
-	InProgressResponse string
-	IsGenerating       bool
 	ScreenWidth        int
 	ScreenHeight       int
-	CancelGenerate     context.CancelFunc
 	ViewportFocused    bool
+	KeyMap             KeyMap
+	PendingKeyMap      KeyMap
+	KeyConflicts       []KeyConflict
+	ConflictTable      table.Model
+	SlashIndex         int
+	StatusMessage      string
+	GenerationTimeout  time.Duration
+	PromptHistory      []string
+	HistoryIndex       int
+	PersistHistory     bool
+	HistorySearching   bool
+	HistoryQuery       string
+	HistorySearchIndex int
+	MultilineInput     bool
+	MaxInputLines      int
+	Transcript         string
+	Searching          bool
+	SearchQuery        string
+	SearchMatches      []int
+	SearchIndex        int
+	SessionStore       *session.Store
+	SessionList        list.Model
+	// ServedSessions finds the session of a conversation answered by the
+	// local API server from the key of its messages
+	ServedSessions map[string]string
+	KeepAlive      string
+	SafePrompt     bool
+	// Stop, Seed and ProviderOptions are passed through to the requests of
+	// every client, the options by provider
+	Stop            []string
+	Seed            *int
+	ProviderOptions map[string]map[string]interface{}
+	// ContextStrategy is what is left out of the conversation when it
+	// nears the end of the context window
+	ContextStrategy string
+	// AutoMemory folds older turns into a memory kept in context
+	AutoMemory bool
+	// RateLimits are the quotas hosted providers said are left, by provider
+	RateLimits map[string]api.RateLimit
+	// Deterministic pins the temperature to 0 and the seed, recording each
+	// request for /replay; TemperatureBeforeDeterministic is restored when
+	// it is turned off
+	Deterministic                  bool
+	TemperatureBeforeDeterministic *float64
+	RunningModels                  []models.RunningModel
+	// Loaded models screen
+	RunningModelsTable       table.Model
+	RunningModelsTick        int
+	RunningModelsStatus      string
+	RunningModelsReturnState int
+	// Copy/rename prompt in the model list
+	ModelOp        string
+	ModelOpSource  string
+	ModelOpConfirm bool
+	ModelNameInput textinput.Model
+	// Model list order
+	ModelSort      ModelSort
+	GroupModels    bool
+	RecentModels   []string
+	FavoriteModels []string
+	LastProvider   string
+
+	// ConfirmQuit shows the quit confirmation dialog
+	ConfirmQuit bool
+
+	// Recovered is what a run that didn't exit cleanly left, until /recover
+	// restores or discards it, and RecoveryState what was last saved for
+	// recovery
+	Recovered     *session.Recovery
+	RecoveryState string
+
+	MCP            *mcp.Manager
+	MCPServers     map[string]utils.MCPServerConfig
+	MCPConnecting  bool
+	MCPViewport    viewport.Model
+	MCPReturnState int
+
+	// Diagnostics screen
+	Diagnostics       []Diagnostic
+	DoctorRunning     bool
+	DoctorViewport    viewport.Model
+	DoctorReturnState int
+
+	// Request inspector, showing request bodies indented unless
+	// InspectorRaw is set
+	InspectorViewport    viewport.Model
+	InspectorRaw         bool
+	InspectorReturnState int
+
+	// Preview of what a prompt would be sent with
+	Preview            ContextPreview
+	PreviewViewport    viewport.Model
+	PreviewReturnState int
+
+	// Usage screen
+	Usage            UsageStats
+	UsageErr         error
+	UsageLoading     bool
+	UsageViewport    viewport.Model
+	UsageReturnState int
+
+	// Model pull screen
+	Pull            *ModelPull
+	PullID          int
+	PullBar         progress.Model
+	PullViewport    viewport.Model
+	PullReturnState int
+
+	// Batch is the batch of prompts running in the background
+	Batch   *BatchRun
+	BatchID int
+
+	// Registry browser
+	RegistryList        list.Model
+	RegistryCatalog     api.RegistryCatalog
+	RegistryUpdating    bool
+	RegistryReturnState int
+
+	// ShellProposal is a command shown for confirmation before it runs
+	ShellProposal *ShellProposal
+	// CommitProposal is a commit message shown for confirmation
+	CommitProposal *CommitProposal
+
+	// Code blocks screen
+	CodeBlocks           []utils.CodeBlock
+	CodeBlockTargets     []string
+	CodeBlockSideBySide  bool
+	CodeBlockIndex       int
+	CodeBlockStep        int
+	CodeBlockPathInput   textinput.Model
+	CodeBlockExisting    string
+	CodeBlockShowDiff    bool
+	CodeBlockStatus      string
+	CodeViewport         viewport.Model
+	CodeBlockReturnState int
+
+	// APIKeyChecking is the entered API key while it is checked with the
+	// provider, and APIKeyError why the provider rejected the last one
+	APIKeyChecking string
+	APIKeyError    string
+
+	// API keys screen
+	CredentialsTable       table.Model
+	CredentialInput        textinput.Model
+	CredentialsEditing     string
+	CredentialsStatus      string
+	CredentialsReturnState int
+
+	// Memories are the facts remembered with /remember, added to the system
+	// prompt unless NoMemories pauses them
+	Memories            []utils.Memory
+	NoMemories          bool
+	MemoriesTable       table.Model
+	MemoriesStatus      string
+	MemoriesReturnState int
+
+	// ImageProtocol is how images are drawn in the transcript
+	ImageProtocol utils.ImageProtocol
+	// ImagePlacements are the images drawn over the rendered transcript
+	ImagePlacements []ImagePlacement
+
+	// Recorder is capturing a voice prompt while set
+	Recorder       speech.Recorder
+	RecordingStart time.Time
+
+	// TerminalFocused is false while the terminal window is in the background
+	TerminalFocused bool
+	// NotifyMethod, NotifyBell and NotifyAfter announce finished responses
+	NotifyMethod utils.NotifyMethod
+	NotifyBell   bool
+	NotifyAfter  time.Duration
+
+	// Accessible prints the conversation as plain text for screen readers
+	Accessible bool
+	// PrintedMessages and PrintedLines count what was printed in accessible
+	// mode: whole messages, then lines of the message after them
+	PrintedMessages int
+	PrintedLines    int
+
+	// Endpoints are the servers found on the network, listed after the
+	// providers; Discovering is set while looking for them
+	Endpoints   []api.Endpoint
+	Discovering bool
+	// LMStudio is whether LM Studio's server is running, once detected
+	LMStudio *api.LMStudioStatus
+
+	// LoadedConfig is config.json as last loaded, to tell which settings a
+	// change to the file touched; ConfigModTime is when it was written
+	LoadedConfig  utils.Config
+	ConfigModTime time.Time
+
+	// UpdateNotice announces a newer release in the status bar
+	UpdateNotice string
 }
 
 // TokenMsg represents a token message
 type TokenMsg struct {
-	Token string
-	Done  bool
+	// Tab is the tab the response is for and ID the response in it
+	Tab      int
+	ID       int
+	Token    string
+	Done     bool
+	TimedOut bool
+	// Stopped is set when the response was stopped before it finished
+	Stopped bool
+	Err     error
+	// Metadata is what Ollama reported about the response, sent when done
+	Metadata *models.ResponseMetadata
+	// Citations are the pages the response cites, sent when done
+	Citations []string
+	// Request is the first request of the response, sent when done in
+	// deterministic mode
+	Request *models.RecordedRequest
+	// Reason is why the model stopped, sent when done
+	Reason string
+	// Exchanges are the raw HTTP exchanges of the response, sent when done
+	Exchanges []api.Exchange
+}
+
+// CompareTokenMsg carries a token streamed to one pane of a comparison
+type CompareTokenMsg struct {
+	Round    int
+	Pane     int
+	Token    string
+	Done     bool
+	TimedOut bool
+	Err      error
+}
+
+// DiscoveryMsg carries the servers a discovery run found
+type DiscoveryMsg struct {
+	Endpoints []api.Endpoint
+	// Probed is how many addresses were tried
+	Probed int
+}
+
+// LMStudioStatusMsg carries whether LM Studio's server is running
+type LMStudioStatusMsg struct {
+	Status api.LMStudioStatus
+}
+
+// SessionTitleMsg carries a title generated for the session
+type SessionTitleMsg struct {
+	Tab   int
+	Title string
+	Err   error
+}
+
+// RecallMsg carries the stored messages that best match a /recall query
+type RecallMsg struct {
+	Query    string
+	Snippets []session.Snippet
+	Err      error
+}
+
+// ShellCommandMsg carries the command the model wrote for a /sh task
+type ShellCommandMsg struct {
+	Task    string
+	Command string
+	Err     error
+}
+
+// ShellResultMsg carries the output of a command run from the chat
+type ShellResultMsg struct {
+	Command  string
+	Output   string
+	ExitCode int
+	Err      error
+}
+
+// CommitMessageMsg carries the commit message written for the staged changes
+type CommitMessageMsg struct {
+	Message   string
+	Stat      string
+	Truncated bool
+	Hint      string
+	Err       error
+}
+
+// CommitMessageEditedMsg is sent when the editor closes on a commit message
+type CommitMessageEditedMsg struct {
+	Proposal CommitProposal
+	Content  string
+	Err      error
+}
+
+// CommitDoneMsg carries the result of git commit
+type CommitDoneMsg struct {
+	Output string
+	Err    error
+}
+
+// ClipboardImageMsg carries the image read from the clipboard by /paste-image
+type ClipboardImageMsg struct {
+	Data []byte
+	Err  error
+}
+
+// RecordingTickMsg updates the length of a voice recording
+type RecordingTickMsg struct {
+	Start time.Time
+}
+
+// TranscriptionMsg carries the text transcribed from a voice recording
+type TranscriptionMsg struct {
+	Text string
+	Err  error
+}
+
+// NotificationMsg reports whether a notification could be shown
+type NotificationMsg struct {
+	Err error
+}
+
+// MCPConnectedMsg is sent once the configured MCP servers were connected
+type MCPConnectedMsg struct {
+	Manager *mcp.Manager
+}
+
+// WebResultsMsg carries the pages fetched for a /web search
+type WebResultsMsg struct {
+	Query       string
+	Backend     string
+	Attachments []models.Attachment
+	Err         error
 }
 
 // FetchModelsMsg represents a fetch models message
 type FetchModelsMsg struct {
 	Models []models.Model
+	// Errs are the providers that failed when lists were merged
+	Errs []error
+}
+
+// RunningModelsMsg carries the models Ollama currently has loaded
+type RunningModelsMsg struct {
+	Models []models.RunningModel
+	Err    error
+}
+
+// ModelUnloadedMsg is sent after a model was asked to unload
+type ModelUnloadedMsg struct {
+	Model string
+	Err   error
+}
+
+// ModelfileEditedMsg is sent when the Modelfile editor exits
+type ModelfileEditedMsg struct {
+	Name    string
+	Content string
+	Err     error
+}
+
+// CreateProgressMsg reports progress while a model is being created
+type CreateProgressMsg struct {
+	Model  string
+	Status string
+	Done   bool
+	Err    error
+}
+
+// ModelOpMsg is sent after a model was copied or renamed
+type ModelOpMsg struct {
+	Op          string
+	Source      string
+	Destination string
+	Err         error
 }
 
 // ErrorMsg represents an error message
@@ -70,16 +480,22 @@ type ErrorMsg struct {
 	Err error
 }
 
-// SetCancelFuncMsg represents a message to set the cancel function
-type SetCancelFuncMsg struct {
-	Cancel context.CancelFunc
+// EditorFinishedMsg is sent when the external editor exits
+type EditorFinishedMsg struct {
+	Content string
+	Err     error
 }
 
 // NewModel creates a new UI model
 func NewModel() Model {
+	// The styles are built from the configured theme before anything copies
+	// them
+	config, configErr := utils.LoadEffectiveConfig()
+	themeErr := ApplyTheme(config.Theme)
+
 	s := spinner.New()
 	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	s.Style = lipgloss.NewStyle().Foreground(CurrentTheme.Accent)
 
 	// Provider list
 	pl := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
@@ -88,17 +504,7 @@ func NewModel() Model {
 	pl.SetFilteringEnabled(false)
 	pl.Styles.Title = TitleStyle
 
-	// Add Ollama as the only provider for now
-	pl.SetItems([]list.Item{
-		models.ListItem{
-			Name:    "ollama",
-			Details: "Local LLM server",
-		},
-		models.ListItem{
-			Name:    "openai",
-			Details: "OpenAI API",
-		},
-	})
+	pl.SetItems(ProviderItems(nil))
 
 	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
 	l.Title = "Available models"
@@ -111,46 +517,152 @@ func NewModel() Model {
 	ta.Focus()
 	ta.CharLimit = 5000
 	ta.SetWidth(100)
-	ta.SetHeight(3)
+	ta.SetHeight(MinInputLines)
 	ta.ShowLineNumbers = false
 
 	// API Key input
-	apiKeyInput := textarea.New()
+	apiKeyInput := NewAPIKeyInput()
 	apiKeyInput.Placeholder = "Enter your OpenAI API key..."
 	apiKeyInput.Focus()
-	apiKeyInput.CharLimit = 100
-	apiKeyInput.SetWidth(100)
-	apiKeyInput.SetHeight(3)
-	apiKeyInput.ShowLineNumbers = false
+	apiKeyInput.Width = 100
 
 	vp := viewport.New(0, 0)
 	vp.Style = ResponseStyle
 	vp.SetContent("Responses will appear here.\n\n")
 
-	return Model{
-		State:              StateProviderSelect,
+	// Apply custom keybindings from the config file, holding them back for
+	// review if they conflict with each other or with terminal defaults
+	state := StateProviderSelect
+	keyMap := DefaultKeyMap()
+	var pending KeyMap
+	var conflicts []KeyConflict
+	if len(config.Keybindings) > 0 {
+		custom, unknown := keyMap.WithOverrides(config.Keybindings)
+		conflicts = append(unknown, custom.DetectConflicts()...)
+		if len(conflicts) > 0 {
+			state = StateKeyConflicts
+			pending = custom
+		} else {
+			keyMap = custom
+		}
+	}
+
+	maxInputLines := config.MaxInputLines
+	if maxInputLines <= 0 {
+		maxInputLines = DefaultMaxInputLines
+	}
+
+	// Facts the user asked to remember in earlier sessions
+	memories, _ := utils.LoadMemories()
+
+	// Restore prompt history from previous sessions if enabled
+	var history []string
+	if config.PersistHistory {
+		history, _ = utils.LoadHistory()
+	}
+
+	m := Model{
+		State:              state,
+		Tab:                NewTab(),
 		ProviderList:       pl,
 		List:               l,
 		Spinner:            s,
 		Input:              ta,
 		APIKeyInput:        apiKeyInput,
 		Viewport:           vp,
-		Responses:          []string{},
-		InProgressResponse: "",
-		IsGenerating:       false,
+		HideReasoning:      config.HideReasoning,
+		ShowMetadata:       !config.HideResponseMetadata,
+		ShowTimestamps:     !config.HideTimestamps,
+		ShowSidePane:       config.SidePane,
 		ScreenWidth:        80,
 		ScreenHeight:       24,
 		ViewportFocused:    false,
+		PendingKeyMap:      pending,
+		KeyConflicts:       conflicts,
+		ConflictTable:      NewConflictTable(conflicts),
+		GenerationTimeout:  config.GenerationTimeoutDuration(),
+		PromptHistory:      history,
+		HistoryIndex:       len(history),
+		PersistHistory:     config.PersistHistory,
+		MultilineInput:     config.MultilineInput,
+		MaxInputLines:      maxInputLines,
+		SessionList:        NewSessionList(),
+		KeepAlive:          config.KeepAlive,
+		SafePrompt:         config.MistralSafePrompt,
+		Stop:               config.Stop,
+		Seed:               config.Seed,
+		ProviderOptions:    config.Options,
+		Deterministic:      config.Deterministic,
+		ContextStrategy:    config.ContextStrategy,
+		AutoMemory:         config.AutoMemory,
+		RunningModelsTable: NewRunningModelsTable(),
+		ModelNameInput:     textinput.New(),
+		CodeBlockPathInput: textinput.New(),
+		CodeViewport:       viewport.New(80, 10),
+		CredentialsTable:   NewCredentialsTable(),
+		CredentialInput:    NewAPIKeyInput(),
+		Memories:           memories,
+		NoMemories:         config.NoMemories,
+		MemoriesTable:      NewMemoriesTable(),
+		ModelSort:          ParseModelSort(config.ModelSort),
+		GroupModels:        config.GroupModels,
+		RecentModels:       config.RecentModels,
+		FavoriteModels:     config.FavoriteModels,
+		LastProvider:       config.LastProvider,
+		MCPServers:         config.MCPServers,
+		MCPConnecting:      len(config.MCPServers) > 0,
+		MCPViewport:        viewport.New(80, 20),
+		DoctorViewport:     viewport.New(80, 20),
+		InspectorViewport:  viewport.New(80, 20),
+		PreviewViewport:    viewport.New(80, 20),
+		UsageViewport:      viewport.New(80, 20),
+		PullBar:            newPullBar(),
+		PullViewport:       viewport.New(80, 20),
+		RegistryList:       NewRegistryList(),
+		ImageProtocol:      utils.ParseImageProtocol(config.ImageProtocol),
+		TerminalFocused:    true,
+		NotifyMethod:       utils.ParseNotifyMethod(config.Notifications.Method),
+		NotifyBell:         config.Notifications.Bell,
+		NotifyAfter:        config.Notifications.AfterDuration(),
+		Err:                errors.Join(configErr, themeErr),
+	}
+	m.SetKeyMap(keyMap)
+	m.LoadedConfig = config
+	if config.Profile != "" {
+		m.ProviderList.Title = "Available providers · " + config.Profile + " profile"
+	}
+	m.ConfigModTime = configModTime()
+
+	if config.AccessibleMode {
+		m.EnableAccessibleMode()
+	}
+
+	// Skip the selection screens when defaults are configured. A default
+	// model without a provider is an Ollama model, as with --model.
+	if config.DefaultProvider != "" || config.DefaultModel != "" {
+		provider := config.DefaultProvider
+		if provider == "" {
+			provider = "ollama"
+		}
+		m.StartWithModel(provider, config.DefaultModel)
+	} else if config.StartWithLastModel {
+		m.ResumeLastModel()
+	}
+
+	if recovery, err := session.LoadRecovery(); err == nil && recovery != nil {
+		m.OfferRecovery(recovery)
 	}
+
+	return m
 }
 
 // Init initializes the UI model
 func (m Model) Init() tea.Cmd {
 	// Send initial commands to start the spinner and enter alt screen
 	// We'll fetch models after provider selection
-	cmds := []tea.Cmd{
-		m.Spinner.Tick,
-		tea.EnterAltScreen,
+	var cmds []tea.Cmd
+	if !m.Accessible {
+		cmds = append(cmds, m.Spinner.Tick, tea.EnterAltScreen)
 	}
 
 	// Get initial terminal size and add a command to send a window size message
@@ -163,6 +675,24 @@ func (m Model) Init() tea.Cmd {
 		cmds = append(cmds, InitializeWindowSizeCmd)
 	}
 
+	if m.MCPConnecting {
+		cmds = append(cmds, ConnectMCPCmd(m.MCPServers))
+	}
+
+	cmds = append(cmds, WatchConfigCmd(m.ConfigModTime), RecoveryTickCmd())
+	if UpdateCheckDue(m.LoadedConfig) {
+		cmds = append(cmds, UpdateCheckCmd())
+	}
+
+	if m.State == StateProviderSelect {
+		cmds = append(cmds, DetectLMStudioCmd())
+	}
+
+	// Load the model list when provider selection was skipped
+	if m.State == StatePrompting || m.State == StateModelSelect {
+		cmds = append(cmds, m.RefreshModelsCmd())
+	}
+
 	return tea.Batch(cmds...)
 }
 
@@ -174,7 +704,11 @@ func InitializeWindowSizeCmd() tea.Msg {
 
 // AppLayout returns the layout dimensions for the application
 func AppLayout(width, height int, state int) (int, int) {
-	if state == StateProviderSelect || state == StateModelSelect || state == StateAPIKeyInput {
+	if state == StateProviderSelect || state == StateModelSelect || state == StateAPIKeyInput || state == StateKeyConflicts ||
+		state == StateSessionBrowser || state == StateRunningModels || state == StateMCP ||
+		state == StateCodeBlocks || state == StateCredentials || state == StateDiagnostics ||
+		state == StateUsage || state == StatePull || state == StateRegistry || state == StateMemories ||
+		state == StateInspector || state == StatePreview {
 		return width, height - 4
 	}
 
@@ -183,7 +717,28 @@ func AppLayout(width, height int, state int) (int, int) {
 }
 
 // View renders the UI
-func (m Model) View() string {
+func (m Model) View() (view string) {
+	// Kitty keeps images on screen until they are deleted, which the chat
+	// view does before drawing its own
+	defer func() {
+		if m.ImageProtocol == utils.ImageKitty && !strings.Contains(view, utils.KittyDeleteImages) {
+			view += utils.KittyDeleteImages
+		}
+	}()
+
+	if m.tooSmall() {
+		return m.TooSmallView()
+	}
+	if m.ConfirmQuit {
+		return m.QuitConfirmView()
+	}
+	if m.ShellProposal != nil {
+		return m.ShellConfirmView()
+	}
+	if m.CommitProposal != nil {
+		return m.CommitConfirmView()
+	}
+
 	switch m.State {
 	case StateProviderSelect:
 		return m.ProviderList.View()
@@ -194,10 +749,18 @@ func (m Model) View() string {
 		height := m.ScreenHeight
 
 		// Title
-		titleView := TitleStyle.Render("OpenAI API Key Required")
+		provider := KeyedProviders[m.SelectedProvider]
+		titleView := TitleStyle.Render(provider.Name + " API Key Required")
 
 		// Instructions
-		instructions := "Please enter your OpenAI API key to continue.\nYou can find your API key at https://platform.openai.com/api-keys\n\nPress Enter to continue or Esc to go back."
+		instructions := fmt.Sprintf("Please enter your %s API key to continue.\nYou can find your API key at %s\n\nPress Enter to continue or Esc to go back.", provider.Name, provider.KeysURL)
+		var feedback string
+		switch {
+		case m.APIKeyChecking != "":
+			feedback = fmt.Sprintf("Checking the key %s with %s…", maskKey(m.APIKeyChecking), provider.Name)
+		case m.APIKeyError != "":
+			feedback = ErrorTextStyle.Render(m.APIKeyError)
+		}
 		instructionsView := lipgloss.NewStyle().
 			Width(width-4).
 			Padding(1, 0, 1, 0).
@@ -215,6 +778,7 @@ func (m Model) View() string {
 			instructionsView,
 			"\n",
 			inputView,
+			lipgloss.NewStyle().Width(width-4).Render(feedback),
 		)
 
 		return lipgloss.Place(
@@ -226,79 +790,84 @@ func (m Model) View() string {
 		)
 
 	case StateModelSelect:
-		return m.List.View()
-
-	case StatePrompting, StateLoading:
-		// Get terminal dimensions
-		width := m.ScreenWidth
-		height := m.ScreenHeight
-		if width <= 0 {
-			width = 80 // Default width if not set
-		}
-		if height <= 0 {
-			height = 24 // Default height if not set
+		if m.ModelOp != "" {
+			return lipgloss.JoinVertical(lipgloss.Left, m.List.View(), m.ModelListFooter(), m.ModelOpView(m.ScreenWidth))
 		}
+		return lipgloss.JoinVertical(lipgloss.Left, m.List.View(), m.ModelListFooter())
 
-		// Create a container for the entire UI
-		container := lipgloss.NewStyle().Width(width).Height(height)
+	case StateSessionBrowser:
+		return m.SessionList.View()
 
-		// Title section
-		titleView := TitleStyle.Render(fmt.Sprintf("Chat with %s", m.SelectedModel))
-		titleHeight := lipgloss.Height(titleView) + 2 // +2 for spacing
+	case StateRunningModels:
+		return m.RunningModelsView()
 
-		// Input section (fixed at bottom)
-		inputStyle := InputBoxStyle.Copy().Width(width - 4)
-		if !m.ViewportFocused {
-			inputStyle = inputStyle.BorderForeground(lipgloss.Color("#FF5F87"))
-		} else {
-			inputStyle = inputStyle.BorderForeground(lipgloss.Color("#AFAFAF"))
-		}
-		inputView := inputStyle.Render(m.Input.View())
-		inputHeight := lipgloss.Height(inputView)
+	case StateMCP:
+		return m.MCPStatusView()
+	case StateCodeBlocks:
+		return m.CodeBlocksView()
+	case StateCredentials:
+		return m.CredentialsView()
+	case StateDiagnostics:
+		return m.DiagnosticsView()
+	case StateUsage:
+		return m.UsageView()
+	case StatePull:
+		return m.PullView()
+	case StateRegistry:
+		return m.RegistryList.View()
+	case StateMemories:
+		return m.MemoriesView()
+	case StateInspector:
+		return m.InspectorView()
+	case StatePreview:
+		return m.PreviewView()
 
-		// Status bar (fixed at bottom)
-		contextIndicator := ""
-		if APIClient.HasContext() {
-			contextIndicator = "🔄 Context Active | "
-		}
-		statusText := fmt.Sprintf(" %s | %sTab: Toggle focus | Ctrl+N: New Chat | Ctrl+C: Exit ", m.SelectedModel, contextIndicator)
-		statusView := StatusBarStyle.Copy().Width(width).Render(statusText)
-		statusHeight := lipgloss.Height(statusView)
-
-		// Loading indicator
-		var loadingView string
-		loadingHeight := 0
-		if m.State == StateLoading && m.IsGenerating {
-			loadingView = fmt.Sprintf("  %s Generating...", m.Spinner.View())
-			loadingHeight = 1
-		}
+	case StateKeyConflicts:
+		titleView := TitleStyle.Render("Keybinding conflicts")
+		instructions := "Some custom keybindings conflict with each other or with terminal defaults.\n\n" +
+			"Enter: apply suggested keys | i: keep configured keys | Esc: use default keys"
+		instructionsView := lipgloss.NewStyle().
+			Width(m.ScreenWidth-4).
+			Padding(1, 0, 1, 2).
+			Render(instructions)
 
-		// Calculate viewport height
-		// Available height = total height - (title + input + status + loading + spacing)
-		viewportHeight := height - titleHeight - inputHeight - statusHeight - loadingHeight - 2
-		if viewportHeight < 5 {
-			viewportHeight = 5
-		}
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			titleView,
+			instructionsView,
+			ResponseStyle.Render(m.ConflictTable.View()),
+		)
 
-		// Set viewport style with calculated height
-		viewportStyle := ResponseStyle.Copy()
-		if m.ViewportFocused {
-			viewportStyle = viewportStyle.BorderStyle(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#FF5F87"))
+	case StatePrompting, StateLoading:
+		if m.Accessible {
+			return m.AccessibleChatView()
 		}
 
-		// Ensure viewport has the correct height
-		m.Viewport.Height = viewportHeight
-		m.Viewport.Width = width - 4
+		layout := m.ApplyLayout()
+		width, height := layout.width, layout.height
+		titleView, inputView, popupView, statusView, loadingView := layout.title, layout.input, layout.popup, layout.status, layout.loading
+		viewportHeight, viewportStyle := layout.viewportHeight, layout.viewportStyle
+
+		// Create a container for the entire UI
+		container := lipgloss.NewStyle().Width(width).Height(height)
 
-		// Render the viewport
+		// Render the viewport, or the comparison panes in comparison mode,
+		// with the info pane beside it when shown
 		viewportView := viewportStyle.Render(m.Viewport.View())
+		if len(m.Comparison) > 0 {
+			viewportView = m.ComparisonView(width-4, viewportHeight)
+		} else if m.sidePaneWidth() > 0 {
+			viewportView = lipgloss.JoinHorizontal(lipgloss.Top, viewportView, m.SidePaneView(lipgloss.Height(viewportView)))
+		}
 
 		// Build the final view with fixed positions
 		var sb strings.Builder
 
 		// Title at the top
-		sb.WriteString(titleView)
-		sb.WriteString("\n\n")
+		if titleView != "" {
+			sb.WriteString(titleView)
+			sb.WriteString("\n\n")
+		}
 
 		// Viewport in the middle (takes available space)
 		sb.WriteString(viewportView)
@@ -310,6 +879,12 @@ func (m Model) View() string {
 			sb.WriteString("\n")
 		}
 
+		// Command suggestions directly above the input
+		if popupView != "" {
+			sb.WriteString(popupView)
+			sb.WriteString("\n")
+		}
+
 		// Input box fixed at the bottom
 		sb.WriteString(inputView)
 		sb.WriteString("\n")
@@ -317,38 +892,297 @@ func (m Model) View() string {
 		// Status bar at the very bottom
 		sb.WriteString(statusView)
 
-		return container.Render(sb.String())
+		// Images are drawn over their place in the transcript once the rest
+		// of the screen is written, which a compact screen has no room for
+		if layout.compact {
+			return container.Render(sb.String())
+		}
+		top, left := frameOffset(viewportStyle)
+		innerTop, innerLeft := frameOffset(m.Viewport.Style)
+		top += lipgloss.Height(titleView) + 1 + innerTop
+		return container.Render(sb.String()) + m.ImageOverlay(top, left+innerLeft)
 
 	default:
 		return "Unknown state"
 	}
 }
 
-// UpdateViewportContent updates the viewport content with the current responses
-func (m *Model) UpdateViewportContent() {
-	var content strings.Builder
-	for _, resp := range m.Responses {
-		content.WriteString(resp)
-		content.WriteString("\n\n")
+// chatLayout holds the parts of the chat screen around the transcript and
+// the height left for it
+type chatLayout struct {
+	// compact is set on screens too small for borders and spacing
+	compact        bool
+	width, height  int
+	viewportWidth  int
+	title          string
+	input          string
+	popup          string
+	status         string
+	loading        string
+	viewportHeight int
+	viewportStyle  lipgloss.Style
+}
+
+// ApplyLayout sizes the transcript and the input box for the chat screen.
+// The View and the scrolling done in Update rely on the same sizes. A
+// transcript scrolled to the bottom stays at the bottom.
+func (m *Model) ApplyLayout() chatLayout {
+	width := m.ScreenWidth
+	if width <= 0 {
+		width = 80
+	}
+	atBottom := m.Viewport.AtBottom()
+	if m.compact() {
+		m.Input.SetWidth(width)
+	} else {
+		m.Input.SetWidth(width - 4 - InputBoxStyle.GetHorizontalPadding())
+	}
+	layout := m.layoutChat()
+	m.Viewport.Height = layout.viewportHeight
+	m.Viewport.Width = layout.viewportWidth
+	if atBottom {
+		m.Viewport.GotoBottom()
+	}
+	return layout
+}
+
+// layoutChat renders the parts of the chat screen around the transcript and
+// works out the size left for it
+func (m Model) layoutChat() chatLayout {
+	// Get terminal dimensions
+	width := m.ScreenWidth
+	height := m.ScreenHeight
+	if width <= 0 {
+		width = 80 // Default width if not set
 	}
-	m.Viewport.SetContent(content.String())
-	m.Viewport.GotoBottom()
+	if height <= 0 {
+		height = 24 // Default height if not set
+	}
+	if m.compact() {
+		return m.layoutCompact(width, height)
+	}
+
+	// Title section
+	title := fmt.Sprintf("Chat with %s", m.SelectedModel)
+	if len(m.Comparison) > 0 {
+		labels := make([]string, len(m.Comparison))
+		for i, pane := range m.Comparison {
+			labels[i] = pane.Label(m.SelectedProvider)
+		}
+		title = "Comparing " + strings.Join(labels, " vs ")
+	}
+	if m.Session.Title != "" {
+		title += " · " + m.Session.Title
+	}
+	titleView := TitleStyle.Render(title)
+	if tabs := m.TabBar(); tabs != "" {
+		titleView = lipgloss.JoinHorizontal(lipgloss.Center, titleView, " ", tabs)
+	}
+	titleHeight := lipgloss.Height(titleView) + 1 // +1 for the blank line below
+
+	// Input section (fixed at bottom)
+	inputStyle := InputBoxStyle.Copy().Width(width - 4)
+	if !m.ViewportFocused {
+		inputStyle = inputStyle.BorderForeground(CurrentTheme.Accent)
+	} else {
+		inputStyle = inputStyle.BorderForeground(CurrentTheme.Muted)
+	}
+	inputView := inputStyle.Render(m.Input.View())
+	inputHeight := lipgloss.Height(inputView)
+
+	// Slash command autocompletion popup (shown above the input)
+	popupView := m.SlashPopupView(width - 8)
+	if m.HistorySearching {
+		popupView = m.HistorySearchView(width - 8)
+	}
+	popupHeight := 0
+	if popupView != "" {
+		popupHeight = lipgloss.Height(popupView)
+	}
+
+	// Status bar (fixed at bottom)
+	statusView := StatusBarStyle.Copy().Width(width).Render(m.ChatStatusText())
+	statusHeight := lipgloss.Height(statusView)
+
+	// Loading indicator
+	var loadingView string
+	loadingHeight := 0
+	if m.State == StateLoading && m.IsGenerating {
+		loadingView = fmt.Sprintf("  %s %s", m.Spinner.View(), m.ProgressText())
+	}
+	// Point to the lines added below while scrolled up
+	if m.NewLines > 0 && len(m.Comparison) == 0 {
+		lines := "lines"
+		if m.NewLines == 1 {
+			lines = "line"
+		}
+		indicator := SelectedHeaderStyle.Render(fmt.Sprintf("%d new %s ↓ (End)", m.NewLines, lines))
+		if loadingView != "" {
+			loadingView += " · " + indicator
+		} else {
+			loadingView = "  " + indicator
+		}
+	}
+	if loadingView != "" {
+		loadingView = lipgloss.NewStyle().MaxWidth(width).Render(loadingView)
+		loadingHeight = 1
+	}
+
+	// Set viewport style, with a border while it is focused
+	viewportStyle := ResponseStyle.Copy()
+	if m.ViewportFocused {
+		viewportStyle = viewportStyle.Border(lipgloss.RoundedBorder()).BorderForeground(CurrentTheme.Accent)
+	}
+
+	// Calculate viewport height
+	// Available height = total height - (title + viewport border + loading + popup + input + status)
+	viewportHeight := height - titleHeight - viewportStyle.GetVerticalFrameSize() - loadingHeight - popupHeight - inputHeight - statusHeight
+	if viewportHeight < 1 {
+		viewportHeight = 1
+	}
+
+	return chatLayout{
+		width:          width,
+		viewportWidth:  width - viewportStyle.GetHorizontalFrameSize() - m.sidePaneWidth(),
+		height:         height,
+		title:          titleView,
+		input:          inputView,
+		popup:          popupView,
+		status:         statusView,
+		loading:        loadingView,
+		viewportHeight: viewportHeight,
+		viewportStyle:  viewportStyle,
+	}
+}
+
+// ChatStatusText returns the text of the chat screen's status bar
+func (m Model) ChatStatusText() string {
+	contextIndicator := ""
+	if APIClient.HasContext() {
+		contextIndicator = "🔄 Context Active | "
+	}
+	if m.Recorder != nil {
+		contextIndicator += m.RecordingIndicator()
+	}
+	if len(m.PendingAttachments) > 0 {
+		contextIndicator += fmt.Sprintf("📎 %d attached | ", len(m.PendingAttachments))
+	}
+	if m.PendingSchema != nil {
+		contextIndicator += "📐 Schema | "
+	} else if m.JSONFormat != nil {
+		contextIndicator += "{} JSON | "
+	}
+	if m.MultilineInput {
+		contextIndicator += fmt.Sprintf("%s: Send | ", m.KeyMap.Help(ActionSend))
+	}
+	if m.UpdateNotice != "" {
+		contextIndicator += "⬆ " + m.UpdateNotice + " | "
+	}
+	contextIndicator += m.BatchIndicator()
+	contextIndicator += m.TrimIndicator()
+	contextIndicator += m.RateLimitIndicator()
+	if m.SelectionMode {
+		contextIndicator += fmt.Sprintf("✂ Selecting (%s: done) | ", m.KeyMap.Help(ActionSelectionMode))
+	}
+	statusText := fmt.Sprintf(" %s | %s%s: Toggle focus | %s: New Chat | /help: Commands | %s: Exit ",
+		m.SelectedModel, contextIndicator,
+		m.KeyMap.Help(ActionToggleFocus), m.KeyMap.Help(ActionNewChat), m.KeyMap.Help(ActionQuit))
+	if m.StatusMessage != "" {
+		statusText = fmt.Sprintf(" %s | %s%s ", m.SelectedModel, contextIndicator, m.StatusMessage)
+	}
+	if m.Searching || m.SearchQuery != "" {
+		statusText = fmt.Sprintf(" %s | %s ", m.SelectedModel, m.SearchStatus())
+	}
+	return statusText
+}
+
+// NewConflictTable creates a keyboard-navigable table listing keybinding conflicts
+func NewConflictTable(conflicts []KeyConflict) table.Model {
+	rows := make([]table.Row, 0, len(conflicts))
+	for _, c := range conflicts {
+		suggestion := c.Suggestion
+		if suggestion == "" {
+			suggestion = "-"
+		}
+		rows = append(rows, table.Row{string(c.Action), c.Key, c.Reason, suggestion})
+	}
+
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Action", Width: 14},
+			{Title: "Key", Width: 10},
+			{Title: "Conflict", Width: 36},
+			{Title: "Suggested", Width: 10},
+		}),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(len(rows)+1),
+	)
+
+	t.SetStyles(TableStyles())
+
+	return t
 }
 
-// AddResponse adds a response to the list of responses
-func (m *Model) AddResponse(prompt, response string) {
-	m.Responses = append(m.Responses, fmt.Sprintf("Prompt: %s\n\nResponse:\n%s", prompt, response))
-	m.UpdateViewportContent()
+// SetKeyMap activates a keymap and advertises the keys of the provider and
+// model lists in their help lines
+func (m *Model) SetKeyMap(keyMap KeyMap) {
+	m.KeyMap = keyMap
+	sortKey := key.NewBinding(
+		key.WithKeys(keyMap.keys(ActionSortModels)...),
+		key.WithHelp(strings.Join(keyMap.keys(ActionSortModels), "/"), "sort"),
+	)
+	groupKey := key.NewBinding(
+		key.WithKeys(keyMap.keys(ActionGroupModels)...),
+		key.WithHelp(strings.Join(keyMap.keys(ActionGroupModels), "/"), "group"),
+	)
+	copyKey := key.NewBinding(
+		key.WithKeys(keyMap.keys(ActionCopyModel)...),
+		key.WithHelp(strings.Join(keyMap.keys(ActionCopyModel), "/"), "copy"),
+	)
+	renameKey := key.NewBinding(
+		key.WithKeys(keyMap.keys(ActionRenameModel)...),
+		key.WithHelp(strings.Join(keyMap.keys(ActionRenameModel), "/"), "rename"),
+	)
+	createKey := key.NewBinding(
+		key.WithKeys(keyMap.keys(ActionCreateModel)...),
+		key.WithHelp(strings.Join(keyMap.keys(ActionCreateModel), "/"), "new model"),
+	)
+	unloadKey := key.NewBinding(
+		key.WithKeys(keyMap.keys(ActionUnloadModel)...),
+		key.WithHelp(strings.Join(keyMap.keys(ActionUnloadModel), "/"), "unload model"),
+	)
+	registryKey := key.NewBinding(
+		key.WithKeys(keyMap.keys(ActionBrowseRegistry)...),
+		key.WithHelp(strings.Join(keyMap.keys(ActionBrowseRegistry), "/"), "browse registry"),
+	)
+	m.List.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{sortKey, groupKey, unloadKey, createKey, copyKey, renameKey, registryKey}
+	}
+	discoverKey := key.NewBinding(
+		key.WithKeys(keyMap.keys(ActionDiscover)...),
+		key.WithHelp(strings.Join(keyMap.keys(ActionDiscover), "/"), "find servers"),
+	)
+	keysKey := key.NewBinding(
+		key.WithKeys(keyMap.keys(ActionManageKeys)...),
+		key.WithHelp(strings.Join(keyMap.keys(ActionManageKeys), "/"), "API keys"),
+	)
+	m.ProviderList.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{discoverKey, keysKey}
+	}
 }
 
-// UpdateResponse updates the last response with new content
-func (m *Model) UpdateResponse(prompt, response string) {
-	if len(m.Responses) > 0 {
-		responseText := response
-		if m.ScreenWidth > 10 {
-			responseText = utils.WrapText(response, m.ScreenWidth-10)
+// ApplyPendingKeyMap activates the reviewed custom keymap, optionally
+// replacing conflicting keys with their suggested alternatives
+func (m *Model) ApplyPendingKeyMap(useSuggestions bool) {
+	if useSuggestions {
+		for _, c := range m.KeyConflicts {
+			if c.Suggestion != "" {
+				m.PendingKeyMap.Rebind(c.Action, c.Key, c.Suggestion)
+			}
 		}
-		m.Responses[len(m.Responses)-1] = fmt.Sprintf("Prompt: %s\n\nResponse:\n%s", prompt, responseText)
-		m.UpdateViewportContent()
 	}
+	m.SetKeyMap(m.PendingKeyMap)
+	m.KeyConflicts = nil
+	m.State = StateProviderSelect
 }
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+)
+
+// newTestModel returns a model chatting with llama3 on a screen of the given
+// size, with the config and data directories in a temporary directory and a
+// client without a conversation
+func newTestModel(t *testing.T, width, height int) Model {
+	t.Helper()
+	isolateTest(t)
+
+	m := NewModel()
+	m.State = StatePrompting
+	m.SelectedModel = "llama3"
+	m.ScreenWidth, m.ScreenHeight = width, height
+	return m
+}
+
+// isolateTest gives a test a client without a conversation and moves the
+// config and data directories to a temporary directory
+func isolateTest(t *testing.T) {
+	t.Helper()
+	client := APIClient
+	APIClient = api.NewClient("ollama", "")
+	t.Cleanup(func() { APIClient = client })
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir+"/config")
+	t.Setenv("XDG_DATA_HOME", dir+"/data")
+	t.Setenv("XDG_CACHE_HOME", dir+"/cache")
+}
+
+func TestApplyLayout(t *testing.T) {
+	longStatus := strings.Repeat("word ", 60)
+	tests := []struct {
+		name          string
+		width, height int
+		sidePane      bool
+		status        string
+		inputLines    int
+		// The transcript's size and the height of the input box's text
+		viewportWidth, viewportHeight, inputHeight int
+	}{
+		{name: "compact", width: 30, height: 12, viewportWidth: 30, viewportHeight: 10, inputHeight: 1},
+		{name: "compact ignores the info pane", width: 30, height: 12, sidePane: true, viewportWidth: 30, viewportHeight: 10, inputHeight: 1},
+		{name: "compact keeps one input line", width: 30, height: 12, inputLines: 6, viewportWidth: 30, viewportHeight: 10, inputHeight: 1},
+		{name: "80x24", width: 80, height: 24, viewportWidth: 76, viewportHeight: 16, inputHeight: 3},
+		{name: "80x24 too narrow for the info pane", width: 80, height: 24, sidePane: true, viewportWidth: 76, viewportHeight: 16, inputHeight: 3},
+		{name: "80x24 wrapped status bar", width: 80, height: 24, status: longStatus, viewportWidth: 76, viewportHeight: 13, inputHeight: 3},
+		{name: "80x24 grown input", width: 80, height: 24, inputLines: 6, viewportWidth: 76, viewportHeight: 13, inputHeight: 6},
+		{name: "120x40", width: 120, height: 40, viewportWidth: 116, viewportHeight: 32, inputHeight: 3},
+		{name: "120x40 info pane", width: 120, height: 40, sidePane: true, viewportWidth: 80, viewportHeight: 32, inputHeight: 3},
+		{name: "120x40 info pane and wrapped status bar", width: 120, height: 40, sidePane: true, status: longStatus, viewportWidth: 80, viewportHeight: 30, inputHeight: 3},
+		{name: "200x60 input capped", width: 200, height: 60, inputLines: 20, viewportWidth: 196, viewportHeight: 45, inputHeight: DefaultMaxInputLines},
+		{name: "200x60 info pane and wrapped status bar", width: 200, height: 60, sidePane: true, status: longStatus, viewportWidth: 160, viewportHeight: 51, inputHeight: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, tt.width, tt.height)
+			m.ShowSidePane = tt.sidePane
+			m.StatusMessage = tt.status
+			if tt.inputLines > 0 {
+				m.Input.SetValue(strings.Repeat("line\n", tt.inputLines-1) + "line")
+			}
+			m.ResizeInput()
+			m.ApplyLayout()
+
+			if m.Viewport.Width != tt.viewportWidth || m.Viewport.Height != tt.viewportHeight {
+				t.Errorf("viewport is %dx%d, want %dx%d", m.Viewport.Width, m.Viewport.Height, tt.viewportWidth, tt.viewportHeight)
+			}
+			if m.Input.Height() != tt.inputHeight {
+				t.Errorf("input is %d lines high, want %d", m.Input.Height(), tt.inputHeight)
+			}
+			// The screen drawn with these sizes fills the terminal exactly
+			view := m.View()
+			if h, w := lipgloss.Height(view), lipgloss.Width(view); h != tt.height || w != tt.width {
+				t.Errorf("view is %dx%d, want %dx%d", w, h, tt.width, tt.height)
+			}
+		})
+	}
+}
+
+func TestApplyLayoutOnResize(t *testing.T) {
+	m := newTestModel(t, 120, 40)
+	m.ApplyLayout()
+	m.ScreenWidth, m.ScreenHeight = 80, 24
+	m.ResizeInput()
+	m.ApplyLayout()
+	if m.Viewport.Width != 76 || m.Viewport.Height != 16 {
+		t.Errorf("after shrinking, viewport is %dx%d, want 76x16", m.Viewport.Width, m.Viewport.Height)
+	}
+
+	// Going compact and back restores the input box
+	m.ScreenWidth, m.ScreenHeight = 30, 12
+	m.ResizeInput()
+	m.ScreenWidth, m.ScreenHeight = 80, 24
+	m.ResizeInput()
+	m.ApplyLayout()
+	if m.Input.Height() != MinInputLines || m.Viewport.Height != 16 {
+		t.Errorf("after compact, input is %d lines and viewport %d, want %d and 16", m.Input.Height(), m.Viewport.Height, MinInputLines)
+	}
+}
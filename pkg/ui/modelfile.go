@@ -0,0 +1,135 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+)
+
+// CreateChan is a channel for progress updates while a model is being created
+var CreateChan = make(chan CreateProgressMsg, 100)
+
+// modelNamePrefix marks the Modelfile comment holding the new model's name
+const modelNamePrefix = "# name:"
+
+// ModelfileTemplate returns a Modelfile deriving a model from base, prefilled
+// with the current system prompt and temperature
+func ModelfileTemplate(name, base string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s\n", modelNamePrefix, name))
+	sb.WriteString("# Edit the instructions below, save and quit to create the model.\n")
+	sb.WriteString("# Leave the file empty to cancel.\n\n")
+	sb.WriteString(fmt.Sprintf("FROM %s\n\n", base))
+
+	system := APIClient.SystemPrompt
+	if system == "" {
+		system = "You are a helpful assistant."
+	}
+	sb.WriteString(fmt.Sprintf("SYSTEM \"\"\"%s\"\"\"\n\n", system))
+
+	if APIClient.Temperature != nil {
+		sb.WriteString(fmt.Sprintf("PARAMETER temperature %g\n", *APIClient.Temperature))
+	} else {
+		sb.WriteString("# PARAMETER temperature 0.7\n")
+	}
+	sb.WriteString("# PARAMETER num_ctx 4096\n")
+	sb.WriteString("# PARAMETER stop \"<|end|>\"\n")
+	return sb.String()
+}
+
+// EditModelfileCmd opens the Modelfile template in the user's editor
+func EditModelfileCmd(name, base string) tea.Cmd {
+	return editFileCmd(ModelfileTemplate(name, base), "Modelfile-*", func(content string, err error) tea.Msg {
+		return ModelfileEditedMsg{Name: name, Content: content, Err: err}
+	})
+}
+
+// ModelfileName returns the model name from the Modelfile's name comment,
+// falling back to name when it was removed
+func ModelfileName(content, name string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), modelNamePrefix); ok {
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+	}
+	return name
+}
+
+// CreateModelCmd creates the model in the background, streaming its progress
+// into CreateChan
+func CreateModelCmd(name, modelfile string) tea.Cmd {
+	req, err := api.ParseModelfile(name, modelfile)
+	if err != nil {
+		return func() tea.Msg { return CreateProgressMsg{Model: name, Done: true, Err: err} }
+	}
+
+	client := APIClient
+	go func() {
+		err := client.CreateModel(context.Background(), req, func(status string) {
+			CreateChan <- CreateProgressMsg{Model: name, Status: status}
+		})
+		CreateChan <- CreateProgressMsg{Model: name, Done: true, Err: err}
+	}()
+	return ListenForCreateProgressCmd()
+}
+
+// ListenForCreateProgressCmd waits for the next model creation update
+func ListenForCreateProgressCmd() tea.Cmd {
+	return func() tea.Msg {
+		return <-CreateChan
+	}
+}
+
+// RefreshModelsCmd reloads the model list with the current client, or
+// from every provider when the list is merged
+func (m Model) RefreshModelsCmd() tea.Cmd {
+	if m.AllProviders {
+		return FetchAllModelsCmd()
+	}
+	return func() tea.Msg {
+		models, err := APIClient.FetchModels()
+		if err != nil {
+			return ErrorMsg{Err: err}
+		}
+		return FetchModelsMsg{Models: models}
+	}
+}
+
+// SetModelStatus reports progress on the current screen
+func (m *Model) SetModelStatus(status string) {
+	if m.State == StateModelSelect {
+		m.List.Title = "Available models · " + status
+		return
+	}
+	m.StatusMessage = status
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "create",
+		Usage:       "<name> [base]",
+		Description: "Create a custom Ollama model from a Modelfile",
+		Run: func(m *Model, args string) tea.Cmd {
+			if m.SelectedProvider != "ollama" {
+				m.StatusMessage = "Creating models is only available for Ollama"
+				return nil
+			}
+			fields := strings.Fields(args)
+			if len(fields) == 0 {
+				m.StatusMessage = "Usage: /create <name> [base model]"
+				return nil
+			}
+			base := m.SelectedModel
+			if len(fields) > 1 {
+				base = fields[1]
+			}
+			return EditModelfileCmd(fields[0], base)
+		},
+	})
+}
@@ -0,0 +1,527 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// ModelSort is the order of the model selection list
+type ModelSort int
+
+const (
+	// SortDefault keeps the order reported by the provider
+	SortDefault ModelSort = iota
+	// SortName orders models alphabetically
+	SortName
+	// SortSize lists the largest models first
+	SortSize
+	// SortFamily groups models of the same family together
+	SortFamily
+	// SortRecent lists the most recently used models first
+	SortRecent
+)
+
+// modelSortNames are the labels and config values of the sort modes
+var modelSortNames = []string{"default", "name", "size", "family", "recent"}
+
+// String returns the name of the sort mode
+func (s ModelSort) String() string {
+	if int(s) < len(modelSortNames) {
+		return modelSortNames[s]
+	}
+	return modelSortNames[0]
+}
+
+// ParseModelSort returns the sort mode with the given name
+func ParseModelSort(name string) ModelSort {
+	for i, n := range modelSortNames {
+		if n == name {
+			return ModelSort(i)
+		}
+	}
+	return SortDefault
+}
+
+// groupHeader separates model families in the grouped model list
+type groupHeader struct {
+	name  string
+	count int
+}
+
+// Title returns the group name
+func (h groupHeader) Title() string {
+	if plainText {
+		return h.name + ":"
+	}
+	return "── " + h.name + " ──"
+}
+
+// Description returns the number of models in the group
+func (h groupHeader) Description() string { return fmt.Sprintf("%d models", h.count) }
+
+// FilterValue is empty so headers are hidden while filtering
+func (h groupHeader) FilterValue() string { return "" }
+
+// SortModels returns the models in the chosen order
+func SortModels(list []models.Model, mode ModelSort, recent []string) []models.Model {
+	sorted := append([]models.Model(nil), list...)
+
+	rank := map[string]int{}
+	for i, name := range recent {
+		rank[name] = i + 1
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch mode {
+		case SortName:
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		case SortSize:
+			return a.Size > b.Size
+		case SortFamily:
+			if a.Details.Family != b.Details.Family {
+				return strings.ToLower(a.Details.Family) < strings.ToLower(b.Details.Family)
+			}
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		case SortRecent:
+			ra, rb := rank[a.Name], rank[b.Name]
+			if ra == 0 || rb == 0 {
+				return ra != 0
+			}
+			return ra < rb
+		}
+		return false
+	})
+	return sorted
+}
+
+// RefreshModelItems rebuilds the model list in the chosen order, marking
+// models that are loaded in memory and optionally grouping them by family
+func (m *Model) RefreshModelItems() {
+	running := map[string]models.RunningModel{}
+	for _, r := range m.RunningModels {
+		running[r.Name] = r
+	}
+
+	sorted := SortModels(m.Models, m.ModelSort, m.RecentModels)
+
+	// Favorites and recently used models get their own sections at the top
+	var favorites, recent, rest []models.Model
+	recentRank := map[string]bool{}
+	for i, name := range m.RecentModels {
+		if i < RecentSectionSize {
+			recentRank[name] = true
+		}
+	}
+	for _, model := range sorted {
+		switch {
+		case m.IsFavorite(model.Name):
+			favorites = append(favorites, model)
+		case recentRank[model.Name]:
+			recent = append(recent, model)
+		default:
+			rest = append(rest, model)
+		}
+	}
+	recent = SortModels(recent, SortRecent, m.RecentModels)
+	hasSections := len(favorites) > 0 || len(recent) > 0
+	sorted = rest
+
+	families := map[string]int{}
+	if m.GroupModels {
+		// Grouping keeps the chosen order within each family
+		order := []string{}
+		for _, model := range sorted {
+			if _, ok := families[familyName(model)]; !ok {
+				order = append(order, familyName(model))
+			}
+			families[familyName(model)]++
+		}
+		sort.Strings(order)
+		grouped := make([]models.Model, 0, len(sorted))
+		for _, family := range order {
+			for _, model := range sorted {
+				if familyName(model) == family {
+					grouped = append(grouped, model)
+				}
+			}
+		}
+		sorted = grouped
+	}
+
+	items := make([]list.Item, 0, len(m.Models))
+	item := func(model models.Model) list.Item {
+		details := ModelDetails(model)
+		if m.IsFavorite(model.Name) {
+			details = "★ " + details
+		}
+		if r, ok := running[model.Name]; ok && r.SizeVRAM > 0 {
+			details += fmt.Sprintf(" · ● loaded (%s VRAM)", utils.FormatBytes(r.SizeVRAM))
+		} else if ok {
+			details += " · ● loaded"
+		}
+		return models.ListItem{Name: model.Name, Details: details, Provider: model.Provider}
+	}
+	if len(favorites) > 0 {
+		items = append(items, groupHeader{name: "★ Favorites", count: len(favorites)})
+		for _, model := range favorites {
+			items = append(items, item(model))
+		}
+	}
+	if len(recent) > 0 {
+		items = append(items, groupHeader{name: "Recent", count: len(recent)})
+		for _, model := range recent {
+			items = append(items, item(model))
+		}
+	}
+	if hasSections && !m.GroupModels && len(sorted) > 0 {
+		items = append(items, groupHeader{name: "All models", count: len(sorted)})
+	}
+
+	lastFamily := ""
+	for i, model := range sorted {
+		if m.GroupModels && (i == 0 || familyName(model) != lastFamily) {
+			lastFamily = familyName(model)
+			items = append(items, groupHeader{name: lastFamily, count: families[lastFamily]})
+		}
+		items = append(items, item(model))
+	}
+	if m.SelectedProvider == "ollama" {
+		items = append(items, registryEntry{})
+	}
+	m.List.SetItems(items)
+}
+
+// familyName returns the model's family for grouping
+func familyName(model models.Model) string {
+	if model.Details.Family == "" {
+		return "other"
+	}
+	return model.Details.Family
+}
+
+// CycleModelSort switches to the next sort mode and remembers it
+func (m *Model) CycleModelSort() {
+	m.ModelSort = (m.ModelSort + 1) % ModelSort(len(modelSortNames))
+	m.RefreshModelItems()
+	m.List.Select(0)
+	mode := m.ModelSort.String()
+	if err := utils.UpdateConfig(func(c *utils.Config) { c.ModelSort = mode }); err != nil {
+		m.Err = err
+	}
+}
+
+// ToggleModelGrouping shows or hides the family headers and remembers the choice
+func (m *Model) ToggleModelGrouping() {
+	m.GroupModels = !m.GroupModels
+	m.RefreshModelItems()
+	m.List.Select(0)
+	group := m.GroupModels
+	if err := utils.UpdateConfig(func(c *utils.Config) { c.GroupModels = group }); err != nil {
+		m.Err = err
+	}
+}
+
+// RecordModelUse moves a model to the front of the recently used list
+func (m *Model) RecordModelUse(name string) {
+	recent := []string{name}
+	for _, r := range m.RecentModels {
+		if r != name && len(recent) < MaxRecentModels {
+			recent = append(recent, r)
+		}
+	}
+	m.RecentModels = recent
+	m.LastProvider = m.SelectedProvider
+	provider := m.SelectedProvider
+	if err := utils.UpdateConfig(func(c *utils.Config) {
+		c.RecentModels = recent
+		c.LastProvider = provider
+	}); err != nil {
+		m.Err = err
+	}
+}
+
+// IsFavorite reports whether a model is pinned as a favorite
+func (m Model) IsFavorite(name string) bool {
+	for _, f := range m.FavoriteModels {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleFavorite pins or unpins a model at the top of the model list
+func (m *Model) ToggleFavorite(name string) {
+	favorites := make([]string, 0, len(m.FavoriteModels)+1)
+	for _, f := range m.FavoriteModels {
+		if f != name {
+			favorites = append(favorites, f)
+		}
+	}
+	if len(favorites) == len(m.FavoriteModels) {
+		favorites = append(favorites, name)
+	}
+	m.FavoriteModels = favorites
+	m.RefreshModelItems()
+	if err := utils.UpdateConfig(func(c *utils.Config) { c.FavoriteModels = favorites }); err != nil {
+		m.Err = err
+	}
+}
+
+// ResumeLastModel skips provider and model selection and opens a chat with
+// the most recently used model. It reports false if there is none to resume.
+func (m *Model) ResumeLastModel() bool {
+	if len(m.RecentModels) == 0 {
+		return false
+	}
+	return m.StartWithModel(m.LastProvider, m.RecentModels[0])
+}
+
+// StartWithModel skips provider selection and, when model is set, model
+// selection too. It reports false if the provider is unknown, needs an API
+// key that is not configured, or the key binding conflicts still need review.
+func (m *Model) StartWithModel(provider, model string) bool {
+	if m.State == StateKeyConflicts || (!IsProvider(provider)) {
+		return false
+	}
+
+	apiKey := ProviderAPIKey(provider)
+	if KeyRequired(provider) && apiKey == "" {
+		return false
+	}
+
+	APIClient = api.NewClient(provider, apiKey)
+	APIClient.KeepAlive = m.KeepAlive
+	APIClient.SafePrompt = m.SafePrompt
+	m.ApplyRequestOptions(APIClient, provider)
+	m.SelectedProvider = provider
+	m.SelectedModel = model
+	m.State = StateModelSelect
+	if model != "" {
+		m.State = StatePrompting
+	}
+	return true
+}
+
+// OpenModelPicker returns to the model list with the current model highlighted
+func (m *Model) OpenModelPicker() tea.Cmd {
+	for i, item := range m.List.Items() {
+		if li, ok := item.(models.ListItem); ok && li.Name == m.SelectedModel {
+			m.List.Select(i)
+			break
+		}
+	}
+	m.State = StateModelSelect
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// ModelDetails describes a model's family, parameter count, quantization
+// and size on disk, skipping anything the provider does not report
+func ModelDetails(model models.Model) string {
+	parts := []string{fmt.Sprintf("Family: %s", model.Details.Family)}
+	if model.Details.ParameterSize != "" {
+		parts = append(parts, model.Details.ParameterSize+" params")
+	}
+	if model.Details.QuantizationLevel != "" {
+		parts = append(parts, model.Details.QuantizationLevel)
+	}
+	if model.Details.Context > 0 {
+		parts = append(parts, fmt.Sprintf("Context: %d", model.Details.Context))
+	}
+	if model.Size > 0 {
+		parts = append(parts, utils.FormatBytes(model.Size))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ModelListFooter summarizes the disk usage of the listed models
+func (m Model) ModelListFooter() string {
+	var total int64
+	for _, model := range m.Models {
+		total += model.Size
+	}
+
+	order := "sorted by " + m.ModelSort.String()
+	if m.GroupModels {
+		order += ", grouped by family"
+	}
+	footer := fmt.Sprintf("%d models · %s", len(m.Models), order)
+	if total > 0 {
+		footer = fmt.Sprintf("%d models · %s on disk · %s", len(m.Models), utils.FormatBytes(total), order)
+	}
+	return HelpStyle.Render(footer)
+}
+
+// FetchRunningModelsCmd fetches the models Ollama currently has loaded
+func FetchRunningModelsCmd() tea.Cmd {
+	return func() tea.Msg {
+		running, err := APIClient.RunningModels()
+		if err != nil {
+			return RunningModelsMsg{Err: err}
+		}
+		return RunningModelsMsg{Models: running}
+	}
+}
+
+// UnloadModelCmd unloads a model to free memory and reports the result
+func UnloadModelCmd(model string) tea.Cmd {
+	return func() tea.Msg {
+		return ModelUnloadedMsg{Model: model, Err: APIClient.UnloadModel(model)}
+	}
+}
+
+// ValidKeepAlive reports whether value is a keep_alive setting Ollama accepts:
+// a number of seconds or a duration such as "5m"
+func ValidKeepAlive(value string) bool {
+	if _, err := strconv.Atoi(value); err == nil {
+		return true
+	}
+	_, err := time.ParseDuration(value)
+	return err == nil
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "keepalive",
+		Usage:       "<duration>",
+		Description: "Set how long Ollama keeps the model loaded (5m, 0 to unload, -1 forever)",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				current := APIClient.KeepAlive
+				if current == "" {
+					current = "Ollama default"
+				}
+				m.StatusMessage = fmt.Sprintf("Keep alive: %s", current)
+				return nil
+			}
+			if !ValidKeepAlive(args) {
+				m.StatusMessage = fmt.Sprintf("Invalid keep alive %q: expected a duration like 5m, 0 or -1", args)
+				return nil
+			}
+			APIClient.KeepAlive = args
+			m.KeepAlive = args
+			if err := utils.UpdateConfig(func(c *utils.Config) { c.KeepAlive = args }); err != nil {
+				m.Err = err
+			}
+			m.StatusMessage = fmt.Sprintf("Keep alive set to %s", args)
+			return nil
+		},
+	})
+}
+
+// StartModelOp asks for the destination name to copy or rename the
+// highlighted model to
+func (m *Model) StartModelOp(op string) tea.Cmd {
+	item, ok := m.List.SelectedItem().(models.ListItem)
+	if !ok {
+		return nil
+	}
+
+	suggestion := item.Name
+	if op == "copy" {
+		base, tag, _ := strings.Cut(item.Name, ":")
+		suggestion = base + "-copy"
+		if tag != "" {
+			suggestion += ":" + tag
+		}
+	}
+
+	m.ModelOp = op
+	m.ModelOpSource = item.Name
+	m.ModelOpConfirm = false
+	m.ModelNameInput.SetValue(suggestion)
+	m.ModelNameInput.CursorEnd()
+	m.ModelNameInput.Focus()
+	return RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight)
+}
+
+// UpdateModelOp handles keys while the copy/rename prompt is open
+func (m Model) UpdateModelOp(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	if m.ModelOpConfirm {
+		switch msg.String() {
+		case "y", "Y", "enter":
+			source, destination, op := m.ModelOpSource, strings.TrimSpace(m.ModelNameInput.Value()), m.ModelOp
+			m.ModelOp = ""
+			m.List.Title = fmt.Sprintf("Available models · %s %s…", opVerb(op), source)
+			return m, tea.Batch(ModelOpCmd(op, source, destination), RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+		case "n", "N", "esc":
+			m.ModelOpConfirm = false
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.ModelOp = ""
+		return m, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight)
+	case "enter":
+		name := strings.TrimSpace(m.ModelNameInput.Value())
+		if name != "" && name != m.ModelOpSource {
+			m.ModelOpConfirm = true
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.ModelNameInput, cmd = m.ModelNameInput.Update(msg)
+	return m, cmd
+}
+
+// ModelOpView renders the copy/rename prompt below the model list
+func (m Model) ModelOpView(width int) string {
+	destination := strings.TrimSpace(m.ModelNameInput.Value())
+	content := fmt.Sprintf("%s %s to: %s", FormatKey(m.ModelOp), m.ModelOpSource, m.ModelNameInput.View())
+	if m.ModelOpConfirm {
+		content = fmt.Sprintf("%s %s to %s?", FormatKey(m.ModelOp), m.ModelOpSource, destination)
+		if m.hasModel(destination) {
+			content += " The existing model will be overwritten."
+		}
+		content += " (y/n)"
+	}
+	return InputBoxStyle.Copy().Width(width - 4).Render(content)
+}
+
+// hasModel reports whether a model with the given name is in the list
+func (m Model) hasModel(name string) bool {
+	for _, model := range m.Models {
+		if model.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelOpCmd copies or renames a model in the background
+func ModelOpCmd(op, source, destination string) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		if op == "rename" {
+			err = APIClient.RenameModel(source, destination)
+		} else {
+			err = APIClient.CopyModel(source, destination)
+		}
+		return ModelOpMsg{Op: op, Source: source, Destination: destination, Err: err}
+	}
+}
+
+func opVerb(op string) string {
+	if op == "rename" {
+		return "renaming"
+	}
+	return "copying"
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// mouseWheelLines is how far a turn of the mouse wheel scrolls comparison panes
+const mouseWheelLines = 3
+
+// SetFocus moves the keyboard focus to the transcript or to the input box
+func (m *Model) SetFocus(viewport bool) {
+	m.ViewportFocused = viewport
+	if viewport {
+		m.Input.Blur()
+	} else {
+		m.Input.Focus()
+	}
+	// The border of the focused transcript takes two lines
+	m.ApplyLayout()
+}
+
+// inputTop returns the screen row of the top border of the input box, below
+// the title, the transcript and the lines shown above the input
+func (m Model) inputTop() int {
+	layout := m.layoutChat()
+	top := layout.viewportHeight + layout.viewportStyle.GetVerticalFrameSize()
+	if layout.title != "" {
+		top += lipgloss.Height(layout.title) + 1
+	}
+	for _, part := range []string{layout.loading, layout.popup} {
+		if part != "" {
+			top += lipgloss.Height(part)
+		}
+	}
+	return top
+}
+
+// HandleMouse scrolls the transcript, or every comparison pane, with the
+// wheel, and moves the focus to the input box or the transcript clicked on
+func (m *Model) HandleMouse(msg tea.MouseMsg) tea.Cmd {
+	if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+		top := m.inputTop()
+		switch {
+		case msg.Y >= top:
+			m.SetFocus(false)
+		case len(m.Comparison) == 0:
+			m.SetFocus(true)
+		}
+		return nil
+	}
+
+	if len(m.Comparison) > 0 && msg.Action == tea.MouseActionPress {
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			m.ScrollComparison(mouseWheelLines)
+		case tea.MouseButtonWheelDown:
+			m.ScrollComparison(-mouseWheelLines)
+		}
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.Viewport, cmd = m.Viewport.Update(msg)
+	return cmd
+}
+
+// ToggleSelectionMode stops or resumes capturing the mouse. While the mouse
+// isn't captured the terminal selects text with it as usual.
+func (m *Model) ToggleSelectionMode() tea.Cmd {
+	if m.Accessible {
+		m.StatusMessage = "The mouse isn't captured in accessible mode"
+		return nil
+	}
+	m.SelectionMode = !m.SelectionMode
+	if m.SelectionMode {
+		m.StatusMessage = fmt.Sprintf("Select text with the mouse; %s to scroll and click again", m.KeyMap.Help(ActionSelectionMode))
+		return tea.DisableMouse
+	}
+	m.StatusMessage = "Mouse scrolling and clicks are back"
+	return tea.EnableMouseCellMotion
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// notificationLength is how much of a response a notification shows
+const notificationLength = 100
+
+// ShouldNotify reports whether a generation that took elapsed finished
+// while nobody was watching: the terminal is unfocused, or it took longer
+// than NotifyAfter
+func (m Model) ShouldNotify(elapsed time.Duration) bool {
+	if m.NotifyMethod == utils.NotifyOff && !m.NotifyBell {
+		return false
+	}
+	return !m.TerminalFocused || (m.NotifyAfter > 0 && elapsed >= m.NotifyAfter)
+}
+
+// NotifyResponseCmd notifies that the latest response finished, quoting its
+// first line
+func (m Model) NotifyResponseCmd(timedOut bool) tea.Cmd {
+	elapsed := time.Since(m.GenerationStart)
+	if !m.ShouldNotify(elapsed) {
+		return nil
+	}
+
+	title := fmt.Sprintf("%s finished in %s", m.SelectedModel, elapsed.Round(time.Second))
+	if timedOut {
+		title = fmt.Sprintf("%s reached the time limit", m.SelectedModel)
+	}
+	return m.NotifyCmd(title, firstLine(models.StripReasoning(m.InProgressResponse)))
+}
+
+// NotifyCmd shows a notification with the configured method
+func (m Model) NotifyCmd(title, body string) tea.Cmd {
+	method, bell := m.NotifyMethod, m.NotifyBell
+	return func() tea.Msg {
+		return NotificationMsg{Err: utils.Notify(os.Stdout, method, title, body, bell)}
+	}
+}
+
+// firstLine returns the first non-empty line of text, shortened for a
+// notification
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return truncate(line, notificationLength)
+		}
+	}
+	return ""
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "notify",
+		Description: "Send a test notification to check how finished responses are announced",
+		Run: func(m *Model, args string) tea.Cmd {
+			if m.NotifyMethod == utils.NotifyOff && !m.NotifyBell {
+				m.StatusMessage = "Notifications are off: set \"notifications\" in config.json"
+				return nil
+			}
+			m.StatusMessage = fmt.Sprintf("Sent a test notification (%s)", m.NotifyMethod)
+			return m.NotifyCmd("ollama-tui", "Notifications are working")
+		},
+	})
+}
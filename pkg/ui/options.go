@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+)
+
+// ApplyRequestOptions gives the client the stop sequences, seed, the
+// options of its provider and the user's memories, pinning the temperature
+// and seed in deterministic mode
+func (m Model) ApplyRequestOptions(client *api.Client, provider string) {
+	client.Stop = m.Stop
+	client.Seed = m.Seed
+	client.Options = m.ProviderOptions[provider]
+	client.RecordRequests = m.Deterministic
+	client.Memories = m.memoryTexts()
+	if m.Deterministic {
+		temperature := 0.0
+		client.Temperature = &temperature
+		if client.Seed == nil {
+			seed := DeterministicSeed
+			client.Seed = &seed
+		}
+	}
+}
+
+// ParseStopSequences splits the arguments of /stop on "|", reading escapes
+// such as \n in each sequence
+func ParseStopSequences(args string) ([]string, error) {
+	var stop []string
+	for _, part := range strings.Split(args, "|") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		sequence, err := strconv.Unquote(`"` + strings.ReplaceAll(part, `"`, `\"`) + `"`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stop sequence %q", part)
+		}
+		stop = append(stop, sequence)
+	}
+	return stop, nil
+}
+
+// ParseOptionValue reads the value of /option as JSON, such as 8192, true
+// or ["a", "b"], or as a plain string otherwise
+func ParseOptionValue(value string) interface{} {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err == nil {
+		return parsed
+	}
+	return value
+}
+
+// SetProviderOption sets an option passed through to the requests of the
+// current provider, removing it when value is nil
+func (m *Model) SetProviderOption(key string, value interface{}) {
+	provider := m.SelectedProvider
+	// The maps are copied, as clients streaming in the background hold them
+	options := make(map[string]interface{}, len(m.ProviderOptions[provider])+1)
+	for k, v := range m.ProviderOptions[provider] {
+		options[k] = v
+	}
+	if value == nil {
+		delete(options, key)
+	} else {
+		options[key] = value
+	}
+
+	all := make(map[string]map[string]interface{}, len(m.ProviderOptions)+1)
+	for p, o := range m.ProviderOptions {
+		all[p] = o
+	}
+	all[provider] = options
+	m.ProviderOptions = all
+	m.ApplyRequestOptions(APIClient, provider)
+}
+
+// FormatOptions lists options as key=value pairs in key order
+func FormatOptions(options map[string]interface{}) string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value, _ := json.Marshal(options[key])
+		pairs = append(pairs, key+"="+string(value))
+	}
+	return strings.Join(pairs, " ")
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "stop",
+		Usage:       "<sequence> [| sequence ...]",
+		Description: "End responses at these sequences, e.g. \\n\\n | ### (empty to clear)",
+		Run: func(m *Model, args string) tea.Cmd {
+			stop, err := ParseStopSequences(args)
+			if err != nil {
+				m.StatusMessage = err.Error()
+				return nil
+			}
+			m.Stop = stop
+			m.ApplyRequestOptions(APIClient, m.SelectedProvider)
+			if len(stop) == 0 {
+				m.StatusMessage = "Stop sequences cleared"
+				return nil
+			}
+			quoted := make([]string, len(stop))
+			for i, sequence := range stop {
+				quoted[i] = strconv.Quote(sequence)
+			}
+			m.StatusMessage = "Stop sequences: " + strings.Join(quoted, ", ")
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "seed",
+		Usage:       "<number>",
+		Description: "Make sampling repeatable with a fixed seed (empty to clear)",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				m.Seed = nil
+				m.ApplyRequestOptions(APIClient, m.SelectedProvider)
+				m.StatusMessage = "Seed cleared"
+				return nil
+			}
+			seed, err := strconv.Atoi(args)
+			if err != nil {
+				m.StatusMessage = fmt.Sprintf("Invalid seed %q: expected a whole number", args)
+				return nil
+			}
+			m.Seed = &seed
+			m.ApplyRequestOptions(APIClient, m.SelectedProvider)
+			m.StatusMessage = fmt.Sprintf("Seed set to %d", seed)
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "option",
+		Usage:       "[key=value]",
+		Description: "Pass an option through to the provider, e.g. num_ctx=8192 (key= to remove, empty to list)",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				options := m.ProviderOptions[m.SelectedProvider]
+				if len(options) == 0 {
+					m.StatusMessage = fmt.Sprintf("No options for %s; usage: /option <key>=<value>", m.SelectedProvider)
+					return nil
+				}
+				m.StatusMessage = fmt.Sprintf("Options for %s: %s", m.SelectedProvider, FormatOptions(options))
+				return nil
+			}
+			key, value, ok := strings.Cut(args, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				m.StatusMessage = "Usage: /option <key>=<value>, or /option <key>= to remove it"
+				return nil
+			}
+			value = strings.TrimSpace(value)
+			if value == "" {
+				m.SetProviderOption(key, nil)
+				m.StatusMessage = fmt.Sprintf("Option %s removed", key)
+				return nil
+			}
+			m.SetProviderOption(key, ParseOptionValue(value))
+			m.StatusMessage = fmt.Sprintf("Option %s set for %s", FormatOptions(map[string]interface{}{key: ParseOptionValue(value)}), m.SelectedProvider)
+			return nil
+		},
+	})
+}
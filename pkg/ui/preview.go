@@ -0,0 +1,223 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// ContextPreview is what sending a prompt would send, assembled without
+// calling the provider
+type ContextPreview struct {
+	Request     api.RequestPreview
+	Provider    string
+	Model       string
+	Attachments []models.Attachment
+	// Trim says what would be left out of the conversation for the prompt
+	// to fit, when anything would
+	Trim string
+	// Window is the context window of the model, when known
+	Window int
+}
+
+// Tokens estimates the tokens the request takes up; images are not counted
+func (p ContextPreview) Tokens() int {
+	total := utils.EstimateTokens(p.Request.System) + utils.EstimateTokens(p.Request.Prompt) + p.Request.ContextTokens
+	for _, msg := range p.Request.History {
+		total += utils.EstimateTokens(msg.Content)
+	}
+	return total
+}
+
+// PreviewContext assembles the request prompt would be sent with: the
+// attachments it references expanded into it, the system prompt, and the
+// conversation trimmed the way sending it would trim it
+func (m Model) PreviewContext(prompt string) (ContextPreview, error) {
+	attachments, err := m.CollectAttachments(prompt)
+	if err != nil {
+		return ContextPreview{}, err
+	}
+	requestPrompt := BuildPrompt(prompt, attachments)
+
+	// A schema attached to this prompt takes precedence over JSON mode
+	format := m.JSONFormat
+	if m.PendingSchema != nil {
+		format = m.PendingSchema
+	}
+
+	// Work on a copy so that nothing changes the conversation
+	client := APIClient.NewConversation()
+	client.Format = format
+	client.Images = AttachmentImages(attachments)
+
+	preview := ContextPreview{
+		Provider:    m.SelectedProvider,
+		Model:       m.SelectedModel,
+		Attachments: attachments,
+		Window:      m.contextWindow(),
+	}
+	n := len(m.Messages)
+	trim, strategy, ok := m.plannedTrim(n, requestPrompt)
+	switch {
+	case !ok:
+		client.RestoreConversation(APIClient.Conversation())
+	case strategy == ContextStrategySummarize:
+		trim.Summary = fmt.Sprintf("(the summary of messages %d-%d the model would write first)", trim.From+1, trim.To)
+		client.SetHistory(m.historyWith(trim, n))
+		preview.Trim = fmt.Sprintf("Near the context limit: messages %d-%d would be summarized first, in a request of their own", trim.From+1, trim.To)
+	default:
+		client.SetHistory(m.historyWith(trim, n))
+		preview.Trim = fmt.Sprintf("Near the context limit: the %d oldest messages would be left out (%s)", trim.Dropped(), strategy)
+	}
+	preview.Request = client.Preview(m.SelectedModel, requestPrompt)
+	return preview, nil
+}
+
+// OpenPreview shows what sending prompt would send
+func (m *Model) OpenPreview(prompt string) tea.Cmd {
+	preview, err := m.PreviewContext(prompt)
+	if err != nil {
+		m.StatusMessage = err.Error()
+		return nil
+	}
+	m.Preview = preview
+	if m.State != StatePreview {
+		m.PreviewReturnState = m.State
+	}
+	m.State = StatePreview
+	m.UpdatePreviewView()
+	m.PreviewViewport.GotoTop()
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// UpdatePreview handles keys on the preview
+func (m Model) UpdatePreview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		m.State = m.PreviewReturnState
+		return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	case "ctrl+c":
+		return m, m.RequestQuit()
+	}
+
+	var cmd tea.Cmd
+	m.PreviewViewport, cmd = m.PreviewViewport.Update(msg)
+	return m, cmd
+}
+
+// UpdatePreviewView renders the preview into its viewport
+func (m *Model) UpdatePreviewView() {
+	p := m.Preview
+	r := p.Request
+	section := func(title string) string {
+		return "\n" + SelectedHeaderStyle.Render(title) + "\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s · %s · %s\n", p.Provider, p.Model, r.Endpoint))
+	estimate := fmt.Sprintf("~%s tokens", formatCount(p.Tokens()))
+	if p.Window > 0 {
+		estimate += fmt.Sprintf(" of the %s-token context (%d%%)", formatCount(p.Window), p.Tokens()*100/p.Window)
+	}
+	if r.Images == 1 {
+		estimate += ", plus an image"
+	} else if r.Images > 1 {
+		estimate += fmt.Sprintf(", plus %d images", r.Images)
+	}
+	sb.WriteString(estimate + "\n")
+	if p.Trim != "" {
+		sb.WriteString("⚠ " + p.Trim + "\n")
+	}
+
+	sb.WriteString(section(fmt.Sprintf("System prompt (~%s tokens)", formatCount(utils.EstimateTokens(r.System)))))
+	if r.System == "" {
+		sb.WriteString(CollapsedStyle.Render("(none)") + "\n")
+	} else {
+		sb.WriteString(r.System + "\n")
+	}
+
+	switch {
+	case r.ContextTokens > 0:
+		sb.WriteString(section("History"))
+		sb.WriteString(fmt.Sprintf("Ollama's token context of %s tokens from the earlier responses stands in for the conversation\n", formatCount(r.ContextTokens)))
+	case len(r.History) == 0:
+		sb.WriteString(section("History"))
+		sb.WriteString(CollapsedStyle.Render("(none)") + "\n")
+	default:
+		tokens := 0
+		for _, msg := range r.History {
+			tokens += utils.EstimateTokens(msg.Content)
+		}
+		sb.WriteString(section(fmt.Sprintf("History: %d messages (~%s tokens)", len(r.History), formatCount(tokens))))
+		for _, msg := range r.History {
+			sb.WriteString(CollapsedStyle.Render(msg.Role+":") + "\n" + msg.Content + "\n")
+		}
+	}
+
+	if len(p.Attachments) > 0 {
+		sb.WriteString(section(fmt.Sprintf("Attachments (%d)", len(p.Attachments))))
+		for _, a := range p.Attachments {
+			if a.Image != nil {
+				sb.WriteString(fmt.Sprintf("%s · image, %s\n", a.Source, utils.FormatBytes(int64(len(a.Image)))))
+			} else {
+				sb.WriteString(fmt.Sprintf("%s · ~%s tokens, in the prompt\n", a.Source, formatCount(utils.EstimateTokens(a.Content))))
+			}
+		}
+	}
+
+	sb.WriteString(section(fmt.Sprintf("Prompt (~%s tokens)", formatCount(utils.EstimateTokens(r.Prompt)))))
+	if r.Prompt == "" {
+		sb.WriteString(CollapsedStyle.Render("(empty; /preview <prompt> previews one)") + "\n")
+	} else {
+		sb.WriteString(r.Prompt + "\n")
+	}
+
+	var settings []string
+	if len(r.Format) > 0 {
+		settings = append(settings, "Format: "+string(r.Format))
+	}
+	if len(r.Options) > 0 {
+		options, _ := json.Marshal(r.Options)
+		settings = append(settings, "Options: "+string(options))
+	}
+	if len(r.Tools) > 0 {
+		settings = append(settings, "Tools: "+strings.Join(r.Tools, ", "))
+	}
+	if len(settings) > 0 {
+		sb.WriteString(section("Settings"))
+		sb.WriteString(strings.Join(settings, "\n") + "\n")
+	}
+
+	m.PreviewViewport.SetContent(lipgloss.NewStyle().Width(m.PreviewViewport.Width).Render(strings.TrimRight(sb.String(), "\n")))
+}
+
+// PreviewView renders the preview
+func (m Model) PreviewView() string {
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		TitleStyle.Render("Preview · nothing was sent"),
+		lipgloss.NewStyle().Padding(0, 2).Render(m.PreviewViewport.View()),
+		HelpStyle.Render("↑/↓: scroll | Esc: back, with the prompt left to send"),
+	)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "preview",
+		Usage:       "[prompt]",
+		Description: "Show the system prompt, history, attachments and token estimate a prompt would be sent with, without sending it",
+		Run: func(m *Model, args string) tea.Cmd {
+			cmd := m.OpenPreview(args)
+			// Leave the prompt in the input to send once it looks right
+			m.Input.SetValue(args)
+			return cmd
+		},
+	})
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// profilePicker is the list of profiles shown at startup before the
+// interface, since the profile decides how everything else is set up
+type profilePicker struct {
+	List   list.Model
+	Choice string
+}
+
+// profileItems lists the profiles of config with the settings each one sets
+func profileItems(config utils.Config) []list.Item {
+	var items []list.Item
+	for _, name := range utils.ProfileNames(config) {
+		details := "Sets nothing"
+		if keys := utils.ProfileKeys(config, name); len(keys) > 0 {
+			details = "Sets " + strings.Join(keys, ", ")
+		}
+		items = append(items, models.ListItem{Name: name, Details: details})
+	}
+	return items
+}
+
+func (p profilePicker) Init() tea.Cmd {
+	return nil
+}
+
+func (p profilePicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.List.SetSize(msg.Width, msg.Height)
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter":
+			if i, ok := p.List.SelectedItem().(models.ListItem); ok {
+				p.Choice = i.Name
+			}
+			return p, tea.Quit
+		case "esc", "q", "ctrl+c":
+			return p, tea.Quit
+		}
+	}
+	var cmd tea.Cmd
+	p.List, cmd = p.List.Update(msg)
+	return p, cmd
+}
+
+func (p profilePicker) View() string {
+	return p.List.View()
+}
+
+// PickProfile asks which profile of config to use. It returns an empty name
+// when the user quits instead.
+func PickProfile(config utils.Config) (string, error) {
+	l := list.New(profileItems(config), list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Profiles"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.Styles.Title = TitleStyle
+
+	result, err := tea.NewProgram(profilePicker{List: l}, tea.WithAltScreen()).Run()
+	if err != nil {
+		return "", err
+	}
+	return result.(profilePicker).Choice, nil
+}
@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// ColdStartMsg tells whether the model was already in memory when a prompt
+// was sent to it
+type ColdStartMsg struct {
+	Tab    int
+	ID     int
+	Loaded bool
+}
+
+// ColdStartCmd asks Ollama whether the model is loaded, so the wait for the
+// first token can be shown as loading the model into memory
+func ColdStartCmd(tab, id int, model string) tea.Cmd {
+	client := APIClient
+	return func() tea.Msg {
+		running, err := client.RunningModels()
+		if err != nil {
+			// Without an answer the wait is shown as usual
+			return ColdStartMsg{Tab: tab, ID: id, Loaded: true}
+		}
+		return ColdStartMsg{Tab: tab, ID: id, Loaded: modelLoaded(running, model)}
+	}
+}
+
+// modelLoaded reports whether model is among the running models, allowing
+// for the implicit ":latest" tag
+func modelLoaded(running []models.RunningModel, model string) bool {
+	name := strings.TrimSuffix(model, ":latest")
+	for _, r := range running {
+		if strings.TrimSuffix(r.Name, ":latest") == name || strings.TrimSuffix(r.Model, ":latest") == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleColdStart marks the response as waiting for the model to load while
+// no token has arrived yet
+func (m *Model) HandleColdStart(msg ColdStartMsg) {
+	if msg.ID != m.GenerationID || !m.IsGenerating || !m.FirstTokenAt.IsZero() {
+		return
+	}
+	m.ColdStart = !msg.Loaded
+}
+
+// resetProgress forgets the progress of the previous response
+func (m *Model) resetProgress() {
+	m.StreamedTokens = 0
+	m.FirstTokenAt = time.Time{}
+	m.ColdStart = false
+}
+
+// countToken records a token of the response in progress
+func (m *Model) countToken(token string) {
+	if token == "" {
+		return
+	}
+	if m.FirstTokenAt.IsZero() {
+		m.FirstTokenAt = time.Now()
+		m.ColdStart = false
+	}
+	m.StreamedTokens++
+}
+
+// ProgressText describes the response in progress: how long it has been
+// running, or loading the model, and once tokens arrive how many and how fast
+func (m Model) ProgressText() string {
+	if len(m.Comparison) > 0 || m.GenerationStart.IsZero() {
+		return "Generating..."
+	}
+	elapsed := time.Since(m.GenerationStart).Truncate(100 * time.Millisecond)
+	if m.FirstTokenAt.IsZero() {
+		if m.ColdStart {
+			return fmt.Sprintf("Loading %s into memory… %s", m.SelectedModel, elapsed)
+		}
+		return fmt.Sprintf("Generating… %s", elapsed)
+	}
+	text := fmt.Sprintf("Generating… %s · %d tokens", elapsed, m.StreamedTokens)
+	if streaming := time.Since(m.FirstTokenAt).Seconds(); streaming >= 0.5 {
+		text += fmt.Sprintf(" · %.1f tok/s", float64(m.StreamedTokens)/streaming)
+	}
+	return text
+}
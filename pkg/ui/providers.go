@@ -0,0 +1,295 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// AllProviders is the provider list entry that merges the models of every
+// configured provider into one list
+const AllProviders = "all"
+
+// KeyedProvider describes a provider that needs an API key
+type KeyedProvider struct {
+	// Name is how the provider is called in the interface
+	Name string
+	// Env is the environment variable the key is read from first
+	Env string
+	// KeysURL is where users create keys
+	KeysURL string
+}
+
+// KeyedProviders are the providers that need an API key
+var KeyedProviders = map[string]KeyedProvider{
+	"openai":     {Name: "OpenAI", Env: "OPENAI_API_KEY", KeysURL: "https://platform.openai.com/api-keys"},
+	"mistral":    {Name: "Mistral", Env: "MISTRAL_API_KEY", KeysURL: "https://console.mistral.ai/api-keys"},
+	"deepseek":   {Name: "DeepSeek", Env: "DEEPSEEK_API_KEY", KeysURL: "https://platform.deepseek.com/api_keys"},
+	"together":   {Name: "Together AI", Env: "TOGETHER_API_KEY", KeysURL: "https://api.together.ai/settings/api-keys"},
+	"fireworks":  {Name: "Fireworks", Env: "FIREWORKS_API_KEY", KeysURL: "https://fireworks.ai/account/api-keys"},
+	"perplexity": {Name: "Perplexity", Env: "PERPLEXITY_API_KEY", KeysURL: "https://www.perplexity.ai/account/api/keys"},
+	// Hugging Face tokens are optional: endpoints may be public
+	"huggingface": {Name: "Hugging Face", Env: "HF_TOKEN", KeysURL: "https://huggingface.co/settings/tokens"},
+}
+
+// configAPIKey returns the config field holding a provider's API key
+func configAPIKey(c *utils.Config, provider string) *string {
+	switch provider {
+	case "mistral":
+		return &c.MistralAPIKey
+	case "deepseek":
+		return &c.DeepSeekAPIKey
+	case "huggingface":
+		return &c.HuggingFaceToken
+	case "together":
+		return &c.TogetherAPIKey
+	case "fireworks":
+		return &c.FireworksAPIKey
+	case "perplexity":
+		return &c.PerplexityAPIKey
+	}
+	return &c.OpenAIAPIKey
+}
+
+// KeyRequired reports whether a provider needs an API key. The openai
+// provider doesn't when it points at a compatible server.
+func KeyRequired(provider string) bool {
+	switch provider {
+	case "openai":
+		return OpenAIKeyRequired()
+	case "huggingface":
+		return false
+	}
+	_, ok := KeyedProviders[provider]
+	return ok
+}
+
+// ProviderAPIKey returns the API key a provider's client is created with,
+// from the environment or the config file
+func ProviderAPIKey(provider string) string {
+	if _, ok := KeyedProviders[provider]; !ok || (provider == "openai" && !OpenAIKeyRequired()) {
+		return ""
+	}
+	if apiKey := utils.GetEnv(KeyedProviders[provider].Env, ""); apiKey != "" {
+		return apiKey
+	}
+	if config, err := utils.LoadConfig(); err == nil {
+		return *configAPIKey(&config, provider)
+	}
+	return ""
+}
+
+// SaveProviderAPIKey uses apiKey for the provider for the rest of the
+// session and saves it to the config file for the next ones
+func SaveProviderAPIKey(provider, apiKey string) error {
+	setErr := utils.SetEnv(KeyedProviders[provider].Env, apiKey)
+	saveErr := utils.UpdateConfig(func(c *utils.Config) { *configAPIKey(c, provider) = apiKey })
+	return errors.Join(setErr, saveErr)
+}
+
+// IsProvider reports whether name is a built-in provider
+func IsProvider(name string) bool {
+	_, keyed := KeyedProviders[name]
+	_, preset := api.Presets[name]
+	_, plugin := api.Plugins[name]
+	mock := name == api.MockProvider && api.MockEnabled
+	return name == "ollama" || keyed || preset || plugin || mock
+}
+
+// EnabledProviders limits the provider list to these providers when set
+var EnabledProviders []string
+
+// providerEnabled reports whether a provider is offered, by
+// EnabledProviders or because every provider is
+func providerEnabled(name string) bool {
+	if len(EnabledProviders) == 0 || name == AllProviders {
+		return true
+	}
+	for _, enabled := range EnabledProviders {
+		if name == enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginNames returns the names of the provider plugins in order
+func pluginNames() []string {
+	names := make([]string, 0, len(api.Plugins))
+	for name := range api.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyProviderConfig points the providers at the servers, models and
+// plugins of the config, or at their defaults for settings it leaves out.
+// It runs at startup and again when config.json changes.
+func ApplyProviderConfig(config utils.Config) {
+	api.OpenAIURL = api.DefaultOpenAIURL
+	if config.OpenAIBaseURL != "" {
+		api.OpenAIURL = strings.TrimRight(config.OpenAIBaseURL, "/")
+	}
+	api.OpenAIModelAllowlist = config.OpenAIModels
+	EnabledProviders = config.Providers
+
+	lmStudio := api.Presets["lmstudio"]
+	lmStudio.URL = api.DefaultLMStudioURL
+	if config.LMStudioURL != "" {
+		lmStudio.URL = strings.TrimRight(config.LMStudioURL, "/")
+	}
+	api.Presets["lmstudio"] = lmStudio
+
+	pluginsDir := config.PluginsDir
+	if pluginsDir == "" {
+		if configDir, err := utils.GetConfigDir(); err == nil {
+			pluginsDir = filepath.Join(configDir, "plugins")
+		}
+	}
+	api.Plugins = map[string]api.Plugin{}
+	if plugins, err := api.LoadPlugins(pluginsDir); err == nil {
+		for _, plugin := range plugins {
+			api.Plugins[plugin.Name] = plugin
+		}
+	}
+
+	api.HuggingFaceEndpoints = nil
+	for _, hf := range config.HuggingFace {
+		url := hf.URL
+		if url == "" {
+			url = api.HuggingFaceURL
+		}
+		api.HuggingFaceEndpoints = append(api.HuggingFaceEndpoints, api.HuggingFaceEndpoint{
+			Name: hf.Name, URL: url, Model: hf.Model, API: hf.API, Token: hf.Token,
+		})
+	}
+}
+
+// ConfiguredProviders returns the providers that can be used without
+// asking for anything: Ollama, and the others once they have an API key or,
+// for OpenAI, point at a compatible server. Plugins manage their own
+// credentials.
+func ConfiguredProviders() []string {
+	candidates := []string{"ollama"}
+	for _, provider := range []string{"openai", "mistral", "deepseek", "together", "fireworks", "perplexity"} {
+		if !KeyRequired(provider) || ProviderAPIKey(provider) != "" {
+			candidates = append(candidates, provider)
+		}
+	}
+	if len(api.HuggingFaceEndpoints) > 0 {
+		candidates = append(candidates, "huggingface")
+	}
+	candidates = append(candidates, pluginNames()...)
+	if api.MockEnabled {
+		candidates = append(candidates, api.MockProvider)
+	}
+
+	var providers []string
+	for _, provider := range candidates {
+		if providerEnabled(provider) {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// FetchAllModelsCmd fetches the model lists of every configured provider at
+// once and merges them, each model tagged with its provider. Providers that
+// fail are reported alongside the models of the others.
+func FetchAllModelsCmd() tea.Cmd {
+	providers := ConfiguredProviders()
+	clients := make([]*api.Client, len(providers))
+	for i, provider := range providers {
+		clients[i] = api.NewClient(provider, ProviderAPIKey(provider))
+	}
+
+	return func() tea.Msg {
+		lists := make([][]models.Model, len(providers))
+		errs := make([]error, len(providers))
+		var wg sync.WaitGroup
+		for i := range providers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lists[i], errs[i] = clients[i].FetchModels()
+			}()
+		}
+		wg.Wait()
+
+		var merged []models.Model
+		var failed []error
+		for i, provider := range providers {
+			if errs[i] != nil {
+				failed = append(failed, fmt.Errorf("%s: %w", provider, errs[i]))
+				continue
+			}
+			for _, model := range lists[i] {
+				model.Provider = provider
+				merged = append(merged, model)
+			}
+		}
+		if len(failed) == len(providers) {
+			return ErrorMsg{Err: errors.Join(failed...)}
+		}
+		return FetchModelsMsg{Models: merged, Errs: failed}
+	}
+}
+
+// UseProvider moves the chat to a client for another provider, handing the
+// conversation and the chat settings over to it
+func (m *Model) UseProvider(provider string) {
+	previous := APIClient
+	APIClient = api.NewClient(provider, ProviderAPIKey(provider))
+	APIClient.KeepAlive = m.KeepAlive
+	APIClient.SystemPrompt = previous.SystemPrompt
+	APIClient.Temperature = previous.Temperature
+	APIClient.SafePrompt = m.SafePrompt
+	m.ApplyRequestOptions(APIClient, provider)
+	m.SelectedProvider = provider
+	m.ApplyTools()
+	if len(m.Messages) > 0 {
+		APIClient.SetHistory(m.ChatHistory())
+	}
+}
+
+// managesHighlightedModel reports whether the model highlighted in the
+// model list lives on the Ollama server the client talks to, so that it can
+// be unloaded, copied or renamed
+func (m Model) managesHighlightedModel() bool {
+	if m.SelectedProvider != "ollama" {
+		return false
+	}
+	i, ok := m.List.SelectedItem().(models.ListItem)
+	return ok && (i.Provider == "" || i.Provider == "ollama")
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "safeprompt",
+		Description: "Toggle Mistral's safety system prompt",
+		Run: func(m *Model, args string) tea.Cmd {
+			m.SafePrompt = !m.SafePrompt
+			APIClient.SafePrompt = m.SafePrompt
+			safePrompt := m.SafePrompt
+			if err := utils.UpdateConfig(func(c *utils.Config) { c.MistralSafePrompt = safePrompt }); err != nil {
+				m.Err = err
+			}
+			if safePrompt {
+				m.StatusMessage = "Safe prompt on: Mistral prepends its safety system prompt"
+			} else {
+				m.StatusMessage = "Safe prompt off"
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,300 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// PullChan is a channel for progress updates while a model is being pulled
+var PullChan = make(chan PullProgressMsg, 100)
+
+// PullProgressMsg carries a progress update of the pull with the given ID
+type PullProgressMsg struct {
+	ID       int
+	Progress models.ProgressResponse
+	Done     bool
+	Err      error
+}
+
+// PullLayer is a layer of a model being downloaded
+type PullLayer struct {
+	Digest    string
+	Total     int64
+	Completed int64
+	// Started and Resumed are when the layer was first reported and how
+	// much of it was already downloaded then, to measure the throughput
+	Started time.Time
+	Resumed int64
+}
+
+// Throughput returns the bytes per second downloaded since the layer was
+// first reported
+func (l PullLayer) Throughput(now time.Time) float64 {
+	elapsed := now.Sub(l.Started).Seconds()
+	if elapsed < 0.5 {
+		return 0
+	}
+	return float64(l.Completed-l.Resumed) / elapsed
+}
+
+// ETA returns how long the rest of the layer will take at its throughput,
+// or zero when it can't be told yet
+func (l PullLayer) ETA(now time.Time) time.Duration {
+	rate := l.Throughput(now)
+	if rate <= 0 || l.Completed >= l.Total {
+		return 0
+	}
+	return time.Duration(float64(l.Total-l.Completed) / rate * float64(time.Second))
+}
+
+// ModelPull is a model being downloaded from the Ollama library
+type ModelPull struct {
+	ID     int
+	Model  string
+	Status string
+	Layers []*PullLayer
+	// Select switches to the model once it is pulled
+	Select bool
+	Done   bool
+	Err    error
+	cancel context.CancelFunc
+}
+
+// Apply records a progress update of the pull
+func (p *ModelPull) Apply(update models.ProgressResponse, now time.Time) {
+	p.Status = update.Status
+	if update.Digest == "" || update.Total == 0 {
+		return
+	}
+	for _, layer := range p.Layers {
+		if layer.Digest == update.Digest {
+			layer.Total = update.Total
+			layer.Completed = update.Completed
+			return
+		}
+	}
+	p.Layers = append(p.Layers, &PullLayer{
+		Digest:    update.Digest,
+		Total:     update.Total,
+		Completed: update.Completed,
+		Started:   now,
+		Resumed:   update.Completed,
+	})
+}
+
+// PullModelCmd pulls the model in the background, streaming its progress
+// into PullChan until ctx is cancelled
+func PullModelCmd(ctx context.Context, id int, name string) tea.Cmd {
+	client := APIClient
+	go func() {
+		err := client.PullModel(ctx, name, func(update models.ProgressResponse) {
+			PullChan <- PullProgressMsg{ID: id, Progress: update}
+		})
+		PullChan <- PullProgressMsg{ID: id, Done: true, Err: err}
+	}()
+	return ListenForPullProgressCmd()
+}
+
+// ListenForPullProgressCmd waits for the next model pull update
+func ListenForPullProgressCmd() tea.Cmd {
+	return func() tea.Msg {
+		return <-PullChan
+	}
+}
+
+// StartPull downloads the model from the Ollama library on the pull screen,
+// switching to it once it is pulled when selectModel is set
+func (m *Model) StartPull(name string, selectModel bool) tea.Cmd {
+	if m.SelectedProvider != "ollama" {
+		m.StatusMessage = "Pulling models is only available for Ollama"
+		return nil
+	}
+	if m.Pull != nil && !m.Pull.Done {
+		m.StatusMessage = fmt.Sprintf("Already pulling %s", m.Pull.Model)
+		return m.OpenPull()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.PullID++
+	m.Pull = &ModelPull{
+		ID:     m.PullID,
+		Model:  name,
+		Status: "starting",
+		Select: selectModel,
+		cancel: cancel,
+	}
+	return tea.Batch(m.OpenPull(), PullModelCmd(ctx, m.PullID, name))
+}
+
+// OpenPull shows the progress of the current pull
+func (m *Model) OpenPull() tea.Cmd {
+	if m.State != StatePull {
+		m.PullReturnState = m.State
+	}
+	m.State = StatePull
+	m.UpdatePullView()
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// CancelPull stops the pull in progress
+func (m *Model) CancelPull() {
+	if m.Pull == nil || m.Pull.Done {
+		return
+	}
+	m.Pull.cancel()
+	m.Pull.Done = true
+	m.Pull.Err = context.Canceled
+	m.StatusMessage = fmt.Sprintf("Cancelled pulling %s", m.Pull.Model)
+}
+
+// HandlePullProgress records a pull update, and once the model is pulled
+// refreshes the model list, switches to it if asked and leaves the pull
+// screen
+func (m *Model) HandlePullProgress(msg PullProgressMsg) tea.Cmd {
+	if m.Pull == nil || msg.ID != m.Pull.ID || m.Pull.Done {
+		// Updates of a cancelled pull are drained until it stops
+		if msg.Done {
+			return nil
+		}
+		return ListenForPullProgressCmd()
+	}
+
+	pull := m.Pull
+	if !msg.Done {
+		pull.Apply(msg.Progress, time.Now())
+		m.UpdatePullView()
+		return ListenForPullProgressCmd()
+	}
+
+	pull.Done = true
+	pull.cancel()
+	if msg.Err != nil {
+		pull.Err = msg.Err
+		m.StatusMessage = fmt.Sprintf("Failed to pull %s: %v", pull.Model, msg.Err)
+		m.UpdatePullView()
+		return nil
+	}
+
+	m.StatusMessage = fmt.Sprintf("Pulled %s", pull.Model)
+	if pull.Select && m.SelectedModel != pull.Model {
+		m.SwitchModel(pull.Model)
+		m.RecordModelUse(pull.Model)
+	}
+	var cmds []tea.Cmd
+	if m.State == StatePull {
+		m.State = m.PullReturnState
+		cmds = append(cmds, tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	}
+	cmds = append(cmds, m.RefreshModelsCmd())
+	return tea.Batch(cmds...)
+}
+
+// UpdatePull handles keys on the pull screen
+func (m Model) UpdatePull(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		m.CancelPull()
+		m.State = m.PullReturnState
+		return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	case "ctrl+c":
+		m.CancelPull()
+		return m, m.RequestQuit()
+	}
+
+	var cmd tea.Cmd
+	m.PullViewport, cmd = m.PullViewport.Update(msg)
+	return m, cmd
+}
+
+// UpdatePullView renders the layers of the pull into the pull viewport,
+// each with a bar, the bytes downloaded, the throughput and the time left
+func (m *Model) UpdatePullView() {
+	pull := m.Pull
+	if pull == nil {
+		m.PullViewport.SetContent("Nothing is being pulled")
+		return
+	}
+
+	var sb strings.Builder
+	status := pull.Status
+	switch {
+	case errors.Is(pull.Err, context.Canceled):
+		status = "cancelled"
+	case pull.Err != nil:
+		status = ErrorTextStyle.Render(pull.Err.Error())
+	}
+	sb.WriteString(SelectedHeaderStyle.Render(pull.Model) + " · " + status + "\n\n")
+
+	now := time.Now()
+	m.PullBar.Width = max(m.PullViewport.Width-2, 10)
+	for _, layer := range pull.Layers {
+		percent := float64(layer.Completed) / float64(layer.Total)
+		sb.WriteString(shortDigest(layer.Digest) + "\n")
+		sb.WriteString(m.PullBar.ViewAs(percent) + "\n")
+
+		details := fmt.Sprintf("%s / %s", utils.FormatBytes(layer.Completed), utils.FormatBytes(layer.Total))
+		if layer.Completed < layer.Total {
+			if rate := layer.Throughput(now); rate > 0 {
+				details += fmt.Sprintf(" · %s/s · ETA %s", utils.FormatBytes(int64(rate)), layer.ETA(now).Round(time.Second))
+			}
+		} else {
+			details += " · done"
+		}
+		sb.WriteString(MetadataStyle.Render(details) + "\n\n")
+	}
+	m.PullViewport.SetContent(strings.TrimRight(sb.String(), "\n"))
+}
+
+// PullView renders the pull screen
+func (m Model) PullView() string {
+	help := "↑/↓: scroll | Esc: cancel"
+	if m.Pull == nil || m.Pull.Done {
+		help = "Esc: back"
+	}
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		TitleStyle.Render("Pull model"),
+		lipgloss.NewStyle().Padding(0, 2).Render(m.PullViewport.View()),
+		HelpStyle.Render(help),
+	)
+}
+
+// shortDigest shortens a layer digest the way ollama prints it
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	return digest[:min(12, len(digest))]
+}
+
+// newPullBar returns the bar drawn for each layer of a pull
+func newPullBar() progress.Model {
+	return progress.New(progress.WithDefaultGradient())
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "pull",
+		Usage:       "[name]",
+		Description: "Download a model from the Ollama library, or show the last pull",
+		Run: func(m *Model, args string) tea.Cmd {
+			name := strings.TrimSpace(args)
+			if name == "" {
+				if m.Pull == nil {
+					m.StatusMessage = "Usage: /pull <model>"
+					return nil
+				}
+				return m.OpenPull()
+			}
+			return m.StartPull(name, false)
+		},
+	})
+}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// QueuedPrompt is a prompt submitted during a response, waiting to be sent
+type QueuedPrompt struct {
+	Text        string
+	Attachments []models.Attachment
+}
+
+// QueuePrompt puts the prompt in the input box, with its attachments, at the
+// end of the queue
+func (m Model) QueuePrompt() (tea.Model, tea.Cmd) {
+	if strings.TrimSpace(m.Input.Value()) == "" {
+		return m, nil
+	}
+	m.Queue = append(m.Queue, QueuedPrompt{Text: m.Input.Value(), Attachments: m.PendingAttachments})
+	m.PendingAttachments = nil
+	m.Input.Reset()
+	m.ResizeInput()
+	m.StatusMessage = fmt.Sprintf("Queued, %d waiting (%s: remove the last)", len(m.Queue), m.KeyMap.Help(ActionCancelQueued))
+	m.UpdateViewportContent()
+	return m, nil
+}
+
+// CancelQueued removes the most recently queued prompt
+func (m *Model) CancelQueued() {
+	if len(m.Queue) == 0 {
+		m.StatusMessage = "No queued prompts"
+		return
+	}
+	m.Queue = m.Queue[:len(m.Queue)-1]
+	m.StatusMessage = fmt.Sprintf("Removed the last queued prompt, %d waiting", len(m.Queue))
+	m.RefreshTranscript()
+}
+
+// SendQueued sends the next queued prompt once the response is finished.
+// Queued slash commands run in turn until one starts a response. What is
+// being typed in the input box stays there.
+func (m Model) SendQueued() (Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	draft, attachments := m.Input.Value(), m.PendingAttachments
+	for len(m.Queue) > 0 && !m.IsGenerating {
+		next := m.Queue[0]
+		m.Queue = m.Queue[1:]
+		m.Input.SetValue(next.Text)
+		m.PendingAttachments = next.Attachments
+		result, cmd := m.SubmitPrompt()
+		m = result.(Model)
+		cmds = append(cmds, cmd)
+		// A slash command may have opened another screen
+		if m.State != StatePrompting && m.State != StateLoading {
+			break
+		}
+	}
+	m.Input.SetValue(draft)
+	m.PendingAttachments = attachments
+	m.ResizeInput()
+	m.RefreshTranscript()
+	return m, tea.Batch(cmds...)
+}
+
+// renderQueued renders the queued prompts below the transcript
+func (m Model) renderQueued(width int) string {
+	var blocks []string
+	for _, q := range m.Queue {
+		text := q.Text
+		if width > 10 {
+			text = utils.WrapText(text, width)
+		}
+		blocks = append(blocks, UserHeaderStyle.Render("▌ You · queued")+"\n"+CollapsedStyle.Render(text))
+	}
+	return strings.Join(blocks, "\n\n")
+}
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// HasUnsavedConversation reports whether quitting would lose messages that
+// were never saved to a session, in any tab
+func (m Model) HasUnsavedConversation() bool {
+	if m.Tab.unsaved() {
+		return true
+	}
+	for i, t := range m.Tabs {
+		if i != m.ActiveTab && t.unsaved() {
+			return true
+		}
+	}
+	return false
+}
+
+// unsaved reports whether the tab has messages that were never saved
+func (t Tab) unsaved() bool {
+	if t.Session.ID != "" {
+		return false
+	}
+	for _, msg := range t.Messages {
+		if msg.Role != models.RoleInfo {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestQuit exits right away, or asks for confirmation first when the
+// conversation has not been saved
+func (m *Model) RequestQuit() tea.Cmd {
+	if !m.HasUnsavedConversation() {
+		return tea.Quit
+	}
+	m.ConfirmQuit = true
+	return nil
+}
+
+// StepBack returns to the previous screen: chat → model select → provider
+// select. It quits from the provider list.
+func (m *Model) StepBack() tea.Cmd {
+	switch m.State {
+	case StatePrompting:
+		return m.OpenModelPicker()
+	case StateModelSelect:
+		if m.SelectedModel != "" {
+			m.State = StatePrompting
+		} else {
+			m.State = StateProviderSelect
+		}
+	case StateAPIKeyInput:
+		m.APIKeyChecking, m.APIKeyError = "", ""
+		m.State = StateProviderSelect
+	case StateSessionBrowser:
+		m.State = StatePrompting
+	default:
+		return m.RequestQuit()
+	}
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// UpdateQuitConfirm handles keys while the quit confirmation is shown
+func (m Model) UpdateQuitConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "ctrl+c":
+		return m, tea.Quit
+	case "s":
+		if err := m.SaveTabs(); err != nil {
+			m.ConfirmQuit = false
+			m.StatusMessage = fmt.Sprintf("Failed to save session: %v", err)
+			return m, nil
+		}
+		return m, tea.Quit
+	}
+
+	m.ConfirmQuit = false
+	return m, nil
+}
+
+// QuitConfirmView renders the quit confirmation dialog
+func (m Model) QuitConfirmView() string {
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		TitleStyle.Render("Quit ollama-tui?"),
+		"",
+		"This conversation has not been saved.",
+		"",
+		"y / ctrl+c  quit without saving",
+		"s           save the session and quit",
+		"any key     keep chatting",
+	)
+
+	return lipgloss.Place(
+		m.ScreenWidth,
+		m.ScreenHeight,
+		lipgloss.Center,
+		lipgloss.Center,
+		InputBoxStyle.Copy().Padding(1, 2).Render(content),
+	)
+}
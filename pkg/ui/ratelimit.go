@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+)
+
+// RateLimitMsg carries the rate limits a provider answered with, and the
+// wait before a request is sent when a limit was hit
+type RateLimitMsg struct {
+	Provider string
+	Limit    api.RateLimit
+	Wait     time.Duration
+	Attempt  int
+}
+
+// HandleRateLimit keeps the quota left at a provider and tells while a
+// request waits for its limit to reset
+func (m *Model) HandleRateLimit(msg RateLimitMsg) {
+	if m.RateLimits == nil {
+		m.RateLimits = map[string]api.RateLimit{}
+	}
+	m.RateLimits[msg.Provider] = msg.Limit
+	wait := msg.Wait.Round(time.Second)
+	switch {
+	case msg.Wait <= 0:
+	case msg.Attempt > 0:
+		m.StatusMessage = fmt.Sprintf("%s rate limit reached: retrying in %s (%d of %d)", msg.Provider, wait, msg.Attempt, api.MaxRateLimitRetries)
+	default:
+		m.StatusMessage = fmt.Sprintf("%s rate limit used up: the request waits %s for it to reset", msg.Provider, wait)
+	}
+}
+
+// RateLimitIndicator shows the requests and tokens left at the provider, as
+// far as it said and until its limits reset
+func (m Model) RateLimitIndicator() string {
+	limit, ok := m.RateLimits[m.SelectedProvider]
+	if !ok {
+		return ""
+	}
+	now := time.Now()
+	var left []string
+	if limit.RemainingRequests >= 0 && (limit.ResetRequests.IsZero() || limit.ResetRequests.After(now)) {
+		left = append(left, formatCount(limit.RemainingRequests)+" req")
+	}
+	if limit.RemainingTokens >= 0 && (limit.ResetTokens.IsZero() || limit.ResetTokens.After(now)) {
+		left = append(left, formatCount(limit.RemainingTokens)+" tok")
+	}
+	if len(left) == 0 {
+		return ""
+	}
+	return "⏱ " + strings.Join(left, " · ") + " left | "
+}
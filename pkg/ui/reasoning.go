@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// renderReasoning renders the reasoning a model wrote before its answer as a
+// dimmed block, collapsed to one line once the answer starts unless it was
+// expanded. It returns the block and the answer.
+func (m Model) renderReasoning(index int, content string, width int) (string, string) {
+	reasoning, answer, closed := models.SplitReasoning(content)
+	if reasoning == "" && closed {
+		return "", answer
+	}
+	if m.HideReasoning {
+		if !closed {
+			return CollapsedStyle.Render("Thinking…"), answer
+		}
+		return "", answer
+	}
+
+	if closed && !m.ExpandedReasoning[index] {
+		lines := strings.Count(reasoning, "\n") + 1
+		return ReasoningStyle.Render(fmt.Sprintf("▸ Reasoning · %d lines (%s to expand)",
+			lines, m.KeyMap.Help(ActionToggleReasoning))), answer
+	}
+
+	label := "▾ Reasoning"
+	if !closed {
+		label = "▾ Thinking…"
+	}
+	if width > 10 {
+		reasoning = utils.WrapText(reasoning, width)
+	}
+	return ReasoningStyle.Render(label + "\n" + reasoning), answer
+}
+
+// ToggleReasoning expands or collapses the reasoning of the selected
+// message, or of the latest response with reasoning
+func (m *Model) ToggleReasoning() {
+	index := m.SelectedMessage
+	if index < 0 {
+		for i := len(m.Messages) - 1; i >= 0; i-- {
+			if reasoning, _, _ := models.SplitReasoning(m.Messages[i].Content); reasoning != "" {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 || index >= len(m.Messages) || m.Messages[index].Role != models.RoleAssistant {
+		m.StatusMessage = "No reasoning to show"
+		return
+	}
+	if reasoning, _, _ := models.SplitReasoning(m.Messages[index].Content); reasoning == "" {
+		m.StatusMessage = "The selected message has no reasoning"
+		return
+	}
+	if m.HideReasoning {
+		m.StatusMessage = "Reasoning is hidden: /reasoning to show it"
+		return
+	}
+
+	m.ExpandedReasoning[index] = !m.ExpandedReasoning[index]
+	m.RefreshTranscript()
+	if index < len(m.MessageOffsets) {
+		m.Viewport.SetYOffset(m.MessageOffsets[index])
+	}
+}
+
+// exportedContent returns a message as it is exported: a prompt quoted, as
+// it is shaded in the transcript, and a response with its reasoning in a
+// collapsible details block, or without it when reasoning is hidden
+func (m Model) exportedContent(msg models.Message) string {
+	if msg.Role != models.RoleAssistant {
+		return "> " + strings.ReplaceAll(msg.Content, "\n", "\n> ")
+	}
+	reasoning, answer, _ := models.SplitReasoning(msg.Content)
+	if reasoning == "" || m.HideReasoning {
+		return answer
+	}
+	return fmt.Sprintf("<details>\n<summary>Reasoning</summary>\n\n%s\n\n</details>\n\n%s", reasoning, answer)
+}
+
+// printedContent returns a message as accessible mode prints it, with the
+// reasoning tags spelled out or the reasoning left out when hidden
+func (m Model) printedContent(msg models.Message) string {
+	if msg.Role != models.RoleAssistant {
+		return msg.Content
+	}
+	if m.HideReasoning {
+		return msg.Answer()
+	}
+	trimmed := strings.TrimLeft(msg.Content, " \t\r\n")
+	if !strings.HasPrefix(trimmed, models.ReasoningStart) {
+		return msg.Content
+	}
+	trimmed = "Reasoning:" + strings.TrimPrefix(trimmed, models.ReasoningStart)
+	return strings.Replace(trimmed, models.ReasoningEnd, "End of reasoning.", 1)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "reasoning",
+		Description: "Show or hide the reasoning of models like deepseek-r1 (hidden reasoning is also left out of exports)",
+		Run: func(m *Model, args string) tea.Cmd {
+			m.HideReasoning = !m.HideReasoning
+			hide := m.HideReasoning
+			if err := utils.UpdateConfig(func(c *utils.Config) { c.HideReasoning = hide }); err != nil {
+				m.Err = err
+			}
+			if hide {
+				m.StatusMessage = "Reasoning hidden"
+			} else {
+				m.StatusMessage = "Reasoning shown"
+			}
+			m.RefreshTranscript()
+			return nil
+		},
+	})
+}
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/session"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+const (
+	// RecallLimit is the number of snippets /recall adds to the next prompt
+	RecallLimit = 3
+	// RecallSnippetLength caps how much of each recalled message is included
+	RecallSnippetLength = 1500
+	// recallTimeout bounds embedding every stored message plus the query
+	recallTimeout = 2 * time.Minute
+)
+
+// RecallCmd embeds stored messages and the query in the background and
+// returns the closest matches from sessions other than the current one
+func RecallCmd(store *session.Store, exclude, query string) tea.Cmd {
+	client := APIClient.Detached()
+	return func() tea.Msg {
+		model := client.DefaultEmbeddingModel()
+		if config, err := utils.LoadConfig(); err == nil && config.EmbeddingModel != "" {
+			model = config.EmbeddingModel
+		}
+
+		index, err := store.LoadEmbeddingIndex(model)
+		if err != nil {
+			return RecallMsg{Query: query, Err: err}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), recallTimeout)
+		defer cancel()
+		embed := func(input []string) ([][]float64, error) {
+			return client.Embeddings(ctx, model, input)
+		}
+
+		snippets, err := store.Recall(query, index, embed, exclude, RecallLimit)
+		if err != nil {
+			return RecallMsg{Query: query, Err: fmt.Errorf("recall with %s failed: %w", model, err)}
+		}
+		return RecallMsg{Query: query, Snippets: snippets}
+	}
+}
+
+// ApplyRecall attaches recalled snippets to the next prompt and lists them
+// in the transcript
+func (m *Model) ApplyRecall(msg RecallMsg) {
+	if msg.Err != nil {
+		m.StatusMessage = msg.Err.Error()
+		return
+	}
+	if len(msg.Snippets) == 0 {
+		m.StatusMessage = fmt.Sprintf("Nothing found for %q in saved sessions", msg.Query)
+		return
+	}
+
+	var notice strings.Builder
+	notice.WriteString(fmt.Sprintf("Recalled for %q:", msg.Query))
+	for _, s := range msg.Snippets {
+		source := fmt.Sprintf("session %q, %s message", s.Title, s.Message.Role)
+		if hasAttachment(m.PendingAttachments, source) {
+			continue
+		}
+		m.PendingAttachments = append(m.PendingAttachments, models.Attachment{
+			Name:    "recall: " + truncate(s.Title, 40),
+			Source:  source,
+			Content: truncate(strings.TrimSpace(s.Message.Content), RecallSnippetLength),
+		})
+		notice.WriteString(fmt.Sprintf("\n  %.2f  %s: %s", s.Score, s.Title, truncate(strings.Join(strings.Fields(s.Message.Content), " "), 80)))
+	}
+
+	m.AddNotice(notice.String())
+	m.StatusMessage = fmt.Sprintf("Recalled %d snippets for the next prompt (/detach to drop them)", len(msg.Snippets))
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "recall",
+		Usage:       "<query>",
+		Description: "Search saved sessions by meaning and add the best matches to the next prompt",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				m.StatusMessage = "Usage: /recall <query>"
+				return nil
+			}
+			if m.SessionStore == nil {
+				store, err := session.DefaultStore()
+				if err != nil {
+					m.StatusMessage = err.Error()
+					return nil
+				}
+				m.SessionStore = store
+			}
+			m.StatusMessage = fmt.Sprintf("Searching saved sessions for %q…", args)
+			return RecallCmd(m.SessionStore, m.Session.ID, args)
+		},
+	})
+}
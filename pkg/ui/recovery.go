@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/session"
+)
+
+// RecoveryInterval is how often the draft and the conversation on screen
+// are saved for crash recovery
+const RecoveryInterval = 5 * time.Second
+
+// RecoveryTickMsg triggers saving what would be lost in a crash
+type RecoveryTickMsg struct{}
+
+// RecoveryTickCmd schedules the next recovery save
+func RecoveryTickCmd() tea.Cmd {
+	return tea.Tick(RecoveryInterval, func(time.Time) tea.Msg {
+		return RecoveryTickMsg{}
+	})
+}
+
+// OfferRecovery tells about what a run that didn't exit cleanly left
+// behind, until it is restored or discarded with /recover
+func (m *Model) OfferRecovery(recovery *session.Recovery) {
+	m.Recovered = recovery
+	var what []string
+	if n := len(recovery.Session.Messages); n > 0 {
+		conversation := fmt.Sprintf("a conversation of %d messages", n)
+		if recovery.Streaming {
+			conversation += " with an interrupted response"
+		}
+		what = append(what, conversation)
+	}
+	if recovery.Draft != "" {
+		what = append(what, fmt.Sprintf("an unsent draft (%q)", truncate(recovery.Draft, 40)))
+	}
+	m.AddNotice(fmt.Sprintf("The last run didn't exit cleanly, leaving %s at %s. /recover restores it, /recover discard drops it.",
+		strings.Join(what, " and "), recovery.SavedAt.Local().Format("Jan 2 15:04")))
+}
+
+// recoverySnapshot returns what a crash would lose now: the draft and the
+// conversation on screen when it is unsaved or a response is streaming
+func (m Model) recoverySnapshot() (session.Recovery, bool) {
+	recovery := session.Recovery{Draft: m.Input.Value(), Streaming: m.IsGenerating}
+	if m.Tab.unsaved() || m.IsGenerating {
+		recovery.Session = m.Session
+		recovery.Session.Provider = m.SelectedProvider
+		recovery.Session.Model = m.SelectedModel
+		recovery.Session.Messages = append([]models.Message(nil), m.Messages...)
+	}
+	return recovery, recovery.Draft != "" || len(recovery.Session.Messages) > 0
+}
+
+// HandleRecoveryTick saves what a crash would lose when it changed, and
+// removes the recovery file once there is nothing to lose. A recovery left
+// by the last run is kept until /recover.
+func (m *Model) HandleRecoveryTick() tea.Cmd {
+	if m.Recovered != nil {
+		return RecoveryTickCmd()
+	}
+	recovery, ok := m.recoverySnapshot()
+	state := ""
+	if ok {
+		var last string
+		if n := len(recovery.Session.Messages); n > 0 {
+			last = recovery.Session.Messages[n-1].Content
+		}
+		state = fmt.Sprintf("%s\x00%d\x00%d\x00%s\x00%t", recovery.Draft, len(recovery.Session.Messages), len(last), recovery.Session.ID, recovery.Streaming)
+	}
+	if state == m.RecoveryState {
+		return RecoveryTickCmd()
+	}
+
+	var err error
+	if ok {
+		recovery.SavedAt = time.Now()
+		err = session.SaveRecovery(recovery)
+	} else {
+		err = session.ClearRecovery()
+	}
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Failed to save the draft for recovery: %v", err)
+	} else {
+		m.RecoveryState = state
+	}
+	return RecoveryTickCmd()
+}
+
+// RestoreRecovery brings back the conversation and draft the last run left,
+// in a new tab when this one has a conversation
+func (m *Model) RestoreRecovery() tea.Cmd {
+	recovery := m.Recovered
+	if m.IsGenerating {
+		m.StatusMessage = "Wait for the response to finish before recovering"
+		return nil
+	}
+
+	var cmd tea.Cmd
+	if m.Tab.unsaved() || m.Session.ID != "" {
+		cmd = m.OpenTab()
+	}
+	m.Recovered = nil
+
+	messages := recovery.Session.Messages
+	if n := len(messages); recovery.Streaming && n > 0 && messages[n-1].Role == models.RoleAssistant {
+		if messages[n-1].Content == "" {
+			messages = messages[:n-1]
+		} else {
+			messages[n-1].CutOff = models.CutOffStopped
+		}
+	}
+	m.Session = recovery.Session
+	m.LoadMessages(messages)
+	if recovery.Draft != "" {
+		m.Input.SetValue(recovery.Draft)
+		m.ResizeInput()
+	}
+	// A saved session gets the response as far as it streamed
+	m.AutosaveSession()
+
+	status := fmt.Sprintf("Recovered %d messages", len(m.Messages))
+	if recovery.Draft != "" {
+		status += " and the draft"
+	}
+	if recovery.Session.Model != "" && recovery.Session.Model != m.SelectedModel {
+		status += fmt.Sprintf(" (they were with %s)", recovery.Session.Model)
+	}
+	if n := len(m.Messages); n > 0 && m.Messages[n-1].CutOff == models.CutOffStopped {
+		status += "; /continue resumes the interrupted response"
+	}
+	m.StatusMessage = status
+	return cmd
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "recover",
+		Usage:       "[discard]",
+		Description: "Restore the conversation and draft left by a run that didn't exit cleanly",
+		Run: func(m *Model, args string) tea.Cmd {
+			if m.Recovered == nil {
+				m.StatusMessage = "Nothing to recover"
+				return nil
+			}
+			if args == "discard" {
+				m.Recovered = nil
+				if err := session.ClearRecovery(); err != nil {
+					m.StatusMessage = fmt.Sprintf("Failed to discard the recovery: %v", err)
+					return nil
+				}
+				m.StatusMessage = "Recovery discarded"
+				return nil
+			}
+			return m.RestoreRecovery()
+		},
+	})
+}
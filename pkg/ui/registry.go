@@ -0,0 +1,294 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+const (
+	// registryCatalogFile is the downloaded catalog, kept in the cache
+	// directory in place of the bundled one
+	registryCatalogFile = "registry.json"
+	// registryUpdateTimeout limits downloading the catalog
+	registryUpdateTimeout = 15 * time.Second
+)
+
+// registryEntry is the entry at the end of the Ollama model list that opens
+// the registry browser
+type registryEntry struct{}
+
+// Title names the entry
+func (registryEntry) Title() string { return "＋ Browse registry…" }
+
+// Description says what the registry browser is for
+func (registryEntry) Description() string { return "Pull popular models from the Ollama library" }
+
+// FilterValue is empty so the entry is hidden while filtering
+func (registryEntry) FilterValue() string { return "" }
+
+// registryItem is a library model in the registry browser with the tag
+// that would be pulled
+type registryItem struct {
+	model     api.RegistryModel
+	tag       int
+	installed bool
+}
+
+// Name returns the model and tag to pull
+func (i registryItem) Name() string {
+	if len(i.model.Tags) == 0 {
+		return i.model.Name
+	}
+	return i.model.Name + ":" + i.model.Tags[i.tag].Name
+}
+
+// Title returns the model and tag with its download size
+func (i registryItem) Title() string {
+	title := i.Name()
+	if len(i.model.Tags) > 0 && i.model.Tags[i.tag].Size > 0 {
+		title += " · " + utils.FormatBytes(i.model.Tags[i.tag].Size)
+	}
+	if i.installed {
+		title += " · ✓ installed"
+	}
+	return title
+}
+
+// Description lists the tags, the chosen one in brackets, and describes
+// the model
+func (i registryItem) Description() string {
+	var tags []string
+	for n, tag := range i.model.Tags {
+		if n == i.tag {
+			tags = append(tags, "["+tag.Name+"]")
+		} else {
+			tags = append(tags, tag.Name)
+		}
+	}
+	if len(tags) <= 1 {
+		return i.model.Description
+	}
+	return strings.Join(tags, " ") + " · " + i.model.Description
+}
+
+// FilterValue returns the value to use for filtering the list
+func (i registryItem) FilterValue() string { return i.model.Name + " " + i.model.Description }
+
+// RegistryCatalogMsg carries a newly downloaded catalog
+type RegistryCatalogMsg struct {
+	Catalog api.RegistryCatalog
+	Err     error
+}
+
+// NewRegistryList creates the list used by the registry browser
+func NewRegistryList() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Ollama library"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(true)
+	l.Styles.Title = TitleStyle
+	// Left, right and u choose tags and update the catalog instead of paging
+	l.KeyMap.PrevPage.SetKeys("pgup", "b")
+	l.KeyMap.NextPage.SetKeys("pgdown", "f", "d")
+	l.KeyMap.Quit.SetHelp("q", "back")
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "pull")),
+			key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←/→", "tag")),
+			key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "update catalog")),
+		}
+	}
+	return l
+}
+
+// registryCatalogPath returns where the downloaded catalog is kept
+func registryCatalogPath() (string, error) {
+	dir, err := utils.GetCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, registryCatalogFile), nil
+}
+
+// LoadRegistryCatalog returns the downloaded catalog, or the bundled one
+// when none was downloaded or it can't be read
+func LoadRegistryCatalog() api.RegistryCatalog {
+	path, err := registryCatalogPath()
+	if err != nil {
+		return api.BundledRegistryCatalog()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return api.BundledRegistryCatalog()
+	}
+	catalog, err := api.ParseRegistryCatalog(data)
+	if err != nil {
+		return api.BundledRegistryCatalog()
+	}
+	return catalog
+}
+
+// UpdateRegistryCatalogCmd downloads the latest catalog and keeps it in the
+// cache directory
+func UpdateRegistryCatalogCmd(url string) tea.Cmd {
+	if url == "" {
+		url = api.RegistryCatalogURL
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), registryUpdateTimeout)
+		defer cancel()
+		catalog, err := api.FetchRegistryCatalog(ctx, url)
+		if err != nil {
+			return RegistryCatalogMsg{Err: err}
+		}
+		path, err := registryCatalogPath()
+		if err != nil {
+			return RegistryCatalogMsg{Err: err}
+		}
+		data, err := json.MarshalIndent(catalog, "", "  ")
+		if err != nil {
+			return RegistryCatalogMsg{Err: err}
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return RegistryCatalogMsg{Err: err}
+		}
+		return RegistryCatalogMsg{Catalog: catalog}
+	}
+}
+
+// OpenRegistry shows the registry browser
+func (m *Model) OpenRegistry() tea.Cmd {
+	if m.SelectedProvider != "ollama" {
+		m.StatusMessage = "Browsing the registry is only available for Ollama"
+		return nil
+	}
+	if len(m.RegistryCatalog.Models) == 0 {
+		m.RegistryCatalog = LoadRegistryCatalog()
+	}
+	m.RefreshRegistryItems()
+	if m.State != StateRegistry {
+		m.RegistryReturnState = m.State
+	}
+	m.State = StateRegistry
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// RefreshRegistryItems lists the catalog, marking the models already
+// pulled and keeping the tag chosen for each model
+func (m *Model) RefreshRegistryItems() {
+	chosen := map[string]int{}
+	for _, item := range m.RegistryList.Items() {
+		if i, ok := item.(registryItem); ok {
+			chosen[i.model.Name] = i.tag
+		}
+	}
+
+	items := make([]list.Item, 0, len(m.RegistryCatalog.Models))
+	for _, model := range m.RegistryCatalog.Models {
+		item := registryItem{model: model, tag: chosen[model.Name]}
+		if item.tag >= len(model.Tags) {
+			item.tag = 0
+		}
+		item.installed = m.pulled(item.Name())
+		items = append(items, item)
+	}
+	m.RegistryList.SetItems(items)
+}
+
+// CycleRegistryTag chooses the next or previous tag of the highlighted model
+func (m *Model) CycleRegistryTag(delta int) {
+	item, ok := m.RegistryList.SelectedItem().(registryItem)
+	if !ok || len(item.model.Tags) < 2 {
+		return
+	}
+	item.tag = (item.tag + delta + len(item.model.Tags)) % len(item.model.Tags)
+	item.installed = m.pulled(item.Name())
+	m.RegistryList.SetItem(m.RegistryList.Index(), item)
+}
+
+// pulled reports whether the model is among the local models, allowing for
+// the implicit ":latest" tag
+func (m Model) pulled(name string) bool {
+	if !strings.Contains(name, ":") {
+		name += ":latest"
+	}
+	for _, model := range m.Models {
+		if model.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleRegistryCatalog lists the downloaded catalog
+func (m *Model) HandleRegistryCatalog(msg RegistryCatalogMsg) {
+	m.RegistryUpdating = false
+	if msg.Err != nil {
+		m.RegistryList.Title = fmt.Sprintf("Ollama library · update failed: %v", msg.Err)
+		return
+	}
+	m.RegistryCatalog = msg.Catalog
+	m.RefreshRegistryItems()
+	m.RegistryList.Title = fmt.Sprintf("Ollama library · catalog updated, %d models", len(msg.Catalog.Models))
+}
+
+// UpdateRegistry handles keys in the registry browser
+func (m Model) UpdateRegistry(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, m.RequestQuit()
+	}
+
+	if m.RegistryList.FilterState() != list.Filtering {
+		switch msg.String() {
+		case "esc", "q":
+			if m.RegistryList.FilterState() == list.Unfiltered || msg.String() == "q" {
+				m.State = m.RegistryReturnState
+				return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+			}
+		case "enter":
+			if item, ok := m.RegistryList.SelectedItem().(registryItem); ok {
+				return m, m.StartPull(item.Name(), false)
+			}
+			return m, nil
+		case "left", "h":
+			m.CycleRegistryTag(-1)
+			return m, nil
+		case "right", "l":
+			m.CycleRegistryTag(1)
+			return m, nil
+		case "u":
+			if !m.RegistryUpdating {
+				m.RegistryUpdating = true
+				m.RegistryList.Title = "Ollama library · updating catalog…"
+				return m, UpdateRegistryCatalogCmd(m.LoadedConfig.RegistryURL)
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.RegistryList, cmd = m.RegistryList.Update(msg)
+	return m, cmd
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "registry",
+		Description: "Browse popular models of the Ollama library and pull them",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenRegistry()
+		},
+	})
+}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SetTranscript replaces the viewport content, keeping the raw text so it
+// can be searched and re-highlighted
+func (m *Model) SetTranscript(content string) {
+	m.Transcript = content
+	m.refreshSearch()
+}
+
+// refreshSearch recomputes search matches and renders the transcript with
+// matches highlighted
+func (m *Model) refreshSearch() {
+	m.SearchMatches = nil
+	if m.SearchQuery == "" {
+		m.Viewport.SetContent(m.Transcript)
+		return
+	}
+
+	query := strings.ToLower(m.SearchQuery)
+	lines := strings.Split(m.Transcript, "\n")
+	for i, line := range lines {
+		if !strings.Contains(strings.ToLower(line), query) {
+			continue
+		}
+		m.SearchMatches = append(m.SearchMatches, i)
+		lines[i] = highlightMatches(line, m.SearchQuery)
+	}
+	if m.SearchIndex >= len(m.SearchMatches) {
+		m.SearchIndex = 0
+	}
+	m.Viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// highlightMatches wraps every case-insensitive occurrence of query in line
+// with the search highlight style
+func highlightMatches(line, query string) string {
+	lower := strings.ToLower(line)
+	lowerQuery := strings.ToLower(query)
+
+	var sb strings.Builder
+	for {
+		i := strings.Index(lower, lowerQuery)
+		if i < 0 {
+			sb.WriteString(line)
+			return sb.String()
+		}
+		sb.WriteString(line[:i])
+		sb.WriteString(SearchMatchStyle.Render(line[i : i+len(query)]))
+		line = line[i+len(query):]
+		lower = lower[i+len(query):]
+	}
+}
+
+// JumpToMatch scrolls the viewport to the match delta positions away from
+// the current one (1 for next, -1 for previous)
+func (m *Model) JumpToMatch(delta int) {
+	if len(m.SearchMatches) == 0 {
+		return
+	}
+	m.SearchIndex = (m.SearchIndex + delta + len(m.SearchMatches)) % len(m.SearchMatches)
+	m.Viewport.SetYOffset(m.SearchMatches[m.SearchIndex])
+}
+
+// ClearSearch removes the search query and highlighting
+func (m *Model) ClearSearch() {
+	m.Searching = false
+	m.SearchQuery = ""
+	m.SearchIndex = 0
+	m.refreshSearch()
+}
+
+// SearchStatus describes the current search for the status bar
+func (m Model) SearchStatus() string {
+	if m.Searching {
+		return fmt.Sprintf("Search: %s_ (%d matches, Enter: done, Esc: cancel)", m.SearchQuery, len(m.SearchMatches))
+	}
+	if len(m.SearchMatches) == 0 {
+		return fmt.Sprintf("Search: %q not found (Esc: clear)", m.SearchQuery)
+	}
+	return fmt.Sprintf("Search: %q %d/%d (n/N: next/previous, Esc: clear)", m.SearchQuery, m.SearchIndex+1, len(m.SearchMatches))
+}
+
+// UpdateSearch handles key presses while the transcript search is active
+func (m Model) UpdateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Typing the query
+	if m.Searching {
+		switch msg.Type {
+		case tea.KeyEsc, tea.KeyCtrlC:
+			m.ClearSearch()
+		case tea.KeyEnter:
+			m.Searching = false
+			if m.SearchQuery == "" {
+				m.ClearSearch()
+			}
+		case tea.KeyBackspace:
+			if m.SearchQuery != "" {
+				runes := []rune(m.SearchQuery)
+				m.SearchQuery = string(runes[:len(runes)-1])
+				m.SearchIndex = 0
+				m.refreshSearch()
+				m.JumpToMatch(0)
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			m.SearchQuery += string(msg.Runes)
+			m.SearchIndex = 0
+			m.refreshSearch()
+			m.JumpToMatch(0)
+		}
+		return m, nil
+	}
+
+	// Navigating matches of a completed query
+	switch msg.String() {
+	case "n":
+		m.JumpToMatch(1)
+	case "N":
+		m.JumpToMatch(-1)
+	case "esc":
+		m.ClearSearch()
+	case "/":
+		m.Searching = true
+	default:
+		return m, nil
+	}
+	return m, nil
+}
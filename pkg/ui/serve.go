@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/serve"
+	"github.com/evilvic/ollama-tui/pkg/session"
+)
+
+// ServeRouteMsg asks the interface where a request to the local API server
+// goes, answered on Reply
+type ServeRouteMsg struct {
+	Reply chan<- ServeRoute
+}
+
+// ServeRoute is the answer to a ServeRouteMsg
+type ServeRoute struct {
+	Route serve.Route
+	Err   error
+}
+
+// ServedMsg reports an exchange the local API server answered
+type ServedMsg struct {
+	Exchange serve.Exchange
+}
+
+// StartServer starts the local API server on addr, answering requests with
+// the provider and model selected in the interface and saving them as
+// sessions. Requests must send token, when set, as a bearer token. Program
+// must be set first.
+func StartServer(addr, token string) (*serve.Server, error) {
+	route := func(ctx context.Context) (serve.Route, error) {
+		reply := make(chan ServeRoute, 1)
+		Program.Send(ServeRouteMsg{Reply: reply})
+		select {
+		case r := <-reply:
+			return r.Route, r.Err
+		case <-ctx.Done():
+			return serve.Route{}, ctx.Err()
+		}
+	}
+	record := func(exchange serve.Exchange) {
+		Program.Send(ServedMsg{Exchange: exchange})
+	}
+	return serve.Start(addr, token, route, record)
+}
+
+// ServeRoute answers where a request to the local API server goes: a client
+// of the selected provider with the settings of the conversation but none of
+// its messages or tools
+func (m Model) ServeRoute() ServeRoute {
+	if m.SelectedModel == "" {
+		return ServeRoute{Err: errors.New("no model is selected in ollama-tui yet")}
+	}
+	client := APIClient.Detached()
+	client.Options = maps.Clone(APIClient.Options)
+	client.Temperature = APIClient.Temperature
+	client.Stop = slices.Clone(APIClient.Stop)
+	client.Seed = APIClient.Seed
+	return ServeRoute{Route: serve.Route{Client: client, Provider: m.SelectedProvider, Model: m.SelectedModel}}
+}
+
+// servedKey identifies a conversation served through the API by its
+// messages, so that a request continuing it finds its session
+func servedKey(messages []models.ChatMessage) string {
+	var kept []models.ChatMessage
+	for _, msg := range messages {
+		if msg.Role == models.RoleUser || msg.Role == models.RoleAssistant {
+			kept = append(kept, models.ChatMessage{Role: msg.Role, Content: msg.Content})
+		}
+	}
+	data, _ := json.Marshal(kept)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// HandleServed saves an exchange of the local API server: to the session of
+// the conversation it continues when there is one, else to a new session
+func (m *Model) HandleServed(msg ServedMsg) {
+	e := msg.Exchange
+	if e.Response == "" {
+		if e.Err != nil {
+			m.StatusMessage = fmt.Sprintf("API request failed: %v", e.Err)
+		}
+		return
+	}
+	if m.SessionStore == nil {
+		store, err := session.DefaultStore()
+		if err != nil {
+			m.StatusMessage = fmt.Sprintf("Failed to save API exchange: %v", err)
+			return
+		}
+		m.SessionStore = store
+	}
+	if m.ServedSessions == nil {
+		m.ServedSessions = make(map[string]string)
+	}
+
+	prompt := e.Messages[len(e.Messages)-1]
+	var s session.Session
+	earlier := e.Messages[:len(e.Messages)-1]
+	if id, ok := m.ServedSessions[servedKey(earlier)]; ok {
+		s, _ = m.SessionStore.Load(id)
+	}
+	if s.ID == "" {
+		// A conversation the server has not seen starts with the messages
+		// the request brought along
+		s = session.Session{Provider: e.Provider, Model: e.Model}
+		for _, msg := range earlier {
+			if msg.Role == models.RoleUser || msg.Role == models.RoleAssistant {
+				s.Messages = append(s.Messages, models.Message{Role: msg.Role, Content: msg.Content, CreatedAt: e.Started})
+			}
+		}
+	}
+
+	s.Messages = append(s.Messages, models.Message{Role: models.RoleUser, Content: prompt.Content, CreatedAt: e.Started})
+	response := models.Message{
+		Role:      models.RoleAssistant,
+		Content:   e.Response,
+		Model:     e.Model,
+		CreatedAt: e.Started,
+		Metadata:  e.Metadata,
+		Duration:  e.Duration,
+	}
+	if e.Stopped || e.Err != nil {
+		response.CutOff = models.CutOffStopped
+	}
+	s.Messages = append(s.Messages, response)
+	if s.Title == "" {
+		s.Title = "API: " + defaultSessionTitle(s.Messages)
+	}
+	if err := m.SessionStore.Save(&s); err != nil {
+		m.StatusMessage = fmt.Sprintf("Failed to save API exchange: %v", err)
+		return
+	}
+	m.ServedSessions[servedKey(append(slices.Clone(e.Messages), models.ChatMessage{Role: models.RoleAssistant, Content: e.Response}))] = s.ID
+	m.StatusMessage = fmt.Sprintf("Answered an API request with %s, saved to %q", e.Model, s.Title)
+}
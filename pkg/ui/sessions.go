@@ -0,0 +1,326 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/session"
+)
+
+// sessionItem is a saved session shown in the session browser
+type sessionItem struct {
+	entry   session.Entry
+	current bool
+}
+
+// Title returns the session title, indented under the session it branched from
+func (i sessionItem) Title() string {
+	title := i.entry.Session.Title
+	if title == "" {
+		title = "Untitled"
+	}
+	if i.entry.Depth > 0 {
+		branch := "└─ "
+		if plainText {
+			branch = "branch: "
+		}
+		title = strings.Repeat("  ", i.entry.Depth-1) + branch + title
+	}
+	if i.current {
+		title += " •"
+	}
+	return title
+}
+
+// Description returns the session's model, size and branch point
+func (i sessionItem) Description() string {
+	s := i.entry.Session
+	desc := fmt.Sprintf("%s · %d messages · %s", s.Model, len(s.Messages), s.UpdatedAt.Format("2006-01-02 15:04"))
+	if s.ParentID != "" {
+		desc += fmt.Sprintf(" · branched at message %d", s.ForkIndex)
+	}
+	if i.entry.Depth > 0 {
+		desc = strings.Repeat("  ", i.entry.Depth) + desc
+	}
+	return desc
+}
+
+// FilterValue returns the value to use for filtering the list
+func (i sessionItem) FilterValue() string { return i.entry.Session.Title }
+
+// NewSessionList creates the list used by the session browser
+func NewSessionList() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Sessions"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(true)
+	l.Styles.Title = TitleStyle
+	return l
+}
+
+// SaveSession writes the current conversation to the session store
+func (m *Model) SaveSession() error {
+	if m.SessionStore == nil {
+		store, err := session.DefaultStore()
+		if err != nil {
+			return err
+		}
+		m.SessionStore = store
+	}
+
+	if m.Session.Title == "" {
+		m.Session.Title = defaultSessionTitle(m.Messages)
+	}
+	m.Session.Provider = m.SelectedProvider
+	m.Session.Model = m.SelectedModel
+	m.Session.Messages = append([]models.Message(nil), m.Messages...)
+	return m.SessionStore.Save(&m.Session)
+}
+
+// NeedsTitle reports whether the conversation has just completed its first
+// exchange and has not been named by the user
+func (m Model) NeedsTitle() bool {
+	if m.Session.CustomTitle {
+		return false
+	}
+	prompts, responses := 0, 0
+	for _, msg := range m.Messages {
+		switch msg.Role {
+		case models.RoleUser:
+			prompts++
+		case models.RoleAssistant:
+			if msg.Content != "" {
+				responses++
+			}
+		}
+	}
+	return prompts == 1 && responses == 1
+}
+
+// RenameSession gives the session a title chosen by the user
+func (m *Model) RenameSession(title string) tea.Cmd {
+	m.Session.Title = title
+	m.Session.CustomTitle = true
+	m.AutosaveSession()
+	return tea.SetWindowTitle("ollama-tui · " + title)
+}
+
+// AutosaveSession saves the conversation if it has already been saved as a session
+func (m *Model) AutosaveSession() {
+	if m.Session.ID == "" {
+		return
+	}
+	if err := m.SaveSession(); err != nil {
+		m.StatusMessage = fmt.Sprintf("Failed to save session: %v", err)
+	}
+}
+
+// ForkSession branches the conversation at the message with the given index.
+// Forking at a prompt leaves it out of the branch and puts it back in the
+// input box so it can be edited and sent again.
+func (m *Model) ForkSession(index int) error {
+	if index < 0 || index >= len(m.Messages) {
+		return fmt.Errorf("no message %d to fork at", index+1)
+	}
+
+	if err := m.SaveSession(); err != nil {
+		return err
+	}
+
+	n := index + 1
+	prompt := ""
+	if m.Messages[index].Role == models.RoleUser {
+		n = index
+		prompt = m.Messages[index].Content
+	}
+
+	branch := m.Session.Fork(n)
+	parentTitle := m.Session.Title
+	m.Session = branch
+	m.LoadMessages(branch.Messages)
+	if err := m.SaveSession(); err != nil {
+		return err
+	}
+
+	if prompt != "" {
+		m.Input.SetValue(prompt)
+		m.Input.CursorEnd()
+		m.ResizeInput()
+	}
+	m.StatusMessage = fmt.Sprintf("Branched from %q at message %d; the original thread is kept in /sessions", parentTitle, index+1)
+	return nil
+}
+
+// LoadMessages replaces the transcript and rebuilds the model's conversation
+// memory from it
+func (m *Model) LoadMessages(messages []models.Message) {
+	m.Messages = append([]models.Message(nil), messages...)
+	m.Collapsed = map[int]bool{}
+	m.ExpandedReasoning = map[int]bool{}
+	m.SelectedMessage = -1
+	m.ResetTrim()
+	APIClient.ClearContext()
+	if history := m.ChatHistory(); len(history) > 0 {
+		APIClient.SetHistory(history)
+	}
+	m.UpdateViewportContent()
+}
+
+// OpenSessionBrowser lists saved sessions as a branch tree, only those with
+// a message containing query when it is set
+func (m *Model) OpenSessionBrowser(query string) tea.Cmd {
+	if m.SessionStore == nil {
+		store, err := session.DefaultStore()
+		if err != nil {
+			m.StatusMessage = fmt.Sprintf("Failed to open sessions: %v", err)
+			return nil
+		}
+		m.SessionStore = store
+	}
+
+	var sessions []session.Session
+	var err error
+	if query != "" {
+		sessions, err = m.SessionStore.Search(query)
+	} else {
+		sessions, err = m.SessionStore.List()
+	}
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Failed to list sessions: %v", err)
+		return nil
+	}
+	switch {
+	case len(sessions) == 0 && query != "":
+		m.StatusMessage = fmt.Sprintf("No saved session mentions %q", query)
+		return nil
+	case len(sessions) == 0:
+		m.StatusMessage = "No saved sessions yet (use /save or /fork)"
+		return nil
+	}
+
+	entries := session.Tree(sessions)
+	items := make([]list.Item, len(entries))
+	for i, entry := range entries {
+		items[i] = sessionItem{entry: entry, current: entry.Session.ID == m.Session.ID}
+	}
+	m.SessionList.SetItems(items)
+	m.SessionList.ResetFilter()
+	m.State = StateSessionBrowser
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+}
+
+// OpenSelectedSession loads the session highlighted in the browser
+func (m *Model) OpenSelectedSession() tea.Cmd {
+	item, ok := m.SessionList.SelectedItem().(sessionItem)
+	if !ok {
+		return nil
+	}
+
+	// Keep the current conversation before switching away from it
+	m.AutosaveSession()
+
+	s := item.entry.Session
+	m.Session = s
+	if s.Provider == m.SelectedProvider && s.Model != "" {
+		m.SelectedModel = s.Model
+	}
+	m.State = StatePrompting
+	m.LoadMessages(s.Messages)
+	m.StatusMessage = fmt.Sprintf("Opened session %q", s.Title)
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight), tea.SetWindowTitle("ollama-tui · "+s.Title))
+}
+
+// forkIndex returns the message to fork at: the argument (1-based), the
+// selected message, or the latest message
+func (m Model) forkIndex(args string) (int, error) {
+	if args != "" {
+		var n int
+		if _, err := fmt.Sscanf(args, "%d", &n); err != nil || n < 1 || n > len(m.Messages) {
+			return 0, fmt.Errorf("invalid message number %q (1-%d)", args, len(m.Messages))
+		}
+		return n - 1, nil
+	}
+	if m.SelectedMessage >= 0 {
+		return m.SelectedMessage, nil
+	}
+	return len(m.Messages) - 1, nil
+}
+
+// defaultSessionTitle derives a title from the first prompt
+func defaultSessionTitle(messages []models.Message) string {
+	for _, msg := range messages {
+		if msg.Role != models.RoleUser {
+			continue
+		}
+		title := strings.Join(strings.Fields(msg.Content), " ")
+		return truncate(title, 50)
+	}
+	return "Untitled"
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "save",
+		Usage:       "[title]",
+		Description: "Save the conversation as a session (kept up to date afterwards)",
+		Run: func(m *Model, args string) tea.Cmd {
+			var cmd tea.Cmd
+			if args != "" {
+				cmd = m.RenameSession(args)
+			}
+			if err := m.SaveSession(); err != nil {
+				m.StatusMessage = fmt.Sprintf("Failed to save session: %v", err)
+				return cmd
+			}
+			m.StatusMessage = fmt.Sprintf("Session %q saved", m.Session.Title)
+			return cmd
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "rename",
+		Usage:       "<title>",
+		Description: "Rename the session",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				m.StatusMessage = "Usage: /rename <title>"
+				return nil
+			}
+			m.StatusMessage = fmt.Sprintf("Session renamed to %q", args)
+			return m.RenameSession(args)
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "fork",
+		Usage:       "[message]",
+		Description: "Branch the conversation at a message into a new session",
+		Run: func(m *Model, args string) tea.Cmd {
+			if len(m.Messages) == 0 {
+				m.StatusMessage = "Nothing to fork yet"
+				return nil
+			}
+			index, err := m.forkIndex(args)
+			if err == nil {
+				err = m.ForkSession(index)
+			}
+			if err != nil {
+				m.StatusMessage = err.Error()
+			}
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "sessions",
+		Usage:       "[text]",
+		Description: "Browse saved sessions and branches, or those mentioning text",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenSessionBrowser(args)
+		},
+	})
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// Where /share uploads transcripts
+const (
+	ShareGist  = "gist"
+	SharePaste = "paste"
+)
+
+// shareTimeout bounds uploading a transcript
+const shareTimeout = 30 * time.Second
+
+// ShareMsg carries the URL of an uploaded transcript, and whether it was
+// copied to the clipboard
+type ShareMsg struct {
+	Target  string
+	URL     string
+	CopyErr error
+	Err     error
+}
+
+// shareTarget returns where to upload to when /share doesn't say: the
+// configured target, else a gist when there is a token, else the paste
+// service
+func shareTarget(config utils.ShareConfig) string {
+	switch {
+	case config.Target != "":
+		return config.Target
+	case gistToken(config) != "":
+		return ShareGist
+	case config.PasteURL != "":
+		return SharePaste
+	}
+	return ""
+}
+
+// gistToken returns the token gists are created with
+func gistToken(config utils.ShareConfig) string {
+	if config.GistToken != "" {
+		return config.GistToken
+	}
+	return utils.GetEnv("GITHUB_TOKEN", "")
+}
+
+// Share uploads the transcript as Markdown to target, or where the config
+// says when it is empty, and copies the URL to the clipboard
+func (m *Model) Share(target string) tea.Cmd {
+	if len(m.Messages) == 0 {
+		m.StatusMessage = "Nothing to share yet"
+		return nil
+	}
+	config, err := utils.LoadConfig()
+	if err != nil {
+		m.StatusMessage = fmt.Sprintf("Share failed: %v", err)
+		return nil
+	}
+	share := config.Share
+	if target == "" {
+		target = shareTarget(share)
+	}
+	switch {
+	case target == "":
+		m.StatusMessage = "Set share.gist_token or share.paste_url in config.json to share transcripts"
+		return nil
+	case target != ShareGist && target != SharePaste:
+		m.StatusMessage = fmt.Sprintf("Unknown share target %q: share.target must be gist or paste", target)
+		return nil
+	case target == ShareGist && gistToken(share) == "":
+		m.StatusMessage = "Sharing as a gist needs a GitHub token with the gist scope: share.gist_token in config.json or GITHUB_TOKEN"
+		return nil
+	case target == SharePaste && share.PasteURL == "":
+		m.StatusMessage = "Sharing to a paste service needs its URL: share.paste_url in config.json"
+		return nil
+	}
+
+	content := m.MarkdownTranscript()
+	filename := fmt.Sprintf("ollama-tui-%s.md", time.Now().Format("20060102-150405"))
+	description := m.Session.Title
+	if description == "" {
+		description = "Chat with " + m.SelectedModel
+	}
+	m.StatusMessage = fmt.Sprintf("Uploading the transcript to a %s…", target)
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), shareTimeout)
+		defer cancel()
+		var url string
+		var err error
+		if target == ShareGist {
+			url, err = api.CreateGist(ctx, gistToken(share), filename, description, content, share.PublicGists)
+		} else {
+			url, err = api.UploadPaste(ctx, share.PasteURL, content)
+		}
+		if err != nil {
+			return ShareMsg{Target: target, Err: err}
+		}
+		return ShareMsg{Target: target, URL: url, CopyErr: utils.WriteClipboard(url)}
+	}
+}
+
+// HandleShare announces the URL of an uploaded transcript
+func (m *Model) HandleShare(msg ShareMsg) {
+	switch {
+	case errors.Is(msg.Err, context.DeadlineExceeded):
+		m.StatusMessage = fmt.Sprintf("Share failed: the %s upload timed out", msg.Target)
+	case msg.Err != nil:
+		m.StatusMessage = fmt.Sprintf("Share failed: %v", msg.Err)
+	case msg.CopyErr != nil:
+		m.StatusMessage = fmt.Sprintf("Shared at %s (not copied: %v)", msg.URL, msg.CopyErr)
+	default:
+		m.StatusMessage = fmt.Sprintf("Shared at %s (copied to the clipboard)", msg.URL)
+	}
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "share",
+		Usage:       "[gist|paste]",
+		Description: "Upload the transcript as Markdown to a GitHub Gist or a paste service and copy the URL",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args != "" && args != ShareGist && args != SharePaste {
+				m.StatusMessage = "Usage: /share [gist|paste]"
+				return nil
+			}
+			return m.Share(args)
+		},
+	})
+}
@@ -0,0 +1,364 @@
+package ui
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// ShellOutputLength is the most command output attached to the next prompt
+const ShellOutputLength = 8000
+
+// shellOutputPreviewLines is how much output is shown in the transcript
+const shellOutputPreviewLines = 20
+
+// shellTimeout limits how long the model may take to write a command
+const shellTimeout = 60 * time.Second
+
+// shellPrompt asks the model for a single command
+const shellPrompt = `Write a single %s command for %s that does the following task. Reply with only the command, without any explanation or Markdown.
+
+Task: %s`
+
+// ShellProposal is a command waiting for the user to approve it
+type ShellProposal struct {
+	Task    string
+	Command string
+	// Blocked explains why the allowlist or denylist refuses the command
+	Blocked error
+}
+
+// userShell returns the user's shell, falling back to sh
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "sh"
+}
+
+// GenerateShellCmd asks the model for a shell command that performs task
+func GenerateShellCmd(model, task string) tea.Cmd {
+	client := APIClient.Detached()
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), shellTimeout)
+		defer cancel()
+
+		shell := userShell()
+		shell = shell[strings.LastIndex(shell, "/")+1:]
+		response, err := client.Complete(ctx, model, fmt.Sprintf(shellPrompt, shell, runtime.GOOS, task))
+		if err != nil {
+			return ShellCommandMsg{Task: task, Err: err}
+		}
+		return ShellCommandMsg{Task: task, Command: CleanShellCommand(response)}
+	}
+}
+
+// CleanShellCommand strips Markdown fences, backticks and prompt markers
+// that models add around a command
+func CleanShellCommand(response string) string {
+	command := strings.TrimSpace(response)
+	if strings.HasPrefix(command, "```") {
+		command = command[strings.Index(command, "\n")+1:]
+		if end := strings.LastIndex(command, "```"); end >= 0 {
+			command = command[:end]
+		}
+	}
+	command = strings.TrimSpace(strings.Trim(strings.TrimSpace(command), "`"))
+	return strings.TrimSpace(strings.TrimPrefix(command, "$ "))
+}
+
+// ProposeShellCommand opens the confirmation dialog for command
+func (m *Model) ProposeShellCommand(task, command string) {
+	config, _ := utils.LoadConfig()
+	m.ShellProposal = &ShellProposal{
+		Task:    task,
+		Command: command,
+		Blocked: utils.CheckShellCommand(command, config.Shell),
+	}
+	m.StatusMessage = ""
+}
+
+// HandleShellCommand shows a generated command for confirmation
+func (m *Model) HandleShellCommand(msg ShellCommandMsg) {
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("Failed to generate a command: %v", msg.Err)
+		return
+	}
+	if msg.Command == "" {
+		m.StatusMessage = "The model did not return a command"
+		return
+	}
+	m.ProposeShellCommand(msg.Task, msg.Command)
+}
+
+// UpdateShellConfirm handles keys while a command waits for approval
+func (m Model) UpdateShellConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	proposal := m.ShellProposal
+	// Only an explicit y runs the command; a stray Enter cancels it
+	switch msg.String() {
+	case "y":
+		if proposal.Blocked != nil {
+			return m, nil
+		}
+		m.ShellProposal = nil
+		return m, RunShellCmd(proposal.Command)
+	case "e":
+		// Let the user adjust the command and confirm it again
+		m.ShellProposal = nil
+		m.Input.SetValue("/run " + proposal.Command)
+		m.Input.CursorEnd()
+		m.ResizeInput()
+		return m, nil
+	}
+
+	m.ShellProposal = nil
+	m.StatusMessage = "Command cancelled"
+	return m, nil
+}
+
+// ShellConfirmView renders the dialog asking to run a command
+func (m Model) ShellConfirmView() string {
+	proposal := m.ShellProposal
+	width := m.ScreenWidth - 12
+	if width > 100 {
+		width = 100
+	}
+
+	lines := []string{TitleStyle.Render("Run this command?"), ""}
+	if proposal.Task != "" {
+		lines = append(lines, CollapsedStyle.Render(utils.WrapText("Task: "+proposal.Task, width)), "")
+	}
+	lines = append(lines, HighlightShell(proposal.Command), "")
+	if proposal.Blocked != nil {
+		lines = append(lines,
+			JSONViolationStyle.Render(utils.WrapText("⚠ "+proposal.Blocked.Error(), width)),
+			"",
+			"e           edit the command",
+			"any key     cancel",
+		)
+	} else {
+		lines = append(lines,
+			"y           run it in "+userShell(),
+			"e           edit the command",
+			"any key     cancel",
+		)
+	}
+
+	return lipgloss.Place(
+		m.ScreenWidth,
+		m.ScreenHeight,
+		lipgloss.Center,
+		lipgloss.Center,
+		InputBoxStyle.Copy().Padding(1, 2).Render(lipgloss.JoinVertical(lipgloss.Left, lines...)),
+	)
+}
+
+// HighlightShell colors the programs, flags, strings, variables and
+// operators of a command line
+func HighlightShell(command string) string {
+	var sb strings.Builder
+	runes := []rune(command)
+	commandStart := true
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			sb.WriteRune(r)
+			i++
+		case r == '\'' || r == '"' || r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				if runes[end] == '\\' && r != '\'' {
+					end++
+				}
+				end++
+			}
+			if end < len(runes) {
+				end++
+			} else {
+				end = len(runes)
+			}
+			sb.WriteString(ShellStringStyle.Render(string(runes[i:end])))
+			commandStart = false
+			i = end
+		case strings.ContainsRune("|&;<>()", r):
+			end := i + 1
+			for end < len(runes) && strings.ContainsRune("|&;<>", runes[end]) {
+				end++
+			}
+			op := string(runes[i:end])
+			sb.WriteString(ShellOperatorStyle.Render(op))
+			// A program follows a separator but not a redirection
+			commandStart = !strings.ContainsAny(op, "<>")
+			i = end
+		default:
+			end := i
+			for end < len(runes) && !strings.ContainsRune(" \t\n'\"`|&;<>()", runes[end]) {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end > len(runes) {
+				end = len(runes)
+			}
+			word := string(runes[i:end])
+			switch {
+			case strings.HasPrefix(word, "$"):
+				sb.WriteString(ShellVariableStyle.Render(word))
+			case commandStart && strings.Contains(word, "=") && !strings.HasPrefix(word, "-"):
+				sb.WriteString(ShellVariableStyle.Render(word))
+			case commandStart:
+				sb.WriteString(ShellProgramStyle.Render(word))
+				commandStart = word == "sudo" || word == "env" || word == "nohup" || word == "time"
+			case strings.HasPrefix(word, "-"):
+				sb.WriteString(ShellFlagStyle.Render(word))
+			default:
+				sb.WriteString(word)
+			}
+			i = end
+		}
+	}
+	return sb.String()
+}
+
+// shellExec runs a command in the user's shell while the TUI is suspended,
+// copying its output so it can be added to the conversation
+type shellExec struct {
+	command  string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	output   strings.Builder
+	exitCode int
+}
+
+func (s *shellExec) SetStdin(r io.Reader)  { s.stdin = r }
+func (s *shellExec) SetStdout(w io.Writer) { s.stdout = w }
+func (s *shellExec) SetStderr(w io.Writer) { s.stderr = w }
+
+// Run runs the command and waits for Enter so its output can be read
+// before the TUI comes back
+func (s *shellExec) Run() error {
+	fmt.Fprintf(s.stdout, "$ %s\n", s.command)
+
+	cmd := exec.Command(userShell(), "-c", s.command)
+	cmd.Stdin = s.stdin
+	// One writer for both streams keeps their output in order and lets
+	// exec copy them from a single goroutine
+	out := io.MultiWriter(s.stdout, &s.output)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		s.exitCode = exitErr.ExitCode()
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(s.stdout, "\n[exit status %d] Press Enter to return to ollama-tui", s.exitCode)
+	bufio.NewReader(s.stdin).ReadString('\n')
+	return nil
+}
+
+// RunShellCmd suspends the TUI, runs command and reports its output
+func RunShellCmd(command string) tea.Cmd {
+	run := &shellExec{command: command}
+	return tea.Exec(run, func(err error) tea.Msg {
+		return ShellResultMsg{
+			Command:  command,
+			Output:   run.output.String(),
+			ExitCode: run.exitCode,
+			Err:      err,
+		}
+	})
+}
+
+// HandleShellResult adds a command's output to the transcript and attaches
+// it to the next prompt
+func (m *Model) HandleShellResult(msg ShellResultMsg) tea.Cmd {
+	refresh := tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("Failed to run command: %v", msg.Err)
+		return refresh
+	}
+
+	output := strings.TrimRight(msg.Output, "\n")
+	preview := output
+	if lines := strings.Split(output, "\n"); len(lines) > shellOutputPreviewLines {
+		preview = fmt.Sprintf("… %d earlier lines\n%s", len(lines)-shellOutputPreviewLines,
+			strings.Join(lines[len(lines)-shellOutputPreviewLines:], "\n"))
+	}
+	notice := []string{"$ " + msg.Command}
+	if preview != "" {
+		notice = append(notice, preview)
+	}
+	m.AddNotice(strings.Join(append(notice, fmt.Sprintf("[exit status %d]", msg.ExitCode)), "\n"))
+
+	// Keep the end of long output, where errors and summaries usually are
+	if output == "" {
+		output = "(no output)"
+	} else if len(output) > ShellOutputLength {
+		cut := len(output) - ShellOutputLength
+		for cut < len(output) && !utf8.RuneStart(output[cut]) {
+			cut++
+		}
+		output = "…" + output[cut:]
+	}
+	m.PendingAttachments = append(m.PendingAttachments, models.Attachment{
+		Name:    "$ " + truncate(msg.Command, 40),
+		Source:  fmt.Sprintf("output of `%s` (exit status %d)", msg.Command, msg.ExitCode),
+		Content: output,
+	})
+	m.StatusMessage = "Command output attached to the next prompt (/detach to drop it)"
+	return refresh
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "sh",
+		Usage:       "<task>",
+		Description: "Have the model write a shell command for a task, then confirm to run it",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				m.StatusMessage = "Usage: /sh <task>"
+				return nil
+			}
+			m.StatusMessage = "Writing a command…"
+			return GenerateShellCmd(m.SelectedModel, args)
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "run",
+		Usage:       "<command>",
+		Description: "Run a shell command after confirming it, and attach its output",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				m.StatusMessage = "Usage: /run <command>"
+				return nil
+			}
+			m.ProposeShellCommand("", args)
+			return nil
+		},
+	})
+}
@@ -0,0 +1,218 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+const (
+	// SidePaneWidth is the width of the info pane, borders included
+	SidePaneWidth = 36
+	// MinSidePaneScreen is the narrowest screen the info pane is shown on
+	MinSidePaneScreen = 90
+	// sidePanePromptLines is how much of the system prompt the pane shows
+	sidePanePromptLines = 6
+)
+
+// sidePaneWidth returns the width taken by the info pane, or 0 while it is
+// hidden or doesn't fit
+func (m Model) sidePaneWidth() int {
+	if !m.ShowSidePane || m.Accessible || len(m.Comparison) > 0 || m.ScreenWidth < MinSidePaneScreen {
+		return 0
+	}
+	return SidePaneWidth
+}
+
+// ToggleSidePane shows or hides the info pane and remembers the choice
+func (m *Model) ToggleSidePane() {
+	m.ShowSidePane = !m.ShowSidePane
+	show := m.ShowSidePane
+	if err := utils.UpdateConfig(func(c *utils.Config) { c.SidePane = show }); err != nil {
+		m.Err = err
+	}
+	switch {
+	case !m.ShowSidePane:
+		m.StatusMessage = "Info pane hidden"
+	case m.sidePaneWidth() == 0:
+		m.StatusMessage = fmt.Sprintf("The info pane needs a wider window (%d columns)", MinSidePaneScreen)
+	default:
+		m.StatusMessage = "Info pane shown"
+	}
+	m.UpdateViewportContent()
+}
+
+// contextWindow returns the context length the provider lists for the
+// selected model, or 0 when it lists none
+func (m Model) contextWindow() int {
+	// Ollama only uses as much of the window as num_ctx allows
+	if numCtx := contextNumCtx(APIClient); numCtx > 0 {
+		return numCtx
+	}
+	for _, model := range m.Models {
+		if model.Name == m.SelectedModel {
+			return model.Details.Context
+		}
+	}
+	return 0
+}
+
+// conversationTokens estimates the tokens the conversation sent to the
+// model takes up
+func (m Model) conversationTokens() int {
+	return m.tokensWith(m.Trim, len(m.Messages), "", 0)
+}
+
+// SidePaneView renders the info pane: the session, the system prompt, the
+// request parameters, how full the context is, the memory and the attached
+// files
+func (m Model) SidePaneView(height int) string {
+	width := SidePaneWidth - SidePaneStyle.GetHorizontalFrameSize()
+	wrap := lipgloss.NewStyle().Width(width)
+	var sb strings.Builder
+	section := func(title string) {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(UserHeaderStyle.Render(title) + "\n")
+	}
+	field := func(name, value string) {
+		sb.WriteString(wrap.Render(CollapsedStyle.Render(name+": ")+value) + "\n")
+	}
+
+	section("Session")
+	title := m.Session.Title
+	if title == "" {
+		title = "unsaved"
+	}
+	field("Title", title)
+	if !m.Session.CreatedAt.IsZero() {
+		field("Started", m.Session.CreatedAt.Format("Jan 2 15:04"))
+	}
+	field("Messages", fmt.Sprint(len(m.Messages)))
+	if len(m.Tabs) > 1 {
+		field("Tab", fmt.Sprintf("%d of %d", m.ActiveTab+1, len(m.Tabs)))
+	}
+	if m.Session.ParentID != "" {
+		field("Forked at", fmt.Sprintf("message %d", m.Session.ForkIndex+1))
+	}
+
+	section("Model")
+	field("Provider", m.SelectedProvider)
+	field("Model", m.SelectedModel)
+
+	section("System prompt")
+	prompt := "none"
+	if APIClient.SystemPrompt != "" {
+		lines := strings.Split(wrap.Render(APIClient.SystemPrompt), "\n")
+		if len(lines) > sidePanePromptLines {
+			lines = append(lines[:sidePanePromptLines], CollapsedStyle.Render("… (/system to see all)"))
+		}
+		prompt = strings.Join(lines, "\n")
+	}
+	sb.WriteString(prompt + "\n")
+
+	if n := len(APIClient.Memories); n > 0 {
+		field("Memories", fmt.Sprintf("%d (/memories)", n))
+	}
+
+	section("Parameters")
+	temperature := "default"
+	if APIClient.Temperature != nil {
+		temperature = fmt.Sprintf("%g", *APIClient.Temperature)
+	}
+	field("Temperature", temperature)
+	if m.Deterministic {
+		field("Mode", "deterministic")
+	}
+	if len(APIClient.Stop) > 0 {
+		field("Stop", fmt.Sprintf("%q", APIClient.Stop))
+	}
+	if APIClient.Seed != nil {
+		field("Seed", fmt.Sprint(*APIClient.Seed))
+	}
+	if len(APIClient.Options) > 0 {
+		field("Options", FormatOptions(APIClient.Options))
+	}
+	if m.KeepAlive != "" {
+		field("Keep alive", m.KeepAlive)
+	}
+	timeout := "none"
+	if m.GenerationTimeout > 0 {
+		timeout = m.GenerationTimeout.String()
+	}
+	field("Time limit", timeout)
+	switch {
+	case m.PendingSchema != nil:
+		field("Format", "schema (next prompt)")
+	case m.JSONFormat != nil:
+		field("Format", "JSON")
+	}
+	if len(APIClient.Tools) > 0 {
+		field("Tools", fmt.Sprint(len(APIClient.Tools)))
+	}
+
+	section("Context")
+	used := m.conversationTokens()
+	if window := m.contextWindow(); window > 0 {
+		percent := float64(used) / float64(window) * 100
+		field("Used", fmt.Sprintf("~%s of %s (%.0f%%)", formatCount(used), formatCount(window), percent))
+		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Accent).Render(Bar(float64(min(used, window)), float64(window), width)) + "\n")
+	} else {
+		field("Used", "~"+formatCount(used)+" tokens")
+	}
+	if m.Trim.Dropped() > 0 {
+		trimmed := fmt.Sprintf("%d messages", m.Trim.Dropped())
+		if m.Trim.Summary != "" {
+			trimmed += " (summarized)"
+		}
+		field("Trimmed", trimmed)
+	}
+	field("Strategy", m.contextStrategy())
+	for i := len(m.Messages) - 1; i >= 0; i-- {
+		if meta := m.Messages[i].Metadata; meta != nil && meta.PromptEvalCount > 0 {
+			field("Last prompt", fmt.Sprintf("%d tokens", meta.PromptEvalCount))
+			break
+		}
+	}
+
+	section("Memory")
+	memory := CollapsedStyle.Render("none")
+	if m.Memorizing {
+		memory = CollapsedStyle.Render("updating…")
+	}
+	if m.Trim.Summary != "" {
+		lines := strings.Split(wrap.Render(m.Trim.Summary), "\n")
+		if len(lines) > sidePanePromptLines {
+			lines = append(lines[:sidePanePromptLines], CollapsedStyle.Render("… (/memory edit to see all)"))
+		}
+		memory = strings.Join(lines, "\n")
+	}
+	if m.AutoMemory {
+		field("Auto", "on")
+	}
+	sb.WriteString(memory + "\n")
+
+	section("Attachments")
+	if len(m.PendingAttachments) == 0 && len(m.CurrentAttachments) == 0 {
+		sb.WriteString(CollapsedStyle.Render("none") + "\n")
+	}
+	for _, a := range m.PendingAttachments {
+		field("Next", a.Name)
+	}
+	for _, a := range m.CurrentAttachments {
+		field("Sent", a.Name)
+	}
+
+	content := strings.TrimRight(sb.String(), "\n")
+	// Keep the pane as tall as the transcript beside it
+	lines := strings.Split(content, "\n")
+	innerHeight := height - SidePaneStyle.GetVerticalFrameSize()
+	if innerHeight > 0 && len(lines) > innerHeight {
+		content = strings.Join(lines[:innerHeight], "\n")
+	}
+	return SidePaneStyle.Width(width + SidePaneStyle.GetHorizontalPadding()).Height(max(innerHeight, 1)).Render(content)
+}
@@ -0,0 +1,308 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/session"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// SlashCommand describes a command typed in the input box with a leading "/"
+type SlashCommand struct {
+	Name        string
+	Usage       string
+	Description string
+	Run         func(m *Model, args string) tea.Cmd
+}
+
+// slashCommands is the registry of available slash commands, keyed by name
+var slashCommands = map[string]SlashCommand{}
+
+// RegisterSlashCommand adds a command to the registry, replacing any
+// existing command with the same name
+func RegisterSlashCommand(cmd SlashCommand) {
+	slashCommands[cmd.Name] = cmd
+}
+
+// SlashCommands returns all registered commands sorted by name
+func SlashCommands() []SlashCommand {
+	cmds := make([]SlashCommand, 0, len(slashCommands))
+	for _, cmd := range slashCommands {
+		cmds = append(cmds, cmd)
+	}
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].Name < cmds[j].Name
+	})
+	return cmds
+}
+
+// MatchSlashCommands returns the commands whose name starts with prefix
+func MatchSlashCommands(prefix string) []SlashCommand {
+	var matches []SlashCommand
+	for _, cmd := range SlashCommands() {
+		if strings.HasPrefix(cmd.Name, prefix) {
+			matches = append(matches, cmd)
+		}
+	}
+	return matches
+}
+
+// ParseSlashCommand splits input like "/temp 0.2" into its name and
+// arguments. It reports false if the input is not a slash command.
+func ParseSlashCommand(input string) (string, string, bool) {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, "/") {
+		return "", "", false
+	}
+
+	name, args, _ := strings.Cut(input[1:], " ")
+	return name, strings.TrimSpace(args), name != ""
+}
+
+// SlashSuggestions returns the completions for the current input, or nil if
+// the autocompletion popup should not be shown
+func (m Model) SlashSuggestions() []SlashCommand {
+	value := m.Input.Value()
+	if m.State != StatePrompting || m.ViewportFocused || !strings.HasPrefix(value, "/") {
+		return nil
+	}
+	if strings.ContainsAny(value, " \n") {
+		return nil
+	}
+	return MatchSlashCommands(value[1:])
+}
+
+// CompleteSlashCommand replaces the input with the selected suggestion
+func (m *Model) CompleteSlashCommand() {
+	suggestions := m.SlashSuggestions()
+	if len(suggestions) == 0 {
+		return
+	}
+	cmd := suggestions[m.SlashIndex%len(suggestions)]
+	m.Input.SetValue("/" + cmd.Name + " ")
+	m.Input.CursorEnd()
+	m.SlashIndex = 0
+}
+
+// RunSlashCommand executes the slash command in input
+func (m *Model) RunSlashCommand(input string) tea.Cmd {
+	name, args, _ := ParseSlashCommand(input)
+
+	cmd, ok := slashCommands[name]
+	if !ok {
+		// Fall back to the highlighted completion for partially typed names
+		if suggestions := m.SlashSuggestions(); args == "" && len(suggestions) > 0 {
+			cmd = suggestions[m.SlashIndex%len(suggestions)]
+			ok = true
+		}
+	}
+
+	m.Input.Reset()
+	m.SlashIndex = 0
+
+	if !ok {
+		m.StatusMessage = fmt.Sprintf("Unknown command: /%s (try /help)", name)
+		return nil
+	}
+	return cmd.Run(m, args)
+}
+
+// SlashPopupView renders the autocompletion popup for slash commands
+func (m Model) SlashPopupView(width int) string {
+	suggestions := m.SlashSuggestions()
+	if len(suggestions) == 0 {
+		return ""
+	}
+
+	selected := m.SlashIndex % len(suggestions)
+	lines := make([]string, 0, len(suggestions))
+	for i, cmd := range suggestions {
+		line := fmt.Sprintf("/%s %s  %s", cmd.Name, cmd.Usage, cmd.Description)
+		if i == selected {
+			line = SlashSelectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	return SlashPopupStyle.Copy().Width(width).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "help",
+		Description: "List available commands",
+		Run: func(m *Model, args string) tea.Cmd {
+			var sb strings.Builder
+			sb.WriteString("Available commands:\n")
+			for _, cmd := range SlashCommands() {
+				sb.WriteString(fmt.Sprintf("  /%s %s - %s\n", cmd.Name, cmd.Usage, cmd.Description))
+			}
+			m.AddNotice(strings.TrimRight(sb.String(), "\n"))
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "model",
+		Usage:       "[name]",
+		Description: "Switch models without losing the conversation",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args != "" {
+				for _, model := range m.Models {
+					if model.Name == args {
+						if args != m.SelectedModel {
+							m.SwitchModel(args)
+							m.RecordModelUse(args)
+						}
+						return nil
+					}
+				}
+				if m.SelectedProvider == "ollama" {
+					// Models not downloaded yet are pulled, then selected
+					return m.StartPull(args, true)
+				}
+				m.StatusMessage = fmt.Sprintf("Unknown model %q", args)
+				return nil
+			}
+
+			return m.OpenModelPicker()
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "system",
+		Usage:       "[prompt]",
+		Description: "Set the system prompt (empty to clear)",
+		Run: func(m *Model, args string) tea.Cmd {
+			APIClient.SystemPrompt = args
+			if args == "" {
+				m.StatusMessage = "System prompt cleared"
+			} else {
+				m.StatusMessage = "System prompt set"
+			}
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "clear",
+		Description: "Clear the transcript and start a new chat",
+		Run: func(m *Model, args string) tea.Cmd {
+			APIClient.ClearContext()
+			m.Messages = []models.Message{}
+			m.ResetTrim()
+			m.Collapsed = map[int]bool{}
+			m.ExpandedReasoning = map[int]bool{}
+			m.SelectedMessage = -1
+			m.Session = session.Session{}
+			m.UpdateViewportContent()
+			m.StatusMessage = "Started a new chat"
+			return tea.SetWindowTitle("ollama-tui")
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "export",
+		Usage:       "[path|html|gist|paste]",
+		Description: "Export the transcript as Markdown or an HTML page (html or a .html path), or upload it to a gist or paste service",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == ShareGist || args == SharePaste {
+				return m.Share(args)
+			}
+			path := args
+			if path == "" || path == "html" {
+				ext := "md"
+				if path == "html" {
+					ext = "html"
+				}
+				path = fmt.Sprintf("ollama-tui-%s.%s", time.Now().Format("20060102-150405"), ext)
+			}
+			export := m.ExportMarkdown
+			if isHTMLPath(path) {
+				export = m.ExportHTML
+			}
+			if err := export(path); err != nil {
+				m.Err = err
+				m.StatusMessage = fmt.Sprintf("Export failed: %v", err)
+				return nil
+			}
+			m.StatusMessage = fmt.Sprintf("Transcript exported to %s", path)
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "temp",
+		Usage:       "<value>",
+		Description: "Set the sampling temperature (0-2, empty to reset)",
+		Run: func(m *Model, args string) tea.Cmd {
+			if m.Deterministic {
+				m.StatusMessage = "Deterministic mode pins the temperature to 0: /deterministic to turn it off"
+				return nil
+			}
+			if args == "" {
+				APIClient.Temperature = nil
+				m.StatusMessage = "Temperature reset to the provider default"
+				return nil
+			}
+			value, err := strconv.ParseFloat(args, 64)
+			if err != nil || value < 0 || value > 2 {
+				m.StatusMessage = fmt.Sprintf("Invalid temperature %q: expected a number between 0 and 2", args)
+				return nil
+			}
+			APIClient.Temperature = &value
+			m.StatusMessage = fmt.Sprintf("Temperature set to %g", value)
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "timeout",
+		Usage:       "<duration>",
+		Description: "Set the generation time limit (e.g. 120s, 0 to disable)",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				m.StatusMessage = fmt.Sprintf("Generation time limit: %s", m.GenerationTimeout)
+				return nil
+			}
+			d, err := time.ParseDuration(args)
+			if err != nil || d < 0 {
+				m.StatusMessage = fmt.Sprintf("Invalid duration %q: expected a value like 90s or 5m", args)
+				return nil
+			}
+			m.GenerationTimeout = d
+			if d == 0 {
+				m.StatusMessage = "Generation time limit disabled"
+			} else {
+				m.StatusMessage = fmt.Sprintf("Generation time limit set to %s", d)
+			}
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "multiline",
+		Description: "Toggle multi-line input (Enter inserts a newline, Alt+Enter sends)",
+		Run: func(m *Model, args string) tea.Cmd {
+			m.MultilineInput = !m.MultilineInput
+			multiline := m.MultilineInput
+			if err := utils.UpdateConfig(func(c *utils.Config) { c.MultilineInput = multiline }); err != nil {
+				m.Err = err
+			}
+			if multiline {
+				m.StatusMessage = fmt.Sprintf("Multi-line input on: Enter inserts a newline, %s sends", m.KeyMap.Help(ActionSend))
+			} else {
+				m.StatusMessage = "Multi-line input off: Enter sends"
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// Snapshot captures the state of a conversation so it can be rolled back to
+type Snapshot struct {
+	Name              string
+	CreatedAt         time.Time
+	Model             string
+	Messages          []models.Message
+	Conversation      api.ConversationState
+	SystemPrompt      string
+	Temperature       *float64
+	GenerationTimeout time.Duration
+}
+
+// TakeSnapshot records the current conversation under name, replacing any
+// existing snapshot with the same name
+func (m *Model) TakeSnapshot(name string) Snapshot {
+	if name == "" {
+		name = fmt.Sprintf("snapshot-%d", len(m.Snapshots)+1)
+	}
+
+	snapshot := Snapshot{
+		Name:              name,
+		CreatedAt:         time.Now(),
+		Model:             m.SelectedModel,
+		Messages:          append([]models.Message(nil), m.Messages...),
+		Conversation:      APIClient.Conversation(),
+		SystemPrompt:      APIClient.SystemPrompt,
+		GenerationTimeout: m.GenerationTimeout,
+	}
+	if APIClient.Temperature != nil {
+		temperature := *APIClient.Temperature
+		snapshot.Temperature = &temperature
+	}
+
+	for i, existing := range m.Snapshots {
+		if existing.Name == name {
+			m.Snapshots[i] = snapshot
+			return snapshot
+		}
+	}
+	m.Snapshots = append(m.Snapshots, snapshot)
+	return snapshot
+}
+
+// RestoreSnapshot rolls the conversation back to the named snapshot
+func (m *Model) RestoreSnapshot(name string) error {
+	for _, snapshot := range m.Snapshots {
+		if snapshot.Name != name {
+			continue
+		}
+
+		m.SelectedModel = snapshot.Model
+		m.Messages = append([]models.Message(nil), snapshot.Messages...)
+		m.ResetTrim()
+		m.Collapsed = map[int]bool{}
+		m.ExpandedReasoning = map[int]bool{}
+		m.SelectedMessage = -1
+		m.GenerationTimeout = snapshot.GenerationTimeout
+		APIClient.RestoreConversation(snapshot.Conversation)
+		APIClient.SystemPrompt = snapshot.SystemPrompt
+		APIClient.Temperature = nil
+		if snapshot.Temperature != nil {
+			temperature := *snapshot.Temperature
+			APIClient.Temperature = &temperature
+		}
+		m.ApplyRequestOptions(APIClient, m.SelectedProvider)
+
+		m.UpdateViewportContent()
+		return nil
+	}
+	return fmt.Errorf("no snapshot named %q", name)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "snapshot",
+		Usage:       "[name]",
+		Description: "Save a named snapshot of the conversation",
+		Run: func(m *Model, args string) tea.Cmd {
+			snapshot := m.TakeSnapshot(args)
+			m.StatusMessage = fmt.Sprintf("Snapshot %q saved (%d messages)", snapshot.Name, len(snapshot.Messages))
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "snapshots",
+		Description: "List saved snapshots",
+		Run: func(m *Model, args string) tea.Cmd {
+			if len(m.Snapshots) == 0 {
+				m.StatusMessage = "No snapshots yet (use /snapshot [name])"
+				return nil
+			}
+
+			var sb strings.Builder
+			sb.WriteString("Snapshots:\n")
+			for _, s := range m.Snapshots {
+				sb.WriteString(fmt.Sprintf("  %s - %s, %d messages, %s\n",
+					s.Name, s.Model, len(s.Messages), s.CreatedAt.Format("15:04:05")))
+			}
+			m.AddNotice(strings.TrimRight(sb.String(), "\n"))
+			return nil
+		},
+	})
+
+	RegisterSlashCommand(SlashCommand{
+		Name:        "rollback",
+		Usage:       "<name>",
+		Description: "Restore the conversation to a saved snapshot",
+		Run: func(m *Model, args string) tea.Cmd {
+			if m.IsGenerating {
+				m.StatusMessage = "Cannot roll back while a response is being generated"
+				return nil
+			}
+			if args == "" {
+				m.StatusMessage = "Usage: /rollback <name> (see /snapshots)"
+				return nil
+			}
+			if err := m.RestoreSnapshot(args); err != nil {
+				m.StatusMessage = err.Error()
+				return nil
+			}
+			m.StatusMessage = fmt.Sprintf("Rolled back to snapshot %q", args)
+			return nil
+		},
+	})
+}
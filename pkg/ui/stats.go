@@ -0,0 +1,367 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/session"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// UsageDays is how many days the tokens per day chart covers
+const UsageDays = 14
+
+// ModelUsage sums the responses of a model
+type ModelUsage struct {
+	Model    string
+	Messages int
+	Tokens   int
+	// EvalCount and EvalDuration cover only the responses with timings, for
+	// the generation speed
+	EvalCount    int
+	EvalDuration time.Duration
+}
+
+// TokensPerSecond returns the model's average generation speed, or 0 when
+// none of its responses were timed
+func (u ModelUsage) TokensPerSecond() float64 {
+	if u.EvalDuration <= 0 {
+		return 0
+	}
+	return float64(u.EvalCount) / u.EvalDuration.Seconds()
+}
+
+// ProviderSpend sums what the responses of a provider cost
+type ProviderSpend struct {
+	Provider string
+	Cost     float64
+	Tokens   int
+	// Unpriced counts the tokens of models without a price
+	Unpriced int
+	Local    bool
+}
+
+// UsageStats summarizes the saved sessions
+type UsageStats struct {
+	Sessions  int
+	Models    []ModelUsage
+	Days      []time.Time
+	DayTokens []int
+	Providers []ProviderSpend
+}
+
+// UsageStatsMsg carries the usage summary once the sessions are read
+type UsageStatsMsg struct {
+	Stats UsageStats
+	Err   error
+}
+
+// exchangeTokens returns the prompt and response tokens of the assistant
+// message at i: what the provider reported, or estimates from the text
+func exchangeTokens(messages []models.Message, i int) (int, int) {
+	msg := messages[i]
+	completion := msg.Tokens
+	if completion == 0 {
+		completion = utils.EstimateTokens(msg.Content)
+	}
+	if msg.Metadata != nil && msg.Metadata.PromptEvalCount > 0 {
+		return msg.Metadata.PromptEvalCount, completion
+	}
+	for j := i - 1; j >= 0; j-- {
+		if messages[j].Role == models.RoleUser {
+			return utils.EstimateTokens(messages[j].Content), completion
+		}
+	}
+	return 0, completion
+}
+
+// ComputeUsageStats sums the responses of sessions by model, by day over the
+// UsageDays up to now and by provider, pricing them with prices
+func ComputeUsageStats(sessions []session.Session, prices map[string]utils.ModelPrice, now time.Time) UsageStats {
+	stats := UsageStats{Sessions: len(sessions)}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for d := UsageDays - 1; d >= 0; d-- {
+		stats.Days = append(stats.Days, today.AddDate(0, 0, -d))
+	}
+	stats.DayTokens = make([]int, UsageDays)
+
+	byModel := map[string]*ModelUsage{}
+	byProvider := map[string]*ProviderSpend{}
+	for _, s := range sessions {
+		for i, msg := range s.Messages {
+			if msg.Role != models.RoleAssistant {
+				continue
+			}
+			name := msg.Model
+			if name == "" {
+				name = s.Model
+			}
+			prompt, completion := exchangeTokens(s.Messages, i)
+
+			usage := byModel[name]
+			if usage == nil {
+				usage = &ModelUsage{Model: name}
+				byModel[name] = usage
+			}
+			usage.Messages++
+			usage.Tokens += prompt + completion
+			if msg.Metadata != nil && msg.Metadata.EvalDuration > 0 {
+				usage.EvalCount += msg.Metadata.EvalCount
+				usage.EvalDuration += msg.Metadata.EvalDuration
+			}
+
+			created := msg.CreatedAt.In(now.Location())
+			day := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, now.Location())
+			if d := int(today.Sub(day).Hours()/24 + 0.5); d >= 0 && d < UsageDays {
+				stats.DayTokens[UsageDays-1-d] += prompt + completion
+			}
+
+			provider := s.Provider
+			if provider == "" {
+				provider = "ollama"
+			}
+			spend := byProvider[provider]
+			if spend == nil {
+				_, keyed := KeyedProviders[provider]
+				spend = &ProviderSpend{Provider: provider, Local: !keyed}
+				byProvider[provider] = spend
+			}
+			spend.Tokens += prompt + completion
+			if spend.Local {
+				continue
+			}
+			if price, ok := prices[name]; ok {
+				spend.Cost += price.Cost(prompt, completion)
+			} else {
+				spend.Unpriced += prompt + completion
+			}
+		}
+	}
+
+	for _, usage := range byModel {
+		stats.Models = append(stats.Models, *usage)
+	}
+	sort.Slice(stats.Models, func(i, j int) bool {
+		if stats.Models[i].Messages != stats.Models[j].Messages {
+			return stats.Models[i].Messages > stats.Models[j].Messages
+		}
+		return stats.Models[i].Model < stats.Models[j].Model
+	})
+	for _, spend := range byProvider {
+		stats.Providers = append(stats.Providers, *spend)
+	}
+	sort.Slice(stats.Providers, func(i, j int) bool {
+		if stats.Providers[i].Cost != stats.Providers[j].Cost {
+			return stats.Providers[i].Cost > stats.Providers[j].Cost
+		}
+		return stats.Providers[i].Provider < stats.Providers[j].Provider
+	})
+	return stats
+}
+
+// UsageStatsCmd reads the saved sessions and summarizes them in the
+// background
+func UsageStatsCmd(store *session.Store, prices map[string]utils.ModelPrice) tea.Cmd {
+	return func() tea.Msg {
+		sessions, err := store.List()
+		if err != nil {
+			return UsageStatsMsg{Err: err}
+		}
+		return UsageStatsMsg{Stats: ComputeUsageStats(sessions, prices, time.Now())}
+	}
+}
+
+// Bar draws value as a bar of at most width cells, max filling all of them
+func Bar(value, max float64, width int) string {
+	if max <= 0 || value <= 0 || width <= 0 {
+		return ""
+	}
+	eighths := int(value / max * float64(width*8))
+	if eighths == 0 {
+		eighths = 1
+	}
+	bar := strings.Repeat("█", eighths/8)
+	if rest := eighths % 8; rest > 0 {
+		bar += string([]rune("▏▎▍▌▋▊▉")[rest-1])
+	}
+	return bar
+}
+
+// barRow renders a label, its bar and its value in columns
+func barRow(label string, labelWidth int, value, max float64, barWidth int, text string) string {
+	bar := lipgloss.NewStyle().Foreground(CurrentTheme.Accent).Render(Bar(value, max, barWidth))
+	padding := strings.Repeat(" ", barWidth-lipgloss.Width(Bar(value, max, barWidth)))
+	return fmt.Sprintf("  %-*s %s%s %s\n", labelWidth, truncate(label, labelWidth), bar, padding, text)
+}
+
+// formatCount abbreviates large counts: 1234 as 1.2k, 2500000 as 2.5M
+func formatCount(n int) string {
+	switch {
+	case n >= 1000000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	}
+	return fmt.Sprint(n)
+}
+
+// OpenUsageStats shows the usage screen and reads the saved sessions for it
+func (m *Model) OpenUsageStats() tea.Cmd {
+	if m.SessionStore == nil {
+		store, err := session.DefaultStore()
+		if err != nil {
+			m.StatusMessage = fmt.Sprintf("Failed to open sessions: %v", err)
+			return nil
+		}
+		m.SessionStore = store
+	}
+	// The current conversation counts too
+	m.AutosaveSession()
+
+	if m.State != StateUsage {
+		m.UsageReturnState = m.State
+	}
+	m.State = StateUsage
+	m.UsageLoading = true
+	m.UpdateUsageView()
+	return tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight), UsageStatsCmd(m.SessionStore, m.LoadedConfig.Prices))
+}
+
+// HandleUsageStats shows the usage summary
+func (m *Model) HandleUsageStats(msg UsageStatsMsg) {
+	m.UsageLoading = false
+	m.Usage = msg.Stats
+	m.UsageErr = msg.Err
+	m.UpdateUsageView()
+}
+
+// UpdateUsage handles keys on the usage screen
+func (m Model) UpdateUsage(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		m.State = m.UsageReturnState
+		return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+	case "ctrl+c":
+		return m, m.RequestQuit()
+	case "r":
+		if m.UsageLoading {
+			return m, nil
+		}
+		m.UsageLoading = true
+		m.UpdateUsageView()
+		return m, UsageStatsCmd(m.SessionStore, m.LoadedConfig.Prices)
+	}
+
+	var cmd tea.Cmd
+	m.UsageViewport, cmd = m.UsageViewport.Update(msg)
+	return m, cmd
+}
+
+// UpdateUsageView renders the usage summary into the usage viewport
+func (m *Model) UpdateUsageView() {
+	switch {
+	case m.UsageLoading:
+		m.UsageViewport.SetContent("Reading sessions…")
+		return
+	case m.UsageErr != nil:
+		m.UsageViewport.SetContent(ErrorTextStyle.Render(fmt.Sprintf("Failed to read sessions: %v", m.UsageErr)))
+		return
+	case len(m.Usage.Models) == 0:
+		m.UsageViewport.SetContent("No responses saved yet")
+		return
+	}
+
+	const labelWidth = 24
+	const valueWidth = 22
+	barWidth := m.UsageViewport.Width - labelWidth - valueWidth - 4
+	if barWidth < 10 {
+		barWidth = 10
+	}
+	stats := m.Usage
+	var sb strings.Builder
+
+	sb.WriteString(SelectedHeaderStyle.Render(fmt.Sprintf("Messages per model (%d sessions)", stats.Sessions)) + "\n")
+	maxMessages := 0
+	for _, u := range stats.Models {
+		maxMessages = max(maxMessages, u.Messages)
+	}
+	for _, u := range stats.Models {
+		sb.WriteString(barRow(u.Model, labelWidth, float64(u.Messages), float64(maxMessages), barWidth,
+			fmt.Sprintf("%d · %s tokens", u.Messages, formatCount(u.Tokens))))
+	}
+
+	sb.WriteString("\n" + SelectedHeaderStyle.Render(fmt.Sprintf("Tokens per day (last %d days)", UsageDays)) + "\n")
+	maxTokens := 0
+	for _, n := range stats.DayTokens {
+		maxTokens = max(maxTokens, n)
+	}
+	for i, day := range stats.Days {
+		sb.WriteString(barRow(day.Format("Mon Jan 2"), labelWidth, float64(stats.DayTokens[i]), float64(maxTokens), barWidth, formatCount(stats.DayTokens[i])))
+	}
+
+	sb.WriteString("\n" + SelectedHeaderStyle.Render("Average speed per model") + "\n")
+	maxSpeed := 0.0
+	for _, u := range stats.Models {
+		maxSpeed = max(maxSpeed, u.TokensPerSecond())
+	}
+	timed := false
+	for _, u := range stats.Models {
+		if speed := u.TokensPerSecond(); speed > 0 {
+			sb.WriteString(barRow(u.Model, labelWidth, speed, maxSpeed, barWidth, fmt.Sprintf("%.1f tok/s", speed)))
+			timed = true
+		}
+	}
+	if !timed {
+		sb.WriteString(CollapsedStyle.Render("  No response was timed by its provider") + "\n")
+	}
+
+	sb.WriteString("\n" + SelectedHeaderStyle.Render("Estimated spend per provider") + "\n")
+	maxCost := 0.0
+	unpriced := false
+	for _, p := range stats.Providers {
+		maxCost = max(maxCost, p.Cost)
+	}
+	for _, p := range stats.Providers {
+		text := fmt.Sprintf("$%.2f", p.Cost)
+		switch {
+		case p.Local:
+			text = "local · " + formatCount(p.Tokens) + " tokens"
+		case p.Unpriced > 0:
+			text += fmt.Sprintf(" + %s unpriced", formatCount(p.Unpriced))
+			unpriced = true
+		}
+		sb.WriteString(barRow(p.Provider, labelWidth, p.Cost, maxCost, barWidth, text))
+	}
+	if unpriced {
+		sb.WriteString(CollapsedStyle.Render(`  → Set "prices" in config.json to price the remaining models`) + "\n")
+	}
+
+	m.UsageViewport.SetContent(strings.TrimRight(sb.String(), "\n"))
+}
+
+// UsageView renders the usage screen
+func (m Model) UsageView() string {
+	help := "r: refresh | ↑/↓: scroll | Esc: back"
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		TitleStyle.Render("Usage"),
+		lipgloss.NewStyle().Padding(0, 2).Render(m.UsageViewport.View()),
+		HelpStyle.Render(help),
+	)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "stats",
+		Description: "Show usage from saved sessions: messages, tokens, speed and spend",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.OpenUsageStats()
+		},
+	})
+}
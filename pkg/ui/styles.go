@@ -4,32 +4,182 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// The styles are built from the current theme by setStyles, and built
+// again when the theme changes
 var (
 	// TitleStyle is the style for titles
-	TitleStyle = lipgloss.NewStyle().
-			MarginLeft(2).
-			Bold(true).
-			Foreground(lipgloss.Color("#FF5F87"))
+	TitleStyle lipgloss.Style
 
 	// ResponseStyle is the style for responses
-	ResponseStyle = lipgloss.NewStyle().
-			MarginLeft(2).
-			MarginRight(2)
+	ResponseStyle lipgloss.Style
 
 	// StatusBarStyle is the style for the status bar
-	StatusBarStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#AFAFAF")).
-			Reverse(true)
+	StatusBarStyle lipgloss.Style
 
 	// InputBoxStyle is the style for the input box
-	InputBoxStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#FF5F87")).
-			Padding(0, 1)
+	InputBoxStyle lipgloss.Style
+
+	// SlashPopupStyle is the style for the slash command autocompletion popup
+	SlashPopupStyle lipgloss.Style
+
+	// SlashSelectedStyle is the style for the highlighted slash command
+	SlashSelectedStyle lipgloss.Style
+
+	// SearchMatchStyle is the style for transcript search matches
+	SearchMatchStyle lipgloss.Style
+
+	// UserHeaderStyle is the style for the header of user messages
+	UserHeaderStyle lipgloss.Style
+
+	// AssistantHeaderStyle is the style for the header of model responses
+	AssistantHeaderStyle lipgloss.Style
+
+	// UserMessageStyle shades the prompts in the transcript
+	UserMessageStyle lipgloss.Style
+
+	// AssistantMessageStyle indents the responses in the transcript
+	AssistantMessageStyle lipgloss.Style
+
+	// SelectedHeaderStyle is the style for the header of the selected message
+	SelectedHeaderStyle lipgloss.Style
+
+	// CollapsedStyle is the style for the note shown in collapsed messages
+	CollapsedStyle lipgloss.Style
+
+	// ReasoningStyle is the style for the reasoning of thinking models
+	ReasoningStyle lipgloss.Style
+
+	// MetadataStyle is the style for the metadata footer under responses
+	MetadataStyle lipgloss.Style
+
+	// JSONViolationStyle highlights JSON values that do not match the schema
+	JSONViolationStyle lipgloss.Style
+
+	// ShellProgramStyle is the style for programs in a shell command
+	ShellProgramStyle lipgloss.Style
+
+	// ShellFlagStyle is the style for flags in a shell command
+	ShellFlagStyle lipgloss.Style
+
+	// ShellStringStyle is the style for quoted strings in a shell command
+	ShellStringStyle lipgloss.Style
+
+	// ShellVariableStyle is the style for variables and assignments in a shell command
+	ShellVariableStyle lipgloss.Style
+
+	// ShellOperatorStyle is the style for pipes, separators and redirections
+	ShellOperatorStyle lipgloss.Style
+
+	// DiffAddedStyle is the style for added lines in a diff
+	DiffAddedStyle lipgloss.Style
+
+	// DiffRemovedStyle is the style for removed lines in a diff
+	DiffRemovedStyle lipgloss.Style
+
+	// ErrorTextStyle is the style for errors shown on a screen
+	ErrorTextStyle lipgloss.Style
+
+	// InfoMessageStyle is the style for local notices in the transcript
+	InfoMessageStyle lipgloss.Style
+
+	// HelpStyle is the style for the help line at the bottom of a screen
+	HelpStyle lipgloss.Style
+
+	// ComparePaneStyle is the style for a pane in comparison mode
+	ComparePaneStyle lipgloss.Style
+
+	// SidePaneStyle is the style for the info pane beside the transcript
+	SidePaneStyle lipgloss.Style
 
 	// ContainerStyle is the style for the container
-	ContainerStyle = lipgloss.NewStyle()
+	ContainerStyle lipgloss.Style
 
 	// ChatAreaStyle is the style for the chat area
-	ChatAreaStyle = lipgloss.NewStyle()
+	ChatAreaStyle lipgloss.Style
 )
+
+// setStyles builds every style from the colors of t
+func setStyles(t Theme) {
+	TitleStyle = lipgloss.NewStyle().
+		MarginLeft(2).
+		Bold(true).
+		Foreground(t.Accent)
+	ResponseStyle = lipgloss.NewStyle().
+		MarginLeft(2).
+		MarginRight(2)
+	StatusBarStyle = lipgloss.NewStyle().
+		Foreground(t.Muted).
+		Reverse(true)
+	InputBoxStyle = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(t.Accent).
+		Padding(0, 1)
+	SlashPopupStyle = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(t.Muted).
+		Padding(0, 1).
+		MarginLeft(2)
+	SlashSelectedStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.Accent)
+	SearchMatchStyle = lipgloss.NewStyle().
+		Foreground(t.OnHighlight).
+		Background(t.Highlight)
+	UserHeaderStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.Secondary)
+	AssistantHeaderStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.Accent)
+	UserMessageStyle = lipgloss.NewStyle().
+		Background(t.PromptBackground).
+		Padding(0, 1)
+	AssistantMessageStyle = lipgloss.NewStyle().
+		PaddingLeft(2)
+	SelectedHeaderStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.OnHighlight).
+		Background(t.Highlight)
+	CollapsedStyle = lipgloss.NewStyle().
+		Italic(true).
+		Foreground(t.Subtle)
+	ReasoningStyle = lipgloss.NewStyle().
+		Faint(true).
+		Italic(true).
+		Foreground(t.Faint)
+	MetadataStyle = lipgloss.NewStyle().
+		Faint(true).
+		Foreground(t.Subtle)
+	JSONViolationStyle = lipgloss.NewStyle().
+		Foreground(t.Danger)
+	ShellProgramStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.Secondary)
+	ShellFlagStyle = lipgloss.NewStyle().
+		Foreground(t.Highlight)
+	ShellStringStyle = lipgloss.NewStyle().
+		Foreground(t.Success)
+	ShellVariableStyle = lipgloss.NewStyle().
+		Foreground(t.Tertiary)
+	ShellOperatorStyle = lipgloss.NewStyle().
+		Foreground(t.Accent)
+	DiffAddedStyle = lipgloss.NewStyle().
+		Foreground(t.Success)
+	DiffRemovedStyle = lipgloss.NewStyle().
+		Foreground(t.Danger)
+	ErrorTextStyle = lipgloss.NewStyle().
+		Foreground(t.Danger)
+	InfoMessageStyle = lipgloss.NewStyle().
+		Foreground(t.Muted)
+	HelpStyle = lipgloss.NewStyle().Padding(0, 2).Foreground(t.Subtle)
+	ComparePaneStyle = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(t.Muted).
+		Padding(0, 1)
+	SidePaneStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Muted).
+		Padding(0, 1)
+	ContainerStyle = lipgloss.NewStyle()
+	ChatAreaStyle = lipgloss.NewStyle()
+}
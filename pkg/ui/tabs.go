@@ -0,0 +1,363 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/session"
+)
+
+// Tab is a conversation with its own model, transcript and response in
+// progress. The fields of the tab on screen are promoted to Model.
+type Tab struct {
+	// TabID tells which tab the messages of a response belong to
+	TabID              int
+	SelectedProvider   string
+	SelectedModel      string
+	Messages           []models.Message
+	Collapsed          map[int]bool
+	ExpandedReasoning  map[int]bool
+	SelectedMessage    int
+	CurrentPrompt      string
+	CurrentResponse    string
+	InProgressResponse string
+	IsGenerating       bool
+	CancelGenerate     context.CancelFunc
+	GenerationID       int
+	GenerationStart    time.Time
+	// FirstTokenAt, StreamedTokens and ColdStart describe the progress of
+	// the response being generated
+	FirstTokenAt       time.Time
+	StreamedTokens     int
+	ColdStart          bool
+	LastExchange       ExchangeStats
+	Snapshots          []Snapshot
+	PendingAttachments []models.Attachment
+	// Queue holds the prompts submitted during a response
+	Queue              []QueuedPrompt
+	CurrentAttachments []models.Attachment
+	Comparison         []ComparePane
+	CompareRound       int
+	CompareOffset      int
+	Session            session.Session
+
+	// JSONFormat is "json" or a JSON Schema the responses must follow
+	JSONFormat json.RawMessage
+	// PendingSchema is a JSON Schema that applies to the next prompt only
+	PendingSchema json.RawMessage
+	// Trim is what was left out of the conversation to fit the context,
+	// with the memory standing in for it
+	Trim ContextTrim
+	// MemoryID tells the memory being summarized in the background from
+	// those discarded, and PendingMemory holds it until the response
+	// being generated is done
+	MemoryID      int
+	Memorizing    bool
+	PendingMemory *ContextTrim
+	// Continued is the response as it was before /continue resumed it
+	Continued *models.Message
+	// Exchanges are the HTTP requests of the latest response and the raw
+	// replies, for the inspector
+	Exchanges []api.Exchange
+
+	// Client remembers the conversation while the tab is in the background,
+	// with what was typed in its input box and how far it was scrolled
+	Client  *api.Client
+	Draft   string
+	YOffset int
+
+	// NewLines counts the lines of the response added below the scroll
+	// position since the transcript was last at the bottom
+	NewLines int
+	// Blocks are the messages as last rendered in the transcript
+	Blocks []RenderedBlock
+}
+
+// NewTab returns an empty conversation
+func NewTab() Tab {
+	return Tab{
+		Messages:          []models.Message{},
+		Collapsed:         map[int]bool{},
+		ExpandedReasoning: map[int]bool{},
+		SelectedMessage:   -1,
+	}
+}
+
+// Label names the tab in the tab bar
+func (t Tab) Label() string {
+	label := t.SelectedModel
+	if t.Session.Title != "" {
+		label = t.Session.Title
+	}
+	return truncate(label, 24)
+}
+
+// tabScreen is what is drawn of the tab on screen, kept aside while a
+// message of a tab in the background is handled
+type tabScreen struct {
+	state           int
+	input           string
+	transcript      string
+	viewport        int
+	searchIndex     int
+	messageOffsets  []int
+	imagePlacements []ImagePlacement
+	printedMessages int
+	printedLines    int
+}
+
+func (m Model) saveScreen() tabScreen {
+	return tabScreen{
+		state:           m.State,
+		input:           m.Input.Value(),
+		transcript:      m.Transcript,
+		viewport:        m.Viewport.YOffset,
+		searchIndex:     m.SearchIndex,
+		messageOffsets:  m.MessageOffsets,
+		imagePlacements: m.ImagePlacements,
+		printedMessages: m.PrintedMessages,
+		printedLines:    m.PrintedLines,
+	}
+}
+
+func (m *Model) restoreScreen(s tabScreen) {
+	m.State = s.state
+	if m.Input.Value() != s.input {
+		m.Input.SetValue(s.input)
+		m.ResizeInput()
+	}
+	m.Transcript = s.transcript
+	m.refreshSearch()
+	m.SearchIndex = s.searchIndex
+	m.Viewport.SetYOffset(s.viewport)
+	m.MessageOffsets = s.messageOffsets
+	m.ImagePlacements = s.imagePlacements
+	m.PrintedMessages = s.printedMessages
+	m.PrintedLines = s.printedLines
+}
+
+// tabIndex returns the position of the tab with the given ID, or -1 once
+// it is closed
+func (m Model) tabIndex(id int) int {
+	if len(m.Tabs) == 0 && id == m.TabID {
+		return 0
+	}
+	for i, t := range m.Tabs {
+		if t.TabID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// storeTab puts the tab on screen back in Tabs with its client, input and
+// scroll position
+func (m *Model) storeTab() {
+	m.Tab.Client = APIClient
+	m.Tab.Draft = m.Input.Value()
+	m.Tab.YOffset = m.Viewport.YOffset
+	if len(m.Tabs) == 0 {
+		m.Tabs = []Tab{m.Tab}
+	}
+	m.Tabs[m.ActiveTab] = m.Tab
+}
+
+// showTab brings the tab at index i on screen
+func (m *Model) showTab(i int) tea.Cmd {
+	provider := m.SelectedProvider
+	m.ActiveTab = i
+	m.Tab = m.Tabs[i]
+	APIClient = m.Tab.Client
+	m.ApplyRequestOptions(APIClient, m.SelectedProvider)
+	m.Input.SetValue(m.Tab.Draft)
+	m.ResizeInput()
+	m.State = StatePrompting
+	if m.IsGenerating {
+		m.State = StateLoading
+	}
+	m.PrintedMessages, m.PrintedLines = 0, 0
+	m.RefreshTranscript()
+	m.Viewport.SetYOffset(m.Tab.YOffset)
+
+	cmds := []tea.Cmd{tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight)}
+	if m.Session.Title != "" {
+		cmds = append(cmds, tea.SetWindowTitle("ollama-tui · "+m.Session.Title))
+	}
+	// The model list shows the models of the tab's provider
+	if m.SelectedProvider != provider {
+		cmds = append(cmds, m.RefreshModelsCmd())
+	}
+	return tea.Batch(cmds...)
+}
+
+// comparing reports whether a comparison is streaming, which keeps the tab
+// on screen until it is done
+func (m Model) comparing() bool {
+	return len(m.Comparison) > 0 && m.IsGenerating
+}
+
+// OpenTab starts a new conversation with the current model in a new tab,
+// leaving the current one running in the background
+func (m *Model) OpenTab() tea.Cmd {
+	if m.comparing() {
+		m.StatusMessage = "Wait for the comparison to finish before opening a tab"
+		return nil
+	}
+	client := APIClient.NewConversation()
+	m.storeTab()
+
+	m.NextTabID++
+	tab := NewTab()
+	tab.TabID = m.NextTabID
+	tab.SelectedProvider = m.SelectedProvider
+	tab.SelectedModel = m.SelectedModel
+	tab.Client = client
+	m.Tabs = append(m.Tabs, tab)
+	cmd := m.showTab(len(m.Tabs) - 1)
+	m.StatusMessage = fmt.Sprintf("Opened tab %d", len(m.Tabs))
+	return cmd
+}
+
+// SwitchTab brings the tab at index i on screen
+func (m *Model) SwitchTab(i int) tea.Cmd {
+	if len(m.Tabs) < 2 || i == m.ActiveTab {
+		return nil
+	}
+	if m.comparing() {
+		m.StatusMessage = "Wait for the comparison to finish before switching tabs"
+		return nil
+	}
+	i = (i + len(m.Tabs)) % len(m.Tabs)
+	m.storeTab()
+	cmd := m.showTab(i)
+	m.StatusMessage = ""
+	return cmd
+}
+
+// CloseTab cancels the response of the tab on screen, saves its session and
+// closes it
+func (m *Model) CloseTab() tea.Cmd {
+	if len(m.Tabs) < 2 {
+		m.StatusMessage = "This is the only tab"
+		return nil
+	}
+	if m.IsGenerating && m.CancelGenerate != nil {
+		m.CancelGenerate()
+	}
+	m.AutosaveSession()
+
+	closed := m.ActiveTab
+	m.Tabs = append(m.Tabs[:closed], m.Tabs[closed+1:]...)
+	cmd := m.showTab(min(closed, len(m.Tabs)-1))
+	m.StatusMessage = fmt.Sprintf("Closed tab %d", closed+1)
+	return cmd
+}
+
+// SaveTabs saves the session of the tab on screen and of every tab in the
+// background with unsaved messages
+func (m *Model) SaveTabs() error {
+	if err := m.SaveSession(); err != nil {
+		return err
+	}
+	active := m.Tab
+	defer func() { m.Tab = active }()
+	for i := range m.Tabs {
+		if i == m.ActiveTab || !m.Tabs[i].unsaved() {
+			continue
+		}
+		m.Tab = m.Tabs[i]
+		err := m.SaveSession()
+		m.Tabs[i] = m.Tab
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateTab handles a message of the tab with the given ID while it is in
+// the background, as if it were on screen, then puts the tab on screen back
+func (m Model) UpdateTab(id int, msg tea.Msg) (tea.Model, tea.Cmd) {
+	i := m.tabIndex(id)
+	if i < 0 {
+		return m, nil
+	}
+	screen := m.saveScreen()
+	active := m.ActiveTab
+	m.storeTab()
+	m.ActiveTab = i
+	m.Tab = m.Tabs[i]
+	APIClient = m.Tab.Client
+	generating := m.IsGenerating
+	// Scroll the tab's own transcript so it follows or counts new lines
+	m.RefreshTranscript()
+	m.Viewport.SetYOffset(m.Tab.YOffset)
+
+	result, cmd := m.Update(msg)
+	m = result.(Model)
+
+	m.Tab.Client = APIClient
+	m.Tab.YOffset = m.Viewport.YOffset
+	m.Tabs[i] = m.Tab
+	if generating && !m.IsGenerating {
+		m.StatusMessage = fmt.Sprintf("Tab %d finished its response", i+1)
+	}
+	m.ActiveTab = active
+	m.Tab = m.Tabs[active]
+	APIClient = m.Tab.Client
+	m.restoreScreen(screen)
+	return m, cmd
+}
+
+// TabBar renders the tabs for the title line, or nothing with a single tab
+func (m Model) TabBar() string {
+	if len(m.Tabs) < 2 {
+		return ""
+	}
+	var labels []string
+	for i, t := range m.Tabs {
+		if i == m.ActiveTab {
+			t = m.Tab
+		}
+		label := fmt.Sprintf(" %d %s ", i+1, t.Label())
+		if t.IsGenerating {
+			label += "● "
+		}
+		if i == m.ActiveTab {
+			labels = append(labels, SelectedHeaderStyle.Render("["+strings.TrimSpace(label)+"]"))
+		} else {
+			labels = append(labels, CollapsedStyle.Render(label))
+		}
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, labels...)
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "tab",
+		Description: "Open a tab, switch to tab <n> or close the current one (close)",
+		Run: func(m *Model, args string) tea.Cmd {
+			switch args = strings.TrimSpace(args); args {
+			case "", "new":
+				return m.OpenTab()
+			case "close":
+				return m.CloseTab()
+			}
+			n, err := strconv.Atoi(args)
+			if err != nil || n < 1 || n > max(len(m.Tabs), 1) {
+				m.StatusMessage = fmt.Sprintf("No tab %q", args)
+				return nil
+			}
+			return m.SwitchTab(n - 1)
+		},
+	})
+}
@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DefaultTheme is the theme used when config.json names none
+const DefaultTheme = "default"
+
+// Theme is the palette every style of the interface is built from
+type Theme struct {
+	Name string
+	// Accent marks titles, borders of focused elements and model responses
+	Accent lipgloss.TerminalColor
+	// OnAccent is text drawn on the accent color
+	OnAccent lipgloss.TerminalColor
+	// Muted is for the status bar, unfocused borders and notices
+	Muted lipgloss.TerminalColor
+	// Subtle is for help lines, footers and collapsed messages
+	Subtle lipgloss.TerminalColor
+	// Faint is for the reasoning of thinking models
+	Faint lipgloss.TerminalColor
+	// Secondary marks prompts and programs
+	Secondary lipgloss.TerminalColor
+	// Tertiary marks shell variables
+	Tertiary lipgloss.TerminalColor
+	// Highlight is the background of search matches and the selected message,
+	// with OnHighlight the text drawn on it
+	Highlight   lipgloss.TerminalColor
+	OnHighlight lipgloss.TerminalColor
+	// Success, Warning and Danger are for added lines, warnings and errors
+	Success lipgloss.TerminalColor
+	Warning lipgloss.TerminalColor
+	Danger  lipgloss.TerminalColor
+	// PromptBackground shades the prompts in the transcript
+	PromptBackground lipgloss.TerminalColor
+}
+
+// Themes are the built-in themes, by name: default for dark terminals, light
+// for light ones and mono for no colors at all
+var Themes = map[string]Theme{
+	DefaultTheme: {
+		Name:             DefaultTheme,
+		Accent:           lipgloss.Color("#FF5F87"),
+		OnAccent:         lipgloss.Color("#FFFFFF"),
+		Muted:            lipgloss.Color("#AFAFAF"),
+		Subtle:           lipgloss.Color("#767676"),
+		Faint:            lipgloss.Color("#8A8A8A"),
+		Secondary:        lipgloss.Color("#5FAFFF"),
+		Tertiary:         lipgloss.Color("#D787FF"),
+		Highlight:        lipgloss.Color("#FFD75F"),
+		OnHighlight:      lipgloss.Color("#000000"),
+		Success:          lipgloss.Color("#87D787"),
+		Warning:          lipgloss.Color("#FFAF00"),
+		Danger:           lipgloss.Color("#FF5F5F"),
+		PromptBackground: lipgloss.AdaptiveColor{Light: "#EEEEEE", Dark: "#262626"},
+	},
+	"light": {
+		Name:             "light",
+		Accent:           lipgloss.Color("#D7005F"),
+		OnAccent:         lipgloss.Color("#FFFFFF"),
+		Muted:            lipgloss.Color("#585858"),
+		Subtle:           lipgloss.Color("#6C6C6C"),
+		Faint:            lipgloss.Color("#808080"),
+		Secondary:        lipgloss.Color("#005FAF"),
+		Tertiary:         lipgloss.Color("#8700AF"),
+		Highlight:        lipgloss.Color("#FFD75F"),
+		OnHighlight:      lipgloss.Color("#000000"),
+		Success:          lipgloss.Color("#008700"),
+		Warning:          lipgloss.Color("#AF5F00"),
+		Danger:           lipgloss.Color("#D70000"),
+		PromptBackground: lipgloss.Color("#EEEEEE"),
+	},
+	"mono": {
+		Name:             "mono",
+		Accent:           lipgloss.NoColor{},
+		OnAccent:         lipgloss.NoColor{},
+		Muted:            lipgloss.NoColor{},
+		Subtle:           lipgloss.NoColor{},
+		Faint:            lipgloss.NoColor{},
+		Secondary:        lipgloss.NoColor{},
+		Tertiary:         lipgloss.NoColor{},
+		Highlight:        lipgloss.NoColor{},
+		OnHighlight:      lipgloss.NoColor{},
+		Success:          lipgloss.NoColor{},
+		Warning:          lipgloss.NoColor{},
+		Danger:           lipgloss.NoColor{},
+		PromptBackground: lipgloss.NoColor{},
+	},
+}
+
+// CurrentTheme is the theme the styles were last built from
+var CurrentTheme = Themes[DefaultTheme]
+
+func init() {
+	setStyles(CurrentTheme)
+}
+
+// ThemeNames returns the names of the built-in themes in order
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ApplyTheme rebuilds every style from the theme called name, or the
+// default theme when name is empty. An unknown name leaves the styles as
+// they were.
+func ApplyTheme(name string) error {
+	if name == "" {
+		name = DefaultTheme
+	}
+	theme, ok := Themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q (use %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	CurrentTheme = theme
+	setStyles(theme)
+	return nil
+}
+
+// RefreshTheme gives the parts of the model that copied a style when they
+// were built the styles of the current theme, and renders the transcript
+// again
+func (m *Model) RefreshTheme() {
+	m.Spinner.Style = lipgloss.NewStyle().Foreground(CurrentTheme.Accent)
+	m.ProviderList.Styles.Title = TitleStyle
+	m.List.Styles.Title = TitleStyle
+	m.SessionList.Styles.Title = TitleStyle
+	m.RegistryList.Styles.Title = TitleStyle
+	for _, t := range []*table.Model{&m.ConflictTable, &m.RunningModelsTable, &m.CredentialsTable, &m.MemoriesTable} {
+		t.SetStyles(TableStyles())
+	}
+	m.RefreshTranscript()
+}
+
+// TableStyles returns the styles of the tables of the interface
+func TableStyles() table.Styles {
+	styles := table.DefaultStyles()
+	styles.Header = styles.Header.Bold(true).Foreground(CurrentTheme.Accent)
+	styles.Selected = styles.Selected.Foreground(CurrentTheme.OnAccent).Background(CurrentTheme.Accent)
+	return styles
+}
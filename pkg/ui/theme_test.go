@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReloadConfigTheme(t *testing.T) {
+	m := newTestModel(t, 80, 24)
+	t.Cleanup(func() { ApplyTheme(DefaultTheme) })
+
+	config := m.LoadedConfig
+	config.Theme = "light"
+	if notice := m.ReloadConfig(config, nil); !strings.Contains(notice, "theme") {
+		t.Errorf("switching to the light theme gave the notice %q", notice)
+	}
+	if CurrentTheme.Name != "light" || TitleStyle.GetForeground() != Themes["light"].Accent {
+		t.Errorf("the styles were not rebuilt from the light theme")
+	}
+	if m.List.Styles.Title.GetForeground() != Themes["light"].Accent {
+		t.Errorf("the model list kept the title style of the previous theme")
+	}
+
+	config.Theme = "neon"
+	if notice := m.ReloadConfig(config, nil); !strings.Contains(notice, "kept the current theme") {
+		t.Errorf("an unknown theme gave the notice %q", notice)
+	}
+	if CurrentTheme.Name != "light" {
+		t.Errorf("an unknown theme replaced the current one with %q", CurrentTheme.Name)
+	}
+
+	if err := ApplyTheme(""); err != nil || CurrentTheme.Name != DefaultTheme {
+		t.Errorf("ApplyTheme(\"\") = %v with the theme %q, want the default", err, CurrentTheme.Name)
+	}
+}
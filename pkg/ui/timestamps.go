@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// SetResponseDuration records how long the latest response took
+func (m *Model) SetResponseDuration(d time.Duration) {
+	if len(m.Messages) == 0 {
+		return
+	}
+	last := &m.Messages[len(m.Messages)-1]
+	if last.Role == models.RoleAssistant {
+		last.Duration = d
+	}
+}
+
+// ToggleTimestamps shows or hides the times and durations in the message
+// headers and remembers the choice
+func (m *Model) ToggleTimestamps() {
+	m.ShowTimestamps = !m.ShowTimestamps
+	hide := !m.ShowTimestamps
+	if err := utils.UpdateConfig(func(c *utils.Config) { c.HideTimestamps = hide }); err != nil {
+		m.Err = err
+	}
+	if m.ShowTimestamps {
+		m.StatusMessage = "Timestamps shown"
+	} else {
+		m.StatusMessage = "Timestamps hidden"
+	}
+	m.RefreshTranscript()
+}
+
+// exportedTiming describes when a message was written and how long a
+// response took, for the heading of an exported message
+func exportedTiming(msg models.Message) string {
+	var timing string
+	if !msg.CreatedAt.IsZero() {
+		timing = " · " + msg.CreatedAt.Format("2006-01-02 15:04:05")
+	}
+	if msg.Duration > 0 {
+		timing += " · " + formatDuration(msg.Duration)
+	}
+	return timing
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "timestamps",
+		Description: "Show or hide the time of each message and how long each response took",
+		Run: func(m *Model, args string) tea.Cmd {
+			m.ToggleTimestamps()
+			return nil
+		},
+	})
+}
@@ -0,0 +1,317 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+const (
+	// CollapseThreshold is the number of lines above which a message can be collapsed
+	CollapseThreshold = 12
+	// collapsedPreviewLines is the number of lines shown for a collapsed message
+	collapsedPreviewLines = 4
+)
+
+// AddNotice appends a local informational message to the transcript
+func (m *Model) AddNotice(text string) {
+	m.Messages = append(m.Messages, models.Message{Role: models.RoleInfo, Content: text})
+	m.UpdateViewportContent()
+}
+
+// ChatHistory converts the transcript into the message history sent to a
+// model, leaving out the messages trimmed to fit the context
+func (m Model) ChatHistory() []models.ChatMessage {
+	return m.sentHistory(len(m.Messages))
+}
+
+// chatHistory converts messages of the transcript into a message history
+func chatHistory(messages []models.Message) []models.ChatMessage {
+	history := make([]models.ChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == models.RoleInfo || msg.Content == "" {
+			continue
+		}
+		// Reasoning is not sent back to the model
+		content := msg.Answer()
+		if content == "" {
+			continue
+		}
+		history = append(history, models.ChatMessage{Role: msg.Role, Content: content})
+	}
+	return history
+}
+
+// SwitchModel changes the active model while keeping the conversation
+func (m *Model) SwitchModel(name string) {
+	previous := m.SelectedModel
+	m.SelectedModel = name
+	if len(m.Messages) == 0 {
+		return
+	}
+
+	APIClient.SwitchModel(m.ChatHistory())
+	m.AddNotice(fmt.Sprintf("Switched from %s to %s; the conversation continues with the new model", previous, name))
+	m.StatusMessage = fmt.Sprintf("Now chatting with %s", name)
+}
+
+// UpdateResponse replaces the content of the message being generated
+func (m *Model) UpdateResponse(response string) {
+	if len(m.Messages) == 0 {
+		return
+	}
+	last := &m.Messages[len(m.Messages)-1]
+	if last.Role != models.RoleAssistant {
+		return
+	}
+	last.Content = response
+	last.Tokens = utils.EstimateTokens(response)
+	m.FollowTranscript()
+}
+
+// FollowTranscript re-renders the transcript and keeps following its end
+// when it was scrolled to the bottom. Otherwise the scroll position is left
+// alone and the lines added below it are counted in NewLines.
+func (m *Model) FollowTranscript() {
+	atBottom := m.Viewport.AtBottom()
+	lines := m.Viewport.TotalLineCount()
+	m.ApplyLayout()
+	m.RefreshTranscript()
+	if atBottom {
+		m.Viewport.GotoBottom()
+		m.NewLines = 0
+		return
+	}
+	m.NewLines += max(m.Viewport.TotalLineCount()-lines, 0)
+}
+
+// ReflowTranscript re-renders the transcript after the screen was resized.
+// A transcript at the bottom stays there; otherwise the message at the top
+// of the screen stays at the top.
+func (m *Model) ReflowTranscript() {
+	if m.Viewport.AtBottom() {
+		m.UpdateViewportContent()
+		return
+	}
+	m.ApplyLayout()
+	index, within := -1, 0
+	for i, offset := range m.MessageOffsets {
+		if offset > m.Viewport.YOffset {
+			break
+		}
+		index, within = i, m.Viewport.YOffset-offset
+	}
+	m.RefreshTranscript()
+	if index >= 0 && index < len(m.MessageOffsets) {
+		end := m.Viewport.TotalLineCount()
+		if index+1 < len(m.MessageOffsets) {
+			end = m.MessageOffsets[index+1]
+		}
+		m.Viewport.SetYOffset(min(m.MessageOffsets[index]+within, end-1))
+	}
+}
+
+// ScrollToBottom jumps to the end of the transcript
+func (m *Model) ScrollToBottom() {
+	m.Viewport.GotoBottom()
+	m.NewLines = 0
+}
+
+// UpdateViewportContent re-renders the transcript and scrolls to the bottom
+func (m *Model) UpdateViewportContent() {
+	m.ApplyLayout()
+	m.RefreshTranscript()
+	m.ScrollToBottom()
+}
+
+// RefreshTranscript re-renders the transcript without changing the scroll
+// position, recording the line offset of each message. Messages that didn't
+// change since the last time are reused from Blocks.
+func (m *Model) RefreshTranscript() {
+	if len(m.Messages) == 0 {
+		m.MessageOffsets = nil
+		m.ImagePlacements = nil
+		m.SetTranscript("No responses yet. Send a prompt to start.\n\n")
+		return
+	}
+
+	width := m.ScreenWidth - 10 - m.sidePaneWidth()
+	if m.compact() {
+		width = m.ScreenWidth - 1
+	}
+	view := m.transcriptView(width)
+	if len(m.Blocks) > len(m.Messages) {
+		m.Blocks = m.Blocks[:len(m.Messages)]
+	}
+	blocks := make([]RenderedBlock, len(m.Messages))
+	size := 0
+	for i, msg := range m.Messages {
+		blocks[i] = m.renderBlock(i, msg, view)
+		size += len(blocks[i].Text) + 2
+	}
+
+	var sb strings.Builder
+	sb.Grow(size)
+	offsets := make([]int, len(m.Messages))
+	var placements []ImagePlacement
+	line := 0
+
+	for i, block := range blocks {
+		offsets[i] = line
+		for _, p := range block.Placements {
+			p.Line += line
+			placements = append(placements, p)
+		}
+		sb.WriteString(block.Text)
+		sb.WriteString("\n\n")
+		line += block.Lines + 2
+	}
+	if len(m.Queue) > 0 {
+		sb.WriteString(m.renderQueued(width))
+		sb.WriteString("\n\n")
+	}
+
+	m.MessageOffsets = offsets
+	m.ImagePlacements = placements
+	m.SetTranscript(sb.String())
+}
+
+// renderMessage renders a message header and its (possibly collapsed) content
+func (m Model) renderMessage(index int, msg models.Message, width int) string {
+	if msg.Role == models.RoleInfo {
+		if width > 10 {
+			return InfoMessageStyle.Render(utils.WrapText(msg.Content, width))
+		}
+		return InfoMessageStyle.Render(msg.Content)
+	}
+
+	// Prompts are shaded and responses indented below their headers
+	bodyStyle := UserMessageStyle
+	if msg.Role == models.RoleAssistant {
+		bodyStyle = AssistantMessageStyle
+	}
+	bodyWidth := width - bodyStyle.GetHorizontalFrameSize()
+
+	content := msg.Content
+	reasoning := ""
+	if msg.Role == models.RoleAssistant {
+		reasoning, content = m.renderReasoning(index, content, bodyWidth)
+	}
+	if msg.Role == models.RoleAssistant && len(msg.Format) > 0 {
+		complete := !(m.IsGenerating && index == len(m.Messages)-1)
+		content = RenderJSONResponse(content, msg.Format, complete, bodyWidth)
+	} else if bodyWidth > 10 {
+		content = utils.WrapText(content, bodyWidth)
+	}
+
+	lines := strings.Split(content, "\n")
+	if m.Collapsed[index] && len(lines) > CollapseThreshold {
+		hidden := len(lines) - collapsedPreviewLines
+		lines = append(lines[:collapsedPreviewLines:collapsedPreviewLines],
+			CollapsedStyle.Render(fmt.Sprintf("… %d more lines (c to expand)", hidden)))
+		content = strings.Join(lines, "\n")
+	}
+
+	if footer := m.metadataFooter(msg); footer != "" {
+		content += "\n" + footer
+	}
+	if reasoning != "" && content != "" {
+		content = reasoning + "\n" + content
+	} else if reasoning != "" {
+		content = reasoning
+	}
+	if msg.Role == models.RoleUser && width > 10 {
+		bodyStyle = bodyStyle.Width(width)
+	}
+	return m.messageHeader(index, msg) + "\n" + bodyStyle.Render(content)
+}
+
+// messageHeader renders the role, model, time and token count of a message
+func (m Model) messageHeader(index int, msg models.Message) string {
+	parts := []string{"You"}
+	style := UserHeaderStyle
+	if msg.Role == models.RoleAssistant {
+		parts = []string{msg.Model}
+		style = AssistantHeaderStyle
+	}
+	if m.ShowTimestamps && !msg.CreatedAt.IsZero() {
+		parts = append(parts, msg.CreatedAt.Format("15:04:05"))
+	}
+	if m.ShowTimestamps && msg.Duration > 0 {
+		parts = append(parts, "took "+formatDuration(msg.Duration))
+	}
+	if msg.Metadata != nil && msg.Metadata.EvalCount > 0 {
+		parts = append(parts, fmt.Sprintf("%d tokens", msg.Tokens))
+	} else if msg.Tokens > 0 {
+		parts = append(parts, fmt.Sprintf("~%d tokens", msg.Tokens))
+	}
+	if msg.CutOff != "" {
+		parts = append(parts, cutOffLabel(msg.CutOff))
+	}
+	if m.Collapsed[index] {
+		parts = append(parts, "collapsed")
+	}
+
+	header := strings.Join(parts, " · ")
+	if index == m.SelectedMessage {
+		return SelectedHeaderStyle.Render("▶ " + header)
+	}
+	return style.Render("▌ " + header)
+}
+
+// SelectMessage moves the message selection by delta and scrolls to it
+func (m *Model) SelectMessage(delta int) {
+	if len(m.Messages) == 0 {
+		return
+	}
+
+	// Start from the message currently at the top of the viewport
+	if m.SelectedMessage < 0 {
+		m.SelectedMessage = len(m.Messages)
+		for i, offset := range m.MessageOffsets {
+			if offset >= m.Viewport.YOffset {
+				m.SelectedMessage = i
+				break
+			}
+		}
+		if delta > 0 {
+			delta--
+		}
+	}
+
+	m.SelectedMessage += delta
+	if m.SelectedMessage < 0 {
+		m.SelectedMessage = 0
+	}
+	if m.SelectedMessage >= len(m.Messages) {
+		m.SelectedMessage = len(m.Messages) - 1
+	}
+
+	m.RefreshTranscript()
+	m.Viewport.SetYOffset(m.MessageOffsets[m.SelectedMessage])
+}
+
+// ToggleCollapse collapses or expands the selected message, or the latest
+// response if nothing is selected
+func (m *Model) ToggleCollapse() {
+	index := m.SelectedMessage
+	if index < 0 {
+		for i := len(m.Messages) - 1; i >= 0; i-- {
+			if m.Messages[i].Role == models.RoleAssistant {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 || index >= len(m.Messages) {
+		return
+	}
+
+	m.Collapsed[index] = !m.Collapsed[index]
+	m.RefreshTranscript()
+	if index < len(m.MessageOffsets) {
+		m.Viewport.SetYOffset(m.MessageOffsets[index])
+	}
+}
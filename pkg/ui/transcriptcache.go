@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// RenderedBlock is a message as rendered in the transcript, with the images
+// drawn below it. It is reused until the message or the way it is shown
+// changes, so a streaming response re-renders only itself.
+type RenderedBlock struct {
+	key         blockKey
+	attachments []string
+	Text        string
+	// Lines is the number of line breaks in Text
+	Lines int
+	// Placements are the images drawn in the block, by line in the block
+	Placements []ImagePlacement
+}
+
+// transcriptView is what every message of the transcript is rendered with
+type transcriptView struct {
+	width         int
+	theme         string
+	showMetadata  bool
+	showTimes     bool
+	hideReasoning bool
+	imageProtocol utils.ImageProtocol
+	reasoningKey  string
+	openImageKey  string
+}
+
+// blockKey is everything a rendered message depends on
+type blockKey struct {
+	view      transcriptView
+	role      string
+	content   string
+	model     string
+	createdAt time.Time
+	tokens    int
+	duration  time.Duration
+	format    string
+	metadata  *models.ResponseMetadata
+	collapsed bool
+	expanded  bool
+	selected  bool
+	streaming bool
+}
+
+// transcriptView returns how the transcript is rendered at the given width
+func (m Model) transcriptView(width int) transcriptView {
+	return transcriptView{
+		width:         width,
+		theme:         CurrentTheme.Name,
+		showMetadata:  m.ShowMetadata,
+		showTimes:     m.ShowTimestamps,
+		hideReasoning: m.HideReasoning,
+		imageProtocol: m.ImageProtocol,
+		reasoningKey:  m.KeyMap.Help(ActionToggleReasoning),
+		openImageKey:  m.KeyMap.Help(ActionOpenImage),
+	}
+}
+
+// renderBlock returns the message at index rendered for the transcript,
+// rendering it again only when it changed since the last time
+func (m *Model) renderBlock(index int, msg models.Message, view transcriptView) RenderedBlock {
+	key := blockKey{
+		view:      view,
+		role:      msg.Role,
+		content:   msg.Content,
+		model:     msg.Model,
+		createdAt: msg.CreatedAt,
+		tokens:    msg.Tokens,
+		duration:  msg.Duration,
+		format:    string(msg.Format),
+		metadata:  msg.Metadata,
+		collapsed: m.Collapsed[index],
+		expanded:  m.ExpandedReasoning[index],
+		selected:  index == m.SelectedMessage,
+		streaming: m.IsGenerating && index == len(m.Messages)-1,
+	}
+	if index < len(m.Blocks) && m.Blocks[index].key == key && slices.Equal(m.Blocks[index].attachments, msg.Attachments) {
+		return m.Blocks[index]
+	}
+
+	text := m.renderMessage(index, msg, view.width)
+	var placements []ImagePlacement
+	if images, inline := m.renderImages(msg, view.width); images != "" {
+		start := strings.Count(text, "\n") + 1
+		for _, p := range inline {
+			p.Line += start
+			placements = append(placements, p)
+		}
+		text += "\n" + images
+	}
+	block := RenderedBlock{
+		key:         key,
+		attachments: msg.Attachments,
+		Text:        text,
+		Lines:       strings.Count(text, "\n"),
+		Placements:  placements,
+	}
+	if index < len(m.Blocks) {
+		m.Blocks[index] = block
+	} else {
+		m.Blocks = append(m.Blocks, block)
+	}
+	return block
+}
@@ -1,49 +1,217 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/evilvic/ollama-tui/pkg/models"
-	"github.com/evilvic/ollama-tui/pkg/utils"
 )
 
 // Update updates the UI model
-func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (m Model) Update(msg tea.Msg) (next tea.Model, cmd tea.Cmd) {
+	// Accessible mode prints new messages before running the update's
+	// commands, which may quit
+	defer func() {
+		if model, ok := next.(Model); ok && model.Accessible {
+			if printCmd := model.PrintNewMessages(); printCmd != nil {
+				next, cmd = model, tea.Sequence(printCmd, cmd)
+			}
+		}
+	}()
+
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "esc":
-			if m.IsGenerating && m.CancelGenerate != nil {
-				m.CancelGenerate()
+		// Keybinding conflicts are resolved before the custom keymap is active
+		if m.State == StateKeyConflicts {
+			switch msg.String() {
+			case "enter":
+				m.ApplyPendingKeyMap(true)
+			case "i":
+				m.ApplyPendingKeyMap(false)
+			case "esc":
+				m.KeyConflicts = nil
+				m.State = StateProviderSelect
+			case "ctrl+c":
+				return m, tea.Quit
+			default:
+				var cmd tea.Cmd
+				m.ConflictTable, cmd = m.ConflictTable.Update(msg)
+				return m, cmd
 			}
+			return m, tea.Batch(tea.ClearScreen, RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight))
+		}
 
-			// If we're in the API key input state, go back to provider selection
-			if m.State == StateAPIKeyInput {
-				m.State = StateProviderSelect
+		if m.ConfirmQuit {
+			return m.UpdateQuitConfirm(msg)
+		}
+
+		if m.ShellProposal != nil {
+			return m.UpdateShellConfirm(msg)
+		}
+
+		if m.CommitProposal != nil {
+			return m.UpdateCommitConfirm(msg)
+		}
+
+		if m.State == StateRunningModels {
+			return m.UpdateRunningModels(msg)
+		}
+
+		if m.State == StateMCP {
+			return m.UpdateMCPStatus(msg)
+		}
+
+		if m.State == StateCodeBlocks {
+			return m.UpdateCodeBlocks(msg)
+		}
+
+		if m.State == StateCredentials {
+			return m.UpdateCredentials(msg)
+		}
+
+		if m.State == StateDiagnostics {
+			return m.UpdateDiagnostics(msg)
+		}
+
+		if m.State == StateUsage {
+			return m.UpdateUsage(msg)
+		}
+
+		if m.State == StatePull {
+			return m.UpdatePull(msg)
+		}
+
+		if m.State == StateRegistry {
+			return m.UpdateRegistry(msg)
+		}
+
+		if m.State == StateMemories {
+			return m.UpdateMemories(msg)
+		}
+
+		if m.State == StateInspector {
+			return m.UpdateInspector(msg)
+		}
+
+		if m.State == StatePreview {
+			return m.UpdatePreview(msg)
+		}
+
+		// The copy/rename prompt captures all keys until it is closed
+		if m.State == StateModelSelect && m.ModelOp != "" {
+			return m.UpdateModelOp(msg)
+		}
+
+		// The history search popup captures all keys until it is closed
+		if m.HistorySearching {
+			return m.UpdateHistorySearch(msg)
+		}
+
+		// Transcript search: "/" in the focused viewport starts a query, which
+		// then captures typing; n/N navigate matches once it is entered
+		if m.State == StatePrompting && m.ViewportFocused {
+			if m.Searching || (m.SearchQuery != "" && (msg.String() == "n" || msg.String() == "N" || msg.String() == "esc")) {
+				return m.UpdateSearch(msg)
+			}
+			if msg.String() == "/" {
+				m.Searching = true
+				m.SearchQuery = ""
+				m.refreshSearch()
 				return m, nil
 			}
+		}
 
-			return m, tea.Quit
+		// Navigate and complete the slash command popup while it is visible
+		if suggestions := m.SlashSuggestions(); len(suggestions) > 0 {
+			switch msg.String() {
+			case "tab":
+				m.CompleteSlashCommand()
+				return m, nil
+			case "up":
+				m.SlashIndex = (m.SlashIndex + len(suggestions) - 1) % len(suggestions)
+				return m, nil
+			case "down":
+				m.SlashIndex = (m.SlashIndex + 1) % len(suggestions)
+				return m, nil
+			case "esc":
+				m.Input.Reset()
+				return m, nil
+			}
+		}
 
-		case "tab":
-			if m.State == StatePrompting {
-				m.ViewportFocused = !m.ViewportFocused
-				if m.ViewportFocused {
-					m.Input.Blur()
-				} else {
-					m.Input.Focus()
+		// Esc discards a voice recording instead of leaving the chat
+		if m.Recorder != nil && msg.String() == "esc" {
+			m.CancelRecording()
+			return m, nil
+		}
+
+		// Image files dropped onto the terminal are attached instead of typed
+		if msg.Paste && m.State == StatePrompting && !m.ViewportFocused && m.AttachPastedImage(string(msg.Runes)) {
+			return m, nil
+		}
+
+		// Up/Down in an empty input cycle through previously submitted prompts
+		if m.State == StatePrompting && !m.ViewportFocused {
+			switch msg.String() {
+			case "up":
+				if m.RecallHistory(-1) {
+					return m, nil
+				}
+			case "down":
+				if m.RecallHistory(1) {
+					return m, nil
+				}
+			}
+		}
+
+		action, _ := m.KeyMap.Lookup(m.State, msg.String())
+		switch action {
+		case ActionHistorySearch:
+			if !m.ViewportFocused {
+				m.HistorySearching = true
+				m.HistoryQuery = ""
+				m.HistorySearchIndex = 0
+				return m, nil
+			}
+
+		case ActionQuit:
+			// Stop a running generation without leaving the chat
+			if m.IsGenerating {
+				if m.CancelGenerate != nil {
+					m.CancelGenerate()
 				}
 				return m, nil
 			}
 
-		case "ctrl+n":
+			if msg.Type == tea.KeyEsc {
+				// Let Esc clear an active list filter first
+				if (m.State == StateModelSelect && m.List.FilterState() != list.Unfiltered) ||
+					(m.State == StateSessionBrowser && m.SessionList.FilterState() != list.Unfiltered) {
+					break
+				}
+				return m, m.StepBack()
+			}
+
+			return m, m.RequestQuit()
+
+		case ActionExternalEditor:
+			return m, OpenEditorCmd(m.Input.Value())
+
+		case ActionToggleFocus:
+			if m.State == StatePrompting || m.State == StateLoading {
+				m.SetFocus(!m.ViewportFocused)
+				return m, nil
+			}
+
+		case ActionNewChat:
 			// Clear conversation context and start a new chat
 			if m.State == StatePrompting {
 				APIClient.ClearContext()
@@ -58,31 +226,41 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				)
 			}
 
-		case "enter":
+		case ActionSubmit:
 			if m.State == StateProviderSelect {
+				if i, ok := m.ProviderList.SelectedItem().(EndpointItem); ok {
+					return m, m.SelectEndpoint(i.Endpoint)
+				}
 				if i, ok := m.ProviderList.SelectedItem().(models.ListItem); ok {
-					m.SelectedProvider = i.Name
-
-					// If OpenAI is selected, check for API key
-					if m.SelectedProvider == "openai" {
-						// First check for OPENAI_API_KEY environment variable
-						apiKey := utils.GetEnv("OPENAI_API_KEY", "")
-
-						// If not found in environment, try to load from config file
-						if apiKey == "" {
-							config, err := utils.LoadConfig()
-							if err == nil && config.OpenAIAPIKey != "" {
-								apiKey = config.OpenAIAPIKey
-
-								// Set the API key as an environment variable for the current session
-								_ = utils.SetEnv("OPENAI_API_KEY", apiKey)
-							}
+					m.AllProviders = i.Name == AllProviders
+					if m.AllProviders {
+						providers := ConfiguredProviders()
+						if len(providers) == 0 {
+							m.AllProviders = false
+							return m, m.ProviderList.NewStatusMessage("None of the providers of this profile is configured")
 						}
+						// The chat starts on the first provider until a model
+						// of another one is picked
+						m.UseProvider(providers[0])
+						m.State = StateModelSelect
+						m.List.Title = "Available models · all providers"
+						return m, tea.Batch(
+							tea.ClearScreen,
+							RefreshLayoutCmd(m.ScreenWidth, m.ScreenHeight),
+							FetchAllModelsCmd(),
+						)
+					}
+					m.SelectedProvider = i.Name
 
+					// Providers that need an API key ask for one if none is configured
+					if KeyRequired(m.SelectedProvider) {
+						apiKey := ProviderAPIKey(m.SelectedProvider)
 						if apiKey == "" {
 							// No API key found, transition to API key input state
 							m.State = StateAPIKeyInput
+							m.APIKeyChecking, m.APIKeyError = "", ""
 							m.APIKeyInput.Reset()
+							m.APIKeyInput.Placeholder = fmt.Sprintf("Enter your %s API key...", KeyedProviders[m.SelectedProvider].Name)
 							m.APIKeyInput.Focus()
 
 							return m, tea.Batch(
@@ -132,42 +310,34 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			if m.State == StateAPIKeyInput {
 				apiKey := strings.TrimSpace(m.APIKeyInput.Value())
-				if apiKey != "" {
-					// Set the API key as an environment variable for the current session
-					err := utils.SetEnv("OPENAI_API_KEY", apiKey)
-					if err != nil {
-						// If there's an error setting the environment variable,
-						// we can still proceed with the API key for the current session
-						m.Err = err
-					}
-
-					// Save the API key to the configuration file for future sessions
-					err = utils.SaveAPIKey(apiKey)
-					if err != nil {
-						// If there's an error saving the API key, we can still proceed
-						// with the API key for the current session
-						m.Err = err
-					}
-
-					// Transition to model selection with the provided API key
-					m.State = StateModelSelect
-
-					return m, tea.Batch(
-						tea.ClearScreen,
-						func() tea.Msg {
-							return tea.WindowSizeMsg{
-								Width:  m.ScreenWidth,
-								Height: m.ScreenHeight,
-							}
-						},
-						FetchModelsCmd(m.SelectedProvider, apiKey),
-					)
+				if apiKey != "" && m.APIKeyChecking == "" {
+					// The key is only saved once the provider accepts it
+					m.APIKeyChecking = apiKey
+					m.APIKeyError = ""
+					return m, KeyCheckCmd(m.SelectedProvider, apiKey)
 				}
+				return m, nil
+			}
+
+			if m.State == StateSessionBrowser && m.SessionList.FilterState() != list.Filtering {
+				return m, m.OpenSelectedSession()
 			}
 
 			if m.State == StateModelSelect {
+				if _, ok := m.List.SelectedItem().(registryEntry); ok {
+					return m, m.OpenRegistry()
+				}
 				if i, ok := m.List.SelectedItem().(models.ListItem); ok {
+					// A merged list can move the chat to another provider
+					if i.Provider != "" && i.Provider != m.SelectedProvider {
+						m.UseProvider(i.Provider)
+					}
+					// Picking a model mid-conversation hands the transcript over to it
+					if m.SelectedModel != "" && m.SelectedModel != i.Name {
+						m.SwitchModel(i.Name)
+					}
 					m.SelectedModel = i.Name
+					m.RecordModelUse(i.Name)
 					m.State = StatePrompting
 
 					// Return a batch of commands:
@@ -185,66 +355,538 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 			if m.State == StatePrompting {
-				if strings.TrimSpace(m.Input.Value()) != "" {
-					if m.IsGenerating && m.CancelGenerate != nil {
-						m.CancelGenerate()
-					}
+				// In multi-line mode Enter inserts a newline unless the input is
+				// a single-line slash command
+				_, _, isCommand := ParseSlashCommand(m.Input.Value())
+				if !m.MultilineInput || (isCommand && !strings.Contains(m.Input.Value(), "\n")) {
+					return m.SubmitPrompt()
+				}
+			}
+			// Prompts sent during a response wait for it to finish
+			if m.State == StateLoading && !m.ViewportFocused && len(m.Comparison) == 0 && !m.MultilineInput {
+				return m.QueuePrompt()
+			}
+
+		case ActionSend:
+			if m.State == StateLoading && !m.ViewportFocused && len(m.Comparison) == 0 {
+				if m.MultilineInput {
+					return m.QueuePrompt()
+				}
+				m.Input.InsertString("\n")
+				m.ResizeInput()
+				return m, nil
+			}
+			if m.State == StatePrompting {
+				if m.MultilineInput {
+					return m.SubmitPrompt()
+				}
+				m.Input.InsertString("\n")
+				m.ResizeInput()
+				return m, nil
+			}
+
+		// Message navigation keys only apply while the chat history is focused,
+		// otherwise they are typed into the input
+		case ActionPrevMessage:
+			if m.ViewportFocused {
+				m.SelectMessage(-1)
+				return m, nil
+			}
+
+		case ActionNextMessage:
+			if m.ViewportFocused {
+				m.SelectMessage(1)
+				return m, nil
+			}
+
+		case ActionToggleCollapse:
+			if m.ViewportFocused {
+				m.ToggleCollapse()
+				return m, nil
+			}
 
-					m.CurrentPrompt = m.Input.Value()
-					m.Input.Reset()
-					m.State = StateLoading
-					m.IsGenerating = true
-					m.InProgressResponse = ""
+		case ActionToggleMetadata:
+			if m.ViewportFocused {
+				m.ToggleMetadata()
+				return m, nil
+			}
 
-					m.Responses = append(m.Responses, fmt.Sprintf("Prompt: %s\n\nResponse:\n", m.CurrentPrompt))
+		case ActionToggleReasoning:
+			if m.ViewportFocused {
+				m.ToggleReasoning()
+				return m, nil
+			}
 
-					// Update viewport content with the new prompt
-					m.UpdateViewportContent()
+		case ActionUnloadModel:
+			if m.State == StateModelSelect && m.managesHighlightedModel() && m.List.FilterState() != list.Filtering {
+				if i, ok := m.List.SelectedItem().(models.ListItem); ok {
+					return m, UnloadModelCmd(i.Name)
+				}
+			}
+
+		case ActionCreateModel:
+			if m.State == StateModelSelect && m.managesHighlightedModel() && m.List.FilterState() != list.Filtering {
+				if i, ok := m.List.SelectedItem().(models.ListItem); ok {
+					base, _, _ := strings.Cut(i.Name, ":")
+					return m, EditModelfileCmd(base+"-custom", i.Name)
+				}
+			}
 
-					return m, StartGenerateResponseCmd(m.SelectedModel, m.CurrentPrompt)
+		case ActionCopyModel, ActionRenameModel:
+			if m.State == StateModelSelect && m.managesHighlightedModel() && m.List.FilterState() != list.Filtering {
+				if action == ActionCopyModel {
+					return m, m.StartModelOp("copy")
 				}
+				return m, m.StartModelOp("rename")
 			}
+
+		case ActionDiscover:
+			if m.State == StateProviderSelect {
+				return m, m.StartDiscovery()
+			}
+
+		case ActionManageKeys:
+			if m.State == StateProviderSelect && m.ProviderList.FilterState() != list.Filtering {
+				return m, m.OpenCredentials()
+			}
+
+		case ActionCancelQueued:
+			m.CancelQueued()
+			return m, nil
+
+		case ActionNewTab:
+			return m, m.OpenTab()
+
+		case ActionNextTab:
+			return m, m.SwitchTab(m.ActiveTab + 1)
+
+		case ActionPrevTab:
+			return m, m.SwitchTab(m.ActiveTab - 1)
+
+		case ActionCloseTab:
+			return m, m.CloseTab()
+
+		case ActionToggleSidePane:
+			m.ToggleSidePane()
+			return m, nil
+
+		case ActionSelectionMode:
+			return m, m.ToggleSelectionMode()
+
+		case ActionInspector:
+			return m, m.OpenInspector()
+
+		case ActionSortModels:
+			if m.State == StateModelSelect && m.List.FilterState() != list.Filtering {
+				m.CycleModelSort()
+				return m, nil
+			}
+
+		case ActionSelectModel:
+			if !m.IsGenerating && len(m.Comparison) == 0 {
+				return m, m.OpenModelPicker()
+			}
+
+		case ActionFavoriteModel:
+			if m.State == StateModelSelect && m.List.FilterState() != list.Filtering {
+				if i, ok := m.List.SelectedItem().(models.ListItem); ok {
+					m.ToggleFavorite(i.Name)
+					return m, nil
+				}
+			}
+
+		case ActionBrowseRegistry:
+			if m.State == StateModelSelect && m.List.FilterState() != list.Filtering {
+				return m, m.OpenRegistry()
+			}
+
+		case ActionGroupModels:
+			if m.State == StateModelSelect && m.List.FilterState() != list.Filtering {
+				m.ToggleModelGrouping()
+				return m, nil
+			}
+
+		case ActionSaveCode:
+			if m.ViewportFocused && !m.IsGenerating {
+				return m, m.OpenCodeBlocks(false)
+			}
+
+		case ActionReviewEdits:
+			if m.ViewportFocused && !m.IsGenerating {
+				return m, m.OpenCodeBlocks(true)
+			}
+
+		case ActionVoiceInput:
+			return m, m.ToggleRecording()
+
+		case ActionOpenImage:
+			if m.ViewportFocused {
+				m.OpenImages()
+				return m, nil
+			}
+
+		case ActionOpenCitation:
+			if m.ViewportFocused {
+				m.OpenCitation(msg.String())
+				return m, nil
+			}
+
+		case ActionReplay:
+			if m.ViewportFocused && len(m.Messages) > 0 {
+				return m, m.StartReplay("")
+			}
+
+		case ActionFork:
+			if m.ViewportFocused && len(m.Messages) > 0 && !m.IsGenerating {
+				index, _ := m.forkIndex("")
+				if err := m.ForkSession(index); err != nil {
+					m.StatusMessage = err.Error()
+				}
+				return m, nil
+			}
+		}
+
+	case EditorFinishedMsg:
+		if msg.Err != nil {
+			m.Err = msg.Err
+			m.StatusMessage = fmt.Sprintf("Editor failed: %v", msg.Err)
+			return m, nil
+		}
+		m.Input.SetValue(msg.Content)
+		m.Input.CursorEnd()
+		m.ViewportFocused = false
+		m.Input.Focus()
+		m.ResizeInput()
+		return m, nil
+
+	case ModelfileEditedMsg:
+		if msg.Err != nil {
+			m.SetModelStatus(fmt.Sprintf("Editor failed: %v", msg.Err))
+			return m, nil
+		}
+		if strings.TrimSpace(msg.Content) == "" {
+			m.SetModelStatus("Model creation cancelled")
+			return m, nil
+		}
+		name := ModelfileName(msg.Content, msg.Name)
+		m.SetModelStatus(fmt.Sprintf("Creating %s…", name))
+		return m, CreateModelCmd(name, msg.Content)
+
+	case CreateProgressMsg:
+		if !msg.Done {
+			m.SetModelStatus(fmt.Sprintf("Creating %s: %s", msg.Model, msg.Status))
+			return m, ListenForCreateProgressCmd()
+		}
+		if msg.Err != nil {
+			m.SetModelStatus(fmt.Sprintf("Failed to create %s: %v", msg.Model, msg.Err))
+			return m, nil
 		}
+		m.SetModelStatus(fmt.Sprintf("Created %s", msg.Model))
+		return m, m.RefreshModelsCmd()
 
-	case SetCancelFuncMsg:
-		m.CancelGenerate = msg.Cancel
+	case PullProgressMsg:
+		return m, m.HandlePullProgress(msg)
+
+	case BatchProgressMsg:
+		return m, m.HandleBatchProgress(msg)
+
+	case RegistryCatalogMsg:
+		m.HandleRegistryCatalog(msg)
 		return m, nil
 
+	case ModelOpMsg:
+		if msg.Err != nil {
+			m.List.Title = fmt.Sprintf("Available models · %s failed: %v", msg.Op, msg.Err)
+			return m, nil
+		}
+		if msg.Op == "rename" {
+			m.List.Title = fmt.Sprintf("Available models · renamed %s to %s", msg.Source, msg.Destination)
+			if m.SelectedModel == msg.Source {
+				m.SelectedModel = msg.Destination
+			}
+		} else {
+			m.List.Title = fmt.Sprintf("Available models · copied %s to %s", msg.Source, msg.Destination)
+		}
+		return m, m.RefreshModelsCmd()
+
 	case FetchModelsMsg:
-		items := []list.Item{}
-		for _, model := range msg.Models {
-			items = append(items, models.ListItem{
-				Name:    model.Name,
-				Details: fmt.Sprintf("Family: %s, Context: %d", model.Details.Family, model.Details.Context),
-			})
-		}
-		m.List.SetItems(items)
 		m.Models = msg.Models
+		if len(msg.Errs) > 0 {
+			m.List.Title = "Available models · " + errors.Join(msg.Errs...).Error()
+		}
+		m.RefreshModelItems()
+		m.RefreshRegistryItems()
+		APIClient.KeepAlive = m.KeepAlive
+		APIClient.SafePrompt = m.SafePrompt
+		m.ApplyRequestOptions(APIClient, m.SelectedProvider)
+		m.ApplyTools()
+		return m, FetchRunningModelsCmd()
+
+	case RunningModelsMsg:
+		if msg.Err != nil {
+			m.RunningModelsStatus = msg.Err.Error()
+			return m, nil
+		}
+		m.RunningModels = msg.Models
+		m.RefreshModelItems()
+		m.UpdateRunningModelsTable()
+		return m, nil
+
+	case KeyCheckedMsg:
+		if m.State == StateAPIKeyInput && msg.Provider == m.SelectedProvider && msg.APIKey == m.APIKeyChecking {
+			return m, m.HandleAPIKeyChecked(msg)
+		}
+		m.HandleKeyChecked(msg)
 		return m, nil
 
+	case RunningModelsTickMsg:
+		if m.State != StateRunningModels || msg.ID != m.RunningModelsTick {
+			return m, nil
+		}
+		return m, tea.Batch(FetchRunningModelsCmd(), RunningModelsTickCmd(msg.ID))
+
+	case ModelUnloadedMsg:
+		if msg.Err != nil {
+			m.List.Title = fmt.Sprintf("Available models · failed to unload %s: %v", msg.Model, msg.Err)
+			m.RunningModelsStatus = fmt.Sprintf("Failed to unload %s: %v", msg.Model, msg.Err)
+			return m, nil
+		}
+		m.List.Title = fmt.Sprintf("Available models · unloaded %s", msg.Model)
+		m.RunningModelsStatus = fmt.Sprintf("Unloaded %s", msg.Model)
+		return m, FetchRunningModelsCmd()
+
 	case TokenMsg:
-		if msg.Done && !m.IsGenerating {
+		if msg.Tab != m.TabID {
+			return m.UpdateTab(msg.Tab, msg)
+		}
+		if msg.ID != m.GenerationID || !m.IsGenerating {
 			return m, nil
 		}
 
 		m.InProgressResponse += msg.Token
+		m.countToken(msg.Token)
+		if msg.Err != nil {
+			m.InProgressResponse += fmt.Sprintf("%s%v]", errorLabel, msg.Err)
+		}
+
+		// Keep the partial response but label it when the time limit cut it off
+		if msg.TimedOut {
+			m.InProgressResponse += fmt.Sprintf("%s %s reached]", timeLimitLabel, m.GenerationTimeout)
+			m.StatusMessage = "Generation cancelled after reaching the time limit"
+		}
+
+		// Map citation numbers back to sources when several were attached
+		if msg.Done && len(m.CurrentAttachments) > 1 {
+			m.InProgressResponse += "\n\n" + SourcesLegend(m.CurrentAttachments)
+		}
+		if len(msg.Citations) > 0 {
+			m.InProgressResponse += "\n\n" + CitationsLegend(msg.Citations)
+		} else if msg.Done && m.Continued != nil {
+			// A continued response keeps the legends of the part it continues
+			_, legends := splitResponse(m.Continued.Content)
+			m.InProgressResponse += legends
+		}
 
 		// Update the response with the new token
-		m.UpdateResponse(m.CurrentPrompt, m.InProgressResponse)
+		m.UpdateResponse(m.InProgressResponse)
+		if msg.Metadata != nil {
+			m.SetResponseMetadata(msg.Metadata)
+		}
+		if msg.Request != nil {
+			m.SetResponseRequest(msg.Request)
+		}
+		if msg.Done && len(msg.Exchanges) > 0 {
+			m.Exchanges = msg.Exchanges
+		}
 
 		if msg.Done {
+			m.LastExchange.ResponseChars = len(m.InProgressResponse)
+			m.LastExchange.Duration = time.Since(m.GenerationStart)
+			m.SetResponseDuration(m.LastExchange.Duration)
+			m.SetResponseCutOff(msg)
+			m.LastExchange.TimedOut = msg.TimedOut
 			m.CurrentResponse = m.InProgressResponse
 			m.IsGenerating = false
-			m.State = StatePrompting
+			if m.State == StateInspector {
+				// The inspector stays open, now on this response
+				m.InspectorReturnState = StatePrompting
+				m.UpdateInspectorView()
+			} else {
+				m.State = StatePrompting
+			}
 			m.CancelGenerate = nil
 
 			// Make sure we update the viewport one last time
-			m.UpdateViewportContent()
+			m.FollowTranscript()
+			m.AutosaveSession()
+			m.LogConversation(msg.TimedOut, msg.Err)
+
+			memory := m.FinishMemory()
+
+			if edits := m.ResponseEdits(len(m.Messages) - 1); len(edits) > 0 && m.StatusMessage == "" {
+				m.StatusMessage = fmt.Sprintf("The response changes %s: /diff to review and apply", strings.Join(edits, ", "))
+			}
 
+			// Announce the response when nobody may be watching
+			notify := m.NotifyResponseCmd(msg.TimedOut)
+
+			// Name the session after its first exchange
+			var title tea.Cmd
+			if m.NeedsTitle() {
+				title = GenerateTitleCmd(m.TabID, m.SelectedModel, m.CurrentPrompt, models.StripReasoning(m.CurrentResponse))
+			}
+
+			var next tea.Cmd
+			m, next = m.SendQueued()
+			return m, tea.Batch(notify, title, memory, next)
+		}
+
+		return m, nil
+
+	case SessionTitleMsg:
+		if msg.Tab != m.TabID {
+			return m.UpdateTab(msg.Tab, msg)
+		}
+		if msg.Err != nil || msg.Title == "" || m.Session.CustomTitle {
 			return m, nil
 		}
+		m.Session.Title = msg.Title
+		m.AutosaveSession()
+		return m, tea.SetWindowTitle("ollama-tui · " + msg.Title)
+
+	case ColdStartMsg:
+		if msg.Tab != m.TabID {
+			return m.UpdateTab(msg.Tab, msg)
+		}
+		m.HandleColdStart(msg)
+		return m, nil
+
+	case CompareTokenMsg:
+		return m, m.HandleCompareToken(msg)
+
+	case RateLimitMsg:
+		m.HandleRateLimit(msg)
+		return m, nil
+
+	case ContextSummaryMsg:
+		if msg.Tab != m.TabID {
+			return m.UpdateTab(msg.Tab, msg)
+		}
+		return m, m.HandleContextSummary(msg)
+
+	case MemoryMsg:
+		if msg.Tab != m.TabID {
+			return m.UpdateTab(msg.Tab, msg)
+		}
+		m.HandleMemory(msg)
+		return m, nil
+
+	case MemoryEditedMsg:
+		if msg.Tab != m.TabID {
+			return m.UpdateTab(msg.Tab, msg)
+		}
+		m.HandleMemoryEdited(msg)
+		return m, nil
+
+	case ReplayMsg:
+		if msg.Tab != m.TabID {
+			return m.UpdateTab(msg.Tab, msg)
+		}
+		m.HandleReplay(msg)
+		return m, nil
+
+	case DiscoveryMsg:
+		m.HandleDiscovery(msg)
+		return m, nil
+
+	case UpdateCheckMsg:
+		m.HandleUpdateCheck(msg)
+		return m, nil
+
+	case ShareMsg:
+		m.HandleShare(msg)
+		return m, nil
+
+	case ServeRouteMsg:
+		msg.Reply <- m.ServeRoute()
+		return m, nil
+
+	case ServedMsg:
+		m.HandleServed(msg)
+		return m, nil
+
+	case DiagnosticsMsg:
+		m.HandleDiagnostics(msg)
+		return m, nil
+
+	case UsageStatsMsg:
+		m.HandleUsageStats(msg)
+		return m, nil
+
+	case RecoveryTickMsg:
+		return m, m.HandleRecoveryTick()
+
+	case ConfigWatchMsg:
+		return m, m.HandleConfigWatch(msg)
+
+	case LMStudioStatusMsg:
+		m.LMStudio = &msg.Status
+		m.RefreshProviderItems()
+		return m, nil
 
-		return m, ListenForTokensCmd()
+	case MCPConnectedMsg:
+		m.HandleMCPConnected(msg)
+		return m, nil
+
+	case WebResultsMsg:
+		return m.HandleWebResults(msg)
+
+	case RecallMsg:
+		m.ApplyRecall(msg)
+		return m, nil
+
+	case ShellCommandMsg:
+		m.HandleShellCommand(msg)
+		return m, nil
+
+	case ShellResultMsg:
+		return m, m.HandleShellResult(msg)
+
+	case CommitMessageMsg:
+		m.HandleCommitMessage(msg)
+		return m, nil
+
+	case CommitMessageEditedMsg:
+		return m, m.HandleCommitMessageEdited(msg)
+
+	case CommitDoneMsg:
+		m.HandleCommitDone(msg)
+		return m, nil
+
+	case ClipboardImageMsg:
+		m.HandleClipboardImage(msg)
+		return m, nil
+
+	case tea.FocusMsg:
+		m.TerminalFocused = true
+		return m, nil
+
+	case tea.BlurMsg:
+		m.TerminalFocused = false
+		return m, nil
+
+	case NotificationMsg:
+		if msg.Err != nil {
+			m.StatusMessage = fmt.Sprintf("Cannot show a notification: %v", msg.Err)
+		}
+		return m, nil
+
+	case RecordingTickMsg:
+		return m, m.HandleRecordingTick(msg)
+
+	case TranscriptionMsg:
+		m.HandleTranscription(msg)
+		return m, nil
 
 	case ErrorMsg:
 		m.Err = msg.Err
@@ -262,59 +904,76 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.ProviderList.SetSize(h, v)
 			return m, nil
 		} else if m.State == StateAPIKeyInput {
-			m.APIKeyInput.SetWidth(h - 10) // Adjust width for padding
+			m.APIKeyInput.Width = h - 10 // Adjust width for padding
+			return m, nil
+		} else if m.State == StateRunningModels {
+			m.RunningModelsTable.SetWidth(h - 4)
+			m.RunningModelsTable.SetHeight(v - 4)
+			return m, nil
+		} else if m.State == StateSessionBrowser {
+			m.SessionList.SetSize(h, v)
+			return m, nil
+		} else if m.State == StateMCP {
+			m.MCPViewport.Width = h - 4
+			m.MCPViewport.Height = v - 2
+			m.UpdateMCPView()
+			return m, nil
+		} else if m.State == StateDiagnostics {
+			m.DoctorViewport.Width = h - 4
+			m.DoctorViewport.Height = v - 2
+			m.UpdateDiagnosticsView()
+			return m, nil
+		} else if m.State == StateUsage {
+			m.UsageViewport.Width = h - 4
+			m.UsageViewport.Height = v - 2
+			m.UpdateUsageView()
+			return m, nil
+		} else if m.State == StatePull {
+			m.PullViewport.Width = h - 4
+			m.PullViewport.Height = v - 2
+			m.UpdatePullView()
+			return m, nil
+		} else if m.State == StateRegistry {
+			m.RegistryList.SetSize(h, v)
+			return m, nil
+		} else if m.State == StateMemories {
+			m.ResizeMemories(h, v)
+			return m, nil
+		} else if m.State == StateInspector {
+			m.InspectorViewport.Width = h - 4
+			m.InspectorViewport.Height = v - 2
+			m.UpdateInspectorView()
+			return m, nil
+		} else if m.State == StatePreview {
+			m.PreviewViewport.Width = h - 4
+			m.PreviewViewport.Height = v - 2
+			m.UpdatePreviewView()
+			return m, nil
+		} else if m.State == StateCodeBlocks {
+			m.ResizeCodeBlocks(h, v)
+			return m, nil
+		} else if m.State == StateCredentials {
+			m.CredentialsTable.SetWidth(h - 4)
+			m.CredentialsTable.SetHeight(len(credentialProviders) + 2)
+			m.CredentialInput.Width = h - 10
 			return m, nil
 		} else if m.State == StateModelSelect {
+			// Leave room for the footer and the copy/rename prompt below the list
+			v--
+			if m.ModelOp != "" {
+				v -= 3
+			}
 			m.List.SetSize(h, v)
 			return m, nil
+		} else if m.State == StateKeyConflicts {
+			m.ConflictTable.SetWidth(h - 4)
+			return m, nil
 		}
 
-		// For chat view, update the layout
-		// Fixed input height (3 lines + borders)
-		inputHeight := 5
-
-		// Status bar height
-		statusBarHeight := 1
-
-		// Title height (including spacing)
-		titleHeight := 3
-
-		// Loading indicator height
-		loadingHeight := 0
-		if m.State == StateLoading && m.IsGenerating {
-			loadingHeight = 1
-		}
-
-		// Set input width to full width minus margins
-		m.Input.SetWidth(h - 4)
-
-		// Viewport takes the remaining height
-		// Total height minus fixed elements and spacing
-		viewportHeight := v - inputHeight - statusBarHeight - titleHeight - loadingHeight - 3
-		if viewportHeight < 5 {
-			viewportHeight = 5
-		}
-		m.Viewport.Height = viewportHeight
-		m.Viewport.Width = h - 4
-
-		// Update content wrapping based on new width
-		if len(m.Responses) > 0 {
-			var content strings.Builder
-			for i, resp := range m.Responses {
-				// For the last response that's in progress, rewrap it
-				if i == len(m.Responses)-1 && len(m.InProgressResponse) > 0 {
-					responseText := utils.WrapText(m.InProgressResponse, h-10)
-					content.WriteString(fmt.Sprintf("Prompt: %s\n\nResponse:\n%s", m.CurrentPrompt, responseText))
-				} else {
-					content.WriteString(resp)
-				}
-				content.WriteString("\n\n")
-			}
-			m.Viewport.SetContent(content.String())
-			m.Viewport.GotoBottom()
-		} else {
-			m.Viewport.SetContent("No responses yet. Send a prompt to start.\n\n")
-		}
+		// For chat view, size the transcript and input box as View draws
+		// them and re-wrap the transcript for the new width
+		m.ResizeInput()
+		m.ReflowTranscript()
 
 		// Force a redraw to ensure the layout is correct
 		return m, tea.ClearScreen
@@ -337,37 +996,221 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.APIKeyInput, cmd = m.APIKeyInput.Update(msg)
 		cmds = append(cmds, cmd)
 
+	case StateSessionBrowser:
+		var cmd tea.Cmd
+		m.SessionList, cmd = m.SessionList.Update(msg)
+		cmds = append(cmds, cmd)
+
 	case StateModelSelect:
 		var cmd tea.Cmd
 		m.List, cmd = m.List.Update(msg)
 		cmds = append(cmds, cmd)
 
 	case StatePrompting:
+		// Scroll keys move every comparison pane together
+		if keyMsg, ok := msg.(tea.KeyMsg); ok && len(m.Comparison) > 0 {
+			step := m.Viewport.Height / 2
+			switch keyMsg.String() {
+			case "pgup":
+				m.ScrollComparison(step)
+				return m, nil
+			case "pgdown":
+				m.ScrollComparison(-step)
+				return m, nil
+			case "home":
+				m.ScrollComparison(1 << 20)
+				return m, nil
+			case "end":
+				m.CompareOffset = 0
+				return m, nil
+			}
+			if m.ViewportFocused {
+				switch keyMsg.String() {
+				case "up", "k":
+					m.ScrollComparison(1)
+				case "down", "j":
+					m.ScrollComparison(-1)
+				}
+				return m, nil
+			}
+		}
+
+		cmds = append(cmds, m.UpdateChat(msg))
+
+	case StateLoading:
+		var cmd tea.Cmd
+		m.Spinner, cmd = m.Spinner.Update(msg)
+		cmds = append(cmds, cmd)
+
+		// The next prompt can be written and the transcript scrolled while
+		// the response streams in
+		switch msg.(type) {
+		case tea.KeyMsg:
+			if len(m.Comparison) == 0 {
+				cmds = append(cmds, m.UpdateChat(msg))
+			}
+		case tea.MouseMsg:
+			cmds = append(cmds, m.UpdateChat(msg))
+		}
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// SubmitPrompt runs the slash command or sends the prompt in the input box
+func (m Model) SubmitPrompt() (tea.Model, tea.Cmd) {
+	// Slash commands are handled locally instead of being sent to the model
+	if _, _, ok := ParseSlashCommand(m.Input.Value()); ok {
+		m.AddToHistory(m.Input.Value())
+		m.StatusMessage = ""
+		cmd := m.RunSlashCommand(m.Input.Value())
+		m.ResizeInput()
+		return m, cmd
+	}
+
+	if strings.TrimSpace(m.Input.Value()) == "" {
+		return m, nil
+	}
+
+	// Load attached files and URLs before sending anything
+	attachments, err := m.CollectAttachments(m.Input.Value())
+	if err != nil {
+		m.StatusMessage = err.Error()
+		return m, nil
+	}
+
+	m.AddToHistory(m.Input.Value())
+	m.StatusMessage = ""
+	if m.IsGenerating && m.CancelGenerate != nil {
+		m.CancelGenerate()
+	}
+
+	requestPrompt := BuildPrompt(m.Input.Value(), attachments)
+	images := AttachmentImages(attachments)
+
+	// In comparison mode the prompt goes to every pane instead of the chat model
+	if len(m.Comparison) > 0 {
+		m.CurrentPrompt = m.Input.Value() + AttachmentSummary(attachments)
+		m.PendingAttachments = nil
+		m.Input.Reset()
+		m.ResizeInput()
+		m.State = StateLoading
+		m.IsGenerating = true
+		m.Messages = append(m.Messages, models.Message{Role: models.RoleUser, Content: m.CurrentPrompt, CreatedAt: time.Now()})
+		m.SelectedMessage = -1
+		for i := range m.Comparison {
+			m.Comparison[i].Client.Images = images
+		}
+		return m, m.StartComparison(requestPrompt)
+	}
+
+	m.CurrentPrompt = m.Input.Value() + AttachmentSummary(attachments)
+	m.CurrentAttachments = TextAttachments(attachments)
+	m.PendingAttachments = nil
+	m.Input.Reset()
+	m.ResizeInput()
+	m.State = StateLoading
+	m.IsGenerating = true
+	m.InProgressResponse = ""
+	m.GenerationStart = time.Now()
+	m.LastExchange = ExchangeStats{
+		Provider:       m.SelectedProvider,
+		Model:          m.SelectedModel,
+		PromptChars:    len(requestPrompt),
+		Attachments:    len(attachments),
+		ContextEnabled: APIClient.HasContext(),
+	}
+
+	// A schema attached to this prompt takes precedence over JSON mode
+	format := m.JSONFormat
+	if m.PendingSchema != nil {
+		format = m.PendingSchema
+		m.PendingSchema = nil
+	}
+
+	var sources []string
+	for _, a := range attachments {
+		sources = append(sources, a.Source)
+	}
+
+	now := time.Now()
+	m.Messages = append(m.Messages,
+		models.Message{Role: models.RoleUser, Content: m.CurrentPrompt, Model: m.SelectedModel, CreatedAt: now, Attachments: sources},
+		models.Message{Role: models.RoleAssistant, Model: m.SelectedModel, CreatedAt: now, Format: format},
+	)
+	m.SelectedMessage = -1
+	APIClient.Format = format
+	APIClient.Images = images
+
+	// Update viewport content with the new prompt
+	m.UpdateViewportContent()
+
+	return m, m.FitContext(requestPrompt)
+}
+
+// ResizeInput grows or shrinks the input box to fit its content, between
+// the minimum height and the configured maximum
+func (m *Model) ResizeInput() {
+	height := m.Input.LineCount()
+	if height < MinInputLines {
+		height = MinInputLines
+	}
+	if m.MaxInputLines > MinInputLines && height > m.MaxInputLines {
+		height = m.MaxInputLines
+	}
+	// Small screens keep the input to one line
+	if m.compact() {
+		height = 1
+	}
+	if height != m.Input.Height() {
+		m.Input.SetHeight(height)
+		m.ApplyLayout()
+	}
+}
+
+// UpdateChat passes a key message to the input box or, when it is focused,
+// to the transcript. Scroll keys always scroll the transcript and mouse
+// messages are handled by HandleMouse.
+func (m *Model) UpdateChat(msg tea.Msg) tea.Cmd {
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.MouseMsg:
+		cmds = append(cmds, m.HandleMouse(msg))
+	case tea.KeyMsg:
 		if !m.ViewportFocused {
-			var cmd tea.Cmd
 			m.Input, cmd = m.Input.Update(msg)
 			cmds = append(cmds, cmd)
+			m.ResizeInput()
 
 			// These keys should be handled by the viewport even when input is focused
-			switch msg := msg.(type) {
-			case tea.KeyMsg:
-				switch msg.String() {
-				case "pgup", "pgdown", "home", "end":
-					m.Viewport, cmd = m.Viewport.Update(msg)
-					cmds = append(cmds, cmd)
-				}
+			switch msg.String() {
+			case "pgup", "pgdown":
+				m.Viewport, cmd = m.Viewport.Update(msg)
+				cmds = append(cmds, cmd)
 			}
 		} else {
-			var cmd tea.Cmd
 			m.Viewport, cmd = m.Viewport.Update(msg)
 			cmds = append(cmds, cmd)
 		}
-
-	case StateLoading:
-		var cmd tea.Cmd
-		m.Spinner, cmd = m.Spinner.Update(msg)
+		switch msg.String() {
+		case "home":
+			m.Viewport.GotoTop()
+		case "end":
+			m.ScrollToBottom()
+		}
+	default:
+		if !m.ViewportFocused {
+			m.Input, cmd = m.Input.Update(msg)
+		} else {
+			m.Viewport, cmd = m.Viewport.Update(msg)
+		}
 		cmds = append(cmds, cmd)
 	}
 
-	return m, tea.Batch(cmds...)
+	// Reaching the bottom catches up with the response
+	if m.Viewport.AtBottom() {
+		m.NewLines = 0
+	}
+	return tea.Batch(cmds...)
 }
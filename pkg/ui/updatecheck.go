@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/api"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+const (
+	// UpdateCheckInterval is how often GitHub is asked for a new release
+	UpdateCheckInterval = 24 * time.Hour
+	// updateCheckTimeout limits asking GitHub for the latest release
+	updateCheckTimeout = 10 * time.Second
+)
+
+// UpdateCheckMsg carries the latest release, once asked for
+type UpdateCheckMsg struct {
+	Release api.Release
+	Err     error
+}
+
+// UpdateCheckDue reports whether to ask for a new release: on release
+// builds, unless turned off, once a day
+func UpdateCheckDue(config utils.Config) bool {
+	if config.NoUpdateCheck || !utils.GetBuildInfo().IsRelease() {
+		return false
+	}
+	return config.UpdateCheck == nil || time.Since(config.UpdateCheck.At) >= UpdateCheckInterval
+}
+
+// UpdateCheckCmd asks GitHub for the latest release in the background
+func UpdateCheckCmd() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+		defer cancel()
+		release, err := api.LatestRelease(ctx)
+		return UpdateCheckMsg{Release: release, Err: err}
+	}
+}
+
+// updateNotice returns the status bar notice for a newer release than the
+// running one, unless it was dismissed
+func updateNotice(check *utils.UpdateCheck) string {
+	if check == nil || check.Latest == "" || check.Latest == check.Dismissed {
+		return ""
+	}
+	current := utils.GetBuildInfo()
+	if !current.IsRelease() || !versionBefore(current.Version, check.Latest) {
+		return ""
+	}
+	return check.Latest + " available (/dismiss)"
+}
+
+// HandleUpdateCheck remembers the latest release until the next check and
+// announces it when it is newer. A failed check waits for the next day
+// too, so being offline costs one request a day.
+func (m *Model) HandleUpdateCheck(msg UpdateCheckMsg) {
+	var check utils.UpdateCheck
+	_ = utils.UpdateConfig(func(c *utils.Config) {
+		if c.UpdateCheck == nil {
+			c.UpdateCheck = &utils.UpdateCheck{}
+		}
+		c.UpdateCheck.At = time.Now()
+		if msg.Err == nil && msg.Release.Version != "" {
+			c.UpdateCheck.Latest = msg.Release.Version
+			c.UpdateCheck.URL = msg.Release.URL
+		}
+		check = *c.UpdateCheck
+	})
+	m.UpdateNotice = updateNotice(&check)
+	if m.UpdateNotice != "" && m.StatusMessage == "" {
+		m.StatusMessage = fmt.Sprintf("ollama-tui %s is out: %s", check.Latest, check.URL)
+	}
+}
+
+// DismissUpdate hides the notice of the latest release until a newer one
+// comes out
+func (m *Model) DismissUpdate() error {
+	m.UpdateNotice = ""
+	return utils.UpdateConfig(func(c *utils.Config) {
+		if c.UpdateCheck != nil {
+			c.UpdateCheck.Dismissed = c.UpdateCheck.Latest
+		}
+	})
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "dismiss",
+		Description: "Hide the notice of a new release until the next one",
+		Run: func(m *Model, args string) tea.Cmd {
+			if m.UpdateNotice == "" {
+				m.StatusMessage = "No notice to dismiss"
+				return nil
+			}
+			if err := m.DismissUpdate(); err != nil {
+				m.StatusMessage = err.Error()
+				return nil
+			}
+			m.StatusMessage = "Hid the update notice until the next release"
+			return nil
+		},
+	})
+}
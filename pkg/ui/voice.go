@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/speech"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+const (
+	// MaxRecordingDuration stops a voice recording that was left running
+	MaxRecordingDuration = 5 * time.Minute
+	// transcriptionTimeout bounds uploading and transcribing a recording
+	transcriptionTimeout = 2 * time.Minute
+)
+
+// ToggleRecording starts recording a voice prompt, or stops the recording
+// and transcribes it into the input box
+func (m *Model) ToggleRecording() tea.Cmd {
+	if m.Recorder != nil {
+		return m.StopRecording()
+	}
+
+	config, _ := utils.LoadConfig()
+	// Check the backend before recording anything it cannot transcribe
+	if _, err := speech.NewTranscriber(config.Speech, OpenAIAPIKey()); err != nil {
+		m.StatusMessage = err.Error()
+		return nil
+	}
+	recorder, err := speech.NewRecorder(config.Speech)
+	if err != nil {
+		m.StatusMessage = err.Error()
+		return nil
+	}
+	if err := recorder.Start(); err != nil {
+		m.StatusMessage = err.Error()
+		return nil
+	}
+
+	m.Recorder = recorder
+	m.RecordingStart = time.Now()
+	m.StatusMessage = ""
+	return recordingTick(m.RecordingStart)
+}
+
+// StopRecording ends the recording and transcribes it in the background
+func (m *Model) StopRecording() tea.Cmd {
+	recorder := m.Recorder
+	m.Recorder = nil
+	m.StatusMessage = "Transcribing…"
+	return func() tea.Msg {
+		wav, err := recorder.Stop()
+		if err != nil {
+			return TranscriptionMsg{Err: err}
+		}
+
+		config, _ := utils.LoadConfig()
+		transcriber, err := speech.NewTranscriber(config.Speech, OpenAIAPIKey())
+		if err != nil {
+			return TranscriptionMsg{Err: err}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), transcriptionTimeout)
+		defer cancel()
+		text, err := transcriber.Transcribe(ctx, wav)
+		if err != nil {
+			return TranscriptionMsg{Err: fmt.Errorf("%s: %w", transcriber.Name(), err)}
+		}
+		return TranscriptionMsg{Text: text}
+	}
+}
+
+// CancelRecording discards the recording in progress
+func (m *Model) CancelRecording() {
+	m.Recorder.Cancel()
+	m.Recorder = nil
+	m.StatusMessage = "Recording discarded"
+}
+
+// recordingTick updates the recording time shown in the status bar
+func recordingTick(start time.Time) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return RecordingTickMsg{Start: start}
+	})
+}
+
+// HandleRecordingTick keeps the recording time current and stops recordings
+// that reach MaxRecordingDuration
+func (m *Model) HandleRecordingTick(msg RecordingTickMsg) tea.Cmd {
+	// Ticks of an earlier recording stop with it
+	if m.Recorder == nil || !msg.Start.Equal(m.RecordingStart) {
+		return nil
+	}
+	if time.Since(m.RecordingStart) >= MaxRecordingDuration {
+		return m.StopRecording()
+	}
+	return recordingTick(msg.Start)
+}
+
+// HandleTranscription inserts the transcribed text at the cursor
+func (m *Model) HandleTranscription(msg TranscriptionMsg) {
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("Voice input failed: %v", msg.Err)
+		return
+	}
+	if msg.Text == "" {
+		m.StatusMessage = "No speech was recognized"
+		return
+	}
+
+	text := msg.Text
+	if value := m.Input.Value(); value != "" && !strings.HasSuffix(value, " ") && !strings.HasSuffix(value, "\n") {
+		text = " " + text
+	}
+	m.ViewportFocused = false
+	m.Input.Focus()
+	m.Input.InsertString(text)
+	m.ResizeInput()
+	m.StatusMessage = ""
+}
+
+// RecordingIndicator shows the length of the recording in the status bar
+func (m Model) RecordingIndicator() string {
+	elapsed := time.Since(m.RecordingStart).Truncate(time.Second)
+	return fmt.Sprintf("🎙 %d:%02d %s: stop, esc: discard | ",
+		int(elapsed.Minutes()), int(elapsed.Seconds())%60, m.KeyMap.Help(ActionVoiceInput))
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "voice",
+		Description: "Record a prompt from the microphone and transcribe it into the input box",
+		Run: func(m *Model, args string) tea.Cmd {
+			return m.ToggleRecording()
+		},
+	})
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+	"github.com/evilvic/ollama-tui/pkg/utils"
+	"github.com/evilvic/ollama-tui/pkg/web"
+)
+
+const (
+	// DefaultWebResults is the number of pages fetched per /web search
+	DefaultWebResults = 3
+	// WebExcerptLength caps how much of each page is sent to the model
+	WebExcerptLength = 6000
+	// webSearchTimeout bounds searching and fetching every result
+	webSearchTimeout = 45 * time.Second
+)
+
+// WebSearchCmd searches the web and fetches the top results in the
+// background, keeping the passages most relevant to the query
+func WebSearchCmd(query string) tea.Cmd {
+	return func() tea.Msg {
+		config, err := utils.LoadConfig()
+		if err != nil {
+			return WebResultsMsg{Query: query, Err: err}
+		}
+		searcher, err := web.NewSearcher(config.WebSearch)
+		if err != nil {
+			return WebResultsMsg{Query: query, Err: err}
+		}
+		limit := config.WebSearch.Results
+		if limit <= 0 {
+			limit = DefaultWebResults
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), webSearchTimeout)
+		defer cancel()
+
+		results, err := searcher.Search(ctx, query, limit)
+		if err != nil {
+			return WebResultsMsg{Query: query, Backend: searcher.Name(), Err: err}
+		}
+
+		pages := web.FetchAll(ctx, results)
+		attachments := make([]models.Attachment, 0, len(pages))
+		for _, page := range pages {
+			name := page.Title
+			if name == "" {
+				if u, err := url.Parse(page.URL); err == nil {
+					name = u.Host
+				}
+			}
+			attachments = append(attachments, models.Attachment{
+				Name:    truncate(name, 60),
+				Source:  page.URL,
+				Content: web.Excerpt(page.Text, query, WebExcerptLength),
+			})
+		}
+		return WebResultsMsg{Query: query, Backend: searcher.Name(), Attachments: attachments}
+	}
+}
+
+// HandleWebResults sends the query to the model with the fetched pages as
+// numbered sources, so the answer can cite them
+func (m Model) HandleWebResults(msg WebResultsMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		m.StatusMessage = fmt.Sprintf("Web search failed: %v", msg.Err)
+		return m, nil
+	}
+	if len(msg.Attachments) == 0 {
+		m.StatusMessage = fmt.Sprintf("%s found nothing for %q", msg.Backend, msg.Query)
+		return m, nil
+	}
+
+	for _, a := range msg.Attachments {
+		if !hasAttachment(m.PendingAttachments, a.Source) {
+			m.PendingAttachments = append(m.PendingAttachments, a)
+		}
+	}
+
+	// Leave the results attached if the user has moved on in the meantime
+	if m.State != StatePrompting || m.IsGenerating || m.Input.Value() != "" {
+		m.StatusMessage = fmt.Sprintf("Attached %d web results for %q to the next prompt", len(msg.Attachments), msg.Query)
+		return m, nil
+	}
+
+	m.Input.SetValue(msg.Query)
+	return m.SubmitPrompt()
+}
+
+func init() {
+	RegisterSlashCommand(SlashCommand{
+		Name:        "web",
+		Usage:       "<query>",
+		Description: "Search the web and answer from the top results, with citations",
+		Run: func(m *Model, args string) tea.Cmd {
+			if args == "" {
+				m.StatusMessage = "Usage: /web <query>"
+				return nil
+			}
+			m.StatusMessage = fmt.Sprintf("Searching the web for %q…", args)
+			return WebSearchCmd(args)
+		},
+	})
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// MaxAttachmentSize is the largest file or URL body that can be attached
+const MaxAttachmentSize = 256 * 1024
+
+// IsURL reports whether source looks like an HTTP(S) URL
+func IsURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// LoadAttachment reads a local file or fetches a URL to attach to a prompt.
+// Image files are attached as images for vision models.
+func LoadAttachment(source string) (models.Attachment, error) {
+	if IsURL(source) {
+		return loadURLAttachment(source)
+	}
+	return loadFileAttachment(source)
+}
+
+// ExpandHome replaces a leading ~/ in path with the user's home directory
+func ExpandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, path[2:])
+		}
+	}
+	return path
+}
+
+func loadFileAttachment(source string) (models.Attachment, error) {
+	filePath := ExpandHome(source)
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("cannot attach %s: %w", source, err)
+	}
+	if info.IsDir() {
+		return models.Attachment{}, fmt.Errorf("cannot attach %s: is a directory", source)
+	}
+	if IsImagePath(filePath) {
+		if info.Size() > MaxImageSize {
+			return models.Attachment{}, fmt.Errorf("cannot attach %s: larger than %d MB", source, MaxImageSize/1024/1024)
+		}
+		return loadImageAttachment(source, filePath)
+	}
+	if info.Size() > MaxAttachmentSize {
+		return models.Attachment{}, fmt.Errorf("cannot attach %s: larger than %d KB", source, MaxAttachmentSize/1024)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("cannot attach %s: %w", source, err)
+	}
+	if !utf8.Valid(data) {
+		return models.Attachment{}, fmt.Errorf("cannot attach %s: not a text file", source)
+	}
+
+	return models.Attachment{
+		Name:    filepath.Base(filePath),
+		Source:  source,
+		Content: string(data),
+	}, nil
+}
+
+func loadURLAttachment(source string) (models.Attachment, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(source)
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("cannot fetch %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return models.Attachment{}, fmt.Errorf("cannot fetch %s: status %d", source, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxAttachmentSize))
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("cannot fetch %s: %w", source, err)
+	}
+
+	name := path.Base(strings.TrimRight(resp.Request.URL.Path, "/"))
+	if name == "" || name == "." || name == "/" {
+		name = resp.Request.URL.Host
+	}
+
+	return models.Attachment{
+		Name:    name,
+		Source:  source,
+		Content: strings.ToValidUTF8(string(data), ""),
+	}, nil
+}
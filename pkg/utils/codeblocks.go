@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// CodeBlock is a fenced code block in a Markdown response
+type CodeBlock struct {
+	Lang string
+	// Filename is the file the block is meant for, when the response says
+	Filename string
+	Code     string
+}
+
+var (
+	// fencePattern matches an opening or closing ``` or ~~~ fence
+	fencePattern = regexp.MustCompile("^\\s*(```+|~~~+)\\s*(.*)$")
+	// filenameAttrPattern matches title="x" or filename=x in a fence's info string
+	filenameAttrPattern = regexp.MustCompile(`(?:title|file|filename|path)=["']?([^"'\s]+)`)
+	// filenameCommentPattern matches a first line like "// file: main.go"
+	filenameCommentPattern = regexp.MustCompile(`^\s*(?://|#|--|;|/\*|<!--)\s*(?:file(?:name)?|path)?:?\s*([\w./-]+\.\w+)\s*(?:\*/|-->)?\s*$`)
+)
+
+// ExtractCodeBlocks returns the fenced code blocks in text, with the file
+// name hinted by the fence (```go main.go, ```go:main.go, title="main.go")
+// or by a comment on the first line of the block
+func ExtractCodeBlocks(text string) []CodeBlock {
+	var blocks []CodeBlock
+	var current *CodeBlock
+	var fence string
+	var code []string
+
+	for _, line := range strings.Split(text, "\n") {
+		m := fencePattern.FindStringSubmatch(line)
+		if current == nil {
+			if m != nil {
+				current = parseFenceInfo(m[2])
+				fence = m[1]
+				code = nil
+			}
+			continue
+		}
+		// A closing fence is at least as long as the opening one, with no info
+		if m != nil && m[1][0] == fence[0] && len(m[1]) >= len(fence) && strings.TrimSpace(m[2]) == "" {
+			current.Code = strings.Join(code, "\n")
+			if current.Filename == "" && len(code) > 0 {
+				if c := filenameCommentPattern.FindStringSubmatch(code[0]); c != nil {
+					current.Filename = c[1]
+				}
+			}
+			blocks = append(blocks, *current)
+			current = nil
+			continue
+		}
+		code = append(code, line)
+	}
+
+	// A response cut off mid-block still yields what was written
+	if current != nil && len(code) > 0 {
+		current.Code = strings.TrimRight(strings.Join(code, "\n"), "\n")
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+// parseFenceInfo reads the language and file name from a fence's info string
+func parseFenceInfo(info string) *CodeBlock {
+	block := &CodeBlock{}
+	if m := filenameAttrPattern.FindStringSubmatch(info); m != nil {
+		block.Filename = m[1]
+		info = filenameAttrPattern.ReplaceAllString(info, "")
+	}
+
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return block
+	}
+	lang := strings.Trim(fields[0], "{}.")
+	// ```go:main.go
+	if i := strings.Index(lang, ":"); i > 0 {
+		if block.Filename == "" {
+			block.Filename = lang[i+1:]
+		}
+		lang = lang[:i]
+	}
+	// ```main.go names a file without a language
+	if path.Ext(lang) != "" && block.Filename == "" {
+		block.Filename = lang
+		lang = strings.TrimPrefix(path.Ext(lang), ".")
+	}
+	block.Lang = lang
+	if block.Filename == "" && len(fields) > 1 && strings.Contains(fields[1], ".") {
+		block.Filename = fields[1]
+	}
+	return block
+}
+
+// languageExtensions maps fence languages to file extensions for blocks
+// without a file name
+var languageExtensions = map[string]string{
+	"go": "go", "golang": "go", "python": "py", "py": "py", "javascript": "js",
+	"js": "js", "typescript": "ts", "ts": "ts", "tsx": "tsx", "jsx": "jsx",
+	"rust": "rs", "rs": "rs", "java": "java", "kotlin": "kt", "c": "c",
+	"cpp": "cpp", "c++": "cpp", "csharp": "cs", "cs": "cs", "ruby": "rb",
+	"rb": "rb", "php": "php", "swift": "swift", "bash": "sh", "sh": "sh",
+	"shell": "sh", "zsh": "sh", "fish": "fish", "powershell": "ps1",
+	"sql": "sql", "html": "html", "css": "css", "scss": "scss", "json": "json",
+	"yaml": "yaml", "yml": "yaml", "toml": "toml", "xml": "xml",
+	"markdown": "md", "md": "md", "dockerfile": "Dockerfile", "makefile": "Makefile",
+	"lua": "lua", "r": "r", "dart": "dart", "elixir": "ex", "haskell": "hs",
+}
+
+// SuggestedFilename returns the block's file name hint, or a name built
+// from its language
+func (b CodeBlock) SuggestedFilename() string {
+	if b.Filename != "" {
+		return b.Filename
+	}
+	ext, ok := languageExtensions[strings.ToLower(b.Lang)]
+	switch {
+	case !ok:
+		return "snippet.txt"
+	case ext == "Dockerfile" || ext == "Makefile":
+		return ext
+	}
+	return "snippet." + ext
+}
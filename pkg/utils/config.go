@@ -4,21 +4,472 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // Config represents the application configuration
 type Config struct {
-	OpenAIAPIKey string `json:"openai_api_key,omitempty"`
+	OpenAIAPIKey string              `json:"openai_api_key,omitempty"`
+	Keybindings  map[string][]string `json:"keybindings,omitempty"`
+
+	// GenerationTimeout is the wall-clock limit for a single response, as a
+	// Go duration string (e.g. "120s", "5m"). "0" disables the limit.
+	GenerationTimeout string `json:"generation_timeout,omitempty"`
+
+	// PersistHistory saves submitted prompts across sessions
+	PersistHistory bool `json:"persist_history,omitempty"`
+
+	// MultilineInput makes Enter insert a newline and Alt+Enter send
+	MultilineInput bool `json:"multiline_input,omitempty"`
+	// MaxInputLines is the height the input box may grow to (default 10)
+	MaxInputLines int `json:"max_input_lines,omitempty"`
+
+	// KeepAlive is sent to Ollama with every request to control how long the
+	// model stays loaded ("5m", "0" to unload right away, "-1" to keep it)
+	KeepAlive string `json:"keep_alive,omitempty"`
+
+	// Stop sequences end responses where they appear, and Seed makes
+	// sampling repeatable
+	Stop []string `json:"stop,omitempty"`
+	Seed *int     `json:"seed,omitempty"`
+	// Options are passed through as is to requests, keyed by provider: into
+	// Ollama's options object (e.g. {"ollama": {"num_ctx": 8192,
+	// "mirostat": 2}}), or into the body for OpenAI-compatible providers
+	Options map[string]map[string]interface{} `json:"options,omitempty"`
+	// Deterministic sends requests with temperature 0 and a fixed seed, and
+	// saves each with its response so /replay can send it again
+	Deterministic bool `json:"deterministic,omitempty"`
+
+	// ContextStrategy decides what is left out of the conversation when it
+	// nears the end of the context window: "off", "drop-oldest",
+	// "sliding-window" (the default) or "summarize"
+	ContextStrategy string `json:"context_strategy,omitempty"`
+
+	// AutoMemory folds the older turns of long conversations into a
+	// summary kept in context, in the background
+	AutoMemory bool `json:"auto_memory,omitempty"`
+
+	// ModelSort is the model list order: default, name, size, family or recent
+	ModelSort string `json:"model_sort,omitempty"`
+	// GroupModels shows family headers in the model list
+	GroupModels bool `json:"group_models,omitempty"`
+	// RecentModels lists recently used models, most recent first
+	RecentModels []string `json:"recent_models,omitempty"`
+	// FavoriteModels are pinned at the top of the model list
+	FavoriteModels []string `json:"favorite_models,omitempty"`
+	// LastProvider is the provider of the most recently used model
+	LastProvider string `json:"last_provider,omitempty"`
+	// StartWithLastModel skips provider and model selection on startup and
+	// opens a chat with the most recently used model
+	StartWithLastModel bool `json:"start_with_last_model,omitempty"`
+	// DefaultProvider skips provider selection on startup
+	DefaultProvider string `json:"default_provider,omitempty"`
+	// DefaultModel skips model selection on startup when a default provider is set
+	DefaultModel string `json:"default_model,omitempty"`
+	// SystemPrompt gives every new chat these instructions, until /system
+	// changes them
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// Providers limits the provider list, and the models of "all", to these
+	// providers
+	Providers []string `json:"providers,omitempty"`
+
+	// Profile is the profile used when --profile is not given
+	Profile string `json:"profile,omitempty"`
+	// Profiles are named sets of settings, each an object of config.json
+	// keys that replace the ones of the file while the profile is used
+	Profiles map[string]json.RawMessage `json:"profiles,omitempty"`
+
+	// EmbeddingModel is used to embed stored messages for /recall
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+
+	// Storage is where sessions, their usage and the embeddings for /recall
+	// are kept: "json" files, the default, or a "sqlite" database
+	Storage string `json:"storage,omitempty"`
+
+	// MCPServers are Model Context Protocol servers whose tools are offered
+	// to models, keyed by a short name
+	MCPServers map[string]MCPServerConfig `json:"mcp_servers,omitempty"`
+
+	// WebSearch selects the backend used by /web
+	WebSearch WebSearchConfig `json:"web_search,omitempty"`
+
+	// Shell limits the commands /sh and /run may execute
+	Shell ShellConfig `json:"shell,omitempty"`
+
+	// ImageProtocol draws images in the transcript: "kitty", "iterm2",
+	// "sixel", "none", or "auto" (the default) to detect it from the terminal
+	ImageProtocol string `json:"image_protocol,omitempty"`
+
+	// Speech configures voice input
+	Speech SpeechConfig `json:"speech,omitempty"`
+
+	// Notifications announce responses that finish in the background
+	Notifications NotificationConfig `json:"notifications,omitempty"`
+
+	// ConversationLog appends every exchange to a JSONL file for analysis
+	ConversationLog ConversationLogConfig `json:"conversation_log,omitempty"`
+
+	// Share says where /share uploads transcripts
+	Share ShareConfig `json:"share,omitempty"`
+
+	// Prices are what hosted models cost, by model name, for the spend
+	// estimates of the usage screen
+	Prices map[string]ModelPrice `json:"prices,omitempty"`
+
+	// HideReasoning hides the <think> sections of reasoning models in the
+	// transcript and leaves them out of exports
+	HideReasoning bool `json:"hide_reasoning,omitempty"`
+
+	// HideResponseMetadata hides the footer with Ollama's timings and token
+	// counts under each response
+	HideResponseMetadata bool `json:"hide_response_metadata,omitempty"`
+
+	// Theme names the colors of the interface: "default", "light" or "mono"
+	Theme string `json:"theme,omitempty"`
+
+	// HideTimestamps hides the time of each message and how long each
+	// response took from the message headers
+	HideTimestamps bool `json:"hide_timestamps,omitempty"`
+
+	// SidePane shows the session, model and context pane beside the
+	// transcript (toggled with F2)
+	SidePane bool `json:"side_pane,omitempty"`
+
+	// AccessibleMode prints the conversation as plain text for screen
+	// readers instead of drawing a full-screen interface
+	AccessibleMode bool `json:"accessible_mode,omitempty"`
+
+	// HTTP tunes the connections to the providers
+	HTTP HTTPConfig `json:"http,omitempty"`
+
+	// OllamaHost is the Ollama server to use, like OLLAMA_HOST (default
+	// http://localhost:11434)
+	OllamaHost string `json:"ollama_host,omitempty"`
+	// Hosts are credentials for servers behind an authenticating reverse
+	// proxy, keyed by host name, host:port or URL
+	Hosts map[string]HostConfig `json:"hosts,omitempty"`
+	// OpenAIBaseURL points the openai provider at a server compatible with
+	// the OpenAI API instead of api.openai.com
+	OpenAIBaseURL string `json:"openai_base_url,omitempty"`
+	// LMStudioURL is where LM Studio's OpenAI-compatible server listens
+	// (default http://localhost:1234/v1)
+	LMStudioURL string `json:"lmstudio_url,omitempty"`
+	// RegistryURL is where the registry browser downloads its catalog of
+	// library models from (default the catalog in the ollama-tui repository)
+	RegistryURL string `json:"registry_url,omitempty"`
+	// PluginsDir is where provider plugins, executables named
+	// ollama-tui-provider-<name>, are looked for (default "plugins" in the
+	// config directory)
+	PluginsDir string `json:"plugins_dir,omitempty"`
+	// OpenAIModels limits the OpenAI models listed to these ids or shell
+	// patterns like "gpt-4o*"
+	OpenAIModels []string `json:"openai_models,omitempty"`
+
+	// MistralAPIKey is the key for Mistral's platform, used when
+	// MISTRAL_API_KEY is not set
+	MistralAPIKey string `json:"mistral_api_key,omitempty"`
+	// MistralSafePrompt asks Mistral to prepend its safety system prompt
+	MistralSafePrompt bool `json:"mistral_safe_prompt,omitempty"`
+	// DeepSeekAPIKey is the key for DeepSeek's API, used when
+	// DEEPSEEK_API_KEY is not set
+	DeepSeekAPIKey string `json:"deepseek_api_key,omitempty"`
+	// TogetherAPIKey and FireworksAPIKey are the keys for the Together AI
+	// and Fireworks presets, used when TOGETHER_API_KEY or FIREWORKS_API_KEY
+	// are not set
+	TogetherAPIKey  string `json:"together_api_key,omitempty"`
+	FireworksAPIKey string `json:"fireworks_api_key,omitempty"`
+	// PerplexityAPIKey is the key for Perplexity, used when
+	// PERPLEXITY_API_KEY is not set
+	PerplexityAPIKey string `json:"perplexity_api_key,omitempty"`
+	// HuggingFaceToken is the access token for Hugging Face, used when
+	// HF_TOKEN is not set
+	HuggingFaceToken string `json:"huggingface_token,omitempty"`
+	// HuggingFace lists the models of the huggingface provider
+	HuggingFace []HuggingFaceConfig `json:"huggingface,omitempty"`
+	// KeyChecks are the last checks of each provider's API key, by provider
+	KeyChecks map[string]KeyCheck `json:"key_checks,omitempty"`
+
+	// Discovery lists where to look for servers on the network
+	Discovery DiscoveryConfig `json:"discovery,omitempty"`
+
+	// NoMemories pauses adding the facts remembered with /remember to the
+	// system prompt
+	NoMemories bool `json:"no_memories,omitempty"`
+
+	// NoUpdateCheck turns off the daily check for a new release
+	NoUpdateCheck bool `json:"no_update_check,omitempty"`
+	// UpdateCheck is the last check for a new release
+	UpdateCheck *UpdateCheck `json:"update_check,omitempty"`
+}
+
+// UpdateCheck is the outcome of checking for a new release
+type UpdateCheck struct {
+	At time.Time `json:"at"`
+	// Latest is the latest release and URL its page
+	Latest string `json:"latest,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// Dismissed is the release whose notice was dismissed
+	Dismissed string `json:"dismissed,omitempty"`
+}
+
+// KeyCheck is the outcome of checking an API key with its provider
+type KeyCheck struct {
+	At time.Time `json:"at"`
+	// Error is why the key was rejected; empty when it was accepted
+	Error string `json:"error,omitempty"`
 }
 
-// GetConfigDir returns the directory where configuration files are stored
+// DiscoveryConfig lists where to look for Ollama and OpenAI-compatible
+// servers
+type DiscoveryConfig struct {
+	// Hosts are probed on each of Ports, or only on the port they name, as
+	// in "gpu-box:11434" (default localhost)
+	Hosts []string `json:"hosts,omitempty"`
+	// Ports default to 11434 (Ollama), 1234 (LM Studio), 8080 (llama.cpp)
+	// and 8000 (vLLM)
+	Ports []int `json:"ports,omitempty"`
+	// ScanLAN also probes every address of the local networks
+	ScanLAN bool `json:"scan_lan,omitempty"`
+}
+
+// HuggingFaceConfig is a model served by a Hugging Face Inference Endpoint,
+// a text-generation-inference server or the Inference API
+type HuggingFaceConfig struct {
+	// Name is how the model is listed
+	Name string `json:"name"`
+	// URL is the endpoint (default the Inference API router)
+	URL string `json:"url,omitempty"`
+	// Model is the model id for the Inference API, e.g.
+	// "meta-llama/Llama-3.1-8B-Instruct"
+	Model string `json:"model,omitempty"`
+	// API is "chat" (default) for the Messages API or "generate" for
+	// text-generation-inference's /generate_stream
+	API string `json:"api,omitempty"`
+	// Token replaces huggingface_token for this endpoint
+	Token string `json:"token,omitempty"`
+}
+
+// HostConfig authenticates requests to a server behind a reverse proxy:
+// a bearer token or a username and password, and any other headers
+type HostConfig struct {
+	BearerToken string `json:"bearer_token,omitempty"`
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	// Headers are sent as they are, e.g. Cloudflare Access service tokens
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Defaults for the connections to the providers
+const (
+	DefaultConnectTimeout  = 10 * time.Second
+	DefaultReadTimeout     = 5 * time.Minute
+	DefaultIdleConnTimeout = 90 * time.Second
+)
+
+// HTTPConfig tunes the connections to the providers. Durations are Go
+// duration strings; "0" disables a timeout.
+type HTTPConfig struct {
+	// ConnectTimeout limits establishing a connection, including the TLS
+	// handshake (default 10s)
+	ConnectTimeout string `json:"connect_timeout,omitempty"`
+	// ReadTimeout limits the wait for a response to start, which includes
+	// loading the model, and for each further piece of it (default 5m)
+	ReadTimeout string `json:"read_timeout,omitempty"`
+	// IdleConnTimeout closes reusable connections left unused this long
+	// (default 90s)
+	IdleConnTimeout string `json:"idle_conn_timeout,omitempty"`
+	// MaxIdleConnsPerHost is how many unused connections are kept open per
+	// host for reuse (default 2)
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host,omitempty"`
+	// DisableKeepAlives opens a new connection for every request
+	DisableKeepAlives bool `json:"disable_keep_alives,omitempty"`
+	// Proxy is the URL of a proxy for every request. HTTP_PROXY, HTTPS_PROXY
+	// and NO_PROXY are used when it is unset.
+	Proxy string `json:"proxy,omitempty"`
+	// CACert is a PEM file of certificate authorities to trust besides the
+	// system's
+	CACert string `json:"ca_cert,omitempty"`
+	// InsecureSkipVerify accepts any server certificate, for local servers
+	// with self-signed ones
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// ConnectTimeoutDuration returns the configured connect timeout
+func (c HTTPConfig) ConnectTimeoutDuration() time.Duration {
+	return durationOr(c.ConnectTimeout, DefaultConnectTimeout)
+}
+
+// ReadTimeoutDuration returns the configured read timeout
+func (c HTTPConfig) ReadTimeoutDuration() time.Duration {
+	return durationOr(c.ReadTimeout, DefaultReadTimeout)
+}
+
+// IdleConnTimeoutDuration returns the configured idle connection timeout
+func (c HTTPConfig) IdleConnTimeoutDuration() time.Duration {
+	return durationOr(c.IdleConnTimeout, DefaultIdleConnTimeout)
+}
+
+// durationOr parses a duration string, falling back when it is unset or
+// invalid
+func durationOr(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fallback
+	}
+	return d
+}
+
+// DefaultNotifyAfter is how long a response must take to be announced
+// while the terminal is focused
+const DefaultNotifyAfter = 30 * time.Second
+
+// NotificationConfig selects how finished responses are announced
+type NotificationConfig struct {
+	// Method is "osc9", "osc777", "system", "bell", "off", or "auto" (the
+	// default) to pick one for the terminal
+	Method string `json:"method,omitempty"`
+	// Bell also rings the terminal bell
+	Bell bool `json:"bell,omitempty"`
+	// After is how long a response must take to be announced while the
+	// terminal is focused, as a Go duration string. "0" only announces
+	// responses that finish while the terminal is unfocused.
+	After string `json:"after,omitempty"`
+}
+
+// AfterDuration returns the configured notification delay, falling back to
+// the default when unset or invalid
+func (c NotificationConfig) AfterDuration() time.Duration {
+	if c.After == "" {
+		return DefaultNotifyAfter
+	}
+	d, err := time.ParseDuration(c.After)
+	if err != nil || d < 0 {
+		return DefaultNotifyAfter
+	}
+	return d
+}
+
+// ModelPrice is what a model costs in US dollars per million tokens
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Cost returns what a response cost
+func (p ModelPrice) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// Conversation log defaults
+const (
+	DefaultConversationLogMaxSizeMB = 10
+	DefaultConversationLogMaxFiles  = 5
+)
+
+// ConversationLogConfig turns on the conversation log
+type ConversationLogConfig struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Path is the log file (default conversations.jsonl in the data
+	// directory)
+	Path string `json:"path,omitempty"`
+	// MaxSizeMB is the size the log is rotated at (default 10)
+	MaxSizeMB int `json:"max_size_mb,omitempty"`
+	// MaxFiles is how many rotated logs are kept besides the current one
+	// (default 5)
+	MaxFiles int `json:"max_files,omitempty"`
+}
+
+// SpeechConfig selects how voice prompts are recorded and transcribed
+type SpeechConfig struct {
+	// Backend is "whisper.cpp" (the default) for a whisper.cpp server, or
+	// "openai" for the OpenAI audio API or a server compatible with it
+	Backend string `json:"backend,omitempty"`
+	// URL is the whisper.cpp inference endpoint or the OpenAI base URL
+	URL    string `json:"url,omitempty"`
+	APIKey string `json:"api_key,omitempty"`
+	// Model is the OpenAI transcription model (default whisper-1)
+	Model string `json:"model,omitempty"`
+	// Language is the spoken language as an ISO-639-1 code; detected when empty
+	Language string `json:"language,omitempty"`
+	// Recorder is the program capturing the microphone: sox, arecord or
+	// ffmpeg (default: the first one installed)
+	Recorder string `json:"recorder,omitempty"`
+	// Device is the input device passed to arecord or ffmpeg
+	Device string `json:"device,omitempty"`
+}
+
+// ShellConfig limits which shell commands may be run. Each entry is a
+// command with optional leading arguments, such as "git" or "rm -rf /".
+type ShellConfig struct {
+	// Allow, when set, is the only commands that may run
+	Allow []string `json:"allow,omitempty"`
+	// Deny is never run; DefaultShellDeny is used when it is unset
+	Deny []string `json:"deny,omitempty"`
+}
+
+// WebSearchConfig selects a web search backend: "searxng" with the URL of
+// an instance, or "brave" or "tavily" with an API key
+type WebSearchConfig struct {
+	Backend string `json:"backend,omitempty"`
+	URL     string `json:"url,omitempty"`
+	APIKey  string `json:"api_key,omitempty"`
+	// Results is how many pages are fetched per search (default 3)
+	Results int `json:"results,omitempty"`
+}
+
+// ShareConfig says where /share uploads transcripts: to a GitHub Gist with
+// GistToken, or to a paste service at PasteURL
+type ShareConfig struct {
+	// GistToken is a GitHub token allowed to create gists (GITHUB_TOKEN
+	// when empty)
+	GistToken string `json:"gist_token,omitempty"`
+	// PublicGists makes the gists public instead of secret
+	PublicGists bool `json:"public_gists,omitempty"`
+	// PasteURL receives the transcript as the body of a POST and answers
+	// with its URL, like paste.rs
+	PasteURL string `json:"paste_url,omitempty"`
+	// Target is the default of /share: "gist" or "paste"
+	Target string `json:"target,omitempty"`
+}
+
+// MCPServerConfig describes how to reach an MCP server: a Command to run
+// over stdio, or the URL of an SSE endpoint
+type MCPServerConfig struct {
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	URL     string            `json:"url,omitempty"`
+}
+
+// DefaultGenerationTimeout is used when no generation timeout is configured
+const DefaultGenerationTimeout = 120 * time.Second
+
+// GenerationTimeoutDuration returns the configured generation time limit,
+// falling back to the default when unset or invalid
+func (c Config) GenerationTimeoutDuration() time.Duration {
+	if c.GenerationTimeout == "" {
+		return DefaultGenerationTimeout
+	}
+	d, err := time.ParseDuration(c.GenerationTimeout)
+	if err != nil || d < 0 {
+		return DefaultGenerationTimeout
+	}
+	return d
+}
+
+// GetConfigDir returns the directory where configuration files are stored:
+// ollama-tui in $XDG_CONFIG_HOME (~/.config) on Linux, ~/Library/Application
+// Support on macOS and %AppData% on Windows
 func GetConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	userConfigDir, err := os.UserConfigDir()
 	if err != nil {
 		return "", err
 	}
 
-	configDir := filepath.Join(homeDir, ".config", "ollama-tui")
+	configDir := filepath.Join(userConfigDir, appName)
 	err = os.MkdirAll(configDir, 0755)
 	if err != nil {
 		return "", err
@@ -27,8 +478,12 @@ func GetConfigDir() (string, error) {
 	return configDir, nil
 }
 
-// GetConfigPath returns the path to the configuration file
+// GetConfigPath returns the path to the configuration file, which
+// OLLAMA_TUI_CONFIG can move
 func GetConfigPath() (string, error) {
+	if path := os.Getenv(ConfigPathEnv); path != "" {
+		return path, nil
+	}
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return "", err
@@ -49,7 +504,8 @@ func SaveConfig(config Config) error {
 		return err
 	}
 
-	return os.WriteFile(configPath, data, 0644)
+	// Only its owner may read it, as it holds API keys
+	return os.WriteFile(configPath, data, 0600)
 }
 
 // LoadConfig loads the configuration from a file
@@ -80,14 +536,14 @@ func LoadConfig() (Config, error) {
 	return config, nil
 }
 
-// SaveAPIKey saves the API key to the configuration file
-func SaveAPIKey(apiKey string) error {
+// UpdateConfig loads the configuration, applies update and saves it
+func UpdateConfig(update func(*Config)) error {
 	config, err := LoadConfig()
 	if err != nil {
 		return err
 	}
 
-	config.OpenAIAPIKey = apiKey
+	update(&config)
 
 	return SaveConfig(config)
 }
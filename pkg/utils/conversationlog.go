@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ConversationLogEntry is a line of the conversation log: one prompt and
+// the response to it
+type ConversationLogEntry struct {
+	// Time is when the response finished
+	Time      time.Time `json:"time"`
+	StartedAt time.Time `json:"started_at"`
+	// DurationMS is how long the response took in milliseconds
+	DurationMS int64  `json:"duration_ms"`
+	SessionID  string `json:"session_id,omitempty"`
+	Profile    string `json:"profile,omitempty"`
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	Prompt     string `json:"prompt"`
+	Response   string `json:"response"`
+	// PromptTokens and CompletionTokens are the counts the provider
+	// reported, or estimates when TokensEstimated is set
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TokensEstimated  bool   `json:"tokens_estimated,omitempty"`
+	TimedOut         bool   `json:"timed_out,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// LogPath returns the conversation log file
+func (c ConversationLogConfig) LogPath() (string, error) {
+	if c.Path != "" {
+		return c.Path, nil
+	}
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "conversations.jsonl"), nil
+}
+
+// AppendConversationLog writes an entry at the end of the conversation log,
+// first rotating the log when the entry would take it past its size limit:
+// conversations.jsonl moves to conversations.jsonl.1, which moves to .2, and
+// so on, dropping the oldest beyond MaxFiles.
+func AppendConversationLog(config ConversationLogConfig, entry ConversationLogEntry) error {
+	path, err := config.LogPath()
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode log entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	maxSize := int64(config.MaxSizeMB) * 1024 * 1024
+	if config.MaxSizeMB <= 0 {
+		maxSize = DefaultConversationLogMaxSizeMB * 1024 * 1024
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > maxSize {
+		if err := rotateLog(path, config.MaxFiles); err != nil {
+			return fmt.Errorf("failed to rotate %s: %w", path, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(line)
+	return err
+}
+
+// rotateLog shifts path to path.1, path.1 to path.2 and so on, keeping
+// maxFiles rotated files
+func rotateLog(path string, maxFiles int) error {
+	if maxFiles <= 0 {
+		maxFiles = DefaultConversationLogMaxFiles
+	}
+	if err := os.Remove(fmt.Sprintf("%s.%d", path, maxFiles)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := maxFiles - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(path, path+".1")
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GetCrashDir returns the directory crash reports are written to
+func GetCrashDir() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(dataDir, "crashes")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// WriteCrashReport saves what a panic left: its value, the stack trace and
+// the build, for a bug report. It returns the path of the report.
+func WriteCrashReport(value any, stack []byte) (string, error) {
+	dir, err := GetCrashDir()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ollama-tui crashed at %s\n\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "Version: %s\n", GetBuildInfo())
+	if ActiveProfile != "" {
+		fmt.Fprintf(&sb, "Profile: %s\n", ActiveProfile)
+	}
+	fmt.Fprintf(&sb, "\npanic: %v\n\n%s", value, stack)
+
+	path := filepath.Join(dir, "crash-"+now.Format("20060102-150405")+".log")
+	if err := os.WriteFile(path, []byte(sb.String()), 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the line-by-line comparison, which grows with the
+// product of the two files' line counts
+const maxDiffCells = 4_000_000
+
+// DiffLine is a line of a diff: ' ' unchanged, '-' removed, '+' added, or
+// '@' for a note about skipped lines
+type DiffLine struct {
+	Kind byte
+	Text string
+}
+
+// LineDiff compares two texts line by line using their longest common
+// subsequence. It returns false when the texts are too large to compare.
+func LineDiff(oldText, newText string) ([]DiffLine, bool) {
+	a := strings.Split(strings.TrimSuffix(oldText, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(newText, "\n"), "\n")
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return nil, false
+	}
+
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, DiffLine{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, DiffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{'+', b[j]})
+			j++
+		}
+	}
+	return lines, true
+}
+
+// DiffHunks keeps the changed lines and up to context unchanged lines
+// around them, marking skipped stretches with a "@@" line
+func DiffHunks(lines []DiffLine, context int) []DiffLine {
+	keep := make([]bool, len(lines))
+	for i, l := range lines {
+		if l.Kind == ' ' {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(lines)-1, i+context); j++ {
+			keep[j] = true
+		}
+	}
+
+	var hunks []DiffLine
+	skipped := 0
+	for i, l := range lines {
+		if !keep[i] {
+			skipped++
+			continue
+		}
+		if skipped > 0 {
+			hunks = append(hunks, DiffLine{'@', fmt.Sprintf("@@ %d unchanged lines @@", skipped)})
+			skipped = 0
+		}
+		hunks = append(hunks, l)
+	}
+	if skipped > 0 && len(hunks) > 0 {
+		hunks = append(hunks, DiffLine{'@', fmt.Sprintf("@@ %d unchanged lines @@", skipped)})
+	}
+	return hunks
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the environment variables that override settings of
+// config.json: OLLAMA_TUI_DEFAULT_MODEL overrides "default_model"
+const EnvPrefix = "OLLAMA_TUI_"
+
+// ConfigPathEnv is the environment variable that moves config.json
+const ConfigPathEnv = EnvPrefix + "CONFIG"
+
+// DotEnvFiles are read at startup, in this order: .env in the current
+// directory when local is set, then .env in the config directory. The
+// current directory's is opt-in since it belongs to whatever project the app
+// is started in.
+func DotEnvFiles(local bool) []string {
+	var files []string
+	if local {
+		files = append(files, ".env")
+	}
+	if configDir, err := GetConfigDir(); err == nil {
+		files = append(files, filepath.Join(configDir, ".env"))
+	}
+	return files
+}
+
+// LoadDotEnv sets the OLLAMA_TUI_* variables of a .env file that aren't set
+// yet. Lines are KEY=VALUE, optionally starting with "export"; values may be
+// quoted and lines starting with # are comments. Other variables and lines
+// that can't be read are skipped, and returned as an error after the rest
+// was applied. A missing file is not an error.
+func LoadDotEnv(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var errs []error
+	var ignored []string
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			errs = append(errs, fmt.Errorf("%s:%d: expected KEY=VALUE, skipped", path, n))
+			continue
+		}
+		if !strings.HasPrefix(key, EnvPrefix) {
+			ignored = append(ignored, key)
+			continue
+		}
+		value = dotEnvValue(strings.TrimSpace(value))
+		if _, set := os.LookupEnv(key); !set {
+			if err := os.Setenv(key, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s:%d: %w", path, n, err))
+			}
+		}
+	}
+	if len(ignored) > 0 {
+		errs = append(errs, fmt.Errorf("%s: ignored %s, only %s* variables are read", path, strings.Join(ignored, ", "), EnvPrefix))
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// dotEnvValue unquotes a .env value, or strips a trailing comment from an
+// unquoted one
+func dotEnvValue(value string) string {
+	if len(value) >= 2 {
+		switch value[0] {
+		case '"':
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+		case '\'':
+			if value[len(value)-1] == '\'' {
+				return value[1 : len(value)-1]
+			}
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// ApplyEnvOverrides sets the top-level text, number and switch settings of
+// config from their OLLAMA_TUI_* variables, named after their key in
+// config.json in upper case
+func ApplyEnvOverrides(config *Config) error {
+	var errs []error
+	v := reflect.ValueOf(config).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := EnvPrefix + strings.ToUpper(tag)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not true or false", name, value))
+				continue
+			}
+			field.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", name, value))
+				continue
+			}
+			field.SetInt(int64(n))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LoadEffectiveConfig loads config.json with the active profile and the
+// environment's overrides applied. Settings are layered, the first one that
+// sets a value winning: command-line flags, OLLAMA_TUI_* variables (from the
+// environment, then .env files), the active profile, config.json and the
+// built-in defaults.
+//
+// The result is for reading settings: saving it would write the overrides to
+// the file, so changes go through UpdateConfig.
+func LoadEffectiveConfig() (Config, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return config, err
+	}
+	if ActiveProfile != "" {
+		if err := ApplyProfile(&config, ActiveProfile); err != nil {
+			return config, err
+		}
+	}
+	err = ApplyEnvOverrides(&config)
+	if ActiveProfile != "" {
+		config.Profile = ActiveProfile
+	}
+	return config, err
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDotEnvOnlySetsPrefixedVariables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := strings.Join([]string{
+		"# comment",
+		"export OLLAMA_TUI_DEFAULT_MODEL=llama3",
+		`OLLAMA_TUI_SYSTEM_PROMPT="be brief"`,
+		"EDITOR=evil",
+		"PRIVATE_KEY=\"-----BEGIN",
+		"not a variable",
+		"-----END\"",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OLLAMA_TUI_DEFAULT_MODEL", "")
+	os.Unsetenv("OLLAMA_TUI_DEFAULT_MODEL")
+	t.Setenv("OLLAMA_TUI_SYSTEM_PROMPT", "already set")
+	t.Setenv("EDITOR", "vi")
+
+	err := LoadDotEnv(path)
+	if err == nil {
+		t.Fatal("expected the skipped lines to be reported")
+	}
+	for _, want := range []string{"EDITOR", "PRIVATE_KEY", ":6:", ":7:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	if got := os.Getenv("OLLAMA_TUI_DEFAULT_MODEL"); got != "llama3" {
+		t.Errorf("OLLAMA_TUI_DEFAULT_MODEL = %q, want llama3", got)
+	}
+	if got := os.Getenv("OLLAMA_TUI_SYSTEM_PROMPT"); got != "already set" {
+		t.Errorf("OLLAMA_TUI_SYSTEM_PROMPT = %q, the environment should win", got)
+	}
+	if got := os.Getenv("EDITOR"); got != "vi" {
+		t.Errorf("EDITOR = %q, unprefixed variables must be ignored", got)
+	}
+}
+
+func TestDotEnvFilesCurrentDirectoryIsOptIn(t *testing.T) {
+	for _, file := range DotEnvFiles(false) {
+		if file == ".env" {
+			t.Error("the current directory's .env is read without --dotenv")
+		}
+	}
+	if files := DotEnvFiles(true); len(files) == 0 || files[0] != ".env" {
+		t.Errorf("DotEnvFiles(true) = %v, want .env first", files)
+	}
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// MaxHistoryEntries is the number of prompts kept in the history file
+const MaxHistoryEntries = 1000
+
+// GetHistoryPath returns the path to the prompt history file
+func GetHistoryPath() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dataDir, "history.jsonl"), nil
+}
+
+// LoadHistory loads previously submitted prompts, oldest first
+func LoadHistory() ([]string, error) {
+	historyPath, err := GetHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(historyPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var prompt string
+		if err := json.Unmarshal(scanner.Bytes(), &prompt); err != nil {
+			continue
+		}
+		history = append(history, prompt)
+	}
+
+	if len(history) > MaxHistoryEntries {
+		history = history[len(history)-MaxHistoryEntries:]
+	}
+
+	return history, scanner.Err()
+}
+
+// SaveHistory writes the prompt history, keeping only the most recent entries
+func SaveHistory(history []string) error {
+	historyPath, err := GetHistoryPath()
+	if err != nil {
+		return err
+	}
+
+	if len(history) > MaxHistoryEntries {
+		history = history[len(history)-MaxHistoryEntries:]
+	}
+
+	file, err := os.OpenFile(historyPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, prompt := range history {
+		if err := encoder.Encode(prompt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/evilvic/ollama-tui/pkg/models"
+)
+
+// MaxImageSize is the largest image that can be attached
+const MaxImageSize = 10 * 1024 * 1024
+
+// imageExtensions are the image formats vision models accept
+var imageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+}
+
+// IsImagePath reports whether path names an image file by its extension
+func IsImagePath(path string) bool {
+	return imageExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// ImageMediaType returns the media type of image data, or "" when it is not
+// an image format vision models accept
+func ImageMediaType(data []byte) string {
+	switch mediaType := http.DetectContentType(data); mediaType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return mediaType
+	}
+	return ""
+}
+
+func loadImageAttachment(source, filePath string) (models.Attachment, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("cannot attach %s: %w", source, err)
+	}
+	if ImageMediaType(data) == "" {
+		return models.Attachment{}, fmt.Errorf("cannot attach %s: not a PNG, JPEG, GIF or WebP image", source)
+	}
+	return models.Attachment{
+		Name:   filepath.Base(filePath),
+		Source: source,
+		Image:  data,
+	}, nil
+}
+
+// SaveClipboardImage stores an image pasted from the clipboard in the data
+// directory, so it can be shown and opened like an attached file
+func SaveClipboardImage(data []byte) (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(dataDir, "images")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	ext := strings.TrimPrefix(ImageMediaType(data), "image/")
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	base := "clipboard-" + time.Now().Format("20060102-150405")
+	path := filepath.Join(dir, base+"."+ext)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.%s", base, i, ext))
+	}
+	return path, os.WriteFile(path, data, 0644)
+}
+
+// PastedPath returns the file path in pasted text, as terminals paste files
+// dropped onto them: possibly quoted, with escaped spaces, or as a file:// URL
+func PastedPath(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && (text[0] == '\'' || text[0] == '"') && text[len(text)-1] == text[0] {
+		return text[1 : len(text)-1]
+	}
+	if strings.HasPrefix(text, "file://") {
+		if u, err := url.Parse(text); err == nil {
+			return u.Path
+		}
+	}
+	if strings.ContainsAny(text, "\n\t") {
+		return ""
+	}
+	return strings.ReplaceAll(text, `\ `, " ")
+}
+
+// ReadClipboardImage returns the image on the system clipboard as PNG data,
+// using the platform's clipboard tools
+func ReadClipboardImage() ([]byte, error) {
+	var data []byte
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		data, err = readMacClipboardImage()
+	case "windows":
+		data, err = readWindowsClipboardImage()
+	default:
+		data, err = readUnixClipboardImage()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || ImageMediaType(data) == "" {
+		return nil, errors.New("the clipboard does not contain an image")
+	}
+	if len(data) > MaxImageSize {
+		return nil, fmt.Errorf("the clipboard image is larger than %d MB", MaxImageSize/1024/1024)
+	}
+	return data, nil
+}
+
+// readMacClipboardImage asks AppleScript for the clipboard as PNG, which it
+// prints as «data PNGf89504E47…»
+func readMacClipboardImage() ([]byte, error) {
+	out, err := exec.Command("osascript", "-e", "the clipboard as «class PNGf»").Output()
+	if err != nil {
+		return nil, errors.New("the clipboard does not contain an image")
+	}
+	text := strings.TrimSpace(string(out))
+	text = strings.TrimPrefix(text, "«data PNGf")
+	text = strings.TrimSuffix(text, "»")
+	return hex.DecodeString(text)
+}
+
+// readWindowsClipboardImage has PowerShell save the clipboard image as PNG
+// and print it base64-encoded
+func readWindowsClipboardImage() ([]byte, error) {
+	script := `Add-Type -AssemblyName System.Windows.Forms, System.Drawing
+$img = [System.Windows.Forms.Clipboard]::GetImage()
+if ($img) { $ms = New-Object System.IO.MemoryStream; $img.Save($ms, [System.Drawing.Imaging.ImageFormat]::Png); [Convert]::ToBase64String($ms.ToArray()) }`
+	out, err := exec.Command("powershell", "-NoProfile", "-STA", "-Command", script).Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the clipboard: %w", err)
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+// readUnixClipboardImage uses wl-paste on Wayland and xclip on X11
+func readUnixClipboardImage() ([]byte, error) {
+	var tools [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		tools = append(tools, []string{"wl-paste", "--no-newline", "--type", "image/png"})
+	}
+	tools = append(tools, []string{"xclip", "-selection", "clipboard", "-target", "image/png", "-out"})
+
+	found := false
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		found = true
+		var stdout bytes.Buffer
+		cmd := exec.Command(tool[0], tool[1:]...)
+		cmd.Stdout = &stdout
+		if err := cmd.Run(); err == nil && stdout.Len() > 0 {
+			return stdout.Bytes(), nil
+		}
+	}
+	if !found {
+		return nil, errors.New("reading images from the clipboard needs wl-paste (Wayland) or xclip (X11)")
+	}
+	return nil, errors.New("the clipboard does not contain an image")
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSONLine is a line of indented JSON and the path of the value it starts,
+// in the same form as SchemaViolation paths
+type JSONLine struct {
+	Text string
+	Path string
+}
+
+// IndentJSON pretty-prints a JSON document with two-space indentation,
+// keeping the original key order, and records the path of every line
+func IndentJSON(data []byte) ([]JSONLine, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	p := &jsonPrinter{dec: dec}
+	if err := p.value("$", "", ""); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return p.lines, nil
+}
+
+type jsonPrinter struct {
+	dec   *json.Decoder
+	lines []JSONLine
+}
+
+// value prints the next value in the stream, with lead (such as a key)
+// before it on its first line
+func (p *jsonPrinter) value(path, indent, lead string) error {
+	tok, err := p.dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		text, err := encodeToken(tok)
+		if err != nil {
+			return err
+		}
+		p.emit(path, indent+lead+text)
+		return nil
+	}
+
+	closing := "}"
+	if delim == '[' {
+		closing = "]"
+	}
+	if !p.dec.More() {
+		p.dec.Token()
+		p.emit(path, indent+lead+delim.String()+closing)
+		return nil
+	}
+
+	p.emit(path, indent+lead+delim.String())
+	for i := 0; p.dec.More(); i++ {
+		childPath := fmt.Sprintf("%s[%d]", path, i)
+		childLead := ""
+		if delim == '{' {
+			key, err := p.dec.Token()
+			if err != nil {
+				return err
+			}
+			name, _ := key.(string)
+			encoded, err := encodeToken(name)
+			if err != nil {
+				return err
+			}
+			childPath = path + "." + name
+			childLead = encoded + ": "
+		}
+		if err := p.value(childPath, indent+"  ", childLead); err != nil {
+			return err
+		}
+		if p.dec.More() {
+			p.lines[len(p.lines)-1].Text += ","
+		}
+	}
+	if _, err := p.dec.Token(); err != nil {
+		return err
+	}
+	p.emit(path, indent+closing)
+	return nil
+}
+
+func (p *jsonPrinter) emit(path, text string) {
+	p.lines = append(p.lines, JSONLine{Text: text, Path: path})
+}
+
+// encodeToken encodes a scalar token without escaping HTML characters
+func encodeToken(tok json.Token) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(tok); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaViolation is a place where a JSON value does not match its schema
+type SchemaViolation struct {
+	// Path locates the value, like $.items[2].name
+	Path    string
+	Message string
+}
+
+// ValidateJSON checks a decoded JSON value against the commonly used parts
+// of a JSON Schema: type, enum, const, properties, required,
+// additionalProperties, items, the length and range limits, and pattern.
+// Other keywords are ignored. Every violation is returned, not just the first.
+func ValidateJSON(value interface{}, schema json.RawMessage) ([]SchemaViolation, error) {
+	var s map[string]interface{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	var violations []SchemaViolation
+	validateValue(value, s, "$", &violations)
+	return violations, nil
+}
+
+func validateValue(value interface{}, schema map[string]interface{}, path string, violations *[]SchemaViolation) {
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(value, t) {
+		fail("expected %v, got %s", t, jsonType(value))
+		return
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, value) {
+		fail("must be %v", c)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("%v is not one of %v", value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				if _, ok := v[fmt.Sprint(r)]; !ok {
+					fail("missing required property %q", r)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		// Check properties in a stable order so errors are reproducible
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := properties[name].(map[string]interface{}); ok {
+				validateValue(v[name], sub, path+"."+name, violations)
+				continue
+			}
+			if _, ok := properties[name]; ok {
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					*violations = append(*violations, SchemaViolation{Path: path + "." + name, Message: "property is not allowed"})
+				}
+			case map[string]interface{}:
+				validateValue(v[name], extra, path+"."+name, violations)
+			}
+		}
+	case []interface{}:
+		if n, ok := schema["minItems"].(float64); ok && float64(len(v)) < n {
+			fail("needs at least %v items, has %d", n, len(v))
+		}
+		if n, ok := schema["maxItems"].(float64); ok && float64(len(v)) > n {
+			fail("allows at most %v items, has %d", n, len(v))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateValue(item, items, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if n, ok := schema["minLength"].(float64); ok && length < n {
+			fail("must be at least %v characters", n)
+		}
+		if n, ok := schema["maxLength"].(float64); ok && length > n {
+			fail("must be at most %v characters", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				fail("does not match the pattern %s", pattern)
+			}
+		}
+	case float64:
+		if n, ok := schema["minimum"].(float64); ok && v < n {
+			fail("must be at least %v", n)
+		}
+		if n, ok := schema["maximum"].(float64); ok && v > n {
+			fail("must be at most %v", n)
+		}
+	}
+}
+
+// matchesType reports whether value has the schema type t, which may be a
+// single type name or a list of them
+func matchesType(value interface{}, t interface{}) bool {
+	types, ok := t.([]interface{})
+	if !ok {
+		types = []interface{}{t}
+	}
+	actual := jsonType(value)
+	for _, want := range types {
+		name := fmt.Sprint(want)
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type name of a decoded value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return strings.ToLower(fmt.Sprintf("%T", value))
+}
@@ -0,0 +1,359 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MarkdownHTML renders the Markdown models answer in as HTML: headings,
+// paragraphs, lists, block quotes, tables, rules and fenced code blocks,
+// with code, emphasis and links inside them. Raw HTML is escaped, not
+// passed through.
+func MarkdownHTML(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var sb strings.Builder
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			i++
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence := trimmed[:3]
+			lang := strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1]))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+			sb.WriteString(codeBlockHTML(strings.Join(code, "\n"), lang))
+		case headingPattern.MatchString(line):
+			match := headingPattern.FindStringSubmatch(line)
+			fmt.Fprintf(&sb, "<h%d>%s</h%d>\n", len(match[1]), inlineHTML(strings.TrimRight(match[2], " #")), len(match[1]))
+			i++
+		case rulePattern.MatchString(trimmed):
+			sb.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(quoted, " "))
+			}
+			sb.WriteString("<blockquote>\n" + MarkdownHTML(strings.Join(quote, "\n")) + "</blockquote>\n")
+		case listPattern.MatchString(line):
+			i = writeList(&sb, lines, i)
+		case i+1 < len(lines) && strings.Contains(line, "|") && tableRulePattern.MatchString(strings.TrimSpace(lines[i+1])):
+			i = writeTable(&sb, lines, i)
+		default:
+			var paragraph []string
+			for ; i < len(lines) && startsParagraph(lines, i, len(paragraph) == 0); i++ {
+				paragraph = append(paragraph, inlineHTML(strings.TrimSpace(lines[i])))
+			}
+			sb.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+		}
+	}
+	return sb.String()
+}
+
+var (
+	headingPattern   = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	rulePattern      = regexp.MustCompile(`^([-*_])(\s*[-*_]){2,}$`)
+	listPattern      = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	tableRulePattern = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+)
+
+// startsParagraph reports whether line i goes on the paragraph being
+// collected rather than starting another block
+func startsParagraph(lines []string, i int, first bool) bool {
+	line := lines[i]
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return false
+	}
+	if first {
+		return true
+	}
+	return !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") && !strings.HasPrefix(trimmed, ">") &&
+		!headingPattern.MatchString(line) && !listPattern.MatchString(line) && !rulePattern.MatchString(trimmed)
+}
+
+// writeList renders the list starting at line i, with the lines indented
+// under an item as its content, and returns the line after it
+func writeList(sb *strings.Builder, lines []string, i int) int {
+	match := listPattern.FindStringSubmatch(lines[i])
+	indent, tag := len(match[1]), listTag(match)
+	// Items of the same list are indented alike and numbered or not alike
+	sameList := func(line string) bool {
+		match := listPattern.FindStringSubmatch(line)
+		return match != nil && len(match[1]) == indent && listTag(match) == tag
+	}
+	sb.WriteString("<" + tag + ">\n")
+	for i < len(lines) && sameList(lines[i]) {
+		match := listPattern.FindStringSubmatch(lines[i])
+		item := []string{match[3]}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// A blank line ends the item unless indented content follows
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) > indent {
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			if leadingSpaces(line) <= indent {
+				break
+			}
+			item = append(item, strings.TrimPrefix(line, strings.Repeat(" ", min(leadingSpaces(line), indent+2))))
+		}
+		if len(item) == 1 {
+			sb.WriteString("<li>" + inlineHTML(item[0]) + "</li>\n")
+		} else {
+			sb.WriteString("<li>" + MarkdownHTML(strings.Join(item, "\n")) + "</li>\n")
+		}
+		// Blank lines between the items of a list
+		for i+1 < len(lines) && strings.TrimSpace(lines[i]) == "" && sameList(lines[i+1]) {
+			i++
+		}
+	}
+	sb.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// listTag returns the tag of the list an item belongs to
+func listTag(match []string) string {
+	if unicode.IsDigit(rune(match[2][0])) {
+		return "ol"
+	}
+	return "ul"
+}
+
+// leadingSpaces counts the spaces a line is indented by, a tab as four
+func leadingSpaces(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// writeTable renders the table whose header is line i and returns the line
+// after it
+func writeTable(sb *strings.Builder, lines []string, i int) int {
+	sb.WriteString("<table>\n<thead><tr>")
+	for _, cell := range tableCells(lines[i]) {
+		sb.WriteString("<th>" + inlineHTML(cell) + "</th>")
+	}
+	sb.WriteString("</tr></thead>\n<tbody>\n")
+	for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+		sb.WriteString("<tr>")
+		for _, cell := range tableCells(lines[i]) {
+			sb.WriteString("<td>" + inlineHTML(cell) + "</td>")
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+// tableCells splits a table row into its cells
+func tableCells(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	cells := strings.Split(row, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells
+}
+
+var (
+	linkPattern    = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+	urlPattern     = regexp.MustCompile(`(^|[\s(])(https?://[^\s<)]+)`)
+	boldPattern    = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	italicPattern  = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*)\*`)
+	strikePattern  = regexp.MustCompile(`~~([^~]+)~~`)
+	placeholderFmt = "\x00%d\x00"
+)
+
+// inlineHTML renders the code spans, links, bold, italic and struck text of
+// a line, escaping everything else
+func inlineHTML(text string) string {
+	var sb strings.Builder
+	var links []string
+	for i, part := range strings.Split(text, "`") {
+		// Odd parts are inside backticks, unless the last one is unclosed
+		if i%2 == 1 && i < strings.Count(text, "`") {
+			sb.WriteString("<code>" + html.EscapeString(part) + "</code>")
+			continue
+		}
+		if i > 0 && i%2 == 1 {
+			sb.WriteString("`")
+		}
+		part = html.EscapeString(strings.ReplaceAll(part, "\x00", ""))
+		// Links go in first and are kept out of the way of the emphasis
+		part = linkPattern.ReplaceAllStringFunc(part, func(link string) string {
+			match := linkPattern.FindStringSubmatch(link)
+			links = append(links, fmt.Sprintf(`<a href="%s">%s</a>`, match[2], match[1]))
+			return fmt.Sprintf(placeholderFmt, len(links)-1)
+		})
+		part = urlPattern.ReplaceAllStringFunc(part, func(url string) string {
+			match := urlPattern.FindStringSubmatch(url)
+			// Punctuation ending a sentence is not part of the URL
+			link := strings.TrimRight(match[2], ".,;:!?")
+			links = append(links, fmt.Sprintf(`<a href="%s">%s</a>`, link, link))
+			return match[1] + fmt.Sprintf(placeholderFmt, len(links)-1) + strings.TrimPrefix(match[2], link)
+		})
+		part = boldPattern.ReplaceAllString(part, "<strong>$1</strong>")
+		part = italicPattern.ReplaceAllString(part, "$1<em>$2</em>")
+		part = strikePattern.ReplaceAllString(part, "<del>$1</del>")
+		sb.WriteString(part)
+	}
+	result := sb.String()
+	for i, link := range links {
+		result = strings.Replace(result, fmt.Sprintf(placeholderFmt, i), link, 1)
+	}
+	return result
+}
+
+// codeBlockHTML renders a fenced code block, highlighted when its language
+// is given
+func codeBlockHTML(code, lang string) string {
+	class := ""
+	if fields := strings.Fields(lang); len(fields) > 0 {
+		lang = strings.ToLower(fields[0])
+		class = fmt.Sprintf(` class="language-%s"`, html.EscapeString(lang))
+	}
+	return fmt.Sprintf("<pre><code%s>%s</code></pre>\n", class, HighlightCodeHTML(code, lang))
+}
+
+// codeKeywords are the keywords highlighted in code, from the languages
+// models answer in most
+var codeKeywords = map[string]bool{}
+
+func init() {
+	for _, keyword := range strings.Fields(`
+		if else elif for while do return break continue switch case default goto
+		func function def fn lambda class struct interface enum trait impl type
+		import from package use mod export module require include
+		var let const mut static final public private protected pub
+		new delete this self super extends implements
+		try catch except finally throw throws raise with as defer go select chan map range
+		async await yield in is not and or of
+		true false nil null None True False undefined void
+		then fi esac done echo local readonly
+		match where loop unsafe ref
+		SELECT FROM WHERE INSERT INTO VALUES UPDATE SET DELETE JOIN LEFT RIGHT INNER
+		ON GROUP BY ORDER HAVING LIMIT CREATE TABLE DROP ALTER INDEX AND OR NOT NULL AS`) {
+		codeKeywords[keyword] = true
+	}
+}
+
+// commentPrefixes returns how line comments start in lang, and whether it
+// has /* */ comments
+func commentPrefixes(lang string) ([]string, bool) {
+	switch lang {
+	case "python", "py", "sh", "bash", "shell", "zsh", "console", "ruby", "rb", "yaml", "yml",
+		"toml", "perl", "r", "dockerfile", "makefile", "make", "ini", "conf", "nginx":
+		return []string{"#"}, false
+	case "sql", "lua", "haskell", "hs":
+		return []string{"--"}, true
+	case "json", "text", "txt", "plain", "markdown", "md", "csv":
+		return nil, false
+	case "css", "scss":
+		return nil, true
+	}
+	return []string{"//"}, true
+}
+
+// HighlightCodeHTML escapes code and marks its keywords, strings, numbers
+// and comments with spans of the classes kw, str, num and com. Code in no
+// given language is only escaped.
+func HighlightCodeHTML(code, lang string) string {
+	if lang == "" {
+		return html.EscapeString(code)
+	}
+	lineComments, blockComments := commentPrefixes(lang)
+
+	var sb strings.Builder
+	span := func(class, text string) {
+		sb.WriteString(`<span class="` + class + `">` + html.EscapeString(text) + "</span>")
+	}
+	runes := []rune(code)
+	for i := 0; i < len(runes); {
+		rest := string(runes[i:min(i+3, len(runes))])
+		r := runes[i]
+		switch {
+		case blockComments && strings.HasPrefix(rest, "/*"):
+			end := i + 2
+			for end+1 < len(runes) && (runes[end] != '*' || runes[end+1] != '/') {
+				end++
+			}
+			end = min(end+2, len(runes))
+			span("com", string(runes[i:end]))
+			i = end
+		case hasAnyPrefix(rest, lineComments):
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			span("com", string(runes[i:end]))
+			i = end
+		case r == '"' || r == '\'' || r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != r && (r == '`' || runes[end] != '\n') {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(runes))
+			span("str", string(runes[i:end]))
+			i = end
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || unicode.IsLetter(runes[end]) || runes[end] == '.' || runes[end] == '_') {
+				end++
+			}
+			span("num", string(runes[i:end]))
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			word := string(runes[i:end])
+			if codeKeywords[word] {
+				span("kw", word)
+			} else {
+				sb.WriteString(html.EscapeString(word))
+			}
+			i = end
+		default:
+			sb.WriteString(html.EscapeString(string(r)))
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// hasAnyPrefix reports whether s starts with one of prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Memory is a fact the user asked to remember across sessions
+type Memory struct {
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetMemoriesPath returns the path to the file of remembered facts
+func GetMemoriesPath() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dataDir, "memories.json"), nil
+}
+
+// LoadMemories loads the remembered facts, oldest first
+func LoadMemories() ([]Memory, error) {
+	memoriesPath, err := GetMemoriesPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(memoriesPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var memories []Memory
+	if err := json.Unmarshal(data, &memories); err != nil {
+		return nil, err
+	}
+	return memories, nil
+}
+
+// SaveMemories writes the remembered facts
+func SaveMemories(memories []Memory) error {
+	memoriesPath, err := GetMemoriesPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(memories, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(memoriesPath, append(data, '\n'), 0600)
+}
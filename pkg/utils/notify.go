@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// NotifyMethod is a way of showing a desktop notification
+type NotifyMethod int
+
+const (
+	// NotifyOff sends no notifications
+	NotifyOff NotifyMethod = iota
+	// NotifyOSC9 asks the terminal with OSC 9 (iTerm2, WezTerm, Kitty, Ghostty)
+	NotifyOSC9
+	// NotifyOSC777 asks the terminal with OSC 777 (foot, urxvt, VTE terminals)
+	NotifyOSC777
+	// NotifySystem runs the platform's notifier (osascript or notify-send)
+	NotifySystem
+	// NotifyBell only rings the terminal bell
+	NotifyBell
+)
+
+// notifyMethodNames are the config values of the methods
+var notifyMethodNames = []string{"off", "osc9", "osc777", "system", "bell"}
+
+// String returns the name of the method
+func (n NotifyMethod) String() string {
+	if int(n) < len(notifyMethodNames) {
+		return notifyMethodNames[n]
+	}
+	return notifyMethodNames[0]
+}
+
+// ParseNotifyMethod returns the method with the given name, detecting it
+// from the terminal for "auto" or an empty name
+func ParseNotifyMethod(name string) NotifyMethod {
+	for i, n := range notifyMethodNames {
+		if n == name {
+			return NotifyMethod(i)
+		}
+	}
+	return DetectNotifyMethod()
+}
+
+// DetectNotifyMethod picks the terminal's own notifications when it is known
+// to support them, and the platform's notifier otherwise
+func DetectNotifyMethod() NotifyMethod {
+	term := os.Getenv("TERM")
+	program := os.Getenv("TERM_PROGRAM")
+	switch {
+	case program == "iTerm.app" || program == "WezTerm" || program == "ghostty" ||
+		os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || term == "xterm-ghostty":
+		return NotifyOSC9
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "rxvt") || os.Getenv("VTE_VERSION") != "":
+		return NotifyOSC777
+	}
+	if systemNotifier() != "" {
+		return NotifySystem
+	}
+	return NotifyBell
+}
+
+// systemNotifier returns the notification program of the platform, if it is
+// installed
+func systemNotifier() string {
+	program := "notify-send"
+	switch runtime.GOOS {
+	case "darwin":
+		program = "osascript"
+	case "windows":
+		return ""
+	}
+	if _, err := exec.LookPath(program); err != nil {
+		return ""
+	}
+	return program
+}
+
+// Notify shows a notification with the given method, writing terminal
+// sequences to out. bell also rings the terminal bell.
+func Notify(out io.Writer, method NotifyMethod, title, body string, bell bool) error {
+	// Control characters would end the escape sequence early
+	title = sanitizeNotification(title)
+	body = sanitizeNotification(body)
+
+	var sequence string
+	var err error
+	switch method {
+	case NotifyOSC9:
+		sequence = passthrough(fmt.Sprintf("\x1b]9;%s: %s\x07", title, body))
+	case NotifyOSC777:
+		// OSC 777 separates its fields with semicolons
+		sequence = passthrough(fmt.Sprintf("\x1b]777;notify;%s;%s\x07",
+			strings.ReplaceAll(title, ";", ","), body))
+	case NotifySystem:
+		err = systemNotify(title, body)
+	case NotifyBell:
+		bell = true
+	}
+	if bell {
+		sequence += "\a"
+	}
+	if sequence != "" {
+		if _, werr := io.WriteString(out, sequence); err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// systemNotify shows a notification with the platform's notifier
+func systemNotify(title, body string) error {
+	switch systemNotifier() {
+	case "osascript":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(title))
+		return exec.Command("osascript", "-e", script).Run()
+	case "notify-send":
+		// A response starting with "- item" or "--foo" is not an option
+		return exec.Command("notify-send", "--app-name=ollama-tui", "--", title, body).Run()
+	}
+	if runtime.GOOS == "windows" {
+		return errors.New("system notifications are not supported on Windows; use osc9 or bell")
+	}
+	return errors.New("system notifications need notify-send to be installed")
+}
+
+// appleScriptString quotes text as an AppleScript string literal
+func appleScriptString(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+}
+
+// passthrough wraps a sequence so that tmux forwards it to the terminal
+func passthrough(sequence string) string {
+	if os.Getenv("TMUX") == "" {
+		return sequence
+	}
+	return "\x1bPtmux;" + strings.ReplaceAll(sequence, "\x1b", "\x1b\x1b") + "\x1b\\"
+}
+
+// sanitizeNotification puts text on one line without control characters
+func sanitizeNotification(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
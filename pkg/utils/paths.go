@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// appName names the application's directories
+const appName = "ollama-tui"
+
+// userDataDir returns the directory for application data: $XDG_DATA_HOME
+// (~/.local/share) on Linux, the same as the config directory on macOS and
+// %LocalAppData% on Windows
+func userDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return os.UserConfigDir()
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return dir, nil
+		}
+		return os.UserConfigDir()
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// appDir returns the application's directory in base, creating it
+func appDir(base string, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, appName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// GetDataDir returns the directory where sessions, prompt history, pasted
+// images and feedback reports are stored
+func GetDataDir() (string, error) {
+	return appDir(userDataDir())
+}
+
+// GetCacheDir returns the directory for files that can be recreated, such
+// as logs: ollama-tui in $XDG_CACHE_HOME (~/.cache) on Linux,
+// ~/Library/Caches on macOS and %LocalAppData% on Windows
+func GetCacheDir() (string, error) {
+	return appDir(os.UserCacheDir())
+}
+
+// GetLogDir returns the directory debug logs are written to
+func GetLogDir() (string, error) {
+	cacheDir, err := GetCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cacheDir, "logs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// isDataFile reports whether an entry of the old config directory belongs
+// in the data directory
+func isDataFile(name string) bool {
+	switch name {
+	case "sessions", "images", "history.jsonl":
+		return true
+	}
+	return strings.HasPrefix(name, "feedback-")
+}
+
+// moveMissing moves from to to unless to exists. Directories that exist at
+// both places are merged.
+func moveMissing(from, to string) error {
+	info, err := os.Lstat(to)
+	if err != nil {
+		if err := os.Rename(from, to); err != nil {
+			return fmt.Errorf("moving %s to %s: %w", from, filepath.Dir(to), err)
+		}
+		return nil
+	}
+	if !info.IsDir() {
+		return nil
+	}
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return nil
+	}
+	var errs []error
+	for _, entry := range entries {
+		errs = append(errs, moveMissing(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name())))
+	}
+	_ = os.Remove(from)
+	return errors.Join(errs...)
+}
+
+// MigrateLegacyDirs moves the files of ~/.config/ollama-tui, where every
+// platform used to keep everything, to the config and data directories.
+// Files that already exist at their new place are left alone.
+func MigrateLegacyDirs() error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	legacy := filepath.Join(home, ".config", appName)
+	entries, err := os.ReadDir(legacy)
+	if err != nil {
+		return nil
+	}
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return err
+	}
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		target := configDir
+		if isDataFile(entry.Name()) {
+			target = dataDir
+		}
+		if target == legacy {
+			continue
+		}
+		if err := moveMissing(filepath.Join(legacy, entry.Name()), filepath.Join(target, entry.Name())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// Only removed once everything was moved
+	if legacy != configDir {
+		_ = os.Remove(legacy)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ActiveProfile is the profile LoadEffectiveConfig layers over config.json,
+// chosen at startup
+var ActiveProfile string
+
+// ProfileNames returns the names of the profiles of config in order
+func ProfileNames(config Config) []string {
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileKeys returns the config.json keys a profile sets, in order
+func ProfileKeys(config Config, name string) []string {
+	var settings map[string]json.RawMessage
+	if json.Unmarshal(config.Profiles[name], &settings) != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ApplyProfile layers the settings of a profile over config. Keys of the
+// profile replace those of the file, except objects such as "hosts", whose
+// entries are added to the file's.
+func ApplyProfile(config *Config, name string) error {
+	raw, ok := config.Profiles[name]
+	if !ok {
+		return fmt.Errorf("no profile named %q in config.json", name)
+	}
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("%q can't name a profile", name)
+	}
+	profiles := config.Profiles
+	if err := json.Unmarshal(raw, config); err != nil {
+		return fmt.Errorf("profile %s: %w", name, err)
+	}
+	// Profiles don't nest
+	config.Profiles = profiles
+	config.Profile = name
+	return nil
+}
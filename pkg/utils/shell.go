@@ -0,0 +1,390 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultShellDeny blocks destructive commands when no denylist is configured
+var DefaultShellDeny = []string{
+	"rm -rf /", "rm -rf /*", "rm -rf ~", "rm -rf ~/", "rm -rf *",
+	"mkfs", "dd", "shred", "shutdown", "reboot", "halt", "poweroff",
+	"chmod -R 777 /", "chown -R",
+}
+
+// SplitShellCommand splits a command line into the simple commands joined
+// by pipes, ;, &&, || and newlines. Quoted text is kept intact, but the
+// commands substituted with $(...) or backticks, which run even inside
+// double quotes, are returned as commands of their own.
+func SplitShellCommand(command string) []string {
+	var segments []string
+	var current strings.Builder
+	var quote rune
+	escaped := false
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			segments = append(segments, s)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote == '\'':
+			if r == quote {
+				quote = 0
+			}
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '(':
+			end := substitutionEnd(runes, i+2)
+			segments = append(segments, SplitShellCommand(string(runes[i+2:end]))...)
+			current.WriteString(string(runes[i:min(end+1, len(runes))]))
+			i = end
+			continue
+		case r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != '`' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end, len(runes))
+			segments = append(segments, SplitShellCommand(string(runes[i+1:end]))...)
+			current.WriteString(string(runes[i:min(end+1, len(runes))]))
+			i = end
+			continue
+		case quote == '"':
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '|' || r == ';' || r == '\n' || r == '&' || r == '(' || r == ')':
+			// "2>&1" and "&>" redirect output rather than separate commands
+			if r == '&' && (i > 0 && runes[i-1] == '>' || i+1 < len(runes) && runes[i+1] == '>') {
+				break
+			}
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	flush()
+	return segments
+}
+
+// substitutionEnd returns the index of the parenthesis closing the command
+// substitution whose text starts at start, or the end of runes when it is
+// never closed
+func substitutionEnd(runes []rune, start int) int {
+	depth := 1
+	var quote rune
+	for i := start; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && quote != '\'':
+			i++
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(runes)
+}
+
+// CheckShellCommand returns an error when any part of command is denied,
+// or is missing from a configured allowlist. The scripts run by sh -c and
+// bash -c are checked as commands of their own.
+func CheckShellCommand(command string, cfg ShellConfig) error {
+	deny := cfg.Deny
+	if deny == nil {
+		deny = DefaultShellDeny
+	}
+
+	segments := SplitShellCommand(command)
+	if len(segments) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	for _, segment := range segments {
+		words := shellWords(segment)
+		simple, fromInput := stripCommandPrefix(words)
+		if script, ok := shellScript(simple); ok && strings.TrimSpace(script) != "" {
+			if err := CheckShellCommand(script, cfg); err != nil {
+				return err
+			}
+		}
+		for _, entry := range deny {
+			entryWords := shellWords(entry)
+			if matchesCommand(simple, entryWords, fromInput) || matchesCommand(words, entryWords, false) {
+				return fmt.Errorf("%q is blocked by the shell denylist (%s)", segment, entry)
+			}
+		}
+		if len(cfg.Allow) == 0 {
+			continue
+		}
+		allowed := false
+		for _, entry := range cfg.Allow {
+			if matchesCommand(simple, shellWords(entry), false) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%q is not in the shell allowlist", segment)
+		}
+	}
+	return nil
+}
+
+// shellWords splits a simple command into its words, removing the quotes
+// and backslashes the shell would
+func shellWords(command string) []string {
+	var words []string
+	var current strings.Builder
+	var quote rune
+	inWord, escaped := false, false
+	for _, r := range command {
+		switch {
+		case escaped:
+			escaped = false
+			current.WriteRune(r)
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words
+}
+
+// commandWrapper is a program that runs the command given after its own
+// options
+type commandWrapper struct {
+	// shortArgs are the short options followed by an argument, and
+	// longArgs the long ones that take the next word as theirs
+	shortArgs string
+	longArgs  []string
+	// fromInput is set when the wrapper adds arguments read from its input
+	fromInput bool
+}
+
+// commandWrappers are the wrappers stripCommandPrefix looks through
+var commandWrappers = map[string]commandWrapper{
+	"sudo":    {shortArgs: "CDghprtTUu", longArgs: []string{"--chdir", "--close-from", "--command-timeout", "--group", "--host", "--other-user", "--prompt", "--role", "--type", "--user"}},
+	"env":     {shortArgs: "CSu", longArgs: []string{"--chdir", "--split-string", "--unset"}},
+	"nohup":   {},
+	"time":    {shortArgs: "fo", longArgs: []string{"--format", "--output"}},
+	"command": {},
+	"exec":    {shortArgs: "a"},
+	"nice":    {shortArgs: "n", longArgs: []string{"--adjustment"}},
+	"xargs":   {shortArgs: "aEILnPds", longArgs: []string{"--arg-file", "--delimiter", "--max-args", "--max-chars", "--max-lines", "--max-procs", "--process-slot-var"}, fromInput: true},
+}
+
+// stripCommandPrefix drops leading variable assignments and wrappers such
+// as sudo, env or xargs, with their options, so the command that actually
+// runs is checked. fromInput reports whether xargs adds arguments to it.
+func stripCommandPrefix(words []string) (command []string, fromInput bool) {
+	for len(words) > 0 {
+		wrapper, ok := commandWrappers[programName(words[0])]
+		switch {
+		case ok:
+			words = skipOptions(words[1:], wrapper)
+			fromInput = fromInput || wrapper.fromInput
+		case strings.Contains(words[0], "=") && !strings.HasPrefix(words[0], "-"):
+			words = words[1:]
+		default:
+			return words, fromInput
+		}
+	}
+	return nil, fromInput
+}
+
+// skipOptions drops the options of a wrapper, with their arguments, from
+// the start of words
+func skipOptions(words []string, wrapper commandWrapper) []string {
+	for len(words) > 0 {
+		word := words[0]
+		if word == "--" {
+			return words[1:]
+		}
+		if !strings.HasPrefix(word, "-") {
+			return words
+		}
+		words = words[1:]
+		if strings.HasPrefix(word, "--") {
+			if slices.Contains(wrapper.longArgs, word) && len(words) > 0 {
+				words = words[1:]
+			}
+			continue
+		}
+		for i, r := range word[1:] {
+			if strings.ContainsRune(wrapper.shortArgs, r) {
+				// The rest of the word is the argument, or else the next word
+				if i == len(word)-2 && len(words) > 0 {
+					words = words[1:]
+				}
+				break
+			}
+		}
+	}
+	return words
+}
+
+// shells are the programs whose -c option runs a script
+var shells = []string{"sh", "bash", "dash", "zsh", "ksh"}
+
+// shellScript returns the script a shell is asked to run with -c
+func shellScript(command []string) (string, bool) {
+	if len(command) == 0 || !slices.Contains(shells, programName(command[0])) {
+		return "", false
+	}
+	hasScript := false
+	for i := 1; i < len(command); i++ {
+		arg := command[i]
+		switch {
+		case arg == "--":
+			if hasScript && i+1 < len(command) {
+				return command[i+1], true
+			}
+			return "", false
+		case arg == "-o" || arg == "+o" || arg == "-O" || arg == "+O":
+			i++
+		case strings.HasPrefix(arg, "--"):
+		case len(arg) > 1 && (arg[0] == '-' || arg[0] == '+'):
+			hasScript = hasScript || arg[0] == '-' && strings.Contains(arg, "c")
+		default:
+			return arg, hasScript
+		}
+	}
+	return "", false
+}
+
+// programName returns the name of the program a command word runs,
+// without its directory
+func programName(word string) string {
+	return word[strings.LastIndex(word, "/")+1:]
+}
+
+// flagPrograms are the programs whose short flags are compared as a set,
+// in any order and spelling, with the long flags they stand for. Their
+// other arguments are compared as a set too.
+var flagPrograms = map[string]map[string]rune{
+	"rm":    {"-R": 'r', "--recursive": 'r', "--force": 'f'},
+	"chmod": {"--recursive": 'R'},
+	"chown": {"--recursive": 'R'},
+}
+
+// parsedCommand is a simple command reduced to its program, the set of
+// its short flags when the program is one of flagPrograms, and its other
+// arguments
+type parsedCommand struct {
+	program string
+	flags   string
+	args    []string
+}
+
+// parseCommand reduces the words of a simple command to a parsedCommand
+func parseCommand(words []string) parsedCommand {
+	parsed := parsedCommand{program: programName(words[0])}
+	aliases, ok := flagPrograms[parsed.program]
+	if !ok {
+		parsed.args = words[1:]
+		return parsed
+	}
+
+	var flags []rune
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case word == "--":
+			parsed.args = append(parsed.args, words[i+1:]...)
+			i = len(words)
+		case strings.HasPrefix(word, "--"):
+			if flag, ok := aliases[word]; ok {
+				flags = append(flags, flag)
+			} else {
+				parsed.args = append(parsed.args, word)
+			}
+		case len(word) > 1 && word[0] == '-':
+			for _, r := range word[1:] {
+				if flag, ok := aliases["-"+string(r)]; ok {
+					r = flag
+				}
+				flags = append(flags, r)
+			}
+		default:
+			parsed.args = append(parsed.args, word)
+		}
+	}
+	slices.Sort(flags)
+	parsed.flags = string(slices.Compact(flags))
+	return parsed
+}
+
+// matchesCommand reports whether command runs the program of entry with at
+// least its flags and starts with its other arguments. For flagPrograms the
+// arguments of entry may come in any order among the command's, and none
+// are needed when fromInput is set since xargs may supply them. Entries
+// that are a program name also match its variants such as mkfs.ext4.
+func matchesCommand(command, entry []string, fromInput bool) bool {
+	if len(command) == 0 || len(entry) == 0 {
+		return false
+	}
+	c, e := parseCommand(command), parseCommand(entry)
+	if c.program != e.program && !(len(entry) == 1 && strings.HasPrefix(c.program, e.program+".")) {
+		return false
+	}
+	for _, flag := range e.flags {
+		if !strings.ContainsRune(c.flags, flag) {
+			return false
+		}
+	}
+	if _, ok := flagPrograms[e.program]; ok {
+		if fromInput {
+			return true
+		}
+		for _, arg := range e.args {
+			if !slices.Contains(c.args, arg) {
+				return false
+			}
+		}
+		return true
+	}
+	return len(c.args) >= len(e.args) && slices.Equal(c.args[:len(e.args)], e.args)
+}
